@@ -12,6 +12,14 @@
 //       --header 'Content-Type: application/json' \
 //       --header 'idempotency-key: ' \
 //       --data '{  "login": "jcgregorio",  "patchset": 13,  "pr": 7, "sha": "01482eb651c1881437dc8f9e928677222943e1dc" }'
+//
+// Secrets, such as the credentials used by UploadGoldResults, are stored in the
+// cluster and mounted into --secrets_dir. A repo injects them into specific
+// activities, as environment variables, by referring to them by name in its
+// ci.json5, see shared.CIConfig. Their values are redacted from the logged
+// output of those activities. Since the activities run the code being tested,
+// secrets are only injected into runs on main and into PRs from
+// --trusted_authors, never into other PRs.
 
 package main
 
@@ -51,6 +59,9 @@ type ServerFlags struct {
 	Branch  string
 
 	RestateURL string
+
+	SecretsDir     string
+	TrustedAuthors []string
 }
 
 // Flagset constructs a flag.FlagSet for the App.
@@ -69,12 +80,16 @@ func (s *ServerFlags) Flagset() *flag.FlagSet {
 
 	fs.StringVar(&s.RestateURL, "restate_url", "https://restate-server.tail433733.ts.net", "The URL of the Restate UI.")
 
+	fs.StringVar(&s.SecretsDir, "secrets_dir", "", "The directory holding the CI secrets, one file per secret, e.g. a mounted Kubernetes Secret. Repos refer to them by name in their "+shared.CIConfigFilename+".")
+	common.FSMultiStringFlagVar(fs, &s.TrustedAuthors, "trusted_authors", nil, "GitHub logins whose PRs get the CI secrets injected. PRs from anyone else run without them.")
+
 	return fs
 }
 
 var (
-	flags  ServerFlags
-	gitApi *gitapi.GitApi = nil
+	flags   ServerFlags
+	gitApi  *gitapi.GitApi = nil
+	secrets *shared.SecretStore
 
 	// https://bazel.build/run/scripts#exit-codes
 	bazelExitCodesForNonInfraErrors = []int{1, 3, 4}
@@ -99,6 +114,20 @@ func (c CI) RunAllBuildsAndTestsV1(ctx restate.Context, input shared.CIWorkflowA
 		return err
 	}
 
+	// Load the CI config from the tip of main, before checking out the code to
+	// test, since the config decides which secrets are injected into each
+	// activity and a PR must not be able to change that.
+	if err = gitCommand(ctx, input, checkout, "fetch", "origin", "refs/heads/main"); err != nil {
+		return err
+	}
+	if err = gitCommand(ctx, input, checkout, "checkout", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	ciConfig, err := shared.LoadCIConfig(filepath.Join(flags.CheckoutDir, flags.Repo))
+	if err != nil {
+		return infraError(ctx, input, err, "Failed to load %s", shared.CIConfigFilename)
+	}
+
 	// Check out either the PR or a commit on main.
 	if input.PRNumber > 0 {
 		if err = gitCommand(ctx, input, checkout, "fetch", "origin", fmt.Sprintf("refs/pull/%d/head", input.PRNumber)); err != nil {
@@ -109,10 +138,6 @@ func (c CI) RunAllBuildsAndTestsV1(ctx restate.Context, input shared.CIWorkflowA
 			return err
 		}
 	} else {
-		if err = gitCommand(ctx, input, checkout, "fetch", "origin", "refs/heads/main"); err != nil {
-			return err
-		}
-
 		if err = gitCommand(ctx, input, checkout, "checkout", input.SHA); err != nil {
 			return err
 		}
@@ -124,25 +149,29 @@ func (c CI) RunAllBuildsAndTestsV1(ctx restate.Context, input shared.CIWorkflowA
 	}
 
 	sklog.Info("Sanity Check")
-	err = runBazelCommand(ctx, input, "Sanity Check", bazel, "query", "//...")
+	err = runBazelCommand(ctx, input, ciConfig, "Sanity Check", bazel, "query", "//...")
 	if err != nil {
 		return err
 	}
 
 	sklog.Info("Build")
-	err = runBazelCommand(ctx, input, "Build", bazel, "build", "//golden/...", "//perf/...", "//go/...")
+	err = runBazelCommand(ctx, input, ciConfig, "Build", bazel, "build", "//golden/...", "//perf/...", "//go/...")
 	if err != nil {
 		return err
 	}
 
 	sklog.Info("Test")
-	err = runBazelCommand(ctx, input, "Test", bazel, "test", "//golden/modules/...", "//perf/modules/...", "//go/...")
+	err = runBazelCommand(ctx, input, ciConfig, "Test", bazel, "test", "//golden/modules/...", "//perf/modules/...", "//go/...")
 	if err != nil {
 		return err
 	}
 
 	// TODO Make this into a bazel command also?
 	sklog.Info("UploadGoldResults")
+	env, redactor, err := activityEnv(input, ciConfig, "UploadGoldResults")
+	if err != nil {
+		return infraError(ctx, input, err, "Failed to load secrets")
+	}
 	var cmd *exec.Cmd
 	if input.PRNumber > 0 {
 		cmd = exec.CommandContext(ctx, "./upload_to_gold/upload.sh", input.SHA, fmt.Sprintf("%d", input.PRNumber))
@@ -150,8 +179,9 @@ func (c CI) RunAllBuildsAndTestsV1(ctx restate.Context, input shared.CIWorkflowA
 		// Passing in an empty PR Number indicates this is on main and not in a PR.
 		cmd = exec.CommandContext(ctx, "./upload_to_gold/upload.sh", input.SHA)
 	}
+	cmd.Env = env
 	if b, err := cmd.CombinedOutput(); err != nil {
-		sklog.Errorf("Failed to run upload.sh script: %s: %s", err, string(b))
+		sklog.Errorf("Failed to run upload.sh script: %s: %s", err, redactor.Redact(string(b)))
 		return infraError(ctx, input, err, "Infrastructure error trying to upload to Gold.")
 	}
 	sklog.Info("UploadGoldResults Complete")
@@ -169,11 +199,36 @@ func gitCommand(ctx restate.Context, input shared.CIWorkflowArgs, checkout *git.
 	return nil
 }
 
-func runBazelCommand(ctx restate.Context, input shared.CIWorkflowArgs, step string, bazel string, args ...string) error {
+// secretsAllowed returns true if the code being tested may be given secrets,
+// i.e. it is on main or the PR is from a trusted author.
+func secretsAllowed(input shared.CIWorkflowArgs) bool {
+	return input.PRNumber == 0 || slices.Contains(flags.TrustedAuthors, input.Login)
+}
+
+// activityEnv returns the environment to run the given activity with, i.e. the
+// environment of this process plus the secrets the repo's CI config injects
+// into that activity, along with a Redactor that removes those secrets from the
+// output of the activity. No secrets are injected if !secretsAllowed(input).
+func activityEnv(input shared.CIWorkflowArgs, ciConfig *shared.CIConfig, activity string) ([]string, *shared.Redactor, error) {
+	if !secretsAllowed(input) {
+		sklog.Infof("Not injecting secrets into %s of PR %d from untrusted author %q.", activity, input.PRNumber, input.Login)
+		return os.Environ(), nil, nil
+	}
+	secretEnv, redactor, err := ciConfig.ActivitySecrets(activity, secrets)
+	if err != nil {
+		return nil, nil, skerr.Wrap(err)
+	}
+	return append(os.Environ(), secretEnv...), redactor, nil
+}
+
+func runBazelCommand(ctx restate.Context, input shared.CIWorkflowArgs, ciConfig *shared.CIConfig, step string, bazel string, args ...string) error {
 	cmd := exec.CommandContext(ctx, bazel, args...)
+	env, redactor, err := activityEnv(input, ciConfig, step)
+	if err != nil {
+		return infraError(ctx, input, err, "Failed to load secrets")
+	}
 	// Point to the running emulators.
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, "COCKROACHDB_EMULATOR_HOST=localhost:8895", "PUBSUB_EMULATOR_HOST=localhost:8893")
+	cmd.Env = append(env, "COCKROACHDB_EMULATOR_HOST=localhost:8895", "PUBSUB_EMULATOR_HOST=localhost:8893")
 	os.Chdir(filepath.Join(flags.CheckoutDir, flags.Repo))
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
@@ -191,7 +246,7 @@ func runBazelCommand(ctx restate.Context, input shared.CIWorkflowArgs, step stri
 	go func() {
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			sklog.Info(scanner.Text())
+			sklog.Info(redactor.Redact(scanner.Text()))
 		}
 		if err := scanner.Err(); err != nil {
 			sklog.Errorf("reading stderr: %s", err)
@@ -256,6 +311,8 @@ func main() {
 		sklog.Info("Emulators started")
 	}()
 
+	secrets = shared.NewSecretStore(flags.SecretsDir)
+
 	gitApi, err = gitapi.New(context.Background(), flags.PatPath, flags.Owner, flags.Repo, flags.Branch)
 	if err != nil {
 		sklog.Fatalf("Unable to create GitHub API: %s", err)
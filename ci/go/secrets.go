@@ -0,0 +1,165 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.goldmine.build/go/config"
+	"go.goldmine.build/go/skerr"
+)
+
+// CIConfigFilename is the name of the file, at the root of a repo, that
+// configures how the CI runs for that repo. It is optional, and is always read
+// from main, never from the PR being tested.
+const CIConfigFilename = "ci.json5"
+
+// redacted replaces secret values in logged output.
+const redacted = "[REDACTED]"
+
+// minRedactedLineLength is the length below which the individual lines of a
+// multi-line secret are not redacted on their own, since lines such as "{"
+// would otherwise be redacted everywhere.
+const minRedactedLineLength = 8
+
+// validSecretName is the format of secret names, which are also filenames, so
+// they can't refer to files outside of the secrets directory.
+var validSecretName = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.\-]*$`)
+
+// ActivityConfig configures a single activity of the CI, e.g.
+// "UploadGoldResults".
+type ActivityConfig struct {
+	// Secrets maps the names of environment variables to the names of the
+	// secrets that are injected into them, only for this activity.
+	Secrets map[string]string `json:"secrets"`
+}
+
+// CIConfig is the format of CIConfigFilename, for example:
+//
+//	{
+//	  activities: {
+//	    UploadGoldResults: {
+//	      secrets: {
+//	        GOLD_SERVICE_ACCOUNT: "gold-service-account",
+//	      },
+//	    },
+//	  },
+//	}
+type CIConfig struct {
+	// Activities are keyed by activity name.
+	Activities map[string]ActivityConfig `json:"activities"`
+}
+
+// LoadCIConfig loads the CIConfigFilename from the root of the checkout in dir.
+// An empty CIConfig is returned if the repo doesn't have one.
+func LoadCIConfig(dir string) (*CIConfig, error) {
+	ret := &CIConfig{}
+	filename := filepath.Join(dir, CIConfigFilename)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err := config.ParseConfigFile(filename, "", ret); err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	return ret, nil
+}
+
+// SecretStore reads secrets from a directory containing one file per secret,
+// named after the secret, such as a Kubernetes Secret mounted as a volume.
+type SecretStore struct {
+	dir string
+}
+
+// NewSecretStore returns a SecretStore that reads secrets from dir. If dir is
+// the empty string then every lookup fails.
+func NewSecretStore(dir string) *SecretStore {
+	return &SecretStore{dir: dir}
+}
+
+// Get returns the value of the named secret.
+func (s *SecretStore) Get(name string) (string, error) {
+	if !validSecretName.MatchString(name) {
+		return "", skerr.Fmt("invalid secret name %q", name)
+	}
+	if s.dir == "" {
+		return "", skerr.Fmt("secret %q requested, but no secrets are configured", name)
+	}
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return "", skerr.Wrapf(err, "reading secret %q", name)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// ActivitySecrets returns the environment variables, in the form "NAME=value",
+// to add to the environment of the given activity, along with a Redactor that
+// removes their values from the output of the activity.
+func (c *CIConfig) ActivitySecrets(activity string, store *SecretStore) ([]string, *Redactor, error) {
+	activityConfig := c.Activities[activity]
+	names := make([]string, 0, len(activityConfig.Secrets))
+	for envName := range activityConfig.Secrets {
+		names = append(names, envName)
+	}
+	sort.Strings(names)
+
+	env := make([]string, 0, len(names))
+	values := make([]string, 0, len(names))
+	for _, envName := range names {
+		secretName := activityConfig.Secrets[envName]
+		value, err := store.Get(secretName)
+		if err != nil {
+			return nil, nil, skerr.Wrapf(err, "activity %q", activity)
+		}
+		env = append(env, envName+"="+value)
+		values = append(values, value)
+	}
+	return env, NewRedactor(values), nil
+}
+
+// Redactor removes secret values from text before it is logged.
+type Redactor struct {
+	replacer *strings.Replacer
+}
+
+// NewRedactor returns a Redactor for the given secret values. Multi-line
+// secrets are also redacted line by line, since commands may print them that
+// way.
+func NewRedactor(secrets []string) *Redactor {
+	var toRedact []string
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		toRedact = append(toRedact, secret)
+		lines := strings.Split(secret, "\n")
+		if len(lines) == 1 {
+			continue
+		}
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if len(line) >= minRedactedLineLength {
+				toRedact = append(toRedact, line)
+			}
+		}
+	}
+	// Replace the longest values first, so a secret that contains another
+	// secret is redacted as a whole.
+	sort.Slice(toRedact, func(i, j int) bool {
+		return len(toRedact[i]) > len(toRedact[j])
+	})
+	oldnew := make([]string, 0, 2*len(toRedact))
+	for _, secret := range toRedact {
+		oldnew = append(oldnew, secret, redacted)
+	}
+	return &Redactor{replacer: strings.NewReplacer(oldnew...)}
+}
+
+// Redact returns s with all the secret values replaced.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
@@ -0,0 +1,79 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0600))
+}
+
+func TestLoadCIConfig_NoConfigFile_ReturnsEmptyConfig(t *testing.T) {
+	cfg, err := LoadCIConfig(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Activities)
+}
+
+func TestActivitySecrets_SecretsConfigured_OnlyInjectedIntoTheirActivity(t *testing.T) {
+	repoDir := t.TempDir()
+	writeFile(t, repoDir, CIConfigFilename, `{
+		// Comments are allowed.
+		activities: {
+			UploadGoldResults: {
+				secrets: {
+					GOLD_KEY: "gold-key",
+				},
+			},
+		},
+	}`)
+	secretsDir := t.TempDir()
+	writeFile(t, secretsDir, "gold-key", "s3cr3t-value\n")
+
+	cfg, err := LoadCIConfig(repoDir)
+	require.NoError(t, err)
+	store := NewSecretStore(secretsDir)
+
+	env, redactor, err := cfg.ActivitySecrets("UploadGoldResults", store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GOLD_KEY=s3cr3t-value"}, env)
+	assert.Equal(t, "key is [REDACTED].", redactor.Redact("key is s3cr3t-value."))
+
+	env, redactor, err = cfg.ActivitySecrets("Build", store)
+	require.NoError(t, err)
+	assert.Empty(t, env)
+	assert.Equal(t, "key is s3cr3t-value.", redactor.Redact("key is s3cr3t-value."))
+}
+
+func TestActivitySecrets_MissingSecret_ReturnsError(t *testing.T) {
+	cfg := &CIConfig{
+		Activities: map[string]ActivityConfig{
+			"UploadGoldResults": {Secrets: map[string]string{"GOLD_KEY": "gold-key"}},
+		},
+	}
+	_, _, err := cfg.ActivitySecrets("UploadGoldResults", NewSecretStore(t.TempDir()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `reading secret "gold-key"`)
+}
+
+func TestSecretStoreGet_NameOutsideOfDirectory_ReturnsError(t *testing.T) {
+	_, err := NewSecretStore(t.TempDir()).Get("../etc/passwd")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid secret name")
+}
+
+func TestRedactor_MultiLineSecret_LongLinesRedactedIndividually(t *testing.T) {
+	r := NewRedactor([]string{"{\n  \"private_key\": \"abcdefghij\"\n}"})
+	assert.Equal(t, "got [REDACTED]", r.Redact("got {\n  \"private_key\": \"abcdefghij\"\n}"))
+	assert.Equal(t, "line: [REDACTED]", r.Redact(`line: "private_key": "abcdefghij"`))
+	assert.Equal(t, "{ stays }", r.Redact("{ stays }"))
+}
+
+func TestRedactor_Nil_ReturnsInputUnchanged(t *testing.T) {
+	var r *Redactor
+	assert.Equal(t, "unchanged", r.Redact("unchanged"))
+}
@@ -82,6 +82,51 @@ func (ShortcutFunc) Describe() string {
 
 var shortcutFunc = ShortcutFunc{}
 
+type MetricFunc struct{}
+
+// maxMetricDepth is the maximum number of metric() calls that can be nested,
+// which stops derived metrics that refer to each other from recursing
+// forever.
+const maxMetricDepth = 10
+
+// metricFunc is a Func that evaluates the formula of a derived metric.
+//
+// It expects a single argument that is the name of a derived metric.
+func (MetricFunc) Eval(ctx *Context, node *Node) (types.TraceSet, error) {
+	if len(node.Args) != 1 {
+		return nil, fmt.Errorf("metric() takes a single argument.")
+	}
+	if node.Args[0].Typ != NodeString {
+		return nil, fmt.Errorf("metric() takes a string argument.")
+	}
+	if ctx.Metrics == nil {
+		return nil, fmt.Errorf("metric() is not available.")
+	}
+	formula, ok := ctx.Metrics[node.Args[0].Val]
+	if !ok {
+		return nil, fmt.Errorf("metric() unknown derived metric: %q", node.Args[0].Val)
+	}
+	if ctx.metricDepth >= maxMetricDepth {
+		return nil, fmt.Errorf("metric() calls are nested too deeply, derived metrics may refer to each other.")
+	}
+	n, err := parse(formula)
+	if err != nil {
+		return nil, fmt.Errorf("metric() failed to parse the formula of %q: %s", node.Args[0].Val, err)
+	}
+	ctx.metricDepth++
+	defer func() { ctx.metricDepth-- }()
+	return n.Eval(ctx)
+}
+
+func (MetricFunc) Describe() string {
+	return `metric() returns the Rows of a derived metric.
+
+  It expects a single argument that is the name of a derived metric, which
+  is evaluated as if its formula had been written in place of metric().`
+}
+
+var metricFunc = MetricFunc{}
+
 type NormFunc struct{}
 
 // normFunc implements Func and normalizes the traces to a mean of 0 and a
@@ -80,7 +80,13 @@ type Context struct {
 	RowsFromQuery    RowsFromQuery
 	RowsFromShortcut RowsFromShortcut
 	Funcs            map[string]Func
-	formula          string // The current formula being evaluated.
+
+	// Metrics maps the names of derived metrics to their formulas. It is used
+	// by metric() and may be nil, in which case metric() is not available.
+	Metrics map[string]string
+
+	formula     string // The current formula being evaluated.
+	metricDepth int    // The number of nested metric() calls being evaluated.
 }
 
 // NewContext create a new parsing context that includes the basic functions.
@@ -91,6 +97,7 @@ func NewContext(rowsFromQuery RowsFromQuery, rowsFromShortcut RowsFromShortcut)
 		Funcs: map[string]Func{
 			"filter":       filterFunc,
 			"shortcut":     shortcutFunc,
+			"metric":       metricFunc,
 			"norm":         normFunc,
 			"fill":         fillFunc,
 			"ave":          aveFunc,
@@ -123,6 +130,12 @@ func (ctx *Context) Eval(exp string) (types.TraceSet, error) {
 	return n.Eval(ctx)
 }
 
+// Parse parses the given string expression and returns the root of the parse
+// tree, or an error if the expression isn't valid.
+func Parse(exp string) (*Node, error) {
+	return parse(exp)
+}
+
 // parse starts the parsing.
 func parse(input string) (*Node, error) {
 	l := newLexer(input)
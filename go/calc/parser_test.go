@@ -98,6 +98,42 @@ func TestShortcut(t *testing.T) {
 	}
 }
 
+func TestMetric_NamedFormula_EvaluatesFormula(t *testing.T) {
+	ctx := newTestContext(nil, nil)
+	ctx.Metrics = map[string]string{
+		"gpu":       `filter("config=gpu")`,
+		"gpu_count": `count(metric("gpu"))`,
+	}
+
+	rows, err := ctx.Eval(`metric("gpu")`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.TraceSet{",config=gpu,os=Ubuntu12,": testRows[",config=gpu,os=Ubuntu12,"]}, rows)
+
+	// Metrics can refer to other metrics.
+	rows, err = ctx.Eval(`metric("gpu_count")`)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
+
+func TestMetric_Errors(t *testing.T) {
+	ctx := newTestContext(nil, nil)
+	_, err := ctx.Eval(`metric("gpu")`)
+	assert.Contains(t, err.Error(), "not available")
+
+	ctx.Metrics = map[string]string{
+		"loop":    `metric("loop")`,
+		"invalid": `filter(`,
+	}
+	_, err = ctx.Eval(`metric("unknown")`)
+	assert.Contains(t, err.Error(), "unknown derived metric")
+	_, err = ctx.Eval(`metric("loop")`)
+	assert.Contains(t, err.Error(), "nested too deeply")
+	_, err = ctx.Eval(`metric("invalid")`)
+	assert.Contains(t, err.Error(), "failed to parse")
+	_, err = ctx.Eval(`metric(1)`)
+	assert.Error(t, err)
+}
+
 func TestEvalNoModifyTile(t *testing.T) {
 	ctx := newTestContext(nil, nil)
 
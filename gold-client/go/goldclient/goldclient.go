@@ -360,10 +360,11 @@ func (c *CloudClient) addTest(ctx context.Context, name types.TestName, imgFileN
 			// If the image is untriaged, but matches the latest positive digest in its baseline via the
 			// specified non-exact image matching algorithm, then triage the image as positive.
 			if match && algorithmName != imgmatching.ExactMatching {
-				infof(ctx, "Triaging digest %q for test %q as positive (algorithm name: %q)\n", imgDigest, name, algorithmName)
-				err = c.TriageAsPositive(ctx, name, imgDigest, string(algorithmName))
+				author := triageAuthor(algorithmName, optionalKeys)
+				infof(ctx, "Triaging digest %q for test %q as positive (algorithm name: %q)\n", imgDigest, name, author)
+				err = c.TriageAsPositive(ctx, name, imgDigest, author)
 				if err != nil {
-					return skerr.Wrapf(err, "triaging image as positive, image hash %q, test name %q, algorithm name %q", imgDigest, name, algorithmName)
+					return skerr.Wrapf(err, "triaging image as positive, image hash %q, test name %q, algorithm name %q", imgDigest, name, author)
 				}
 			}
 
@@ -555,6 +556,16 @@ func (c *CloudClient) matchImageAgainstBaseline(ctx context.Context, testName ty
 	return matcher.Match(mostRecentPositiveImage, img), algorithmName, nil
 }
 
+// triageAuthor returns the author recorded in the triage log when an image is triaged as positive
+// by the given non-exact image matching algorithm. For TransformMatching, the rules are included
+// so it's clear from the triage log which transforms made the images match.
+func triageAuthor(algorithmName imgmatching.AlgorithmName, optionalKeys map[string]string) string {
+	if algorithmName != imgmatching.TransformMatching {
+		return string(algorithmName)
+	}
+	return fmt.Sprintf("%s:%s", algorithmName, strings.ReplaceAll(optionalKeys[string(imgmatching.TransformRules)], " ", ""))
+}
+
 // Finalize implements the GoldClient interface.
 func (c *CloudClient) Finalize(ctx context.Context) error {
 	return c.uploadResultJSON(ctx)
@@ -2783,3 +2783,18 @@ func openNRGBAFromFile(fileName string) (*image.NRGBA, error) {
 	}
 	return img, nil
 }
+
+func TestTriageAuthor_TransformMatching_IncludesRules(t *testing.T) {
+	author := triageAuthor(imgmatching.TransformMatching, map[string]string{
+		imgmatching.AlgorithmNameOptKey:    string(imgmatching.TransformMatching),
+		string(imgmatching.TransformRules): "crop_transparent_border, integer_scale",
+	})
+	assert.Equal(t, "transform:crop_transparent_border,integer_scale", author)
+}
+
+func TestTriageAuthor_OtherAlgorithms_JustAlgorithmName(t *testing.T) {
+	author := triageAuthor(imgmatching.FuzzyMatching, map[string]string{
+		imgmatching.AlgorithmNameOptKey: string(imgmatching.FuzzyMatching),
+	})
+	assert.Equal(t, "fuzzy", author)
+}
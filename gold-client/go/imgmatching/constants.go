@@ -13,6 +13,7 @@ const (
 	PositiveIfOnlyImageMatching = AlgorithmName("positive_if_only_image")
 	SampleAreaMatching          = AlgorithmName("sample_area")
 	SobelFuzzyMatching          = AlgorithmName("sobel")
+	TransformMatching           = AlgorithmName("transform")
 )
 
 // AlgorithmParamOptKey is an optional key indicating a parameter for the specified non-exact image
@@ -51,4 +52,8 @@ const (
 	// SampleAreaChannelDeltaThreshold is the optional key used to specify the
 	// SampleAreaChannelDeltaThreshold parameter of the SampleAreaMatching algorithm.
 	SampleAreaChannelDeltaThreshold = AlgorithmParamOptKey("sample_area_channel_delta_threshold")
+
+	// TransformRules is the optional key used to specify the comma-separated list of rules, e.g.
+	// "crop_transparent_border,integer_scale", of the TransformMatching algorithm.
+	TransformRules = AlgorithmParamOptKey("transform_rules")
 )
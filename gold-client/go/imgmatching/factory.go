@@ -12,6 +12,7 @@ import (
 	"go.goldmine.build/gold-client/go/imgmatching/positive_if_only_image"
 	"go.goldmine.build/gold-client/go/imgmatching/sample_area"
 	"go.goldmine.build/gold-client/go/imgmatching/sobel"
+	"go.goldmine.build/gold-client/go/imgmatching/transform"
 )
 
 // MakeMatcher takes a map of optional keys and returns the specified image matching algorithm
@@ -56,6 +57,13 @@ func MakeMatcher(optionalKeys map[string]string) (AlgorithmName, Matcher, error)
 		}
 		return SobelFuzzyMatching, matcher, nil
 
+	case TransformMatching:
+		matcher, err := makeTransformMatcher(optionalKeys)
+		if err != nil {
+			return "", nil, skerr.Wrap(err)
+		}
+		return TransformMatching, matcher, nil
+
 	default:
 		return "", nil, skerr.Fmt("unrecognized image matching algorithm: %q", algorithmName)
 	}
@@ -163,6 +171,31 @@ func makeSobelFuzzyMatcher(optionalKeys map[string]string) (*sobel.Matcher, erro
 	}, nil
 }
 
+// makeTransformMatcher returns a transform.Matcher instance with the rules in the given optional
+// keys map enabled.
+func makeTransformMatcher(optionalKeys map[string]string) (*transform.Matcher, error) {
+	stringVal, ok := optionalKeys[string(TransformRules)]
+	if !ok {
+		return nil, skerr.Fmt("required image matching parameter not found: %q", TransformRules)
+	}
+	if strings.TrimSpace(stringVal) == "" {
+		return nil, skerr.Fmt("image matching parameter %q cannot be empty", TransformRules)
+	}
+
+	matcher := &transform.Matcher{}
+	for _, rule := range strings.Split(stringVal, ",") {
+		switch transform.Rule(strings.TrimSpace(rule)) {
+		case transform.CropTransparentBorder:
+			matcher.CropTransparentBorder = true
+		case transform.IntegerScale:
+			matcher.IntegerScale = true
+		default:
+			return nil, skerr.Fmt("image matching parameter %q contains unknown rule %q, must be one of %v", TransformRules, rule, transform.AllRules)
+		}
+	}
+	return matcher, nil
+}
+
 // getAndValidateIntParameter extracts and validates the given required integer parameter from the
 // given map of optional keys.
 //
@@ -11,6 +11,7 @@ import (
 	"go.goldmine.build/gold-client/go/imgmatching/positive_if_only_image"
 	"go.goldmine.build/gold-client/go/imgmatching/sample_area"
 	"go.goldmine.build/gold-client/go/imgmatching/sobel"
+	"go.goldmine.build/gold-client/go/imgmatching/transform"
 )
 
 func TestMakeMatcher_UnknownAlgorithm_ReturnsError(t *testing.T) {
@@ -981,3 +982,80 @@ func TestMakeMatcher_SampleAreaMatching_Success(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeMatcher_TransformMatching_Success(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  transform.Matcher
+	}{
+		{
+			name:  "crop transparent border",
+			rules: "crop_transparent_border",
+			want:  transform.Matcher{CropTransparentBorder: true},
+		},
+		{
+			name:  "integer scale",
+			rules: "integer_scale",
+			want:  transform.Matcher{IntegerScale: true},
+		},
+		{
+			name:  "all rules, with whitespace",
+			rules: "crop_transparent_border, integer_scale",
+			want:  transform.Matcher{CropTransparentBorder: true, IntegerScale: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			algorithmName, matcher, err := MakeMatcher(map[string]string{
+				AlgorithmNameOptKey:    string(TransformMatching),
+				string(TransformRules): tc.rules,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, TransformMatching, algorithmName)
+			assert.Equal(t, &tc.want, matcher)
+		})
+	}
+}
+
+func TestMakeMatcher_TransformMatching_InvalidRules_ReturnsError(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		error string
+	}{
+		{
+			name:  "missing",
+			rules: missing,
+			error: `required image matching parameter not found: "transform_rules"`,
+		},
+		{
+			name:  "empty",
+			rules: " ",
+			error: `image matching parameter "transform_rules" cannot be empty`,
+		},
+		{
+			name:  "unknown rule",
+			rules: "crop_transparent_border,rotate",
+			error: `image matching parameter "transform_rules" contains unknown rule "rotate"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			optionalKeys := map[string]string{
+				AlgorithmNameOptKey: string(TransformMatching),
+			}
+			if tc.rules != missing {
+				optionalKeys[string(TransformRules)] = tc.rules
+			}
+
+			_, _, err := MakeMatcher(optionalKeys)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.error)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"go.goldmine.build/gold-client/go/imgmatching/positive_if_only_image"
 	"go.goldmine.build/gold-client/go/imgmatching/sample_area"
 	"go.goldmine.build/gold-client/go/imgmatching/sobel"
+	"go.goldmine.build/gold-client/go/imgmatching/transform"
 )
 
 // Matcher represents a generic image matching algorithm.
@@ -23,3 +24,4 @@ var _ Matcher = (*fuzzy.Matcher)(nil)
 var _ Matcher = (*positive_if_only_image.Matcher)(nil)
 var _ Matcher = (*sample_area.Matcher)(nil)
 var _ Matcher = (*sobel.Matcher)(nil)
+var _ Matcher = (*transform.Matcher)(nil)
@@ -0,0 +1,113 @@
+package transform
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Rule is a transform that may be applied to the images before they are compared.
+type Rule string
+
+const (
+	// CropTransparentBorder crops both images to the smallest rectangle that contains all of
+	// their non-transparent pixels.
+	CropTransparentBorder = Rule("crop_transparent_border")
+
+	// IntegerScale allows one image to be a nearest-neighbor upscale of the other by an integer
+	// factor, which may be different for each axis.
+	IntegerScale = Rule("integer_scale")
+)
+
+// AllRules are all the supported rules, in the order they are applied.
+var AllRules = []Rule{CropTransparentBorder, IntegerScale}
+
+// Matcher is an image matching algorithm.
+//
+// It considers the two images to be equal if they are pixel-identical after applying the enabled
+// rules, e.g. once their transparent borders have been cropped. It is meant for corpora that
+// re-render identical content at new sizes, which would otherwise produce new digests that need
+// to be triaged by hand.
+type Matcher struct {
+	CropTransparentBorder bool
+	IntegerScale          bool
+}
+
+// Match implements the imgmatching.Matcher interface.
+func (m *Matcher) Match(expected, actual image.Image) bool {
+	// There is no positive image to compare against.
+	if expected == nil || actual == nil {
+		return false
+	}
+
+	expectedNRGBA := toNRGBA(expected)
+	actualNRGBA := toNRGBA(actual)
+	expectedBounds := expectedNRGBA.Bounds()
+	actualBounds := actualNRGBA.Bounds()
+	if m.CropTransparentBorder {
+		expectedBounds = opaqueBounds(expectedNRGBA)
+		actualBounds = opaqueBounds(actualNRGBA)
+	}
+
+	if expectedBounds.Size() == actualBounds.Size() {
+		return equal(expectedNRGBA, expectedBounds, actualNRGBA, actualBounds)
+	}
+	if !m.IntegerScale {
+		return false
+	}
+	// Compare the larger image against the upscaled smaller one.
+	if expectedBounds.Dx() > actualBounds.Dx() || expectedBounds.Dy() > actualBounds.Dy() {
+		expectedNRGBA, actualNRGBA = actualNRGBA, expectedNRGBA
+		expectedBounds, actualBounds = actualBounds, expectedBounds
+	}
+	if expectedBounds.Empty() || actualBounds.Dx()%expectedBounds.Dx() != 0 || actualBounds.Dy()%expectedBounds.Dy() != 0 {
+		return false
+	}
+	return equal(expectedNRGBA, expectedBounds, actualNRGBA, actualBounds)
+}
+
+// toNRGBA returns img as an *image.NRGBA, converting it if needed.
+func toNRGBA(img image.Image) *image.NRGBA {
+	if ret, ok := img.(*image.NRGBA); ok {
+		return ret
+	}
+	bounds := img.Bounds()
+	ret := image.NewNRGBA(bounds)
+	draw.Draw(ret, bounds, img, bounds.Min, draw.Src)
+	return ret
+}
+
+// opaqueBounds returns the smallest rectangle that contains all the pixels of img that are not
+// fully transparent. An empty rectangle is returned if all the pixels are transparent.
+func opaqueBounds(img *image.NRGBA) image.Rectangle {
+	ret := image.Rectangle{}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.NRGBAAt(x, y).A != 0 {
+				ret = ret.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return ret
+}
+
+// equal returns true if the pixels of large within largeBounds are identical to the pixels of
+// small within smallBounds, scaled up to the size of largeBounds. The size of largeBounds must be
+// a multiple of the size of smallBounds.
+func equal(small *image.NRGBA, smallBounds image.Rectangle, large *image.NRGBA, largeBounds image.Rectangle) bool {
+	if largeBounds.Empty() {
+		return smallBounds.Empty()
+	}
+	scaleX := largeBounds.Dx() / smallBounds.Dx()
+	scaleY := largeBounds.Dy() / smallBounds.Dy()
+	for y := 0; y < largeBounds.Dy(); y++ {
+		for x := 0; x < largeBounds.Dx(); x++ {
+			p1 := small.NRGBAAt(smallBounds.Min.X+x/scaleX, smallBounds.Min.Y+y/scaleY)
+			p2 := large.NRGBAAt(largeBounds.Min.X+x, largeBounds.Min.Y+y)
+			if p1 != p2 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.goldmine.build/golden/go/image/text"
+)
+
+func TestMatcher_NoExistingPositiveImage_ReturnsFalse(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true, IntegerScale: true}
+	assert.False(t, matcher.Match(nil, text.MustToNRGBA(image2x2)))
+}
+
+func TestMatcher_IdenticalImages_ReturnsTrue(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true, IntegerScale: true}
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2), text.MustToNRGBA(image2x2)))
+}
+
+func TestMatcher_TransparentBorder_CropEnabled_ReturnsTrue(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true}
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2), text.MustToNRGBA(image2x2WithTransparentBorder)))
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2WithTransparentBorder), text.MustToNRGBA(image2x2)))
+}
+
+func TestMatcher_TransparentBorder_CropDisabled_ReturnsFalse(t *testing.T) {
+	matcher := Matcher{IntegerScale: true}
+	assert.False(t, matcher.Match(text.MustToNRGBA(image2x2), text.MustToNRGBA(image2x2WithTransparentBorder)))
+}
+
+func TestMatcher_DifferentContentInsideTransparentBorder_ReturnsFalse(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true, IntegerScale: true}
+	assert.False(t, matcher.Match(text.MustToNRGBA(image2x2Different), text.MustToNRGBA(image2x2WithTransparentBorder)))
+}
+
+func TestMatcher_IntegerUpscale_ScaleEnabled_ReturnsTrue(t *testing.T) {
+	matcher := Matcher{IntegerScale: true}
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2), text.MustToNRGBA(image2x2ScaledTo4x2)))
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2ScaledTo4x2), text.MustToNRGBA(image2x2)))
+}
+
+func TestMatcher_IntegerUpscale_ScaleDisabled_ReturnsFalse(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true}
+	assert.False(t, matcher.Match(text.MustToNRGBA(image2x2), text.MustToNRGBA(image2x2ScaledTo4x2)))
+}
+
+func TestMatcher_NonIntegerScale_ReturnsFalse(t *testing.T) {
+	matcher := Matcher{IntegerScale: true}
+	assert.False(t, matcher.Match(text.MustToNRGBA(image2x2), text.MustToNRGBA(image3x2)))
+}
+
+func TestMatcher_CropAndScale_ReturnsTrue(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true, IntegerScale: true}
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2WithTransparentBorder), text.MustToNRGBA(image2x2ScaledTo4x2)))
+}
+
+func TestMatcher_FullyTransparentImages_ReturnsTrue(t *testing.T) {
+	matcher := Matcher{CropTransparentBorder: true, IntegerScale: true}
+	assert.True(t, matcher.Match(text.MustToNRGBA(image2x2Transparent), text.MustToNRGBA(image3x3Transparent)))
+	assert.False(t, matcher.Match(text.MustToNRGBA(image2x2Transparent), text.MustToNRGBA(image2x2)))
+}
+
+const image2x2 = `! SKTEXTSIMPLE
+2 2
+0xff0000ff 0x00ff00ff
+0x0000ffff 0xffffffff`
+
+const image2x2Different = `! SKTEXTSIMPLE
+2 2
+0xff0000ff 0x00ff00ff
+0x0000ffff 0x000000ff`
+
+const image2x2WithTransparentBorder = `! SKTEXTSIMPLE
+4 4
+0x00000000 0x00000000 0x00000000 0x00000000
+0x00000000 0xff0000ff 0x00ff00ff 0x00000000
+0x00000000 0x0000ffff 0xffffffff 0x00000000
+0x00000000 0x00000000 0x00000000 0x00000000`
+
+const image2x2ScaledTo4x2 = `! SKTEXTSIMPLE
+4 2
+0xff0000ff 0xff0000ff 0x00ff00ff 0x00ff00ff
+0x0000ffff 0x0000ffff 0xffffffff 0xffffffff`
+
+const image3x2 = `! SKTEXTSIMPLE
+3 2
+0xff0000ff 0xff0000ff 0x00ff00ff
+0x0000ffff 0x0000ffff 0xffffffff`
+
+const image2x2Transparent = `! SKTEXTSIMPLE
+2 2
+0x00000000 0x00000000
+0x00000000 0x00000000`
+
+const image3x3Transparent = `! SKTEXTSIMPLE
+3 3
+0x00000000 0x00000000 0x00000000
+0x00000000 0x00000000 0x00000000
+0x00000000 0x00000000 0x00000000`
@@ -143,8 +143,10 @@ func main() {
 	// Serve the known hashes from GCS.
 	v0("GET", frontend.KnownHashesRoute, handlers.KnownHashesHandler)
 	v1("GET", frontend.KnownHashesRouteV1, handlers.KnownHashesHandler)
-	// Serve the expectations for the primary branch and for CLs in progress.
+	// Serve the expectations for the primary branch (now or as of a past commit) and for CLs in
+	// progress.
 	v2("GET", frontend.ExpectationsRouteV2, handlers.BaselineHandlerV2)
+	v1("GET", frontend.ExpectationsAtCommitRouteV1, handlers.BaselineAtCommitHandler)
 	v1("GET", frontend.GroupingsRouteV1, handlers.GroupingsHandler)
 
 	// Only log and compress the app routes, but not the health check.
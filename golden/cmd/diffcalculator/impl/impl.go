@@ -58,7 +58,7 @@ func DiffCalculatorMain(ctx context.Context, cfg config.Common, flags config.Ser
 	}
 
 	sqlProcessor := &processor{
-		calculator:         worker.New(db, gis, cfg.WindowSize),
+		calculator:         worker.New(db, gis, cfg.WindowSize, cfg.ComputePerceptualDiffMetrics),
 		db:                 db,
 		groupingCache:      gc,
 		primaryCounter:     metrics2.GetCounter("diffcalculator_primarybranch_processed"),
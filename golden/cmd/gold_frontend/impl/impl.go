@@ -27,11 +27,14 @@ import (
 	"go.goldmine.build/go/gerrit"
 	"go.goldmine.build/go/httputils"
 	"go.goldmine.build/go/metrics2"
+	"go.goldmine.build/go/pubsub"
 	"go.goldmine.build/go/sklog"
 	"go.goldmine.build/golden/go/clstore"
 	"go.goldmine.build/golden/go/code_review"
 	"go.goldmine.build/golden/go/code_review/gerrit_crs"
 	"go.goldmine.build/golden/go/code_review/github_crs"
+	"go.goldmine.build/golden/go/comment"
+	"go.goldmine.build/golden/go/comment/sqlcommentstore"
 	"go.goldmine.build/golden/go/config"
 	"go.goldmine.build/golden/go/db"
 	"go.goldmine.build/golden/go/ignore"
@@ -39,11 +42,18 @@ import (
 	"go.goldmine.build/golden/go/publicparams"
 	"go.goldmine.build/golden/go/search"
 	"go.goldmine.build/golden/go/storage"
+	"go.goldmine.build/golden/go/triageevents"
 	"go.goldmine.build/golden/go/web"
 	"go.goldmine.build/golden/go/web/frontend"
+	"go.goldmine.build/golden/go/web/imageurl"
 )
 
 func FrontendMain(ctx context.Context, cfg config.Common, flags config.ServerFlags) {
+	if cfg.FrontendServerConfig.IsReadOnlyMirror {
+		// A read-only mirror only serves the public view of the data.
+		cfg.FrontendServerConfig.IsPublicView = true
+	}
+
 	client := mustMakeAuthenticatedHTTPClient(cfg.Local)
 
 	sqlDB := db.MustInitSQLDatabase(ctx, cfg, flags.LogSQLQueries)
@@ -84,11 +94,19 @@ func mustLoadSearchAPI(ctx context.Context, cfg config.Common, sqlDB *pgxpool.Po
 	s2a := search.New(sqlDB, cfg.WindowSize)
 	s2a.SetReviewSystemTemplates(templates)
 	sklog.Infof("SQL Search loaded with CRS templates %s", templates)
+	s2a.SetPerceptualDiffMetrics(cfg.ComputePerceptualDiffMetrics)
+	if err := s2a.SetDiffMetrics(cfg.FrontendServerConfig.DiffMetricsByCorpus); err != nil {
+		sklog.Fatalf("Invalid diff metrics: %s", err)
+	}
+	s2a.SetVariantKey(cfg.FrontendServerConfig.VariantKey)
 	err := s2a.StartCacheProcess(ctx, 5*time.Minute, cfg.WindowSize)
 	if err != nil {
 		sklog.Fatalf("Cannot load caches for search2 backend: %s", err)
 	}
-	if err := s2a.StartMaterializedViews(ctx, cfg.FrontendServerConfig.MaterializedViewCorpora, 5*time.Minute); err != nil {
+	if cfg.FrontendServerConfig.IsReadReplica {
+		// The main frontend creates and refreshes the views.
+		s2a.UseMaterializedViews(cfg.FrontendServerConfig.MaterializedViewCorpora)
+	} else if err := s2a.StartMaterializedViews(ctx, cfg.FrontendServerConfig.MaterializedViewCorpora, 5*time.Minute); err != nil {
 		sklog.Fatalf("Cannot create materialized views %s: %s", cfg.FrontendServerConfig.MaterializedViewCorpora, err)
 	}
 	if cfg.FrontendServerConfig.IsPublicView {
@@ -209,15 +227,30 @@ func mustInitializeReviewSystems(cfg config.Common, hc *http.Client) []clstore.R
 
 // mustMakeWebHandlers returns a new web.Handlers.
 func mustMakeWebHandlers(ctx context.Context, cfg config.Common, db *pgxpool.Pool, gsClient storage.GCSClient, ignoreStore ignore.Store, reviewSystems []clstore.ReviewSystem, s2a search.API, alogin alogin.Login) *web.Handlers {
-	handlers, err := web.NewHandlers(web.HandlersConfig{
+	hc := web.HandlersConfig{
 		DB:                        db,
 		GCSClient:                 gsClient,
 		IgnoreStore:               ignoreStore,
+		CommentStore:              makeCommentStore(cfg, db),
 		ReviewSystems:             reviewSystems,
 		Search2API:                s2a,
 		WindowSize:                cfg.WindowSize,
 		GroupingParamKeysByCorpus: cfg.GroupingParamKeysByCorpus,
-	}, web.FullFrontEnd, alogin)
+		DiffBudget:                cfg.FrontendServerConfig.DiffBudget,
+		TriageEvents:              mustMakeTriageEventPublisher(ctx, cfg),
+		ImageURLSigner:            mustMakeImageURLSigner(cfg),
+		LegacyRPCDeprecation:      mustParseLegacyRPCDate("legacy_rpc_deprecation", cfg.FrontendServerConfig.LegacyRPCDeprecation),
+		LegacyRPCSunset:           mustParseLegacyRPCDate("legacy_rpc_sunset", cfg.FrontendServerConfig.LegacyRPCSunset),
+		ImageGC:                   cfg.PeriodicTasksConfig.ImageGC,
+	}
+	if cfg.FrontendServerConfig.AllowHTTPIngestion && !cfg.FrontendServerConfig.IsReadOnly() {
+		hc.PrimaryBranchResults = &cfg.IngestionServerConfig.PrimaryBranchConfig.Source
+		if sb := cfg.IngestionServerConfig.SecondaryBranchConfig; sb != nil {
+			hc.SecondaryBranchResults = &sb.Source
+		}
+		sklog.Infof("Accepting uploaded results for gs://%s/%s", hc.PrimaryBranchResults.Bucket, hc.PrimaryBranchResults.Prefix)
+	}
+	handlers, err := web.NewHandlers(hc, web.FullFrontEnd, alogin)
 	if err != nil {
 		sklog.Fatalf("Failed to initialize web handlers: %s", err)
 	}
@@ -225,6 +258,77 @@ func mustMakeWebHandlers(ctx context.Context, cfg config.Common, db *pgxpool.Poo
 	return handlers
 }
 
+// imageURLSignatureTTL is the minimum time the image URLs signed when a page is loaded stay valid.
+const imageURLSignatureTTL = 12 * time.Hour
+
+// mustMakeImageURLSigner returns an imageurl.Signer with the configured key, or nil if image URLs
+// should not be signed.
+func mustMakeImageURLSigner(cfg config.Common) *imageurl.Signer {
+	keyPath := cfg.FrontendServerConfig.ImageURLSigningKeyPath
+	if keyPath == "" {
+		return nil
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		sklog.Fatalf("Could not read image URL signing key from %s: %s", keyPath, err)
+	}
+	signer, err := imageurl.NewSigner(key, imageURLSignatureTTL)
+	if err != nil {
+		sklog.Fatalf("Invalid image URL signing key in %s: %s", keyPath, err)
+	}
+	return signer
+}
+
+// mustParseLegacyRPCDate returns the given configured date about deprecated JSON RPCs, or the zero
+// time if none is configured.
+func mustParseLegacyRPCDate(name, date string) time.Time {
+	if date == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		sklog.Fatalf("Invalid %s %q, want YYYY-MM-DD: %s", name, date, err)
+	}
+	return t
+}
+
+// makeCommentStore returns the comment.Store for the instance, or nil if comments are disabled.
+// Public views don't show comments, which might mention data that isn't public.
+func makeCommentStore(cfg config.Common, db *pgxpool.Pool) comment.Store {
+	if cfg.FrontendServerConfig.IsPublicView {
+		return nil
+	}
+	return sqlcommentstore.New(db)
+}
+
+// mustMakeTriageEventPublisher returns a triageevents.Publisher for the destinations in the
+// TriageEvents config, or nil if none are configured.
+func mustMakeTriageEventPublisher(ctx context.Context, cfg config.Common) triageevents.Publisher {
+	tCfg := cfg.FrontendServerConfig.TriageEvents
+	if tCfg == nil || cfg.FrontendServerConfig.IsReadOnly() {
+		// Read-only frontends never change expectations.
+		return nil
+	}
+	var publishers triageevents.MultiPublisher
+	if tCfg.PubSubTopic != "" {
+		psc, err := pubsub.NewClient(ctx, cfg.PubsubProjectID)
+		if err != nil {
+			sklog.Fatalf("Could not create PubSub client for project %s: %s", cfg.PubsubProjectID, err)
+		}
+		publishers = append(publishers, triageevents.NewPubSubPublisher(psc.Topic(tCfg.PubSubTopic)))
+		sklog.Infof("Publishing triage events to topic %s", tCfg.PubSubTopic)
+	}
+	if tCfg.WebhookURL != "" {
+		c := httputils.DefaultClientConfig().WithoutRetries().Client()
+		publishers = append(publishers, triageevents.NewWebhookPublisher(c, tCfg.WebhookURL))
+		sklog.Infof("Publishing triage events to %s", tCfg.WebhookURL)
+	}
+	if len(publishers) == 0 {
+		sklog.Fatal("triage_events must specify pubsub_topic and/or webhook_url")
+	}
+	return publishers
+}
+
 // mustMakeRootRouter returns a chi.Router that can be used to serve Gold's web UI and JSON API.
 func mustMakeRootRouter(cfg config.Common, handlers *web.Handlers, plogin alogin.Login) chi.Router {
 	rootRouter := chi.NewRouter()
@@ -279,6 +383,11 @@ func addUIRoutes(router chi.Router, cfg config.Common, handlers *web.Handlers, p
 	loadTemplates()
 
 	cfg.FrontendServerConfig.FrontendConfig.IsPublic = cfg.FrontendServerConfig.IsPublicView
+	cfg.FrontendServerConfig.FrontendConfig.IsReadOnlyMirror = cfg.FrontendServerConfig.IsReadOnly()
+	cfg.FrontendServerConfig.FrontendConfig.VariantKey = cfg.FrontendServerConfig.VariantKey
+	if handlers.ImageURLSigner != nil {
+		cfg.FrontendServerConfig.FrontendConfig.ImmutableImageURLs = true
+	}
 
 	frontendConfigBytes, err := json.Marshal(cfg.FrontendServerConfig.FrontendConfig)
 	if err != nil {
@@ -298,12 +407,26 @@ func addUIRoutes(router chi.Router, cfg config.Common, handlers *web.Handlers, p
 				loadTemplates()
 			}
 
+			settings := frontendConfigBytes
+			if handlers.ImageURLSigner != nil {
+				// Page loads share a signature until it is rounded to the next expiration, so the
+				// image URLs stay the same and can be served from caches.
+				frontendConfig := cfg.FrontendServerConfig.FrontendConfig
+				frontendConfig.ImageURLParams = handlers.ImageURLSigner.Params(time.Now()).Encode()
+				b, err := json.Marshal(frontendConfig)
+				if err != nil {
+					httputils.ReportError(w, err, "Failed to marshal frontend config", http.StatusInternalServerError)
+					return
+				}
+				settings = b
+			}
+
 			templateData := struct {
 				Title        string
 				GoldSettings template.JS
 			}{
 				Title:        cfg.FrontendServerConfig.FrontendConfig.Title,
-				GoldSettings: template.JS(frontendConfigBytes),
+				GoldSettings: template.JS(settings),
 			}
 			if err := templates.ExecuteTemplate(w, name, templateData); err != nil {
 				sklog.Errorf("Failed to expand template %s : %s", name, err)
@@ -347,7 +470,15 @@ func addAuthenticatedJSONRoutes(router chi.Router, cfg config.Common, handlers *
 			}
 			handlerToProtect(w, r)
 		}
-		addJSONRoute(method, jsonRoute, wrappedHandler, jsonRouter, pathPrefix)
+		addVersionedJSONRoute(method, jsonRoute, wrappedHandler, jsonRouter, pathPrefix, handlers)
+	}
+	// addMutating adds routes which modify data. They are left out entirely on read-only mirrors
+	// and read replicas, so requests to them get a 404 like any other unknown route.
+	addMutating := func(jsonRoute string, handlerToProtect http.HandlerFunc, method string) {
+		if cfg.FrontendServerConfig.IsReadOnly() {
+			return
+		}
+		add(jsonRoute, handlerToProtect, method)
 	}
 
 	add("/json/v2/byblame", handlers.ByBlameHandler, "GET")
@@ -356,31 +487,47 @@ func addAuthenticatedJSONRoutes(router chi.Router, cfg config.Common, handlers *
 	add("/json/v2/commits", handlers.CommitsHandler, "GET")
 	add("/json/v1/positivedigestsbygrouping/{groupingID}", handlers.PositiveDigestsByGroupingIDHandler, "GET")
 	add("/json/v2/details", handlers.DetailsHandler, "POST")
+	add("/json/v1/details/bulk", handlers.DetailsBulkHandler, "POST")
 	add("/json/v2/diff", handlers.DiffHandler, "POST")
 	add("/json/v2/digests", handlers.DigestListHandler, "GET")
+	add("/json/v1/flaky", handlers.FlakyTestsHandler, "GET")
+	addMutating("/json/ingest", handlers.IngestHandler, "POST")
 	add("/json/v2/latestpositivedigest/{traceID}", handlers.LatestPositiveDigestHandler, "GET")
 	add("/json/v2/list", handlers.ListTestsHandler, "GET")
 	add("/json/v2/paramset", handlers.ParamsHandler, "GET")
 	add("/json/v2/search", handlers.SearchHandler, "GET")
-	add("/json/v2/triage", handlers.TriageHandlerV2, "POST") // TODO(lovisolo): Delete when unused.
-	add("/json/v3/triage", handlers.TriageHandlerV3, "POST")
+	add("/json/v1/search/facets", handlers.SearchFacetsHandler, "GET")
+	addMutating("/json/v2/triage", handlers.TriageHandlerV2, "POST") // TODO(lovisolo): Delete when unused.
+	addMutating("/json/v3/triage", handlers.TriageHandlerV3, "POST")
 	add("/json/v2/triagelog", handlers.TriageLogHandler, "GET")
-	add("/json/v2/triagelog/undo", handlers.TriageUndoHandler, "POST")
+	addMutating("/json/v2/triagelog/undo", handlers.TriageUndoHandler, "POST")
 	add("/json/whoami", handlers.Whoami, "GET")
 	add("/json/v1/whoami", handlers.Whoami, "GET")
 	// TODO(lovisolo): Delete once all links to details page include grouping information.
 	add("/json/v1/groupingfortest", handlers.GroupingForTestHandler, "POST")
 
 	// Only expose these endpoints if this instance is not a public view. The reason we want to hide
-	// ignore rules is so that we don't leak params that might be in them.
+	// ignore rules is so that we don't leak params that might be in them. Likewise, exported
+	// expectations, compared changelists, similar digests and comments include data of corpora which
+	// are not publicly visible.
 	if !cfg.FrontendServerConfig.IsPublicView {
+		add("/json/v1/comments", handlers.CommentsHandler, "GET")
+		addMutating("/json/v1/comments/add", handlers.AddCommentHandler, "POST")
+		addMutating("/json/v1/comments/del/{id}", handlers.DeleteCommentHandler, "POST")
+		add("/json/v1/changelists/compare", handlers.CompareChangelistsHandler, "GET")
+		add("/json/v1/similar", handlers.SimilarDigestsHandler, "GET")
+		add("/json/v1/tests/priority", handlers.TestPriorityHandler, "GET")
+		add("/json/v1/expectations/export", handlers.ExpectationsExportHandler, "GET")
+		add("/json/v1/imagegc/report", handlers.ImageGCReportHandler, "GET")
+		addMutating("/json/v1/expectations/import", handlers.ExpectationsImportHandler, "POST")
 		add("/json/v2/ignores", handlers.ListIgnoreRules2, "GET")
-		add("/json/ignores/add/", handlers.AddIgnoreRule, "POST")
-		add("/json/v1/ignores/add/", handlers.AddIgnoreRule, "POST")
-		add("/json/ignores/del/{id}", handlers.DeleteIgnoreRule, "POST")
-		add("/json/v1/ignores/del/{id}", handlers.DeleteIgnoreRule, "POST")
-		add("/json/ignores/save/{id}", handlers.UpdateIgnoreRule, "POST")
-		add("/json/v1/ignores/save/{id}", handlers.UpdateIgnoreRule, "POST")
+		add("/json/v1/ignores/stats", handlers.IgnoreStatsHandler, "GET")
+		addMutating("/json/ignores/add/", handlers.AddIgnoreRule, "POST")
+		addMutating("/json/v1/ignores/add/", handlers.AddIgnoreRule, "POST")
+		addMutating("/json/ignores/del/{id}", handlers.DeleteIgnoreRule, "POST")
+		addMutating("/json/v1/ignores/del/{id}", handlers.DeleteIgnoreRule, "POST")
+		addMutating("/json/ignores/save/{id}", handlers.UpdateIgnoreRule, "POST")
+		addMutating("/json/v1/ignores/save/{id}", handlers.UpdateIgnoreRule, "POST")
 	}
 
 	// Make sure we return a 404 for anything that starts with /json and could not be found.
@@ -392,12 +539,13 @@ func addAuthenticatedJSONRoutes(router chi.Router, cfg config.Common, handlers *
 // that do not require authentication.
 func addUnauthenticatedJSONRoutes(router chi.Router, _ config.Common, handlers *web.Handlers) {
 	add := func(jsonRoute string, handlerFunc http.HandlerFunc) {
-		addJSONRoute("GET", jsonRoute, httputils.CorsHandler(handlerFunc), router, "")
+		addVersionedJSONRoute("GET", jsonRoute, httputils.CorsHandler(handlerFunc), router, "", handlers)
 	}
 
 	add("/json/v2/trstatus", handlers.StatusHandler)
 	add("/json/v2/changelist/{system}/{id}", handlers.PatchsetsAndTryjobsForCL2)
 	add("/json/v1/changelist_summary/{system}/{id}", handlers.ChangelistSummaryHandler)
+	add("/json/v1/diff_budget/{system}/{id}", handlers.DiffBudgetCheckHandler)
 
 	// Routes shared with the baseline server. These usually don't see traffic because the envoy
 	// routing directs these requests to the baseline servers, if there are some.
@@ -406,7 +554,9 @@ func addUnauthenticatedJSONRoutes(router chi.Router, _ config.Common, handlers *
 	// Retrieving a baseline for the primary branch and a Gerrit issue are handled the same way.
 	// These routes can be served with baseline_server for higher availability.
 	add(frontend.ExpectationsRouteV2, handlers.BaselineHandlerV2)
+	add(frontend.ExpectationsAtCommitRouteV1, handlers.BaselineAtCommitHandler)
 	add(frontend.GroupingsRouteV1, handlers.GroupingsHandler)
+	add(frontend.RPCsRouteV1, handlers.RPCsHandler)
 }
 
 var (
@@ -435,28 +585,7 @@ func addJSONRoute(method, jsonRoute string, handlerFunc http.HandlerFunc, router
 		panic(fmt.Sprintf(`Prefix "%s" not found in JSON RPC route: %s`, routerPathPrefix, jsonRoute))
 	}
 
-	// Parse the JSON RPC route, which can be of the form "/json/v<n>/<path>" or "/json/<path>", and
-	// extract <path> and <n>, defaulting to 0 for the unversioned case.
-	var path string
-	version := 0 // Default value is used for unversioned JSON RPCs.
-	if matches := versionedJSONRouteRegexp.FindStringSubmatch(jsonRoute); matches != nil {
-		var err error
-		version, err = strconv.Atoi(matches[1])
-		if err != nil {
-			// Should never happen.
-			panic("Failed to convert RPC version to integer (indicates a bug in the regexp): " + jsonRoute)
-		}
-		if version == 0 {
-			// Disallow /json/v0/* because we indicate unversioned RPCs with version 0.
-			panic("JSON RPC version cannot be 0: " + jsonRoute)
-		}
-		path = matches[2]
-	} else if matches := unversionedJSONRouteRegexp.FindStringSubmatch(jsonRoute); matches != nil {
-		path = matches[1]
-	} else {
-		// The path is neither a versioned nor an unversioned JSON RPC route. This is a coding error.
-		panic("Unrecognized JSON RPC route format: " + jsonRoute)
-	}
+	path, version := parseJSONRoute(jsonRoute)
 
 	counter := metrics2.GetCounter(web.RPCCallCounterMetric, map[string]string{
 		"route":   "/" + path,
@@ -479,6 +608,43 @@ func addJSONRoute(method, jsonRoute string, handlerFunc http.HandlerFunc, router
 	}
 }
 
+// addVersionedJSONRoute is addJSONRoute, but it also registers the JSON RPC with the given
+// handlers, so that older versions of the RPC are served with deprecation headers once a newer
+// version is added, and so that the RPC is listed by /json/v1/rpcs.
+func addVersionedJSONRoute(method, jsonRoute string, handlerFunc http.HandlerFunc, router chi.Router, routerPathPrefix string, handlers *web.Handlers) {
+	path, version := parseJSONRoute(jsonRoute)
+	handlers.RegisterRPC(method, jsonRoute, "/"+path, version)
+	addJSONRoute(method, jsonRoute, handlers.WithDeprecationHeaders(method, "/"+path, version, handlerFunc), router, routerPathPrefix)
+}
+
+// parseJSONRoute parses a JSON RPC route, which can be of the form "/json/v<n>/<path>" or
+// "/json/<path>", and returns <path> and <n>, defaulting to 0 for the unversioned case.
+//
+// It panics if the route is not a JSON RPC route, or if it uses version 0.
+func parseJSONRoute(jsonRoute string) (string, int) {
+	var path string
+	version := 0 // Default value is used for unversioned JSON RPCs.
+	if matches := versionedJSONRouteRegexp.FindStringSubmatch(jsonRoute); matches != nil {
+		var err error
+		version, err = strconv.Atoi(matches[1])
+		if err != nil {
+			// Should never happen.
+			panic("Failed to convert RPC version to integer (indicates a bug in the regexp): " + jsonRoute)
+		}
+		if version == 0 {
+			// Disallow /json/v0/* because we indicate unversioned RPCs with version 0.
+			panic("JSON RPC version cannot be 0: " + jsonRoute)
+		}
+		path = matches[2]
+	} else if matches := unversionedJSONRouteRegexp.FindStringSubmatch(jsonRoute); matches != nil {
+		path = matches[1]
+	} else {
+		// The path is neither a versioned nor an unversioned JSON RPC route. This is a coding error.
+		panic("Unrecognized JSON RPC route format: " + jsonRoute)
+	}
+	return path, version
+}
+
 // makeResourceHandler creates a static file handler that sets a caching policy.
 func makeResourceHandler(resourceDir string) func(http.ResponseWriter, *http.Request) {
 	fileServer := http.FileServer(http.Dir(resourceDir))
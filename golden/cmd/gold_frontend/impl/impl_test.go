@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.goldmine.build/go/metrics2"
+	"go.goldmine.build/golden/go/config"
 	"go.goldmine.build/golden/go/web"
 )
 
@@ -97,3 +98,90 @@ func TestAddJSONRoute_InvalidRoute_Panics(t *testing.T) {
 	test("", "/foo/", "Unrecognized JSON RPC route format: /foo/")
 	test("", "/foo/bar", "Unrecognized JSON RPC route format: /foo/bar")
 }
+
+func TestAddAuthenticatedJSONRoutes_ReadOnlyMirror_MutatingRoutesNotAdded(t *testing.T) {
+	routes := func(isReadOnlyMirror bool) []string {
+		var cfg config.Common
+		cfg.FrontendServerConfig.IsReadOnlyMirror = isReadOnlyMirror
+		router := chi.NewRouter()
+		addAuthenticatedJSONRoutes(router, cfg, &web.Handlers{}, nil)
+		var rv []string
+		require.NoError(t, chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			rv = append(rv, method+" "+route)
+			return nil
+		}))
+		return rv
+	}
+
+	mutatingRoutes := []string{
+		"POST /json/v2/triage",
+		"POST /json/v3/triage",
+		"POST /json/v2/triagelog/undo",
+		"POST /json/v1/ignores/add/",
+		"POST /json/v1/ignores/del/{id}",
+		"POST /json/v1/ignores/save/{id}",
+	}
+	readOnlyRoutes := []string{
+		"GET /json/v2/search",
+		"GET /json/v2/triagelog",
+		"POST /json/v2/details",
+	}
+
+	all := routes(false)
+	mirror := routes(true)
+	for _, route := range mutatingRoutes {
+		assert.Contains(t, all, route)
+		assert.NotContains(t, mirror, route)
+	}
+	for _, route := range readOnlyRoutes {
+		assert.Contains(t, all, route)
+		assert.Contains(t, mirror, route)
+	}
+}
+
+func TestAddAuthenticatedJSONRoutes_ReadReplica_MutatingRoutesNotAddedAndPrivateRoutesKept(t *testing.T) {
+	var cfg config.Common
+	cfg.FrontendServerConfig.IsReadReplica = true
+	router := chi.NewRouter()
+	addAuthenticatedJSONRoutes(router, cfg, &web.Handlers{}, nil)
+	var routes []string
+	require.NoError(t, chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	}))
+
+	assert.NotContains(t, routes, "POST /json/v3/triage")
+	assert.NotContains(t, routes, "POST /json/ingest")
+	assert.NotContains(t, routes, "POST /json/v1/ignores/add/")
+	assert.Contains(t, routes, "GET /json/v2/search")
+	// Unlike a read-only mirror, a replica isn't a public view.
+	assert.Contains(t, routes, "GET /json/v2/ignores")
+	assert.Contains(t, routes, "GET /json/v1/comments")
+}
+
+func TestAddAuthenticatedJSONRoutes_PublicView_PrivateRoutesNotAdded(t *testing.T) {
+	var cfg config.Common
+	cfg.FrontendServerConfig.IsPublicView = true
+	router := chi.NewRouter()
+	addAuthenticatedJSONRoutes(router, cfg, &web.Handlers{}, nil)
+	var routes []string
+	require.NoError(t, chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	}))
+
+	assert.NotContains(t, routes, "GET /json/v1/similar")
+	assert.NotContains(t, routes, "GET /json/v2/ignores")
+	assert.NotContains(t, routes, "GET /json/v1/comments")
+	assert.NotContains(t, routes, "POST /json/v1/comments/add")
+	assert.Contains(t, routes, "GET /json/v2/search")
+}
+
+func TestMakeCommentStore_PublicView_CommentsDisabled(t *testing.T) {
+	var cfg config.Common
+	cfg.FrontendServerConfig.IsPublicView = true
+	assert.Nil(t, makeCommentStore(cfg, nil))
+
+	cfg.FrontendServerConfig.IsPublicView = false
+	assert.NotNil(t, makeCommentStore(cfg, nil))
+}
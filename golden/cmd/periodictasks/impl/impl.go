@@ -14,6 +14,7 @@ import (
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"go.goldmine.build/email/go/emailclient"
 	"go.goldmine.build/go/auth"
 	"go.goldmine.build/go/gcs"
 	"go.goldmine.build/go/gcs/gcsclient"
@@ -31,7 +32,9 @@ import (
 	"go.goldmine.build/golden/go/code_review/github_crs"
 	"go.goldmine.build/golden/go/config"
 	"go.goldmine.build/golden/go/db"
+	"go.goldmine.build/golden/go/ignore"
 	"go.goldmine.build/golden/go/ignore/sqlignorestore"
+	"go.goldmine.build/golden/go/imagegc"
 	"go.goldmine.build/golden/go/sql"
 	"go.goldmine.build/golden/go/sql/schema"
 	"go.goldmine.build/golden/go/storage"
@@ -69,6 +72,15 @@ func PeriodicTasksMain(ctx context.Context, cfg config.Common, flags config.Serv
 	if cfg.PeriodicTasksConfig.PerfSummaries != nil {
 		startPerfSummarization(ctx, db, cfg.PeriodicTasksConfig.PerfSummaries)
 	}
+	if cfg.PeriodicTasksConfig.ImageGC != nil {
+		startImageGC(ctx, db, cfg)
+	}
+	if cfg.PeriodicTasksConfig.NegativeTriageComments != nil {
+		startNegativeTriageComments(ctx, db, cfg)
+	}
+	if cfg.PeriodicTasksConfig.IgnoreRuleNotifications != nil {
+		startIgnoreRuleNotifications(ctx, db, cfg)
+	}
 }
 
 func startUpdateTracesIgnoreStatus(ctx context.Context, db *pgxpool.Pool, cfg config.Common) {
@@ -114,6 +126,57 @@ func startCommentOnCLs(ctx context.Context, db *pgxpool.Pool, cfg config.Common)
 	})
 }
 
+func startNegativeTriageComments(ctx context.Context, db *pgxpool.Pool, cfg config.Common) {
+	ntCfg := *cfg.PeriodicTasksConfig.NegativeTriageComments
+	if ntCfg.Period.Duration <= 0 {
+		sklog.Infof("Not commenting on landed CLs because duration was zero.")
+		return
+	}
+	systems := mustInitializeSystems(ctx, cfg)
+	cmntr, err := commenter.NewNegativeTriageCommenter(db, systems, ntCfg, cfg.SiteURL)
+	if err != nil {
+		sklog.Fatalf("Could not initialize commenting on landed CLs: %s", err)
+	}
+	liveness := metrics2.NewLiveness("periodic_tasks", map[string]string{
+		"task": "negativeTriageComments",
+	})
+	go util.RepeatCtx(ctx, ntCfg.Period.Duration, func(ctx context.Context) {
+		sklog.Infof("Checking for negatively triaged digests from landed CLs")
+		ctx, span := trace.StartSpan(ctx, "periodic_negativeTriageComments")
+		defer span.End()
+		if err := cmntr.CommentOnLandedChangelists(ctx); err != nil {
+			sklog.Errorf("Error while commenting on landed CLs: %s", err)
+			return // return so the liveness is not updated
+		}
+		liveness.Reset()
+		sklog.Infof("Done checking for negatively triaged digests")
+	})
+}
+
+// startIgnoreRuleNotifications starts the process that notifies the owners of ignore rules which
+// are about to expire. This runs here rather than in the frontend, which has several replicas,
+// so that owners are only notified once.
+func startIgnoreRuleNotifications(ctx context.Context, db *pgxpool.Pool, cfg config.Common) {
+	nCfg := *cfg.PeriodicTasksConfig.IgnoreRuleNotifications
+	if nCfg.Period.Duration <= 0 || nCfg.NotifyBefore.Duration <= 0 {
+		sklog.Infof("Not notifying owners of expiring ignore rules because a duration was zero.")
+		return
+	}
+	var notifiers ignore.MultiNotifier
+	if nCfg.EmailFrom != "" {
+		notifiers = append(notifiers, ignore.NewEmailNotifier(emailclient.New(), nCfg.EmailFrom, cfg.SiteURL))
+	}
+	if nCfg.SlackWebhookURL != "" {
+		c := httputils.DefaultClientConfig().With2xxOnly().Client()
+		notifiers = append(notifiers, ignore.NewSlackNotifier(c, nCfg.SlackWebhookURL, cfg.SiteURL))
+	}
+	if len(notifiers) == 0 {
+		sklog.Fatalf("ignore_rule_notifications must set email_from or slack_webhook_url")
+	}
+	store := sqlignorestore.New(db)
+	ignore.StartExpiryNotifications(ctx, store, store, notifiers, nCfg.NotifyBefore.Duration, nCfg.Period.Duration)
+}
+
 // mustInitializeSystems creates code_review.Clients and returns them wrapped as a ReviewSystem.
 // It panics if any part of configuration fails.
 func mustInitializeSystems(ctx context.Context, cfg config.Common) []commenter.ReviewSystem {
@@ -641,7 +704,7 @@ func startKnownDigestsSync(ctx context.Context, db *pgxpool.Pool, cfg config.Com
 
 		// We grab digests from twice our window length to be overly thorough to avoid excess
 		// uploads from clients who use this.
-		digests, err := getAllRecentDigests(ctx, db, cfg.WindowSize*2)
+		digests, err := getAllRecentDigests(ctx, db, knownDigestsWindow(cfg))
 		if err != nil {
 			sklog.Errorf("Error getting recent digests: %s", err)
 			return
@@ -656,6 +719,12 @@ func startKnownDigestsSync(ctx context.Context, db *pgxpool.Pool, cfg config.Com
 	})
 }
 
+// knownDigestsWindow returns the number of recent commits whose digests are synced to the
+// KnownHashesGCSPath.
+func knownDigestsWindow(cfg config.Common) int {
+	return cfg.WindowSize * 2
+}
+
 // getAllRecentDigests returns all the digests seen on the primary branch in the provided window
 // of commits. If needed, this could combine the digests with the unique digests seen from recent
 // Tryjob results.
@@ -697,6 +766,32 @@ ORDER BY 1
 	return rv, nil
 }
 
+// startImageGC starts the process that regularly deletes images (and their diff metrics) that
+// are no longer referenced. It assumes the ImageGC config is non-nil and will panic if the
+// retention settings are not sane. Images must be retained for at least as long as the known
+// digests tell clients not to upload them, otherwise they would be lost for good.
+func startImageGC(ctx context.Context, db *pgxpool.Pool, cfg config.Common) {
+	gcCfg := *cfg.PeriodicTasksConfig.ImageGC
+	sklog.Infof("Image GC config %+v", gcCfg)
+	minRetention := knownDigestsWindow(cfg)
+	if gcCfg.RetentionCommits < minRetention {
+		panic(fmt.Sprintf("retention_commits must be at least twice the window_size (%d)", minRetention))
+	}
+	for corpus, n := range gcCfg.CorpusRetentionCommits {
+		if n < minRetention {
+			panic(fmt.Sprintf("retention for corpus %s must be at least twice the window_size (%d)", corpus, minRetention))
+		}
+	}
+	storageClient, err := storage.NewGCSClient(ctx, nil, storage.GCSClientOptions{
+		Bucket: cfg.GCSBucket,
+	})
+	if err != nil {
+		sklog.Errorf("Could not start image garbage collection: %s", err)
+		return
+	}
+	imagegc.New(db, storageClient, gcCfg).StartPeriodic(ctx)
+}
+
 // startPerfSummarization starts the process that will summarize gold traces and upload them to
 // Perf. It assumes the config is non-nil, and will panic if the minimally set data is not done so.
 // It starts a go routine that will immediately being summarizing and then repeat the process at
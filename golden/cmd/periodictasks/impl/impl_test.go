@@ -890,3 +890,19 @@ func ts(s string) time.Time {
 func waitForSystemTime() {
 	time.Sleep(150 * time.Millisecond)
 }
+
+func TestStartImageGC_RetentionShorterThanKnownDigestsWindow_Panics(t *testing.T) {
+	cfg := config.Common{WindowSize: 100}
+	cfg.PeriodicTasksConfig.ImageGC = &config.ImageGCConfig{RetentionCommits: 150}
+	assert.Panics(t, func() {
+		startImageGC(context.Background(), nil, cfg)
+	})
+
+	cfg.PeriodicTasksConfig.ImageGC = &config.ImageGCConfig{
+		RetentionCommits:       200,
+		CorpusRetentionCommits: map[string]int{"round": 150},
+	}
+	assert.Panics(t, func() {
+		startImageGC(context.Background(), nil, cfg)
+	})
+}
@@ -1,5 +1,6 @@
 // The sqlinit executable creates a database on the production SQL cluster with the appropriate
-// schema. It will not modify any tables (e.g. add missing indexes or change columns).
+// schema. It will not modify any tables (e.g. add missing indexes or change columns), except for
+// applying schema.Migrations, which adds the columns that were added to existing tables.
 // This executable will schedule new automatic backups, so if there are existing ones, one may have
 // to drop the old schedules.
 // https://www.cockroachlabs.com/docs/v20.2/show-schedules
@@ -62,6 +63,9 @@ func main() {
 	sklog.Infof("Creating tables")
 	execSql(*dbURL, schema.Schema)
 
+	sklog.Infof("Migrating existing tables")
+	execSql(*dbURL, schema.Migrations)
+
 	sklog.Infof("Deleting existing schedules, if any")
 	execSql(*dbURL, dropExistingSchedules(normalizedDB))
 
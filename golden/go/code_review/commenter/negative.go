@@ -0,0 +1,393 @@
+package commenter
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opencensus.io/trace"
+
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/sklog"
+	"go.goldmine.build/go/sql/sqlutil"
+	"go.goldmine.build/go/util"
+	"go.goldmine.build/golden/go/code_review"
+	"go.goldmine.build/golden/go/config"
+	"go.goldmine.build/golden/go/sql"
+	"go.goldmine.build/golden/go/sql/schema"
+	"go.goldmine.build/golden/go/types"
+)
+
+// NegativeTriageCommenter comments on landed CLs when digests they produced are triaged as
+// negative on the primary branch, so the authors learn their change might have caused a
+// regression.
+//
+// The originating CL of a digest is found by blaming the digest on the first commit on the
+// primary branch that produced it, and then finding the landed CL by the author of that commit
+// which produced the same digest in its tryjobs.
+//
+// The digests each CL was commented on about are stored in the NegativeTriageComments table, so
+// restarts don't cause the same CL to be told about the same digest again.
+type NegativeTriageCommenter struct {
+	db                 *pgxpool.Pool
+	instanceURL        string
+	messageTemplate    *template.Template
+	systems            []ReviewSystem
+	disabledCorpora    util.StringSet
+	maxCommentsPerRun  int
+	minCommentInterval time.Duration
+
+	lastCheck time.Time
+	// pending are the notifications which have not been sent yet, keyed by qualified CL id.
+	pending map[string]*landedCLNotification
+}
+
+// NewNegativeTriageCommenter returns a NegativeTriageCommenter that comments on CLs in the given
+// systems.
+func NewNegativeTriageCommenter(db *pgxpool.Pool, systems []ReviewSystem, cfg config.NegativeTriageCommentsConfig, instanceURL string) (*NegativeTriageCommenter, error) {
+	templ, err := template.New("negative").Parse(cfg.CommentTemplate)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Message template %q", cfg.CommentTemplate)
+	}
+	return &NegativeTriageCommenter{
+		db:                 db,
+		instanceURL:        instanceURL,
+		messageTemplate:    templ,
+		systems:            systems,
+		disabledCorpora:    util.NewStringSet(cfg.DisabledCorpora),
+		maxCommentsPerRun:  cfg.MaxCommentsPerRun,
+		minCommentInterval: cfg.MinCommentInterval.Duration,
+		pending:            map[string]*landedCLNotification{},
+	}, nil
+}
+
+// negativeDigest is a digest which was triaged as negative on the primary branch.
+type negativeDigest struct {
+	groupingID schema.GroupingID
+	digest     schema.DigestBytes
+	grouping   paramtools.Params
+	triagedBy  util.StringSet
+}
+
+// landedCLNotification collects the negative digests that were blamed on a single landed CL.
+type landedCLNotification struct {
+	system       string
+	changelistID string // qualified id
+	commits      util.StringSet
+	digests      []negativeDigest
+	triagedBy    util.StringSet
+}
+
+// CommentOnLandedChangelists finds the digests that were triaged as negative on the primary
+// branch since the last check, blames them on the landed CLs which produced them and comments on
+// those CLs. To avoid spamming a CL, it is commented on at most once per minCommentInterval and
+// there are at most maxCommentsPerRun comments per call. Any CLs that are skipped because of
+// this are commented on in a later call.
+func (n *NegativeTriageCommenter) CommentOnLandedChangelists(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "commenter_CommentOnLandedChangelists")
+	defer span.End()
+	if n.lastCheck.IsZero() {
+		// Default to checking everything triaged within the last day.
+		n.lastCheck = now.Now(ctx).Add(-1 * 24 * time.Hour)
+	}
+	lastCheckUpdate := now.Now(ctx)
+	digests, err := n.getNewlyNegativeDigests(ctx)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	for _, d := range digests {
+		if err := n.blame(ctx, d); err != nil {
+			return skerr.Wrap(err)
+		}
+	}
+	// The blamed digests are kept in pending until they have been commented on, so it is safe to
+	// move on even if some comments are delayed.
+	n.lastCheck = lastCheckUpdate
+	n.commentOnPending(ctx)
+	return nil
+}
+
+// getNewlyNegativeDigests returns the digests which were triaged as negative on the primary
+// branch since the last check and are still negative. Digests from disabled corpora are skipped.
+func (n *NegativeTriageCommenter) getNewlyNegativeDigests(ctx context.Context) ([]*negativeDigest, error) {
+	ctx, span := trace.StartSpan(ctx, "getNewlyNegativeDigests")
+	defer span.End()
+	const statement = `SELECT ExpectationDeltas.grouping_id, ExpectationDeltas.digest, Groupings.keys,
+	ExpectationRecords.user_name
+FROM ExpectationRecords
+JOIN ExpectationDeltas ON ExpectationRecords.expectation_record_id = ExpectationDeltas.expectation_record_id
+JOIN Expectations ON ExpectationDeltas.grouping_id = Expectations.grouping_id
+	AND ExpectationDeltas.digest = Expectations.digest
+JOIN Groupings ON ExpectationDeltas.grouping_id = Groupings.grouping_id
+WHERE ExpectationRecords.branch_name IS NULL AND ExpectationRecords.triage_time > $1
+	AND ExpectationDeltas.label_before != 'n' AND ExpectationDeltas.label_after = 'n'
+	AND Expectations.label = 'n'`
+	rows, err := n.db.Query(ctx, statement, n.lastCheck)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	// The same digest might have been triaged multiple times, so we de-duplicate them.
+	byKey := map[string]*negativeDigest{}
+	var rv []*negativeDigest
+	for rows.Next() {
+		var d negativeDigest
+		var user string
+		if err := rows.Scan(&d.groupingID, &d.digest, &d.grouping, &user); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		if n.disabledCorpora[d.grouping[types.CorpusField]] {
+			continue
+		}
+		key := string(d.groupingID) + string(d.digest)
+		if existing, ok := byKey[key]; ok {
+			existing.triagedBy[user] = true
+			continue
+		}
+		d.triagedBy = util.NewStringSet([]string{user})
+		byKey[key] = &d
+		rv = append(rv, &d)
+	}
+	return rv, nil
+}
+
+// blame finds the landed CL which produced the given digest and, if there is one, adds the
+// digest to the pending notification for that CL.
+func (n *NegativeTriageCommenter) blame(ctx context.Context, d *negativeDigest) error {
+	ctx, span := trace.StartSpan(ctx, "blame")
+	defer span.End()
+	// Find the first commit at which the digest was produced on the primary branch and then the
+	// most recent landed CL by the author of that commit that produced the same digest.
+	const statement = `WITH
+TracesWithDigest AS (
+	SELECT DISTINCT trace_id FROM TiledTraceDigests
+	WHERE grouping_id = $1 AND digest = $2
+),
+FirstCommit AS (
+	SELECT TraceValues.commit_id FROM TraceValues
+	JOIN TracesWithDigest ON TraceValues.trace_id = TracesWithDigest.trace_id
+	WHERE TraceValues.digest = $2
+	ORDER BY TraceValues.commit_id ASC LIMIT 1
+)
+SELECT Changelists.system, Changelists.changelist_id, GitCommits.git_hash FROM FirstCommit
+JOIN GitCommits ON FirstCommit.commit_id = GitCommits.commit_id
+JOIN Changelists ON GitCommits.author_email = Changelists.owner_email
+WHERE Changelists.status = 'landed' AND EXISTS (
+	SELECT 1 FROM SecondaryBranchValues
+	WHERE SecondaryBranchValues.branch_name = Changelists.changelist_id
+		AND SecondaryBranchValues.grouping_id = $1 AND SecondaryBranchValues.digest = $2
+)
+ORDER BY Changelists.last_ingested_data DESC LIMIT 1`
+	row := n.db.QueryRow(ctx, statement, d.groupingID, d.digest)
+	var system, clID, commit string
+	if err := row.Scan(&system, &clID, &commit); err != nil {
+		if err == pgx.ErrNoRows {
+			// The digest was not produced by a landed CL, so there is nobody to notify.
+			return nil
+		}
+		return skerr.Wrapf(err, "blaming digest %x in grouping %x", d.digest, d.groupingID)
+	}
+	notification, ok := n.pending[clID]
+	if !ok {
+		notification = &landedCLNotification{
+			system:       system,
+			changelistID: clID,
+			commits:      util.StringSet{},
+			triagedBy:    util.StringSet{},
+		}
+		n.pending[clID] = notification
+	}
+	notification.commits[commit] = true
+	notification.triagedBy.AddLists(d.triagedBy.Keys())
+	notification.digests = append(notification.digests, *d)
+	return nil
+}
+
+// commentOnPending comments on the CLs which have pending notifications, unless they have been
+// commented on recently or we have reached the limit of comments for this run. Digests a CL was
+// already commented on about are dropped from its notification.
+func (n *NegativeTriageCommenter) commentOnPending(ctx context.Context) {
+	ts := now.Now(ctx)
+	// Go through the CLs in a deterministic order, so the same ones are not always delayed.
+	clIDs := make([]string, 0, len(n.pending))
+	for clID := range n.pending {
+		clIDs = append(clIDs, clID)
+	}
+	sort.Strings(clIDs)
+	numComments := 0
+	for _, clID := range clIDs {
+		if n.maxCommentsPerRun > 0 && numComments >= n.maxCommentsPerRun {
+			sklog.Infof("Made %d comments about negative digests, delaying the rest", numComments)
+			return
+		}
+		notification := n.pending[clID]
+		lastCommented, err := n.removeCommentedDigests(ctx, notification)
+		if err != nil {
+			sklog.Warningf("Could not check previous comments on CL %s: %s", clID, err)
+			continue
+		}
+		if len(notification.digests) == 0 {
+			delete(n.pending, clID)
+			continue
+		}
+		if ts.Sub(lastCommented) < n.minCommentInterval {
+			// Commented too recently, the digests will be part of the next comment.
+			continue
+		}
+		if err := n.commentOn(ctx, notification); err != nil {
+			sklog.Warningf("Could not comment on CL %s about negative digests: %s", clID, err)
+			// Continue anyway - don't let one problematic CL stop the rest.
+			continue
+		}
+		delete(n.pending, clID)
+		numComments++
+		if err := n.recordComment(ctx, notification, ts); err != nil {
+			sklog.Warningf("Could not record comment on CL %s: %s", clID, err)
+		}
+	}
+}
+
+// removeCommentedDigests removes the digests the CL of the notification was already commented on
+// about from the notification. It returns when the CL was last commented on, or the zero time if
+// it never was.
+func (n *NegativeTriageCommenter) removeCommentedDigests(ctx context.Context, notification *landedCLNotification) (time.Time, error) {
+	ctx, span := trace.StartSpan(ctx, "removeCommentedDigests")
+	defer span.End()
+	const statement = `SELECT grouping_id, digest, commented_ts FROM NegativeTriageComments
+WHERE changelist_id = $1`
+	rows, err := n.db.Query(ctx, statement, notification.changelistID)
+	if err != nil {
+		return time.Time{}, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var lastCommented time.Time
+	commented := util.StringSet{}
+	for rows.Next() {
+		var groupingID schema.GroupingID
+		var digest schema.DigestBytes
+		var ts time.Time
+		if err := rows.Scan(&groupingID, &digest, &ts); err != nil {
+			return time.Time{}, skerr.Wrap(err)
+		}
+		commented[string(groupingID)+string(digest)] = true
+		if ts.After(lastCommented) {
+			lastCommented = ts
+		}
+	}
+	digests := notification.digests[:0]
+	for _, d := range notification.digests {
+		if !commented[string(d.groupingID)+string(d.digest)] {
+			digests = append(digests, d)
+		}
+	}
+	notification.digests = digests
+	return lastCommented, nil
+}
+
+// recordComment stores that the CL of the notification was commented on about its digests at
+// the given time.
+func (n *NegativeTriageCommenter) recordComment(ctx context.Context, notification *landedCLNotification, ts time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "recordComment")
+	defer span.End()
+	const statement = `UPSERT INTO NegativeTriageComments (changelist_id, grouping_id, digest, commented_ts)
+VALUES `
+	arguments := make([]interface{}, 0, 4*len(notification.digests))
+	for _, d := range notification.digests {
+		arguments = append(arguments, notification.changelistID, d.groupingID, d.digest, ts)
+	}
+	vp := sqlutil.ValuesPlaceholders(4, len(notification.digests))
+	if _, err := n.db.Exec(ctx, statement+vp, arguments...); err != nil {
+		return skerr.Wrap(err)
+	}
+	return nil
+}
+
+// commentOn comments on the landed CL about the negative digests it produced.
+func (n *NegativeTriageCommenter) commentOn(ctx context.Context, notification *landedCLNotification) error {
+	clID := sql.Unqualify(notification.changelistID)
+	var client code_review.Client
+	for _, c := range n.systems {
+		if c.ID == notification.system {
+			client = c.Client
+		}
+	}
+	if client == nil {
+		sklog.Errorf("Could not make comment for system %s - not configured", notification.system)
+		return nil
+	}
+	msg, err := n.negativeMessage(clID, notification)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	sklog.Infof("Commenting on CL %s about %d negative digests", clID, len(notification.digests))
+	if err := client.CommentOn(ctx, clID, msg); err != nil {
+		if err == code_review.ErrNotFound {
+			sklog.Infof("CL %s might have been deleted", clID)
+			return nil
+		}
+		return skerr.Wrapf(err, "commenting on %s CL %s", notification.system, clID)
+	}
+	return nil
+}
+
+// negativeTemplateContext contains the fields that can be substituted into the comment about
+// negative digests.
+type negativeTemplateContext struct {
+	ChangelistID string
+	CRS          string
+	InstanceURL  string
+	// Commits are the commits on the primary branch which first produced the digests.
+	Commits    []string
+	Digests    []negativeDigestContext
+	NumDigests int
+	// TriagedBy are the users who triaged the digests as negative.
+	TriagedBy []string
+}
+
+// negativeDigestContext describes a single negative digest in a negativeTemplateContext.
+type negativeDigestContext struct {
+	Corpus     string
+	Test       types.TestName
+	Digest     types.Digest
+	DetailsURL string
+}
+
+// negativeMessage returns a message about the negative digests produced by the given CL.
+func (n *NegativeTriageCommenter) negativeMessage(clID string, notification *landedCLNotification) (string, error) {
+	c := negativeTemplateContext{
+		ChangelistID: clID,
+		CRS:          notification.system,
+		InstanceURL:  n.instanceURL,
+		Commits:      notification.commits.Keys(),
+		NumDigests:   len(notification.digests),
+		TriagedBy:    notification.triagedBy.Keys(),
+	}
+	sort.Strings(c.Commits)
+	sort.Strings(c.TriagedBy)
+	for _, d := range notification.digests {
+		digest := types.Digest(hex.EncodeToString(d.digest))
+		grouping := url.Values{}
+		for k, v := range d.grouping {
+			grouping.Set(k, v)
+		}
+		c.Digests = append(c.Digests, negativeDigestContext{
+			Corpus:     d.grouping[types.CorpusField],
+			Test:       types.TestName(d.grouping[types.PrimaryKeyField]),
+			Digest:     digest,
+			DetailsURL: n.instanceURL + "/detail?grouping=" + url.QueryEscape(grouping.Encode()) + "&digest=" + string(digest),
+		})
+	}
+	var b bytes.Buffer
+	if err := n.messageTemplate.Execute(&b, c); err != nil {
+		return "", skerr.Wrapf(err, "With template context %#v", c)
+	}
+	return b.String(), nil
+}
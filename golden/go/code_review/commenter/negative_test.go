@@ -0,0 +1,211 @@
+package commenter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/go/util"
+	"go.goldmine.build/golden/go/code_review"
+	mock_codereview "go.goldmine.build/golden/go/code_review/mocks"
+	"go.goldmine.build/golden/go/config"
+	"go.goldmine.build/golden/go/sql"
+	dks "go.goldmine.build/golden/go/sql/datakitchensink"
+	"go.goldmine.build/golden/go/sql/schema"
+	"go.goldmine.build/golden/go/sql/sqltest"
+	"go.goldmine.build/golden/go/types"
+)
+
+const (
+	negativeTemplate = `Images from {{.CRS}} CL {{.ChangelistID}} were triaged negative by{{range .TriagedBy}} {{.}}{{end}}:
+{{range .Digests}}{{.Test}} {{.DetailsURL}}
+{{end}}`
+
+	authorEmail  = "author@example.com"
+	triagerEmail = "triager@example.com"
+)
+
+var (
+	negativeNow  = time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+	negativeKeys = paramtools.Params{types.CorpusField: "round", types.PrimaryKeyField: "circle"}
+)
+
+func TestCommentOnLandedChangelists_DigestFromLandedCLTriagedNegative_CommentsOnBlamedCL(t *testing.T) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, negativeNow)
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	_, groupingID := sql.SerializeMap(negativeKeys)
+	traceID := schema.TraceID{0xaa}
+	recordID := uuid.New()
+	secondaryValue := func(clID string) schema.SecondaryBranchValueRow {
+		return schema.SecondaryBranchValueRow{BranchName: clID, VersionName: clID + "_ps", TraceID: traceID, Digest: d(t, dks.DigestA02Pos), GroupingID: groupingID, OptionsID: schema.OptionsID{0x01}, SourceFileID: schema.SourceFileID{0x01}}
+	}
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, schema.Tables{
+		GitCommits: []schema.GitCommitRow{
+			{GitHash: "1111111111111111111111111111111111111111", CommitID: "0000000001", CommitTime: negativeNow.Add(-48 * time.Hour), AuthorEmail: "other@example.com", Subject: "first"},
+			{GitHash: "2222222222222222222222222222222222222222", CommitID: "0000000002", CommitTime: negativeNow.Add(-24 * time.Hour), AuthorEmail: authorEmail, Subject: "second"},
+		},
+		CommitsWithData: []schema.CommitWithDataRow{
+			{CommitID: "0000000001", TileID: 0},
+			{CommitID: "0000000002", TileID: 0},
+		},
+		Groupings: []schema.GroupingRow{{GroupingID: groupingID, Keys: negativeKeys}},
+		TiledTraceDigests: []schema.TiledTraceDigestRow{
+			{TraceID: traceID, TileID: 0, Digest: d(t, dks.DigestA01Pos), GroupingID: groupingID},
+			{TraceID: traceID, TileID: 0, Digest: d(t, dks.DigestA02Pos), GroupingID: groupingID},
+		},
+		TraceValues: []schema.TraceValueRow{
+			{Shard: 0, TraceID: traceID, CommitID: "0000000001", Digest: d(t, dks.DigestA01Pos), GroupingID: groupingID, OptionsID: schema.OptionsID{0x01}, SourceFileID: schema.SourceFileID{0x01}},
+			{Shard: 0, TraceID: traceID, CommitID: "0000000002", Digest: d(t, dks.DigestA02Pos), GroupingID: groupingID, OptionsID: schema.OptionsID{0x01}, SourceFileID: schema.SourceFileID{0x01}},
+		},
+		Changelists: []schema.ChangelistRow{
+			{ChangelistID: "gerrit_123", System: "gerrit", Status: schema.StatusLanded, OwnerEmail: authorEmail, Subject: "second", LastIngestedData: negativeNow.Add(-25 * time.Hour)},
+			// Produced the same digest, but the author did not land the blamed commit.
+			{ChangelistID: "gerrit_456", System: "gerrit", Status: schema.StatusLanded, OwnerEmail: "other@example.com", Subject: "first", LastIngestedData: negativeNow.Add(-2 * time.Hour)},
+			// Produced the same digest, but has not landed.
+			{ChangelistID: "gerrit_789", System: "gerrit", Status: schema.StatusOpen, OwnerEmail: authorEmail, Subject: "third", LastIngestedData: negativeNow.Add(-time.Hour)},
+		},
+		SecondaryBranchValues: []schema.SecondaryBranchValueRow{
+			secondaryValue("gerrit_123"), secondaryValue("gerrit_456"), secondaryValue("gerrit_789"),
+		},
+		ExpectationRecords: []schema.ExpectationRecordRow{
+			{ExpectationRecordID: recordID, UserName: triagerEmail, TriageTime: negativeNow.Add(-time.Hour), NumChanges: 1},
+		},
+		ExpectationDeltas: []schema.ExpectationDeltaRow{
+			{ExpectationRecordID: recordID, GroupingID: groupingID, Digest: d(t, dks.DigestA02Pos), LabelBefore: schema.LabelUntriaged, LabelAfter: schema.LabelNegative},
+		},
+		Expectations: []schema.ExpectationRow{
+			{GroupingID: groupingID, Digest: d(t, dks.DigestA02Pos), Label: schema.LabelNegative, ExpectationRecordID: &recordID},
+		},
+	}))
+
+	gerritClient := mock_codereview.NewClient(t)
+	gerritClient.On("CommentOn", testutils.AnyContext, "123", `Images from gerrit CL 123 were triaged negative by triager@example.com:
+circle gold.skia.org/detail?grouping=name%3Dcircle%26source_type%3Dround&digest=`+string(dks.DigestA02Pos)+`
+`).Return(nil).Once()
+
+	c, err := NewNegativeTriageCommenter(db, []ReviewSystem{{ID: "gerrit", Client: gerritClient}}, negativeConfig(), instanceURL)
+	require.NoError(t, err)
+	require.NoError(t, c.CommentOnLandedChangelists(ctx))
+	assert.Empty(t, c.pending)
+	assert.Equal(t, []schema.NegativeTriageCommentRow{{
+		ChangelistID: "gerrit_123",
+		GroupingID:   groupingID,
+		Digest:       d(t, dks.DigestA02Pos),
+		CommentedTS:  negativeNow,
+	}}, sqltest.GetAllRows(ctx, t, db, "NegativeTriageComments", &schema.NegativeTriageCommentRow{}))
+
+	// A restarted commenter finds the same digest again, but doesn't comment about it twice.
+	restarted, err := NewNegativeTriageCommenter(db, []ReviewSystem{{ID: "gerrit", Client: gerritClient}}, negativeConfig(), instanceURL)
+	require.NoError(t, err)
+	require.NoError(t, restarted.CommentOnLandedChangelists(ctx))
+	assert.Empty(t, restarted.pending)
+}
+
+func TestCommentOnPending_CommentedRecently_DelayedUntilIntervalPasses(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	client := mock_codereview.NewClient(t)
+	client.On("CommentOn", testutils.AnyContext, "123", mock.Anything).Return(nil).Twice()
+	c, err := NewNegativeTriageCommenter(db, []ReviewSystem{{ID: "gerrit", Client: client}}, negativeConfig(), instanceURL)
+	require.NoError(t, err)
+
+	c.pending["gerrit_123"] = testNotification("gerrit_123", 0xa0)
+	c.commentOnPending(context.WithValue(ctx, now.ContextKey, negativeNow))
+	assert.Empty(t, c.pending)
+
+	c.pending["gerrit_123"] = testNotification("gerrit_123", 0xa1)
+	c.commentOnPending(context.WithValue(ctx, now.ContextKey, negativeNow.Add(time.Hour)))
+	assert.Len(t, c.pending, 1)
+
+	c.commentOnPending(context.WithValue(ctx, now.ContextKey, negativeNow.Add(25*time.Hour)))
+	assert.Empty(t, c.pending)
+}
+
+func TestCommentOnPending_DigestAlreadyCommentedOn_Dropped(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, schema.Tables{
+		NegativeTriageComments: []schema.NegativeTriageCommentRow{{
+			ChangelistID: "gerrit_123",
+			GroupingID:   testGroupingID,
+			Digest:       schema.DigestBytes{0xa0},
+			CommentedTS:  negativeNow.Add(-48 * time.Hour),
+		}},
+	}))
+	client := mock_codereview.NewClient(t)
+	c, err := NewNegativeTriageCommenter(db, []ReviewSystem{{ID: "gerrit", Client: client}}, negativeConfig(), instanceURL)
+	require.NoError(t, err)
+
+	c.pending["gerrit_123"] = testNotification("gerrit_123", 0xa0)
+	c.commentOnPending(context.WithValue(ctx, now.ContextKey, negativeNow))
+	assert.Empty(t, c.pending)
+	client.AssertNotCalled(t, "CommentOn", testutils.AnyContext, mock.Anything, mock.Anything)
+}
+
+func TestCommentOnPending_MoreCLsThanMaxCommentsPerRun_RestDelayed(t *testing.T) {
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(context.Background(), t)
+	client := mock_codereview.NewClient(t)
+	client.On("CommentOn", testutils.AnyContext, "123", mock.Anything).Return(nil).Once()
+	client.On("CommentOn", testutils.AnyContext, "456", mock.Anything).Return(code_review.ErrNotFound).Once()
+	cfg := negativeConfig()
+	cfg.MaxCommentsPerRun = 1
+	c, err := NewNegativeTriageCommenter(db, []ReviewSystem{{ID: "gerrit", Client: client}}, cfg, instanceURL)
+	require.NoError(t, err)
+
+	c.pending["gerrit_123"] = testNotification("gerrit_123", 0xa0)
+	c.pending["gerrit_456"] = testNotification("gerrit_456", 0xa0)
+	ctx := context.WithValue(context.Background(), now.ContextKey, negativeNow)
+	c.commentOnPending(ctx)
+	assert.Len(t, c.pending, 1)
+	assert.Contains(t, c.pending, "gerrit_456")
+
+	// A deleted CL is dropped.
+	c.commentOnPending(ctx)
+	assert.Empty(t, c.pending)
+}
+
+func TestNewNegativeTriageCommenter_InvalidTemplate_ReturnsError(t *testing.T) {
+	cfg := negativeConfig()
+	cfg.CommentTemplate = "{{.Oops"
+	_, err := NewNegativeTriageCommenter(nil, nil, cfg, instanceURL)
+	require.Error(t, err)
+}
+
+func negativeConfig() config.NegativeTriageCommentsConfig {
+	return config.NegativeTriageCommentsConfig{
+		CommentTemplate:    negativeTemplate,
+		DisabledCorpora:    []string{"square"},
+		MinCommentInterval: config.Duration{Duration: 24 * time.Hour},
+		Period:             config.Duration{Duration: time.Hour},
+	}
+}
+
+var testGroupingID = schema.GroupingID{0xb0}
+
+func testNotification(clID string, digest byte) *landedCLNotification {
+	return &landedCLNotification{
+		system:       "gerrit",
+		changelistID: clID,
+		commits:      util.NewStringSet([]string{"2222222222222222222222222222222222222222"}),
+		triagedBy:    util.NewStringSet([]string{triagerEmail}),
+		digests: []negativeDigest{{
+			groupingID: testGroupingID,
+			digest:     schema.DigestBytes{digest},
+			grouping:   negativeKeys,
+		}},
+	}
+}
+
+func d(t *testing.T, digest types.Digest) schema.DigestBytes {
+	b, err := sql.DigestToBytes(digest)
+	require.NoError(t, err)
+	return b
+}
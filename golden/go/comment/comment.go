@@ -0,0 +1,79 @@
+// Package comment defines an interface for storing comments that users leave on digests and
+// changelists.
+package comment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/golden/go/types"
+	"go.goldmine.build/golden/go/validation"
+)
+
+// MaxTextLength is the maximum number of bytes in the text of a comment.
+const MaxTextLength = 4096
+
+// Store is an interface for a database that saves comments.
+type Store interface {
+	// Create adds a new comment to the store and returns its ID.
+	Create(ctx context.Context, c Comment) (string, error)
+
+	// ListForDigests returns all comments about any of the given digests, oldest first.
+	ListForDigests(ctx context.Context, digests []types.Digest) ([]Comment, error)
+
+	// ListForChangelist returns all comments about the given changelist, oldest first.
+	ListForChangelist(ctx context.Context, crs, clID string) ([]Comment, error)
+
+	// Get returns the comment with the given ID. If it does not exist, ErrNotFound is returned.
+	Get(ctx context.Context, id string) (Comment, error)
+
+	// Delete removes a comment from the store. If the comment didn't exist before, there will
+	// be no error.
+	Delete(ctx context.Context, id string) error
+}
+
+// ErrNotFound is returned when a comment does not exist.
+var ErrNotFound = errors.New("comment not found")
+
+// Comment is a note left by a user about either a single digest or a changelist.
+type Comment struct {
+	// ID is the id used to store this Comment in a Store.
+	ID string
+	// Digest is the digest this comment is about, if any.
+	Digest types.Digest
+	// CRS and ChangelistID identify the changelist this comment is about, if any.
+	CRS          string
+	ChangelistID string
+	// CreatedBy is the email of the user who wrote the comment.
+	CreatedBy string
+	// Created is when the comment was written.
+	Created time.Time
+	// Text is the comment itself.
+	Text string
+}
+
+// Validate returns an error if the comment is not about exactly one digest or changelist, or if
+// the text is empty or too long.
+func (c Comment) Validate() error {
+	hasDigest := c.Digest != ""
+	hasCL := c.CRS != "" || c.ChangelistID != ""
+	if hasDigest == hasCL {
+		return skerr.Fmt("a comment must be about either a digest or a changelist")
+	}
+	if hasDigest && !validation.IsValidDigest(string(c.Digest)) {
+		return skerr.Fmt("invalid digest %q", c.Digest)
+	}
+	if hasCL && (c.CRS == "" || c.ChangelistID == "") {
+		return skerr.Fmt("a comment about a changelist needs both the CRS and the changelist id")
+	}
+	if strings.TrimSpace(c.Text) == "" {
+		return skerr.Fmt("a comment must not be empty")
+	}
+	if len(c.Text) > MaxTextLength {
+		return skerr.Fmt("a comment must be at most %d bytes long", MaxTextLength)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package comment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate_ValidComments_NoError(t *testing.T) {
+	assert.NoError(t, Comment{Digest: "a01a01a01a01a01a01a01a01a01a01a0", Text: "looks right"}.Validate())
+	assert.NoError(t, Comment{CRS: "gerrit", ChangelistID: "1234", Text: "needs a rebaseline"}.Validate())
+}
+
+func TestValidate_InvalidComments_ReturnsError(t *testing.T) {
+	for name, c := range map[string]Comment{
+		"no target":        {Text: "about nothing"},
+		"two targets":      {Digest: "a01a01a01a01a01a01a01a01a01a01a0", CRS: "gerrit", ChangelistID: "1234", Text: "both"},
+		"invalid digest":   {Digest: "not a digest", Text: "hmm"},
+		"missing CRS":      {ChangelistID: "1234", Text: "which system?"},
+		"missing CL":       {CRS: "gerrit", Text: "which CL?"},
+		"empty text":       {Digest: "a01a01a01a01a01a01a01a01a01a01a0", Text: "  \n"},
+		"text is too long": {Digest: "a01a01a01a01a01a01a01a01a01a01a0", Text: strings.Repeat("x", MaxTextLength+1)},
+	} {
+		assert.Error(t, c.Validate(), name)
+	}
+}
@@ -0,0 +1,161 @@
+// Package sqlcommentstore contains a SQL implementation of comment.Store.
+package sqlcommentstore
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opencensus.io/trace"
+
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/golden/go/comment"
+	"go.goldmine.build/golden/go/sql"
+	"go.goldmine.build/golden/go/sql/schema"
+	"go.goldmine.build/golden/go/types"
+)
+
+const selectComments = `SELECT comment_id, digest, changelist_id, user_name, created_ts, text FROM Comments `
+
+type StoreImpl struct {
+	db *pgxpool.Pool
+}
+
+// New returns a SQL based implementation of comment.Store.
+func New(db *pgxpool.Pool) *StoreImpl {
+	return &StoreImpl{db: db}
+}
+
+// Create implements the comment.Store interface. The creation time is set to the current time.
+func (s *StoreImpl) Create(ctx context.Context, c comment.Comment) (string, error) {
+	ctx, span := trace.StartSpan(ctx, "commentstore_Create", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if err := c.Validate(); err != nil {
+		return "", skerr.Wrap(err)
+	}
+	row := schema.CommentRow{
+		UserName:  c.CreatedBy,
+		CreatedTS: now.Now(ctx),
+		Text:      c.Text,
+	}
+	if c.Digest != "" {
+		d, err := sql.DigestToBytes(c.Digest)
+		if err != nil {
+			return "", skerr.Wrap(err)
+		}
+		row.Digest = d
+	} else {
+		qCLID := sql.Qualify(c.CRS, c.ChangelistID)
+		row.ChangelistID = &qCLID
+	}
+	err := s.db.QueryRow(ctx, `
+INSERT INTO Comments (digest, changelist_id, user_name, created_ts, text)
+VALUES ($1, $2, $3, $4, $5) RETURNING comment_id`,
+		row.Digest, row.ChangelistID, row.UserName, row.CreatedTS, row.Text).Scan(&row.CommentID)
+	if err != nil {
+		return "", skerr.Wrapf(err, "creating comment %#v", c)
+	}
+	return row.CommentID.String(), nil
+}
+
+// ListForDigests implements the comment.Store interface.
+func (s *StoreImpl) ListForDigests(ctx context.Context, digests []types.Digest) ([]comment.Comment, error) {
+	ctx, span := trace.StartSpan(ctx, "commentstore_ListForDigests")
+	defer span.End()
+	if len(digests) == 0 {
+		return nil, nil
+	}
+	args := make([]schema.DigestBytes, 0, len(digests))
+	for _, d := range digests {
+		b, err := sql.DigestToBytes(d)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		args = append(args, b)
+	}
+	return s.list(ctx, selectComments+`WHERE digest = ANY($1) ORDER BY created_ts ASC, comment_id`, args)
+}
+
+// ListForChangelist implements the comment.Store interface.
+func (s *StoreImpl) ListForChangelist(ctx context.Context, crs, clID string) ([]comment.Comment, error) {
+	ctx, span := trace.StartSpan(ctx, "commentstore_ListForChangelist")
+	defer span.End()
+	return s.list(ctx, selectComments+`WHERE changelist_id = $1 ORDER BY created_ts ASC, comment_id`,
+		sql.Qualify(crs, clID))
+}
+
+// list returns the comments returned by the given statement.
+func (s *StoreImpl) list(ctx context.Context, statement string, args ...interface{}) ([]comment.Comment, error) {
+	rows, err := s.db.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var rv []comment.Comment
+	for rows.Next() {
+		var r schema.CommentRow
+		if err := r.ScanFrom(rows.Scan); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		rv = append(rv, toComment(r))
+	}
+	return rv, nil
+}
+
+// Get implements the comment.Store interface.
+func (s *StoreImpl) Get(ctx context.Context, id string) (comment.Comment, error) {
+	ctx, span := trace.StartSpan(ctx, "commentstore_Get")
+	defer span.End()
+	commentID, err := uuid.Parse(id)
+	if err != nil {
+		return comment.Comment{}, comment.ErrNotFound
+	}
+	row := s.db.QueryRow(ctx, selectComments+`WHERE comment_id = $1`, commentID)
+	var r schema.CommentRow
+	if err := r.ScanFrom(row.Scan); err != nil {
+		if skerr.Unwrap(err) == pgx.ErrNoRows {
+			return comment.Comment{}, comment.ErrNotFound
+		}
+		return comment.Comment{}, skerr.Wrapf(err, "getting comment %s", id)
+	}
+	return toComment(r), nil
+}
+
+// Delete implements the comment.Store interface.
+func (s *StoreImpl) Delete(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "commentstore_Delete", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	commentID, err := uuid.Parse(id)
+	if err != nil {
+		return nil // A comment with an invalid ID cannot exist.
+	}
+	if _, err := s.db.Exec(ctx, `DELETE FROM Comments WHERE comment_id = $1`, commentID); err != nil {
+		return skerr.Wrapf(err, "deleting comment %s", id)
+	}
+	return nil
+}
+
+// toComment converts a row of the Comments table to a comment.Comment.
+func toComment(r schema.CommentRow) comment.Comment {
+	c := comment.Comment{
+		ID:        r.CommentID.String(),
+		CreatedBy: r.UserName,
+		Created:   r.CreatedTS,
+		Text:      r.Text,
+	}
+	if len(r.Digest) > 0 {
+		c.Digest = types.Digest(hex.EncodeToString(r.Digest))
+	}
+	if r.ChangelistID != nil {
+		c.CRS = strings.SplitN(*r.ChangelistID, "_", 2)[0]
+		c.ChangelistID = sql.Unqualify(*r.ChangelistID)
+	}
+	return c
+}
+
+// Make sure StoreImpl fulfills the comment.Store interface.
+var _ comment.Store = (*StoreImpl)(nil)
@@ -0,0 +1,119 @@
+package sqlcommentstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/golden/go/comment"
+	dks "go.goldmine.build/golden/go/sql/datakitchensink"
+	"go.goldmine.build/golden/go/sql/schema"
+	"go.goldmine.build/golden/go/sql/sqltest"
+	"go.goldmine.build/golden/go/types"
+)
+
+var (
+	firstTime  = time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+	secondTime = time.Date(2021, time.March, 5, 5, 6, 7, 0, time.UTC)
+)
+
+func TestCreate_CommentsCanBeListedByDigestAndChangelist(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	store := New(db)
+
+	digestID, err := store.Create(context.WithValue(ctx, now.ContextKey, secondTime), comment.Comment{
+		Digest:    dks.DigestA01Pos,
+		CreatedBy: "alpha@example.com",
+		Text:      "This is the reference rendering.",
+	})
+	require.NoError(t, err)
+	otherDigestID, err := store.Create(context.WithValue(ctx, now.ContextKey, firstTime), comment.Comment{
+		Digest:    dks.DigestB01Pos,
+		CreatedBy: "beta@example.com",
+		Text:      "Slightly blurry, but fine.",
+	})
+	require.NoError(t, err)
+	clID, err := store.Create(context.WithValue(ctx, now.ContextKey, firstTime), comment.Comment{
+		CRS:          dks.GitHubCRS,
+		ChangelistID: dks.ChangelistIDThatAttemptsToFixIOS,
+		CreatedBy:    "alpha@example.com",
+		Text:         "The iOS changes need another look.",
+	})
+	require.NoError(t, err)
+
+	rows := sqltest.GetAllRows(ctx, t, db, "Comments", &schema.CommentRow{}).([]schema.CommentRow)
+	assert.Len(t, rows, 3)
+
+	comments, err := store.ListForDigests(ctx, []types.Digest{dks.DigestA01Pos, dks.DigestB01Pos, dks.DigestC01Pos})
+	require.NoError(t, err)
+	assert.Equal(t, []comment.Comment{{
+		ID:        otherDigestID,
+		Digest:    dks.DigestB01Pos,
+		CreatedBy: "beta@example.com",
+		Created:   firstTime,
+		Text:      "Slightly blurry, but fine.",
+	}, {
+		ID:        digestID,
+		Digest:    dks.DigestA01Pos,
+		CreatedBy: "alpha@example.com",
+		Created:   secondTime,
+		Text:      "This is the reference rendering.",
+	}}, comments)
+
+	comments, err = store.ListForChangelist(ctx, dks.GitHubCRS, dks.ChangelistIDThatAttemptsToFixIOS)
+	require.NoError(t, err)
+	assert.Equal(t, []comment.Comment{{
+		ID:           clID,
+		CRS:          dks.GitHubCRS,
+		ChangelistID: dks.ChangelistIDThatAttemptsToFixIOS,
+		CreatedBy:    "alpha@example.com",
+		Created:      firstTime,
+		Text:         "The iOS changes need another look.",
+	}}, comments)
+
+	c, err := store.Get(ctx, clID)
+	require.NoError(t, err)
+	assert.Equal(t, comments[0], c)
+}
+
+func TestCreate_InvalidComment_ReturnsError(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	store := New(db)
+
+	_, err := store.Create(ctx, comment.Comment{
+		Digest:       dks.DigestA01Pos,
+		CRS:          dks.GitHubCRS,
+		ChangelistID: dks.ChangelistIDThatAttemptsToFixIOS,
+		CreatedBy:    "alpha@example.com",
+		Text:         "Both a digest and a CL",
+	})
+	require.Error(t, err)
+	assert.Empty(t, sqltest.GetAllRows(ctx, t, db, "Comments", &schema.CommentRow{}))
+}
+
+func TestDelete_CommentIsRemoved(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	store := New(db)
+
+	id, err := store.Create(ctx, comment.Comment{
+		Digest:    dks.DigestA01Pos,
+		CreatedBy: "alpha@example.com",
+		Text:      "Wrong digest, oops.",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Delete(ctx, id))
+	_, err = store.Get(ctx, id)
+	assert.Equal(t, comment.ErrNotFound, err)
+
+	// Deleting it again, or deleting something which is not a valid id, is not an error.
+	require.NoError(t, store.Delete(ctx, id))
+	require.NoError(t, store.Delete(ctx, "not a uuid"))
+}
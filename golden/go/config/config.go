@@ -10,6 +10,7 @@ import (
 	"go.goldmine.build/go/git/provider"
 	"go.goldmine.build/go/skerr"
 	"go.goldmine.build/go/util"
+	"go.goldmine.build/golden/go/diff"
 	"go.goldmine.build/golden/go/publicparams"
 )
 
@@ -190,6 +191,12 @@ type Common struct {
 	// for instances with high amounts of secondary branches.
 	HighContentionMode bool `json:"high_contention_mode"`
 
+	// ComputePerceptualDiffMetrics makes the diff calculator also compute the SSIM and delta E of
+	// each pair of images, which the "ssim" and "delta_e" metrics of DiffMetricsByCorpus rank by.
+	// This costs more CPU than computing all the other diff metrics together. The DiffMetrics
+	// table needs the columns from schema.Migrations, which sqlinit applies.
+	ComputePerceptualDiffMetrics bool `json:"compute_perceptual_diff_metrics" optional:"true"`
+
 	// RepoFollowerConfig contains settings specific to the repo follower, i.e. the commits ingestion.
 	RepoFollowerConfig RepoFollowerConfig `json:"repo_follower_config"`
 
@@ -228,6 +235,19 @@ type FrontendServerConfig struct {
 	// If this instance is simply a mirror of another instance's data.
 	IsPublicView bool `json:"is_public_view"`
 
+	// IsReadOnlyMirror disables all endpoints which modify data (triaging, undoing triages and
+	// editing ignore rules). It implies IsPublicView, so only the traces matching
+	// PubliclyAllowableParams are served. This allows a single deployment config to safely expose
+	// internal results externally.
+	IsReadOnlyMirror bool `json:"is_read_only_mirror" optional:"true"`
+
+	// IsReadReplica runs this frontend as a replica of the main frontend of the instance, for read
+	// traffic such as search, status and baselines. Like IsReadOnlyMirror it disables all the
+	// endpoints which modify data, but it serves the same traces as the main frontend. It also
+	// doesn't run the components which write to the shared stores, e.g. creating and refreshing
+	// the materialized views, which must be done by the main frontend.
+	IsReadReplica bool `json:"is_read_replica" optional:"true"`
+
 	// MaterializedViewCorpora is the optional list of corpora that should have a materialized
 	// view created and refreshed to speed up search results.
 	MaterializedViewCorpora []string `json:"materialized_view_corpora" optional:"true"`
@@ -238,11 +258,76 @@ type FrontendServerConfig struct {
 
 	// Path to a directory with static assets that should be served to the frontend (JS, CSS, etc.).
 	ResourcesPath string `json:"resources_path"`
+
+	// DiffMetricsByCorpus configures, per corpus, which diff metric is used to find the closest
+	// positive and negative digests to a given digest. Corpora without an entry use the combined
+	// metric. The "ssim" and "delta_e" metrics require ComputePerceptualDiffMetrics.
+	DiffMetricsByCorpus map[string]diff.MetricConfig `json:"diff_metrics_by_corpus" optional:"true"`
+
+	// DiffBudget, if set, is evaluated against each patchset so that repos can gate merges on
+	// how many image changes a CL introduces.
+	DiffBudget *DiffBudgetConfig `json:"diff_budget" optional:"true"`
+
+	// TriageEvents, if set, configures where events are sent whenever expectations change.
+	TriageEvents *TriageEventsConfig `json:"triage_events" optional:"true"`
+
+	// AllowHTTPIngestion enables the /json/ingest endpoint, which lets editors (e.g. CI service
+	// accounts that cannot write to the ingestion buckets) upload results directly. The results
+	// are written to the sources in IngestionServerConfig and ingested from there as usual.
+	AllowHTTPIngestion bool `json:"allow_http_ingestion" optional:"true"`
+
+	// ImageURLSigningKeyPath, if set, is a file containing a secret key (at least 32 bytes) which
+	// is used to sign the content-addressed image URLs. Unsigned requests for those URLs are then
+	// rejected, so a CDN can serve the images of an instance that requires logging in. Setting
+	// this implies FrontendConfig.ImmutableImageURLs.
+	ImageURLSigningKeyPath string `json:"image_url_signing_key_path" optional:"true"`
+
+	// VariantKey, if set, is the trace key whose values distinguish variants of the same trace,
+	// e.g. "scale" for tests which are rendered at multiple scales. The digests drawn by the
+	// variants of a trace are shown side-by-side and can be triaged together.
+	VariantKey string `json:"variant_key" optional:"true"`
+
+	// LegacyRPCDeprecation, if set, is the date (YYYY-MM-DD) the JSON RPCs that have been superseded
+	// by a newer version were deprecated. It is announced to clients in the Deprecation header of the
+	// responses of those RPCs.
+	LegacyRPCDeprecation string `json:"legacy_rpc_deprecation" optional:"true"`
+
+	// LegacyRPCSunset, if set, is the date (YYYY-MM-DD) after which the JSON RPCs that have been
+	// superseded by a newer version may be removed. It is announced to clients in the Sunset header
+	// of the responses of those RPCs.
+	LegacyRPCSunset string `json:"legacy_rpc_sunset" optional:"true"`
+}
+
+// IsReadOnly returns true if the endpoints which modify data are disabled.
+func (c FrontendServerConfig) IsReadOnly() bool {
+	return c.IsReadOnlyMirror || c.IsReadReplica
+}
+
+// DiffBudgetConfig limits how many image changes a single patchset may introduce. Limits that are
+// not set are not enforced.
+type DiffBudgetConfig struct {
+	// MaxNewUntriagedDigests is the maximum number of new digests (i.e. not seen on the primary
+	// branch) a patchset may produce that are still untriaged.
+	MaxNewUntriagedDigests *int `json:"max_new_untriaged_digests,omitempty"`
+
+	// MaxChangedTests is the maximum number of tests (groupings) for which a patchset may
+	// produce new digests.
+	MaxChangedTests *int `json:"max_changed_tests,omitempty"`
+}
+
+// TriageEventsConfig configures the destinations of triage events. At least one should be set.
+type TriageEventsConfig struct {
+	// PubSubTopic is the topic (in the project given by PubsubProjectID) on which one message is
+	// published per changed expectation.
+	PubSubTopic string `json:"pubsub_topic" optional:"true"`
+
+	// WebhookURL is a URL to which every triage action is POSTed as a JSON list of events.
+	WebhookURL string `json:"webhook_url" optional:"true"`
 }
 
 // IsAuthoritative indicates that this instance can write to known_hashes, update CL statuses, etc.
 func (c Common) IsAuthoritative() bool {
-	return !c.Local && !c.FrontendServerConfig.IsPublicView
+	return !c.Local && !c.FrontendServerConfig.IsPublicView && !c.FrontendServerConfig.IsReadOnly()
 }
 
 type FrontendConfig struct {
@@ -250,6 +335,15 @@ type FrontendConfig struct {
 	Title                       string `json:"title"`
 	CustomTriagingDisallowedMsg string `json:"customTriagingDisallowedMsg,omitempty" optional:"true"`
 	IsPublic                    bool   `json:"isPublic"`
+	IsReadOnlyMirror            bool   `json:"isReadOnlyMirror"`
+	// ImmutableImageURLs makes the frontend use the content-addressed image URLs, which can be
+	// cached forever (e.g. by a CDN in front of the instance).
+	ImmutableImageURLs bool `json:"immutableImageURLs" optional:"true"`
+	// ImageURLParams is filled in by the server whenever a page is loaded. If image URLs are
+	// signed, it holds the query parameters with a fresh signature.
+	ImageURLParams string `json:"imageURLParams,omitempty" optional:"true"`
+	// VariantKey is filled in by the server from FrontendServerConfig.VariantKey.
+	VariantKey string `json:"variantKey,omitempty" optional:"true"`
 }
 
 type PeriodicTasksConfig struct {
@@ -268,6 +362,18 @@ type PeriodicTasksConfig struct {
 	// untriaged digests and comment on them if appropriate.
 	CommentOnCLsPeriod config.Duration `json:"comment_on_cls_period" optional:"true"`
 
+	// IgnoreRuleNotifications, if set, configures notifying the owners of ignore rules shortly
+	// before those rules expire.
+	IgnoreRuleNotifications *IgnoreRuleNotificationsConfig `json:"ignore_rule_notifications" optional:"true"`
+
+	// ImageGC, if set, configures the periodic deletion of images (and the diff metrics computed
+	// from them) that are no longer referenced by recent data or by any baseline.
+	ImageGC *ImageGCConfig `json:"image_gc" optional:"true"`
+
+	// NegativeTriageComments, if set, configures commenting on landed CLs whose images were later
+	// triaged as negative on the primary branch.
+	NegativeTriageComments *NegativeTriageCommentsConfig `json:"negative_triage_comments" optional:"true"`
+
 	// PerfSummaries configures summary data (e.g. triage status, ignore count) that is fed into
 	// a GCS bucket which an instance of Perf can ingest from.
 	PerfSummaries *PerfSummariesConfig `json:"perf_summaries" optional:"true"`
@@ -290,6 +396,75 @@ type PerfSummariesConfig struct {
 	ValuesToIgnore     []string        `json:"values_to_ignore"`
 }
 
+// ImageGCConfig configures how long images are retained before they are garbage collected.
+type ImageGCConfig struct {
+	// ArchiveBucket, if set, is a GCS bucket to which unreferenced images are moved instead of
+	// being deleted outright. A lifecycle rule on that bucket can then delete them later.
+	ArchiveBucket string `json:"archive_bucket" optional:"true"`
+
+	// ChangelistRetention is how long images produced by a CL that is no longer open are kept after
+	// the CL last had data ingested. Images produced by open CLs are always kept.
+	ChangelistRetention config.Duration `json:"changelist_retention"`
+
+	// CorpusRetentionCommits overrides RetentionCommits for the given corpora. Like
+	// RetentionCommits, each must be at least twice the WindowSize.
+	CorpusRetentionCommits map[string]int `json:"corpus_retention_commits" optional:"true"`
+
+	// DryRun, if true, makes the garbage collector only log and count the images it would delete.
+	DryRun bool `json:"dry_run" optional:"true"`
+
+	// GracePeriod is how long after being uploaded an image is protected from deletion. This avoids
+	// deleting images that have been uploaded, but whose results have not been ingested yet.
+	GracePeriod config.Duration `json:"grace_period"`
+
+	// Period is how often to look for and delete unreferenced images.
+	Period config.Duration `json:"period"`
+
+	// RetentionCommits is the number of most recent commits on the primary branch in which an image
+	// must have been seen to be kept (unless it is part of a baseline). It must be at least twice
+	// the WindowSize, which is the window of the known hashes that clients don't upload again.
+	RetentionCommits int `json:"retention_commits"`
+}
+
+// IgnoreRuleNotificationsConfig configures how the owners of ignore rules are told that their
+// rules are about to expire. At least one of EmailFrom and SlackWebhookURL should be set.
+type IgnoreRuleNotificationsConfig struct {
+	// EmailFrom, if set, is the address from which the owner of a rule is emailed.
+	EmailFrom string `json:"email_from" optional:"true"`
+
+	// NotifyBefore is how long before a rule expires its owner is notified.
+	NotifyBefore config.Duration `json:"notify_before"`
+
+	// Period is how often to check for rules which will expire soon.
+	Period config.Duration `json:"period"`
+
+	// SlackWebhookURL, if set, is a Slack incoming webhook to which a message mentioning the owner
+	// of a rule is posted.
+	SlackWebhookURL string `json:"slack_webhook_url" optional:"true"`
+}
+
+// NegativeTriageCommentsConfig configures how the authors of landed CLs are notified that images
+// produced by their CL were triaged as negative on the primary branch.
+type NegativeTriageCommentsConfig struct {
+	// CommentTemplate is a string with placeholders for generating a comment message. See
+	// commenter.negativeTemplateContext for the exact fields.
+	CommentTemplate string `json:"comment_template"`
+
+	// DisabledCorpora lists the corpora for which no comments should be made.
+	DisabledCorpora []string `json:"disabled_corpora" optional:"true"`
+
+	// MaxCommentsPerRun limits how many CLs are commented on each Period. Any remaining CLs are
+	// commented on in later runs. Zero means there is no limit.
+	MaxCommentsPerRun int `json:"max_comments_per_run" optional:"true"`
+
+	// MinCommentInterval is the minimum time between two comments on the same CL. Digests triaged
+	// in between are batched into the next comment.
+	MinCommentInterval config.Duration `json:"min_comment_interval"`
+
+	// Period is how often to check for newly negative digests.
+	Period config.Duration `json:"period"`
+}
+
 // CodeReviewSystem represents the details needed to interact with a CodeReviewSystem (e.g.
 // "gerrit", "github")
 type CodeReviewSystem struct {
@@ -76,11 +76,27 @@ type DiffMetrics struct {
 
 	// DimDiffer is true if the dimensions between the two images are different.
 	DimDiffer bool
+
+	// SSIM is the structural similarity of the two images. See SSIM() for details.
+	SSIM float32
+
+	// DeltaE is the average perceptual color difference of the two images. See MeanDeltaE() for
+	// details.
+	DeltaE float32
 }
 
 // ComputeDiffMetrics computes and returns the diff metrics between two given images.
 func ComputeDiffMetrics(leftImg *image.NRGBA, rightImg *image.NRGBA) *DiffMetrics {
 	defer metrics2.FuncTimer().Stop()
+	ret := ComputePixelDiffMetrics(leftImg, rightImg)
+	ret.SSIM = SSIM(leftImg, rightImg)
+	ret.DeltaE = MeanDeltaE(leftImg, rightImg)
+	return ret
+}
+
+// ComputePixelDiffMetrics is like ComputeDiffMetrics, except that it leaves SSIM and DeltaE
+// unset, since they take more CPU to compute than all the other metrics together.
+func ComputePixelDiffMetrics(leftImg *image.NRGBA, rightImg *image.NRGBA) *DiffMetrics {
 	ret, _ := PixelDiff(leftImg, rightImg)
 	ret.CombinedMetric = CombinedDiffMetric(ret.MaxRGBADiffs, ret.PixelDiffPercent)
 	return ret
@@ -24,14 +24,18 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   0.04604,
 			PixelDiffPercent: 0.0064,
 			MaxRGBADiffs:     [4]int{54, 100, 125, 0},
-			DimDiffer:        false})
+			DimDiffer:        false,
+			SSIM:             0.9998,
+			DeltaE:           0.00144})
 	assertDiffs(t, "5024150605949408692", "11069776588985027208",
 		&DiffMetrics{
 			NumDiffPixels:    2233,
 			CombinedMetric:   0.04185,
 			PixelDiffPercent: 0.8932,
 			MaxRGBADiffs:     [4]int{0, 0, 1, 0},
-			DimDiffer:        false})
+			DimDiffer:        false,
+			SSIM:             1,
+			DeltaE:           0.00554})
 	// Assert the same image.
 	assertDiffs(t, "5024150605949408692", "5024150605949408692",
 		&DiffMetrics{
@@ -39,7 +43,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   0,
 			PixelDiffPercent: 0,
 			MaxRGBADiffs:     [4]int{0, 0, 0, 0},
-			DimDiffer:        false})
+			DimDiffer:        false,
+			SSIM:             1,
+			DeltaE:           0})
 	// Assert different images with different dimensions.
 	assertDiffs(t, "ffce5042b4ac4a57bd7c8657b557d495", "fffbcca7e8913ec45b88cc2c6a3a73ad",
 		&DiffMetrics{
@@ -47,7 +53,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   8.79528,
 			PixelDiffPercent: 89.32407,
 			MaxRGBADiffs:     [4]int{255, 255, 255, 0},
-			DimDiffer:        true})
+			DimDiffer:        true,
+			SSIM:             0.09073,
+			DeltaE:           89.10084})
 	// Assert with images that match in dimensions but where all pixels differ.
 	assertDiffs(t, "4029959456464745507", "4029959456464745507-inverted",
 		&DiffMetrics{
@@ -55,7 +63,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   9.30605,
 			PixelDiffPercent: 100.0,
 			MaxRGBADiffs:     [4]int{255, 255, 255, 0},
-			DimDiffer:        false})
+			DimDiffer:        false,
+			SSIM:             0.0733,
+			DeltaE:           98.37659})
 
 	// Assert different images where neither fits into the other.
 	assertDiffs(t, "fffbcca7e8913ec45b88cc2c6a3a73ad", "fffbcca7e8913ec45b88cc2c6a3a73ad-rotated",
@@ -64,7 +74,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   8.05148,
 			PixelDiffPercent: 74.85503,
 			MaxRGBADiffs:     [4]int{255, 255, 255, 0},
-			DimDiffer:        true})
+			DimDiffer:        true,
+			SSIM:             0.21713,
+			DeltaE:           74.3754})
 	// Make sure the metric is symmetric.
 	assertDiffs(t, "fffbcca7e8913ec45b88cc2c6a3a73ad-rotated", "fffbcca7e8913ec45b88cc2c6a3a73ad",
 		&DiffMetrics{
@@ -72,7 +84,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   8.05148,
 			PixelDiffPercent: 74.85503,
 			MaxRGBADiffs:     [4]int{255, 255, 255, 0},
-			DimDiffer:        true})
+			DimDiffer:        true,
+			SSIM:             0.21713,
+			DeltaE:           74.3754})
 
 	// Compare two images where one has an alpha channel and the other doesn't.
 	assertDiffs(t, "b716a12d5b98d04b15db1d9dd82c82ea", "df1591dde35907399734ea19feb76663",
@@ -81,7 +95,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   1.41919,
 			PixelDiffPercent: 2.84831,
 			MaxRGBADiffs:     [4]int{255, 2, 255, 0},
-			DimDiffer:        false})
+			DimDiffer:        false,
+			SSIM:             0.99569,
+			DeltaE:           2.81577})
 
 	// Compare two images where the alpha differs.
 	assertDiffs(t, "df1591dde35907399734ea19feb76663", "df1591dde35907399734ea19feb76663-6-alpha-diff",
@@ -90,7 +106,9 @@ func TestDiffMetrics(t *testing.T) {
 			CombinedMetric:   0.03,
 			PixelDiffPercent: 0.00195,
 			MaxRGBADiffs:     [4]int{0, 0, 0, 235},
-			DimDiffer:        false})
+			DimDiffer:        false,
+			SSIM:             0.99978,
+			DeltaE:           0.00162})
 }
 
 // lineDiff lists the differences in the lines of a and b.
@@ -167,6 +185,8 @@ func assertDiffs(t *testing.T, d1, d2 string, expectedDiffMetrics *DiffMetrics)
 	diffMetrics := ComputeDiffMetrics(img1, img2)
 	diffMetrics.PixelDiffPercent = roundToDecimalPlace(diffMetrics.PixelDiffPercent, 5)
 	diffMetrics.CombinedMetric = roundToDecimalPlace(diffMetrics.CombinedMetric, 5)
+	diffMetrics.SSIM = roundToDecimalPlace(diffMetrics.SSIM, 5)
+	diffMetrics.DeltaE = roundToDecimalPlace(diffMetrics.DeltaE, 5)
 	assert.Equal(t, expectedDiffMetrics, diffMetrics)
 }
 
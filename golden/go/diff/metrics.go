@@ -0,0 +1,220 @@
+package diff
+
+import (
+	"image"
+	"math"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/util"
+)
+
+// Metric is the name of a way to measure how different two images are.
+type Metric string
+
+const (
+	// MetricCombined uses DiffMetrics.CombinedMetric. This is the default.
+	MetricCombined Metric = "combined"
+	// MetricSSIM uses DiffMetrics.SSIM, which is better at ignoring noise (e.g. anti-aliasing)
+	// than metrics that only look at how many pixels differ.
+	MetricSSIM Metric = "ssim"
+	// MetricDeltaE uses DiffMetrics.DeltaE, which weighs color differences by how noticeable they
+	// are to a human.
+	MetricDeltaE Metric = "delta_e"
+	// MetricChannelWeighted uses ChannelWeightedDiffMetric, which allows differences in some
+	// channels (e.g. alpha) to count less than others.
+	MetricChannelWeighted Metric = "channel_weighted"
+)
+
+// MetricConfig configures how to decide which of several images is closest to a given image,
+// e.g. when finding the closest positive digest to an untriaged one.
+type MetricConfig struct {
+	// Metric is the metric by which images are ranked. The empty string means MetricCombined.
+	Metric Metric `json:"metric"`
+
+	// ChannelWeights are the weights of the red, green, blue and alpha channels. They are only
+	// used by MetricChannelWeighted.
+	ChannelWeights [4]float32 `json:"channel_weights" optional:"true"`
+}
+
+// Validate returns an error if the config is not usable.
+func (c MetricConfig) Validate() error {
+	switch c.Metric {
+	case "", MetricCombined, MetricSSIM, MetricDeltaE:
+		return nil
+	case MetricChannelWeighted:
+		sum := float32(0)
+		for _, w := range c.ChannelWeights {
+			if w < 0 {
+				return skerr.Fmt("channel weights must not be negative: %v", c.ChannelWeights)
+			}
+			sum += w
+		}
+		if sum == 0 {
+			return skerr.Fmt("at least one channel weight must be positive")
+		}
+		return nil
+	default:
+		return skerr.Fmt("unknown diff metric %q", c.Metric)
+	}
+}
+
+// ChannelWeightedDiffMetric is like CombinedDiffMetric, except that the maximum difference of
+// each channel is weighted by the given weights. If all weights are equal, it returns the same
+// value as CombinedDiffMetric.
+func ChannelWeightedDiffMetric(channelDiffs [4]int, pixelDiffPercent float32, weights [4]float32) float32 {
+	sum, sumWeights := 0.0, 0.0
+	for i, c := range channelDiffs {
+		sum += float64(weights[i]) * float64(c) * float64(c)
+		sumWeights += float64(weights[i])
+	}
+	if sumWeights <= 0 {
+		return CombinedDiffMetric(channelDiffs, pixelDiffPercent)
+	}
+	normalizedRGBA := math.Sqrt(sum/sumWeights) / 255.0
+	return float32(math.Sqrt(float64(pixelDiffPercent) * normalizedRGBA))
+}
+
+const (
+	// ssimWindow is the size of the square windows over which the structural similarity is
+	// computed. Partial windows at the right and bottom edges are included.
+	ssimWindow = 8
+
+	// ssimC1 and ssimC2 stabilize the division for windows with a near zero mean or variance.
+	// These are the constants suggested by the SSIM paper for 8 bit images.
+	ssimC1 = (0.01 * 255) * (0.01 * 255)
+	ssimC2 = (0.03 * 255) * (0.03 * 255)
+
+	// maxDeltaE is the DeltaE between black and white, which is used for pixels that only exist
+	// in one of the images.
+	maxDeltaE = 100
+)
+
+// SSIM returns the mean structural similarity of the luminance of the two images, which is 1 for
+// identical images and gets smaller (possibly negative) the more the images differ. If the
+// dimensions differ, only the overlapping area is compared and the result is scaled by how much
+// of the larger area overlaps. Transparent pixels are treated as if they were drawn on a white
+// background.
+func SSIM(img1, img2 *image.NRGBA) float32 {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	w := util.MinInt(b1.Dx(), b2.Dx())
+	h := util.MinInt(b1.Dy(), b2.Dy())
+	totalPixels := util.MaxInt(b1.Dx(), b2.Dx()) * util.MaxInt(b1.Dy(), b2.Dy())
+	if w == 0 || h == 0 {
+		if totalPixels == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	sum := 0.0
+	numWindows := 0
+	for y0 := 0; y0 < h; y0 += ssimWindow {
+		y1 := util.MinInt(y0+ssimWindow, h)
+		for x0 := 0; x0 < w; x0 += ssimWindow {
+			x1 := util.MinInt(x0+ssimWindow, w)
+			sum += windowSSIM(img1, img2, x0, y0, x1, y1)
+			numWindows++
+		}
+	}
+	overlap := float64(w*h) / float64(totalPixels)
+	return float32(sum / float64(numWindows) * overlap)
+}
+
+// windowSSIM returns the structural similarity of the luminance of the two images in the
+// rectangle [x0, x1) x [y0, y1).
+func windowSSIM(img1, img2 *image.NRGBA, x0, y0, x1, y1 int) float64 {
+	n := float64((x1 - x0) * (y1 - y0))
+	var sum1, sum2, sumSq1, sumSq2, sumProd float64
+	for y := y0; y < y1; y++ {
+		row1 := img1.Pix[y*img1.Stride:]
+		row2 := img2.Pix[y*img2.Stride:]
+		for x := x0; x < x1; x++ {
+			l1 := luminance(row1[x*4 : x*4+4])
+			l2 := luminance(row2[x*4 : x*4+4])
+			sum1 += l1
+			sum2 += l2
+			sumSq1 += l1 * l1
+			sumSq2 += l2 * l2
+			sumProd += l1 * l2
+		}
+	}
+	mean1, mean2 := sum1/n, sum2/n
+	var1 := sumSq1/n - mean1*mean1
+	var2 := sumSq2/n - mean2*mean2
+	covar := sumProd/n - mean1*mean2
+	return ((2*mean1*mean2 + ssimC1) * (2*covar + ssimC2)) /
+		((mean1*mean1 + mean2*mean2 + ssimC1) * (var1 + var2 + ssimC2))
+}
+
+// MeanDeltaE returns the average perceptual color difference (CIE76 delta E) between the pixels
+// of the two images. A value of about 2.3 corresponds to a just noticeable difference; black and
+// white differ by 100. Pixels that are only in one of the images (i.e. the dimensions differ)
+// count as the maximum difference. Transparent pixels are treated as if they were drawn on a white
+// background.
+func MeanDeltaE(img1, img2 *image.NRGBA) float32 {
+	b1, b2 := img1.Bounds(), img2.Bounds()
+	w := util.MinInt(b1.Dx(), b2.Dx())
+	h := util.MinInt(b1.Dy(), b2.Dy())
+	totalPixels := util.MaxInt(b1.Dx(), b2.Dx()) * util.MaxInt(b1.Dy(), b2.Dy())
+	if totalPixels == 0 {
+		return 0
+	}
+
+	sum := float64(totalPixels-w*h) * maxDeltaE
+	for y := 0; y < h; y++ {
+		row1 := img1.Pix[y*img1.Stride:]
+		row2 := img2.Pix[y*img2.Stride:]
+		for x := 0; x < w; x++ {
+			p1 := row1[x*4 : x*4+4]
+			p2 := row2[x*4 : x*4+4]
+			// Most pixels are identical, so avoid the color conversions for those.
+			if p1[0] == p2[0] && p1[1] == p2[1] && p1[2] == p2[2] && p1[3] == p2[3] {
+				continue
+			}
+			l1, a1, bb1 := toLab(p1)
+			l2, a2, bb2 := toLab(p2)
+			sum += math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (bb1-bb2)*(bb1-bb2))
+		}
+	}
+	return float32(sum / float64(totalPixels))
+}
+
+// srgbToLinear maps an 8 bit sRGB channel value to linear light in [0, 1].
+var srgbToLinear = func() [256]float64 {
+	var ret [256]float64
+	for i := range ret {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			ret[i] = c / 12.92
+		} else {
+			ret[i] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+	return ret
+}()
+
+// toLab converts a non-premultiplied sRGB pixel, composited over a white background, to the
+// CIELAB color space (D65 white point).
+func toLab(p []uint8) (float64, float64, float64) {
+	a := float64(p[3]) / 255
+	r := srgbToLinear[p[0]]*a + (1 - a)
+	g := srgbToLinear[p[1]]*a + (1 - a)
+	b := srgbToLinear[p[2]]*a + (1 - a)
+
+	// Convert to XYZ, normalized by the D65 reference white.
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// labF is the non-linear compression used when converting XYZ to CIELAB.
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSIM_SameImage_One(t *testing.T) {
+	img := gradient(64, 48, false)
+	assert.InDelta(t, 1, SSIM(img, img), 0.00001)
+}
+
+func TestSSIM_NoiseScoresHigherThanStructuralChange(t *testing.T) {
+	a := gradient(64, 48, false)
+	noisy := gradient(64, 48, false)
+	// Slightly change every other pixel, similar to anti-aliasing differences.
+	for y := 0; y < 48; y++ {
+		for x := y % 2; x < 64; x += 2 {
+			c := noisy.NRGBAAt(x, y)
+			if c.R < 0xff {
+				c.R, c.G, c.B = c.R+1, c.G+1, c.B+1
+			}
+			noisy.SetNRGBA(x, y, c)
+		}
+	}
+	reversed := gradient(64, 48, true)
+	assert.Greater(t, SSIM(a, noisy), float32(0.99))
+	assert.Less(t, SSIM(a, reversed), SSIM(a, noisy))
+}
+
+func TestSSIM_DifferentSize_ScaledByOverlap(t *testing.T) {
+	a := gradient(64, 48, false)
+	b := image.NewNRGBA(image.Rect(0, 0, 64, 96))
+	copy(b.Pix, a.Pix)
+	assert.InDelta(t, 0.5, SSIM(a, b), 0.00001)
+	assert.Equal(t, SSIM(a, b), SSIM(b, a))
+}
+
+func TestMeanDeltaE_BlackAndWhite_Hundred(t *testing.T) {
+	black := solid(4, 4, color.NRGBA{A: 0xff})
+	white := solid(4, 4, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	assert.InDelta(t, 100, MeanDeltaE(black, white), 0.01)
+	assert.Equal(t, float32(0), MeanDeltaE(black, black))
+}
+
+func TestMeanDeltaE_TransparentAndWhite_Zero(t *testing.T) {
+	transparent := solid(4, 4, color.NRGBA{})
+	white := solid(4, 4, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	assert.InDelta(t, 0, MeanDeltaE(transparent, white), 0.01)
+}
+
+func TestMeanDeltaE_DifferentSize_MissingPixelsAreMaxDiff(t *testing.T) {
+	a := solid(4, 4, color.NRGBA{A: 0xff})
+	b := solid(4, 8, color.NRGBA{A: 0xff})
+	assert.InDelta(t, 50, MeanDeltaE(a, b), 0.01)
+}
+
+func TestChannelWeightedDiffMetric_EqualWeights_SameAsCombined(t *testing.T) {
+	channelDiffs := [4]int{10, 20, 30, 40}
+	assert.InDelta(t, CombinedDiffMetric(channelDiffs, 5), ChannelWeightedDiffMetric(channelDiffs, 5, [4]float32{2, 2, 2, 2}), 0.00001)
+}
+
+func TestChannelWeightedDiffMetric_IgnoredChannel_DoesNotCount(t *testing.T) {
+	alphaOnly := [4]int{0, 0, 0, 255}
+	assert.Equal(t, float32(0), ChannelWeightedDiffMetric(alphaOnly, 50, [4]float32{1, 1, 1, 0}))
+	assert.Greater(t, ChannelWeightedDiffMetric(alphaOnly, 50, [4]float32{1, 1, 1, 1}), float32(0))
+}
+
+func TestMetricConfig_Validate(t *testing.T) {
+	assert.NoError(t, MetricConfig{}.Validate())
+	assert.NoError(t, MetricConfig{Metric: MetricSSIM}.Validate())
+	assert.NoError(t, MetricConfig{Metric: MetricChannelWeighted, ChannelWeights: [4]float32{1, 1, 1, 0}}.Validate())
+	assert.Error(t, MetricConfig{Metric: MetricChannelWeighted}.Validate())
+	assert.Error(t, MetricConfig{Metric: MetricChannelWeighted, ChannelWeights: [4]float32{1, 1, 1, -1}}.Validate())
+	assert.Error(t, MetricConfig{Metric: "not a metric"}.Validate())
+}
+
+// solid returns an image of the given size filled with c.
+func solid(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
@@ -0,0 +1,80 @@
+package diff
+
+import (
+	"image"
+	"math/bits"
+)
+
+const (
+	// The perceptual hash is computed from a grayscale thumbnail of this size. Each row produces
+	// phashWidth-1 bits, so the hash fits exactly in 64 bits.
+	phashWidth  = 9
+	phashHeight = 8
+)
+
+// PerceptualHash returns a 64 bit "difference hash" of the given image. Visually similar images
+// (e.g. ones that differ only by anti-aliasing, a few pixels or a small shift in color) have
+// hashes that differ in few bits, regardless of the dimensions of the images. Use
+// PerceptualHashDistance to compare two hashes. Transparent pixels are treated as if they were
+// drawn on a white background.
+func PerceptualHash(img *image.NRGBA) uint64 {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return 0
+	}
+	// Downscale to a phashWidth x phashHeight grayscale image by averaging all the pixels that
+	// fall into each cell. For images smaller than the thumbnail, some pixels contribute to
+	// multiple cells.
+	var thumb [phashHeight][phashWidth]float64
+	for ty := 0; ty < phashHeight; ty++ {
+		y0, y1 := cellBounds(ty, phashHeight, b.Dy())
+		for tx := 0; tx < phashWidth; tx++ {
+			x0, x1 := cellBounds(tx, phashWidth, b.Dx())
+			sum := 0.0
+			for y := y0; y < y1; y++ {
+				row := img.Pix[y*img.Stride:]
+				for x := x0; x < x1; x++ {
+					sum += luminance(row[x*4 : x*4+4])
+				}
+			}
+			thumb[ty][tx] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	var hash uint64
+	for ty := 0; ty < phashHeight; ty++ {
+		for tx := 0; tx < phashWidth-1; tx++ {
+			hash <<= 1
+			if thumb[ty][tx] > thumb[ty][tx+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// PerceptualHashDistance returns the number of bits that differ between the two hashes. A
+// distance of 0 means the images are likely visually identical; distances above 10 or so
+// usually indicate unrelated images.
+func PerceptualHashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// cellBounds returns the half-open range of source pixels [start, end) that correspond to the
+// given cell when dividing size pixels into numCells. The returned range is never empty.
+func cellBounds(cell, numCells, size int) (int, int) {
+	start := cell * size / numCells
+	end := (cell + 1) * size / numCells
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// luminance returns the perceived brightness of a non-premultiplied RGBA pixel, composited over
+// a white background.
+func luminance(p []uint8) float64 {
+	l := 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+	a := float64(p[3]) / 255
+	return l*a + 255*(1-a)
+}
@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerceptualHash_SameImage_ZeroDistance(t *testing.T) {
+	img := gradient(64, 48, false)
+	assert.Equal(t, 0, PerceptualHashDistance(PerceptualHash(img), PerceptualHash(img)))
+}
+
+func TestPerceptualHash_SmallChange_SmallDistance(t *testing.T) {
+	a := gradient(64, 48, false)
+	b := gradient(64, 48, false)
+	// Change a handful of pixels, similar to a small rendering difference.
+	for x := 10; x < 14; x++ {
+		b.SetNRGBA(x, 20, color.NRGBA{R: 0, G: 0, B: 0, A: 0xff})
+	}
+	assert.LessOrEqual(t, PerceptualHashDistance(PerceptualHash(a), PerceptualHash(b)), 2)
+}
+
+func TestPerceptualHash_DifferentSize_SameHash(t *testing.T) {
+	a := gradient(64, 48, false)
+	b := gradient(128, 96, false)
+	assert.Equal(t, PerceptualHash(a), PerceptualHash(b))
+}
+
+func TestPerceptualHash_DifferentImages_LargeDistance(t *testing.T) {
+	a := gradient(64, 48, false)
+	b := gradient(64, 48, true)
+	assert.Greater(t, PerceptualHashDistance(PerceptualHash(a), PerceptualHash(b)), 32)
+}
+
+func TestPerceptualHash_TinyAndEmptyImages_DoNotPanic(t *testing.T) {
+	assert.Equal(t, uint64(0), PerceptualHash(image.NewNRGBA(image.Rect(0, 0, 0, 0))))
+	one := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	one.SetNRGBA(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+	assert.Equal(t, uint64(0), PerceptualHash(one))
+}
+
+// gradient returns an opaque grayscale image that gets brighter from left to right. If reverse
+// is set, it gets darker instead.
+func gradient(w, h int, reverse bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / (w - 1))
+			if reverse {
+				v = 255 - v
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 0xff})
+		}
+	}
+	return img
+}
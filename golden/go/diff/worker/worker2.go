@@ -67,17 +67,22 @@ type WorkerImpl struct {
 	badDigestsCache *ttlcache.Cache
 	windowSize      int
 
+	// computePerceptualMetrics enables computing and storing the SSIM and delta E of each diff.
+	computePerceptualMetrics bool
+
 	inputDigestsSummary      metrics2.Float64SummaryMetric
 	digestsOfInterestSummary metrics2.Float64SummaryMetric
 	metricsCalculatedCounter metrics2.Counter
 }
 
-// New returns a diff worker which uses the provided ImageSource.
-func New(db *pgxpool.Pool, src ImageSource, windowSize int) *WorkerImpl {
+// New returns a diff worker which uses the provided ImageSource. If computePerceptualMetrics is
+// true, it also computes the SSIM and delta E of each diff, which costs significantly more CPU.
+func New(db *pgxpool.Pool, src ImageSource, windowSize int, computePerceptualMetrics bool) *WorkerImpl {
 	return &WorkerImpl{
 		db:                       db,
 		imageSource:              src,
 		windowSize:               windowSize,
+		computePerceptualMetrics: computePerceptualMetrics,
 		badDigestsCache:          ttlcache.New(badImageCooldown, 2*badImageCooldown),
 		metricsCalculatedCounter: metrics2.GetCounter("diffcalculator_metricscalculated"),
 		inputDigestsSummary:      metrics2.GetFloat64SummaryMetric("diffcalculator_inputdigests"),
@@ -401,7 +406,48 @@ func (w *WorkerImpl) computeDiffsInParallel(ctx context.Context, work []digestPa
 		w.metricsCalculatedCounter.Inc(int64(len(metricsBuffer)))
 		return nil
 	})
-	return skerr.Wrap(err)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	return skerr.Wrap(w.writePerceptualHashes(ctx, imgCache))
+}
+
+// writePerceptualHashes computes the perceptual hash for every image that was decoded while
+// computing diffs and stores them in the PerceptualHashes table. This makes it possible to
+// search for similar images across all groupings.
+func (w *WorkerImpl) writePerceptualHashes(ctx context.Context, imgCache *lru.Cache) error {
+	ctx, span := trace.StartSpan(ctx, "writePerceptualHashes")
+	defer span.End()
+	var rows []schema.PerceptualHashRow
+	for _, key := range imgCache.Keys() {
+		img, ok := imgCache.Peek(key)
+		if !ok {
+			continue
+		}
+		db, err := sql.DigestToBytes(types.Digest(key.(string)))
+		if err != nil {
+			continue
+		}
+		rows = append(rows, schema.PerceptualHashRow{
+			Digest: db,
+			Hash:   int64(diff.PerceptualHash(img.(*image.NRGBA))),
+		})
+	}
+	span.AddAttributes(trace.Int64Attribute("num_hashes", int64(len(rows))))
+	if len(rows) == 0 {
+		return nil
+	}
+	const baseStatement = `UPSERT INTO PerceptualHashes (digest, hash) VALUES `
+	const valuesPerRow = 2
+	arguments := make([]interface{}, 0, len(rows)*valuesPerRow)
+	for _, r := range rows {
+		arguments = append(arguments, r.Digest, r.Hash)
+	}
+	vp := sqlutil.ValuesPlaceholders(valuesPerRow, len(rows))
+	if _, err := w.db.Exec(ctx, baseStatement+vp, arguments...); err != nil {
+		return skerr.Wrapf(err, "writing %d perceptual hashes to SQL", len(rows))
+	}
+	return nil
 }
 
 // diff calculates the difference between the two images with the provided digests and returns
@@ -426,10 +472,22 @@ func (w *WorkerImpl) diff(ctx context.Context, left, right types.Digest) (schema
 	if err != nil {
 		return schema.DiffMetricRow{}, &imgError{digest: right, err: skerr.Wrap(err)}
 	}
+	if !w.computePerceptualMetrics {
+		m := diff.ComputePixelDiffMetrics(leftImg, rightImg)
+		return newDiffMetricRow(ctx, lb, rb, m), nil
+	}
 	m := diff.ComputeDiffMetrics(leftImg, rightImg)
+	row := newDiffMetricRow(ctx, lb, rb, m)
+	row.SSIM = &m.SSIM
+	row.DeltaE = &m.DeltaE
+	return row, nil
+}
+
+// newDiffMetricRow returns the row for the given metrics, without the perceptual metrics.
+func newDiffMetricRow(ctx context.Context, left, right schema.DigestBytes, m *diff.DiffMetrics) schema.DiffMetricRow {
 	return schema.DiffMetricRow{
-		LeftDigest:        lb,
-		RightDigest:       rb,
+		LeftDigest:        left,
+		RightDigest:       right,
 		NumPixelsDiff:     m.NumDiffPixels,
 		PercentPixelsDiff: m.PixelDiffPercent,
 		MaxRGBADiffs:      m.MaxRGBADiffs,
@@ -437,7 +495,7 @@ func (w *WorkerImpl) diff(ctx context.Context, left, right types.Digest) (schema
 		CombinedMetric:    m.CombinedMetric,
 		DimensionsDiffer:  m.DimDiffer,
 		Timestamp:         now.Now(ctx),
-	}, nil
+	}
 }
 
 func max(diffs [4]int) int {
@@ -481,17 +539,24 @@ func (w *WorkerImpl) getDecodedImage(ctx context.Context, digest types.Digest) (
 }
 
 // writeMetrics writes two copies of the provided metrics (one for left-right and one for
-// right-left) to the SQL database.
+// right-left) to the SQL database. The ssim and delta_e columns are only written if the perceptual
+// metrics are enabled, so that instances which don't use them don't need the columns.
 func (w *WorkerImpl) writeMetrics(ctx context.Context, metrics []schema.DiffMetricRow) error {
 	if len(metrics) == 0 {
 		return nil
 	}
 	ctx, span := trace.StartSpan(ctx, "writeMetrics")
 	defer span.End()
-	const baseStatement = `UPSERT INTO DiffMetrics
+	baseStatement := `UPSERT INTO DiffMetrics
 (left_digest, right_digest, num_pixels_diff, percent_pixels_diff, max_rgba_diffs,
 max_channel_diff, combined_metric, dimensions_differ, ts) VALUES `
-	const valuesPerRow = 9
+	valuesPerRow := 9
+	if w.computePerceptualMetrics {
+		baseStatement = `UPSERT INTO DiffMetrics
+(left_digest, right_digest, num_pixels_diff, percent_pixels_diff, max_rgba_diffs,
+max_channel_diff, combined_metric, dimensions_differ, ts, ssim, delta_e) VALUES `
+		valuesPerRow = 11
+	}
 
 	arguments := make([]interface{}, 0, len(metrics)*valuesPerRow*2)
 	count := 0
@@ -501,8 +566,14 @@ max_channel_diff, combined_metric, dimensions_differ, ts) VALUES `
 		copy(rgba, r.MaxRGBADiffs[:])
 		arguments = append(arguments, r.LeftDigest, r.RightDigest, r.NumPixelsDiff, r.PercentPixelsDiff, rgba,
 			r.MaxChannelDiff, r.CombinedMetric, r.DimensionsDiffer, r.Timestamp)
+		if w.computePerceptualMetrics {
+			arguments = append(arguments, r.SSIM, r.DeltaE)
+		}
 		arguments = append(arguments, r.RightDigest, r.LeftDigest, r.NumPixelsDiff, r.PercentPixelsDiff, rgba,
 			r.MaxChannelDiff, r.CombinedMetric, r.DimensionsDiffer, r.Timestamp)
+		if w.computePerceptualMetrics {
+			arguments = append(arguments, r.SSIM, r.DeltaE)
+		}
 	}
 	vp := sqlutil.ValuesPlaceholders(valuesPerRow, count)
 	_, err := w.db.Exec(ctx, baseStatement+vp, arguments...)
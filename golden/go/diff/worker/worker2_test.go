@@ -20,6 +20,7 @@ import (
 	"go.goldmine.build/go/now"
 	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/golden/go/diff"
 	"go.goldmine.build/golden/go/diff/mocks"
 	"go.goldmine.build/golden/go/sql"
 	dks "go.goldmine.build/golden/go/sql/datakitchensink"
@@ -64,6 +65,47 @@ func TestWorkerImpl_CalculateDiffs_NoExistingData_Success(t *testing.T) {
 	assert.Empty(t, getAllProblemImageRows(t, db))
 }
 
+func TestWorkerImpl_CalculateDiffs_PerceptualMetricsEnabled_SSIMAndDeltaEWritten(t *testing.T) {
+	fakeNow := time.Date(2021, time.February, 1, 1, 1, 1, 0, time.UTC)
+	ctx := context.WithValue(context.Background(), now.ContextKey, fakeNow)
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	waitForSystemTime()
+	w := New(db, &fsImageSource{root: kitchenSinkRoot(t)}, 200, true)
+
+	grouping := paramtools.Params{
+		types.CorpusField:     "not used",
+		types.PrimaryKeyField: "not used",
+	}
+	require.NoError(t, w.CalculateDiffs(ctx, grouping, []types.Digest{dks.DigestA01Pos, dks.DigestA02Pos}))
+
+	assert.Equal(t, []schema.DiffMetricRow{
+		expectedWithPerceptualFromKS(t, dks.DigestA01Pos, dks.DigestA02Pos, fakeNow),
+		expectedWithPerceptualFromKS(t, dks.DigestA02Pos, dks.DigestA01Pos, fakeNow),
+	}, getAllDiffMetricRows(t, db))
+}
+
+func TestWorkerImpl_CalculateDiffs_PerceptualHashesWritten(t *testing.T) {
+
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	waitForSystemTime()
+	w := newWorker2UsingImagesFromKitchenSink(t, db)
+
+	grouping := paramtools.Params{
+		types.CorpusField:     "not used",
+		types.PrimaryKeyField: "not used",
+	}
+	imagesToCalculateDiffsFor := []types.Digest{dks.DigestA01Pos, dks.DigestA02Pos, dks.DigestA04Unt}
+	require.NoError(t, w.CalculateDiffs(ctx, grouping, imagesToCalculateDiffsFor))
+
+	rows := sqltest.GetAllRows(ctx, t, db, "PerceptualHashes", &schema.PerceptualHashRow{}).([]schema.PerceptualHashRow)
+	require.Len(t, rows, len(imagesToCalculateDiffsFor))
+	for _, r := range rows {
+		// Images that are identical except for a few pixels should have nearby hashes.
+		assert.LessOrEqual(t, diff.PerceptualHashDistance(uint64(r.Hash), uint64(rows[0].Hash)), 10)
+	}
+}
+
 func TestWorkerImpl_CalculateDiffs_ReadFromPrimaryBranch_Success(t *testing.T) {
 
 	fakeNow := time.Date(2021, time.February, 1, 1, 1, 1, 0, time.UTC)
@@ -174,7 +216,7 @@ func TestWorkerImpl_CalculateDiffs_ReadFromPrimaryBranch_SparseData_Success(t *t
 	sparseData := makeSparseData()
 	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, sparseData))
 	waitForSystemTime()
-	w := New(db, &fsImageSource{root: kitchenSinkRoot(t)}, 3)
+	w := New(db, &fsImageSource{root: kitchenSinkRoot(t)}, 3, false)
 
 	grouping := paramtools.Params{
 		types.CorpusField:     dks.RoundCorpus,
@@ -219,7 +261,7 @@ func TestWorkerImpl_CalculateDiffs_ImageNotFound_PartialData(t *testing.T) {
 	mis.On("GetImage", testutils.AnyContext, dks.DigestA02Pos).Return(b02, nil)
 	mis.On("GetImage", testutils.AnyContext, dks.DigestA04Unt).Return(nil, errors.New("not found"))
 
-	w := New(db, mis, 2)
+	w := New(db, mis, 2, false)
 
 	grouping := paramtools.Params{
 		types.CorpusField:     "not used",
@@ -270,7 +312,7 @@ func TestWorkerImpl_CalculateDiffs_CorruptedImage_PartialData(t *testing.T) {
 	mis.On("GetImage", testutils.AnyContext, dks.DigestA02Pos).Return(b02, nil)
 	mis.On("GetImage", testutils.AnyContext, dks.DigestA04Unt).Return([]byte(`not a png`), nil)
 
-	w := New(db, mis, 2)
+	w := New(db, mis, 2, false)
 
 	grouping := paramtools.Params{
 		types.CorpusField:     "not used",
@@ -303,7 +345,7 @@ func TestWorkerImpl_GetTriagedDigests_Success(t *testing.T) {
 	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
 	waitForSystemTime()
 
-	w := New(db, nil, 100)
+	w := New(db, nil, 100, false)
 
 	squareGrouping := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
 	triangleGrouping := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.TriangleTest}
@@ -336,7 +378,7 @@ func TestWorkerImpl_GetCommonAndRecentDigests_SmokeTest(t *testing.T) {
 	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
 	waitForSystemTime()
 
-	w := New(db, nil, 100)
+	w := New(db, nil, 100, false)
 
 	squareGrouping := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
 
@@ -350,14 +392,23 @@ func TestWorkerImpl_GetCommonAndRecentDigests_SmokeTest(t *testing.T) {
 }
 
 func newWorker2UsingImagesFromKitchenSink(t *testing.T, db *pgxpool.Pool) *WorkerImpl {
-	return New(db, &fsImageSource{root: kitchenSinkRoot(t)}, 200)
+	return New(db, &fsImageSource{root: kitchenSinkRoot(t)}, 200, false)
 }
 
 var kitchenSinkData = dks.Build()
 
-// expectedFromKS returns the computed diff metric from the kitchen sink data. It replaces the
-// default timestamp with the provided timestamp.
+// expectedFromKS returns the computed diff metric from the kitchen sink data, without the
+// perceptual metrics, which the worker only computes if asked to. It replaces the default
+// timestamp with the provided timestamp.
 func expectedFromKS(t *testing.T, left types.Digest, right types.Digest, ts time.Time) schema.DiffMetricRow {
+	row := expectedWithPerceptualFromKS(t, left, right, ts)
+	row.SSIM, row.DeltaE = nil, nil
+	return row
+}
+
+// expectedWithPerceptualFromKS is like expectedFromKS, except that it keeps the perceptual
+// metrics.
+func expectedWithPerceptualFromKS(t *testing.T, left types.Digest, right types.Digest, ts time.Time) schema.DiffMetricRow {
 	leftB := d(left)
 	rightB := d(right)
 	for _, row := range kitchenSinkData.DiffMetrics {
@@ -0,0 +1,215 @@
+package ignore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"go.goldmine.build/email/go/emailclient"
+	"go.goldmine.build/go/metrics2"
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/sklog"
+	"go.goldmine.build/go/util"
+)
+
+// ExpiryNotifier tells the owner of an ignore rule that the rule is about to expire.
+type ExpiryNotifier interface {
+	// NotifyExpiring sends a single notification about the given rule.
+	NotifyExpiring(ctx context.Context, rule Rule) error
+}
+
+// ExpiryNotificationStore remembers which expiration times the owners of rules were notified
+// about, so that they are not notified again when the process restarts.
+type ExpiryNotificationStore interface {
+	// NotifiedExpirations returns the expiration time the owner of each rule was last notified
+	// about, keyed by rule ID.
+	NotifiedExpirations(ctx context.Context) (map[string]time.Time, error)
+
+	// MarkNotified records that the owner of the rule with the given ID was notified that the rule
+	// expires at the given time.
+	MarkNotified(ctx context.Context, id string, expires time.Time) error
+}
+
+// Owner returns the user responsible for the given rule, which is the last person who updated it.
+func Owner(rule Rule) string {
+	if rule.UpdatedBy != "" {
+		return rule.UpdatedBy
+	}
+	return rule.CreatedBy
+}
+
+// expiryMessage returns a human readable description of the rule that is about to expire.
+func expiryMessage(rule Rule, siteURL string) string {
+	return fmt.Sprintf(`The Gold ignore rule %q (note: %q) owned by %s expires at %s.
+If the rule is still needed, please extend it; otherwise it can be deleted.
+%s/ignores`, rule.Query, rule.Note, Owner(rule), rule.Expires.UTC().Format(time.RFC3339), strings.TrimSuffix(siteURL, "/"))
+}
+
+// EmailNotifier emails the owner of a rule.
+type EmailNotifier struct {
+	client  emailclient.Client
+	from    string
+	siteURL string
+}
+
+// NewEmailNotifier returns an EmailNotifier which sends emails from the given address. siteURL
+// is the Gold instance the rules belong to, e.g. "https://gold.skia.org".
+func NewEmailNotifier(client emailclient.Client, from, siteURL string) *EmailNotifier {
+	return &EmailNotifier{client: client, from: from, siteURL: siteURL}
+}
+
+// NotifyExpiring implements the ExpiryNotifier interface. Rules whose owner is not an email
+// address (e.g. rules created by a service) are skipped.
+func (e *EmailNotifier) NotifyExpiring(ctx context.Context, rule Rule) error {
+	to := Owner(rule)
+	if !strings.Contains(to, "@") {
+		sklog.Infof("Not emailing owner %q of ignore rule %s", to, rule.ID)
+		return nil
+	}
+	subject := fmt.Sprintf("Gold ignore rule %q is about to expire", rule.Query)
+	// The email is sent as HTML.
+	body := strings.ReplaceAll(html.EscapeString(expiryMessage(rule, e.siteURL)), "\n", "<br>")
+	if _, err := e.client.SendWithMarkup("Gold", e.from, []string{to}, subject, body, "", ""); err != nil {
+		return skerr.Wrapf(err, "emailing %s about ignore rule %s", to, rule.ID)
+	}
+	return nil
+}
+
+// SlackNotifier posts to a Slack incoming webhook, mentioning the owner of the rule.
+type SlackNotifier struct {
+	client     *http.Client
+	webhookURL string
+	siteURL    string
+}
+
+// NewSlackNotifier returns a SlackNotifier which posts to the given incoming webhook URL.
+func NewSlackNotifier(client *http.Client, webhookURL, siteURL string) *SlackNotifier {
+	return &SlackNotifier{client: client, webhookURL: webhookURL, siteURL: siteURL}
+}
+
+// slackMessage is the payload accepted by Slack incoming webhooks.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// NotifyExpiring implements the ExpiryNotifier interface.
+func (s *SlackNotifier) NotifyExpiring(ctx context.Context, rule Rule) error {
+	b, err := json.Marshal(slackMessage{Text: expiryMessage(rule, s.siteURL)})
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(b))
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return skerr.Wrapf(err, "posting to Slack about ignore rule %s", rule.ID)
+	}
+	defer util.Close(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return skerr.Fmt("posting to Slack about ignore rule %s: status %s", rule.ID, resp.Status)
+	}
+	return nil
+}
+
+// MultiNotifier sends notifications with all of its ExpiryNotifiers.
+type MultiNotifier []ExpiryNotifier
+
+// NotifyExpiring implements the ExpiryNotifier interface. All notifiers are tried, even if some
+// fail.
+func (m MultiNotifier) NotifyExpiring(ctx context.Context, rule Rule) error {
+	var firstErr error
+	for _, n := range m {
+		if err := n.NotifyExpiring(ctx, rule); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// expiryWatcher notifies the owners of rules which will expire soon. It stores which rules it
+// has notified about, so each owner is only notified once per expiration time. If a rule is
+// extended, the owner will be notified again before the new expiration time.
+type expiryWatcher struct {
+	store         Store
+	notifications ExpiryNotificationStore
+	notifier      ExpiryNotifier
+	notifyBefore  time.Duration
+}
+
+// oneStep notifies the owners of all rules that expire within notifyBefore and that they have not
+// been notified about yet. It returns the number of notifications which were sent.
+func (e *expiryWatcher) oneStep(ctx context.Context) (int, error) {
+	ctx, span := trace.StartSpan(ctx, "ignore_notifyExpiringRules")
+	defer span.End()
+	rules, err := e.store.List(ctx)
+	if err != nil {
+		return 0, skerr.Wrap(err)
+	}
+	notified, err := e.notifications.NotifiedExpirations(ctx)
+	if err != nil {
+		return 0, skerr.Wrap(err)
+	}
+	ts := now.Now(ctx)
+	sent := 0
+	var firstErr error
+	for _, rule := range rules {
+		// Rules which have already expired are reported by the metrics; there is no point in
+		// nagging about them on every run.
+		if rule.Expires.Before(ts) || rule.Expires.After(ts.Add(e.notifyBefore)) {
+			continue
+		}
+		if exp, ok := notified[rule.ID]; ok && exp.Equal(rule.Expires) {
+			continue
+		}
+		if err := e.notifier.NotifyExpiring(ctx, rule); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		sent++
+		if err := e.notifications.MarkNotified(ctx, rule.ID, rule.Expires); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return sent, skerr.Wrap(firstErr)
+}
+
+// StartExpiryNotifications starts a routine which, every interval, notifies the owners of ignore
+// rules that will expire within notifyBefore. This should only be run by a single process per
+// instance, otherwise owners will be notified multiple times.
+func StartExpiryNotifications(ctx context.Context, store Store, notifications ExpiryNotificationStore, notifier ExpiryNotifier, notifyBefore, interval time.Duration) {
+	e := &expiryWatcher{
+		store:         store,
+		notifications: notifications,
+		notifier:      notifier,
+		notifyBefore:  notifyBefore,
+	}
+	numSent := metrics2.GetCounter("gold_ignore_rule_expiry_notifications_sent")
+	liveness := metrics2.NewLiveness("gold_ignore_rule_expiry_notifications")
+	go util.RepeatCtx(ctx, interval, func(ctx context.Context) {
+		n, err := e.oneStep(ctx)
+		numSent.Inc(int64(n))
+		if err != nil {
+			sklog.Errorf("Failed to notify owners of expiring ignore rules: %s", err)
+			return
+		}
+		liveness.Reset()
+	})
+}
+
+// Make sure the notifiers fulfill the ExpiryNotifier interface.
+var _ ExpiryNotifier = (*EmailNotifier)(nil)
+var _ ExpiryNotifier = (*SlackNotifier)(nil)
+var _ ExpiryNotifier = MultiNotifier(nil)
@@ -0,0 +1,145 @@
+package ignore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.goldmine.build/go/httputils"
+	"go.goldmine.build/go/now"
+)
+
+var notifyNow = time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func TestExpiryWatcher_OneStep_OnlyRulesExpiringSoonNotifiedOnce(t *testing.T) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, notifyNow)
+	store := &listStore{rules: []Rule{
+		{ID: "expires-soon", UpdatedBy: "alpha@example.com", Expires: notifyNow.Add(24 * time.Hour)},
+		{ID: "expires-later", UpdatedBy: "beta@example.com", Expires: notifyNow.Add(30 * 24 * time.Hour)},
+		{ID: "already-expired", UpdatedBy: "gamma@example.com", Expires: notifyNow.Add(-time.Hour)},
+	}}
+	n := &recordingNotifier{}
+	notifications := memoryNotificationStore{}
+	e := &expiryWatcher{store: store, notifications: notifications, notifier: n, notifyBefore: 7 * 24 * time.Hour}
+
+	sent, err := e.oneStep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"expires-soon"}, n.ids)
+
+	// Nothing changed, so nobody should be notified again.
+	sent, err = e.oneStep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// Not even after a restart.
+	restarted := &expiryWatcher{store: store, notifications: notifications, notifier: n, notifyBefore: 7 * 24 * time.Hour}
+	sent, err = restarted.oneStep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// The rule was extended, but will still expire soon.
+	store.rules[0].Expires = notifyNow.Add(48 * time.Hour)
+	sent, err = e.oneStep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"expires-soon", "expires-soon"}, n.ids)
+}
+
+func TestExpiryWatcher_OneStep_NotifierFails_RetriedNextStep(t *testing.T) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, notifyNow)
+	store := &listStore{rules: []Rule{
+		{ID: "expires-soon", UpdatedBy: "alpha@example.com", Expires: notifyNow.Add(24 * time.Hour)},
+	}}
+	n := &recordingNotifier{err: errors.New("email service down")}
+	e := &expiryWatcher{store: store, notifications: memoryNotificationStore{}, notifier: n, notifyBefore: 7 * 24 * time.Hour}
+
+	_, err := e.oneStep(ctx)
+	require.Error(t, err)
+
+	n.err = nil
+	sent, err := e.oneStep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestSlackNotifier_NotifyExpiring_PostsMessageWithOwnerAndQuery(t *testing.T) {
+	var received slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	client := httputils.DefaultClientConfig().WithoutRetries().With2xxOnly().Client()
+	n := NewSlackNotifier(client, srv.URL, "https://gold.example.com/")
+	require.NoError(t, n.NotifyExpiring(context.Background(), Rule{
+		ID:        "rule1",
+		CreatedBy: "alpha@example.com",
+		UpdatedBy: "beta@example.com",
+		Expires:   notifyNow,
+		Query:     "config=gles",
+		Note:      "skbug.com/1234",
+	}))
+	assert.Equal(t, `The Gold ignore rule "config=gles" (note: "skbug.com/1234") owned by beta@example.com expires at 2022-03-01T12:00:00Z.
+If the rule is still needed, please extend it; otherwise it can be deleted.
+https://gold.example.com/ignores`, received.Text)
+}
+
+func TestSlackNotifier_NotifyExpiring_Non2xxStatus_ReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	n := NewSlackNotifier(http.DefaultClient, srv.URL, "https://gold.example.com/")
+	err := n.NotifyExpiring(context.Background(), Rule{ID: "rule1", Expires: notifyNow})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+// listStore is a Store which only supports List.
+type listStore struct {
+	Store
+	rules []Rule
+}
+
+func (s *listStore) List(context.Context) ([]Rule, error) {
+	return s.rules, nil
+}
+
+// memoryNotificationStore is an ExpiryNotificationStore which keeps the notifications in memory.
+type memoryNotificationStore map[string]time.Time
+
+func (m memoryNotificationStore) NotifiedExpirations(context.Context) (map[string]time.Time, error) {
+	rv := make(map[string]time.Time, len(m))
+	for id, expires := range m {
+		rv[id] = expires
+	}
+	return rv, nil
+}
+
+func (m memoryNotificationStore) MarkNotified(_ context.Context, id string, expires time.Time) error {
+	m[id] = expires
+	return nil
+}
+
+type recordingNotifier struct {
+	ids []string
+	err error
+}
+
+func (r *recordingNotifier) NotifyExpiring(_ context.Context, rule Rule) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.ids = append(r.ids, rule.ID)
+	return nil
+}
@@ -4,12 +4,15 @@ package sqlignorestore
 import (
 	"context"
 	"net/url"
+	"time"
 
 	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbpgx"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.opencensus.io/trace"
 
+	"go.goldmine.build/go/now"
 	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/skerr"
 	"go.goldmine.build/golden/go/ignore"
@@ -223,6 +226,11 @@ func (s *StoreImpl) Delete(ctx context.Context, id string) error {
 	err = crdbpgx.ExecuteTx(ctx, s.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
 		_, err = tx.Exec(ctx, `
 DELETE FROM IgnoreRules WHERE ignore_rule_id = $1`, id)
+		if err != nil {
+			return err // Don't wrap - crdbpgx might retry
+		}
+		_, err = tx.Exec(ctx, `
+DELETE FROM IgnoreRuleExpiryNotifications WHERE ignore_rule_id = $1`, id)
 		return err // Don't wrap - crdbpgx might retry
 	})
 	// We could be updating a lot of traces and values at head here. If done as one big transaction,
@@ -274,5 +282,40 @@ func (s *StoreImpl) getOtherRules(ctx context.Context, id string) ([]paramtools.
 	return rules, nil
 }
 
-// Make sure Store fulfills the ignore.Store interface
+// NotifiedExpirations implements the ignore.ExpiryNotificationStore interface.
+func (s *StoreImpl) NotifiedExpirations(ctx context.Context) (map[string]time.Time, error) {
+	ctx, span := trace.StartSpan(ctx, "ignorestore_NotifiedExpirations")
+	defer span.End()
+	rows, err := s.db.Query(ctx, `SELECT ignore_rule_id, expires FROM IgnoreRuleExpiryNotifications`)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	rv := map[string]time.Time{}
+	for rows.Next() {
+		var id uuid.UUID
+		var expires time.Time
+		if err := rows.Scan(&id, &expires); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		rv[id.String()] = expires.UTC()
+	}
+	return rv, nil
+}
+
+// MarkNotified implements the ignore.ExpiryNotificationStore interface.
+func (s *StoreImpl) MarkNotified(ctx context.Context, id string, expires time.Time) error {
+	ctx, span := trace.StartSpan(ctx, "ignorestore_MarkNotified")
+	defer span.End()
+	_, err := s.db.Exec(ctx, `
+UPSERT INTO IgnoreRuleExpiryNotifications (ignore_rule_id, expires, notified_ts)
+VALUES ($1, $2, $3)`, id, expires, now.Now(ctx))
+	if err != nil {
+		return skerr.Wrapf(err, "marking owner of ignore rule %s as notified", id)
+	}
+	return nil
+}
+
+// Make sure Store fulfills the ignore.Store and ignore.ExpiryNotificationStore interfaces
 var _ ignore.Store = (*StoreImpl)(nil)
+var _ ignore.ExpiryNotificationStore = (*StoreImpl)(nil)
@@ -713,3 +713,27 @@ func TestList_Success(t *testing.T) {
 		Note:      "Taimen isn't drawing correctly enough yet",
 	}}, rules)
 }
+
+func TestMarkNotified_NotifiedExpirationsStoredAndRemovedWithRule(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	existingData := dks.Build()
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, existingData))
+	store := New(db)
+	taimenID := idForRule(existingData.IgnoreRules, "Taimen")
+	expires := time.Date(2030, time.December, 30, 15, 16, 17, 0, time.UTC)
+
+	notified, err := store.NotifiedExpirations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, notified)
+
+	require.NoError(t, store.MarkNotified(ctx, taimenID, expires))
+	notified, err = store.NotifiedExpirations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{taimenID: expires}, notified)
+
+	require.NoError(t, store.Delete(ctx, taimenID))
+	notified, err = store.NotifiedExpirations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, notified)
+}
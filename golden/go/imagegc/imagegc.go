@@ -0,0 +1,362 @@
+// Package imagegc deletes images, and the data derived from them, which are no longer referenced
+// by recent results or by any baseline.
+package imagegc
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbpgx"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opencensus.io/trace"
+
+	"go.goldmine.build/go/metrics2"
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/sklog"
+	"go.goldmine.build/go/util"
+	"go.goldmine.build/golden/go/config"
+	"go.goldmine.build/golden/go/sql"
+	"go.goldmine.build/golden/go/sql/schema"
+	"go.goldmine.build/golden/go/storage"
+	"go.goldmine.build/golden/go/types"
+	"go.goldmine.build/golden/go/validation"
+)
+
+const (
+	// deleteBatchSize is how many digests have their derived data deleted from the DB at once.
+	deleteBatchSize = 1000
+
+	// deleteParallelism is how many images are deleted from GCS concurrently.
+	deleteParallelism = 16
+)
+
+// Stats summarizes a single garbage collection run.
+type Stats struct {
+	// ImagesSeen is the number of images that were found in GCS.
+	ImagesSeen int
+	// ReferencedDigests is the number of digests that must be kept.
+	ReferencedDigests int
+	// ImagesDeleted is the number of images that were (or, in a dry run, would have been) deleted
+	// or archived.
+	ImagesDeleted int
+	// BytesReclaimed is the combined size of the deleted images.
+	BytesReclaimed int64
+	// DiffMetricsDeleted is the number of rows deleted from the DiffMetrics table.
+	DiffMetricsDeleted int64
+}
+
+// Collector finds images that are no longer needed and deletes them along with their diff metrics.
+type Collector struct {
+	db     *pgxpool.Pool
+	client storage.GCSClient
+	cfg    config.ImageGCConfig
+}
+
+// New returns a Collector that uses the given retention configuration.
+func New(db *pgxpool.Pool, client storage.GCSClient, cfg config.ImageGCConfig) *Collector {
+	return &Collector{
+		db:     db,
+		client: client,
+		cfg:    cfg,
+	}
+}
+
+// Collect runs one round of garbage collection. An image is kept if any of the following is true:
+//   - it was seen on the primary branch in the retention window of its corpus.
+//   - it has been triaged positive or negative on any branch (i.e. it is part of a baseline).
+//   - it was produced by a CL that is open or that had data ingested within the changelist
+//     retention period.
+//   - it was uploaded within the grace period.
+//
+// All other images are deleted from GCS (or moved to the archive bucket, if one is configured),
+// along with any diff metrics and perceptual hashes which were computed from them.
+func (c *Collector) Collect(ctx context.Context) (Stats, error) {
+	ctx, span := trace.StartSpan(ctx, "imagegc_Collect")
+	defer span.End()
+
+	stats, toDelete, err := c.findUnreferenced(ctx)
+	if err != nil {
+		return stats, skerr.Wrap(err)
+	}
+
+	if c.cfg.DryRun {
+		for _, info := range toDelete {
+			sklog.Debugf("dry run: would delete image %s", info.Digest)
+			stats.ImagesDeleted++
+			stats.BytesReclaimed += info.Size
+		}
+		return stats, nil
+	}
+
+	err = util.ChunkIter(len(toDelete), deleteBatchSize, func(startIdx int, endIdx int) error {
+		batch := toDelete[startIdx:endIdx]
+		// Delete the derived data first. If we crash before deleting the images, the next run will
+		// pick them up again.
+		n, err := c.deleteDerivedData(ctx, batch)
+		if err != nil {
+			return skerr.Wrap(err)
+		}
+		stats.DiffMetricsDeleted += n
+		err = util.ChunkIterParallelPool(ctx, len(batch), 1, deleteParallelism, func(ctx context.Context, startIdx, endIdx int) error {
+			digest := batch[startIdx].Digest
+			if c.cfg.ArchiveBucket != "" {
+				return skerr.Wrap(c.client.ArchiveImage(ctx, digest, c.cfg.ArchiveBucket))
+			}
+			return skerr.Wrap(c.client.DeleteImage(ctx, digest))
+		})
+		if err != nil {
+			return skerr.Wrap(err)
+		}
+		for _, info := range batch {
+			stats.ImagesDeleted++
+			stats.BytesReclaimed += info.Size
+		}
+		return nil
+	})
+	return stats, skerr.Wrap(err)
+}
+
+// Report describes the images that a garbage collection run would delete.
+type Report struct {
+	Stats
+	// Unreferenced are the images that would be deleted, largest first.
+	Unreferenced []storage.ImageInfo
+}
+
+// Report finds the images that Collect would delete, without deleting anything, regardless of
+// whether the Collector is configured as a dry run. At most maxImages images are returned in the
+// Report, but the Stats account for all of them.
+func (c *Collector) Report(ctx context.Context, maxImages int) (Report, error) {
+	ctx, span := trace.StartSpan(ctx, "imagegc_Report")
+	defer span.End()
+
+	stats, unreferenced, err := c.findUnreferenced(ctx)
+	if err != nil {
+		return Report{}, skerr.Wrap(err)
+	}
+	for _, info := range unreferenced {
+		stats.ImagesDeleted++
+		stats.BytesReclaimed += info.Size
+	}
+	sort.Slice(unreferenced, func(i, j int) bool {
+		if unreferenced[i].Size != unreferenced[j].Size {
+			return unreferenced[i].Size > unreferenced[j].Size
+		}
+		return unreferenced[i].Digest < unreferenced[j].Digest
+	})
+	if len(unreferenced) > maxImages {
+		unreferenced = unreferenced[:maxImages]
+	}
+	return Report{
+		Stats:        stats,
+		Unreferenced: unreferenced,
+	}, nil
+}
+
+// findUnreferenced returns the images which are not referenced and which were uploaded before the
+// grace period. The returned Stats have ImagesSeen and ReferencedDigests filled in.
+func (c *Collector) findUnreferenced(ctx context.Context) (Stats, []storage.ImageInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "findUnreferenced")
+	defer span.End()
+
+	var stats Stats
+	referenced, err := c.getReferencedDigests(ctx)
+	if err != nil {
+		return stats, nil, skerr.Wrap(err)
+	}
+	stats.ReferencedDigests = len(referenced)
+	if len(referenced) == 0 {
+		// This is almost certainly a misconfiguration or a problem with the DB. Deleting every image
+		// would be catastrophic, so bail out.
+		return stats, nil, skerr.Fmt("no referenced digests found; refusing to delete all images")
+	}
+
+	uploadedBefore := now.Now(ctx).Add(-c.cfg.GracePeriod.Duration)
+	var unreferenced []storage.ImageInfo
+	err = c.client.ListImages(ctx, func(info storage.ImageInfo) error {
+		stats.ImagesSeen++
+		if !validation.IsValidDigest(string(info.Digest)) {
+			return nil
+		}
+		if referenced[info.Digest] || info.Created.After(uploadedBefore) {
+			return nil
+		}
+		unreferenced = append(unreferenced, info)
+		return nil
+	})
+	if err != nil {
+		return stats, nil, skerr.Wrapf(err, "listing images")
+	}
+	sklog.Infof("Found %d unreferenced images out of %d (%d digests referenced)", len(unreferenced), stats.ImagesSeen, stats.ReferencedDigests)
+	return stats, unreferenced, nil
+}
+
+// getReferencedDigests returns all the digests that should be kept, regardless of when the
+// corresponding images were uploaded.
+func (c *Collector) getReferencedDigests(ctx context.Context) (map[types.Digest]bool, error) {
+	ctx, span := trace.StartSpan(ctx, "getReferencedDigests")
+	defer span.End()
+
+	rv := map[types.Digest]bool{}
+	overriddenCorpora := make([]string, 0, len(c.cfg.CorpusRetentionCommits))
+	for corpus, numCommits := range c.cfg.CorpusRetentionCommits {
+		overriddenCorpora = append(overriddenCorpora, corpus)
+		tile, err := c.getFirstTileInWindow(ctx, numCommits)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		const statement = `SELECT DISTINCT encode(TiledTraceDigests.digest, 'hex') FROM TiledTraceDigests
+JOIN Groupings ON TiledTraceDigests.grouping_id = Groupings.grouping_id
+AS OF SYSTEM TIME '-0.1s'
+WHERE TiledTraceDigests.tile_id >= $1 AND Groupings.keys->>'source_type' = $2`
+		if err := c.addDigests(ctx, rv, statement, tile, corpus); err != nil {
+			return nil, skerr.Wrapf(err, "corpus %s", corpus)
+		}
+	}
+
+	tile, err := c.getFirstTileInWindow(ctx, c.cfg.RetentionCommits)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	const primaryStatement = `SELECT DISTINCT encode(TiledTraceDigests.digest, 'hex') FROM TiledTraceDigests
+JOIN Groupings ON TiledTraceDigests.grouping_id = Groupings.grouping_id
+AS OF SYSTEM TIME '-0.1s'
+WHERE TiledTraceDigests.tile_id >= $1 AND NOT (Groupings.keys->>'source_type' = ANY($2))`
+	if err := c.addDigests(ctx, rv, primaryStatement, tile, overriddenCorpora); err != nil {
+		return nil, skerr.Wrapf(err, "primary branch")
+	}
+
+	// Digests triaged positive or negative on any branch are part of some baseline.
+	const baselineStatement = `SELECT DISTINCT encode(digest, 'hex') FROM Expectations
+AS OF SYSTEM TIME '-0.1s'
+WHERE label != 'u'`
+	if err := c.addDigests(ctx, rv, baselineStatement); err != nil {
+		return nil, skerr.Wrapf(err, "baselines")
+	}
+	const secondaryBaselineStatement = `SELECT DISTINCT encode(digest, 'hex') FROM SecondaryBranchExpectations
+AS OF SYSTEM TIME '-0.1s'
+WHERE label != 'u'`
+	if err := c.addDigests(ctx, rv, secondaryBaselineStatement); err != nil {
+		return nil, skerr.Wrapf(err, "secondary branch baselines")
+	}
+
+	const changelistStatement = `SELECT DISTINCT encode(SecondaryBranchValues.digest, 'hex') FROM SecondaryBranchValues
+JOIN Changelists ON SecondaryBranchValues.branch_name = Changelists.changelist_id
+AS OF SYSTEM TIME '-0.1s'
+WHERE Changelists.status = 'open' OR Changelists.last_ingested_data > $1`
+	clCutoff := now.Now(ctx).Add(-c.cfg.ChangelistRetention.Duration)
+	if err := c.addDigests(ctx, rv, changelistStatement, clCutoff); err != nil {
+		return nil, skerr.Wrapf(err, "changelists")
+	}
+	return rv, nil
+}
+
+// getFirstTileInWindow returns the tile that contains the oldest of the most recent numCommits
+// commits with data. Because whole tiles are kept, images are retained for at least numCommits.
+func (c *Collector) getFirstTileInWindow(ctx context.Context, numCommits int) (schema.TileID, error) {
+	ctx, span := trace.StartSpan(ctx, "getFirstTileInWindow")
+	defer span.End()
+	const statement = `WITH
+RecentCommits AS (
+	SELECT tile_id, commit_id FROM CommitsWithData
+	AS OF SYSTEM TIME '-0.1s'
+	ORDER BY commit_id DESC LIMIT $1
+)
+SELECT COALESCE(MIN(tile_id), 0) FROM RecentCommits`
+	row := c.db.QueryRow(ctx, statement, numCommits)
+	var tileID schema.TileID
+	if err := row.Scan(&tileID); err != nil {
+		return 0, skerr.Wrap(err)
+	}
+	return tileID, nil
+}
+
+// addDigests runs the given statement, which must return a single column of hex-encoded digests,
+// and adds the results to digests.
+func (c *Collector) addDigests(ctx context.Context, digests map[types.Digest]bool, statement string, args ...interface{}) error {
+	rows, err := c.db.Query(ctx, statement, args...)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d types.Digest
+		if err := rows.Scan(&d); err != nil {
+			return skerr.Wrap(err)
+		}
+		digests[d] = true
+	}
+	return skerr.Wrap(rows.Err())
+}
+
+// deleteDerivedData deletes the diff metrics and perceptual hashes of the given images. It returns
+// the number of diff metrics rows that were deleted.
+func (c *Collector) deleteDerivedData(ctx context.Context, images []storage.ImageInfo) (int64, error) {
+	ctx, span := trace.StartSpan(ctx, "deleteDerivedData")
+	defer span.End()
+	digests := make([]schema.DigestBytes, 0, len(images))
+	for _, info := range images {
+		b, err := sql.DigestToBytes(info.Digest)
+		if err != nil {
+			return 0, skerr.Wrap(err)
+		}
+		digests = append(digests, b)
+	}
+	// DiffMetrics are written in both directions, so we can use the primary key to find the rows
+	// where the deleted digests are on the right.
+	const deleteRightStatement = `DELETE FROM DiffMetrics
+WHERE (left_digest, right_digest) IN (
+	SELECT right_digest, left_digest FROM DiffMetrics WHERE left_digest = ANY($1)
+)`
+	const deleteLeftStatement = `DELETE FROM DiffMetrics WHERE left_digest = ANY($1)`
+	const deleteHashesStatement = `DELETE FROM PerceptualHashes WHERE digest = ANY($1)`
+	var deleted int64
+	err := crdbpgx.ExecuteTx(ctx, c.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		deleted = 0
+		for _, statement := range []string{deleteRightStatement, deleteLeftStatement} {
+			tag, err := tx.Exec(ctx, statement, digests)
+			if err != nil {
+				return err // Don't wrap - crdbpgx might retry
+			}
+			deleted += tag.RowsAffected()
+		}
+		_, err := tx.Exec(ctx, deleteHashesStatement, digests)
+		return err // Don't wrap - crdbpgx might retry
+	})
+	return deleted, skerr.Wrap(err)
+}
+
+// StartPeriodic runs Collect at the configured period until the context is cancelled, recording
+// metrics about each run.
+func (c *Collector) StartPeriodic(ctx context.Context) {
+	liveness := metrics2.NewLiveness("periodic_tasks", map[string]string{
+		"task": "imageGC",
+	})
+	tags := map[string]string{"dry_run": strconv.FormatBool(c.cfg.DryRun)}
+	deletedImages := metrics2.GetCounter("gold_gc_deleted_images", tags)
+	reclaimedBytes := metrics2.GetCounter("gold_gc_reclaimed_bytes", tags)
+	deletedDiffMetrics := metrics2.GetCounter("gold_gc_deleted_diff_metrics", tags)
+	seenImages := metrics2.GetInt64Metric("gold_gc_seen_images", tags)
+	referencedDigests := metrics2.GetInt64Metric("gold_gc_referenced_digests", tags)
+	go util.RepeatCtx(ctx, c.cfg.Period.Duration, func(ctx context.Context) {
+		sklog.Infof("Garbage collecting unreferenced images")
+		start := time.Now()
+		stats, err := c.Collect(ctx)
+		// Report progress even on failure, since some batches may have been deleted.
+		deletedImages.Inc(int64(stats.ImagesDeleted))
+		reclaimedBytes.Inc(stats.BytesReclaimed)
+		deletedDiffMetrics.Inc(stats.DiffMetricsDeleted)
+		if err != nil {
+			sklog.Errorf("Error garbage collecting images: %s", err)
+			return // return so the liveness is not updated
+		}
+		seenImages.Update(int64(stats.ImagesSeen))
+		referencedDigests.Update(int64(stats.ReferencedDigests))
+		liveness.Reset()
+		sklog.Infof("Done garbage collecting images in %s: %+v", time.Since(start), stats)
+	})
+}
@@ -0,0 +1,245 @@
+package imagegc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/golden/go/config"
+	"go.goldmine.build/golden/go/mocks"
+	"go.goldmine.build/golden/go/sql"
+	dks "go.goldmine.build/golden/go/sql/datakitchensink"
+	"go.goldmine.build/golden/go/sql/schema"
+	"go.goldmine.build/golden/go/sql/sqltest"
+	"go.goldmine.build/golden/go/storage"
+	"go.goldmine.build/golden/go/types"
+)
+
+var (
+	fakeNow = time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	alphaKeys = paramtools.Params{types.CorpusField: "alpha", types.PrimaryKeyField: "a"}
+	betaKeys  = paramtools.Params{types.CorpusField: "beta", types.PrimaryKeyField: "b"}
+)
+
+func TestCollect_UnreferencedImagesDeleted(t *testing.T) {
+	ctx, db := setupTestData(t)
+	client := mocks.NewGCSClient(t)
+	mockImages(client, testImages())
+	for _, digest := range []types.Digest{dks.DigestA02Pos, dks.DigestA04Unt, dks.DigestC02Pos} {
+		client.On("DeleteImage", testutils.AnyContext, digest).Return(nil)
+	}
+
+	stats, err := New(db, client, testConfig()).Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{
+		ImagesSeen:         10,
+		ReferencedDigests:  5,
+		ImagesDeleted:      3,
+		BytesReclaimed:     200 + 400 + 700,
+		DiffMetricsDeleted: 2,
+	}, stats)
+
+	actualMetrics := sqltest.GetAllRows(ctx, t, db, "DiffMetrics", &schema.DiffMetricRow{}).([]schema.DiffMetricRow)
+	require.Len(t, actualMetrics, 2)
+	for _, row := range actualMetrics {
+		assert.NotEqual(t, d(t, dks.DigestA02Pos), row.LeftDigest)
+		assert.NotEqual(t, d(t, dks.DigestA02Pos), row.RightDigest)
+	}
+	actualHashes := sqltest.GetAllRows(ctx, t, db, "PerceptualHashes", &schema.PerceptualHashRow{})
+	assert.Equal(t, []schema.PerceptualHashRow{{Digest: d(t, dks.DigestA01Pos), Hash: 1}}, actualHashes)
+}
+
+func TestCollect_DryRun_NothingDeleted(t *testing.T) {
+	ctx, db := setupTestData(t)
+	client := mocks.NewGCSClient(t)
+	mockImages(client, testImages())
+	cfg := testConfig()
+	cfg.DryRun = true
+
+	stats, err := New(db, client, cfg).Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{
+		ImagesSeen:        10,
+		ReferencedDigests: 5,
+		ImagesDeleted:     3,
+		BytesReclaimed:    200 + 400 + 700,
+	}, stats)
+	client.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+	assert.Len(t, sqltest.GetAllRows(ctx, t, db, "DiffMetrics", &schema.DiffMetricRow{}), 4)
+}
+
+func TestCollect_ArchiveBucket_ImagesArchivedInsteadOfDeleted(t *testing.T) {
+	ctx, db := setupTestData(t)
+	client := mocks.NewGCSClient(t)
+	mockImages(client, testImages())
+	for _, digest := range []types.Digest{dks.DigestA02Pos, dks.DigestA04Unt, dks.DigestC02Pos} {
+		client.On("ArchiveImage", testutils.AnyContext, digest, "archive-bucket").Return(nil)
+	}
+	cfg := testConfig()
+	cfg.ArchiveBucket = "archive-bucket"
+
+	stats, err := New(db, client, cfg).Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.ImagesDeleted)
+	client.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+}
+
+func TestReport_DryRunNotSet_NothingDeletedLargestImagesFirst(t *testing.T) {
+	ctx, db := setupTestData(t)
+	client := mocks.NewGCSClient(t)
+	mockImages(client, testImages())
+
+	report, err := New(db, client, testConfig()).Report(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, Stats{
+		ImagesSeen:        10,
+		ReferencedDigests: 5,
+		ImagesDeleted:     3,
+		BytesReclaimed:    200 + 400 + 700,
+	}, report.Stats)
+	old := fakeNow.Add(-48 * time.Hour)
+	assert.Equal(t, []storage.ImageInfo{
+		{Digest: dks.DigestC02Pos, Size: 700, Created: old},
+		{Digest: dks.DigestA04Unt, Size: 400, Created: old},
+	}, report.Unreferenced)
+	client.AssertNotCalled(t, "DeleteImage", mock.Anything, mock.Anything)
+	assert.Len(t, sqltest.GetAllRows(ctx, t, db, "DiffMetrics", &schema.DiffMetricRow{}), 4)
+}
+
+func TestCollect_NoReferencedDigests_ReturnsErrorWithoutDeleting(t *testing.T) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, fakeNow)
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	client := mocks.NewGCSClient(t)
+
+	_, err := New(db, client, testConfig()).Collect(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to delete all images")
+}
+
+func testConfig() config.ImageGCConfig {
+	return config.ImageGCConfig{
+		ChangelistRetention:    config.Duration{Duration: 7 * 24 * time.Hour},
+		CorpusRetentionCommits: map[string]int{"beta": 2},
+		GracePeriod:            config.Duration{Duration: time.Hour},
+		RetentionCommits:       1,
+	}
+}
+
+// setupTestData creates a DB with two commits in two different tiles. With the test config, the
+// alpha corpus only retains digests from the second tile and the beta corpus from both.
+func setupTestData(t *testing.T) (context.Context, *pgxpool.Pool) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, fakeNow)
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	_, alphaGrouping := sql.SerializeMap(alphaKeys)
+	_, betaGrouping := sql.SerializeMap(betaKeys)
+	alphaTrace := schema.TraceID{0xaa}
+	betaTrace := schema.TraceID{0xbb}
+	recentCL := "gerrit_123"
+	oldCL := "gerrit_456"
+	staleOpenCL := "gerrit_789"
+
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, schema.Tables{
+		CommitsWithData: []schema.CommitWithDataRow{
+			{CommitID: "0000000001", TileID: 0},
+			{CommitID: "0000000002", TileID: 1},
+		},
+		Groupings: []schema.GroupingRow{
+			{GroupingID: alphaGrouping, Keys: alphaKeys},
+			{GroupingID: betaGrouping, Keys: betaKeys},
+		},
+		TiledTraceDigests: []schema.TiledTraceDigestRow{
+			{TraceID: alphaTrace, TileID: 1, Digest: d(t, dks.DigestA01Pos), GroupingID: alphaGrouping},
+			{TraceID: alphaTrace, TileID: 0, Digest: d(t, dks.DigestA02Pos), GroupingID: alphaGrouping},
+			{TraceID: alphaTrace, TileID: 0, Digest: d(t, dks.DigestA03Pos), GroupingID: alphaGrouping},
+			{TraceID: alphaTrace, TileID: 0, Digest: d(t, dks.DigestA04Unt), GroupingID: alphaGrouping},
+			{TraceID: betaTrace, TileID: 0, Digest: d(t, dks.DigestB01Pos), GroupingID: betaGrouping},
+		},
+		Expectations: []schema.ExpectationRow{
+			{GroupingID: alphaGrouping, Digest: d(t, dks.DigestA03Pos), Label: schema.LabelPositive},
+			{GroupingID: alphaGrouping, Digest: d(t, dks.DigestA04Unt), Label: schema.LabelUntriaged},
+		},
+		Changelists: []schema.ChangelistRow{
+			{ChangelistID: recentCL, System: "gerrit", Status: schema.StatusOpen, OwnerEmail: "user@example.com", Subject: "recent", LastIngestedData: fakeNow.Add(-time.Hour)},
+			{ChangelistID: oldCL, System: "gerrit", Status: schema.StatusAbandoned, OwnerEmail: "user@example.com", Subject: "old", LastIngestedData: fakeNow.Add(-30 * 24 * time.Hour)},
+			{ChangelistID: staleOpenCL, System: "gerrit", Status: schema.StatusOpen, OwnerEmail: "user@example.com", Subject: "stale", LastIngestedData: fakeNow.Add(-30 * 24 * time.Hour)},
+		},
+		SecondaryBranchValues: []schema.SecondaryBranchValueRow{
+			{BranchName: recentCL, VersionName: "gerrit_ps_1", TraceID: alphaTrace, Digest: d(t, dks.DigestC01Pos), GroupingID: alphaGrouping, OptionsID: schema.OptionsID{0x01}, SourceFileID: schema.SourceFileID{0x01}},
+			{BranchName: oldCL, VersionName: "gerrit_ps_1", TraceID: alphaTrace, Digest: d(t, dks.DigestC02Pos), GroupingID: alphaGrouping, OptionsID: schema.OptionsID{0x01}, SourceFileID: schema.SourceFileID{0x02}},
+			{BranchName: staleOpenCL, VersionName: "gerrit_ps_1", TraceID: alphaTrace, Digest: d(t, dks.DigestC04Unt), GroupingID: alphaGrouping, OptionsID: schema.OptionsID{0x01}, SourceFileID: schema.SourceFileID{0x03}},
+		},
+		DiffMetrics: []schema.DiffMetricRow{
+			diffMetric(t, dks.DigestA01Pos, dks.DigestA02Pos),
+			diffMetric(t, dks.DigestA02Pos, dks.DigestA01Pos),
+			diffMetric(t, dks.DigestA01Pos, dks.DigestA03Pos),
+			diffMetric(t, dks.DigestA03Pos, dks.DigestA01Pos),
+		},
+		PerceptualHashes: []schema.PerceptualHashRow{
+			{Digest: d(t, dks.DigestA01Pos), Hash: 1},
+			{Digest: d(t, dks.DigestA02Pos), Hash: 2},
+		},
+	}))
+	// Wait for the data to be visible to AS OF SYSTEM TIME queries.
+	time.Sleep(150 * time.Millisecond)
+	return ctx, db
+}
+
+// testImages returns the images in the fake bucket. Only A02, A04 and C02 are unreferenced and
+// old enough to be deleted.
+func testImages() []storage.ImageInfo {
+	old := fakeNow.Add(-48 * time.Hour)
+	return []storage.ImageInfo{
+		{Digest: dks.DigestA01Pos, Size: 100, Created: old},
+		{Digest: dks.DigestA02Pos, Size: 200, Created: old},
+		{Digest: dks.DigestA03Pos, Size: 300, Created: old},
+		{Digest: dks.DigestA04Unt, Size: 400, Created: old},
+		{Digest: dks.DigestB01Pos, Size: 500, Created: old},
+		{Digest: dks.DigestC01Pos, Size: 600, Created: old},
+		{Digest: dks.DigestC02Pos, Size: 700, Created: old},
+		// Only seen on a CL which has not had data for a long time, but which is still open.
+		{Digest: dks.DigestC04Unt, Size: 1000, Created: old},
+		// Not referenced, but uploaded too recently to be deleted.
+		{Digest: dks.DigestC03Unt, Size: 800, Created: fakeNow.Add(-time.Minute)},
+		// Not a valid digest, so it should be left alone.
+		{Digest: "not-a-digest", Size: 900, Created: old},
+	}
+}
+
+func mockImages(client *mocks.GCSClient, images []storage.ImageInfo) {
+	client.On("ListImages", testutils.AnyContext, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(storage.ImageInfo) error)
+		for _, info := range images {
+			if err := fn(info); err != nil {
+				panic(err)
+			}
+		}
+	}).Return(nil)
+}
+
+func diffMetric(t *testing.T, left, right types.Digest) schema.DiffMetricRow {
+	return schema.DiffMetricRow{
+		LeftDigest:        d(t, left),
+		RightDigest:       d(t, right),
+		NumPixelsDiff:     1,
+		PercentPixelsDiff: 0.5,
+		MaxRGBADiffs:      [4]int{1, 1, 1, 1},
+		MaxChannelDiff:    1,
+		CombinedMetric:    0.1,
+		Timestamp:         fakeNow,
+	}
+}
+
+func d(t *testing.T, digest types.Digest) schema.DigestBytes {
+	b, err := sql.DigestToBytes(digest)
+	require.NoError(t, err)
+	return b
+}
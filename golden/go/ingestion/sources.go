@@ -3,6 +3,7 @@ package ingestion
 import (
 	"context"
 	"io"
+	"path"
 	"strings"
 	"time"
 
@@ -13,6 +14,11 @@ import (
 	"go.opencensus.io/trace"
 )
 
+// UploadedDir is the directory, relative to the prefix of a GCSSource, with the results files
+// that were uploaded over HTTP. These files are named after their contents instead of being put
+// in hourly directories, so SearchForFiles looks at when they were last written instead.
+const UploadedDir = "uploaded"
+
 // FileSearcher is an interface around the logic for polling for files that may have been
 // missed via the typical event-based ingestion.
 type FileSearcher interface {
@@ -59,6 +65,15 @@ func (s *GCSSource) SearchForFiles(ctx context.Context, start, end time.Time) []
 			sklog.Errorf("Error occurred while retrieving files from %s/%s: %s", s.Bucket, dir, err)
 		}
 	}
+	uploadedDir := path.Join(s.Prefix, UploadedDir) + "/"
+	err := gcs.AllFilesInDir(s.Client, s.Bucket, uploadedDir, func(item *storage.ObjectAttrs) {
+		if strings.HasSuffix(item.Name, ".json") && !item.Updated.Before(start) && item.Updated.Before(end) {
+			files = append(files, item.Name)
+		}
+	})
+	if err != nil {
+		sklog.Errorf("Error occurred while retrieving files from %s/%s: %s", s.Bucket, uploadedDir, err)
+	}
 	if len(files) > 0 {
 		sklog.Infof("First GCS file in backup range: %s", files[0])
 		sklog.Infof("Last GCS file in backup range: %s", files[len(files)-1])
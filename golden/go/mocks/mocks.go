@@ -40,6 +40,126 @@ func (_m *GCSClient) EXPECT() *GCSClient_Expecter {
 	return &GCSClient_Expecter{mock: &_m.Mock}
 }
 
+// ArchiveImage provides a mock function for the type GCSClient
+func (_mock *GCSClient) ArchiveImage(ctx context.Context, digest types.Digest, bucket string) error {
+	ret := _mock.Called(ctx, digest, bucket)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ArchiveImage")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, types.Digest, string) error); ok {
+		r0 = returnFunc(ctx, digest, bucket)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// GCSClient_ArchiveImage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ArchiveImage'
+type GCSClient_ArchiveImage_Call struct {
+	*mock.Call
+}
+
+// ArchiveImage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - digest types.Digest
+//   - bucket string
+func (_e *GCSClient_Expecter) ArchiveImage(ctx interface{}, digest interface{}, bucket interface{}) *GCSClient_ArchiveImage_Call {
+	return &GCSClient_ArchiveImage_Call{Call: _e.mock.On("ArchiveImage", ctx, digest, bucket)}
+}
+
+func (_c *GCSClient_ArchiveImage_Call) Run(run func(ctx context.Context, digest types.Digest, bucket string)) *GCSClient_ArchiveImage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 types.Digest
+		if args[1] != nil {
+			arg1 = args[1].(types.Digest)
+		}
+		var arg2 string
+		if args[2] != nil {
+			arg2 = args[2].(string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *GCSClient_ArchiveImage_Call) Return(err error) *GCSClient_ArchiveImage_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *GCSClient_ArchiveImage_Call) RunAndReturn(run func(ctx context.Context, digest types.Digest, bucket string) error) *GCSClient_ArchiveImage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteImage provides a mock function for the type GCSClient
+func (_mock *GCSClient) DeleteImage(ctx context.Context, digest types.Digest) error {
+	ret := _mock.Called(ctx, digest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteImage")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, types.Digest) error); ok {
+		r0 = returnFunc(ctx, digest)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// GCSClient_DeleteImage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteImage'
+type GCSClient_DeleteImage_Call struct {
+	*mock.Call
+}
+
+// DeleteImage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - digest types.Digest
+func (_e *GCSClient_Expecter) DeleteImage(ctx interface{}, digest interface{}) *GCSClient_DeleteImage_Call {
+	return &GCSClient_DeleteImage_Call{Call: _e.mock.On("DeleteImage", ctx, digest)}
+}
+
+func (_c *GCSClient_DeleteImage_Call) Run(run func(ctx context.Context, digest types.Digest)) *GCSClient_DeleteImage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 types.Digest
+		if args[1] != nil {
+			arg1 = args[1].(types.Digest)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *GCSClient_DeleteImage_Call) Return(err error) *GCSClient_DeleteImage_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *GCSClient_DeleteImage_Call) RunAndReturn(run func(ctx context.Context, digest types.Digest) error) *GCSClient_DeleteImage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetImage provides a mock function for the type GCSClient
 func (_mock *GCSClient) GetImage(ctx context.Context, digest types.Digest) ([]byte, error) {
 	ret := _mock.Called(ctx, digest)
@@ -108,6 +228,63 @@ func (_c *GCSClient_GetImage_Call) RunAndReturn(run func(ctx context.Context, di
 	return _c
 }
 
+// ListImages provides a mock function for the type GCSClient
+func (_mock *GCSClient) ListImages(ctx context.Context, fn func(storage.ImageInfo) error) error {
+	ret := _mock.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListImages")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, func(storage.ImageInfo) error) error); ok {
+		r0 = returnFunc(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// GCSClient_ListImages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListImages'
+type GCSClient_ListImages_Call struct {
+	*mock.Call
+}
+
+// ListImages is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(storage.ImageInfo) error
+func (_e *GCSClient_Expecter) ListImages(ctx interface{}, fn interface{}) *GCSClient_ListImages_Call {
+	return &GCSClient_ListImages_Call{Call: _e.mock.On("ListImages", ctx, fn)}
+}
+
+func (_c *GCSClient_ListImages_Call) Run(run func(ctx context.Context, fn func(storage.ImageInfo) error)) *GCSClient_ListImages_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 func(storage.ImageInfo) error
+		if args[1] != nil {
+			arg1 = args[1].(func(storage.ImageInfo) error)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *GCSClient_ListImages_Call) Return(err error) *GCSClient_ListImages_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *GCSClient_ListImages_Call) RunAndReturn(run func(ctx context.Context, fn func(storage.ImageInfo) error) error) *GCSClient_ListImages_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // LoadKnownDigests provides a mock function for the type GCSClient
 func (_mock *GCSClient) LoadKnownDigests(ctx context.Context, w io.Writer) error {
 	ret := _mock.Called(ctx, w)
@@ -265,3 +442,66 @@ func (_c *GCSClient_WriteKnownDigests_Call) RunAndReturn(run func(ctx context.Co
 	_c.Call.Return(run)
 	return _c
 }
+
+// WriteResultsFile provides a mock function for the type GCSClient
+func (_mock *GCSClient) WriteResultsFile(ctx context.Context, gcsPath string, data []byte) error {
+	ret := _mock.Called(ctx, gcsPath, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WriteResultsFile")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = returnFunc(ctx, gcsPath, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// GCSClient_WriteResultsFile_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WriteResultsFile'
+type GCSClient_WriteResultsFile_Call struct {
+	*mock.Call
+}
+
+// WriteResultsFile is a helper method to define mock.On call
+//   - ctx context.Context
+//   - gcsPath string
+//   - data []byte
+func (_e *GCSClient_Expecter) WriteResultsFile(ctx interface{}, gcsPath interface{}, data interface{}) *GCSClient_WriteResultsFile_Call {
+	return &GCSClient_WriteResultsFile_Call{Call: _e.mock.On("WriteResultsFile", ctx, gcsPath, data)}
+}
+
+func (_c *GCSClient_WriteResultsFile_Call) Run(run func(ctx context.Context, gcsPath string, data []byte)) *GCSClient_WriteResultsFile_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 []byte
+		if args[2] != nil {
+			arg2 = args[2].([]byte)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *GCSClient_WriteResultsFile_Call) Return(err error) *GCSClient_WriteResultsFile_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *GCSClient_WriteResultsFile_Call) RunAndReturn(run func(ctx context.Context, gcsPath string, data []byte) error) *GCSClient_WriteResultsFile_Call {
+	_c.Call.Return(run)
+	return _c
+}
@@ -743,6 +743,78 @@ func (_c *API_GetDigestsForGrouping_Call) RunAndReturn(run func(ctx context.Cont
 	return _c
 }
 
+// GetFlakyTests provides a mock function for the type API
+func (_mock *API) GetFlakyTests(ctx context.Context, corpus string, limit int) (frontend.FlakyTestsResponse, error) {
+	ret := _mock.Called(ctx, corpus, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFlakyTests")
+	}
+
+	var r0 frontend.FlakyTestsResponse
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int) (frontend.FlakyTestsResponse, error)); ok {
+		return returnFunc(ctx, corpus, limit)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, string, int) frontend.FlakyTestsResponse); ok {
+		r0 = returnFunc(ctx, corpus, limit)
+	} else {
+		r0 = ret.Get(0).(frontend.FlakyTestsResponse)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = returnFunc(ctx, corpus, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// API_GetFlakyTests_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetFlakyTests'
+type API_GetFlakyTests_Call struct {
+	*mock.Call
+}
+
+// GetFlakyTests is a helper method to define mock.On call
+//   - ctx context.Context
+//   - corpus string
+//   - limit int
+func (_e *API_Expecter) GetFlakyTests(ctx interface{}, corpus interface{}, limit interface{}) *API_GetFlakyTests_Call {
+	return &API_GetFlakyTests_Call{Call: _e.mock.On("GetFlakyTests", ctx, corpus, limit)}
+}
+
+func (_c *API_GetFlakyTests_Call) Run(run func(ctx context.Context, corpus string, limit int)) *API_GetFlakyTests_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		var arg2 int
+		if args[2] != nil {
+			arg2 = args[2].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *API_GetFlakyTests_Call) Return(flakyTestsResponse frontend.FlakyTestsResponse, err error) *API_GetFlakyTests_Call {
+	_c.Call.Return(flakyTestsResponse, err)
+	return _c
+}
+
+func (_c *API_GetFlakyTests_Call) RunAndReturn(run func(ctx context.Context, corpus string, limit int) (frontend.FlakyTestsResponse, error)) *API_GetFlakyTests_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetPrimaryBranchParamset provides a mock function for the type API
 func (_mock *API) GetPrimaryBranchParamset(ctx context.Context) (paramtools.ReadOnlyParamSet, error) {
 	ret := _mock.Called(ctx)
@@ -805,6 +877,146 @@ func (_c *API_GetPrimaryBranchParamset_Call) RunAndReturn(run func(ctx context.C
 	return _c
 }
 
+// GetSearchFacets provides a mock function for the type API
+func (_mock *API) GetSearchFacets(ctx context.Context, q *query.Search) (frontend.SearchFacetsResponse, error) {
+	ret := _mock.Called(ctx, q)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSearchFacets")
+	}
+
+	var r0 frontend.SearchFacetsResponse
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *query.Search) (frontend.SearchFacetsResponse, error)); ok {
+		return returnFunc(ctx, q)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, *query.Search) frontend.SearchFacetsResponse); ok {
+		r0 = returnFunc(ctx, q)
+	} else {
+		r0 = ret.Get(0).(frontend.SearchFacetsResponse)
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, *query.Search) error); ok {
+		r1 = returnFunc(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// API_GetSearchFacets_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSearchFacets'
+type API_GetSearchFacets_Call struct {
+	*mock.Call
+}
+
+// GetSearchFacets is a helper method to define mock.On call
+//   - ctx context.Context
+//   - q *query.Search
+func (_e *API_Expecter) GetSearchFacets(ctx interface{}, q interface{}) *API_GetSearchFacets_Call {
+	return &API_GetSearchFacets_Call{Call: _e.mock.On("GetSearchFacets", ctx, q)}
+}
+
+func (_c *API_GetSearchFacets_Call) Run(run func(ctx context.Context, q *query.Search)) *API_GetSearchFacets_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 *query.Search
+		if args[1] != nil {
+			arg1 = args[1].(*query.Search)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *API_GetSearchFacets_Call) Return(searchFacetsResponse frontend.SearchFacetsResponse, err error) *API_GetSearchFacets_Call {
+	_c.Call.Return(searchFacetsResponse, err)
+	return _c
+}
+
+func (_c *API_GetSearchFacets_Call) RunAndReturn(run func(ctx context.Context, q *query.Search) (frontend.SearchFacetsResponse, error)) *API_GetSearchFacets_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetVariants provides a mock function for the type API
+func (_mock *API) GetVariants(ctx context.Context, grouping paramtools.Params, digest types.Digest) ([]frontend.DigestVariant, error) {
+	ret := _mock.Called(ctx, grouping, digest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariants")
+	}
+
+	var r0 []frontend.DigestVariant
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, paramtools.Params, types.Digest) ([]frontend.DigestVariant, error)); ok {
+		return returnFunc(ctx, grouping, digest)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, paramtools.Params, types.Digest) []frontend.DigestVariant); ok {
+		r0 = returnFunc(ctx, grouping, digest)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]frontend.DigestVariant)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, paramtools.Params, types.Digest) error); ok {
+		r1 = returnFunc(ctx, grouping, digest)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// API_GetVariants_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetVariants'
+type API_GetVariants_Call struct {
+	*mock.Call
+}
+
+// GetVariants is a helper method to define mock.On call
+//   - ctx context.Context
+//   - grouping paramtools.Params
+//   - digest types.Digest
+func (_e *API_Expecter) GetVariants(ctx interface{}, grouping interface{}, digest interface{}) *API_GetVariants_Call {
+	return &API_GetVariants_Call{Call: _e.mock.On("GetVariants", ctx, grouping, digest)}
+}
+
+func (_c *API_GetVariants_Call) Run(run func(ctx context.Context, grouping paramtools.Params, digest types.Digest)) *API_GetVariants_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 paramtools.Params
+		if args[1] != nil {
+			arg1 = args[1].(paramtools.Params)
+		}
+		var arg2 types.Digest
+		if args[2] != nil {
+			arg2 = args[2].(types.Digest)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *API_GetVariants_Call) Return(digestVariants []frontend.DigestVariant, err error) *API_GetVariants_Call {
+	_c.Call.Return(digestVariants, err)
+	return _c
+}
+
+func (_c *API_GetVariants_Call) RunAndReturn(run func(ctx context.Context, grouping paramtools.Params, digest types.Digest) ([]frontend.DigestVariant, error)) *API_GetVariants_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewAndUntriagedSummaryForCL provides a mock function for the type API
 func (_mock *API) NewAndUntriagedSummaryForCL(ctx context.Context, qCLID string) (search.NewAndUntriagedSummary, error) {
 	ret := _mock.Called(ctx, qCLID)
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,10 +22,12 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/sync/errgroup"
 
+	"go.goldmine.build/go/now"
 	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/skerr"
 	"go.goldmine.build/go/sklog"
 	"go.goldmine.build/go/util"
+	"go.goldmine.build/golden/go/diff"
 	"go.goldmine.build/golden/go/expectations"
 	"go.goldmine.build/golden/go/publicparams"
 	"go.goldmine.build/golden/go/search/query"
@@ -90,6 +93,21 @@ type API interface {
 	// ComputeGUIStatus looks at all visible traces at head and returns a summary of how many are
 	// untriaged for each corpus, as well as the most recent commit for which we have data.
 	ComputeGUIStatus(ctx context.Context) (frontend.GUIStatus, error)
+
+	// GetFlakyTests returns, for each corpus, up to limit tests whose traces produced the most
+	// distinct digests in the most recent tiles. If corpus is not empty, only that corpus is
+	// returned.
+	GetFlakyTests(ctx context.Context, corpus string, limit int) (frontend.FlakyTestsResponse, error)
+
+	// GetSearchFacets returns, for each key=value of the traces that match the given query, how
+	// many of the search results would be left if the query also required that key=value. Filters
+	// on the diffs to the closest reference images (e.g. RGBA ranges) are not taken into account.
+	GetSearchFacets(ctx context.Context, q *query.Search) (frontend.SearchFacetsResponse, error)
+
+	// GetVariants returns the digests drawn at head by the variants of the traces on the primary
+	// branch which produced the given digest in the given grouping. It returns nothing if no
+	// variant key is configured.
+	GetVariants(ctx context.Context, grouping paramtools.Params, digest types.Digest) ([]frontend.DigestVariant, error)
 }
 
 // NewAndUntriagedSummary is a summary of the results associated with a given CL. It focuses on
@@ -114,6 +132,9 @@ type PatchsetNewAndUntriagedSummary struct {
 	// NewImages is the number of new images (digests) that were produced by this patchset by
 	// non-ignored traces and not seen on the primary branch.
 	NewImages int
+	// ChangedTests is the number of groupings (e.g. tests) for which this patchset produced at
+	// least one of the NewImages.
+	ChangedTests int
 	// NewUntriagedImages is the number of NewImages which are still untriaged. It is less than or
 	// equal to NewImages.
 	NewUntriagedImages int
@@ -192,6 +213,8 @@ type Impl struct {
 	mutex sync.RWMutex
 	// This caches the digests seen per grouping on the primary branch.
 	digestsOnPrimary map[groupingDigestKey]struct{}
+	// This caches the traces which produced more than one digest on the primary branch.
+	flakyTraces []flakyTrace
 	// This caches the trace ids that are publicly visible.
 	publiclyVisibleTraces map[schema.MD5Hash]struct{}
 	// This caches the corpora names that are publicly visible.
@@ -204,6 +227,18 @@ type Impl struct {
 	paramsetCache        *ttlcache.Cache
 
 	materializedViews map[string]bool
+
+	// diffMetricsByCorpus configures how the closest positive and negative digests are chosen.
+	// Corpora which are not in the map use diff.MetricCombined.
+	diffMetricsByCorpus map[string]diff.MetricConfig
+
+	// perceptualDiffMetrics is true if the SSIM and delta E of each diff are computed, and thus
+	// should be read from the DiffMetrics table.
+	perceptualDiffMetrics bool
+
+	// variantKey is the trace key whose values distinguish the variants of a trace, e.g. the scale
+	// the image was rendered at. If empty, variants are not looked up.
+	variantKey string
 }
 
 // New returns an implementation of API.
@@ -239,11 +274,68 @@ func (s *Impl) SetReviewSystemTemplates(m map[string]string) {
 	s.reviewSystemMapping = m
 }
 
+// SetPerceptualDiffMetrics sets whether the SSIM and delta E of the diffs are computed. If they
+// are not, they are not read from the database and the corresponding diff metrics can't be used.
+// It must be called before SetDiffMetrics.
+func (s *Impl) SetPerceptualDiffMetrics(enabled bool) {
+	s.perceptualDiffMetrics = enabled
+}
+
+// SetDiffMetrics sets the diff metric used by each corpus to find the closest positive and
+// negative digests to a given digest. It returns an error if any of the configs is invalid.
+func (s *Impl) SetDiffMetrics(m map[string]diff.MetricConfig) error {
+	for corpus, cfg := range m {
+		if err := cfg.Validate(); err != nil {
+			return skerr.Wrapf(err, "invalid diff metric for corpus %s", corpus)
+		}
+		if (cfg.Metric == diff.MetricSSIM || cfg.Metric == diff.MetricDeltaE) && !s.perceptualDiffMetrics {
+			return skerr.Fmt("diff metric %s for corpus %s requires the perceptual diff metrics to be computed", cfg.Metric, corpus)
+		}
+	}
+	s.diffMetricsByCorpus = m
+	return nil
+}
+
+// SetVariantKey sets the trace key whose values distinguish the variants of a trace. Traces in the
+// same grouping whose keys only differ in the value of this key are variants of each other, and
+// the digests they draw are returned alongside the search results.
+func (s *Impl) SetVariantKey(key string) {
+	s.variantKey = key
+}
+
+// perceptualDiffColumns returns the columns to select for the SSIM and delta E of a diff. They
+// are NULL if the perceptual diff metrics are not computed.
+func (s *Impl) perceptualDiffColumns() string {
+	if s.perceptualDiffMetrics {
+		return "ssim, delta_e"
+	}
+	return "NULL::FLOAT4, NULL::FLOAT4"
+}
+
+// getDiffMetricConfig returns the diff metric config for the corpus of the given grouping.
+func (s *Impl) getDiffMetricConfig(ctx context.Context, groupingID schema.MD5Hash) (diff.MetricConfig, error) {
+	if len(s.diffMetricsByCorpus) == 0 {
+		return diff.MetricConfig{}, nil
+	}
+	grouping, err := s.expandGrouping(ctx, groupingID)
+	if err != nil {
+		return diff.MetricConfig{}, skerr.Wrap(err)
+	}
+	return s.diffMetricsByCorpus[grouping[types.CorpusField]], nil
+}
+
 type groupingDigestKey struct {
 	groupingID schema.MD5Hash
 	digest     schema.MD5Hash
 }
 
+// flakyTrace is a trace which produced more than one distinct digest.
+type flakyTrace struct {
+	traceID    schema.MD5Hash
+	groupingID schema.MD5Hash
+	numDigests int
+}
+
 // StartCacheProcess loads the caches used for searching and starts a goroutine to keep those
 // up to date.
 func (s *Impl) StartCacheProcess(ctx context.Context, interval time.Duration, commitsWithData int) error {
@@ -259,7 +351,7 @@ func (s *Impl) StartCacheProcess(ctx context.Context, interval time.Duration, co
 	return nil
 }
 
-// updateCaches loads the digestsOnPrimary cache.
+// updateCaches loads the digestsOnPrimary and flakyTraces caches.
 func (s *Impl) updateCaches(ctx context.Context, commitsWithData int) error {
 	ctx, span := trace.StartSpan(ctx, "search2_UpdateCaches", trace.WithSampler(trace.AlwaysSample()))
 	defer span.End()
@@ -271,10 +363,16 @@ func (s *Impl) updateCaches(ctx context.Context, commitsWithData int) error {
 	if err != nil {
 		return skerr.Wrapf(err, "getting digests on primary branch")
 	}
+	flaky, err := s.getFlakyTraces(ctx, tile)
+	if err != nil {
+		return skerr.Wrapf(err, "getting flaky traces")
+	}
 	s.mutex.Lock()
 	s.digestsOnPrimary = onPrimary
+	s.flakyTraces = flaky
 	s.mutex.Unlock()
 	sklog.Infof("Digests on Primary cache refreshed with %d entries", len(onPrimary))
+	sklog.Infof("Flaky traces cache refreshed with %d entries", len(flaky))
 	return nil
 }
 
@@ -336,6 +434,35 @@ TiledTraceDigests WHERE tile_id >= $1`, tile)
 	return rv, nil
 }
 
+// getFlakyTraces returns the traces which produced more than one distinct digest on the primary
+// branch starting at the given tile.
+func (s *Impl) getFlakyTraces(ctx context.Context, tile schema.TileID) ([]flakyTrace, error) {
+	ctx, span := trace.StartSpan(ctx, "getFlakyTraces")
+	defer span.End()
+	rows, err := s.db.Query(ctx, `
+SELECT trace_id, grouping_id, count(DISTINCT digest) FROM TiledTraceDigests
+WHERE tile_id >= $1
+GROUP BY trace_id, grouping_id
+HAVING count(DISTINCT digest) > 1`, tile)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var rv []flakyTrace
+	var traceID schema.TraceID
+	var groupingID schema.GroupingID
+	for rows.Next() {
+		var ft flakyTrace
+		if err := rows.Scan(&traceID, &groupingID, &ft.numDigests); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		copy(ft.traceID[:], traceID)
+		copy(ft.groupingID[:], groupingID)
+		rv = append(rv, ft)
+	}
+	return rv, nil
+}
+
 const (
 	unignoredRecentTracesView = "traces"
 	byBlameView               = "byblame"
@@ -399,6 +526,18 @@ func (s *Impl) StartMaterializedViews(ctx context.Context, corpora []string, upd
 	return nil
 }
 
+// UseMaterializedViews makes search use the materialized views of the given corpora, without
+// creating or refreshing them. It is meant for read replicas, the views must be maintained by
+// another process which calls StartMaterializedViews with the same corpora.
+func (s *Impl) UseMaterializedViews(corpora []string) {
+	s.materializedViews = map[string]bool{}
+	for _, corpus := range corpora {
+		s.materializedViews["mv_"+corpus+"_"+unignoredRecentTracesView] = true
+		s.materializedViews["mv_"+corpus+"_"+byBlameView] = true
+	}
+	sklog.Infof("Using %d materialized views", len(s.materializedViews))
+}
+
 func (s *Impl) createUnignoredRecentTracesView(ctx context.Context, corpus string) (string, error) {
 	mvName := "mv_" + corpus + "_" + unignoredRecentTracesView
 	statement := "CREATE MATERIALIZED VIEW IF NOT EXISTS " + mvName
@@ -629,6 +768,7 @@ SELECT * FROM LabeledDigests;`
 	keyGrouping := key.groupingID[:]
 	keyDigest := key.digest[:]
 	var rv PatchsetNewAndUntriagedSummary
+	changedGroupings := map[schema.MD5Hash]bool{}
 
 	for rows.Next() {
 		if err := rows.Scan(&grouping, &digest, &label); err != nil {
@@ -639,6 +779,7 @@ SELECT * FROM LabeledDigests;`
 		_, isExisting := s.digestsOnPrimary[key]
 		if !isExisting {
 			rv.NewImages++
+			changedGroupings[key.groupingID] = true
 		}
 		if label == schema.LabelUntriaged {
 			rv.TotalUntriagedImages++
@@ -647,6 +788,7 @@ SELECT * FROM LabeledDigests;`
 			}
 		}
 	}
+	rv.ChangedTests = len(changedGroupings)
 	return rv, nil
 }
 
@@ -728,6 +870,9 @@ func (s *Impl) Search(ctx context.Context, q *query.Search) (*frontend.SearchRes
 	if err != nil {
 		return nil, skerr.Wrap(err)
 	}
+	if err := s.addVariants(ctx, closestDiffs, results); err != nil {
+		return nil, skerr.Wrap(err)
+	}
 	// Populate the LabelBefore fields of the extendedBulkTriageDeltaInfos with expectations from
 	// the primary branch.
 	if err := s.populateLabelBefore(ctx, extendedBulkTriageDeltaInfos); err != nil {
@@ -1235,7 +1380,7 @@ func (s *Impl) getClosestDiffs(ctx context.Context, inputs []digestWithTraceAndG
 						digestAndClosestDiffs.closestNegative = srdd
 					}
 					if digestAndClosestDiffs.closestNegative != nil && digestAndClosestDiffs.closestPositive != nil {
-						if digestAndClosestDiffs.closestPositive.CombinedMetric < digestAndClosestDiffs.closestNegative.CombinedMetric {
+						if digestAndClosestDiffs.closestPositive.QueryMetric < digestAndClosestDiffs.closestNegative.QueryMetric {
 							digestAndClosestDiffs.closestDigest = digestAndClosestDiffs.closestPositive
 						} else {
 							digestAndClosestDiffs.closestDigest = digestAndClosestDiffs.closestNegative
@@ -1346,6 +1491,10 @@ func (s *Impl) getDiffsForGrouping(ctx context.Context, groupingID schema.MD5Has
 	if err != nil {
 		return nil, skerr.Wrap(err)
 	}
+	cfg, err := s.getDiffMetricConfig(ctx, groupingID)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
 	statement := `
 WITH
 PositiveOrNegativeDigests AS (
@@ -1356,15 +1505,15 @@ ComparisonBetweenUntriagedAndObserved AS (
 	SELECT DiffMetrics.* FROM DiffMetrics
 	WHERE left_digest = ANY($2) AND right_digest = ANY($3)
 )
--- This will return the right_digest with the smallest combined_metric for each left_digest + label
+-- This will return the closest right_digest for each left_digest + label
 SELECT DISTINCT ON (left_digest, label)
   label, left_digest, right_digest, num_pixels_diff, percent_pixels_diff, max_rgba_diffs,
-  combined_metric, dimensions_differ
+  combined_metric, dimensions_differ, ` + s.perceptualDiffColumns() + `
 FROM
   ComparisonBetweenUntriagedAndObserved
 JOIN PositiveOrNegativeDigests
   ON ComparisonBetweenUntriagedAndObserved.right_digest = PositiveOrNegativeDigests.digest
-ORDER BY left_digest, label, combined_metric ASC, max_channel_diff ASC, right_digest ASC
+ORDER BY left_digest, label, ` + closestOrderBy(cfg) + `, combined_metric ASC, max_channel_diff ASC, right_digest ASC
 `
 
 	rows, err := s.db.Query(ctx, statement, groupingID[:], leftDigests, digestsInGrouping)
@@ -1376,9 +1525,10 @@ ORDER BY left_digest, label, combined_metric ASC, max_channel_diff ASC, right_di
 	var label schema.ExpectationLabel
 	var row schema.DiffMetricRow
 	for rows.Next() {
+		row.SSIM, row.DeltaE = nil, nil
 		if err := rows.Scan(&label, &row.LeftDigest, &row.RightDigest, &row.NumPixelsDiff,
 			&row.PercentPixelsDiff, &row.MaxRGBADiffs, &row.CombinedMetric,
-			&row.DimensionsDiffer); err != nil {
+			&row.DimensionsDiffer, &row.SSIM, &row.DeltaE); err != nil {
 			rows.Close()
 			return nil, skerr.Wrap(err)
 		}
@@ -1390,8 +1540,8 @@ ORDER BY left_digest, label, combined_metric ASC, max_channel_diff ASC, right_di
 			MaxRGBADiffs:     row.MaxRGBADiffs,
 			NumDiffPixels:    row.NumPixelsDiff,
 			PixelDiffPercent: row.PercentPixelsDiff,
-			QueryMetric:      row.CombinedMetric,
 		}
+		srdd.QueryMetric = queryMetric(cfg, srdd, row.SSIM, row.DeltaE)
 		key := groupingDigestKey{
 			digest:     sql.AsMD5Hash(row.LeftDigest),
 			groupingID: groupingID,
@@ -1401,6 +1551,52 @@ ORDER BY left_digest, label, combined_metric ASC, max_channel_diff ASC, right_di
 	return results, nil
 }
 
+// closestOrderBy returns the ORDER BY term of a DiffMetrics query which sorts the closest digests
+// according to the given config first. Rows which are missing the metric (because they were
+// computed before it was introduced) are sorted last.
+func closestOrderBy(cfg diff.MetricConfig) string {
+	switch cfg.Metric {
+	case diff.MetricSSIM:
+		return "COALESCE(ssim, -2) DESC"
+	case diff.MetricDeltaE:
+		return "COALESCE(delta_e, 1000000) ASC"
+	case diff.MetricChannelWeighted:
+		// This must match diff.ChannelWeightedDiffMetric. Arrays in SQL are 1-indexed.
+		w := cfg.ChannelWeights
+		return fmt.Sprintf(`sqrt(percent_pixels_diff * sqrt((`+
+			`%g * max_rgba_diffs[1]::FLOAT8 * max_rgba_diffs[1]::FLOAT8 + `+
+			`%g * max_rgba_diffs[2]::FLOAT8 * max_rgba_diffs[2]::FLOAT8 + `+
+			`%g * max_rgba_diffs[3]::FLOAT8 * max_rgba_diffs[3]::FLOAT8 + `+
+			`%g * max_rgba_diffs[4]::FLOAT8 * max_rgba_diffs[4]::FLOAT8) / %g) / 255) ASC`,
+			w[0], w[1], w[2], w[3], w[0]+w[1]+w[2]+w[3])
+	default:
+		return "combined_metric ASC"
+	}
+}
+
+// queryMetric returns the distance between two digests according to the given config, by which
+// diffs can be compared to find the closest. Smaller is closer. ssim and deltaE are nil if they were
+// not computed for the diff.
+func queryMetric(cfg diff.MetricConfig, srdd *frontend.SRDiffDigest, ssim, deltaE *float32) float32 {
+	switch cfg.Metric {
+	case diff.MetricSSIM:
+		if ssim == nil {
+			return math.MaxFloat32
+		}
+		// SSIM is in [-1, 1], with 1 being identical.
+		return 1 - *ssim
+	case diff.MetricDeltaE:
+		if deltaE == nil {
+			return math.MaxFloat32
+		}
+		return *deltaE
+	case diff.MetricChannelWeighted:
+		return diff.ChannelWeightedDiffMetric(srdd.MaxRGBADiffs, srdd.PixelDiffPercent, cfg.ChannelWeights)
+	default:
+		return srdd.CombinedMetric
+	}
+}
+
 // getDigestsForGrouping returns the digests that were produced in the given range by any traces
 // which belong to the grouping and match the provided paramset (if provided). It returns digests
 // from traces regardless of the traces' ignore statuses. As per usual with a ParamSet, we use
@@ -1799,6 +1995,29 @@ func (s *Impl) fillOutTraceHistory(ctx context.Context, inputs []digestAndCloses
 	return results, nil
 }
 
+// addVariants fills in the variants of each of the given results, which must be parallel to
+// inputs.
+func (s *Impl) addVariants(ctx context.Context, inputs []digestAndClosestDiffs, results []*frontend.SearchResult) error {
+	if s.variantKey == "" {
+		return nil
+	}
+	ctx, span := trace.StartSpan(ctx, "addVariants")
+	defer span.End()
+	eg, eCtx := errgroup.WithContext(ctx)
+	for i := range inputs {
+		idx := i
+		eg.Go(func() error {
+			variants, err := s.getVariantsForTraces(eCtx, inputs[idx].groupingID, inputs[idx].traceIDs)
+			if err != nil {
+				return skerr.Wrap(err)
+			}
+			results[idx].Variants = variants
+			return nil
+		})
+	}
+	return skerr.Wrap(eg.Wait())
+}
+
 type traceDigestCommit struct {
 	commitID  schema.CommitID
 	digest    types.Digest
@@ -3646,6 +3865,13 @@ func (s *Impl) GetDigestDetails(ctx context.Context, grouping paramtools.Params,
 		}
 	}
 
+	if clID == "" {
+		result.Variants, err = s.getVariantsForTraces(ctx, digestAndClosestDiffs[0].groupingID, digestAndClosestDiffs[0].traceIDs)
+		if err != nil {
+			return frontend.DigestDetails{}, skerr.Wrap(err)
+		}
+	}
+
 	// Make sure the Test is set, even if the digest wasn't seen in the current window
 	// The frontend relies on this field to be able to triage the results.
 	result.Test = types.TestName(grouping[types.PrimaryKeyField])
@@ -3655,6 +3881,162 @@ func (s *Impl) GetDigestDetails(ctx context.Context, grouping paramtools.Params,
 	}, nil
 }
 
+// GetVariants implements the API interface.
+func (s *Impl) GetVariants(ctx context.Context, grouping paramtools.Params, digest types.Digest) ([]frontend.DigestVariant, error) {
+	if s.variantKey == "" {
+		return nil, nil
+	}
+	ctx, span := trace.StartSpan(ctx, "search2_GetVariants")
+	defer span.End()
+
+	ctx, err := s.addCommitsData(ctx)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	results, err := s.getTracesForGroupingAndDigest(ctx, grouping, digest)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	if s.isPublicView {
+		results = s.applyPublicFilterToDigestWithTraceAndGrouping(results)
+	}
+	var traceIDs []schema.TraceID
+	for _, r := range results {
+		if r.traceID != nil {
+			traceIDs = append(traceIDs, r.traceID)
+		}
+	}
+	_, groupingID := sql.SerializeMap(grouping)
+	return s.getVariantsForTraces(ctx, groupingID, traceIDs)
+}
+
+// getVariantsForTraces returns the distinct digests drawn at head by the variants of the given
+// traces, that is, by the other traces in the grouping whose keys only differ in the value of the
+// variant key. The digests drawn by the given traces themselves are not included.
+func (s *Impl) getVariantsForTraces(ctx context.Context, groupingID schema.GroupingID, traceIDs []schema.TraceID) ([]frontend.DigestVariant, error) {
+	if s.variantKey == "" || len(traceIDs) == 0 {
+		return nil, nil
+	}
+	ctx, span := trace.StartSpan(ctx, "getVariantsForTraces")
+	defer span.End()
+
+	// Ignored traces are only returned if they are one of the given traces, because we need to
+	// know the keys of those.
+	const statement = `SELECT trace_id, keys, digest FROM ValuesAtHead
+WHERE grouping_id = $1 AND most_recent_commit_id >= $2 AND keys->>$3 IS NOT NULL
+AND (matches_any_ignore_rule = FALSE OR trace_id = ANY($4))`
+	rows, err := s.db.Query(ctx, statement, groupingID, getFirstCommitID(ctx), s.variantKey, traceIDs)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+
+	given := make(map[schema.MD5Hash]bool, len(traceIDs))
+	for _, tID := range traceIDs {
+		given[sql.AsMD5Hash(tID)] = true
+	}
+	type variantTrace struct {
+		traceID   schema.TraceID
+		variant   string
+		digest    schema.DigestBytes
+		otherKeys string
+	}
+	var candidates []variantTrace
+	// wantedKeys are the serialized keys, minus the variant key, of the given traces.
+	wantedKeys := map[string]bool{}
+	for rows.Next() {
+		var vt variantTrace
+		var keys paramtools.Params
+		if err := rows.Scan(&vt.traceID, &keys, &vt.digest); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		vt.variant = keys[s.variantKey]
+		delete(keys, s.variantKey)
+		vt.otherKeys, _ = sql.SerializeMap(keys)
+		if given[sql.AsMD5Hash(vt.traceID)] {
+			wantedKeys[vt.otherKeys] = true
+			continue
+		}
+		candidates = append(candidates, vt)
+	}
+	rows.Close()
+
+	type variantAndDigest struct {
+		variant string
+		digest  schema.MD5Hash
+	}
+	seen := map[variantAndDigest]bool{}
+	var ret []frontend.DigestVariant
+	var digests []schema.DigestBytes
+	s.mutex.RLock()
+	for _, vt := range candidates {
+		if !wantedKeys[vt.otherKeys] {
+			continue
+		}
+		if s.isPublicView {
+			if _, ok := s.publiclyVisibleTraces[sql.AsMD5Hash(vt.traceID)]; !ok {
+				continue
+			}
+		}
+		key := variantAndDigest{variant: vt.variant, digest: sql.AsMD5Hash(vt.digest)}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, frontend.DigestVariant{
+			Variant: vt.variant,
+			Digest:  types.Digest(hex.EncodeToString(vt.digest)),
+			Status:  expectations.Untriaged,
+		})
+		digests = append(digests, vt.digest)
+	}
+	s.mutex.RUnlock()
+	if len(ret) == 0 {
+		return nil, nil
+	}
+
+	labels, err := s.getPrimaryBranchLabels(ctx, groupingID, digests)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	for i := range ret {
+		if label, ok := labels[ret[i].Digest]; ok {
+			ret[i].Status = label
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Variant != ret[j].Variant {
+			return ret[i].Variant < ret[j].Variant
+		}
+		return ret[i].Digest < ret[j].Digest
+	})
+	return ret, nil
+}
+
+// getPrimaryBranchLabels returns the labels of the given digests in the given grouping on the
+// primary branch. Digests without expectations are not in the returned map.
+func (s *Impl) getPrimaryBranchLabels(ctx context.Context, groupingID schema.GroupingID, digests []schema.DigestBytes) (map[types.Digest]expectations.Label, error) {
+	ctx, span := trace.StartSpan(ctx, "getPrimaryBranchLabels")
+	defer span.End()
+	const statement = `SELECT encode(digest, 'hex'), label FROM Expectations
+WHERE grouping_id = $1 AND digest = ANY($2)`
+	rows, err := s.db.Query(ctx, statement, groupingID, digests)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	ret := make(map[types.Digest]expectations.Label, len(digests))
+	for rows.Next() {
+		var digest types.Digest
+		var label schema.ExpectationLabel
+		if err := rows.Scan(&digest, &label); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		ret[digest] = label.ToExpectation()
+	}
+	return ret, nil
+}
+
 // applyPublicFilterToDigestWithTraceAndGrouping filters out any digestWithTraceAndGrouping for
 // traces that are not publicly visible.
 func (s *Impl) applyPublicFilterToDigestWithTraceAndGrouping(results []digestWithTraceAndGrouping) []digestWithTraceAndGrouping {
@@ -3777,6 +4159,10 @@ func (s *Impl) GetDigestsDiff(ctx context.Context, grouping paramtools.Params, l
 	if err != nil {
 		return frontend.DigestComparison{}, skerr.Wrapf(err, "missing diff information for %s-%s", left, right)
 	}
+	if cfg := s.diffMetricsByCorpus[grouping[types.CorpusField]]; cfg.Metric == diff.MetricChannelWeighted {
+		m := diff.ChannelWeightedDiffMetric(metrics.MaxRGBADiffs, metrics.PixelDiffPercent, cfg.ChannelWeights)
+		metrics.ChannelWeightedMetric = &m
+	}
 
 	leftLabel, err := s.getExpectationsForDigest(ctx, groupingID, leftBytes, crs, clID)
 	if err != nil {
@@ -3816,13 +4202,13 @@ func (s *Impl) GetDigestsDiff(ctx context.Context, grouping paramtools.Params, l
 func (s *Impl) getDiffBetween(ctx context.Context, left, right schema.DigestBytes) (frontend.SRDiffDigest, error) {
 	ctx, span := trace.StartSpan(ctx, "getDiffBetween")
 	defer span.End()
-	const statement = `SELECT num_pixels_diff, percent_pixels_diff, max_rgba_diffs,
-combined_metric, dimensions_differ
+	statement := `SELECT num_pixels_diff, percent_pixels_diff, max_rgba_diffs,
+combined_metric, dimensions_differ, ` + s.perceptualDiffColumns() + `
 FROM DiffMetrics WHERE left_digest = $1 and right_digest = $2 LIMIT 1`
 	row := s.db.QueryRow(ctx, statement, left, right)
 	var rv frontend.SRDiffDigest
 	if err := row.Scan(&rv.NumDiffPixels, &rv.PixelDiffPercent, &rv.MaxRGBADiffs,
-		&rv.CombinedMetric, &rv.DimDiffer); err != nil {
+		&rv.CombinedMetric, &rv.DimDiffer, &rv.SSIM, &rv.DeltaE); err != nil {
 		return frontend.SRDiffDigest{}, skerr.Wrap(err)
 	}
 	return rv, nil
@@ -4094,10 +4480,113 @@ FROM GitCommits WHERE GitCommits.commit_id = $1`, gs.LastCommit.ID)
 	if err != nil {
 		return frontend.GUIStatus{}, skerr.Wrap(err)
 	}
+	if err := s.addTriageSLOStats(ctx, xcs); err != nil {
+		return frontend.GUIStatus{}, skerr.Wrap(err)
+	}
 	gs.CorpStatus = xcs
 	return gs, nil
 }
 
+// addTriageSLOStats fills in the age of the oldest untriaged digest and the number of digests
+// triaged in the last day for the given corpora.
+func (s *Impl) addTriageSLOStats(ctx context.Context, xcs []frontend.GUICorpusStatus) error {
+	ctx, span := trace.StartSpan(ctx, "addTriageSLOStats")
+	defer span.End()
+	ts := now.Now(ctx)
+	oldest, err := s.getOldestUntriagedByCorpus(ctx)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	triaged, err := s.getTriagedCountByCorpus(ctx, ts.Add(-24*time.Hour))
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	for i := range xcs {
+		if firstSeen, ok := oldest[xcs[i].Name]; ok {
+			xcs[i].OldestUntriagedAgeSeconds = int64(ts.Sub(firstSeen) / time.Second)
+		}
+		xcs[i].TriagedLast24h = triaged[xcs[i].Name]
+	}
+	return nil
+}
+
+// getOldestUntriagedByCorpus returns, for every corpus with untriaged digests at head, the time
+// of the commit at which the oldest of them was first produced. Only the commits in the window
+// are considered, so an untriaged digest can be at most as old as the window.
+func (s *Impl) getOldestUntriagedByCorpus(ctx context.Context) (map[string]time.Time, error) {
+	ctx, span := trace.StartSpan(ctx, "getOldestUntriagedByCorpus")
+	defer span.End()
+	const statement = `WITH
+CommitsInWindow AS (
+	SELECT commit_id FROM CommitsWithData
+	ORDER BY commit_id DESC LIMIT $1
+),
+OldestCommitInWindow AS (
+	SELECT commit_id FROM CommitsInWindow
+	ORDER BY commit_id ASC LIMIT 1
+),
+UntriagedAtHead AS (
+	SELECT corpus, trace_id, ValuesAtHead.digest FROM ValuesAtHead
+	JOIN OldestCommitInWindow ON ValuesAtHead.most_recent_commit_id >= OldestCommitInWindow.commit_id
+	JOIN Expectations ON ValuesAtHead.grouping_id = Expectations.grouping_id AND
+		ValuesAtHead.digest = Expectations.digest AND label = 'u'
+	WHERE matches_any_ignore_rule = FALSE
+),
+FirstSeenByCorpus AS (
+	SELECT corpus, MIN(TraceValues.commit_id) AS commit_id FROM UntriagedAtHead
+	JOIN TraceValues ON UntriagedAtHead.trace_id = TraceValues.trace_id AND
+		UntriagedAtHead.digest = TraceValues.digest
+	JOIN OldestCommitInWindow ON TraceValues.commit_id >= OldestCommitInWindow.commit_id
+	GROUP BY corpus
+)
+SELECT corpus, commit_time FROM FirstSeenByCorpus
+JOIN GitCommits ON FirstSeenByCorpus.commit_id = GitCommits.commit_id`
+
+	rows, err := s.db.Query(ctx, statement, s.windowLength)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	rv := map[string]time.Time{}
+	for rows.Next() {
+		var corpus string
+		var ts time.Time
+		if err := rows.Scan(&corpus, &ts); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		rv[corpus] = ts.UTC()
+	}
+	return rv, nil
+}
+
+// getTriagedCountByCorpus returns how many digests were triaged as positive or negative on the
+// primary branch since the given time, per corpus.
+func (s *Impl) getTriagedCountByCorpus(ctx context.Context, since time.Time) (map[string]int, error) {
+	ctx, span := trace.StartSpan(ctx, "getTriagedCountByCorpus")
+	defer span.End()
+	const statement = `SELECT keys->>'source_type', COUNT(*) FROM ExpectationRecords
+JOIN ExpectationDeltas ON ExpectationRecords.expectation_record_id = ExpectationDeltas.expectation_record_id
+JOIN Groupings ON ExpectationDeltas.grouping_id = Groupings.grouping_id
+WHERE branch_name IS NULL AND triage_time >= $1 AND label_after != 'u'
+GROUP BY 1`
+
+	rows, err := s.db.Query(ctx, statement, since)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	rv := map[string]int{}
+	for rows.Next() {
+		var corpus string
+		var count int
+		if err := rows.Scan(&corpus, &count); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		rv[corpus] = count
+	}
+	return rv, nil
+}
+
 // getCorporaStatuses counts the untriaged digests for all corpora.
 func (s *Impl) getCorporaStatuses(ctx context.Context) ([]frontend.GUICorpusStatus, error) {
 	ctx, span := trace.StartSpan(ctx, "getCorporaStatuses")
@@ -4355,5 +4844,198 @@ func computeDigestIndices(traceGroup *frontend.TraceGroup, primary types.Digest)
 	return digestIndices, totalDigests
 }
 
+// GetFlakyTests implements the API interface. It is computed from the cached flaky traces, so the
+// results are as recent as the last cache update.
+func (s *Impl) GetFlakyTests(ctx context.Context, corpus string, limit int) (frontend.FlakyTestsResponse, error) {
+	ctx, span := trace.StartSpan(ctx, "search2_GetFlakyTests")
+	defer span.End()
+
+	byGrouping := map[schema.MD5Hash]*frontend.FlakyTest{}
+	s.mutex.RLock()
+	for _, ft := range s.flakyTraces {
+		if s.isPublicView {
+			if _, ok := s.publiclyVisibleTraces[ft.traceID]; !ok {
+				continue
+			}
+		}
+		test, ok := byGrouping[ft.groupingID]
+		if !ok {
+			test = &frontend.FlakyTest{}
+			byGrouping[ft.groupingID] = test
+		}
+		test.FlakyTraces++
+		if ft.numDigests > test.MaxDigests {
+			test.MaxDigests = ft.numDigests
+		}
+	}
+	s.mutex.RUnlock()
+
+	byCorpus := map[string][]frontend.FlakyTest{}
+	for groupingID, test := range byGrouping {
+		grouping, err := s.expandGrouping(ctx, groupingID)
+		if err != nil {
+			return frontend.FlakyTestsResponse{}, skerr.Wrap(err)
+		}
+		c := grouping[types.CorpusField]
+		if corpus != "" && c != corpus {
+			continue
+		}
+		test.Grouping = grouping
+		test.Test = types.TestName(grouping[types.PrimaryKeyField])
+		byCorpus[c] = append(byCorpus[c], *test)
+	}
+
+	var rv frontend.FlakyTestsResponse
+	for c, tests := range byCorpus {
+		// The tests with the most unstable traces go first.
+		sort.Slice(tests, func(i, j int) bool {
+			if tests[i].MaxDigests != tests[j].MaxDigests {
+				return tests[i].MaxDigests > tests[j].MaxDigests
+			}
+			if tests[i].FlakyTraces != tests[j].FlakyTraces {
+				return tests[i].FlakyTraces > tests[j].FlakyTraces
+			}
+			return tests[i].Test < tests[j].Test
+		})
+		if len(tests) > limit {
+			tests = tests[:limit]
+		}
+		rv.Corpora = append(rv.Corpora, frontend.FlakyCorpus{Corpus: c, Tests: tests})
+	}
+	sort.Slice(rv.Corpora, func(i, j int) bool {
+		return rv.Corpora[i].Corpus < rv.Corpora[j].Corpus
+	})
+	return rv, nil
+}
+
+// GetSearchFacets implements the API interface.
+func (s *Impl) GetSearchFacets(ctx context.Context, q *query.Search) (frontend.SearchFacetsResponse, error) {
+	ctx, span := trace.StartSpan(ctx, "search2_GetSearchFacets")
+	defer span.End()
+
+	ctx = context.WithValue(ctx, queryKey, *q)
+	ctx, err := s.addCommitsData(ctx)
+	if err != nil {
+		return frontend.SearchFacetsResponse{}, skerr.Wrap(err)
+	}
+	var traceDigests []digestWithTraceAndGrouping
+	if q.ChangelistID != "" {
+		if q.CodeReviewSystemID == "" {
+			return frontend.SearchFacetsResponse{}, skerr.Fmt("Code Review System (crs) must be specified")
+		}
+		if ctx, err = s.addCLData(ctx); err != nil {
+			return frontend.SearchFacetsResponse{}, skerr.Wrap(err)
+		}
+		traceDigests, err = s.getMatchingDigestsAndTracesForCL(ctx)
+	} else {
+		traceDigests, err = s.getMatchingDigestsAndTraces(ctx)
+	}
+	if err != nil {
+		return frontend.SearchFacetsResponse{}, skerr.Wrap(err)
+	}
+
+	keysByTrace, err := s.getTraceKeys(ctx, traceDigests)
+	if err != nil {
+		return frontend.SearchFacetsResponse{}, skerr.Wrap(err)
+	}
+	return countFacets(traceDigests, keysByTrace), nil
+}
+
+// getTraceKeys returns the keys of all the given traces, using the trace cache where possible and
+// loading the rest in a single query.
+func (s *Impl) getTraceKeys(ctx context.Context, traceDigests []digestWithTraceAndGrouping) (map[schema.MD5Hash]paramtools.Params, error) {
+	ctx, span := trace.StartSpan(ctx, "getTraceKeys")
+	defer span.End()
+	keysByTrace := make(map[schema.MD5Hash]paramtools.Params, len(traceDigests))
+	var cacheMisses []schema.TraceID
+	for _, td := range traceDigests {
+		traceID := sql.AsMD5Hash(td.traceID)
+		if _, ok := keysByTrace[traceID]; ok {
+			continue
+		}
+		if keys, ok := s.traceCache.Get(string(td.traceID)); ok {
+			keysByTrace[traceID] = keys.(paramtools.Params)
+		} else {
+			// Mark the trace as seen so it is only looked up once.
+			keysByTrace[traceID] = nil
+			cacheMisses = append(cacheMisses, td.traceID)
+		}
+	}
+	if len(cacheMisses) == 0 {
+		return keysByTrace, nil
+	}
+	const statement = `SELECT trace_id, keys FROM Traces WHERE trace_id = ANY($1)`
+	rows, err := s.db.Query(ctx, statement, cacheMisses)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var traceID schema.TraceID
+	for rows.Next() {
+		var keys paramtools.Params
+		if err := rows.Scan(&traceID, &keys); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		s.traceCache.Add(string(traceID), keys)
+		keysByTrace[sql.AsMD5Hash(traceID)] = keys
+	}
+	return keysByTrace, nil
+}
+
+// countFacets groups the given traces and digests into search results (one per digest and
+// grouping) and counts, for each key=value of the traces, how many of those results were produced
+// by at least one trace with that key=value. Keys for which every value is shared by all results
+// would not narrow down the search, so they are omitted.
+func countFacets(traceDigests []digestWithTraceAndGrouping, keysByTrace map[schema.MD5Hash]paramtools.Params) frontend.SearchFacetsResponse {
+	type result struct {
+		groupingID schema.MD5Hash
+		digest     schema.MD5Hash
+	}
+	type keyValue struct {
+		key   string
+		value string
+	}
+	resultsByKeyValue := map[keyValue]map[result]bool{}
+	allResults := map[result]bool{}
+	for _, td := range traceDigests {
+		r := result{groupingID: sql.AsMD5Hash(td.groupingID), digest: sql.AsMD5Hash(td.digest)}
+		allResults[r] = true
+		for k, v := range keysByTrace[sql.AsMD5Hash(td.traceID)] {
+			kv := keyValue{key: k, value: v}
+			if resultsByKeyValue[kv] == nil {
+				resultsByKeyValue[kv] = map[result]bool{}
+			}
+			resultsByKeyValue[kv][r] = true
+		}
+	}
+
+	valuesByKey := map[string][]frontend.FacetValue{}
+	narrows := map[string]bool{}
+	for kv, results := range resultsByKeyValue {
+		valuesByKey[kv.key] = append(valuesByKey[kv.key], frontend.FacetValue{Value: kv.value, Count: len(results)})
+		if len(results) < len(allResults) {
+			narrows[kv.key] = true
+		}
+	}
+
+	rv := frontend.SearchFacetsResponse{Total: len(allResults)}
+	for key, values := range valuesByKey {
+		if !narrows[key] {
+			continue
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		rv.Facets = append(rv.Facets, frontend.SearchFacet{Key: key, Values: values})
+	}
+	sort.Slice(rv.Facets, func(i, j int) bool {
+		return rv.Facets[i].Key < rv.Facets[j].Key
+	})
+	return rv
+}
+
 // Make sure Impl implements the API interface.
 var _ API = (*Impl)(nil)
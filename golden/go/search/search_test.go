@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,7 +16,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"go.goldmine.build/go/now"
 	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/golden/go/diff"
 	"go.goldmine.build/golden/go/expectations"
 	"go.goldmine.build/golden/go/publicparams"
 	"go.goldmine.build/golden/go/search/query"
@@ -47,7 +50,8 @@ func TestNewAndUntriagedSummaryForCL_OnePatchset_Success(t *testing.T) {
 	assert.Equal(t, NewAndUntriagedSummary{
 		ChangelistID: dks.ChangelistIDThatAttemptsToFixIOS,
 		PatchsetSummaries: []PatchsetNewAndUntriagedSummary{{
-			NewImages: 2, // DigestC07Unt_CL and DigestC06Pos_CL
+			NewImages:    2, // DigestC07Unt_CL and DigestC06Pos_CL
+			ChangedTests: 1,
 			// Only 1 of the two CLs "new images" is untriaged, so that's what we report.
 			NewUntriagedImages: 1,
 			// In addition to DigestC07Unt_CL, this PS produces DigestC05Unt and DigestB01Pos
@@ -77,6 +81,7 @@ func TestNewAndUntriagedSummaryForCL_OnePatchsetWithMultipleDatapointsOnSameTrac
 			// Digests DigestC01Pos, DigestC03Unt and DigestC04Unt were produced on the same trace
 			// at this patchset (i.e. multiple datapoints per trace at the same patchset).
 			NewImages:            3,
+			ChangedTests:         1,
 			NewUntriagedImages:   2,
 			TotalUntriagedImages: 2,
 			PatchsetID:           dks.PatchsetIDWithMultipleDatapointsPerTrace,
@@ -104,6 +109,7 @@ func TestNewAndUntriagedSummaryForCL_TwoPatchsets_Success(t *testing.T) {
 			// before (DigestBlank). This digest *had* been seen on the primary branch in a
 			// different grouping, but that should not prevent us from letting a developer know.
 			NewImages:          1,
+			ChangedTests:       1,
 			NewUntriagedImages: 1,
 			// Two circle tests are producing DigestC03Unt and DigestC04Unt
 			TotalUntriagedImages: 3,
@@ -113,6 +119,7 @@ func TestNewAndUntriagedSummaryForCL_TwoPatchsets_Success(t *testing.T) {
 			// Two groupings (Text-Seven and Round-RoundRect) produced 1 and 3 new digests
 			// respectively. DigestE03Unt_CL remains untriaged.
 			NewImages:          4,
+			ChangedTests:       2,
 			NewUntriagedImages: 1,
 			// Two circle tests are producing DigestC03Unt and DigestC04Unt
 			TotalUntriagedImages: 3,
@@ -171,12 +178,14 @@ func TestNewAndUntriagedSummaryForCL_NoNewDataForPS_Success(t *testing.T) {
 		// Should be sorted by PatchsetOrder
 		PatchsetSummaries: []PatchsetNewAndUntriagedSummary{{
 			NewImages:            0,
+			ChangedTests:         0,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps1ID,
 			PatchsetOrder:        2,
 		}, {
 			NewImages:            0,
+			ChangedTests:         0,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps2ID,
@@ -272,18 +281,21 @@ func TestNewAndUntriagedSummaryForCL_NewDeviceAdded_DigestsOnPrimaryBranchNotCou
 		// Should be sorted by PatchsetOrder
 		PatchsetSummaries: []PatchsetNewAndUntriagedSummary{{
 			NewImages:            1,
+			ChangedTests:         1,
 			NewUntriagedImages:   1,
 			TotalUntriagedImages: 1,
 			PatchsetID:           ps1ID,
 			PatchsetOrder:        2,
 		}, {
 			NewImages:            1,
+			ChangedTests:         1,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps2ID,
 			PatchsetOrder:        4,
 		}, {
 			NewImages:            0,
+			ChangedTests:         0,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps3ID,
@@ -360,12 +372,14 @@ func TestNewAndUntriagedSummaryForCL_IgnoreRulesRespected(t *testing.T) {
 		// Should be sorted by PatchsetOrder
 		PatchsetSummaries: []PatchsetNewAndUntriagedSummary{{
 			NewImages:            2,
+			ChangedTests:         2,
 			NewUntriagedImages:   2,
 			TotalUntriagedImages: 3,
 			PatchsetID:           ps1ID,
 			PatchsetOrder:        1,
 		}, {
 			NewImages:            0,
+			ChangedTests:         0,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 1,
 			PatchsetID:           ps2ID,
@@ -443,18 +457,21 @@ func TestNewAndUntriagedSummaryForCL_TriageStatusAffectsAllPS(t *testing.T) {
 		// Should be sorted by PatchsetOrder
 		PatchsetSummaries: []PatchsetNewAndUntriagedSummary{{
 			NewImages:            1,
+			ChangedTests:         1,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps1ID,
 			PatchsetOrder:        2,
 		}, {
 			NewImages:            1,
+			ChangedTests:         1,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps2ID,
 			PatchsetOrder:        4,
 		}, {
 			NewImages:            1,
+			ChangedTests:         1,
 			NewUntriagedImages:   0,
 			TotalUntriagedImages: 0,
 			PatchsetID:           ps3ID,
@@ -484,7 +501,8 @@ func TestNewAndUntriagedSummaryForCL_MultipleThreadsAtOnce_NoRaces(t *testing.T)
 			assert.Equal(t, NewAndUntriagedSummary{
 				ChangelistID: dks.ChangelistIDThatAttemptsToFixIOS,
 				PatchsetSummaries: []PatchsetNewAndUntriagedSummary{{
-					NewImages: 2, // DigestC07Unt_CL and DigestC06Pos_CL
+					NewImages:    2, // DigestC07Unt_CL and DigestC06Pos_CL
+					ChangedTests: 1,
 					// Only 1 of the two CLs "new images" is untriaged, so that's what we report.
 					NewUntriagedImages: 1,
 					// In addition to DigestC07Unt_CL, this PS produces DigestC05Unt and DigestB01Pos
@@ -823,6 +841,16 @@ func TestStartMaterializedViews_ViewsGetUpdated(t *testing.T) {
 	assertNumRows(t, db, "mv_round_traces", 10)
 }
 
+func TestUseMaterializedViews_ViewsCreatedElsewhere_ViewsAreUsed(t *testing.T) {
+
+	s := New(nil, 10)
+	s.UseMaterializedViews([]string{dks.CornersCorpus})
+
+	assert.Equal(t, "mv_corners_traces", s.getMaterializedView(unignoredRecentTracesView, dks.CornersCorpus))
+	assert.Equal(t, "mv_corners_"+byBlameView, s.getMaterializedView(byBlameView, dks.CornersCorpus))
+	assert.Empty(t, s.getMaterializedView(unignoredRecentTracesView, dks.RoundCorpus))
+}
+
 func assertNumRows(t *testing.T, db *pgxpool.Pool, tableName string, rowCount int) {
 	row := db.QueryRow(context.Background(), `SELECT COUNT(*) FROM `+tableName)
 	var count int
@@ -6674,6 +6702,56 @@ func TestGetDigestDetails_ValidDigestAndGroupingOnPrimary_Success(t *testing.T)
 	}, details)
 }
 
+func TestGetDigestDetails_VariantKeySet_IncludesVariants(t *testing.T) {
+	ctx := context.Background()
+	db := useKitchenSinkData(ctx, t)
+
+	inputGrouping := paramtools.Params{
+		types.PrimaryKeyField: dks.CircleTest,
+		types.CorpusField:     dks.RoundCorpus,
+	}
+
+	s := New(db, 100)
+	s.SetVariantKey(dks.ColorModeKey)
+	details, err := s.GetDigestDetails(ctx, inputGrouping, dks.DigestC02Pos, "", "")
+	require.NoError(t, err)
+	// The GREY traces which drew C02 are the iPhone, iPad, Walleye and Windows 10.2 ones. Their RGB
+	// counterparts draw C01 (Walleye and Windows 10.2) and C05 (iPhone and iPad) at head.
+	assert.Equal(t, []frontend.DigestVariant{
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC01Pos, Status: expectations.Positive},
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC05Unt, Status: expectations.Untriaged},
+	}, details.Result.Variants)
+}
+
+func TestGetVariants_VariantKeySet_ReturnsDigestsOfOtherVariants(t *testing.T) {
+	ctx := context.Background()
+	db := useKitchenSinkData(ctx, t)
+
+	inputGrouping := paramtools.Params{
+		types.PrimaryKeyField: dks.CircleTest,
+		types.CorpusField:     dks.RoundCorpus,
+	}
+
+	s := New(db, 100)
+	s.SetVariantKey(dks.ColorModeKey)
+	variants, err := s.GetVariants(ctx, inputGrouping, dks.DigestC02Pos)
+	require.NoError(t, err)
+	assert.Equal(t, []frontend.DigestVariant{
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC01Pos, Status: expectations.Positive},
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC05Unt, Status: expectations.Untriaged},
+	}, variants)
+}
+
+func TestGetVariants_NoVariantKey_ReturnsNothing(t *testing.T) {
+	s := New(nil, 100)
+	variants, err := s.GetVariants(context.Background(), paramtools.Params{
+		types.PrimaryKeyField: dks.CircleTest,
+		types.CorpusField:     dks.RoundCorpus,
+	}, dks.DigestC02Pos)
+	require.NoError(t, err)
+	assert.Empty(t, variants)
+}
+
 func TestGetDigestDetails_ValidDigestAndGroupingOnPrimary_PublicView_SomeTracesMatchPubliclyAllowedParams_Success(t *testing.T) {
 	ctx := context.Background()
 	db := useKitchenSinkData(ctx, t)
@@ -7200,6 +7278,7 @@ func TestGetDigestsDiff_TwoKnownDigests_Success(t *testing.T) {
 	}
 
 	s := New(db, 100)
+	s.SetPerceptualDiffMetrics(true)
 	rv, err := s.GetDigestsDiff(ctx, inputGrouping, dks.DigestC01Pos, dks.DigestC03Unt, "", "")
 	require.NoError(t, err)
 	assert.Equal(t, frontend.DigestComparison{
@@ -7219,6 +7298,8 @@ func TestGetDigestsDiff_TwoKnownDigests_Success(t *testing.T) {
 		Right: frontend.SRDiffDigest{
 			CombinedMetric: 0.89245414, PixelDiffPercent: 50, NumDiffPixels: 32,
 			MaxRGBADiffs: [4]int{1, 7, 4, 0},
+			SSIM:         f32(0.99896586),
+			DeltaE:       f32(1.8270694),
 			DimDiffer:    false,
 			Digest:       dks.DigestC03Unt,
 			Status:       expectations.Untriaged,
@@ -7270,6 +7351,7 @@ func TestGetDigestsDiff_TwoKnownDigestsOnACL_Success(t *testing.T) {
 	}
 
 	s := New(db, 100)
+	s.SetPerceptualDiffMetrics(true)
 	rv, err := s.GetDigestsDiff(ctx, inputGrouping, dks.DigestC01Pos, dks.DigestC06Pos_CL, dks.ChangelistIDThatAttemptsToFixIOS, dks.GitHubCRS)
 	require.NoError(t, err)
 	assert.Equal(t, frontend.DigestComparison{
@@ -7289,6 +7371,8 @@ func TestGetDigestsDiff_TwoKnownDigestsOnACL_Success(t *testing.T) {
 		Right: frontend.SRDiffDigest{
 			CombinedMetric: 1.0217842, PixelDiffPercent: 6.25, NumDiffPixels: 4,
 			MaxRGBADiffs: [4]int{15, 12, 83, 0},
+			SSIM:         f32(0.99949604),
+			DeltaE:       f32(1.4970057),
 			DimDiffer:    false,
 			Digest:       dks.DigestC06Pos_CL,
 			Status:       expectations.Positive,
@@ -7314,6 +7398,7 @@ func TestGetDigestsDiff_UntriagedDigestOnKnownCL_ReturnsDiffAndParamset(t *testi
 	}
 
 	s := New(db, 100)
+	s.SetPerceptualDiffMetrics(true)
 	rv, err := s.GetDigestsDiff(ctx, inputGrouping, dks.DigestC01Pos, dks.DigestC07Unt_CL, dks.ChangelistIDThatAttemptsToFixIOS, dks.GitHubCRS)
 	require.NoError(t, err)
 	assert.Equal(t, frontend.DigestComparison{
@@ -7333,6 +7418,8 @@ func TestGetDigestsDiff_UntriagedDigestOnKnownCL_ReturnsDiffAndParamset(t *testi
 		Right: frontend.SRDiffDigest{
 			CombinedMetric: 7.0776105, PixelDiffPercent: 100, NumDiffPixels: 64,
 			MaxRGBADiffs: [4]int{141, 131, 168, 0},
+			SSIM:         f32(0.7702559),
+			DeltaE:       f32(86.01002),
 			DimDiffer:    false,
 			Digest:       dks.DigestC07Unt_CL,
 			Status:       expectations.Untriaged,
@@ -7358,6 +7445,7 @@ func TestGetDigestsDiff_TwoKnownDigestsOnACL_OnePatchsetWithMultipleDatapointsOn
 	}
 
 	s := New(db, 100)
+	s.SetPerceptualDiffMetrics(true)
 	// In this CL a tryjob was executed multiple times at the last patchset, generating multiple
 	// datapoints for the same trace at the last patchset. DigestC01Pos was drawn on the last two
 	// tryjob runs.
@@ -7380,6 +7468,8 @@ func TestGetDigestsDiff_TwoKnownDigestsOnACL_OnePatchsetWithMultipleDatapointsOn
 		Right: frontend.SRDiffDigest{
 			CombinedMetric: 9.14646, PixelDiffPercent: 100, NumDiffPixels: 64,
 			MaxRGBADiffs: [4]int{228, 255, 255, 0},
+			SSIM:         f32(0.41078725),
+			DeltaE:       f32(101.06873),
 			DimDiffer:    false,
 			Digest:       dks.DigestA01Pos,
 			Status:       expectations.Positive,
@@ -7408,6 +7498,7 @@ func TestGetDigestsDiff_DigestsExistOnPrimaryInvalidCL_ReturnDiffAndPrimaryParam
 	}
 
 	s := New(db, 100)
+	s.SetPerceptualDiffMetrics(true)
 	rv, err := s.GetDigestsDiff(ctx, inputGrouping, dks.DigestC01Pos, dks.DigestC03Unt, "not a real CL", dks.GitHubCRS)
 	require.NoError(t, err)
 	assert.Equal(t, frontend.DigestComparison{
@@ -7427,6 +7518,8 @@ func TestGetDigestsDiff_DigestsExistOnPrimaryInvalidCL_ReturnDiffAndPrimaryParam
 		Right: frontend.SRDiffDigest{
 			CombinedMetric: 0.89245414, PixelDiffPercent: 50, NumDiffPixels: 32,
 			MaxRGBADiffs: [4]int{1, 7, 4, 0},
+			SSIM:         f32(0.99896586),
+			DeltaE:       f32(1.8270694),
 			DimDiffer:    false,
 			Digest:       dks.DigestC03Unt,
 			Status:       expectations.Untriaged,
@@ -7453,6 +7546,7 @@ func TestGetDigestsDiff_UntriagedDigestOnUnknownCL_ReturnsDiffButNoRightParamset
 	}
 
 	s := New(db, 100)
+	s.SetPerceptualDiffMetrics(true)
 	rv, err := s.GetDigestsDiff(ctx, inputGrouping, dks.DigestC01Pos, dks.DigestC06Pos_CL, "not a real CL", dks.GitHubCRS)
 	require.NoError(t, err)
 	assert.Equal(t, frontend.DigestComparison{
@@ -7474,6 +7568,8 @@ func TestGetDigestsDiff_UntriagedDigestOnUnknownCL_ReturnsDiffButNoRightParamset
 		Right: frontend.SRDiffDigest{
 			CombinedMetric: 1.0217842, PixelDiffPercent: 6.25, NumDiffPixels: 4,
 			MaxRGBADiffs: [4]int{15, 12, 83, 0},
+			SSIM:         f32(0.99949604),
+			DeltaE:       f32(1.4970057),
 			DimDiffer:    false,
 			Digest:       dks.DigestC06Pos_CL,
 			Status:       expectations.Untriaged,
@@ -7670,6 +7766,7 @@ func TestComputeGUIStatus_Success(t *testing.T) {
 
 	s := New(db, 100)
 
+	ctx = context.WithValue(ctx, now.ContextKey, time.Date(2020, time.December, 12, 0, 0, 0, 0, time.UTC))
 	res, err := s.ComputeGUIStatus(ctx)
 	require.NoError(t, err)
 
@@ -7685,10 +7782,15 @@ func TestComputeGUIStatus_Success(t *testing.T) {
 			{
 				Name:           dks.CornersCorpus,
 				UntriagedCount: 0,
+				// DigestA08Pos and DigestA09Neg were triaged on 2020-12-11.
+				TriagedLast24h: 2,
 			},
 			{
 				Name:           dks.RoundCorpus,
 				UntriagedCount: 3,
+				// DigestC03Unt and DigestC04Unt were first drawn at the Windows driver update
+				// (2020-12-04T00:00:00Z).
+				OldestUntriagedAgeSeconds: 8 * 24 * 60 * 60,
 			},
 		},
 	}, res)
@@ -7707,6 +7809,7 @@ func TestComputeGUIStatus_GitCommitIDsDoNotMatch_Success(t *testing.T) {
 
 	s := New(db, 100)
 
+	ctx = context.WithValue(ctx, now.ContextKey, time.Date(2020, time.December, 12, 0, 0, 0, 0, time.UTC))
 	res, err := s.ComputeGUIStatus(ctx)
 	require.NoError(t, err)
 
@@ -7719,8 +7822,10 @@ func TestComputeGUIStatus_GitCommitIDsDoNotMatch_Success(t *testing.T) {
 			{
 				Name:           dks.CornersCorpus,
 				UntriagedCount: 0,
+				TriagedLast24h: 2,
 			},
 			{
+				// Without the commit times, the age of the untriaged digests is unknown.
 				Name:           dks.RoundCorpus,
 				UntriagedCount: 3,
 			},
@@ -7991,6 +8096,125 @@ func makeKitchenSinkCommits() []frontend.Commit {
 
 // waitForSystemTime waits for a time greater than the duration mentioned in "AS OF SYSTEM TIME"
 // clauses in queries. This way, the queries will be accurate.
+func TestQueryMetric_DependsOnConfiguredMetric(t *testing.T) {
+	srdd := &frontend.SRDiffDigest{
+		CombinedMetric:   0.5,
+		PixelDiffPercent: 10,
+		MaxRGBADiffs:     [4]int{0, 0, 0, 200},
+	}
+	ssim, deltaE := f32(0.75), f32(3)
+	assert.Equal(t, float32(0.5), queryMetric(diff.MetricConfig{}, srdd, ssim, deltaE))
+	assert.Equal(t, float32(0.25), queryMetric(diff.MetricConfig{Metric: diff.MetricSSIM}, srdd, ssim, deltaE))
+	assert.Equal(t, float32(3), queryMetric(diff.MetricConfig{Metric: diff.MetricDeltaE}, srdd, ssim, deltaE))
+	// Only the alpha channel differs, which this config ignores.
+	assert.Equal(t, float32(0), queryMetric(diff.MetricConfig{
+		Metric:         diff.MetricChannelWeighted,
+		ChannelWeights: [4]float32{1, 1, 1, 0},
+	}, srdd, ssim, deltaE))
+	// Diffs computed before SSIM was introduced are never the closest.
+	assert.Equal(t, float32(math.MaxFloat32), queryMetric(diff.MetricConfig{Metric: diff.MetricSSIM}, srdd, nil, nil))
+}
+
+func TestSetDiffMetrics_InvalidConfig_ReturnsError(t *testing.T) {
+	s := New(nil, 100)
+	s.SetPerceptualDiffMetrics(true)
+	require.NoError(t, s.SetDiffMetrics(map[string]diff.MetricConfig{dks.RoundCorpus: {Metric: diff.MetricSSIM}}))
+	err := s.SetDiffMetrics(map[string]diff.MetricConfig{dks.CornersCorpus: {Metric: "pixel_count"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), dks.CornersCorpus)
+}
+
+func TestSetDiffMetrics_PerceptualMetricNotComputed_ReturnsError(t *testing.T) {
+	s := New(nil, 100)
+	require.NoError(t, s.SetDiffMetrics(map[string]diff.MetricConfig{dks.RoundCorpus: {Metric: diff.MetricCombined}}))
+	err := s.SetDiffMetrics(map[string]diff.MetricConfig{dks.RoundCorpus: {Metric: diff.MetricDeltaE}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "perceptual")
+}
+
+func TestGetFlakyTests_CachedFlakyTraces_GroupedByCorpusMostUnstableFirst(t *testing.T) {
+	ctx := context.Background()
+	s := New(nil, 100)
+	circle := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	square := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
+	triangle := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.TriangleTest}
+	groupingIDs := map[string]schema.MD5Hash{}
+	for _, g := range []paramtools.Params{circle, square, triangle} {
+		_, id := sql.SerializeMap(g)
+		s.optionsGroupingCache.Add(sql.AsMD5Hash(id), g)
+		groupingIDs[g[types.PrimaryKeyField]] = sql.AsMD5Hash(id)
+	}
+	s.flakyTraces = []flakyTrace{
+		{traceID: schema.MD5Hash{0x01}, groupingID: groupingIDs[dks.CircleTest], numDigests: 2},
+		{traceID: schema.MD5Hash{0x02}, groupingID: groupingIDs[dks.SquareTest], numDigests: 2},
+		{traceID: schema.MD5Hash{0x03}, groupingID: groupingIDs[dks.SquareTest], numDigests: 3},
+		{traceID: schema.MD5Hash{0x04}, groupingID: groupingIDs[dks.TriangleTest], numDigests: 4},
+	}
+
+	resp, err := s.GetFlakyTests(ctx, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, frontend.FlakyTestsResponse{
+		Corpora: []frontend.FlakyCorpus{{
+			Corpus: dks.CornersCorpus,
+			Tests: []frontend.FlakyTest{
+				{Grouping: triangle, Test: dks.TriangleTest, FlakyTraces: 1, MaxDigests: 4},
+				{Grouping: square, Test: dks.SquareTest, FlakyTraces: 2, MaxDigests: 3},
+			},
+		}, {
+			Corpus: dks.RoundCorpus,
+			Tests: []frontend.FlakyTest{
+				{Grouping: circle, Test: dks.CircleTest, FlakyTraces: 1, MaxDigests: 2},
+			},
+		}},
+	}, resp)
+
+	resp, err = s.GetFlakyTests(ctx, dks.CornersCorpus, 1)
+	require.NoError(t, err)
+	assert.Equal(t, frontend.FlakyTestsResponse{
+		Corpora: []frontend.FlakyCorpus{{
+			Corpus: dks.CornersCorpus,
+			Tests: []frontend.FlakyTest{
+				{Grouping: triangle, Test: dks.TriangleTest, FlakyTraces: 1, MaxDigests: 4},
+			},
+		}},
+	}, resp)
+}
+
+func TestCountFacets_ResultsCountedOncePerKeyValue_NonNarrowingKeysOmitted(t *testing.T) {
+	circle := schema.GroupingID{0x01}
+	square := schema.GroupingID{0x02}
+	androidTrace, iosTrace, windowsTrace := schema.TraceID{0x0a}, schema.TraceID{0x0b}, schema.TraceID{0x0c}
+	keysByTrace := map[schema.MD5Hash]paramtools.Params{
+		sql.AsMD5Hash(androidTrace): {types.CorpusField: dks.RoundCorpus, dks.OSKey: dks.AndroidOS},
+		sql.AsMD5Hash(iosTrace):     {types.CorpusField: dks.RoundCorpus, dks.OSKey: dks.IOS},
+		sql.AsMD5Hash(windowsTrace): {types.CorpusField: dks.RoundCorpus, dks.OSKey: dks.Windows10dot2OS},
+	}
+	traceDigests := []digestWithTraceAndGrouping{
+		// The same digest for the same grouping is a single result, even if several traces
+		// produced it.
+		{traceID: androidTrace, groupingID: circle, digest: schema.DigestBytes{0xa1}},
+		{traceID: iosTrace, groupingID: circle, digest: schema.DigestBytes{0xa1}},
+		{traceID: windowsTrace, groupingID: circle, digest: schema.DigestBytes{0xa2}},
+		{traceID: iosTrace, groupingID: square, digest: schema.DigestBytes{0xa1}},
+	}
+
+	assert.Equal(t, frontend.SearchFacetsResponse{
+		Total: 3,
+		Facets: []frontend.SearchFacet{{
+			Key: dks.OSKey,
+			Values: []frontend.FacetValue{
+				{Value: dks.IOS, Count: 2},
+				{Value: dks.AndroidOS, Count: 1},
+				{Value: dks.Windows10dot2OS, Count: 1},
+			},
+		}},
+	}, countFacets(traceDigests, keysByTrace))
+}
+
+func TestCountFacets_NoResults_Empty(t *testing.T) {
+	assert.Equal(t, frontend.SearchFacetsResponse{}, countFacets(nil, nil))
+}
+
 func waitForSystemTime() {
 	time.Sleep(150 * time.Millisecond)
 }
@@ -8021,3 +8245,7 @@ func digestToBytes(t *testing.T, digest types.Digest) []byte {
 	require.NoError(t, err)
 	return bytes
 }
+
+func f32(f float32) *float32 {
+	return &f
+}
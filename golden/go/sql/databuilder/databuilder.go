@@ -313,6 +313,8 @@ func (b *TablesBuilder) ComputeDiffMetricsFromImages(imgDir string, nowStr strin
 					CombinedMetric:    dm.CombinedMetric,
 					DimensionsDiffer:  dm.DimDiffer,
 					Timestamp:         now,
+					SSIM:              &dm.SSIM,
+					DeltaE:            &dm.DeltaE,
 				})
 				// And in the other order of left-right
 				b.diffMetrics = append(b.diffMetrics, schema.DiffMetricRow{
@@ -325,6 +327,8 @@ func (b *TablesBuilder) ComputeDiffMetricsFromImages(imgDir string, nowStr strin
 					CombinedMetric:    dm.CombinedMetric,
 					DimensionsDiffer:  dm.DimDiffer,
 					Timestamp:         now,
+					SSIM:              &dm.SSIM,
+					DeltaE:            &dm.DeltaE,
 				})
 			}
 		}
@@ -345,6 +345,8 @@ func TestBuild_CalledWithValidInput_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    2.9445405,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.6836763),
+		DeltaE:            f32(10.9005785),
 	}, {
 		LeftDigest:        d(t, digestB),
 		RightDigest:       d(t, digestA),
@@ -355,6 +357,8 @@ func TestBuild_CalledWithValidInput_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    2.9445405,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.6836763),
+		DeltaE:            f32(10.9005785),
 	}, {
 		LeftDigest:        d(t, digestC),
 		RightDigest:       d(t, digestD),
@@ -365,6 +369,8 @@ func TestBuild_CalledWithValidInput_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    3.4844475,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.86827904),
+		DeltaE:            f32(16.174389),
 	}, {
 		LeftDigest:        d(t, digestD),
 		RightDigest:       d(t, digestC),
@@ -375,6 +381,8 @@ func TestBuild_CalledWithValidInput_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    3.4844475,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.86827904),
+		DeltaE:            f32(16.174389),
 	}}, tables.DiffMetrics)
 	assert.ElementsMatch(t, []schema.TiledTraceDigestRow{{
 		TraceID:    h(`{"color_mode":"rgb","device":"Crosshatch","name":"test_one","os":"Android","source_type":"corpus_one"}`),
@@ -814,6 +822,8 @@ func TestBuild_CalledWithChangelistData_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    2.9445405,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.6836763),
+		DeltaE:            f32(10.9005785),
 	}, {
 		LeftDigest:        d(t, digestB),
 		RightDigest:       d(t, digestA),
@@ -824,6 +834,8 @@ func TestBuild_CalledWithChangelistData_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    2.9445405,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.6836763),
+		DeltaE:            f32(10.9005785),
 	}, {
 		LeftDigest:        d(t, digestC),
 		RightDigest:       d(t, digestD),
@@ -834,6 +846,8 @@ func TestBuild_CalledWithChangelistData_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    3.4844475,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.86827904),
+		DeltaE:            f32(16.174389),
 	}, {
 		LeftDigest:        d(t, digestD),
 		RightDigest:       d(t, digestC),
@@ -844,6 +858,8 @@ func TestBuild_CalledWithChangelistData_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    3.4844475,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.86827904),
+		DeltaE:            f32(16.174389),
 	}, { // The following 2 were calculated on the new test introduced by this CL
 		LeftDigest:        d(t, digestA),
 		RightDigest:       d(t, digestD),
@@ -854,6 +870,8 @@ func TestBuild_CalledWithChangelistData_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    9.653383,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.038881566),
+		DeltaE:            f32(28.334799),
 	}, {
 		LeftDigest:        d(t, digestD),
 		RightDigest:       d(t, digestA),
@@ -864,6 +882,8 @@ func TestBuild_CalledWithChangelistData_ProducesCorrectData(t *testing.T) {
 		CombinedMetric:    9.653383,
 		DimensionsDiffer:  false,
 		Timestamp:         ts,
+		SSIM:              f32(0.038881566),
+		DeltaE:            f32(28.334799),
 	}}, tables.DiffMetrics)
 }
 
@@ -1686,6 +1706,10 @@ func d(t *testing.T, digest types.Digest) []byte {
 	return b
 }
 
+func f32(f float32) *float32 {
+	return &f
+}
+
 // The generated gitHash is simply the sha1 sum of the commit id.
 func gitHash(cID schema.CommitID) string {
 	h := sha1.Sum([]byte(cID))
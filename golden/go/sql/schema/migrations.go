@@ -0,0 +1,8 @@
+package schema
+
+// Migrations brings tables created by an older version of Schema up to date. CREATE TABLE IF NOT
+// EXISTS leaves existing tables untouched, so columns added to a table after it was created must
+// be added here as well. Every statement must be safe to run more than once.
+const Migrations = `ALTER TABLE DiffMetrics ADD COLUMN IF NOT EXISTS ssim FLOAT4;
+ALTER TABLE DiffMetrics ADD COLUMN IF NOT EXISTS delta_e FLOAT4;
+`
@@ -13,6 +13,16 @@ const Schema = `CREATE TABLE IF NOT EXISTS Changelists (
   INDEX system_status_ingested_idx (system, status, last_ingested_data),
   INDEX status_ingested_idx (status, last_ingested_data DESC)
 );
+CREATE TABLE IF NOT EXISTS Comments (
+  comment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  digest BYTES,
+  changelist_id STRING,
+  user_name STRING NOT NULL,
+  created_ts TIMESTAMP WITH TIME ZONE NOT NULL,
+  text STRING NOT NULL,
+  INDEX digest_idx (digest, created_ts),
+  INDEX changelist_idx (changelist_id, created_ts)
+);
 CREATE TABLE IF NOT EXISTS CommitsWithData (
   commit_id STRING PRIMARY KEY,
   tile_id INT4 NOT NULL
@@ -27,6 +37,8 @@ CREATE TABLE IF NOT EXISTS DiffMetrics (
   combined_metric FLOAT4 NOT NULL,
   dimensions_differ BOOL NOT NULL,
   ts TIMESTAMP WITH TIME ZONE NOT NULL,
+  ssim FLOAT4,
+  delta_e FLOAT4,
   PRIMARY KEY (left_digest, right_digest)
 );
 CREATE TABLE IF NOT EXISTS ExpectationDeltas (
@@ -65,6 +77,11 @@ CREATE TABLE IF NOT EXISTS Groupings (
   grouping_id BYTES PRIMARY KEY,
   keys JSONB NOT NULL
 );
+CREATE TABLE IF NOT EXISTS IgnoreRuleExpiryNotifications (
+  ignore_rule_id UUID PRIMARY KEY,
+  expires TIMESTAMP WITH TIME ZONE NOT NULL,
+  notified_ts TIMESTAMP WITH TIME ZONE NOT NULL
+);
 CREATE TABLE IF NOT EXISTS IgnoreRules (
   ignore_rule_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
   creator_email STRING NOT NULL,
@@ -77,6 +94,13 @@ CREATE TABLE IF NOT EXISTS MetadataCommits (
   commit_id STRING PRIMARY KEY,
   commit_metadata STRING NOT NULL
 );
+CREATE TABLE IF NOT EXISTS NegativeTriageComments (
+  changelist_id STRING,
+  grouping_id BYTES,
+  digest BYTES,
+  commented_ts TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY (changelist_id, grouping_id, digest)
+);
 CREATE TABLE IF NOT EXISTS Options (
   options_id BYTES PRIMARY KEY,
   keys JSONB NOT NULL
@@ -91,6 +115,10 @@ CREATE TABLE IF NOT EXISTS Patchsets (
   created_ts TIMESTAMP WITH TIME ZONE,
   INDEX cl_order_idx (changelist_id, ps_order)
 );
+CREATE TABLE IF NOT EXISTS PerceptualHashes (
+  digest BYTES PRIMARY KEY,
+  hash INT8 NOT NULL
+);
 CREATE TABLE IF NOT EXISTS PrimaryBranchDiffCalculationWork (
   grouping_id BYTES PRIMARY KEY,
   last_calculated_ts TIMESTAMP WITH TIME ZONE NOT NULL,
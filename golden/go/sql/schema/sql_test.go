@@ -20,3 +20,15 @@ func TestSchema_LoadIntoCockroachDB_Success(t *testing.T) {
 	_, err := db.Exec(ctx, schema.Schema)
 	require.NoError(t, err)
 }
+
+func TestMigrations_AppliedTwiceToCurrentSchema_Success(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTests(ctx, t)
+
+	_, err := db.Exec(ctx, schema.Schema)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, schema.Migrations)
+	require.NoError(t, err)
+	_, err = db.Exec(ctx, schema.Migrations)
+	require.NoError(t, err)
+}
@@ -125,6 +125,7 @@ const (
 //go:generate bazelisk run --config=mayberemote //:go -- run ../exporter/tosql --output_file sql.go --output_pkg schema
 type Tables struct {
 	Changelists                        []ChangelistRow                     `sql_backup:"weekly"`
+	Comments                           []CommentRow                        `sql_backup:"daily"`
 	CommitsWithData                    []CommitWithDataRow                 `sql_backup:"daily"`
 	DiffMetrics                        []DiffMetricRow                     `sql_backup:"monthly"`
 	ExpectationDeltas                  []ExpectationDeltaRow               `sql_backup:"daily"`
@@ -132,10 +133,13 @@ type Tables struct {
 	Expectations                       []ExpectationRow                    `sql_backup:"daily"`
 	GitCommits                         []GitCommitRow                      `sql_backup:"daily"`
 	Groupings                          []GroupingRow                       `sql_backup:"monthly"`
+	IgnoreRuleExpiryNotifications      []IgnoreRuleExpiryNotificationRow   `sql_backup:"weekly"`
 	IgnoreRules                        []IgnoreRuleRow                     `sql_backup:"daily"`
 	MetadataCommits                    []MetadataCommitRow                 `sql_backup:"daily"`
+	NegativeTriageComments             []NegativeTriageCommentRow          `sql_backup:"weekly"`
 	Options                            []OptionsRow                        `sql_backup:"monthly"`
 	Patchsets                          []PatchsetRow                       `sql_backup:"weekly"`
+	PerceptualHashes                   []PerceptualHashRow                 `sql_backup:"monthly"`
 	PrimaryBranchDiffCalculationWork   []PrimaryBranchDiffCalculationRow   `sql_backup:"none"`
 	PrimaryBranchParams                []PrimaryBranchParamRow             `sql_backup:"monthly"`
 	ProblemImages                      []ProblemImageRow                   `sql_backup:"none"`
@@ -521,7 +525,8 @@ func (r ExpectationRow) RowsOrderBy() string {
 // groupings an image may have been generated in, the difference between any two images is the same;
 // 2) images can be produced by multiple groupings. To make certain queries easier, data for a given
 // image pairing is inserted twice - once with A being Left, B being Right and once with A being
-// Right and B being Left. See diff.go for more about how these fields are computed.
+// Right and B being Left. See diff.go for more about how these fields are computed. The ssim and
+// delta_e columns were added later, so they are also in Migrations.
 type DiffMetricRow struct {
 	// LeftDigest represents one of the images compared.
 	LeftDigest DigestBytes `sql:"left_digest BYTES"`
@@ -544,22 +549,29 @@ type DiffMetricRow struct {
 	DimensionsDiffer bool `sql:"dimensions_differ BOOL NOT NULL"`
 	// Timestamp represents when this metric was computed or verified (i.e. still in use). This
 	// allows for us to periodically clean up this large table.
-	Timestamp  time.Time `sql:"ts TIMESTAMP WITH TIME ZONE NOT NULL"`
-	primaryKey struct{}  `sql:"PRIMARY KEY (left_digest, right_digest)"`
+	Timestamp time.Time `sql:"ts TIMESTAMP WITH TIME ZONE NOT NULL"`
+	// SSIM is the structural similarity of the two images. It is nil for rows computed before this
+	// metric was introduced.
+	SSIM *float32 `sql:"ssim FLOAT4"`
+	// DeltaE is the average perceptual color difference of the two images. It is nil for rows
+	// computed before this metric was introduced.
+	DeltaE     *float32 `sql:"delta_e FLOAT4"`
+	primaryKey struct{} `sql:"PRIMARY KEY (left_digest, right_digest)"`
 }
 
 // ToSQLRow implements the sqltest.SQLExporter interface.
 func (r DiffMetricRow) ToSQLRow() (colNames []string, colData []interface{}) {
 	return []string{"left_digest", "right_digest", "num_pixels_diff", "percent_pixels_diff", "max_rgba_diffs",
-			"max_channel_diff", "combined_metric", "dimensions_differ", "ts"},
+			"max_channel_diff", "combined_metric", "dimensions_differ", "ts", "ssim", "delta_e"},
 		[]interface{}{r.LeftDigest, r.RightDigest, r.NumPixelsDiff, r.PercentPixelsDiff, r.MaxRGBADiffs,
-			r.MaxChannelDiff, r.CombinedMetric, r.DimensionsDiffer, r.Timestamp}
+			r.MaxChannelDiff, r.CombinedMetric, r.DimensionsDiffer, r.Timestamp, r.SSIM, r.DeltaE}
 }
 
 // ScanFrom implements the sqltest.SQLScanner interface.
 func (r *DiffMetricRow) ScanFrom(scan func(...interface{}) error) error {
 	err := scan(&r.LeftDigest, &r.RightDigest, &r.NumPixelsDiff, &r.PercentPixelsDiff,
-		&r.MaxRGBADiffs, &r.MaxChannelDiff, &r.CombinedMetric, &r.DimensionsDiffer, &r.Timestamp)
+		&r.MaxRGBADiffs, &r.MaxChannelDiff, &r.CombinedMetric, &r.DimensionsDiffer, &r.Timestamp,
+		&r.SSIM, &r.DeltaE)
 	if err != nil {
 		return skerr.Wrap(err)
 	}
@@ -567,6 +579,32 @@ func (r *DiffMetricRow) ScanFrom(scan func(...interface{}) error) error {
 	return nil
 }
 
+// PerceptualHashRow stores a perceptual hash of an image, which allows us to find visually
+// similar images across all groupings without computing a pixel diff against each of them. See
+// diff.PerceptualHash for how the hash is computed.
+type PerceptualHashRow struct {
+	// Digest is the MD5 hash of the pixel data.
+	Digest DigestBytes `sql:"digest BYTES PRIMARY KEY"`
+	// Hash is the 64 bit perceptual hash of the image, stored as a signed integer.
+	Hash int64 `sql:"hash INT8 NOT NULL"`
+}
+
+// ToSQLRow implements the sqltest.SQLExporter interface.
+func (r PerceptualHashRow) ToSQLRow() (colNames []string, colData []interface{}) {
+	return []string{"digest", "hash"},
+		[]interface{}{r.Digest, r.Hash}
+}
+
+// ScanFrom implements the sqltest.SQLScanner interface.
+func (r *PerceptualHashRow) ScanFrom(scan func(...interface{}) error) error {
+	return scan(&r.Digest, &r.Hash)
+}
+
+// RowsOrderBy implements the sqltest.RowsOrder interface.
+func (r PerceptualHashRow) RowsOrderBy() string {
+	return `ORDER BY digest ASC`
+}
+
 // ValueAtHeadRow represents the most recent data point for a each trace. It contains some
 // denormalized data to reduce the number of joins needed to do some frequent queries.
 type ValueAtHeadRow struct {
@@ -736,6 +774,79 @@ func (r IgnoreRuleRow) RowsOrderBy() string {
 	return `ORDER BY expires ASC`
 }
 
+// IgnoreRuleExpiryNotificationRow records that the owner of an ignore rule was notified that the
+// rule is about to expire, so they are only notified once per expiration time.
+type IgnoreRuleExpiryNotificationRow struct {
+	// IgnoreRuleID is the id of the rule. This is a foreign key into the IgnoreRules table.
+	IgnoreRuleID uuid.UUID `sql:"ignore_rule_id UUID PRIMARY KEY"`
+	// Expires is the expiration time of the rule the owner was notified about. If the rule is
+	// extended, the owner is notified again before the new expiration time.
+	Expires time.Time `sql:"expires TIMESTAMP WITH TIME ZONE NOT NULL"`
+	// NotifiedTS is when the owner was notified.
+	NotifiedTS time.Time `sql:"notified_ts TIMESTAMP WITH TIME ZONE NOT NULL"`
+}
+
+// ToSQLRow implements the sqltest.SQLExporter interface.
+func (r IgnoreRuleExpiryNotificationRow) ToSQLRow() (colNames []string, colData []interface{}) {
+	return []string{"ignore_rule_id", "expires", "notified_ts"},
+		[]interface{}{r.IgnoreRuleID, r.Expires, r.NotifiedTS}
+}
+
+// ScanFrom implements the sqltest.SQLScanner interface.
+func (r *IgnoreRuleExpiryNotificationRow) ScanFrom(scan func(...interface{}) error) error {
+	if err := scan(&r.IgnoreRuleID, &r.Expires, &r.NotifiedTS); err != nil {
+		return skerr.Wrap(err)
+	}
+	r.Expires = r.Expires.UTC()
+	r.NotifiedTS = r.NotifiedTS.UTC()
+	return nil
+}
+
+// RowsOrderBy implements the sqltest.RowsOrder interface.
+func (r IgnoreRuleExpiryNotificationRow) RowsOrderBy() string {
+	return `ORDER BY ignore_rule_id`
+}
+
+type CommentRow struct {
+	// CommentID is the id for this comment.
+	CommentID uuid.UUID `sql:"comment_id UUID PRIMARY KEY DEFAULT gen_random_uuid()"`
+	// Digest is the digest this comment is about. It is nil if the comment is about a
+	// changelist.
+	Digest DigestBytes `sql:"digest BYTES"`
+	// ChangelistID is the fully qualified id of the changelist this comment is about. It is nil
+	// if the comment is about a digest.
+	ChangelistID *string `sql:"changelist_id STRING"`
+	// UserName is the email address of the logged-in user who wrote the comment.
+	UserName string `sql:"user_name STRING NOT NULL"`
+	// CreatedTS is the time at which the comment was written.
+	CreatedTS time.Time `sql:"created_ts TIMESTAMP WITH TIME ZONE NOT NULL"`
+	// Text is the comment itself.
+	Text string `sql:"text STRING NOT NULL"`
+
+	digestIndex     struct{} `sql:"INDEX digest_idx (digest, created_ts)"`
+	changelistIndex struct{} `sql:"INDEX changelist_idx (changelist_id, created_ts)"`
+}
+
+// ToSQLRow implements the sqltest.SQLExporter interface.
+func (r CommentRow) ToSQLRow() (colNames []string, colData []interface{}) {
+	return []string{"comment_id", "digest", "changelist_id", "user_name", "created_ts", "text"},
+		[]interface{}{r.CommentID, r.Digest, r.ChangelistID, r.UserName, r.CreatedTS, r.Text}
+}
+
+// ScanFrom implements the sqltest.SQLScanner interface.
+func (r *CommentRow) ScanFrom(scan func(...interface{}) error) error {
+	if err := scan(&r.CommentID, &r.Digest, &r.ChangelistID, &r.UserName, &r.CreatedTS, &r.Text); err != nil {
+		return skerr.Wrap(err)
+	}
+	r.CreatedTS = r.CreatedTS.UTC()
+	return nil
+}
+
+// RowsOrderBy implements the sqltest.RowsOrder interface.
+func (r CommentRow) RowsOrderBy() string {
+	return `ORDER BY created_ts ASC, comment_id`
+}
+
 type ChangelistRow struct {
 	// ChangelistID is the fully qualified id of this changelist. "Fully qualified" means it has
 	// the system as a prefix (e.g "gerrit_1234") which simplifies joining logic and ensures
@@ -996,6 +1107,42 @@ func (r ProblemImageRow) RowsOrderBy() string {
 	return `ORDER BY digest ASC`
 }
 
+// NegativeTriageCommentRow records that a landed CL was commented on about a digest it produced
+// being triaged as negative on the primary branch, so the same CL is not told about the same
+// digest twice.
+type NegativeTriageCommentRow struct {
+	// ChangelistID is the fully qualified id of the landed CL that was commented on.
+	ChangelistID string `sql:"changelist_id STRING"`
+	// GroupingID is the grouping of the negative digest.
+	GroupingID GroupingID `sql:"grouping_id BYTES"`
+	// Digest is the MD5 hash of the pixel data of the negative digest.
+	Digest DigestBytes `sql:"digest BYTES"`
+	// CommentedTS is when the comment was made.
+	CommentedTS time.Time `sql:"commented_ts TIMESTAMP WITH TIME ZONE NOT NULL"`
+
+	primaryKey struct{} `sql:"PRIMARY KEY (changelist_id, grouping_id, digest)"`
+}
+
+// ToSQLRow implements the sqltest.SQLExporter interface.
+func (r NegativeTriageCommentRow) ToSQLRow() (colNames []string, colData []interface{}) {
+	return []string{"changelist_id", "grouping_id", "digest", "commented_ts"},
+		[]interface{}{r.ChangelistID, r.GroupingID, r.Digest, r.CommentedTS}
+}
+
+// ScanFrom implements the sqltest.SQLScanner interface.
+func (r *NegativeTriageCommentRow) ScanFrom(scan func(...interface{}) error) error {
+	if err := scan(&r.ChangelistID, &r.GroupingID, &r.Digest, &r.CommentedTS); err != nil {
+		return skerr.Wrap(err)
+	}
+	r.CommentedTS = r.CommentedTS.UTC()
+	return nil
+}
+
+// RowsOrderBy implements the sqltest.RowsOrder interface.
+func (r NegativeTriageCommentRow) RowsOrderBy() string {
+	return `ORDER BY changelist_id, grouping_id, digest`
+}
+
 // DeprecatedExpectationUndoRow represents an undo operation that we could not automatically
 // apply during the transitional period of expectations. A human will manually apply these when
 // removing the firestore implementation from the loop.
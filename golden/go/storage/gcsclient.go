@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"path"
+	"strings"
+	"time"
 
 	"go.opencensus.io/trace"
 
@@ -15,6 +17,7 @@ import (
 	"go.goldmine.build/go/sklog"
 	"go.goldmine.build/go/util"
 	"go.goldmine.build/golden/go/types"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -42,13 +45,40 @@ type GCSClient interface {
 	// GetImage returns the raw bytes of an image with the corresponding Digest.
 	GetImage(ctx context.Context, digest types.Digest) ([]byte, error)
 
+	// ListImages calls fn with the metadata of every image in the bucket. If fn returns an error,
+	// the iteration stops and that error is returned.
+	ListImages(ctx context.Context, fn func(ImageInfo) error) error
+
+	// DeleteImage deletes the image with the corresponding Digest. It is not an error if the image
+	// does not exist.
+	DeleteImage(ctx context.Context, digest types.Digest) error
+
+	// ArchiveImage copies the image with the corresponding Digest to the same path in the given
+	// bucket and then deletes the original. It is not an error if the image does not exist.
+	ArchiveImage(ctx context.Context, digest types.Digest, bucket string) error
+
+	// WriteResultsFile writes the given Gold results JSON to the given path, which is of the form
+	// "bucket/path/to/file.json". This is how results uploaded over HTTP are handed to ingestion.
+	WriteResultsFile(ctx context.Context, gcsPath string, data []byte) error
+
 	// Options returns the options that were used to initialize the client
 	Options() GCSClientOptions
 }
 
+// ImageInfo is the metadata of an image stored in GCS.
+type ImageInfo struct {
+	Digest types.Digest
+	// Size is the size of the image in bytes.
+	Size    int64
+	Created time.Time
+}
+
 const (
 	// The GCS folder that contains the images, named by their digests.
 	imgFolder = "dm-images-v1"
+
+	// The file extension of every image in imgFolder.
+	imgExtension = ".png"
 )
 
 // ClientImpl implements the GCSClient interface.
@@ -124,6 +154,23 @@ func (g *ClientImpl) LoadKnownDigests(ctx context.Context, w io.Writer) error {
 	return skerr.Wrapf(err, "writing %d bytes of digests to writer", n)
 }
 
+// WriteResultsFile fulfills the GCSClient interface.
+func (g *ClientImpl) WriteResultsFile(ctx context.Context, gcsPath string, data []byte) error {
+	ctx, span := trace.StartSpan(ctx, "gcsclient_WriteResultsFile")
+	defer span.End()
+	if g.options.Dryrun {
+		sklog.Infof("dryrun: Writing %d bytes to %s", len(data), gcsPath)
+		return nil
+	}
+	if bucket, name := gcs.SplitGSPath(gcsPath); bucket == "" || name == "" {
+		return skerr.Fmt("invalid GCS path %q", gcsPath)
+	}
+	return g.writeToPath(ctx, gcsPath, "application/json", func(w *gstorage.Writer) error {
+		_, err := w.Write(data)
+		return skerr.Wrap(err)
+	})
+}
+
 // removeForTestingOnly removes the given file. Should only be used for testing.
 func (g *ClientImpl) removeForTestingOnly(ctx context.Context, targetPath string) error {
 	bucketName, storagePath := gcs.SplitGSPath(targetPath)
@@ -163,7 +210,7 @@ func (g *ClientImpl) GetImage(ctx context.Context, digest types.Digest) ([]byte,
 	ctx, span := trace.StartSpan(ctx, "gcsclient_GetImage")
 	defer span.End()
 	// intentionally using path because gcs is forward slashes
-	imgPath := path.Join(imgFolder, string(digest)+".png")
+	imgPath := path.Join(imgFolder, string(digest)+imgExtension)
 	r, err := g.storageClient.Bucket(g.options.Bucket).Object(imgPath).NewReader(ctx)
 	if err != nil {
 		// If not image not found, this error path will be taken.
@@ -174,5 +221,74 @@ func (g *ClientImpl) GetImage(ctx context.Context, digest types.Digest) ([]byte,
 	return b, skerr.Wrap(err)
 }
 
+// ListImages fulfills the GCSClient interface. Objects in the image folder that are not named like
+// an image are skipped.
+func (g *ClientImpl) ListImages(ctx context.Context, fn func(ImageInfo) error) error {
+	ctx, span := trace.StartSpan(ctx, "gcsclient_ListImages")
+	defer span.End()
+	q := &gstorage.Query{Prefix: imgFolder + "/"}
+	if err := q.SetAttrSelection([]string{"Name", "Size", "Created"}); err != nil {
+		return skerr.Wrap(err)
+	}
+	it := g.storageClient.Bucket(g.options.Bucket).Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return skerr.Wrapf(err, "listing images in %s", g.options.Bucket)
+		}
+		name := path.Base(attrs.Name)
+		if !strings.HasSuffix(name, imgExtension) {
+			continue
+		}
+		info := ImageInfo{
+			Digest:  types.Digest(strings.TrimSuffix(name, imgExtension)),
+			Size:    attrs.Size,
+			Created: attrs.Created,
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+}
+
+// DeleteImage fulfills the GCSClient interface.
+func (g *ClientImpl) DeleteImage(ctx context.Context, digest types.Digest) error {
+	ctx, span := trace.StartSpan(ctx, "gcsclient_DeleteImage")
+	defer span.End()
+	imgPath := path.Join(imgFolder, string(digest)+imgExtension)
+	if g.options.Dryrun {
+		sklog.Infof("dryrun: Deleting %s", imgPath)
+		return nil
+	}
+	err := g.storageClient.Bucket(g.options.Bucket).Object(imgPath).Delete(ctx)
+	if err != nil && err != gstorage.ErrObjectNotExist {
+		return skerr.Wrapf(err, "deleting %s", imgPath)
+	}
+	return nil
+}
+
+// ArchiveImage fulfills the GCSClient interface.
+func (g *ClientImpl) ArchiveImage(ctx context.Context, digest types.Digest, bucket string) error {
+	ctx, span := trace.StartSpan(ctx, "gcsclient_ArchiveImage")
+	defer span.End()
+	imgPath := path.Join(imgFolder, string(digest)+imgExtension)
+	if g.options.Dryrun {
+		sklog.Infof("dryrun: Archiving %s to gs://%s", imgPath, bucket)
+		return nil
+	}
+	src := g.storageClient.Bucket(g.options.Bucket).Object(imgPath)
+	dst := g.storageClient.Bucket(bucket).Object(imgPath)
+	if _, err := dst.CopierFrom(src).Run(ctx); err != nil {
+		if err == gstorage.ErrObjectNotExist {
+			return nil
+		}
+		return skerr.Wrapf(err, "copying %s to gs://%s", imgPath, bucket)
+	}
+	return skerr.Wrap(g.DeleteImage(ctx, digest))
+}
+
 // Ensure ClientImpl fulfills the GCSClient interface.
 var _ GCSClient = (*ClientImpl)(nil)
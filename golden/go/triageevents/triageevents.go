@@ -0,0 +1,146 @@
+// Package triageevents publishes structured events whenever expectations change, so downstream
+// systems (e.g. CI gating or audit pipelines) can react to baseline changes.
+package triageevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	gpubsub "cloud.google.com/go/pubsub"
+	"go.opencensus.io/trace"
+
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/pubsub"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/util"
+	"go.goldmine.build/golden/go/expectations"
+	"go.goldmine.build/golden/go/types"
+)
+
+// Event describes a change to the expectation of a single digest.
+type Event struct {
+	Digest types.Digest `json:"digest"`
+	// Grouping is the set of keys that identifies the test, e.g. the corpus and test name.
+	Grouping    paramtools.Params  `json:"grouping"`
+	Test        types.TestName     `json:"test"`
+	Corpus      string             `json:"corpus"`
+	LabelBefore expectations.Label `json:"label_before"`
+	Label       expectations.Label `json:"label"`
+	Author      string             `json:"author"`
+	// CodeReviewSystem and ChangelistID are empty if the change was made on the primary branch.
+	CodeReviewSystem string `json:"crs,omitempty"`
+	ChangelistID     string `json:"changelist_id,omitempty"`
+	// RecordID identifies the expectation record (i.e. the triage action) that made this change. It
+	// can be used to look up the change in the triage log.
+	RecordID  string    `json:"record_id"`
+	Timestamp time.Time `json:"ts"`
+}
+
+// Publisher sends Events to downstream systems.
+type Publisher interface {
+	// Publish sends the given events. It returns an error if any of them could not be sent.
+	Publish(ctx context.Context, events []Event) error
+}
+
+// PubSubPublisher publishes each Event as a JSON encoded message on a PubSub topic. The corpus,
+// label and changelist ID are also set as message attributes, so subscribers can filter on them.
+type PubSubPublisher struct {
+	topic pubsub.Topic
+}
+
+// NewPubSubPublisher returns a PubSubPublisher that publishes to the given topic.
+func NewPubSubPublisher(topic pubsub.Topic) *PubSubPublisher {
+	return &PubSubPublisher{topic: topic}
+}
+
+// Publish implements the Publisher interface.
+func (p *PubSubPublisher) Publish(ctx context.Context, events []Event) error {
+	ctx, span := trace.StartSpan(ctx, "triageevents_PubSubPublish")
+	defer span.End()
+	results := make([]pubsub.PublishResult, 0, len(events))
+	for _, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return skerr.Wrap(err)
+		}
+		results = append(results, p.topic.Publish(ctx, &gpubsub.Message{
+			Data: b,
+			Attributes: map[string]string{
+				"corpus":        e.Corpus,
+				"label":         string(e.Label),
+				"changelist_id": e.ChangelistID,
+			},
+		}))
+	}
+	// Wait for all the messages to be sent, so a failure can be reported.
+	for i, r := range results {
+		if _, err := r.Get(ctx); err != nil {
+			return skerr.Wrapf(err, "publishing event for digest %s", events[i].Digest)
+		}
+	}
+	return nil
+}
+
+// WebhookRequest is the body POSTed by the WebhookPublisher.
+type WebhookRequest struct {
+	Events []Event `json:"events"`
+}
+
+// WebhookPublisher POSTs all the Events of a triage action as a single JSON encoded
+// WebhookRequest to an HTTP endpoint.
+type WebhookPublisher struct {
+	client *http.Client
+	url    string
+}
+
+// NewWebhookPublisher returns a WebhookPublisher that sends events to the given URL. The client
+// should have a short timeout, since events are published while handling triage requests.
+func NewWebhookPublisher(client *http.Client, url string) *WebhookPublisher {
+	return &WebhookPublisher{client: client, url: url}
+}
+
+// Publish implements the Publisher interface.
+func (p *WebhookPublisher) Publish(ctx context.Context, events []Event) error {
+	ctx, span := trace.StartSpan(ctx, "triageevents_WebhookPublish")
+	defer span.End()
+	b, err := json.Marshal(WebhookRequest{Events: events})
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return skerr.Wrapf(err, "sending %d events to webhook", len(events))
+	}
+	defer util.Close(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return skerr.Fmt("sending %d events to webhook: status %s", len(events), resp.Status)
+	}
+	return nil
+}
+
+// MultiPublisher sends Events to all of its Publishers.
+type MultiPublisher []Publisher
+
+// Publish implements the Publisher interface. All publishers are tried, even if some fail.
+func (m MultiPublisher) Publish(ctx context.Context, events []Event) error {
+	var firstErr error
+	for _, p := range m {
+		if err := p.Publish(ctx, events); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Make sure the publishers fulfill the Publisher interface.
+var _ Publisher = (*PubSubPublisher)(nil)
+var _ Publisher = (*WebhookPublisher)(nil)
+var _ Publisher = MultiPublisher(nil)
@@ -0,0 +1,115 @@
+package triageevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gpubsub "cloud.google.com/go/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"go.goldmine.build/go/httputils"
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/pubsub/mocks"
+	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/golden/go/expectations"
+	"go.goldmine.build/golden/go/types"
+)
+
+var testEvent = Event{
+	Digest:           "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+	Grouping:         paramtools.Params{types.CorpusField: "round", types.PrimaryKeyField: "circle"},
+	Test:             "circle",
+	Corpus:           "round",
+	LabelBefore:      expectations.Untriaged,
+	Label:            expectations.Positive,
+	Author:           "user@example.com",
+	CodeReviewSystem: "gerrit",
+	ChangelistID:     "12345",
+	RecordID:         "e1b5f5c2-7e0c-4f2a-9c1b-1b0f5d3e6a77",
+	Timestamp:        time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestPubSubPublisher_Publish_OneMessagePerEvent(t *testing.T) {
+	topic := mocks.NewTopic(t)
+	result := mocks.NewPublishResult(t)
+	result.On("Get", testutils.AnyContext).Return("server-id", nil).Twice()
+	var published []*gpubsub.Message
+	topic.On("Publish", testutils.AnyContext, mock.Anything).Run(func(args mock.Arguments) {
+		published = append(published, args.Get(1).(*gpubsub.Message))
+	}).Return(result).Twice()
+
+	negative := testEvent
+	negative.Label = expectations.Negative
+	require.NoError(t, NewPubSubPublisher(topic).Publish(context.Background(), []Event{testEvent, negative}))
+
+	require.Len(t, published, 2)
+	assert.Equal(t, map[string]string{"corpus": "round", "label": "positive", "changelist_id": "12345"}, published[0].Attributes)
+	assert.Equal(t, "negative", published[1].Attributes["label"])
+	var actual Event
+	require.NoError(t, json.Unmarshal(published[0].Data, &actual))
+	assert.Equal(t, testEvent, actual)
+}
+
+func TestPubSubPublisher_Publish_PublishFails_ReturnsError(t *testing.T) {
+	topic := mocks.NewTopic(t)
+	result := mocks.NewPublishResult(t)
+	result.On("Get", testutils.AnyContext).Return("", errors.New("topic not found"))
+	topic.On("Publish", testutils.AnyContext, mock.Anything).Return(result)
+
+	err := NewPubSubPublisher(topic).Publish(context.Background(), []Event{testEvent})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "topic not found")
+}
+
+func TestWebhookPublisher_Publish_PostsAllEventsAsJSON(t *testing.T) {
+	var received WebhookRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	client := httputils.DefaultClientConfig().WithoutRetries().With2xxOnly().Client()
+	require.NoError(t, NewWebhookPublisher(client, srv.URL).Publish(context.Background(), []Event{testEvent}))
+	assert.Equal(t, WebhookRequest{Events: []Event{testEvent}}, received)
+}
+
+func TestWebhookPublisher_Publish_ServerError_ReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := NewWebhookPublisher(http.DefaultClient, srv.URL).Publish(context.Background(), []Event{testEvent})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500")
+}
+
+func TestMultiPublisher_Publish_OneFails_OthersStillCalled(t *testing.T) {
+	var calls int
+	ok := publisherFunc(func(ctx context.Context, events []Event) error {
+		calls++
+		return nil
+	})
+	broken := publisherFunc(func(ctx context.Context, events []Event) error {
+		calls++
+		return errors.New("broken")
+	})
+	err := MultiPublisher{broken, ok}.Publish(context.Background(), []Event{testEvent})
+	require.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+type publisherFunc func(ctx context.Context, events []Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
@@ -74,6 +74,12 @@ func addTypes(generator *go2ts.Go2TS) {
 	// Response for the /json/v2/triagelog RPC endpoint.
 	generator.Add(frontend.TriageLogResponse{})
 
+	// Response for the /json/v1/comments RPC endpoint.
+	generator.Add(frontend.CommentsResponse{})
+
+	// Request for the /json/v1/comments/add RPC endpoint.
+	generator.Add(frontend.AddCommentRequest{})
+
 	// Response for the /json/v1/changelists RPC endpoint.
 	generator.Add(frontend.ChangelistsResponse{})
 
@@ -95,6 +101,24 @@ func addTypes(generator *go2ts.Go2TS) {
 	// Response for the /json/v2/details RPC endpoint.
 	generator.Add(frontend.DigestDetails{})
 
+	// Request for the /json/v1/details/bulk RPC endpoint.
+	generator.Add(frontend.DetailsBulkRequest{})
+
+	// Response for the /json/v1/details/bulk RPC endpoint.
+	generator.Add(frontend.DetailsBulkResponse{})
+
+	// Response for the /json/v1/similar RPC endpoint.
+	generator.Add(frontend.SimilarDigestsResponse{})
+
+	// Response for the /json/v1/ignores/stats RPC endpoint.
+	generator.Add(frontend.IgnoreStatsResponse{})
+
+	// Response for the /json/v1/flaky RPC endpoint.
+	generator.Add(frontend.FlakyTestsResponse{})
+
+	// Response for the /json/v1/search/facets RPC endpoint.
+	generator.Add(frontend.SearchFacetsResponse{})
+
 	// Response for the /json/v1/clusterdiff RPC endpoint.
 	generator.AddWithName(frontend.Node{}, "ClusterDiffNode")
 	generator.AddWithName(frontend.Link{}, "ClusterDiffLink")
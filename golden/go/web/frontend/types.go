@@ -40,13 +40,45 @@ const (
 	// merged onto the returned baseline.
 	ExpectationsRouteV2 = "/json/v2/expectations"
 
+	// ExpectationsAtCommitRouteV1 serves the expectations of the master branch as of the commit
+	// with the given git hash.
+	ExpectationsAtCommitRouteV1 = "/json/v1/expectations/commit/{hash}"
+
 	// KnownHashesRoute serves the list of known hashes.
 	KnownHashesRoute   = "/json/hashes"
 	KnownHashesRouteV1 = "/json/v1/hashes"
 
 	GroupingsRouteV1 = "/json/v1/groupings"
+
+	// RPCsRouteV1 serves the list of JSON RPCs and their versions, so clients can find the newest
+	// version of an RPC they support.
+	RPCsRouteV1 = "/json/v1/rpcs"
 )
 
+// RPC describes one version of a JSON RPC.
+type RPC struct {
+	// Method is the HTTP method, e.g. "GET".
+	Method string `json:"method"`
+	// Route is the full route, e.g. "/json/v2/search".
+	Route string `json:"route"`
+	// Path is the route without the "/json" and version prefixes, e.g. "/search". All the versions
+	// of an RPC share the same Path.
+	Path string `json:"path"`
+	// Version is the version of the RPC, or 0 for unversioned RPCs, e.g. "/json/whoami".
+	Version int `json:"version"`
+	// Successor is the Route of the newest version of the RPC if it is newer than this one, in
+	// which case this version is deprecated.
+	Successor string `json:"successor,omitempty"`
+}
+
+// RPCsResponse is the response for /json/v1/rpcs.
+type RPCsResponse struct {
+	RPCs []RPC `json:"rpcs"`
+	// Sunset is the date after which deprecated RPCs may be removed, if one has been set, in the
+	// format of the HTTP Sunset header.
+	Sunset string `json:"sunset,omitempty"`
+}
+
 // Changelist encapsulates how the frontend expects to get information
 // about a code_review.Changelist that has Gold results associated with it.
 // We have a separate struct so we can decouple the JSON representation
@@ -145,6 +177,11 @@ type TriageRequestV3 struct {
 	// the username that initiated the triage operation via Gold's UI will be used as the author of
 	// the operation.
 	ImageMatchingAlgorithm string `json:"image_matching_algorithm,omitempty"`
+
+	// IncludeVariants, if true, applies LabelAfter of each delta to the digests drawn by the other
+	// variants of the triaged digest as well (see DigestVariant). This is not supported when
+	// triaging on a Changelist.
+	IncludeVariants bool `json:"include_variants,omitempty"`
 }
 
 // TriageResponse is the response for the /json/v3/triage RPC.
@@ -197,6 +234,33 @@ type TriageLogEntry struct {
 type TriageLogResponse struct {
 	httputils.ResponsePagination
 	Entries []TriageLogEntry `json:"entries" go2ts:"ignorenil"`
+	// Comments are the comments about the changelist (if any) and about the digests in Entries.
+	Comments []Comment `json:"comments" go2ts:"ignorenil"`
+}
+
+// Comment is a note left by a user about a digest or a changelist.
+type Comment struct {
+	ID           string       `json:"id"`
+	Digest       types.Digest `json:"digest,omitempty"`
+	CRS          string       `json:"crs,omitempty"`
+	ChangelistID string       `json:"changelist_id,omitempty"`
+	CreatedBy    string       `json:"created_by"`
+	Created      time.Time    `json:"created"`
+	Text         string       `json:"text"`
+}
+
+// CommentsResponse is the response for /json/v1/comments.
+type CommentsResponse struct {
+	Comments []Comment `json:"comments" go2ts:"ignorenil"`
+}
+
+// AddCommentRequest is the request for /json/v1/comments/add. Either Digest or both CRS and
+// ChangelistID must be set.
+type AddCommentRequest struct {
+	Digest       types.Digest `json:"digest,omitempty"`
+	CRS          string       `json:"crs,omitempty"`
+	ChangelistID string       `json:"changelist_id,omitempty"`
+	Text         string       `json:"text"`
 }
 
 // DigestListResponse is the response for "what digests belong to..."
@@ -251,6 +315,30 @@ func ConvertIgnoreRule(r ignore.Rule) (IgnoreRule, error) {
 	}, nil
 }
 
+// IgnoreStatsResponse is the response for /json/v1/ignores/stats.
+type IgnoreStatsResponse struct {
+	Rules []IgnoreRuleStats `json:"rules"`
+	// NumUnused is the number of rules which currently match no traces.
+	NumUnused int `json:"num_unused"`
+	// NumExpired is the number of rules which have expired.
+	NumExpired int `json:"num_expired"`
+}
+
+// IgnoreRuleStats describes how much a single ignore rule is currently used.
+type IgnoreRuleStats struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+	// Owner is the user who last updated the rule.
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+	Expired bool      `json:"expired"`
+	// MatchedTraces is how many traces in the current window are matched by this rule.
+	MatchedTraces int `json:"matched_traces"`
+	// ExclusiveTraces is how many of those traces are matched by no other rule, that is, how many
+	// traces would no longer be ignored if this rule were deleted.
+	ExclusiveTraces int `json:"exclusive_traces"`
+}
+
 // IgnoreRuleBody encapsulates a single ignore rule that is submitted for addition or update.
 type IgnoreRuleBody struct {
 	// Duration is a human readable string like "2w", "4h" to specify a duration.
@@ -281,6 +369,58 @@ type Commit struct {
 	ChangelistURL string `json:"cl_url"`
 }
 
+// FlakyTestsResponse is the response for /json/v1/flaky.
+type FlakyTestsResponse struct {
+	Corpora []FlakyCorpus `json:"corpora"`
+}
+
+// FlakyCorpus lists the most unstable tests of a corpus, most unstable first.
+type FlakyCorpus struct {
+	Corpus string      `json:"corpus"`
+	Tests  []FlakyTest `json:"tests"`
+}
+
+// FlakyTest summarizes the traces of a test which produced more than one distinct digest in the
+// most recent tiles.
+type FlakyTest struct {
+	Grouping paramtools.Params `json:"grouping"`
+	Test     types.TestName    `json:"test"`
+	// FlakyTraces is the number of traces of this test which produced more than one digest.
+	FlakyTraces int `json:"flaky_traces"`
+	// MaxDigests is the largest number of distinct digests produced by a single trace.
+	MaxDigests int `json:"max_digests"`
+}
+
+// SearchFacetsResponse is the response for /json/v1/search/facets. It describes how the results
+// of a search would be narrowed down by additionally requiring a trace to have a given key=value.
+type SearchFacetsResponse struct {
+	// Total is the number of results (distinct digests per grouping) the search currently matches.
+	Total int `json:"total"`
+	// Facets has an entry for each trace key which has at least one value that would narrow down
+	// the results, sorted by key.
+	Facets []SearchFacet `json:"facets"`
+}
+
+// SearchFacet is a trace key and the values it has for the traces of the current results.
+type SearchFacet struct {
+	Key string `json:"key"`
+	// Values are sorted by Count, highest first.
+	Values []FacetValue `json:"values"`
+}
+
+// FacetValue is a value of a trace key and how many results would be left if the search were
+// restricted to traces with that value.
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// IngestResponse is the response for /json/ingest.
+type IngestResponse struct {
+	// File is the GCS path the uploaded results were written to, from which they will be ingested.
+	File string `json:"file"`
+}
+
 // ByBlameResponse is the response for /json/v1/byblame.
 type ByBlameResponse struct {
 	Data []ByBlameEntry `json:"data"`
@@ -406,6 +546,38 @@ type SearchResult struct {
 	// ClosestRef labels the reference from RefDiffs that is the absolute closest to the primary
 	// digest.
 	ClosestRef RefClosest `json:"closestRef"` // "pos" or "neg"
+	// Suggestion is a suggested label for an untriaged primary digest, based on the closest
+	// triaged digest in Test. It is nil if the primary digest is already triaged or if there is
+	// no triaged digest close enough to base a suggestion on.
+	Suggestion *TriageSuggestion `json:"suggestion,omitempty"`
+	// Variants are the digests drawn at head by the other variants of the traces that produced the
+	// primary digest, e.g. the same test rendered at a different scale. It is only filled in if the
+	// instance is configured with a variant key, and only for the primary branch.
+	Variants []DigestVariant `json:"variants,omitempty"`
+}
+
+// DigestVariant is a digest drawn by a variant of a trace, that is, a trace in the same grouping
+// whose keys only differ in the value of the configured variant key.
+type DigestVariant struct {
+	// Variant is the value of the variant key, e.g. "2x".
+	Variant string             `json:"variant"`
+	Digest  types.Digest       `json:"digest"`
+	Status  expectations.Label `json:"status"`
+}
+
+// TriageSuggestion is a suggested label for a digest, along with the metrics of the diff against
+// the triaged digest that the suggestion was based on.
+type TriageSuggestion struct {
+	// Label is the label of the closest triaged digest.
+	Label expectations.Label `json:"label"`
+	// ClosestDigest is the triaged digest that the suggestion is based on.
+	ClosestDigest types.Digest `json:"closestDigest"`
+	// CombinedMetric, NumDiffPixels, PixelDiffPercent and MaxRGBADiffs describe the diff between
+	// the primary digest and ClosestDigest. See SRDiffDigest.
+	CombinedMetric   float32 `json:"combinedMetric"`
+	NumDiffPixels    int     `json:"numDiffPixels"`
+	PixelDiffPercent float32 `json:"pixelDiffPercent"`
+	MaxRGBADiffs     [4]int  `json:"maxRGBADiffs"`
 }
 
 // SRDiffDigest captures the diff information between a primary digest and the digest given here.
@@ -425,8 +597,23 @@ type SRDiffDigest struct {
 	// MaxRGBADiffs contains the maximum difference of each channel.
 	MaxRGBADiffs [4]int `json:"maxRGBADiffs"`
 
-	// One of CombinedMetric, PixelDiffPercent, or NumDiffPixels depending on the requested
-	// metric name (see query.go). Used internally in search.
+	// SSIM is the structural similarity of the two images, which is 1 for identical images. It is
+	// only set when comparing two specific digests (i.e. not in search results) and only if it has
+	// been computed for this pair of images.
+	SSIM *float32 `json:"ssim,omitempty"`
+
+	// DeltaE is the average perceptual color difference of the two images. Like SSIM, it is only
+	// set when comparing two specific digests.
+	DeltaE *float32 `json:"deltaE,omitempty"`
+
+	// ChannelWeightedMetric is like CombinedMetric, but uses the channel weights configured for the
+	// corpus. It is only set when comparing two specific digests of a corpus which ranks digests by
+	// it.
+	ChannelWeightedMetric *float32 `json:"channelWeightedMetric,omitempty"`
+
+	// QueryMetric is the distance by which the closest positive and negative digests are chosen,
+	// which depends on the diff metric configured for the corpus. Smaller is closer. Used
+	// internally in search.
 	QueryMetric float32 `json:"-"`
 
 	// DimDiffer is true if the dimensions between the two images are different.
@@ -586,6 +773,9 @@ type PatchsetNewAndUntriagedSummaryV1 struct {
 	// NewImages is the number of new images (digests) that were produced by this patchset by
 	// non-ignored traces and not seen on the primary branch.
 	NewImages int `json:"new_images"`
+	// ChangedTests is the number of groupings (e.g. tests) for which this patchset produced at
+	// least one of the NewImages.
+	ChangedTests int `json:"changed_tests"`
 	// NewUntriagedImages is the number of NewImages which are still untriaged. It is less than or
 	// equal to NewImages.
 	NewUntriagedImages int `json:"new_untriaged_images"`
@@ -598,6 +788,49 @@ type PatchsetNewAndUntriagedSummaryV1 struct {
 	PatchsetID string `json:"patchset_id"`
 	// PatchsetOrder is represents the chronological order the patchsets are in. It starts at 1.
 	PatchsetOrder int `json:"patchset_order"`
+	// DiffBudget is the result of checking this patchset against the instance's diff budget. It
+	// is omitted if the instance does not have a diff budget configured.
+	DiffBudget *DiffBudgetVerdict `json:"diff_budget,omitempty"`
+}
+
+// DiffBudgetVerdict is the result of checking a patchset against the diff budget.
+type DiffBudgetVerdict struct {
+	// Pass is true if the patchset is within all configured limits.
+	Pass bool `json:"pass"`
+	// Violations has one entry per exceeded limit. It is empty if Pass is true.
+	Violations []DiffBudgetViolation `json:"violations"`
+}
+
+// DiffBudgetViolation describes a single diff budget limit that was exceeded.
+type DiffBudgetViolation struct {
+	// Limit is the name of the limit, as it appears in the instance config (e.g.
+	// "max_changed_tests").
+	Limit string `json:"limit"`
+	// Max is the configured limit.
+	Max int `json:"max"`
+	// Actual is the value observed on the patchset.
+	Actual int `json:"actual"`
+}
+
+// DiffBudgetCheckResponse is the response for the /json/v1/diff_budget RPC. It is meant to be
+// consumed by CI systems which gate merges on the result.
+type DiffBudgetCheckResponse struct {
+	// ChangelistID is the nonqualified id of the CL.
+	ChangelistID string `json:"changelist_id"`
+	// PatchsetID is the nonqualified id of the patchset that was checked.
+	PatchsetID string `json:"patchset_id"`
+	// PatchsetOrder is the order of the patchset that was checked.
+	PatchsetOrder int `json:"patchset_order"`
+	// Enforced is false if the instance does not have a diff budget configured, in which case
+	// every patchset passes.
+	Enforced bool `json:"enforced"`
+	// Pass is true if the patchset is within all configured limits.
+	Pass bool `json:"pass"`
+	// Violations has one entry per exceeded limit. It is empty if Pass is true.
+	Violations []DiffBudgetViolation `json:"violations"`
+	// Outdated will be true if the verdict was computed from a stale cached summary. Clients
+	// which need an up to date verdict should try again later.
+	Outdated bool `json:"outdated"`
 }
 
 // ClusterDiffResult contains the result of comparing all digests within a test.
@@ -650,6 +883,110 @@ type BaselineV2Response struct {
 	// CodeReviewSystem indicates which CRS system (if any) this baseline is tied to.
 	// (e.g. "gerrit", "github") "" indicates the master branch.
 	CodeReviewSystem string `json:"crs,omitempty"`
+
+	// GitHash is set if this is the baseline of the master branch as of the given commit, rather
+	// than the current one.
+	GitHash string `json:"git_hash,omitempty"`
+}
+
+// ExportedExpectation is the label of a digest in a grouping, in a form that can be imported into
+// another instance.
+type ExportedExpectation struct {
+	Grouping paramtools.Params  `json:"grouping"`
+	Digest   types.Digest       `json:"digest"`
+	Label    expectations.Label `json:"label"`
+}
+
+// ExpectationsExportResponse is the response for /json/v1/expectations/export.
+type ExpectationsExportResponse struct {
+	// Expectations are the positive and negative expectations of the primary branch.
+	Expectations []ExportedExpectation `json:"expectations"`
+}
+
+// ExpectationsImportRequest is the request for /json/v1/expectations/import.
+type ExpectationsImportRequest struct {
+	// Expectations are the expectations to import, typically exported from another instance.
+	Expectations []ExportedExpectation `json:"expectations"`
+	// ValueMapping maps grouping keys to a mapping of the values used by the exporting instance to
+	// the values used by this instance, e.g. {"source_type": {"upstream": "downstream"}}. Values
+	// which are not mapped are imported unchanged.
+	ValueMapping map[string]map[string]string `json:"value_mapping,omitempty"`
+}
+
+// ExpectationsImportResponse is the response for /json/v1/expectations/import.
+type ExpectationsImportResponse struct {
+	// Imported is the number of expectations which were imported.
+	Imported int `json:"imported"`
+}
+
+// ComparedPatchset identifies one of the sides of a ChangelistComparisonResponse.
+type ComparedPatchset struct {
+	// ChangelistID is the nonqualified id of the CL.
+	ChangelistID string `json:"changelist_id"`
+	// PatchsetID is the nonqualified id of the patchset.
+	PatchsetID string `json:"patchset_id"`
+}
+
+// ChangelistComparisonResponse is the response for /json/v1/changelists/compare.
+type ChangelistComparisonResponse struct {
+	Left  ComparedPatchset `json:"left"`
+	Right ComparedPatchset `json:"right"`
+	// Identical is true if every trace drew the same digests on both patchsets.
+	Identical bool `json:"identical"`
+	// NumTraces is the number of traces with data on either patchset.
+	NumTraces int `json:"num_traces"`
+	// Tests are the tests with at least one trace which differs, sorted by grouping.
+	Tests []TestComparison `json:"tests"`
+}
+
+// TestComparison is a test which drew different digests on two patchsets.
+type TestComparison struct {
+	Grouping paramtools.Params `json:"grouping"`
+	// Traces are the traces of the test which differ, sorted by trace id.
+	Traces []TraceComparison `json:"traces"`
+}
+
+// TraceComparison is a trace which drew different digests on two patchsets.
+type TraceComparison struct {
+	TraceID tiling.TraceIDV2  `json:"trace_id"`
+	Params  paramtools.Params `json:"params"`
+	// LeftDigests and RightDigests are the digests drawn on each patchset. There can be more than
+	// one, e.g. if a tryjob was retried, and none if the trace has no data on that patchset.
+	LeftDigests  []types.Digest `json:"left_digests"`
+	RightDigests []types.Digest `json:"right_digests"`
+	// Diff describes how the digests differ. It is only set if each patchset drew a single digest
+	// for the trace and the diff between them has been computed.
+	Diff *DiffMetrics `json:"diff,omitempty"`
+}
+
+// TestPriorityResponse is the response for /json/v1/tests/priority.
+type TestPriorityResponse struct {
+	// NumChangelists is the number of recent CLs the priorities are based on.
+	NumChangelists int `json:"num_changelists"`
+	// Tests are sorted by Score, highest first.
+	Tests []TestPriority `json:"tests"`
+}
+
+// TestPriority describes how often a test drew new images on recent CLs.
+type TestPriority struct {
+	Grouping paramtools.Params `json:"grouping"`
+	// ChangelistsRun is the number of CLs on which the test drew any images.
+	ChangelistsRun int `json:"changelists_run"`
+	// ChangelistsChanged is the number of CLs on which the test drew at least one image which
+	// isn't positive on the primary branch.
+	ChangelistsChanged int `json:"changelists_changed"`
+	// Score is the fraction of the CLs on which the test changed, discounted for tests that ran on
+	// few CLs. Tests with higher scores are more likely to catch a change.
+	Score float64 `json:"score"`
+}
+
+// DiffMetrics describes the diff between two digests. See SRDiffDigest.
+type DiffMetrics struct {
+	CombinedMetric   float32 `json:"combinedMetric"`
+	NumDiffPixels    int     `json:"numDiffPixels"`
+	PixelDiffPercent float32 `json:"pixelDiffPercent"`
+	MaxRGBADiffs     [4]int  `json:"maxRGBADiffs"`
+	DimDiffer        bool    `json:"dimDiffer"`
 }
 
 // GUIStatus reflects the current triage status of the various corpora at head.
@@ -666,6 +1003,15 @@ type GUICorpusStatus struct {
 
 	// Number of untriaged digests in HEAD.
 	UntriagedCount int `json:"untriagedCount"`
+
+	// OldestUntriagedAgeSeconds is how long ago the commit was made at which the oldest of the
+	// untriaged digests in HEAD was first produced (within the current window). It is 0 if
+	// there are no untriaged digests. This is not computed for public views.
+	OldestUntriagedAgeSeconds int64 `json:"oldestUntriagedAgeSeconds,omitempty"`
+
+	// TriagedLast24h is the number of digests on the primary branch that were triaged as
+	// positive or negative in the last 24 hours. This is not computed for public views.
+	TriagedLast24h int `json:"triagedLast24h,omitempty"`
 }
 
 type PositiveDigestsByGroupingIDResponse struct {
@@ -683,6 +1029,27 @@ type PositiveDigestsTraceInfo struct {
 	PositiveDigests []types.Digest `json:"digests"`
 }
 
+// SimilarDigestsResponse is the response for the /json/v1/similar RPC.
+type SimilarDigestsResponse struct {
+	// Digest is the digest that was searched for.
+	Digest types.Digest `json:"digest"`
+	// Results are the digests in the current window whose perceptual hash is within the
+	// requested distance of Digest's, sorted by ascending distance.
+	Results []SimilarDigest `json:"results"`
+}
+
+// SimilarDigest is a digest that looks similar to the one searched for.
+type SimilarDigest struct {
+	Digest types.Digest `json:"digest"`
+	// Distance is the number of bits (out of 64) that differ between the perceptual hashes of the
+	// two images. 0 means the images are likely visually identical.
+	Distance int `json:"distance"`
+	// Grouping is the grouping (e.g. test) in which this digest was produced.
+	Grouping paramtools.Params `json:"grouping"`
+	// Status is the triage status of this digest in Grouping.
+	Status expectations.Label `json:"status"`
+}
+
 // GroupingsResponse is the response for the /json/v1/groupings RPC.
 type GroupingsResponse struct {
 	// GroupingParamKeysByCorpus contains the param keys that comprise the grouping of each corpus.
@@ -715,3 +1082,58 @@ type DetailsRequest struct {
 	ChangelistID     string            `json:"changelist_id,omitempty"`
 	CodeReviewSystem string            `json:"crs,omitempty"`
 }
+
+// DetailsBulkRequest is the request for the /json/v1/details/bulk RPC. All entries are looked up
+// against the same (optional) CL.
+type DetailsBulkRequest struct {
+	Entries          []DetailsBulkEntry `json:"entries"`
+	ChangelistID     string             `json:"changelist_id,omitempty"`
+	CodeReviewSystem string             `json:"crs,omitempty"`
+}
+
+// DetailsBulkEntry identifies a single digest as produced by a single grouping.
+type DetailsBulkEntry struct {
+	Grouping paramtools.Params `json:"grouping"`
+	Digest   types.Digest      `json:"digest"`
+}
+
+// DetailsBulkResponse is the response for the /json/v1/details/bulk RPC. Results are returned in
+// the same order as the entries in the request.
+type DetailsBulkResponse struct {
+	Results []DetailsBulkResult `json:"results"`
+}
+
+// DetailsBulkResult is the result for a single entry of a DetailsBulkRequest. Exactly one of
+// Details or Error will be set.
+type DetailsBulkResult struct {
+	Grouping paramtools.Params `json:"grouping"`
+	Digest   types.Digest      `json:"digest"`
+	Details  *DigestDetails    `json:"details,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// ImageGCReportResponse is the response for the /json/v1/imagegc/report RPC. It describes the
+// images that the next run of the image garbage collector would delete.
+type ImageGCReportResponse struct {
+	// DryRun is true if the garbage collector only counts the images it would delete.
+	DryRun bool `json:"dry_run"`
+	// ArchiveBucket, if set, is where unreferenced images are moved instead of being deleted.
+	ArchiveBucket string `json:"archive_bucket,omitempty"`
+	// ImagesSeen is the number of images in GCS.
+	ImagesSeen int `json:"images_seen"`
+	// ReferencedDigests is the number of digests that are kept.
+	ReferencedDigests int `json:"referenced_digests"`
+	// UnreferencedImages is the number of images that would be deleted.
+	UnreferencedImages int `json:"unreferenced_images"`
+	// UnreferencedBytes is the combined size of the images that would be deleted.
+	UnreferencedBytes int64 `json:"unreferenced_bytes"`
+	// Images are the largest of the images that would be deleted, largest first.
+	Images []UnreferencedImage `json:"images"`
+}
+
+// UnreferencedImage is an image that would be deleted by the image garbage collector.
+type UnreferencedImage struct {
+	Digest  types.Digest `json:"digest"`
+	Size    int64        `json:"size"`
+	Created time.Time    `json:"created"`
+}
@@ -0,0 +1,113 @@
+// Package imageurl contains helpers for the content-addressed image URLs served by Gold. Images
+// and diffs are identified by the digests of the images involved, so once such a URL resolves it
+// will always resolve to the same bytes. That makes them safe to cache forever, e.g. by a CDN.
+//
+// Instances which are not public can require these URLs to be signed. A signature covers the
+// whole ImmutablePrefix (not a single image) until it expires, so the frontend can build image
+// URLs itself and append the same query parameters to all of them.
+package imageurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/golden/go/types"
+)
+
+const (
+	// ImmutablePrefix is the path prefix of all content-addressed image URLs.
+	ImmutablePrefix = "/img/immutable/"
+
+	// ExpiresParam is the query parameter with the unix time (in seconds) a signature expires.
+	ExpiresParam = "expires"
+	// SignatureParam is the query parameter with the signature itself.
+	SignatureParam = "sig"
+)
+
+// DigestPath returns the content-addressed path for the image with the given digest.
+func DigestPath(d types.Digest) string {
+	return ImmutablePrefix + "images/" + string(d) + ".png"
+}
+
+// DiffPath returns the content-addressed path for the diff between the two given images. The
+// digests are sorted, so there is exactly one path per pair of images.
+func DiffPath(left, right types.Digest) string {
+	left, right = CanonicalDiffOrder(left, right)
+	return ImmutablePrefix + "diffs/" + string(left) + "-" + string(right) + ".png"
+}
+
+// CanonicalDiffOrder returns the given digests in the order used by DiffPath.
+func CanonicalDiffOrder(left, right types.Digest) (types.Digest, types.Digest) {
+	if right < left {
+		return right, left
+	}
+	return left, right
+}
+
+// Signer creates and verifies signatures for the image URLs under ImmutablePrefix.
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSigner returns a Signer which signs with the given key. Signatures are valid for ttl.
+func NewSigner(key []byte, ttl time.Duration) (*Signer, error) {
+	if len(key) < sha256.Size {
+		return nil, skerr.Fmt("image URL signing key must be at least %d bytes long", sha256.Size)
+	}
+	if ttl <= 0 {
+		return nil, skerr.Fmt("image URL signature TTL must be positive, not %s", ttl)
+	}
+	return &Signer{key: key, ttl: ttl}, nil
+}
+
+// Params returns the query parameters which must be appended to image URLs so they are accepted
+// by Verify for at least ttl after the given time. The expiration is rounded up to a multiple of
+// half the ttl, so all the pages loaded in that window get the same URLs and can share cached
+// images.
+func (s *Signer) Params(now time.Time) url.Values {
+	bucket := s.ttl / 2
+	expiresAt := now.Add(s.ttl).Truncate(bucket)
+	if expiresAt.Before(now.Add(s.ttl)) {
+		expiresAt = expiresAt.Add(bucket)
+	}
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return url.Values{
+		ExpiresParam:   []string{expires},
+		SignatureParam: []string{s.sign(expires)},
+	}
+}
+
+// Verify returns the time the signature in the given query parameters expires, or an error if
+// the signature is missing, invalid or already expired.
+func (s *Signer) Verify(q url.Values, now time.Time) (time.Time, error) {
+	expires, sig := q.Get(ExpiresParam), q.Get(SignatureParam)
+	if expires == "" || sig == "" {
+		return time.Time{}, skerr.Fmt("image URL is not signed")
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.sign(expires))) {
+		return time.Time{}, skerr.Fmt("invalid image URL signature")
+	}
+	secs, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return time.Time{}, skerr.Wrapf(err, "invalid expiration %q", expires)
+	}
+	expiresAt := time.Unix(secs, 0)
+	if !now.Before(expiresAt) {
+		return time.Time{}, skerr.Fmt("image URL signature expired at %s", expiresAt)
+	}
+	return expiresAt, nil
+}
+
+// sign returns the signature of ImmutablePrefix with the given expiration.
+func (s *Signer) sign(expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	// Writes to a hash.Hash never fail.
+	_, _ = mac.Write([]byte(ImmutablePrefix + "?" + ExpiresParam + "=" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package imageurl
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	digestA = "81c4d3a64cf32143ff6c1fbf4cbbec2d"
+	digestB = "d20731492287002a3f046eae4bd4ce7d"
+)
+
+var signingTime = time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+func TestDigestPath_Success(t *testing.T) {
+	assert.Equal(t, "/img/immutable/images/81c4d3a64cf32143ff6c1fbf4cbbec2d.png", DigestPath(digestA))
+}
+
+func TestDiffPath_EitherOrder_SamePath(t *testing.T) {
+	const expected = "/img/immutable/diffs/81c4d3a64cf32143ff6c1fbf4cbbec2d-d20731492287002a3f046eae4bd4ce7d.png"
+	assert.Equal(t, expected, DiffPath(digestA, digestB))
+	assert.Equal(t, expected, DiffPath(digestB, digestA))
+}
+
+func TestNewSigner_InvalidArguments_ReturnsError(t *testing.T) {
+	_, err := NewSigner([]byte("too short"), time.Hour)
+	assert.Error(t, err)
+	_, err = NewSigner(bytes.Repeat([]byte("k"), 32), 0)
+	assert.Error(t, err)
+}
+
+func TestVerify_SignedParams_Success(t *testing.T) {
+	s, err := NewSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	require.NoError(t, err)
+
+	params := s.Params(signingTime)
+	// Rounded up from 06:06:07 to the next half hour.
+	assert.Equal(t, "1614839400", params.Get(ExpiresParam))
+	expires, err := s.Verify(params, signingTime.Add(59*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, time.Date(2021, time.March, 4, 6, 30, 0, 0, time.UTC).Equal(expires))
+}
+
+func TestParams_SameHalfTTL_SameParams(t *testing.T) {
+	s, err := NewSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, s.Params(signingTime), s.Params(signingTime.Add(20*time.Minute)))
+	assert.NotEqual(t, s.Params(signingTime), s.Params(signingTime.Add(30*time.Minute)))
+}
+
+func TestVerify_InvalidParams_ReturnsError(t *testing.T) {
+	s, err := NewSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	require.NoError(t, err)
+	other, err := NewSigner(bytes.Repeat([]byte("o"), 32), time.Hour)
+	require.NoError(t, err)
+	params := s.Params(signingTime)
+
+	_, err = s.Verify(url.Values{}, signingTime)
+	assert.Error(t, err, "unsigned")
+
+	_, err = s.Verify(params, signingTime.Add(90*time.Minute))
+	assert.Error(t, err, "expired")
+
+	_, err = other.Verify(params, signingTime)
+	assert.Error(t, err, "different key")
+
+	extended := url.Values{
+		ExpiresParam:   []string{"1714837967"},
+		SignatureParam: []string{params.Get(SignatureParam)},
+	}
+	_, err = s.Verify(extended, signingTime)
+	assert.Error(t, err, "expiration was modified")
+}
@@ -5,14 +5,18 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image"
 	"image/png"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +37,7 @@ import (
 	"go.goldmine.build/go/alogin"
 	"go.goldmine.build/go/httputils"
 	"go.goldmine.build/go/human"
+	"go.goldmine.build/go/metrics2"
 	"go.goldmine.build/go/now"
 	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/skerr"
@@ -40,17 +45,25 @@ import (
 	"go.goldmine.build/go/sql/sqlutil"
 	"go.goldmine.build/go/util"
 	"go.goldmine.build/golden/go/clstore"
+	"go.goldmine.build/golden/go/comment"
+	"go.goldmine.build/golden/go/config"
 	"go.goldmine.build/golden/go/diff"
 	"go.goldmine.build/golden/go/expectations"
 	"go.goldmine.build/golden/go/ignore"
+	"go.goldmine.build/golden/go/imagegc"
+	"go.goldmine.build/golden/go/ingestion"
+	"go.goldmine.build/golden/go/jsonio"
 	"go.goldmine.build/golden/go/search"
 	search_query "go.goldmine.build/golden/go/search/query"
 	"go.goldmine.build/golden/go/sql"
 	"go.goldmine.build/golden/go/sql/schema"
 	"go.goldmine.build/golden/go/storage"
+	"go.goldmine.build/golden/go/tiling"
+	"go.goldmine.build/golden/go/triageevents"
 	"go.goldmine.build/golden/go/types"
 	"go.goldmine.build/golden/go/validation"
 	"go.goldmine.build/golden/go/web/frontend"
+	"go.goldmine.build/golden/go/web/imageurl"
 )
 
 const (
@@ -81,6 +94,47 @@ const (
 	baselineCachePrimaryBranchEntryTTL   = 10 * time.Second
 	baselineCacheSecondaryBranchEntryTTL = time.Minute
 	baselineCacheCleanupInterval         = 10 * time.Minute
+
+	// maxDetailsBulkEntries is the maximum number of digests that can be requested in a single
+	// call to the bulk details RPC.
+	maxDetailsBulkEntries = 200
+	// detailsBulkConcurrency is how many digest details are looked up in parallel for a single
+	// call to the bulk details RPC.
+	detailsBulkConcurrency = 8
+
+	// defaultSimilarDigestsDistance is the default maximum number of bits that may differ between
+	// the perceptual hashes of two images for them to be considered similar.
+	defaultSimilarDigestsDistance = 6
+	// maxSimilarDigestsDistance caps the distance that can be requested; at larger distances
+	// unrelated images start to match.
+	maxSimilarDigestsDistance = 16
+	// maxSimilarDigestsResults is the maximum number of similar digests returned.
+	maxSimilarDigestsResults = 200
+	// similarDigestsCandidatesTTL is how long the perceptual hashes of the digests in the window
+	// are cached, so that searching for similar digests doesn't scan all of them every time.
+	similarDigestsCandidatesTTL = 5 * time.Minute
+
+	// defaultFlakyTestsLimit is the default number of flaky tests returned per corpus.
+	defaultFlakyTestsLimit = 20
+	// maxFlakyTestsLimit caps the number of flaky tests that can be requested per corpus.
+	maxFlakyTestsLimit = 500
+
+	// defaultImageGCReportLimit is the default number of images listed by ImageGCReportHandler.
+	defaultImageGCReportLimit = 100
+	// maxImageGCReportLimit caps the number of images that ImageGCReportHandler can list.
+	maxImageGCReportLimit = 10000
+
+	// maxIngestBodyBytes limits the size of results uploaded to IngestHandler.
+	maxIngestBodyBytes = 64 << 20
+
+	// maxCombinedMetricForSuggestion is the largest diff.CombinedDiffMetric between an untriaged
+	// digest and its closest triaged digest for which we suggest a triage label. Diffs below this
+	// are typically anti-aliasing or small color changes.
+	maxCombinedMetricForSuggestion = 1.0
+
+	// testPriorityChangelists is the number of most recently updated CLs that test priorities
+	// are computed from.
+	testPriorityChangelists = 500
 )
 
 type validateFields int
@@ -101,6 +155,31 @@ type HandlersConfig struct {
 	Search2API                search.API
 	WindowSize                int
 	GroupingParamKeysByCorpus map[string][]string
+	// DiffBudget is the optional per-patchset limit on image changes. See config.DiffBudgetConfig.
+	DiffBudget *config.DiffBudgetConfig
+	// TriageEvents, if set, is notified of every change to the expectations.
+	TriageEvents triageevents.Publisher
+	// PrimaryBranchResults is where results uploaded to IngestHandler are written. If it is nil,
+	// uploading results is disabled.
+	PrimaryBranchResults *config.GCSSourceConfig
+	// SecondaryBranchResults is where uploaded tryjob results are written. If it is nil, uploading
+	// tryjob results is disabled.
+	SecondaryBranchResults *config.GCSSourceConfig
+	// CommentStore stores the comments users leave on digests and changelists. If it is nil,
+	// comments are disabled.
+	CommentStore comment.Store
+	// ImageURLSigner, if set, is used to require valid signatures on the content-addressed image
+	// URLs. If it is nil, those URLs can be fetched by anybody who can reach the server.
+	ImageURLSigner *imageurl.Signer
+	// ImageGC, if set, is the configuration of the image garbage collector, which is needed to
+	// report which images it would delete. If it is nil, the report is disabled.
+	ImageGC *config.ImageGCConfig
+	// LegacyRPCDeprecation, if not zero, is the date JSON RPCs which have a newer version were
+	// deprecated. It is sent in the Deprecation header of the responses of those RPCs.
+	LegacyRPCDeprecation time.Time
+	// LegacyRPCSunset, if not zero, is the date after which deprecated JSON RPCs may be removed.
+	// It is sent in the Sunset header of the responses of those RPCs.
+	LegacyRPCSunset time.Time
 }
 
 // Handlers represents all the handlers (e.g. JSON endpoints) of Gold.
@@ -112,8 +191,9 @@ type Handlers struct {
 	anonymousCheapQuota     *rate.Limiter
 	anonymousGerritQuota    *rate.Limiter
 
-	clSummaryCache *lru.Cache
-	baselineCache  *ttlcache.Cache
+	clSummaryCache      *lru.Cache
+	baselineCache       *ttlcache.Cache
+	similarDigestsCache *ttlcache.Cache
 
 	statusCache      frontend.GUIStatus
 	statusCacheMutex sync.RWMutex
@@ -124,6 +204,9 @@ type Handlers struct {
 	knownHashesMutex sync.RWMutex
 	knownHashesCache string
 
+	rpcs      []frontend.RPC
+	rpcsMutex sync.RWMutex
+
 	alogin alogin.Login
 }
 
@@ -158,6 +241,7 @@ func NewHandlers(conf HandlersConfig, val validateFields, alogin alogin.Login) (
 		anonymousGerritQuota:    rate.NewLimiter(maxAnonQPSGerritPlugin, maxAnonBurstGerritPlugin),
 		clSummaryCache:          clcache,
 		baselineCache:           ttlcache.New(baselineCachePrimaryBranchEntryTTL, baselineCacheCleanupInterval),
+		similarDigestsCache:     ttlcache.New(similarDigestsCandidatesTTL, similarDigestsCandidatesTTL),
 		alogin:                  alogin,
 	}, nil
 }
@@ -450,9 +534,59 @@ func (wh *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		httputils.ReportError(w, err, "Search for digests failed in the SQL backend.", http.StatusInternalServerError)
 		return
 	}
+	addTriageSuggestions(searchResponse)
 	sendJSONResponse(w, searchResponse)
 }
 
+// SearchFacetsHandler returns, for the search described by the same query parameters as
+// SearchHandler, how many results each additional trace key=value would leave. This allows the UI
+// to suggest ways to refine the search.
+func (wh *Handlers) SearchFacetsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	q, ok := parseSearchQuery(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Minute)
+	defer cancel()
+	ctx, span := trace.StartSpan(ctx, "web_SearchFacetsHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	resp, err := wh.Search2API.GetSearchFacets(ctx, q)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not compute search facets.", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, resp)
+}
+
+// addTriageSuggestions suggests a label for every untriaged result whose closest triaged digest
+// (as computed by the search) is similar enough that it likely should have the same label. This
+// allows the UI to offer a one-click triage for such digests.
+func addTriageSuggestions(resp *frontend.SearchResponse) {
+	for _, sr := range resp.Results {
+		if sr.Status != expectations.Untriaged || sr.ClosestRef == frontend.NoRef {
+			continue
+		}
+		closest := sr.RefDiffs[sr.ClosestRef]
+		if closest == nil || closest.DimDiffer || closest.CombinedMetric > maxCombinedMetricForSuggestion {
+			continue
+		}
+		sr.Suggestion = &frontend.TriageSuggestion{
+			Label:            closest.Status,
+			ClosestDigest:    closest.Digest,
+			CombinedMetric:   closest.CombinedMetric,
+			NumDiffPixels:    closest.NumDiffPixels,
+			PixelDiffPercent: closest.PixelDiffPercent,
+			MaxRGBADiffs:     closest.MaxRGBADiffs,
+		}
+	}
+}
+
 // parseSearchQuery extracts the search query from request.
 func parseSearchQuery(w http.ResponseWriter, r *http.Request) (*search_query.Search, bool) {
 	q := search_query.Search{Limit: 50}
@@ -509,6 +643,73 @@ func (wh *Handlers) DetailsHandler(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, ret)
 }
 
+// DetailsBulkHandler returns the details about many digests in a single request. A failure to
+// look up one entry does not fail the whole request; instead, the error is reported alongside
+// that entry and the remaining results are still returned.
+func (wh *Handlers) DetailsBulkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_DetailsBulkHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	// A single request can look up many digests, so it counts as an expensive query.
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	req := frontend.DetailsBulkRequest{}
+	if err := parseJSON(r, &req); err != nil {
+		httputils.ReportError(w, err, "Failed to parse JSON request.", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Entries) == 0 {
+		http.Error(w, "Entries cannot be empty.", http.StatusBadRequest)
+		return
+	}
+	if len(req.Entries) > maxDetailsBulkEntries {
+		http.Error(w, fmt.Sprintf("At most %d entries can be requested at once.", maxDetailsBulkEntries), http.StatusBadRequest)
+		return
+	}
+	if req.CodeReviewSystem != "" && req.ChangelistID != "" {
+		if _, ok := wh.getCodeReviewSystem(req.CodeReviewSystem); !ok {
+			http.Error(w, "Invalid code review system.", http.StatusBadRequest)
+			return
+		}
+	}
+	span.AddAttributes(trace.Int64Attribute("entries", int64(len(req.Entries))))
+
+	results := make([]frontend.DetailsBulkResult, len(req.Entries))
+	eg, eCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(detailsBulkConcurrency)
+	for i, entry := range req.Entries {
+		results[i] = frontend.DetailsBulkResult{
+			Grouping: entry.Grouping,
+			Digest:   entry.Digest,
+		}
+		if len(entry.Grouping) == 0 {
+			results[i].Error = "Grouping cannot be empty."
+			continue
+		}
+		if !validation.IsValidDigest(string(entry.Digest)) {
+			results[i].Error = "Invalid digest."
+			continue
+		}
+		eg.Go(func() error {
+			details, err := wh.Search2API.GetDigestDetails(eCtx, entry.Grouping, entry.Digest, req.ChangelistID, req.CodeReviewSystem)
+			if err != nil {
+				// We don't return the error because that would cancel the other lookups.
+				sklog.Warningf("Could not get details for digest %s in grouping %v: %s", entry.Digest, entry.Grouping, err)
+				results[i].Error = "Unable to get digest details."
+				return nil
+			}
+			results[i].Details = &details
+			return nil
+		})
+	}
+	// None of the goroutines return an error.
+	_ = eg.Wait()
+	sendJSONResponse(w, frontend.DetailsBulkResponse{Results: results})
+}
+
 // GroupingForTestHandler looks up and returns the grouping corresponding to a test. This RPC acts
 // as a bridge for clients that do not have access to grouping information (only Gold's details
 // page at the time of writing.)
@@ -624,6 +825,54 @@ func (wh *Handlers) ListIgnoreRules2(w http.ResponseWriter, r *http.Request) {
 	sendJSONResponse(w, response)
 }
 
+// IgnoreStatsHandler reports how many traces each ignore rule currently matches, with the rules
+// matching the fewest traces first. Rules which match nothing are likely safe to delete.
+func (wh *Handlers) IgnoreStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_IgnoreStatsHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	ignores, err := wh.getIgnores2(ctx)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve ignore rules.", http.StatusInternalServerError)
+		return
+	}
+
+	ts := now.Now(ctx)
+	resp := frontend.IgnoreStatsResponse{
+		Rules: make([]frontend.IgnoreRuleStats, 0, len(ignores)),
+	}
+	for _, rule := range ignores {
+		stats := frontend.IgnoreRuleStats{
+			ID:              rule.ID,
+			Query:           rule.Query,
+			Owner:           ignore.Owner(ignore.Rule{CreatedBy: rule.CreatedBy, UpdatedBy: rule.UpdatedBy}),
+			Expires:         rule.Expires,
+			Expired:         rule.Expires.Before(ts),
+			MatchedTraces:   rule.Count,
+			ExclusiveTraces: rule.ExclusiveCount,
+		}
+		if stats.MatchedTraces == 0 {
+			resp.NumUnused++
+		}
+		if stats.Expired {
+			resp.NumExpired++
+		}
+		resp.Rules = append(resp.Rules, stats)
+	}
+	sort.Slice(resp.Rules, func(i, j int) bool {
+		if resp.Rules[i].MatchedTraces != resp.Rules[j].MatchedTraces {
+			return resp.Rules[i].MatchedTraces < resp.Rules[j].MatchedTraces
+		}
+		return resp.Rules[i].ID < resp.Rules[j].ID
+	})
+	sendJSONResponse(w, resp)
+}
+
 // getIgnores2 fetches all ignore rules and converts them into the frontend format. It will add the
 // trace counts for each rule.
 func (wh *Handlers) getIgnores2(ctx context.Context) ([]frontend.IgnoreRule, error) {
@@ -875,13 +1124,18 @@ func (wh *Handlers) triage2(ctx context.Context, userID string, req frontend.Tri
 		return nil
 	}
 	span.AddAttributes(trace.Int64Attribute("num_changes", int64(len(allDeltas))))
+	return wh.writeTriageDeltas(ctx, userID, branch, allDeltas)
+}
 
+// writeTriageDeltas applies the given partially filled-out deltas to the given branch (or the
+// primary branch if branch is empty), recording them in the triage log as changes made by userID.
+func (wh *Handlers) writeTriageDeltas(ctx context.Context, userID, branch string, allDeltas []schema.ExpectationDeltaRow) error {
 	// If this number is too big, the query can take a long time to land (many retries) and in
 	// extreme cases, exceed the number of parameters a SQL query can support.
 	const maxTriageBatchSize = 1000
 	return util.ChunkIter(len(allDeltas), maxTriageBatchSize, func(startIdx int, endIdx int) error {
 		deltas := allDeltas[startIdx:endIdx]
-		err = crdbpgx.ExecuteTx(ctx, wh.DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		err := crdbpgx.ExecuteTx(ctx, wh.DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
 			newRecordID, err := writeRecord(ctx, tx, userID, len(deltas), branch)
 			if err != nil {
 				return err
@@ -902,6 +1156,7 @@ func (wh *Handlers) triage2(ctx context.Context, userID string, req frontend.Tri
 		if err != nil {
 			return skerr.Wrapf(err, "writing %d expectations from %s to branch %q", len(deltas), userID, branch)
 		}
+		wh.publishTriageEvents(ctx, userID, branch, deltas)
 		return nil
 	})
 }
@@ -1052,7 +1307,19 @@ func (wh *Handlers) triage3(ctx context.Context, userID string, req frontend.Tri
 		userID = req.ImageMatchingAlgorithm
 	}
 
-	allDeltas, err := convertTriageDeltasToExpectationDeltaRows(req.Deltas)
+	deltas := req.Deltas
+	if req.IncludeVariants {
+		if branch != "" {
+			return frontend.TriageResponse{}, skerr.Fmt("triaging the variants of digests is not supported on changelists")
+		}
+		var err error
+		deltas, err = wh.addVariantDeltas(ctx, deltas)
+		if err != nil {
+			return frontend.TriageResponse{}, skerr.Wrap(err)
+		}
+	}
+
+	allDeltas, err := convertTriageDeltasToExpectationDeltaRows(deltas)
 	if err != nil {
 		return frontend.TriageResponse{}, skerr.Wrapf(err, "converting TriageDeltas to ExpectationDeltaRows")
 	}
@@ -1067,7 +1334,7 @@ func (wh *Handlers) triage3(ctx context.Context, userID string, req frontend.Tri
 	const maxTriageBatchSize = 1000
 	err = util.ChunkIter(len(allDeltas), maxTriageBatchSize, func(startIdx int, endIdx int) error {
 		deltas := allDeltas[startIdx:endIdx]
-		return crdbpgx.ExecuteTx(ctx, wh.DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		err := crdbpgx.ExecuteTx(ctx, wh.DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
 			if err := verifyExpectationDeltaRowsLabelBefore(ctx, tx, deltas, branch); err != nil {
 				// Could be a triageConflictError if any of the LabelBefore fields do not match
 				// their expected value. This error is handled outside of the transaction.
@@ -1089,6 +1356,11 @@ func (wh *Handlers) triage3(ctx context.Context, userID string, req frontend.Tri
 			}
 			return applyDeltasToBranch(ctx, tx, deltas, branch)
 		})
+		if err != nil {
+			return err
+		}
+		wh.publishTriageEvents(ctx, userID, branch, deltas)
+		return nil
 	})
 	if err != nil {
 		// If any of the deltas' LabelBefore do not match the corresponding entries in the
@@ -1115,6 +1387,43 @@ func (wh *Handlers) triage3(ctx context.Context, userID string, req frontend.Tri
 	return frontend.TriageResponse{Status: frontend.TriageResponseStatusOK}, nil
 }
 
+// addVariantDeltas returns the given deltas followed by deltas which apply the same labels to the
+// digests drawn by the variants of the triaged digests. Variants which already have the desired
+// label, or which are triaged explicitly, are skipped.
+func (wh *Handlers) addVariantDeltas(ctx context.Context, deltas []frontend.TriageDelta) ([]frontend.TriageDelta, error) {
+	ctx, span := trace.StartSpan(ctx, "addVariantDeltas")
+	defer span.End()
+	key := func(grouping paramtools.Params, digest types.Digest) string {
+		g, _ := sql.SerializeMap(grouping)
+		return g + string(digest)
+	}
+	seen := make(map[string]bool, len(deltas))
+	for _, d := range deltas {
+		seen[key(d.Grouping, d.Digest)] = true
+	}
+	ret := append([]frontend.TriageDelta{}, deltas...)
+	for _, d := range deltas {
+		variants, err := wh.Search2API.GetVariants(ctx, d.Grouping, d.Digest)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "finding variants of %s in grouping %v", d.Digest, d.Grouping)
+		}
+		for _, v := range variants {
+			k := key(d.Grouping, v.Digest)
+			if seen[k] || v.Status == d.LabelAfter {
+				continue
+			}
+			seen[k] = true
+			ret = append(ret, frontend.TriageDelta{
+				Grouping:    d.Grouping,
+				Digest:      v.Digest,
+				LabelBefore: v.Status,
+				LabelAfter:  d.LabelAfter,
+			})
+		}
+	}
+	return ret, nil
+}
+
 // convertTriageDeltasToExpectationDeltaRows converts frontend.TriageDelta structs to
 // schema.ExpectationDeltaRow structs.
 func convertTriageDeltasToExpectationDeltaRows(deltas []frontend.TriageDelta) ([]schema.ExpectationDeltaRow, error) {
@@ -1591,6 +1900,12 @@ func (wh *Handlers) TriageLogHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	comments, err := wh.getTriageLogComments(ctx, crs, clID, logEntries)
+	if err != nil {
+		httputils.ReportError(w, err, "Unable to retrieve comments", http.StatusInternalServerError)
+		return
+	}
+
 	response := frontend.TriageLogResponse{
 		Entries: logEntries,
 		ResponsePagination: httputils.ResponsePagination{
@@ -1598,11 +1913,44 @@ func (wh *Handlers) TriageLogHandler(w http.ResponseWriter, r *http.Request) {
 			Size:   size,
 			Total:  total,
 		},
+		Comments: comments,
 	}
 
 	sendJSONResponse(w, response)
 }
 
+// getTriageLogComments returns the comments about the given CL (if any) followed by the comments
+// about the digests which were triaged in the given entries.
+func (wh *Handlers) getTriageLogComments(ctx context.Context, crs, clID string, entries []frontend.TriageLogEntry) ([]frontend.Comment, error) {
+	ctx, span := trace.StartSpan(ctx, "getTriageLogComments")
+	defer span.End()
+	rv := []frontend.Comment{} // We don't want null in our JSON response.
+	if wh.CommentStore == nil {
+		return rv, nil
+	}
+	if crs != "" {
+		comments, err := wh.CommentStore.ListForChangelist(ctx, crs, clID)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		rv = append(rv, convertComments(comments)...)
+	}
+	digests := types.DigestSet{}
+	for _, e := range entries {
+		for _, d := range e.Details {
+			digests[d.Digest] = true
+		}
+	}
+	if len(digests) == 0 {
+		return rv, nil
+	}
+	comments, err := wh.CommentStore.ListForDigests(ctx, digests.Keys())
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	return append(rv, convertComments(comments)...), nil
+}
+
 // getTriageLog returns the specified entries and the total count of expectation records.
 func (wh *Handlers) getTriageLog(ctx context.Context, crs, clid string, offset, size int) ([]frontend.TriageLogEntry, int, error) {
 	ctx, span := trace.StartSpan(ctx, "getTriageLog2")
@@ -1734,6 +2082,9 @@ func (wh *Handlers) undoExpectationChanges(ctx context.Context, recordID, userID
 	ctx, span := trace.StartSpan(ctx, "undoExpectationChanges")
 	defer span.End()
 
+	// These are set inside the transaction, so they can be used to publish events once it commits.
+	var appliedDeltas []schema.ExpectationDeltaRow
+	var branch string
 	err := crdbpgx.ExecuteTx(ctx, wh.DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
 		deltas, err := getDeltasForRecord(ctx, tx, recordID)
 		if err != nil {
@@ -1763,14 +2114,72 @@ func (wh *Handlers) undoExpectationChanges(ctx context.Context, recordID, userID
 		} else {
 			err = applyDeltasToBranch(ctx, tx, invertedDeltas, branchOfOriginal.String)
 		}
+		appliedDeltas = invertedDeltas
+		branch = branchOfOriginal.String
 		return err
 	})
 	if err != nil {
 		return skerr.Wrap(err)
 	}
+	wh.publishTriageEvents(ctx, userID, branch, appliedDeltas)
 	return nil
 }
 
+// publishTriageEventsTimeout limits how long publishing triage events can delay the response to
+// a triage request.
+const publishTriageEventsTimeout = 5 * time.Second
+
+// publishTriageEvents sends an event for each of the given deltas, which must already have been
+// committed to the DB. Failures are logged but not returned, since the expectations have been
+// changed regardless.
+func (wh *Handlers) publishTriageEvents(ctx context.Context, userID, branch string, deltas []schema.ExpectationDeltaRow) {
+	if wh.TriageEvents == nil || len(deltas) == 0 {
+		return
+	}
+	ctx, span := trace.StartSpan(ctx, "publishTriageEvents")
+	defer span.End()
+
+	var crs, clID string
+	if branch != "" {
+		// Branches are qualified CL IDs, see sql.Qualify.
+		crs, clID, _ = strings.Cut(branch, "_")
+	}
+	ts := now.Now(ctx)
+	groupings := map[schema.MD5Hash]paramtools.Params{}
+	events := make([]triageevents.Event, 0, len(deltas))
+	for _, d := range deltas {
+		key := sql.AsMD5Hash(d.GroupingID)
+		grouping, ok := groupings[key]
+		if !ok {
+			var err error
+			grouping, err = wh.lookupGrouping(ctx, d.GroupingID)
+			if err != nil {
+				sklog.Errorf("Could not publish %d triage events from %s: %s", len(deltas), userID, err)
+				return
+			}
+			groupings[key] = grouping
+		}
+		events = append(events, triageevents.Event{
+			Digest:           types.Digest(hex.EncodeToString(d.Digest)),
+			Grouping:         grouping,
+			Test:             types.TestName(grouping[types.PrimaryKeyField]),
+			Corpus:           grouping[types.CorpusField],
+			LabelBefore:      d.LabelBefore.ToExpectation(),
+			Label:            d.LabelAfter.ToExpectation(),
+			Author:           userID,
+			CodeReviewSystem: crs,
+			ChangelistID:     clID,
+			RecordID:         d.ExpectationRecordID.String(),
+			Timestamp:        ts,
+		})
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTriageEventsTimeout)
+	defer cancel()
+	if err := wh.TriageEvents.Publish(ctx, events); err != nil {
+		sklog.Errorf("Could not publish %d triage events from %s: %s", len(events), userID, err)
+	}
+}
+
 // writeRecord writes a new ExpectationRecord to the DB.
 func writeRecord(ctx context.Context, tx pgx.Tx, userID string, numChanges int, branch string) (uuid.UUID, error) {
 	ctx, span := trace.StartSpan(ctx, "writeRecord")
@@ -2091,100 +2500,473 @@ WHERE label = 'n' OR label = 'p'`
 	return response, nil
 }
 
-// DigestListHandler returns a list of digests for a given test. This is used by goldctl's
-// local diff tech.
-func (wh *Handlers) DigestListHandler(w http.ResponseWriter, r *http.Request) {
-	if err := wh.cheapLimitForAnonUsers(r); err != nil {
-		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
-		return
-	}
-	ctx, span := trace.StartSpan(r.Context(), "web_DigestListHandler")
+// BaselineAtCommitHandler returns the baseline of the primary branch as it was when the given
+// commit landed, which allows tests to be run locally against historical expectations. The baseline
+// is reconstructed by replaying the triage log up to the time of the commit.
+func (wh *Handlers) BaselineAtCommitHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "frontend_BaselineAtCommitHandler")
 	defer span.End()
+	// No limit for anon users - like BaselineHandlerV2, this is used by the baseline servers.
 
-	if err := r.ParseForm(); err != nil {
-		httputils.ReportError(w, err, "Failed to parse form values", http.StatusInternalServerError)
+	hash := chi.URLParam(r, "hash")
+	if !gitHashRegex.MatchString(hash) {
+		http.Error(w, "Invalid commit hash; it must be a full git hash.", http.StatusBadRequest)
 		return
 	}
 
-	encodedGrouping := r.Form.Get("grouping")
-	if encodedGrouping == "" {
-		http.Error(w, "You must include 'grouping'", http.StatusBadRequest)
+	bl, err := wh.fetchBaselineAtCommit(ctx, hash)
+	if skerr.Unwrap(err) == pgx.ErrNoRows {
+		http.Error(w, "Unknown commit.", http.StatusNotFound)
 		return
 	}
-	groupingSet, err := url.ParseQuery(encodedGrouping)
 	if err != nil {
-		httputils.ReportError(w, skerr.Wrapf(err, "bad grouping %s", encodedGrouping), "Invalid grouping", http.StatusBadRequest)
+		httputils.ReportError(w, err, "Fetching baseline failed.", http.StatusInternalServerError)
 		return
 	}
-	grouping := make(paramtools.Params, len(groupingSet))
-	for key, values := range groupingSet {
-		if len(values) == 0 {
-			continue
-		}
-		grouping[key] = values[0]
+
+	sendJSONResponse(w, bl)
+}
+
+// gitHashRegex matches full git hashes.
+var gitHashRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// fetchBaselineAtCommit returns all the digests which were triaged positive or negative on the
+// primary branch at the time the given commit landed. It returns a wrapped pgx.ErrNoRows if the
+// commit is unknown.
+func (wh *Handlers) fetchBaselineAtCommit(ctx context.Context, hash string) (frontend.BaselineV2Response, error) {
+	ctx, span := trace.StartSpan(ctx, "fetchBaselineAtCommit")
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("hash", hash))
+
+	baselineCacheKey := "commit_" + hash
+	if val, ok := wh.baselineCache.Get(baselineCacheKey); ok {
+		return val.(frontend.BaselineV2Response), nil
 	}
 
-	// If needed, we could add a TTL cache here.
-	out, err := wh.Search2API.GetDigestsForGrouping(ctx, grouping)
-	if err != nil {
-		httputils.ReportError(w, err, "Could not retrieve digests", http.StatusInternalServerError)
-		return
+	row := wh.DB.QueryRow(ctx, `SELECT commit_time FROM GitCommits WHERE git_hash = $1`, hash)
+	var commitTime time.Time
+	if err := row.Scan(&commitTime); err != nil {
+		return frontend.BaselineV2Response{}, skerr.Wrap(err)
 	}
-	sendJSONResponse(w, out)
-}
 
-// Whoami returns the email address of the user or service account used to authenticate the
-// request. For debugging purposes only.
-func (wh *Handlers) Whoami(w http.ResponseWriter, r *http.Request) {
-	if err := wh.cheapLimitForAnonUsers(r); err != nil {
-		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
-		return
+	// Undoing a triage also creates a record, so the label after the most recent change to each
+	// digest before the commit is the label it had at that time.
+	const statement = `WITH
+RecordsBeforeCommit AS (
+	SELECT expectation_record_id, triage_time FROM ExpectationRecords
+	WHERE branch_name IS NULL AND triage_time <= $1
+),
+LatestDeltas AS (
+	SELECT DISTINCT ON (grouping_id, digest) grouping_id, digest, label_after
+	FROM ExpectationDeltas
+	JOIN RecordsBeforeCommit
+		ON ExpectationDeltas.expectation_record_id = RecordsBeforeCommit.expectation_record_id
+	ORDER BY grouping_id, digest, triage_time DESC
+)
+SELECT Groupings.keys ->> 'name', encode(digest, 'hex'), label_after FROM LatestDeltas
+JOIN Groupings ON LatestDeltas.grouping_id = Groupings.grouping_id
+WHERE label_after = 'n' OR label_after = 'p'`
+	rows, err := wh.DB.Query(ctx, statement, commitTime)
+	if err != nil {
+		return frontend.BaselineV2Response{}, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	baseline := expectations.Baseline{}
+	for rows.Next() {
+		var testName types.TestName
+		var digest types.Digest
+		var label schema.ExpectationLabel
+		if err := rows.Scan(&testName, &digest, &label); err != nil {
+			return frontend.BaselineV2Response{}, skerr.Wrap(err)
+		}
+		byDigest, ok := baseline[testName]
+		if !ok {
+			byDigest = map[types.Digest]expectations.Label{}
+			baseline[testName] = byDigest
+		}
+		byDigest[digest] = label.ToExpectation()
 	}
-	_, span := trace.StartSpan(r.Context(), "web_Whoami")
-	defer span.End()
 
-	user := wh.alogin.LoggedInAs(r)
-	sendJSONResponse(w, map[string]interface{}{
-		"whoami": user.String(),
-		"roles":  wh.alogin.Roles(r),
-	})
+	response := frontend.BaselineV2Response{
+		Expectations: baseline,
+		GitHash:      hash,
+	}
+	span.AddAttributes(trace.Int64Attribute("numExpectationsReturned", int64(len(response.Expectations))))
+	// The triage history does not change, but it is easy to make many requests for different
+	// commits, so don't keep them around for longer than CL baselines.
+	wh.baselineCache.Set(baselineCacheKey, response, baselineCacheSecondaryBranchEntryTTL)
+	return response, nil
 }
 
-// LatestPositiveDigestHandler returns the most recent positive digest for the given trace.
-// Starting at the tip of tree, it will skip over any missing data, untriaged digests or digests
-// triaged negative until it finds a positive digest.
-func (wh *Handlers) LatestPositiveDigestHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, span := trace.StartSpan(r.Context(), "web_LatestPositiveDigestHandler")
-	defer span.End()
-	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+// ExpectationsExportHandler returns the positive and negative expectations of the primary branch
+// along with their full groupings, so they can be imported into another instance, for example the
+// instance of a downstream fork. The expectations can be filtered by corpus and by any other
+// grouping param, e.g. ?corpus=gm&name=foo.
+func (wh *Handlers) ExpectationsExportHandler(w http.ResponseWriter, r *http.Request) {
+	if err := wh.limitForAnonUsers(r); err != nil {
 		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
 		return
 	}
+	ctx, span := trace.StartSpan(r.Context(), "web_ExpectationsExportHandler")
+	defer span.End()
 
-	tID := chi.URLParam(r, "traceID")
-	if tID == "" {
-		http.Error(w, "Must specify traceID.", http.StatusBadRequest)
-		return
-	}
-	traceID, err := hex.DecodeString(tID)
-	if err != nil {
-		httputils.ReportError(w, err, "Invalid traceID - must be an MD5 hash", http.StatusBadRequest)
-		return
+	filter := paramtools.Params{}
+	for key, values := range r.URL.Query() {
+		if len(values) != 1 {
+			http.Error(w, "Only one value per param is supported.", http.StatusBadRequest)
+			return
+		}
+		if key == "corpus" {
+			key = types.CorpusField
+		}
+		filter[key] = values[0]
 	}
-	digest, err := wh.getLatestPositiveDigest(ctx, traceID)
+
+	exps, err := wh.exportExpectations(ctx, filter)
 	if err != nil {
-		httputils.ReportError(w, err, "Could not complete query.", http.StatusInternalServerError)
+		httputils.ReportError(w, err, "Could not export expectations", http.StatusInternalServerError)
 		return
 	}
-	sendJSONResponse(w, frontend.MostRecentPositiveDigestResponse{Digest: digest})
+	sendJSONResponse(w, frontend.ExpectationsExportResponse{Expectations: exps})
 }
 
-func (wh *Handlers) getLatestPositiveDigest(ctx context.Context, traceID schema.TraceID) (types.Digest, error) {
-	ctx, span := trace.StartSpan(ctx, "getLatestPositiveDigest")
+// exportExpectations returns the positive and negative expectations of the primary branch whose
+// groupings contain all the given params, sorted by grouping and digest.
+func (wh *Handlers) exportExpectations(ctx context.Context, filter paramtools.Params) ([]frontend.ExportedExpectation, error) {
+	ctx, span := trace.StartSpan(ctx, "exportExpectations")
 	defer span.End()
 
-	const statement = `WITH
-RecentDigests AS (
+	statement := `SELECT Groupings.keys, encode(digest, 'hex'), label FROM Expectations
+JOIN Groupings ON Expectations.grouping_id = Groupings.grouping_id
+AS OF SYSTEM TIME '-0.1s'
+WHERE (label = 'n' OR label = 'p')`
+	var args []interface{}
+	if len(filter) > 0 {
+		statement += ` AND Groupings.keys @> $1`
+		args = append(args, filter)
+	}
+	rows, err := wh.DB.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var rv []frontend.ExportedExpectation
+	for rows.Next() {
+		var e frontend.ExportedExpectation
+		var label schema.ExpectationLabel
+		if err := rows.Scan(&e.Grouping, &e.Digest, &label); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		e.Label = label.ToExpectation()
+		rv = append(rv, e)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		gi, _ := sql.SerializeMap(rv[i].Grouping)
+		gj, _ := sql.SerializeMap(rv[j].Grouping)
+		if gi != gj {
+			return gi < gj
+		}
+		return rv[i].Digest < rv[j].Digest
+	})
+	span.AddAttributes(trace.Int64Attribute("num_exported", int64(len(rv))))
+	return rv, nil
+}
+
+// ExpectationsImportHandler imports expectations exported from another instance by
+// ExpectationsExportHandler into the primary branch, after mapping the values of their groupings
+// with the given mapping. The imported expectations show up in the triage log as changes made by
+// the logged-in user, and can be undone like any other triage.
+func (wh *Handlers) ExpectationsImportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_ExpectationsImportHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+
+	user := wh.alogin.LoggedInAs(r)
+	if user == alogin.NotLoggedIn {
+		http.Error(w, "You must be logged in to import expectations.", http.StatusUnauthorized)
+		return
+	}
+	if !wh.alogin.HasRole(r, roles.Editor) {
+		http.Error(w, "You must be logged in as an editor to change expectations", http.StatusUnauthorized)
+		return
+	}
+
+	req := frontend.ExpectationsImportRequest{}
+	if err := parseJSON(r, &req); err != nil {
+		httputils.ReportError(w, err, "Failed to parse JSON request.", http.StatusBadRequest)
+		return
+	}
+	groupings, deltas, err := importedDeltas(req)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid expectations", http.StatusBadRequest)
+		return
+	}
+	if len(deltas) > 0 {
+		if err := wh.storeGroupings(ctx, groupings); err != nil {
+			httputils.ReportError(w, err, "Could not import expectations", http.StatusInternalServerError)
+			return
+		}
+		if err := wh.writeTriageDeltas(ctx, user.String(), "", deltas); err != nil {
+			httputils.ReportError(w, err, "Could not import expectations", http.StatusInternalServerError)
+			return
+		}
+	}
+	sendJSONResponse(w, frontend.ExpectationsImportResponse{Imported: len(deltas)})
+}
+
+// importedDeltas maps the groupings of the expectations being imported and converts them into
+// partially filled-out deltas. It also returns the mapped groupings. If the same digest appears
+// more than once in a grouping, the last label wins.
+func importedDeltas(req frontend.ExpectationsImportRequest) ([]schema.GroupingRow, []schema.ExpectationDeltaRow, error) {
+	type expectationKey struct {
+		groupingID schema.MD5Hash
+		digest     types.Digest
+	}
+	groupings := map[schema.MD5Hash]schema.GroupingRow{}
+	deltaIndex := map[expectationKey]int{}
+	var deltas []schema.ExpectationDeltaRow
+	for _, e := range req.Expectations {
+		if e.Label != expectations.Positive && e.Label != expectations.Negative {
+			return nil, nil, skerr.Fmt("invalid label %q for digest %s, only positive and negative expectations can be imported", e.Label, e.Digest)
+		}
+		grouping := make(paramtools.Params, len(e.Grouping))
+		for key, value := range e.Grouping {
+			if mapped, ok := req.ValueMapping[key][value]; ok {
+				value = mapped
+			}
+			grouping[key] = value
+		}
+		if grouping[types.PrimaryKeyField] == "" || grouping[types.CorpusField] == "" {
+			return nil, nil, skerr.Fmt("grouping %v must have a %s and a %s", grouping, types.PrimaryKeyField, types.CorpusField)
+		}
+		_, groupingID := sql.SerializeMap(grouping)
+		digestBytes, err := sql.DigestToBytes(e.Digest)
+		if err != nil {
+			return nil, nil, skerr.Wrapf(err, "invalid digest %q", e.Digest)
+		}
+		groupings[sql.AsMD5Hash(groupingID)] = schema.GroupingRow{
+			GroupingID: groupingID,
+			Keys:       grouping,
+		}
+		delta := schema.ExpectationDeltaRow{
+			GroupingID: groupingID,
+			Digest:     digestBytes,
+			LabelAfter: schema.FromExpectationLabel(e.Label),
+		}
+		key := expectationKey{groupingID: sql.AsMD5Hash(groupingID), digest: e.Digest}
+		if idx, ok := deltaIndex[key]; ok {
+			deltas[idx] = delta
+			continue
+		}
+		deltaIndex[key] = len(deltas)
+		deltas = append(deltas, delta)
+	}
+	rows := make([]schema.GroupingRow, 0, len(groupings))
+	for _, g := range groupings {
+		rows = append(rows, g)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return bytes.Compare(rows[i].GroupingID, rows[j].GroupingID) < 0
+	})
+	return rows, deltas, nil
+}
+
+// storeGroupings makes sure the given groupings exist, so that imported expectations for tests
+// which have not been ingested yet show up correctly in the triage log.
+func (wh *Handlers) storeGroupings(ctx context.Context, groupings []schema.GroupingRow) error {
+	ctx, span := trace.StartSpan(ctx, "storeGroupings")
+	defer span.End()
+	const chunkSize = 200 // Arbitrarily picked
+	return util.ChunkIter(len(groupings), chunkSize, func(startIdx int, endIdx int) error {
+		batch := groupings[startIdx:endIdx]
+		const valuesPerRow = 2
+		statement := `INSERT INTO Groupings (grouping_id, keys) VALUES ` +
+			sqlutil.ValuesPlaceholders(valuesPerRow, len(batch)) + ` ON CONFLICT DO NOTHING`
+		arguments := make([]interface{}, 0, valuesPerRow*len(batch))
+		for _, row := range batch {
+			arguments = append(arguments, row.GroupingID, row.Keys)
+		}
+		err := crdbpgx.ExecuteTx(ctx, wh.DB, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, statement, arguments...)
+			return err // Don't wrap - crdbpgx might retry
+		})
+		return skerr.Wrapf(err, "storing %d groupings", len(batch))
+	})
+}
+
+// DigestListHandler returns a list of digests for a given test. This is used by goldctl's
+// local diff tech.
+func (wh *Handlers) DigestListHandler(w http.ResponseWriter, r *http.Request) {
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+	ctx, span := trace.StartSpan(r.Context(), "web_DigestListHandler")
+	defer span.End()
+
+	if err := r.ParseForm(); err != nil {
+		httputils.ReportError(w, err, "Failed to parse form values", http.StatusInternalServerError)
+		return
+	}
+
+	encodedGrouping := r.Form.Get("grouping")
+	if encodedGrouping == "" {
+		http.Error(w, "You must include 'grouping'", http.StatusBadRequest)
+		return
+	}
+	groupingSet, err := url.ParseQuery(encodedGrouping)
+	if err != nil {
+		httputils.ReportError(w, skerr.Wrapf(err, "bad grouping %s", encodedGrouping), "Invalid grouping", http.StatusBadRequest)
+		return
+	}
+	grouping := make(paramtools.Params, len(groupingSet))
+	for key, values := range groupingSet {
+		if len(values) == 0 {
+			continue
+		}
+		grouping[key] = values[0]
+	}
+
+	// If needed, we could add a TTL cache here.
+	out, err := wh.Search2API.GetDigestsForGrouping(ctx, grouping)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not retrieve digests", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, out)
+}
+
+// Whoami returns the email address of the user or service account used to authenticate the
+// request. For debugging purposes only.
+func (wh *Handlers) Whoami(w http.ResponseWriter, r *http.Request) {
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+	_, span := trace.StartSpan(r.Context(), "web_Whoami")
+	defer span.End()
+
+	user := wh.alogin.LoggedInAs(r)
+	sendJSONResponse(w, map[string]interface{}{
+		"whoami": user.String(),
+		"roles":  wh.alogin.Roles(r),
+	})
+}
+
+// RegisterRPC records that the given version of a JSON RPC is being served. Once a newer version
+// of the same RPC has been registered, the older versions are deprecated: their responses carry
+// Deprecation, Link and Sunset headers (see WithDeprecationHeaders) and they are marked as such
+// by RPCsHandler.
+func (wh *Handlers) RegisterRPC(method, route, path string, version int) {
+	wh.rpcsMutex.Lock()
+	defer wh.rpcsMutex.Unlock()
+	wh.rpcs = append(wh.rpcs, frontend.RPC{
+		Method:  method,
+		Route:   route,
+		Path:    path,
+		Version: version,
+	})
+}
+
+// successorRPC returns the route of the newest version of the given RPC, if it is newer than the
+// given version.
+func (wh *Handlers) successorRPC(method, path string, version int) (string, bool) {
+	wh.rpcsMutex.RLock()
+	defer wh.rpcsMutex.RUnlock()
+	newest, route := version, ""
+	for _, rpc := range wh.rpcs {
+		if rpc.Method == method && rpc.Path == path && rpc.Version > newest {
+			newest, route = rpc.Version, rpc.Route
+		}
+	}
+	return route, route != ""
+}
+
+// WithDeprecationHeaders wraps the handler of the given version of a JSON RPC, adding headers
+// which tell clients to move to the newest version of the RPC, if this version has been superseded.
+func (wh *Handlers) WithDeprecationHeaders(method, path string, version int, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if successor, ok := wh.successorRPC(method, path, version); ok {
+			if !wh.LegacyRPCDeprecation.IsZero() {
+				// See RFC 9745 for the format.
+				w.Header().Set("Deprecation", fmt.Sprintf("@%d", wh.LegacyRPCDeprecation.Unix()))
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			if !wh.LegacyRPCSunset.IsZero() {
+				w.Header().Set("Sunset", wh.LegacyRPCSunset.UTC().Format(http.TimeFormat))
+			}
+		}
+		h(w, r)
+	}
+}
+
+// RPCsHandler returns all the JSON RPCs served by this instance and their versions. Clients can
+// use it to pick the newest version of an RPC which they support.
+func (wh *Handlers) RPCsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+	_, span := trace.StartSpan(r.Context(), "web_RPCsHandler")
+	defer span.End()
+
+	wh.rpcsMutex.RLock()
+	rpcs := make([]frontend.RPC, len(wh.rpcs))
+	copy(rpcs, wh.rpcs)
+	wh.rpcsMutex.RUnlock()
+	for i := range rpcs {
+		rpcs[i].Successor, _ = wh.successorRPC(rpcs[i].Method, rpcs[i].Path, rpcs[i].Version)
+	}
+	sort.Slice(rpcs, func(i, j int) bool {
+		if rpcs[i].Path != rpcs[j].Path {
+			return rpcs[i].Path < rpcs[j].Path
+		}
+		if rpcs[i].Method != rpcs[j].Method {
+			return rpcs[i].Method < rpcs[j].Method
+		}
+		return rpcs[i].Version < rpcs[j].Version
+	})
+	resp := frontend.RPCsResponse{RPCs: rpcs}
+	if !wh.LegacyRPCSunset.IsZero() {
+		resp.Sunset = wh.LegacyRPCSunset.UTC().Format(http.TimeFormat)
+	}
+	sendJSONResponse(w, resp)
+}
+
+// LatestPositiveDigestHandler returns the most recent positive digest for the given trace.
+// Starting at the tip of tree, it will skip over any missing data, untriaged digests or digests
+// triaged negative until it finds a positive digest.
+func (wh *Handlers) LatestPositiveDigestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_LatestPositiveDigestHandler")
+	defer span.End()
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	tID := chi.URLParam(r, "traceID")
+	if tID == "" {
+		http.Error(w, "Must specify traceID.", http.StatusBadRequest)
+		return
+	}
+	traceID, err := hex.DecodeString(tID)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid traceID - must be an MD5 hash", http.StatusBadRequest)
+		return
+	}
+	digest, err := wh.getLatestPositiveDigest(ctx, traceID)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not complete query.", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, frontend.MostRecentPositiveDigestResponse{Digest: digest})
+}
+
+func (wh *Handlers) getLatestPositiveDigest(ctx context.Context, traceID schema.TraceID) (types.Digest, error) {
+	ctx, span := trace.StartSpan(ctx, "getLatestPositiveDigest")
+	defer span.End()
+
+	const statement = `WITH
+RecentDigests AS (
 	SELECT digest, commit_id, grouping_id FROM TraceValues WHERE trace_id = $1
 	ORDER BY commit_id DESC LIMIT 1000 -- arbitrary limit
 )
@@ -2364,6 +3146,10 @@ func (wh *Handlers) getCodeReviewSystem(crs string) (clstore.ReviewSystem, bool)
 const (
 	validDigestLength = 2 * md5.Size
 	dotPNG            = ".png"
+
+	// immutableMaxAge is how long the content-addressed images may be cached. A year is the
+	// longest time which is universally supported by caches.
+	immutableMaxAge = 365 * 24 * time.Hour
 )
 
 // ImageHandler returns either a single image or a diff between two images identified by their
@@ -2372,7 +3158,7 @@ func (wh *Handlers) ImageHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, span := trace.StartSpan(r.Context(), "web_ImageHandler")
 	defer span.End()
 	// No rate limit, as this should be quite fast.
-	_, imgFile := path.Split(r.URL.Path)
+	imgDir, imgFile := path.Split(r.URL.Path)
 	// Get the file that was requested and verify that it's a valid PNG file.
 	if !strings.HasSuffix(imgFile, dotPNG) {
 		noCacheNotFound(w)
@@ -2381,6 +3167,16 @@ func (wh *Handlers) ImageHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Trim the image extension to get the image or diff ID.
 	imgID := imgFile[:len(imgFile)-len(dotPNG)]
+	if strings.HasPrefix(imgDir, imageurl.ImmutablePrefix) {
+		wh.serveImmutableImage(ctx, w, r, strings.TrimPrefix(imgDir, imageurl.ImmutablePrefix), imgID)
+		return
+	}
+	if wh.ImageURLSigner != nil {
+		// Signatures only cover the content-addressed URLs, so the other image URLs would bypass them.
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		http.Error(w, "Image URLs must be signed.", http.StatusForbidden)
+		return
+	}
 	// Cache images for 12 hours.
 	w.Header().Set("Cache-Control", "public, max-age=43200")
 	if len(imgID) == validDigestLength {
@@ -2400,6 +3196,62 @@ func (wh *Handlers) ImageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveImmutableImage serves an image or a diff from one of the content-addressed URLs described
+// in the imageurl package. The images behind those URLs never change, so they can be cached for
+// as long as their signature (if any) is valid. There is only one URL for each diff; requests for
+// the diff with the digests in the other order are redirected to it.
+func (wh *Handlers) serveImmutableImage(ctx context.Context, w http.ResponseWriter, r *http.Request, imgDir, imgID string) {
+	maxAge := immutableMaxAge
+	if wh.ImageURLSigner != nil {
+		ts := now.Now(ctx)
+		expires, err := wh.ImageURLSigner.Verify(r.URL.Query(), ts)
+		if err != nil {
+			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+			httputils.ReportError(w, err, "Invalid or expired image URL.", http.StatusForbidden)
+			return
+		}
+		// Caches should not serve the image once the URL is no longer valid.
+		if untilExpired := expires.Sub(ts); untilExpired < maxAge {
+			maxAge = untilExpired
+		}
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int64(maxAge/time.Second)))
+
+	switch {
+	case imgDir == "images/" && len(imgID) == validDigestLength:
+		if notModified(w, r, imgID) {
+			return
+		}
+		wh.serveImageWithDigest(ctx, w, types.Digest(imgID))
+	case imgDir == "diffs/" && len(imgID) == validDigestLength*2+1:
+		left := types.Digest(imgID[:validDigestLength])
+		right := types.Digest(imgID[validDigestLength+1:])
+		if canonicalLeft, _ := imageurl.CanonicalDiffOrder(left, right); canonicalLeft != left {
+			u := url.URL{Path: imageurl.DiffPath(left, right), RawQuery: r.URL.RawQuery}
+			http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+			return
+		}
+		if notModified(w, r, imgID) {
+			return
+		}
+		wh.serveImageDiff(ctx, w, left, right)
+	default:
+		noCacheNotFound(w)
+	}
+}
+
+// notModified sets the ETag of a content-addressed image and returns true (after replying with
+// a 304) if the client already has that image.
+func notModified(w http.ResponseWriter, r *http.Request, imgID string) bool {
+	etag := `"` + imgID + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
 // serveImageWithDigest downloads the image from GCS and returns it. If there is an error, a 404
 // or 500 error is returned, as appropriate.
 func (wh *Handlers) serveImageWithDigest(ctx context.Context, w http.ResponseWriter, digest types.Digest) {
@@ -2516,29 +3368,527 @@ func (wh *Handlers) ChangelistSummaryHandler(w http.ResponseWriter, r *http.Requ
 		httputils.ReportError(w, err, "Could not get summary", http.StatusInternalServerError)
 		return
 	}
-	rv := convertChangelistSummaryResponseV1(sum)
+	rv := convertChangelistSummaryResponseV1(sum, wh.DiffBudget)
 	sendJSONResponse(w, rv)
 }
 
-// getCLSummary2 fetches, caches, and returns the summary for a given CL. If the result has already
-// been cached, it will return that cached value with a flag if the value is still up to date or
-// not. If the cached data is stale, it will spawn a goroutine to update the cached value.
-func (wh *Handlers) getCLSummary2(ctx context.Context, qCLID string) (search.NewAndUntriagedSummary, error) {
-	ts, err := wh.Search2API.ChangelistLastUpdated(ctx, qCLID)
-	if err != nil {
-		return search.NewAndUntriagedSummary{}, skerr.Wrap(err)
+// DiffBudgetCheckHandler checks a patchset of a CL against the instance's diff budget and returns
+// a machine-readable verdict, so CI systems can gate merges on it. By default, the most recent
+// patchset with data is checked; a specific one can be selected with the "patchset" query
+// parameter. If the instance has no diff budget configured, every patchset passes.
+func (wh *Handlers) DiffBudgetCheckHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_DiffBudgetCheckHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if err := wh.cheapLimitForGerritPlugin(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
 	}
-	if ts.IsZero() { // A Zero time means we have no data for this CL.
-		return search.NewAndUntriagedSummary{}, nil
+	clID := chi.URLParam(r, "id")
+	if clID == "" {
+		http.Error(w, "Must specify 'id' of Changelist.", http.StatusBadRequest)
+		return
+	}
+	crs := chi.URLParam(r, "system")
+	if crs == "" {
+		http.Error(w, "Must specify 'system' of Changelist.", http.StatusBadRequest)
+		return
+	}
+	system, ok := wh.getCodeReviewSystem(crs)
+	if !ok {
+		http.Error(w, "Invalid Code Review System", http.StatusBadRequest)
+		return
 	}
 
-	cached, ok := wh.clSummaryCache.Get(qCLID)
-	if ok {
-		sum, ok := cached.(search.NewAndUntriagedSummary)
-		if ok {
-			if ts.Before(sum.LastUpdated) || sum.LastUpdated.Equal(ts) {
-				sum.Outdated = false
-				return sum, nil
+	sum, err := wh.getCLSummary2(ctx, sql.Qualify(system.ID, clID))
+	if err != nil {
+		httputils.ReportError(w, err, "Could not get summary", http.StatusInternalServerError)
+		return
+	}
+	psID := r.FormValue("patchset")
+	var ps *search.PatchsetNewAndUntriagedSummary
+	for i, p := range sum.PatchsetSummaries {
+		if psID != "" {
+			if p.PatchsetID == psID {
+				ps = &sum.PatchsetSummaries[i]
+				break
+			}
+		} else if ps == nil || p.PatchsetOrder > ps.PatchsetOrder {
+			ps = &sum.PatchsetSummaries[i]
+		}
+	}
+	if ps == nil {
+		http.Error(w, "No data found for the given Changelist and Patchset.", http.StatusNotFound)
+		return
+	}
+
+	verdict := evaluateDiffBudget(wh.DiffBudget, *ps)
+	sendJSONResponse(w, frontend.DiffBudgetCheckResponse{
+		ChangelistID:  clID,
+		PatchsetID:    ps.PatchsetID,
+		PatchsetOrder: ps.PatchsetOrder,
+		Enforced:      wh.DiffBudget != nil,
+		Pass:          verdict.Pass,
+		Violations:    verdict.Violations,
+		Outdated:      sum.Outdated,
+	})
+}
+
+// evaluateDiffBudget checks the given patchset summary against the limits in the provided budget.
+// A nil budget, or a budget without any limits set, always passes.
+func evaluateDiffBudget(budget *config.DiffBudgetConfig, ps search.PatchsetNewAndUntriagedSummary) frontend.DiffBudgetVerdict {
+	rv := frontend.DiffBudgetVerdict{
+		Pass:       true,
+		Violations: []frontend.DiffBudgetViolation{},
+	}
+	if budget == nil {
+		return rv
+	}
+	check := func(limit string, max *int, actual int) {
+		if max != nil && actual > *max {
+			rv.Pass = false
+			rv.Violations = append(rv.Violations, frontend.DiffBudgetViolation{
+				Limit:  limit,
+				Max:    *max,
+				Actual: actual,
+			})
+		}
+	}
+	check("max_new_untriaged_digests", budget.MaxNewUntriagedDigests, ps.NewUntriagedImages)
+	check("max_changed_tests", budget.MaxChangedTests, ps.ChangedTests)
+	return rv
+}
+
+// CompareChangelistsHandler compares the digests drawn by two patchsets, either of the same CL or
+// of two different CLs, and returns the tests which differ along with the diff metrics, so authors
+// can verify that a refactoring patchset draws exactly the same images as its predecessor. The
+// patchsets are selected with the "left_cl", "left_ps", "right_cl" and "right_ps" query
+// parameters. "right_cl" defaults to "left_cl" and an omitted patchset defaults to the most recent
+// patchset of its CL.
+func (wh *Handlers) CompareChangelistsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_CompareChangelistsHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+	crs := r.FormValue("crs")
+	system, ok := wh.getCodeReviewSystem(crs)
+	if !ok {
+		http.Error(w, "Invalid Code Review System", http.StatusBadRequest)
+		return
+	}
+	left := frontend.ComparedPatchset{
+		ChangelistID: r.FormValue("left_cl"),
+		PatchsetID:   r.FormValue("left_ps"),
+	}
+	if left.ChangelistID == "" {
+		http.Error(w, "Must specify 'left_cl'.", http.StatusBadRequest)
+		return
+	}
+	right := frontend.ComparedPatchset{
+		ChangelistID: r.FormValue("right_cl"),
+		PatchsetID:   r.FormValue("right_ps"),
+	}
+	if right.ChangelistID == "" {
+		right.ChangelistID = left.ChangelistID
+	}
+
+	for _, side := range []*frontend.ComparedPatchset{&left, &right} {
+		if side.PatchsetID != "" {
+			continue
+		}
+		qPSID, _, err := wh.getLatestPatchset(ctx, system.ID, side.ChangelistID)
+		if err != nil {
+			if skerr.Unwrap(err) == pgx.ErrNoRows {
+				http.Error(w, fmt.Sprintf("Changelist %q has no patchsets.", side.ChangelistID), http.StatusNotFound)
+				return
+			}
+			httputils.ReportError(w, err, "Could not find the latest patchset", http.StatusInternalServerError)
+			return
+		}
+		side.PatchsetID = sql.Unqualify(qPSID)
+	}
+	if left == right {
+		http.Error(w, "Must compare two different patchsets.", http.StatusBadRequest)
+		return
+	}
+
+	rv, err := wh.compareChangelists(ctx, system.ID, left, right)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not compare patchsets", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, rv)
+}
+
+// TestPriorityHandler ranks the tests by how often they drew new images on the most recently
+// updated CLs, so CI systems can run the tests most likely to catch a change first and fail fast.
+// Gold doesn't know which files a CL touches, so instead the ranking can be restricted to the
+// traces a CL is going to produce with any trace params, e.g. ?corpus=gm&os=Android. The "limit"
+// query parameter returns only that many of the highest ranked tests.
+func (wh *Handlers) TestPriorityHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_TestPriorityHandler")
+	defer span.End()
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	limit := 0
+	filter := paramtools.Params{}
+	for key, values := range r.URL.Query() {
+		if len(values) != 1 {
+			http.Error(w, "Only one value per param is supported.", http.StatusBadRequest)
+			return
+		}
+		switch key {
+		case "limit":
+			l, err := strconv.Atoi(values[0])
+			if err != nil || l < 1 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = l
+		case "corpus":
+			filter[types.CorpusField] = values[0]
+		default:
+			filter[key] = values[0]
+		}
+	}
+
+	rv, err := wh.getTestPriorities(ctx, filter, testPriorityChangelists)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not compute test priorities", http.StatusInternalServerError)
+		return
+	}
+	if limit > 0 && len(rv.Tests) > limit {
+		rv.Tests = rv.Tests[:limit]
+	}
+	sendJSONResponse(w, rv)
+}
+
+// getTestPriorities returns the ranked tests of the traces which contain all the given params,
+// based on the data of the numCLs most recently updated CLs.
+func (wh *Handlers) getTestPriorities(ctx context.Context, filter paramtools.Params, numCLs int) (frontend.TestPriorityResponse, error) {
+	ctx, span := trace.StartSpan(ctx, "getTestPriorities")
+	defer span.End()
+
+	const statement = `WITH
+RecentChangelists AS (
+	SELECT changelist_id FROM Changelists
+	ORDER BY last_ingested_data DESC
+	LIMIT $1
+),
+ChangelistValues AS (
+	SELECT DISTINCT branch_name, SecondaryBranchValues.grouping_id, digest
+	FROM SecondaryBranchValues
+	JOIN RecentChangelists ON SecondaryBranchValues.branch_name = RecentChangelists.changelist_id
+	JOIN Traces ON SecondaryBranchValues.secondary_branch_trace_id = Traces.trace_id
+	WHERE Traces.keys @> $2
+),
+ChangelistsPerGrouping AS (
+	SELECT ChangelistValues.grouping_id, count(DISTINCT branch_name) AS num_run,
+		count(DISTINCT branch_name) FILTER (WHERE Expectations.label IS NULL OR Expectations.label != 'p') AS num_changed
+	FROM ChangelistValues
+	LEFT JOIN Expectations ON ChangelistValues.grouping_id = Expectations.grouping_id
+		AND ChangelistValues.digest = Expectations.digest
+	GROUP BY ChangelistValues.grouping_id
+)
+SELECT Groupings.keys, num_run, num_changed FROM ChangelistsPerGrouping
+JOIN Groupings ON ChangelistsPerGrouping.grouping_id = Groupings.grouping_id`
+	rows, err := wh.DB.Query(ctx, statement, numCLs, filter)
+	if err != nil {
+		return frontend.TestPriorityResponse{}, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var tests []frontend.TestPriority
+	for rows.Next() {
+		var tp frontend.TestPriority
+		if err := rows.Scan(&tp.Grouping, &tp.ChangelistsRun, &tp.ChangelistsChanged); err != nil {
+			return frontend.TestPriorityResponse{}, skerr.Wrap(err)
+		}
+		tests = append(tests, tp)
+	}
+	rows.Close()
+	rankTestPriorities(tests)
+
+	const countStatement = `SELECT count(*) FROM (
+	SELECT changelist_id FROM Changelists
+	ORDER BY last_ingested_data DESC
+	LIMIT $1
+) AS RecentChangelists`
+	var numChangelists int
+	if err := wh.DB.QueryRow(ctx, countStatement, numCLs).Scan(&numChangelists); err != nil {
+		return frontend.TestPriorityResponse{}, skerr.Wrap(err)
+	}
+	span.AddAttributes(trace.Int64Attribute("num_tests", int64(len(tests))))
+	return frontend.TestPriorityResponse{
+		NumChangelists: numChangelists,
+		Tests:          tests,
+	}, nil
+}
+
+// rankTestPriorities sets the score of the given tests and sorts them by it, highest first. The
+// score is the fraction of the CLs on which a test changed, counting one extra CL on which it
+// didn't, so that a test which changed on 40 of 50 CLs ranks above one which changed on its only
+// CL. Ties are broken by the number of CLs on which the tests changed, then by grouping.
+func rankTestPriorities(tests []frontend.TestPriority) {
+	for i := range tests {
+		tests[i].Score = float64(tests[i].ChangelistsChanged) / float64(tests[i].ChangelistsRun+1)
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Score != tests[j].Score {
+			return tests[i].Score > tests[j].Score
+		}
+		if tests[i].ChangelistsChanged != tests[j].ChangelistsChanged {
+			return tests[i].ChangelistsChanged > tests[j].ChangelistsChanged
+		}
+		gi, _ := sql.SerializeMap(tests[i].Grouping)
+		gj, _ := sql.SerializeMap(tests[j].Grouping)
+		return gi < gj
+	})
+}
+
+// patchsetTrace is the data drawn by a trace on a single patchset.
+type patchsetTrace struct {
+	keys     paramtools.Params
+	grouping paramtools.Params
+	// digests are sorted and contain no duplicates.
+	digests []types.Digest
+}
+
+// compareChangelists returns the comparison of the data drawn by the given patchsets.
+func (wh *Handlers) compareChangelists(ctx context.Context, crs string, left, right frontend.ComparedPatchset) (frontend.ChangelistComparisonResponse, error) {
+	ctx, span := trace.StartSpan(ctx, "compareChangelists")
+	defer span.End()
+
+	leftTraces, err := wh.getPatchsetTraces(ctx, sql.Qualify(crs, left.ChangelistID), sql.Qualify(crs, left.PatchsetID))
+	if err != nil {
+		return frontend.ChangelistComparisonResponse{}, skerr.Wrapf(err, "getting data for %v", left)
+	}
+	rightTraces, err := wh.getPatchsetTraces(ctx, sql.Qualify(crs, right.ChangelistID), sql.Qualify(crs, right.PatchsetID))
+	if err != nil {
+		return frontend.ChangelistComparisonResponse{}, skerr.Wrapf(err, "getting data for %v", right)
+	}
+	tests, numTraces := compareTraces(leftTraces, rightTraces)
+	if err := wh.addDiffMetrics(ctx, tests); err != nil {
+		return frontend.ChangelistComparisonResponse{}, skerr.Wrap(err)
+	}
+	span.AddAttributes(trace.Int64Attribute("num_traces", int64(numTraces)),
+		trace.Int64Attribute("num_differing_tests", int64(len(tests))))
+	return frontend.ChangelistComparisonResponse{
+		Left:      left,
+		Right:     right,
+		Identical: len(tests) == 0,
+		NumTraces: numTraces,
+		Tests:     tests,
+	}, nil
+}
+
+// getPatchsetTraces returns the data drawn on the given patchset, keyed by trace id.
+func (wh *Handlers) getPatchsetTraces(ctx context.Context, qCLID, qPSID string) (map[tiling.TraceIDV2]*patchsetTrace, error) {
+	ctx, span := trace.StartSpan(ctx, "getPatchsetTraces")
+	defer span.End()
+	const statement = `SELECT encode(SecondaryBranchValues.secondary_branch_trace_id, 'hex'), Traces.keys,
+    Groupings.keys, encode(SecondaryBranchValues.digest, 'hex')
+FROM SecondaryBranchValues
+JOIN Traces ON SecondaryBranchValues.secondary_branch_trace_id = Traces.trace_id
+JOIN Groupings ON SecondaryBranchValues.grouping_id = Groupings.grouping_id
+WHERE branch_name = $1 AND version_name = $2`
+	rows, err := wh.DB.Query(ctx, statement, qCLID, qPSID)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	rv := map[tiling.TraceIDV2]*patchsetTrace{}
+	for rows.Next() {
+		var traceID tiling.TraceIDV2
+		var keys, grouping paramtools.Params
+		var digest types.Digest
+		if err := rows.Scan(&traceID, &keys, &grouping, &digest); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		pt, ok := rv[traceID]
+		if !ok {
+			pt = &patchsetTrace{keys: keys, grouping: grouping}
+			rv[traceID] = pt
+		}
+		pt.digests = append(pt.digests, digest)
+	}
+	for _, pt := range rv {
+		sort.Slice(pt.digests, func(i, j int) bool {
+			return pt.digests[i] < pt.digests[j]
+		})
+		pt.digests = dedupDigests(pt.digests)
+	}
+	return rv, nil
+}
+
+// dedupDigests removes consecutive duplicates from the given sorted slice.
+func dedupDigests(digests []types.Digest) []types.Digest {
+	rv := digests[:0]
+	for i, d := range digests {
+		if i == 0 || d != digests[i-1] {
+			rv = append(rv, d)
+		}
+	}
+	return rv
+}
+
+// compareTraces returns the tests with traces which drew different digests on the left and right
+// side, along with the number of traces with data on either side.
+func compareTraces(left, right map[tiling.TraceIDV2]*patchsetTrace) ([]frontend.TestComparison, int) {
+	byGrouping := map[string]*frontend.TestComparison{}
+	compare := func(traceID tiling.TraceIDV2, l, r *patchsetTrace) {
+		pt := l
+		if pt == nil {
+			pt = r
+		}
+		tc := frontend.TraceComparison{
+			TraceID:      traceID,
+			Params:       pt.keys,
+			LeftDigests:  []types.Digest{},
+			RightDigests: []types.Digest{},
+		}
+		if l != nil {
+			tc.LeftDigests = l.digests
+		}
+		if r != nil {
+			tc.RightDigests = r.digests
+		}
+		if digestsEqual(tc.LeftDigests, tc.RightDigests) {
+			return
+		}
+		key, _ := sql.SerializeMap(pt.grouping)
+		test, ok := byGrouping[key]
+		if !ok {
+			test = &frontend.TestComparison{Grouping: pt.grouping}
+			byGrouping[key] = test
+		}
+		test.Traces = append(test.Traces, tc)
+	}
+	numTraces := len(left)
+	for traceID, l := range left {
+		compare(traceID, l, right[traceID])
+	}
+	for traceID, r := range right {
+		if _, ok := left[traceID]; !ok {
+			numTraces++
+			compare(traceID, nil, r)
+		}
+	}
+
+	keys := make([]string, 0, len(byGrouping))
+	for key := range byGrouping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rv := make([]frontend.TestComparison, 0, len(keys))
+	for _, key := range keys {
+		test := byGrouping[key]
+		sort.Slice(test.Traces, func(i, j int) bool {
+			return test.Traces[i].TraceID < test.Traces[j].TraceID
+		})
+		rv = append(rv, *test)
+	}
+	return rv, numTraces
+}
+
+// digestsEqual returns true if both slices contain the same digests in the same order.
+func digestsEqual(a, b []types.Digest) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addDiffMetrics fills in the diff metrics of the traces which drew a single digest on each side,
+// if those metrics have been computed.
+func (wh *Handlers) addDiffMetrics(ctx context.Context, tests []frontend.TestComparison) error {
+	ctx, span := trace.StartSpan(ctx, "addDiffMetrics")
+	defer span.End()
+
+	type digestPair struct {
+		left, right types.Digest
+	}
+	var leftDigests, rightDigests []schema.DigestBytes
+	pairs := map[digestPair][]*frontend.TraceComparison{}
+	for i := range tests {
+		for j := range tests[i].Traces {
+			tc := &tests[i].Traces[j]
+			if len(tc.LeftDigests) != 1 || len(tc.RightDigests) != 1 {
+				continue
+			}
+			pair := digestPair{left: tc.LeftDigests[0], right: tc.RightDigests[0]}
+			if _, ok := pairs[pair]; !ok {
+				l, err := sql.DigestToBytes(pair.left)
+				if err != nil {
+					return skerr.Wrap(err)
+				}
+				r, err := sql.DigestToBytes(pair.right)
+				if err != nil {
+					return skerr.Wrap(err)
+				}
+				leftDigests = append(leftDigests, l)
+				rightDigests = append(rightDigests, r)
+			}
+			pairs[pair] = append(pairs[pair], tc)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	const statement = `SELECT encode(left_digest, 'hex'), encode(right_digest, 'hex'), combined_metric,
+    num_pixels_diff, percent_pixels_diff, max_rgba_diffs, dimensions_differ
+FROM DiffMetrics AS OF SYSTEM TIME '-0.1s'
+WHERE left_digest = ANY($1) AND right_digest = ANY($2)`
+	rows, err := wh.DB.Query(ctx, statement, leftDigests, rightDigests)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pair digestPair
+		var m frontend.DiffMetrics
+		if err := rows.Scan(&pair.left, &pair.right, &m.CombinedMetric, &m.NumDiffPixels,
+			&m.PixelDiffPercent, &m.MaxRGBADiffs, &m.DimDiffer); err != nil {
+			return skerr.Wrap(err)
+		}
+		// The query returns every combination of the left and right digests, so we only keep the
+		// pairs drawn by the same trace.
+		for _, tc := range pairs[pair] {
+			metrics := m
+			tc.Diff = &metrics
+		}
+	}
+	return nil
+}
+
+// getCLSummary2 fetches, caches, and returns the summary for a given CL. If the result has already
+// been cached, it will return that cached value with a flag if the value is still up to date or
+// not. If the cached data is stale, it will spawn a goroutine to update the cached value.
+func (wh *Handlers) getCLSummary2(ctx context.Context, qCLID string) (search.NewAndUntriagedSummary, error) {
+	ts, err := wh.Search2API.ChangelistLastUpdated(ctx, qCLID)
+	if err != nil {
+		return search.NewAndUntriagedSummary{}, skerr.Wrap(err)
+	}
+	if ts.IsZero() { // A Zero time means we have no data for this CL.
+		return search.NewAndUntriagedSummary{}, nil
+	}
+
+	cached, ok := wh.clSummaryCache.Get(qCLID)
+	if ok {
+		sum, ok := cached.(search.NewAndUntriagedSummary)
+		if ok {
+			if ts.Before(sum.LastUpdated) || sum.LastUpdated.Equal(ts) {
+				sum.Outdated = false
+				return sum, nil
 			}
 			// Result is stale. Start a goroutine to fetch it again.
 			done := make(chan struct{})
@@ -2588,17 +3938,23 @@ func (wh *Handlers) getCLSummary2(ctx context.Context, qCLID string) (search.New
 }
 
 // convertChangelistSummaryResponseV1 converts the search2 version of a Changelist summary into
-// the version expected by the frontend.
-func convertChangelistSummaryResponseV1(summary search.NewAndUntriagedSummary) frontend.ChangelistSummaryResponseV1 {
+// the version expected by the frontend. If budget is not nil, each patchset is checked against it.
+func convertChangelistSummaryResponseV1(summary search.NewAndUntriagedSummary, budget *config.DiffBudgetConfig) frontend.ChangelistSummaryResponseV1 {
 	xps := make([]frontend.PatchsetNewAndUntriagedSummaryV1, 0, len(summary.PatchsetSummaries))
 	for _, ps := range summary.PatchsetSummaries {
-		xps = append(xps, frontend.PatchsetNewAndUntriagedSummaryV1{
+		xp := frontend.PatchsetNewAndUntriagedSummaryV1{
 			NewImages:            ps.NewImages,
+			ChangedTests:         ps.ChangedTests,
 			NewUntriagedImages:   ps.NewUntriagedImages,
 			TotalUntriagedImages: ps.TotalUntriagedImages,
 			PatchsetID:           ps.PatchsetID,
 			PatchsetOrder:        ps.PatchsetOrder,
-		})
+		}
+		if budget != nil {
+			verdict := evaluateDiffBudget(budget, ps)
+			xp.DiffBudget = &verdict
+		}
+		xps = append(xps, xp)
 	}
 	// It is convenient for the UI to have these sorted with the latest patchset first.
 	sort.Slice(xps, func(i, j int) bool {
@@ -2692,6 +4048,7 @@ func (wh *Handlers) startStatusCacheProcess(ctx context.Context) {
 			sklog.Errorf("Could not compute GUI Status: %s", err)
 			return
 		}
+		updateStatusMetrics(gs)
 
 		wh.statusCacheMutex.Lock()
 		defer wh.statusCacheMutex.Unlock()
@@ -2699,6 +4056,17 @@ func (wh *Handlers) startStatusCacheProcess(ctx context.Context) {
 	})
 }
 
+// updateStatusMetrics reports the triage status of each corpus, so that alerts can be set up for
+// corpora which are not triaged quickly enough.
+func updateStatusMetrics(gs frontend.GUIStatus) {
+	for _, cs := range gs.CorpStatus {
+		tags := map[string]string{"corpus": cs.Name}
+		metrics2.GetInt64Metric("gold_status_untriaged_digests", tags).Update(int64(cs.UntriagedCount))
+		metrics2.GetInt64Metric("gold_status_oldest_untriaged_age_s", tags).Update(cs.OldestUntriagedAgeSeconds)
+		metrics2.GetInt64Metric("gold_status_triaged_digests_24h", tags).Update(int64(cs.TriagedLast24h))
+	}
+}
+
 // StartKnownHashesCacheProcess will fetch the known hashes on a timer and save it to the cache.
 func (wh *Handlers) StartKnownHashesCacheProcess(ctx context.Context) {
 	go util.RepeatCtx(ctx, time.Minute, func(ctx context.Context) {
@@ -2826,6 +4194,460 @@ func (wh *Handlers) PositiveDigestsByGroupingIDHandler(w http.ResponseWriter, r
 	sendJSONResponse(w, resp)
 }
 
+// SimilarDigestsHandler returns the digests seen in the current window which look similar to
+// the given digest, across all groupings. Similarity is determined by comparing the perceptual
+// hashes computed by the diffcalculator, so digests which have not had any diffs computed yet
+// will not be found. The hashes in the window are cached for similarDigestsCandidatesTTL, so
+// new digests and triage changes may take that long to show up. It takes the following query
+// parameters:
+//   - digest: The digest to compare against. Required.
+//   - max_distance: How many bits (out of 64) of the perceptual hashes may differ. Optional.
+func (wh *Handlers) SimilarDigestsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_SimilarDigestsHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if err := wh.limitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	digest := types.Digest(r.FormValue("digest"))
+	if !validation.IsValidDigest(string(digest)) {
+		http.Error(w, "Invalid digest.", http.StatusBadRequest)
+		return
+	}
+	maxDistance := defaultSimilarDigestsDistance
+	if v := r.FormValue("max_distance"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 || d > maxSimilarDigestsDistance {
+			http.Error(w, fmt.Sprintf("max_distance must be an integer in [0, %d]", maxSimilarDigestsDistance), http.StatusBadRequest)
+			return
+		}
+		maxDistance = d
+	}
+
+	hash, err := wh.getPerceptualHash(ctx, digest)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			http.Error(w, "No perceptual hash has been computed for this digest yet.", http.StatusNotFound)
+			return
+		}
+		httputils.ReportError(w, err, "Could not look up digest.", http.StatusInternalServerError)
+		return
+	}
+
+	beginTile, _, err := wh.getTilesInWindow(ctx)
+	if err != nil {
+		httputils.ReportError(w, err, "Error while finding commits with data", http.StatusInternalServerError)
+		return
+	}
+
+	results, err := wh.getSimilarDigests(ctx, beginTile, hash, maxDistance)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not find similar digests.", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, frontend.SimilarDigestsResponse{
+		Digest:  digest,
+		Results: results,
+	})
+}
+
+// IngestHandler accepts a Gold results JSON file (see jsonio.GoldResults) from CI systems which
+// cannot write to the ingestion buckets themselves. The results are validated the same way
+// ingestion does, so malformed uploads are rejected immediately, and then written to the
+// ingestion source for the primary or secondary branch (depending on whether they are for a
+// tryjob), from where they are ingested like any other results. The caller must be an editor.
+func (wh *Handlers) IngestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_IngestHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	user := wh.alogin.LoggedInAs(r)
+	if user == alogin.NotLoggedIn {
+		http.Error(w, "You must be logged in to upload results.", http.StatusUnauthorized)
+		return
+	}
+	if !wh.alogin.HasRole(r, roles.Editor) {
+		http.Error(w, "You must be logged in as an editor to upload results.", http.StatusUnauthorized)
+		return
+	}
+	if wh.PrimaryBranchResults == nil {
+		http.Error(w, "Uploading results is not enabled on this instance.", http.StatusNotFound)
+		return
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBodyBytes))
+	if err != nil {
+		httputils.ReportError(w, err, "Could not read results.", http.StatusBadRequest)
+		return
+	}
+	gr := jsonio.GoldResults{}
+	if err := json.Unmarshal(b, &gr); err != nil {
+		httputils.ReportError(w, err, "Results are not valid JSON.", http.StatusBadRequest)
+		return
+	}
+	if err := gr.Validate(); err != nil {
+		httputils.ReportError(w, err, "Invalid results.", http.StatusBadRequest)
+		return
+	}
+
+	src := wh.PrimaryBranchResults
+	if gr.ChangelistID != "" {
+		if wh.SecondaryBranchResults == nil {
+			http.Error(w, "This instance does not ingest tryjob results.", http.StatusBadRequest)
+			return
+		}
+		src = wh.SecondaryBranchResults
+	}
+	// Name the file after its contents only, so retried uploads overwrite the same file no matter
+	// when they are retried.
+	sum := md5.Sum(b)
+	name := path.Join(src.Prefix, ingestion.UploadedDir, hex.EncodeToString(sum[:])+".json")
+	if err := wh.GCSClient.WriteResultsFile(ctx, path.Join(src.Bucket, name), b); err != nil {
+		httputils.ReportError(w, err, "Could not store results for ingestion.", http.StatusInternalServerError)
+		return
+	}
+	sklog.Infof("%s uploaded %d results to gs://%s/%s", user, len(gr.Results), src.Bucket, name)
+	sendJSONResponse(w, frontend.IngestResponse{File: "gs://" + src.Bucket + "/" + name})
+}
+
+// ImageGCReportHandler reports which images the image garbage collector would delete if it ran
+// now, without deleting anything. Because it lists every image in the bucket, it is only available
+// to editors. It takes the following query parameters:
+//   - limit: The maximum number of images listed in the response. Optional.
+func (wh *Handlers) ImageGCReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_ImageGCReportHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if !wh.alogin.HasRole(r, roles.Editor) {
+		http.Error(w, "You must be logged in as an editor to view the image GC report.", http.StatusUnauthorized)
+		return
+	}
+	if wh.ImageGC == nil {
+		http.Error(w, "Image garbage collection is not configured on this instance.", http.StatusNotFound)
+		return
+	}
+
+	limit := defaultImageGCReportLimit
+	if v := r.FormValue("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxImageGCReportLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer in [1, %d]", maxImageGCReportLimit), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	report, err := imagegc.New(wh.DB, wh.GCSClient, *wh.ImageGC).Report(ctx, limit)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not compute image GC report.", http.StatusInternalServerError)
+		return
+	}
+	resp := frontend.ImageGCReportResponse{
+		DryRun:             wh.ImageGC.DryRun,
+		ArchiveBucket:      wh.ImageGC.ArchiveBucket,
+		ImagesSeen:         report.ImagesSeen,
+		ReferencedDigests:  report.ReferencedDigests,
+		UnreferencedImages: report.ImagesDeleted,
+		UnreferencedBytes:  report.BytesReclaimed,
+		Images:             make([]frontend.UnreferencedImage, 0, len(report.Unreferenced)),
+	}
+	for _, info := range report.Unreferenced {
+		resp.Images = append(resp.Images, frontend.UnreferencedImage{
+			Digest:  info.Digest,
+			Size:    info.Size,
+			Created: info.Created,
+		})
+	}
+	sendJSONResponse(w, resp)
+}
+
+// FlakyTestsHandler returns the tests whose traces produced the most distinct digests over the
+// current window, grouped by corpus. It takes the following query parameters:
+//   - corpus: Only return tests from this corpus. Optional.
+//   - limit: The maximum number of tests returned per corpus. Optional.
+func (wh *Handlers) FlakyTestsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_FlakyTestsHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+
+	corpus := r.FormValue("corpus")
+	limit := defaultFlakyTestsLimit
+	if v := r.FormValue("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxFlakyTestsLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer in [1, %d]", maxFlakyTestsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	resp, err := wh.Search2API.GetFlakyTests(ctx, corpus, limit)
+	if err != nil {
+		httputils.ReportError(w, err, "Could not compute flaky tests.", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, resp)
+}
+
+// CommentsHandler returns the comments about either the digests given by the "digest" query
+// parameter (which may be repeated) or the changelist given by the "crs" and "changelist_id"
+// query parameters.
+func (wh *Handlers) CommentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_CommentsHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	if err := wh.cheapLimitForAnonUsers(r); err != nil {
+		httputils.ReportError(w, err, "Try again later", http.StatusInternalServerError)
+		return
+	}
+	if wh.CommentStore == nil {
+		http.Error(w, "Comments are not enabled on this instance.", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	var comments []comment.Comment
+	var err error
+	if clID := q.Get("changelist_id"); clID != "" {
+		crs := q.Get("crs")
+		if _, ok := wh.getCodeReviewSystem(crs); !ok {
+			http.Error(w, "Invalid Code Review System; did you include crs?", http.StatusBadRequest)
+			return
+		}
+		comments, err = wh.CommentStore.ListForChangelist(ctx, crs, clID)
+	} else {
+		var digests []types.Digest
+		for _, d := range q["digest"] {
+			if !validation.IsValidDigest(d) {
+				http.Error(w, "Invalid digest", http.StatusBadRequest)
+				return
+			}
+			digests = append(digests, types.Digest(d))
+		}
+		if len(digests) == 0 {
+			http.Error(w, "Must specify digest or changelist_id", http.StatusBadRequest)
+			return
+		}
+		comments, err = wh.CommentStore.ListForDigests(ctx, digests)
+	}
+	if err != nil {
+		httputils.ReportError(w, err, "Could not retrieve comments.", http.StatusInternalServerError)
+		return
+	}
+	sendJSONResponse(w, frontend.CommentsResponse{Comments: convertComments(comments)})
+}
+
+// AddCommentHandler stores the comment given by the POST'd frontend.AddCommentRequest. The caller
+// must be an editor.
+func (wh *Handlers) AddCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_AddCommentHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	user := wh.alogin.LoggedInAs(r)
+	if user == alogin.NotLoggedIn {
+		http.Error(w, "You must be logged in to add a comment.", http.StatusUnauthorized)
+		return
+	}
+	if !wh.alogin.HasRole(r, roles.Editor) {
+		http.Error(w, "You must be logged in as an editor to add a comment.", http.StatusUnauthorized)
+		return
+	}
+	if wh.CommentStore == nil {
+		http.Error(w, "Comments are not enabled on this instance.", http.StatusNotFound)
+		return
+	}
+
+	var req frontend.AddCommentRequest
+	if err := parseJSON(r, &req); err != nil {
+		httputils.ReportError(w, err, "Failed to parse JSON request.", http.StatusBadRequest)
+		return
+	}
+	if req.ChangelistID != "" {
+		if _, ok := wh.getCodeReviewSystem(req.CRS); !ok {
+			http.Error(w, "Invalid Code Review System", http.StatusBadRequest)
+			return
+		}
+	}
+	c := comment.Comment{
+		Digest:       req.Digest,
+		CRS:          req.CRS,
+		ChangelistID: req.ChangelistID,
+		CreatedBy:    user.String(),
+		Text:         req.Text,
+	}
+	if err := c.Validate(); err != nil {
+		httputils.ReportError(w, err, "Invalid comment.", http.StatusBadRequest)
+		return
+	}
+	id, err := wh.CommentStore.Create(ctx, c)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to store comment.", http.StatusInternalServerError)
+		return
+	}
+	sklog.Infof("%s added comment %s", user, id)
+	sendJSONResponse(w, map[string]string{"id": id})
+}
+
+// DeleteCommentHandler deletes the comment with the given id. Only the author of a comment may
+// delete it.
+func (wh *Handlers) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := trace.StartSpan(r.Context(), "web_DeleteCommentHandler", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	user := wh.alogin.LoggedInAs(r)
+	if user == alogin.NotLoggedIn {
+		http.Error(w, "You must be logged in to delete a comment.", http.StatusUnauthorized)
+		return
+	}
+	if wh.CommentStore == nil {
+		http.Error(w, "Comments are not enabled on this instance.", http.StatusNotFound)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	c, err := wh.CommentStore.Get(ctx, id)
+	if err == comment.ErrNotFound {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve comment.", http.StatusInternalServerError)
+		return
+	}
+	if c.CreatedBy != user.String() {
+		http.Error(w, "Only the author of a comment can delete it.", http.StatusForbidden)
+		return
+	}
+	if err := wh.CommentStore.Delete(ctx, id); err != nil {
+		httputils.ReportError(w, err, "Failed to delete comment.", http.StatusInternalServerError)
+		return
+	}
+	sklog.Infof("%s deleted comment %s", user, id)
+	sendJSONResponse(w, map[string]string{"deleted": "true"})
+}
+
+// convertComments converts comments from the store to the type returned by the RPC endpoints.
+func convertComments(comments []comment.Comment) []frontend.Comment {
+	rv := make([]frontend.Comment, 0, len(comments))
+	for _, c := range comments {
+		rv = append(rv, frontend.Comment{
+			ID:           c.ID,
+			Digest:       c.Digest,
+			CRS:          c.CRS,
+			ChangelistID: c.ChangelistID,
+			CreatedBy:    c.CreatedBy,
+			Created:      c.Created,
+			Text:         c.Text,
+		})
+	}
+	return rv
+}
+
+// getPerceptualHash returns the perceptual hash of the given digest. It returns pgx.ErrNoRows if
+// the hash has not been computed.
+func (wh *Handlers) getPerceptualHash(ctx context.Context, digest types.Digest) (uint64, error) {
+	ctx, span := trace.StartSpan(ctx, "getPerceptualHash")
+	defer span.End()
+	db, err := sql.DigestToBytes(digest)
+	if err != nil {
+		return 0, skerr.Wrap(err)
+	}
+	row := wh.DB.QueryRow(ctx, `SELECT hash FROM PerceptualHashes WHERE digest = $1`, db)
+	var hash int64
+	if err := row.Scan(&hash); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, err
+		}
+		return 0, skerr.Wrap(err)
+	}
+	return uint64(hash), nil
+}
+
+// similarDigestCandidate is a digest in the window, along with its perceptual hash, that may be
+// returned by SimilarDigestsHandler.
+type similarDigestCandidate struct {
+	digest   types.Digest
+	hash     uint64
+	grouping paramtools.Params
+	label    schema.ExpectationLabel
+}
+
+// getSimilarDigests returns all digests seen at or after the given tile whose perceptual hash is
+// at most maxDistance bits away from the provided hash. The results are sorted by distance, then
+// by digest and grouping, and truncated to maxSimilarDigestsResults.
+func (wh *Handlers) getSimilarDigests(ctx context.Context, beginTile schema.TileID, hash uint64, maxDistance int) ([]frontend.SimilarDigest, error) {
+	ctx, span := trace.StartSpan(ctx, "getSimilarDigests")
+	defer span.End()
+	candidates, err := wh.getSimilarDigestCandidates(ctx, beginTile)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	var rv []frontend.SimilarDigest
+	for _, c := range candidates {
+		distance := diff.PerceptualHashDistance(hash, c.hash)
+		if distance > maxDistance {
+			continue
+		}
+		rv = append(rv, frontend.SimilarDigest{
+			Digest:   c.digest,
+			Distance: distance,
+			Grouping: c.grouping,
+			Status:   c.label.ToExpectation(),
+		})
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].Distance != rv[j].Distance {
+			return rv[i].Distance < rv[j].Distance
+		}
+		if rv[i].Digest != rv[j].Digest {
+			return rv[i].Digest < rv[j].Digest
+		}
+		return rv[i].Grouping[types.PrimaryKeyField] < rv[j].Grouping[types.PrimaryKeyField]
+	})
+	if len(rv) > maxSimilarDigestsResults {
+		rv = rv[:maxSimilarDigestsResults]
+	}
+	return rv, nil
+}
+
+// getSimilarDigestCandidates returns all digests seen at or after the given tile which have a
+// perceptual hash. The results are cached for similarDigestsCandidatesTTL.
+func (wh *Handlers) getSimilarDigestCandidates(ctx context.Context, beginTile schema.TileID) ([]similarDigestCandidate, error) {
+	ctx, span := trace.StartSpan(ctx, "getSimilarDigestCandidates")
+	defer span.End()
+	cacheKey := strconv.Itoa(int(beginTile))
+	if val, ok := wh.similarDigestsCache.Get(cacheKey); ok {
+		return val.([]similarDigestCandidate), nil
+	}
+	const statement = `WITH
+DigestsInWindow AS (
+	SELECT DISTINCT digest, grouping_id FROM TiledTraceDigests
+	AS OF SYSTEM TIME '-0.1s'
+	WHERE tile_id >= $1
+)
+SELECT encode(DigestsInWindow.digest, 'hex'), PerceptualHashes.hash, Groupings.keys,
+	COALESCE(Expectations.label, 'u')
+FROM DigestsInWindow
+JOIN PerceptualHashes ON PerceptualHashes.digest = DigestsInWindow.digest
+JOIN Groupings ON Groupings.grouping_id = DigestsInWindow.grouping_id
+LEFT JOIN Expectations ON Expectations.grouping_id = DigestsInWindow.grouping_id AND
+	Expectations.digest = DigestsInWindow.digest
+`
+	rows, err := wh.DB.Query(ctx, statement, beginTile)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	var rv []similarDigestCandidate
+	for rows.Next() {
+		var c similarDigestCandidate
+		var hash int64
+		if err := rows.Scan(&c.digest, &hash, &c.grouping, &c.label); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		c.hash = uint64(hash)
+		rv = append(rv, c)
+	}
+	wh.similarDigestsCache.Set(cacheKey, rv, ttlcache.DefaultExpiration)
+	return rv, nil
+}
+
 // lookupGrouping returns the keys associated with the provided grouping id.
 func (wh *Handlers) lookupGrouping(ctx context.Context, id schema.GroupingID) (paramtools.Params, error) {
 	ctx, span := trace.StartSpan(ctx, "lookupGrouping")
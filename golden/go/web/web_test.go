@@ -36,6 +36,9 @@ import (
 	"go.goldmine.build/go/testutils"
 	"go.goldmine.build/golden/go/clstore"
 	mock_crs "go.goldmine.build/golden/go/code_review/mocks"
+	"go.goldmine.build/golden/go/comment"
+	mock_comment "go.goldmine.build/golden/go/comment/mocks"
+	"go.goldmine.build/golden/go/config"
 	"go.goldmine.build/golden/go/expectations"
 	"go.goldmine.build/golden/go/ignore"
 	mock_ignore "go.goldmine.build/golden/go/ignore/mocks"
@@ -44,14 +47,17 @@ import (
 	"go.goldmine.build/golden/go/mocks"
 	"go.goldmine.build/golden/go/search"
 	mock_search "go.goldmine.build/golden/go/search/mocks"
+	search_query "go.goldmine.build/golden/go/search/query"
 	"go.goldmine.build/golden/go/sql"
 	dks "go.goldmine.build/golden/go/sql/datakitchensink"
 	"go.goldmine.build/golden/go/sql/schema"
 	"go.goldmine.build/golden/go/sql/sqltest"
 	one_by_five "go.goldmine.build/golden/go/testutils/data_one_by_five"
 	"go.goldmine.build/golden/go/tiling"
+	"go.goldmine.build/golden/go/triageevents"
 	"go.goldmine.build/golden/go/types"
 	"go.goldmine.build/golden/go/web/frontend"
+	"go.goldmine.build/golden/go/web/imageurl"
 )
 
 const (
@@ -498,6 +504,199 @@ func TestBaselineHandlerV2_PrimaryBranch_Success(t *testing.T) {
 	assertJSONResponseWas(t, http.StatusOK, expectedJSONResponse, w)
 }
 
+func TestBaselineAtCommitHandler_CommitBeforeLatestTriages_ReturnsBaselineAtThatTime(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB: db,
+		},
+		baselineCache: ttlcache.New(time.Minute, 10*time.Minute),
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/expectations/commit/f4412901bfb130a8774c0c719450d1450845f471", nil)
+	r = setChiURLParams(r, map[string]string{"hash": "f4412901bfb130a8774c0c719450d1450845f471"})
+
+	// a08 and a09 were triaged after this commit landed, so they are missing.
+	expectedJSONResponse := `{
+  "primary": {
+    "circle": {
+      "00000000000000000000000000000000": "negative",
+      "c01c01c01c01c01c01c01c01c01c01c0": "positive",
+      "c02c02c02c02c02c02c02c02c02c02c0": "positive"
+    },
+    "square": {
+      "a01a01a01a01a01a01a01a01a01a01a0": "positive",
+      "a02a02a02a02a02a02a02a02a02a02a0": "positive",
+      "a03a03a03a03a03a03a03a03a03a03a0": "positive",
+      "a07a07a07a07a07a07a07a07a07a07a0": "positive"
+    },
+    "triangle": {
+      "b01b01b01b01b01b01b01b01b01b01b0": "positive",
+      "b02b02b02b02b02b02b02b02b02b02b0": "positive",
+      "b03b03b03b03b03b03b03b03b03b03b0": "negative",
+      "b04b04b04b04b04b04b04b04b04b04b0": "negative"
+    }
+  },
+  "git_hash": "f4412901bfb130a8774c0c719450d1450845f471"
+}`
+
+	wh.BaselineAtCommitHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, expectedJSONResponse, w)
+}
+
+func TestBaselineAtCommitHandler_UnknownCommit_NotFound(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB: db,
+		},
+		baselineCache: ttlcache.New(time.Minute, 10*time.Minute),
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/expectations/commit/ffffffffffffffffffffffffffffffffffffffff", nil)
+	r = setChiURLParams(r, map[string]string{"hash": "ffffffffffffffffffffffffffffffffffffffff"})
+
+	wh.BaselineAtCommitHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestBaselineAtCommitHandler_InvalidHash_BadRequest(t *testing.T) {
+	wh := Handlers{}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/expectations/commit/HEAD", nil)
+	r = setChiURLParams(r, map[string]string{"hash": "HEAD"})
+
+	wh.BaselineAtCommitHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestExportExpectations_FilterByCorpus_ReturnsSortedExpectations(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB: db,
+		},
+	}
+	exps, err := wh.exportExpectations(ctx, paramtools.Params{types.CorpusField: dks.RoundCorpus})
+	require.NoError(t, err)
+	circle := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	assert.Equal(t, []frontend.ExportedExpectation{
+		{Grouping: circle, Digest: dks.DigestBlank, Label: expectations.Negative},
+		{Grouping: circle, Digest: dks.DigestC01Pos, Label: expectations.Positive},
+		{Grouping: circle, Digest: dks.DigestC02Pos, Label: expectations.Positive},
+	}, exps)
+}
+
+func TestExpectationsExportHandler_MultipleValuesForParam_BadRequest(t *testing.T) {
+	wh := userIsNotLoggedIn(t)
+	wh.anonymousExpensiveQuota = rate.NewLimiter(rate.Inf, 1)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/expectations/export?name=a&name=b", nil)
+
+	wh.ExpectationsExportHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestImportedDeltas_ValuesMappedAndDuplicatesMerged(t *testing.T) {
+	req := frontend.ExpectationsImportRequest{
+		Expectations: []frontend.ExportedExpectation{
+			{
+				Grouping: paramtools.Params{types.CorpusField: "upstream", types.PrimaryKeyField: dks.CircleTest},
+				Digest:   dks.DigestC03Unt,
+				Label:    expectations.Negative,
+			},
+			{
+				Grouping: paramtools.Params{types.CorpusField: "upstream", types.PrimaryKeyField: dks.CircleTest},
+				Digest:   dks.DigestC03Unt,
+				Label:    expectations.Positive,
+			},
+		},
+		ValueMapping: map[string]map[string]string{
+			types.CorpusField: {"upstream": dks.RoundCorpus},
+		},
+	}
+	groupings, deltas, err := importedDeltas(req)
+	require.NoError(t, err)
+	assert.Equal(t, []schema.GroupingRow{{
+		GroupingID: dks.CircleGroupingID,
+		Keys:       paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest},
+	}}, groupings)
+	assert.Equal(t, []schema.ExpectationDeltaRow{{
+		GroupingID: dks.CircleGroupingID,
+		Digest:     d(dks.DigestC03Unt),
+		LabelAfter: schema.LabelPositive,
+	}}, deltas)
+}
+
+func TestImportedDeltas_InvalidExpectations_ReturnsError(t *testing.T) {
+	circle := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	test := func(name string, e frontend.ExportedExpectation) {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := importedDeltas(frontend.ExpectationsImportRequest{
+				Expectations: []frontend.ExportedExpectation{e},
+			})
+			assert.Error(t, err)
+		})
+	}
+	test("untriaged", frontend.ExportedExpectation{Grouping: circle, Digest: dks.DigestC03Unt, Label: expectations.Untriaged})
+	test("no corpus", frontend.ExportedExpectation{Grouping: paramtools.Params{types.PrimaryKeyField: dks.CircleTest}, Digest: dks.DigestC03Unt, Label: expectations.Positive})
+	test("invalid digest", frontend.ExportedExpectation{Grouping: circle, Digest: "not a digest", Label: expectations.Positive})
+}
+
+func TestExpectationsImportHandler_MappedGrouping_ExpectationsWrittenToPrimaryBranch(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := userIsEditor(t)
+	wh.DB = db
+	req := frontend.ExpectationsImportRequest{
+		Expectations: []frontend.ExportedExpectation{{
+			Grouping: paramtools.Params{types.CorpusField: "upstream", types.PrimaryKeyField: dks.CircleTest},
+			Digest:   dks.DigestC03Unt,
+			Label:    expectations.Positive,
+		}},
+		ValueMapping: map[string]map[string]string{
+			types.CorpusField: {"upstream": dks.RoundCorpus},
+		},
+	}
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/expectations/import", bytes.NewReader(body))
+
+	wh.ExpectationsImportHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{"imported":1}`, w)
+
+	exps := sqltest.GetAllRows(ctx, t, db, "Expectations", &schema.ExpectationRow{}).([]schema.ExpectationRow)
+	found := false
+	for _, e := range exps {
+		if bytes.Equal(e.GroupingID, dks.CircleGroupingID) && bytes.Equal(e.Digest, d(dks.DigestC03Unt)) {
+			assert.Equal(t, schema.LabelPositive, e.Label)
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestExpectationsImportHandler_NotAnEditor_Unauthorized(t *testing.T) {
+	wh := userIsLoggedInButNotEditor(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/expectations/import", strings.NewReader("{}"))
+
+	wh.ExpectationsImportHandler(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
 func TestBaselineHandlerV2_ValidChangelist_Success(t *testing.T) {
 	ctx := context.Background()
 	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
@@ -774,6 +973,82 @@ func TestWhoami_LoggedIn_Success(t *testing.T) {
 }`, w)
 }
 
+func TestWithDeprecationHeaders_NewerVersionRegistered_HeadersSet(t *testing.T) {
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			LegacyRPCDeprecation: time.Date(2029, time.January, 2, 0, 0, 0, 0, time.UTC),
+			LegacyRPCSunset:      time.Date(2030, time.January, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	wh.RegisterRPC(http.MethodGet, "/json/v1/search", "/search", 1)
+	wh.RegisterRPC(http.MethodGet, "/json/v2/search", "/search", 2)
+
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/search", nil)
+	wh.WithDeprecationHeaders(http.MethodGet, "/search", 1, noop)(w, r)
+	assert.Equal(t, "@1862006400", w.Header().Get("Deprecation"))
+	assert.Equal(t, `</json/v2/search>; rel="successor-version"`, w.Header().Get("Link"))
+	assert.Equal(t, "Wed, 02 Jan 2030 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/json/v2/search", nil)
+	wh.WithDeprecationHeaders(http.MethodGet, "/search", 2, noop)(w, r)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Link"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
+
+func TestWithDeprecationHeaders_OtherMethod_NoHeadersSet(t *testing.T) {
+	wh := Handlers{}
+	wh.RegisterRPC(http.MethodGet, "/json/v1/triage", "/triage", 1)
+	wh.RegisterRPC(http.MethodPost, "/json/v2/triage", "/triage", 2)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/triage", nil)
+	wh.WithDeprecationHeaders(http.MethodGet, "/triage", 1, func(w http.ResponseWriter, r *http.Request) {})(w, r)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Link"))
+}
+
+func TestRPCsHandler_ReturnsSortedRPCsWithSuccessors(t *testing.T) {
+	wh := userIsNotLoggedIn(t)
+	wh.anonymousCheapQuota = rate.NewLimiter(rate.Inf, 1)
+	wh.LegacyRPCSunset = time.Date(2030, time.January, 2, 0, 0, 0, 0, time.UTC)
+	wh.RegisterRPC(http.MethodGet, "/json/v2/search", "/search", 2)
+	wh.RegisterRPC(http.MethodGet, "/json/whoami", "/whoami", 0)
+	wh.RegisterRPC(http.MethodGet, "/json/v1/search", "/search", 1)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, requestURL, nil)
+	wh.RPCsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "rpcs": [
+    {
+      "method": "GET",
+      "route": "/json/v1/search",
+      "path": "/search",
+      "version": 1,
+      "successor": "/json/v2/search"
+    },
+    {
+      "method": "GET",
+      "route": "/json/v2/search",
+      "path": "/search",
+      "version": 2
+    },
+    {
+      "method": "GET",
+      "route": "/json/whoami",
+      "path": "/whoami",
+      "version": 0
+    }
+  ],
+  "sunset": "Wed, 02 Jan 2030 00:00:00 GMT"
+}`, w)
+}
+
 func TestChangelistSearchRedirect_CLHasUntriagedDigests_Success(t *testing.T) {
 	ctx := context.Background()
 	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
@@ -1056,6 +1331,110 @@ func TestImageHandler_InvalidImageFormat_404Returned(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
 }
 
+func TestImageHandler_ImmutableImage_CachedForever(t *testing.T) {
+	mgc := &mocks.GCSClient{}
+	mgc.On("GetImage", testutils.AnyContext, types.Digest("0123456789abcdef0123456789abcdef")).Return([]byte("some png bytes"), nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			GCSClient: mgc,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/immutable/images/0123456789abcdef0123456789abcdef.png", nil)
+	wh.ImageHandler(w, r)
+	assertImageResponseWas(t, []byte("some png bytes"), w)
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	assert.Equal(t, `"0123456789abcdef0123456789abcdef"`, w.Header().Get("ETag"))
+}
+
+func TestImageHandler_ImmutableImageWithMatchingETag_NotModified(t *testing.T) {
+	wh := Handlers{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/immutable/images/0123456789abcdef0123456789abcdef.png", nil)
+	r.Header.Set("If-None-Match", `"0123456789abcdef0123456789abcdef"`)
+	wh.ImageHandler(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Result().StatusCode)
+}
+
+func TestImageHandler_ImmutableDiffInNonCanonicalOrder_Redirected(t *testing.T) {
+	wh := Handlers{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/immutable/diffs/22222222222222222222222222222222-11111111111111111111111111111111.png?expires=1&sig=abc", nil)
+	wh.ImageHandler(w, r)
+	assert.Equal(t, http.StatusMovedPermanently, w.Result().StatusCode)
+	assert.Equal(t, "/img/immutable/diffs/11111111111111111111111111111111-22222222222222222222222222222222.png?expires=1&sig=abc", w.Header().Get("Location"))
+}
+
+func TestImageHandler_ImmutableImageWithWrongType_404Returned(t *testing.T) {
+	wh := Handlers{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/immutable/diffs/0123456789abcdef0123456789abcdef.png", nil)
+	wh.ImageHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestImageHandler_SignedImmutableImage_CachedUntilSignatureExpires(t *testing.T) {
+	mgc := &mocks.GCSClient{}
+	mgc.On("GetImage", testutils.AnyContext, types.Digest("0123456789abcdef0123456789abcdef")).Return([]byte("some png bytes"), nil)
+	signer, err := imageurl.NewSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	require.NoError(t, err)
+	signedAt := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			GCSClient:      mgc,
+			ImageURLSigner: signer,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/immutable/images/0123456789abcdef0123456789abcdef.png?"+signer.Params(signedAt).Encode(), nil)
+	r = r.WithContext(context.WithValue(r.Context(), now.ContextKey, signedAt.Add(20*time.Minute)))
+	wh.ImageHandler(w, r)
+	assertImageResponseWas(t, []byte("some png bytes"), w)
+	// The signature expires at 06:30:00.
+	assert.Equal(t, "public, max-age=3833, immutable", w.Header().Get("Cache-Control"))
+}
+
+func TestImageHandler_UnsignedImmutableImage_403Returned(t *testing.T) {
+	signer, err := imageurl.NewSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	require.NoError(t, err)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			ImageURLSigner: signer,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/immutable/images/0123456789abcdef0123456789abcdef.png", nil)
+	wh.ImageHandler(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	assert.Equal(t, "no-cache, no-store, must-revalidate", w.Header().Get("Cache-Control"))
+}
+
+func TestImageHandler_LegacyImageOnSignedInstance_403Returned(t *testing.T) {
+	signer, err := imageurl.NewSigner(bytes.Repeat([]byte("k"), 32), time.Hour)
+	require.NoError(t, err)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			ImageURLSigner: signer,
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/img/images/0123456789abcdef0123456789abcdef.png?"+signer.Params(time.Now()).Encode(), nil)
+	wh.ImageHandler(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	assert.Equal(t, "no-cache, no-store, must-revalidate", w.Header().Get("Cache-Control"))
+}
+
 func loadAsPNGBytes(t *testing.T, textImage string) []byte {
 	img := text.MustToNRGBA(textImage)
 	var buf bytes.Buffer
@@ -1108,6 +1487,7 @@ func TestChangelistSummaryHandler_ValidInput_CorrectJSONReturned(t *testing.T) {
   "patchsets": [
     {
       "new_images": 5,
+      "changed_tests": 0,
       "new_untriaged_images": 6,
       "total_untriaged_images": 7,
       "patchset_id": "patchset8",
@@ -1115,6 +1495,7 @@ func TestChangelistSummaryHandler_ValidInput_CorrectJSONReturned(t *testing.T) {
     },
     {
       "new_images": 1,
+      "changed_tests": 0,
       "new_untriaged_images": 2,
       "total_untriaged_images": 3,
       "patchset_id": "patchset1",
@@ -1188,6 +1569,7 @@ func TestChangelistSummaryHandler_CachedValueStaleButUpdatesQuickly_ReturnsFresh
   "patchsets": [
     {
       "new_images": 5,
+      "changed_tests": 0,
       "new_untriaged_images": 6,
       "total_untriaged_images": 7,
       "patchset_id": "patchset8",
@@ -1195,6 +1577,7 @@ func TestChangelistSummaryHandler_CachedValueStaleButUpdatesQuickly_ReturnsFresh
     },
     {
       "new_images": 1,
+      "changed_tests": 0,
       "new_untriaged_images": 2,
       "total_untriaged_images": 3,
       "patchset_id": "patchset1",
@@ -1274,6 +1657,7 @@ func TestChangelistSummaryHandler_CachedValueStaleUpdatesSlowly_ReturnsStaleResu
   "patchsets": [
     {
       "new_images": 1,
+      "changed_tests": 0,
       "new_untriaged_images": 2,
       "total_untriaged_images": 3,
       "patchset_id": "patchset1",
@@ -1373,30 +1757,424 @@ func TestChangelistSummaryHandler_SearchReturnsError_InternalServerError(t *test
 	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
 }
 
-func TestStartCLCacheProcess_Success(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
-	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+func TestDiffBudgetCheckHandler_LatestPatchset_ViolationsReported(t *testing.T) {
+	ms := &mock_search.API{}
+	ms.On("NewAndUntriagedSummaryForCL", testutils.AnyContext, "my-system_my_cl").Return(search.NewAndUntriagedSummary{
+		ChangelistID: "my_cl",
+		PatchsetSummaries: []search.PatchsetNewAndUntriagedSummary{{
+			NewImages:            1,
+			ChangedTests:         1,
+			NewUntriagedImages:   0,
+			TotalUntriagedImages: 3,
+			PatchsetID:           "patchset1",
+			PatchsetOrder:        1,
+		}, {
+			NewImages:            5,
+			ChangedTests:         3,
+			NewUntriagedImages:   4,
+			TotalUntriagedImages: 7,
+			PatchsetID:           "patchset8",
+			PatchsetOrder:        8,
+		}},
+		LastUpdated: time.Date(2021, time.April, 1, 1, 1, 1, 0, time.UTC),
+	}, nil)
+	ms.On("ChangelistLastUpdated", testutils.AnyContext, "my-system_my_cl").Return(time.Date(2021, time.April, 1, 1, 1, 1, 0, time.UTC), nil)
 
+	maxUntriaged, maxTests := 0, 5
 	wh := initCaches(&Handlers{
 		HandlersConfig: HandlersConfig{
-			Search2API: search.New(db, 10),
-			DB:         db,
+			Search2API: ms,
+			ReviewSystems: []clstore.ReviewSystem{{
+				ID: "my-system",
+			}},
+			DiffBudget: &config.DiffBudgetConfig{
+				MaxNewUntriagedDigests: &maxUntriaged,
+				MaxChangedTests:        &maxTests,
+			},
 		},
+		anonymousGerritQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:               userIsNotLoggedIn(t).alogin,
 	})
 
-	// Set the time to be a few days after both CLs in the sample data land.
-	ctx = context.WithValue(ctx, now.ContextKey, time.Date(2020, time.December, 14, 0, 0, 0, 0, time.UTC))
-	wh.startCLCacheProcess(ctx)
-	require.Eventually(t, func() bool {
-		return wh.clSummaryCache.Len() == 4
-	}, 5*time.Second, 100*time.Millisecond)
-	assert.True(t, wh.clSummaryCache.Contains("github_CL_fix_ios"))
-	assert.True(t, wh.clSummaryCache.Contains("github_CL_new_tests"))
-	assert.True(t, wh.clSummaryCache.Contains("github_CLdisallowtriaging"))
-	assert.True(t, wh.clSummaryCache.Contains("github_CLmultipledatapoints"))
-}
+	test := func(name, target, expectedJSON string) {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			r = setChiURLParams(r, map[string]string{
+				"id":     "my_cl",
+				"system": "my-system",
+			})
+			wh.DiffBudgetCheckHandler(w, r)
+			assertJSONResponseWas(t, http.StatusOK, expectedJSON, w)
+		})
+	}
+
+	test("defaults to latest patchset", "/json/v1/diff_budget/my-system/my_cl", `{
+  "changelist_id": "my_cl",
+  "patchset_id": "patchset8",
+  "patchset_order": 8,
+  "enforced": true,
+  "pass": false,
+  "violations": [
+    {
+      "limit": "max_new_untriaged_digests",
+      "max": 0,
+      "actual": 4
+    }
+  ],
+  "outdated": false
+}`)
+	test("specific patchset", "/json/v1/diff_budget/my-system/my_cl?patchset=patchset1", `{
+  "changelist_id": "my_cl",
+  "patchset_id": "patchset1",
+  "patchset_order": 1,
+  "enforced": true,
+  "pass": true,
+  "violations": [],
+  "outdated": false
+}`)
+}
+
+func TestDiffBudgetCheckHandler_NoBudgetConfigured_AlwaysPasses(t *testing.T) {
+	ms := &mock_search.API{}
+	ms.On("NewAndUntriagedSummaryForCL", testutils.AnyContext, "my-system_my_cl").Return(search.NewAndUntriagedSummary{
+		ChangelistID: "my_cl",
+		PatchsetSummaries: []search.PatchsetNewAndUntriagedSummary{{
+			NewImages:            50,
+			ChangedTests:         20,
+			NewUntriagedImages:   50,
+			TotalUntriagedImages: 50,
+			PatchsetID:           "patchset1",
+			PatchsetOrder:        1,
+		}},
+		LastUpdated: time.Date(2021, time.April, 1, 1, 1, 1, 0, time.UTC),
+	}, nil)
+	ms.On("ChangelistLastUpdated", testutils.AnyContext, "my-system_my_cl").Return(time.Date(2021, time.April, 1, 1, 1, 1, 0, time.UTC), nil)
+
+	wh := initCaches(&Handlers{
+		HandlersConfig: HandlersConfig{
+			Search2API: ms,
+			ReviewSystems: []clstore.ReviewSystem{{
+				ID: "my-system",
+			}},
+		},
+		anonymousGerritQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:               userIsNotLoggedIn(t).alogin,
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/diff_budget/my-system/my_cl", nil)
+	r = setChiURLParams(r, map[string]string{
+		"id":     "my_cl",
+		"system": "my-system",
+	})
+	wh.DiffBudgetCheckHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "changelist_id": "my_cl",
+  "patchset_id": "patchset1",
+  "patchset_order": 1,
+  "enforced": false,
+  "pass": true,
+  "violations": [],
+  "outdated": false
+}`, w)
+}
+
+func TestDiffBudgetCheckHandler_UnknownPatchset_NotFound(t *testing.T) {
+	ms := &mock_search.API{}
+	ms.On("NewAndUntriagedSummaryForCL", testutils.AnyContext, "my-system_my_cl").Return(search.NewAndUntriagedSummary{
+		ChangelistID: "my_cl",
+		PatchsetSummaries: []search.PatchsetNewAndUntriagedSummary{{
+			PatchsetID:    "patchset1",
+			PatchsetOrder: 1,
+		}},
+		LastUpdated: time.Date(2021, time.April, 1, 1, 1, 1, 0, time.UTC),
+	}, nil)
+	ms.On("ChangelistLastUpdated", testutils.AnyContext, "my-system_my_cl").Return(time.Date(2021, time.April, 1, 1, 1, 1, 0, time.UTC), nil)
+
+	wh := initCaches(&Handlers{
+		HandlersConfig: HandlersConfig{
+			Search2API: ms,
+			ReviewSystems: []clstore.ReviewSystem{{
+				ID: "my-system",
+			}},
+		},
+		anonymousGerritQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:               userIsNotLoggedIn(t).alogin,
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/diff_budget/my-system/my_cl?patchset=nope", nil)
+	r = setChiURLParams(r, map[string]string{
+		"id":     "my_cl",
+		"system": "my-system",
+	})
+	wh.DiffBudgetCheckHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestEvaluateDiffBudget_OnlySetLimitsEnforced(t *testing.T) {
+	ps := search.PatchsetNewAndUntriagedSummary{
+		NewImages:          10,
+		ChangedTests:       4,
+		NewUntriagedImages: 2,
+	}
+	assert.Equal(t, frontend.DiffBudgetVerdict{
+		Pass:       true,
+		Violations: []frontend.DiffBudgetViolation{},
+	}, evaluateDiffBudget(&config.DiffBudgetConfig{}, ps))
+
+	limit := 3
+	assert.Equal(t, frontend.DiffBudgetVerdict{
+		Pass: false,
+		Violations: []frontend.DiffBudgetViolation{{
+			Limit:  "max_changed_tests",
+			Max:    3,
+			Actual: 4,
+		}},
+	}, evaluateDiffBudget(&config.DiffBudgetConfig{
+		MaxNewUntriagedDigests: &limit,
+		MaxChangedTests:        &limit,
+	}, ps))
+}
+
+func TestCompareChangelistsHandler_TwoPatchsetsOfSameCL_DifferencesReturned(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB: db,
+			ReviewSystems: []clstore.ReviewSystem{{
+				ID: dks.GitHubCRS,
+			}},
+		},
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:                  userIsNotLoggedIn(t).alogin,
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/changelists/compare?crs=github&left_cl=CL_new_tests&left_ps=PS_adds_new_corpus", nil)
+	wh.CompareChangelistsHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var resp frontend.ChangelistComparisonResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, frontend.ComparedPatchset{
+		ChangelistID: dks.ChangelistIDThatAddsNewTests,
+		PatchsetID:   dks.PatchsetIDAddsNewCorpus,
+	}, resp.Left)
+	// The right patchset defaults to the most recent patchset of the same CL.
+	assert.Equal(t, frontend.ComparedPatchset{
+		ChangelistID: dks.ChangelistIDThatAddsNewTests,
+		PatchsetID:   dks.PatchsetIDAddsNewCorpusAndTest,
+	}, resp.Right)
+	assert.False(t, resp.Identical)
+	// 10 traces from Windows, 10 traces from the walleye, which only has data on the second PS.
+	assert.Equal(t, 20, resp.NumTraces)
+
+	tracesPerTest := map[string]int{}
+	for _, test := range resp.Tests {
+		tracesPerTest[test.Grouping[types.PrimaryKeyField]] = len(test.Traces)
+	}
+	assert.Equal(t, map[string]int{
+		dks.CircleTest:    2,
+		dks.RoundRectTest: 4,
+		dks.SevenTest:     4,
+		dks.SquareTest:    2,
+		dks.TriangleTest:  2,
+	}, tracesPerTest)
+
+	for _, tc := range resp.Tests[2].Traces {
+		if tc.Params[dks.OSKey] == dks.Windows10dot3OS {
+			assert.Equal(t, []types.Digest{dks.DigestBlank}, tc.LeftDigests)
+			assert.Equal(t, []types.Digest{dks.DigestD01Pos_CL}, tc.RightDigests)
+		} else {
+			assert.Empty(t, tc.LeftDigests)
+			assert.Equal(t, []types.Digest{dks.DigestD01Pos_CL}, tc.RightDigests)
+		}
+	}
+}
+
+func TestCompareChangelistsHandler_InvalidInput_BadRequest(t *testing.T) {
+	test := func(name, url string) {
+		t.Run(name, func(t *testing.T) {
+			wh := Handlers{
+				HandlersConfig: HandlersConfig{
+					ReviewSystems: []clstore.ReviewSystem{{
+						ID: dks.GitHubCRS,
+					}},
+				},
+				anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+				alogin:                  userIsNotLoggedIn(t).alogin,
+			}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, url, nil)
+			wh.CompareChangelistsHandler(w, r)
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+	test("unknown CRS", "/json/v1/changelists/compare?crs=nope&left_cl=a&left_ps=1&right_ps=2")
+	test("missing left CL", "/json/v1/changelists/compare?crs=github&left_ps=1&right_ps=2")
+	test("same patchset", "/json/v1/changelists/compare?crs=github&left_cl=a&left_ps=1&right_ps=1")
+}
+
+func TestTestPriorityHandler_FilterByCorpus_RankedTestsReturned(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB: db,
+		},
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:                  userIsNotLoggedIn(t).alogin,
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/tests/priority?corpus=round", nil)
+	wh.TestPriorityHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var resp frontend.TestPriorityResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 6, resp.NumChangelists)
+	require.NotEmpty(t, resp.Tests)
+	for i, tp := range resp.Tests {
+		assert.Equal(t, dks.RoundCorpus, tp.Grouping[types.CorpusField])
+		assert.LessOrEqual(t, tp.ChangelistsChanged, tp.ChangelistsRun)
+		if i > 0 {
+			assert.LessOrEqual(t, tp.Score, resp.Tests[i-1].Score)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/json/v1/tests/priority?corpus=round&limit=1", nil)
+	wh.TestPriorityHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var limited frontend.TestPriorityResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &limited))
+	assert.Equal(t, resp.Tests[:1], limited.Tests)
+}
+
+func TestTestPriorityHandler_InvalidInput_BadRequest(t *testing.T) {
+	test := func(name, url string) {
+		t.Run(name, func(t *testing.T) {
+			wh := userIsNotLoggedIn(t)
+			wh.anonymousExpensiveQuota = rate.NewLimiter(rate.Inf, 1)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, url, nil)
+			wh.TestPriorityHandler(w, r)
+			assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		})
+	}
+	test("multiple values", "/json/v1/tests/priority?os=a&os=b")
+	test("invalid limit", "/json/v1/tests/priority?limit=nope")
+	test("negative limit", "/json/v1/tests/priority?limit=-1")
+}
+
+func TestRankTestPriorities_FrequentlyChangedTestsFirst(t *testing.T) {
+	circle := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	square := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
+	triangle := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.TriangleTest}
+	seven := paramtools.Params{types.CorpusField: dks.TextCorpus, types.PrimaryKeyField: dks.SevenTest}
+	tests := []frontend.TestPriority{
+		{Grouping: circle, ChangelistsRun: 1, ChangelistsChanged: 1},
+		{Grouping: square, ChangelistsRun: 50, ChangelistsChanged: 0},
+		{Grouping: triangle, ChangelistsRun: 50, ChangelistsChanged: 40},
+		{Grouping: seven, ChangelistsRun: 3, ChangelistsChanged: 0},
+	}
+	rankTestPriorities(tests)
+	assert.Equal(t, []frontend.TestPriority{
+		{Grouping: triangle, ChangelistsRun: 50, ChangelistsChanged: 40, Score: 40.0 / 51},
+		{Grouping: circle, ChangelistsRun: 1, ChangelistsChanged: 1, Score: 0.5},
+		// Ties are sorted by grouping.
+		{Grouping: seven, ChangelistsRun: 3, ChangelistsChanged: 0, Score: 0},
+		{Grouping: square, ChangelistsRun: 50, ChangelistsChanged: 0, Score: 0},
+	}, tests)
+}
+
+func TestCompareTraces_OnlyDifferingTracesReturned(t *testing.T) {
+	circle := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	square := paramtools.Params{types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
+	circleRGB := paramtools.Params{dks.ColorModeKey: dks.RGBColorMode, types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	circleGrey := paramtools.Params{dks.ColorModeKey: dks.GreyColorMode, types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	squareRGB := paramtools.Params{dks.ColorModeKey: dks.RGBColorMode, types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
+	squareGrey := paramtools.Params{dks.ColorModeKey: dks.GreyColorMode, types.CorpusField: dks.CornersCorpus, types.PrimaryKeyField: dks.SquareTest}
+
+	left := map[tiling.TraceIDV2]*patchsetTrace{
+		"01": {keys: circleRGB, grouping: circle, digests: []types.Digest{dks.DigestC01Pos}},
+		"02": {keys: circleGrey, grouping: circle, digests: []types.Digest{dks.DigestC02Pos}},
+		"03": {keys: squareRGB, grouping: square, digests: []types.Digest{dks.DigestA01Pos}},
+	}
+	right := map[tiling.TraceIDV2]*patchsetTrace{
+		"01": {keys: circleRGB, grouping: circle, digests: []types.Digest{dks.DigestC01Pos}},
+		"02": {keys: circleGrey, grouping: circle, digests: []types.Digest{dks.DigestC02Pos, dks.DigestC03Unt}},
+		"04": {keys: squareGrey, grouping: square, digests: []types.Digest{dks.DigestA02Pos}},
+	}
+	tests, numTraces := compareTraces(left, right)
+	assert.Equal(t, 4, numTraces)
+	assert.Equal(t, []frontend.TestComparison{
+		{
+			Grouping: circle,
+			Traces: []frontend.TraceComparison{{
+				TraceID:      "02",
+				Params:       circleGrey,
+				LeftDigests:  []types.Digest{dks.DigestC02Pos},
+				RightDigests: []types.Digest{dks.DigestC02Pos, dks.DigestC03Unt},
+			}},
+		},
+		{
+			Grouping: square,
+			Traces: []frontend.TraceComparison{{
+				TraceID:      "03",
+				Params:       squareRGB,
+				LeftDigests:  []types.Digest{dks.DigestA01Pos},
+				RightDigests: []types.Digest{},
+			}, {
+				TraceID:      "04",
+				Params:       squareGrey,
+				LeftDigests:  []types.Digest{},
+				RightDigests: []types.Digest{dks.DigestA02Pos},
+			}},
+		},
+	}, tests)
+}
+
+func TestCompareTraces_IdenticalData_ReturnsNoTests(t *testing.T) {
+	circle := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	data := map[tiling.TraceIDV2]*patchsetTrace{
+		"01": {keys: circle, grouping: circle, digests: []types.Digest{dks.DigestC01Pos}},
+	}
+	tests, numTraces := compareTraces(data, data)
+	assert.Empty(t, tests)
+	assert.Equal(t, 1, numTraces)
+}
+
+func TestStartCLCacheProcess_Success(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := initCaches(&Handlers{
+		HandlersConfig: HandlersConfig{
+			Search2API: search.New(db, 10),
+			DB:         db,
+		},
+	})
+
+	// Set the time to be a few days after both CLs in the sample data land.
+	ctx = context.WithValue(ctx, now.ContextKey, time.Date(2020, time.December, 14, 0, 0, 0, 0, time.UTC))
+	wh.startCLCacheProcess(ctx)
+	require.Eventually(t, func() bool {
+		return wh.clSummaryCache.Len() == 4
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.True(t, wh.clSummaryCache.Contains("github_CL_fix_ios"))
+	assert.True(t, wh.clSummaryCache.Contains("github_CL_new_tests"))
+	assert.True(t, wh.clSummaryCache.Contains("github_CLdisallowtriaging"))
+	assert.True(t, wh.clSummaryCache.Contains("github_CLmultipledatapoints"))
+}
 
 func TestStatusHandler_Success(t *testing.T) {
 	wh := Handlers{statusCache: frontend.GUIStatus{
@@ -2091,7 +2869,8 @@ func TestTriageLogHandler_PrimaryBranch_Success(t *testing.T) {
         }
       ]
     }
-  ]
+  ],
+  "comments": []
 }`
 	assertJSONResponseWas(t, http.StatusOK, expectedJSON, w)
 }
@@ -2149,7 +2928,8 @@ func TestTriageLogHandler_RespectsPagination_Success(t *testing.T) {
         }
       ]
     }
-  ]
+  ],
+  "comments": []
 }`
 	assertJSONResponseWas(t, http.StatusOK, expectedJSON, w)
 }
@@ -2210,7 +2990,8 @@ func TestTriageLogHandler_ValidChangelist_Success(t *testing.T) {
         }
       ]
     }
-  ]
+  ],
+  "comments": []
 }`
 	assertJSONResponseWas(t, http.StatusOK, expectedJSON, w)
 }
@@ -2238,7 +3019,8 @@ func TestTriageLogHandler_InvalidChangelist_ReturnsEmptyEntries(t *testing.T) {
   "offset": 0,
   "size": 20,
   "total": 0,
-  "entries": []
+  "entries": [],
+  "comments": []
 }`
 	assertJSONResponseWas(t, http.StatusOK, expectedJSON, w)
 }
@@ -2759,6 +3541,65 @@ func TestTriage3_SingleDigestOnPrimaryBranch_Success(t *testing.T) {
 	}}, newDeltas)
 }
 
+func TestTriage3_TriageEventsConfigured_EventsPublished(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	publisher := &fakeTriageEventPublisher{}
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB:           db,
+			TriageEvents: publisher,
+		},
+	}
+	circleGrouping := paramtools.Params{
+		types.CorpusField:     dks.RoundCorpus,
+		types.PrimaryKeyField: dks.CircleTest,
+	}
+	request := frontend.TriageRequestV3{
+		Deltas: []frontend.TriageDelta{
+			{
+				Grouping:    circleGrouping,
+				Digest:      dks.DigestC03Unt,
+				LabelBefore: expectations.Untriaged,
+				LabelAfter:  expectations.Positive,
+			},
+		},
+	}
+
+	const user = "single_triage@example.com"
+	fakeNow := time.Date(2021, time.July, 4, 4, 4, 4, 0, time.UTC)
+	ctx = now.TimeTravelingContext(fakeNow)
+	res, err := wh.triage3(ctx, user, request)
+	require.NoError(t, err)
+	assert.Equal(t, frontend.TriageResponse{Status: frontend.TriageResponseStatusOK}, res)
+
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, triageevents.Event{
+		Digest:      dks.DigestC03Unt,
+		Grouping:    circleGrouping,
+		Test:        dks.CircleTest,
+		Corpus:      dks.RoundCorpus,
+		LabelBefore: expectations.Untriaged,
+		Label:       expectations.Positive,
+		Author:      user,
+		RecordID:    publisher.events[0].RecordID, // Randomly generated.
+		Timestamp:   fakeNow,
+	}, publisher.events[0])
+	assert.NotEmpty(t, publisher.events[0].RecordID)
+}
+
+// fakeTriageEventPublisher records all the events it is asked to publish.
+type fakeTriageEventPublisher struct {
+	events []triageevents.Event
+}
+
+func (f *fakeTriageEventPublisher) Publish(_ context.Context, events []triageevents.Event) error {
+	f.events = append(f.events, events...)
+	return nil
+}
+
 func TestTriage3_SingleDigestOnPrimaryBranch_EmptyLabels_Error(t *testing.T) {
 	ctx := context.Background()
 	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
@@ -3538,6 +4379,78 @@ func TestTriage3_BulkTriageOnLandedCL_Error(t *testing.T) {
 	sqltest.AssertNoChanges(del)
 }
 
+func TestAddVariantDeltas_VariantsExist_AddsDeltasForVariantsWithOtherLabels(t *testing.T) {
+	grouping := paramtools.Params{
+		types.CorpusField:     dks.RoundCorpus,
+		types.PrimaryKeyField: dks.CircleTest,
+	}
+	ms := &mock_search.API{}
+	ms.On("GetVariants", testutils.AnyContext, grouping, dks.DigestC02Pos).Return([]frontend.DigestVariant{
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC01Pos, Status: expectations.Positive},
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC03Unt, Status: expectations.Untriaged},
+		{Variant: dks.RGBColorMode, Digest: dks.DigestC05Unt, Status: expectations.Untriaged},
+	}, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			Search2API: ms,
+		},
+	}
+	deltas := []frontend.TriageDelta{{
+		Grouping:    grouping,
+		Digest:      dks.DigestC02Pos,
+		LabelBefore: expectations.Untriaged,
+		LabelAfter:  expectations.Positive,
+	}, {
+		// This variant is triaged explicitly, so it should not be added again.
+		Grouping:    grouping,
+		Digest:      dks.DigestC05Unt,
+		LabelBefore: expectations.Untriaged,
+		LabelAfter:  expectations.Negative,
+	}}
+	ms.On("GetVariants", testutils.AnyContext, grouping, dks.DigestC05Unt).Return(nil, nil)
+
+	actual, err := wh.addVariantDeltas(context.Background(), deltas)
+	require.NoError(t, err)
+	assert.Equal(t, []frontend.TriageDelta{deltas[0], deltas[1], {
+		Grouping:    grouping,
+		Digest:      dks.DigestC03Unt,
+		LabelBefore: expectations.Untriaged,
+		LabelAfter:  expectations.Positive,
+	}}, actual)
+	ms.AssertExpectations(t)
+}
+
+func TestTriage3_IncludeVariantsOnCL_Error(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB: db,
+		},
+	}
+
+	tr := frontend.TriageRequestV3{
+		Deltas: []frontend.TriageDelta{{
+			Grouping: paramtools.Params{
+				types.CorpusField:     dks.RoundCorpus,
+				types.PrimaryKeyField: dks.CircleTest,
+			},
+			Digest:      dks.DigestC06Pos_CL,
+			LabelBefore: expectations.Positive,
+			LabelAfter:  expectations.Negative,
+		}},
+		CodeReviewSystem: dks.GitHubCRS,
+		ChangelistID:     dks.ChangelistIDThatAttemptsToFixIOS,
+		IncludeVariants:  true,
+	}
+	_, err := wh.triage3(ctx, "single_triage@example.com", tr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported on changelists")
+}
+
 func TestLatestPositiveDigest2_TracesExist_Success(t *testing.T) {
 	ctx := context.Background()
 	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
@@ -3879,14 +4792,74 @@ func TestListIgnoreRules2_WithCounts_Success(t *testing.T) {
 	assertJSONResponseWas(t, http.StatusOK, expectedResponse, w)
 }
 
-func TestStartIgnoredTraceCacheProcess(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
-	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
-
-	wh := &Handlers{
-		HandlersConfig: HandlersConfig{
+func TestIgnoreStatsHandler_RulesWithAndWithoutMatches_UnusedRulesFirst(t *testing.T) {
+	var fakeNow = time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	mis := &mock_ignore.Store{}
+	mis.On("List", testutils.AnyContext).Return([]ignore.Rule{{
+		ID:        "used",
+		CreatedBy: "alpha@example.com",
+		UpdatedBy: "beta@example.com",
+		Expires:   fakeNow.Add(time.Hour),
+		Query:     "device=taimen",
+	}, {
+		ID:        "dead",
+		CreatedBy: "alpha@example.com",
+		Expires:   fakeNow.Add(-time.Hour),
+		Query:     "device=Nokia4",
+	}}, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			IgnoreStore: mis,
+		},
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:                  userIsEditor(t).alogin,
+		ignoredTracesCache: []ignoredTrace{
+			{Keys: paramtools.Params{"device": "taimen", types.PrimaryKeyField: dks.CircleTest}, Label: expectations.Untriaged},
+			{Keys: paramtools.Params{"device": "taimen", types.PrimaryKeyField: dks.SquareTest}, Label: expectations.Positive},
+			{Keys: paramtools.Params{"device": "walleye", types.PrimaryKeyField: dks.SquareTest}, Label: expectations.Positive},
+		},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/ignores/stats", nil)
+	r = overwriteNow(r, fakeNow)
+	wh.IgnoreStatsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "rules": [
+    {
+      "id": "dead",
+      "query": "device=Nokia4",
+      "owner": "alpha@example.com",
+      "expires": "2020-01-02T02:04:05Z",
+      "expired": true,
+      "matched_traces": 0,
+      "exclusive_traces": 0
+    },
+    {
+      "id": "used",
+      "query": "device=taimen",
+      "owner": "beta@example.com",
+      "expires": "2020-01-02T04:04:05Z",
+      "expired": false,
+      "matched_traces": 2,
+      "exclusive_traces": 2
+    }
+  ],
+  "num_unused": 1,
+  "num_expired": 1
+}`, w)
+	mis.AssertExpectations(t)
+}
+
+func TestStartIgnoredTraceCacheProcess(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+
+	wh := &Handlers{
+		HandlersConfig: HandlersConfig{
 			DB:         db,
 			WindowSize: 100,
 		},
@@ -4414,6 +5387,652 @@ func TestDetailsHandler_ValidRequest_Success(t *testing.T) {
 }`)
 }
 
+func TestDetailsBulkHandler_InvalidRequest_Error(t *testing.T) {
+	wh := Handlers{
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		HandlersConfig: HandlersConfig{
+			ReviewSystems: []clstore.ReviewSystem{
+				{
+					ID: dks.GitHubCRS,
+				},
+			},
+		},
+		alogin: userIsEditor(t).alogin,
+	}
+
+	test := func(name string, req frontend.DetailsBulkRequest, expectedError string) {
+		t.Run(name, func(t *testing.T) {
+			reqBytes, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/json/v1/details/bulk", bytes.NewReader(reqBytes))
+			wh.DetailsBulkHandler(w, r)
+
+			res := w.Result()
+			resBytes, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			assert.Contains(t, string(resBytes), expectedError)
+		})
+	}
+
+	test("empty request", frontend.DetailsBulkRequest{}, "Entries cannot be empty.")
+	test(
+		"too many entries",
+		frontend.DetailsBulkRequest{
+			Entries: make([]frontend.DetailsBulkEntry, maxDetailsBulkEntries+1),
+		},
+		"At most 200 entries can be requested at once.")
+	test(
+		"invalid code review system",
+		frontend.DetailsBulkRequest{
+			Entries: []frontend.DetailsBulkEntry{{
+				Grouping: paramtools.Params{
+					types.CorpusField:     "fake_corpus",
+					types.PrimaryKeyField: "fake_test",
+				},
+				Digest: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			}},
+			CodeReviewSystem: "invalid code review system",
+			ChangelistID:     "123456",
+		},
+		"Invalid code review system.")
+}
+
+func TestDetailsBulkHandler_SomeEntriesFail_PartialResultsReturned(t *testing.T) {
+	squareGrouping := paramtools.Params{
+		types.CorpusField:     dks.CornersCorpus,
+		types.PrimaryKeyField: dks.SquareTest,
+	}
+	circleGrouping := paramtools.Params{
+		types.CorpusField:     dks.RoundCorpus,
+		types.PrimaryKeyField: dks.CircleTest,
+	}
+
+	ms := &mock_search.API{}
+	ms.On("GetDigestDetails", testutils.AnyContext, squareGrouping, dks.DigestA01Pos, dks.ChangelistIDThatAttemptsToFixIOS, dks.GitHubCRS).
+		Return(frontend.DigestDetails{
+			Result: frontend.SearchResult{
+				Digest: dks.DigestA01Pos,
+				Test:   dks.SquareTest,
+			},
+			Commits: []frontend.Commit{},
+		}, nil)
+	ms.On("GetDigestDetails", testutils.AnyContext, circleGrouping, dks.DigestC01Pos, dks.ChangelistIDThatAttemptsToFixIOS, dks.GitHubCRS).
+		Return(frontend.DigestDetails{}, errors.New("boom"))
+
+	wh := Handlers{
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		HandlersConfig: HandlersConfig{
+			ReviewSystems: []clstore.ReviewSystem{
+				{
+					ID: dks.GitHubCRS,
+				},
+			},
+			Search2API: ms,
+		},
+		alogin: userIsEditor(t).alogin,
+	}
+
+	reqBytes, err := json.Marshal(frontend.DetailsBulkRequest{
+		Entries: []frontend.DetailsBulkEntry{
+			{Grouping: squareGrouping, Digest: dks.DigestA01Pos},
+			{Grouping: circleGrouping, Digest: dks.DigestC01Pos},
+			{Grouping: circleGrouping, Digest: "invalid digest"},
+		},
+		CodeReviewSystem: dks.GitHubCRS,
+		ChangelistID:     dks.ChangelistIDThatAttemptsToFixIOS,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/details/bulk", bytes.NewReader(reqBytes))
+	wh.DetailsBulkHandler(w, r)
+
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "results": [
+    {
+      "grouping": {
+        "name": "square",
+        "source_type": "corners"
+      },
+      "digest": "a01a01a01a01a01a01a01a01a01a01a0",
+      "details": {
+        "digest": {
+          "digest": "a01a01a01a01a01a01a01a01a01a01a0",
+          "test": "square",
+          "status": "",
+          "triage_history": null,
+          "paramset": null,
+          "traces": {
+            "traces": null,
+            "digests": null,
+            "total_digests": 0
+          },
+          "refDiffs": null,
+          "closestRef": ""
+        },
+        "commits": []
+      }
+    },
+    {
+      "grouping": {
+        "name": "circle",
+        "source_type": "round"
+      },
+      "digest": "c01c01c01c01c01c01c01c01c01c01c0",
+      "error": "Unable to get digest details."
+    },
+    {
+      "grouping": {
+        "name": "circle",
+        "source_type": "round"
+      },
+      "digest": "invalid digest",
+      "error": "Invalid digest."
+    }
+  ]
+}`, w)
+	ms.AssertExpectations(t)
+}
+
+func TestSimilarDigestsHandler_InvalidRequest_Error(t *testing.T) {
+	wh := Handlers{
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:                  userIsEditor(t).alogin,
+	}
+
+	test := func(name, target, expectedError string) {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			wh.SimilarDigestsHandler(w, r)
+
+			res := w.Result()
+			resBytes, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			assert.Contains(t, string(resBytes), expectedError)
+		})
+	}
+
+	test("missing digest", "/json/v1/similar", "Invalid digest.")
+	test("invalid digest", "/json/v1/similar?digest=not-a-digest", "Invalid digest.")
+	test("distance not a number", "/json/v1/similar?digest="+string(dks.DigestA01Pos)+"&max_distance=abc",
+		"max_distance must be an integer in [0, 16]")
+	test("distance too large", "/json/v1/similar?digest="+string(dks.DigestA01Pos)+"&max_distance=17",
+		"max_distance must be an integer in [0, 16]")
+}
+
+func TestSimilarDigestsHandler_HashesExist_ReturnsDigestsWithinDistance(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	data := dks.Build()
+	data.PerceptualHashes = []schema.PerceptualHashRow{
+		{Digest: d(dks.DigestA01Pos), Hash: 0},
+		{Digest: d(dks.DigestA02Pos), Hash: 0b11},
+		{Digest: d(dks.DigestB01Pos), Hash: 0b1},
+		{Digest: d(dks.DigestA05Unt), Hash: -1}, // All 64 bits set.
+	}
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, data))
+	waitForSystemTime()
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB:         db,
+			WindowSize: 100,
+		},
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		similarDigestsCache:     ttlcache.New(similarDigestsCandidatesTTL, similarDigestsCandidatesTTL),
+		alogin:                  userIsEditor(t).alogin,
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/similar?digest="+string(dks.DigestA01Pos), nil)
+	wh.SimilarDigestsHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	var resp frontend.SimilarDigestsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, dks.DigestA01Pos, resp.Digest)
+	require.NotEmpty(t, resp.Results)
+	distances := map[types.Digest]int{}
+	for _, res := range resp.Results {
+		distances[res.Digest] = res.Distance
+	}
+	assert.Equal(t, map[types.Digest]int{
+		dks.DigestA01Pos: 0,
+		dks.DigestB01Pos: 1,
+		dks.DigestA02Pos: 2,
+	}, distances)
+	// The results are sorted by distance.
+	assert.Equal(t, dks.DigestA01Pos, resp.Results[0].Digest)
+	assert.Equal(t, expectations.Positive, resp.Results[0].Status)
+	assert.Equal(t, dks.DigestA02Pos, resp.Results[len(resp.Results)-1].Digest)
+}
+
+func TestSimilarDigestsHandler_NoHashForDigest_NotFound(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTestsWithProductionSchema(ctx, t)
+	require.NoError(t, sqltest.BulkInsertDataTables(ctx, db, dks.Build()))
+	waitForSystemTime()
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			DB:         db,
+			WindowSize: 100,
+		},
+		anonymousExpensiveQuota: rate.NewLimiter(rate.Inf, 1),
+		similarDigestsCache:     ttlcache.New(similarDigestsCandidatesTTL, similarDigestsCandidatesTTL),
+		alogin:                  userIsEditor(t).alogin,
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/similar?digest="+string(dks.DigestA01Pos), nil)
+	wh.SimilarDigestsHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestGetSimilarDigests_CandidatesCached_UsesCacheWithoutQueryingDB(t *testing.T) {
+	wh := Handlers{
+		similarDigestsCache: ttlcache.New(similarDigestsCandidatesTTL, similarDigestsCandidatesTTL),
+	}
+	grouping := paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest}
+	wh.similarDigestsCache.Set("3", []similarDigestCandidate{
+		{digest: dks.DigestC01Pos, hash: 0b111, grouping: grouping, label: schema.LabelPositive},
+		{digest: dks.DigestC02Pos, hash: 0b1, grouping: grouping, label: schema.LabelUntriaged},
+		{digest: dks.DigestC03Unt, hash: 0b1111111, grouping: grouping, label: schema.LabelUntriaged},
+	}, ttlcache.DefaultExpiration)
+
+	// wh.DB is nil, so this would panic if the candidates weren't cached.
+	results, err := wh.getSimilarDigests(context.Background(), 3, 0, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []frontend.SimilarDigest{
+		{Digest: dks.DigestC02Pos, Distance: 1, Grouping: grouping, Status: expectations.Untriaged},
+		{Digest: dks.DigestC01Pos, Distance: 3, Grouping: grouping, Status: expectations.Positive},
+	}, results)
+}
+
+func TestFlakyTestsHandler_ValidRequest_ReturnsTestsFromSearch(t *testing.T) {
+	ms := &mock_search.API{}
+	ms.On("GetFlakyTests", testutils.AnyContext, dks.RoundCorpus, 5).Return(frontend.FlakyTestsResponse{
+		Corpora: []frontend.FlakyCorpus{{
+			Corpus: dks.RoundCorpus,
+			Tests: []frontend.FlakyTest{{
+				Grouping:    paramtools.Params{types.CorpusField: dks.RoundCorpus, types.PrimaryKeyField: dks.CircleTest},
+				Test:        dks.CircleTest,
+				FlakyTraces: 2,
+				MaxDigests:  3,
+			}},
+		}},
+	}, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			Search2API: ms,
+		},
+		anonymousCheapQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:              userIsEditor(t).alogin,
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/flaky?corpus=round&limit=5", nil)
+	wh.FlakyTestsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "corpora": [
+    {
+      "corpus": "round",
+      "tests": [
+        {
+          "grouping": {
+            "name": "circle",
+            "source_type": "round"
+          },
+          "test": "circle",
+          "flaky_traces": 2,
+          "max_digests": 3
+        }
+      ]
+    }
+  ]
+}`, w)
+	ms.AssertExpectations(t)
+}
+
+func TestFlakyTestsHandler_InvalidLimit_BadRequest(t *testing.T) {
+	wh := Handlers{
+		anonymousCheapQuota: rate.NewLimiter(rate.Inf, 1),
+		alogin:              userIsEditor(t).alogin,
+	}
+	for _, limit := range []string{"abc", "0", "501"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/json/v1/flaky?limit="+limit, nil)
+		wh.FlakyTestsHandler(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode, limit)
+	}
+}
+
+func TestSearchFacetsHandler_ValidRequest_ReturnsFacetsFromSearch(t *testing.T) {
+	ms := &mock_search.API{}
+	ms.On("GetSearchFacets", testutils.AnyContext, mock.MatchedBy(func(q *search_query.Search) bool {
+		return assert.Equal(t, []string{dks.RoundCorpus}, q.TraceValues[types.CorpusField])
+	})).Return(frontend.SearchFacetsResponse{
+		Total: 3,
+		Facets: []frontend.SearchFacet{{
+			Key: dks.OSKey,
+			Values: []frontend.FacetValue{
+				{Value: dks.AndroidOS, Count: 2},
+				{Value: dks.IOS, Count: 1},
+			},
+		}},
+	}, nil)
+
+	wh := Handlers{
+		HandlersConfig: HandlersConfig{
+			Search2API: ms,
+		},
+		alogin: userIsEditor(t).alogin,
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/search/facets?query=source_type%3Dround&untriaged=true", nil)
+	wh.SearchFacetsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "total": 3,
+  "facets": [
+    {
+      "key": "os",
+      "values": [
+        {
+          "value": "Android",
+          "count": 2
+        },
+        {
+          "value": "iOS",
+          "count": 1
+        }
+      ]
+    }
+  ]
+}`, w)
+	ms.AssertExpectations(t)
+}
+
+const ingestTestResults = `{
+  "gitHash": "f4412901bfb130a8774c0c719450d1450845f471",
+  "key": {"os": "Android", "source_type": "round"},
+  "results": [{"key": {"name": "circle"}, "options": {"ext": "png"}, "md5": "00000000000000000000000000000000"}]
+}`
+
+const ingestTestTryjobResults = `{
+  "key": {"os": "Android", "source_type": "round"},
+  "change_list_id": "12",
+  "patch_set_order": 1,
+  "crs": "github",
+  "try_job_id": "12-abc",
+  "cis": "github",
+  "results": [{"key": {"name": "circle"}, "options": {"ext": "png"}, "md5": "00000000000000000000000000000000"}]
+}`
+
+// enableIngestion configures wh to accept uploaded results and write them with gcs.
+func enableIngestion(wh *Handlers, gcs *mocks.GCSClient) {
+	wh.GCSClient = gcs
+	wh.PrimaryBranchResults = &config.GCSSourceConfig{Bucket: "gold-results", Prefix: "dm-json-v1"}
+	wh.SecondaryBranchResults = &config.GCSSourceConfig{Bucket: "gold-results", Prefix: "trybot/dm-json-v1"}
+}
+
+func ingestTestFile(prefix, body string) string {
+	sum := md5.Sum([]byte(body))
+	return prefix + "/uploaded/" + hex.EncodeToString(sum[:]) + ".json"
+}
+
+func TestIngestHandler_PrimaryBranchResults_WrittenToPrimarySource(t *testing.T) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC))
+	name := ingestTestFile("dm-json-v1", ingestTestResults)
+	gcs := &mocks.GCSClient{}
+	gcs.On("WriteResultsFile", testutils.AnyContext, "gold-results/"+name, []byte(ingestTestResults)).Return(nil)
+	wh := userIsEditor(t)
+	enableIngestion(&wh, gcs)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(ingestTestResults)).WithContext(ctx)
+	wh.IngestHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "file": "gs://gold-results/`+name+`"
+}`, w)
+	gcs.AssertExpectations(t)
+}
+
+func TestIngestHandler_RetriedInNextHour_WritesSameFile(t *testing.T) {
+	name := ingestTestFile("dm-json-v1", ingestTestResults)
+	gcs := &mocks.GCSClient{}
+	gcs.On("WriteResultsFile", testutils.AnyContext, "gold-results/"+name, []byte(ingestTestResults)).Return(nil).Twice()
+	wh := userIsEditor(t)
+	enableIngestion(&wh, gcs)
+
+	for _, ts := range []time.Time{
+		time.Date(2022, time.March, 1, 12, 59, 59, 0, time.UTC),
+		time.Date(2022, time.March, 1, 13, 0, 1, 0, time.UTC),
+	} {
+		ctx := context.WithValue(context.Background(), now.ContextKey, ts)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(ingestTestResults)).WithContext(ctx)
+		wh.IngestHandler(w, r)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	}
+	gcs.AssertExpectations(t)
+}
+
+func TestIngestHandler_TryjobResults_WrittenToSecondarySource(t *testing.T) {
+	ctx := context.WithValue(context.Background(), now.ContextKey, time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC))
+	name := ingestTestFile("trybot/dm-json-v1", ingestTestTryjobResults)
+	gcs := &mocks.GCSClient{}
+	gcs.On("WriteResultsFile", testutils.AnyContext, "gold-results/"+name, []byte(ingestTestTryjobResults)).Return(nil)
+	wh := userIsEditor(t)
+	enableIngestion(&wh, gcs)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(ingestTestTryjobResults)).WithContext(ctx)
+	wh.IngestHandler(w, r)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	gcs.AssertExpectations(t)
+}
+
+func TestIngestHandler_TryjobResultsWithoutSecondarySource_BadRequest(t *testing.T) {
+	wh := userIsEditor(t)
+	enableIngestion(&wh, &mocks.GCSClient{})
+	wh.SecondaryBranchResults = nil
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(ingestTestTryjobResults))
+	wh.IngestHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestIngestHandler_InvalidResults_BadRequest(t *testing.T) {
+	wh := userIsEditor(t)
+	enableIngestion(&wh, &mocks.GCSClient{})
+	for _, body := range []string{
+		"not json",
+		// Missing gitHash.
+		`{"key": {"source_type": "round"}, "results": [{"key": {"name": "circle"}, "md5": "00000000000000000000000000000000"}]}`,
+		// Digest is not hex.
+		`{"gitHash": "abcd", "key": {"source_type": "round"}, "results": [{"key": {"name": "circle"}, "md5": "not-a-digest"}]}`,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(body))
+		wh.IngestHandler(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode, body)
+	}
+}
+
+func TestIngestHandler_NotEditor_Unauthorized(t *testing.T) {
+	for _, user := range []func(*testing.T) Handlers{userIsNotLoggedIn, userIsLoggedInButNotEditor} {
+		wh := user(t)
+		enableIngestion(&wh, &mocks.GCSClient{})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(ingestTestResults))
+		wh.IngestHandler(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	}
+}
+
+func TestImageGCReportHandler_NotEditor_Unauthorized(t *testing.T) {
+	for _, user := range []func(*testing.T) Handlers{userIsNotLoggedIn, userIsLoggedInButNotEditor} {
+		wh := user(t)
+		wh.ImageGC = &config.ImageGCConfig{RetentionCommits: 10}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/json/v1/imagegc/report", nil)
+		wh.ImageGCReportHandler(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	}
+}
+
+func TestImageGCReportHandler_NotConfigured_NotFound(t *testing.T) {
+	wh := userIsEditor(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/imagegc/report", nil)
+	wh.ImageGCReportHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestImageGCReportHandler_InvalidLimit_BadRequest(t *testing.T) {
+	wh := userIsEditor(t)
+	wh.ImageGC = &config.ImageGCConfig{RetentionCommits: 10}
+	for _, limit := range []string{"0", "-1", "nope", "10001"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/json/v1/imagegc/report?limit="+limit, nil)
+		wh.ImageGCReportHandler(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode, limit)
+	}
+}
+
+func TestIngestHandler_NotEnabled_NotFound(t *testing.T) {
+	wh := userIsEditor(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/ingest", strings.NewReader(ingestTestResults))
+	wh.IngestHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestCommentsHandler_ByDigest_Success(t *testing.T) {
+	created := time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC)
+	cs := mock_comment.NewStore(t)
+	cs.On("ListForDigests", testutils.AnyContext, []types.Digest{dks.DigestA01Pos, dks.DigestB01Pos}).Return([]comment.Comment{{
+		ID:        "abc",
+		Digest:    dks.DigestA01Pos,
+		CreatedBy: "user@example.com",
+		Created:   created,
+		Text:      "Looks right to me.",
+	}}, nil)
+	wh := userIsNotLoggedIn(t)
+	wh.CommentStore = cs
+	wh.anonymousCheapQuota = rate.NewLimiter(rate.Inf, 1)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/comments?digest="+string(dks.DigestA01Pos)+"&digest="+string(dks.DigestB01Pos), nil)
+	wh.CommentsHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "comments": [
+    {
+      "id": "abc",
+      "digest": "a01a01a01a01a01a01a01a01a01a01a0",
+      "created_by": "user@example.com",
+      "created": "2022-03-01T12:30:00Z",
+      "text": "Looks right to me."
+    }
+  ]
+}`, w)
+}
+
+func TestCommentsHandler_InvalidCRS_BadRequest(t *testing.T) {
+	wh := userIsEditor(t)
+	wh.CommentStore = mock_comment.NewStore(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/comments?crs=nope&changelist_id=1234", nil)
+	wh.CommentsHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestAddCommentHandler_ChangelistComment_Stored(t *testing.T) {
+	cs := mock_comment.NewStore(t)
+	cs.On("Create", testutils.AnyContext, comment.Comment{
+		CRS:          dks.GitHubCRS,
+		ChangelistID: dks.ChangelistIDThatAttemptsToFixIOS,
+		CreatedBy:    "user@example.com",
+		Text:         "The new iPad images are expected.",
+	}).Return("abc", nil)
+	wh := userIsEditor(t)
+	wh.CommentStore = cs
+	wh.ReviewSystems = []clstore.ReviewSystem{{ID: dks.GitHubCRS}}
+
+	w := httptest.NewRecorder()
+	body := `{"crs": "github", "changelist_id": "CL_fix_ios", "text": "The new iPad images are expected."}`
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/comments/add", strings.NewReader(body))
+	wh.AddCommentHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "id": "abc"
+}`, w)
+}
+
+func TestAddCommentHandler_InvalidComment_BadRequest(t *testing.T) {
+	wh := userIsEditor(t)
+	wh.CommentStore = mock_comment.NewStore(t)
+	w := httptest.NewRecorder()
+	body := `{"digest": "a01a01a01a01a01a01a01a01a01a01a0", "text": ""}`
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/comments/add", strings.NewReader(body))
+	wh.AddCommentHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestAddCommentHandler_NotEditor_Unauthorized(t *testing.T) {
+	for _, user := range []func(*testing.T) Handlers{userIsNotLoggedIn, userIsLoggedInButNotEditor} {
+		wh := user(t)
+		wh.CommentStore = mock_comment.NewStore(t)
+		w := httptest.NewRecorder()
+		body := `{"digest": "a01a01a01a01a01a01a01a01a01a01a0", "text": "hello"}`
+		r := httptest.NewRequest(http.MethodPost, "/json/v1/comments/add", strings.NewReader(body))
+		wh.AddCommentHandler(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	}
+}
+
+func TestDeleteCommentHandler_Author_Deleted(t *testing.T) {
+	cs := mock_comment.NewStore(t)
+	cs.On("Get", testutils.AnyContext, "abc").Return(comment.Comment{ID: "abc", CreatedBy: "user@example.com"}, nil)
+	cs.On("Delete", testutils.AnyContext, "abc").Return(nil)
+	wh := userIsEditor(t)
+	wh.CommentStore = cs
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/comments/del/abc", nil)
+	r = setChiURLParams(r, map[string]string{"id": "abc"})
+	wh.DeleteCommentHandler(w, r)
+	assertJSONResponseWas(t, http.StatusOK, `{
+  "deleted": "true"
+}`, w)
+}
+
+func TestDeleteCommentHandler_NotAuthor_Forbidden(t *testing.T) {
+	cs := mock_comment.NewStore(t)
+	cs.On("Get", testutils.AnyContext, "abc").Return(comment.Comment{ID: "abc", CreatedBy: "someone-else@example.com"}, nil)
+	wh := userIsEditor(t)
+	wh.CommentStore = cs
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/json/v1/comments/del/abc", nil)
+	r = setChiURLParams(r, map[string]string{"id": "abc"})
+	wh.DeleteCommentHandler(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+}
+
+func TestCommentsHandler_NotEnabled_NotFound(t *testing.T) {
+	wh := userIsEditor(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/json/v1/comments?digest="+string(dks.DigestA01Pos), nil)
+	wh.CommentsHandler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
 // Because we are calling our handlers directly, the target URL doesn't matter. The target URL
 // would only matter if we were calling into the router, so it knew which handler to call.
 const requestURL = "/does/not/matter"
@@ -4514,3 +6133,56 @@ func initCaches(handlers *Handlers) *Handlers {
 func overwriteNow(r *http.Request, fakeNow time.Time) *http.Request {
 	return r.WithContext(context.WithValue(r.Context(), now.ContextKey, fakeNow))
 }
+
+func TestAddTriageSuggestions_OnlyCloseUntriagedDigestsGetSuggestions(t *testing.T) {
+	closePositive := &frontend.SRDiffDigest{
+		Digest:           dks.DigestA01Pos,
+		Status:           expectations.Positive,
+		CombinedMetric:   0.2,
+		NumDiffPixels:    3,
+		PixelDiffPercent: 0.1,
+		MaxRGBADiffs:     [4]int{4, 5, 6, 0},
+	}
+	farNegative := &frontend.SRDiffDigest{
+		Digest:         dks.DigestA09Neg,
+		Status:         expectations.Negative,
+		CombinedMetric: 4.5,
+	}
+	resp := &frontend.SearchResponse{
+		Results: []*frontend.SearchResult{{
+			Digest:     dks.DigestA04Unt,
+			Status:     expectations.Untriaged,
+			RefDiffs:   map[frontend.RefClosest]*frontend.SRDiffDigest{frontend.PositiveRef: closePositive},
+			ClosestRef: frontend.PositiveRef,
+		}, {
+			// Already triaged, so no suggestion.
+			Digest:     dks.DigestA02Pos,
+			Status:     expectations.Positive,
+			RefDiffs:   map[frontend.RefClosest]*frontend.SRDiffDigest{frontend.PositiveRef: closePositive},
+			ClosestRef: frontend.PositiveRef,
+		}, {
+			// Closest digest is too different to base a suggestion on.
+			Digest:     dks.DigestA05Unt,
+			Status:     expectations.Untriaged,
+			RefDiffs:   map[frontend.RefClosest]*frontend.SRDiffDigest{frontend.NegativeRef: farNegative},
+			ClosestRef: frontend.NegativeRef,
+		}, {
+			// No triaged digests to compare against.
+			Digest: dks.DigestA06Unt,
+			Status: expectations.Untriaged,
+		}},
+	}
+	addTriageSuggestions(resp)
+
+	assert.Equal(t, &frontend.TriageSuggestion{
+		Label:            expectations.Positive,
+		ClosestDigest:    dks.DigestA01Pos,
+		CombinedMetric:   0.2,
+		NumDiffPixels:    3,
+		PixelDiffPercent: 0.1,
+		MaxRGBADiffs:     [4]int{4, 5, 6, 0},
+	}, resp.Results[0].Suggestion)
+	assert.Nil(t, resp.Results[1].Suggestion)
+	assert.Nil(t, resp.Results[2].Suggestion)
+	assert.Nil(t, resp.Results[3].Suggestion)
+}
@@ -0,0 +1,16 @@
+import './index';
+
+import fetchMock from 'fetch-mock';
+import { $$ } from '../../../infra-sk/modules/dom';
+import '../../../infra-sk/modules/theme-chooser-sk';
+import { digestComments } from './test_data';
+import { CommentsSk } from './comments-sk';
+
+fetchMock.get('glob:/json/v1/comments?*', digestComments);
+fetchMock.post('/json/v1/comments/add', { id: 'new' });
+fetchMock.post('glob:/json/v1/comments/del/*', { deleted: 'true' });
+
+// Now that the mock RPCs are set up, create the element.
+const ele = document.createElement('comments-sk') as CommentsSk;
+ele.digest = 'a01a01a01a01a01a01a01a01a01a01a0';
+$$('#container')!.appendChild(ele);
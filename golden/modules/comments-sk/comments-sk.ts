@@ -0,0 +1,171 @@
+/**
+ * @module module/comments-sk
+ * @description <h2><code>comments-sk</code></h2>
+ *
+ * Shows the comments users left about either a digest or a changelist, and lets editors add new
+ * ones. Set either the digest property or both the crs and changelistID properties.
+ */
+import { html } from 'lit-html';
+import { define } from '../../../elements-sk/modules/define';
+import { jsonOrThrow } from '../../../infra-sk/modules/jsonOrThrow';
+import { ElementSk } from '../../../infra-sk/modules/ElementSk';
+import { sendBeginTask, sendEndTask, sendFetchError } from '../common';
+import {
+  AddCommentRequest,
+  Comment,
+  CommentsResponse,
+  Digest,
+} from '../rpc_types';
+
+export class CommentsSk extends ElementSk {
+  private static template = (ele: CommentsSk) => html`
+    <div class="comments">
+      ${ele.comments.map((c) => CommentsSk.commentTemplate(ele, c))}
+    </div>
+    <div class="new_comment" ?hidden=${ele._readOnly}>
+      <textarea
+        placeholder="Leave a note for other reviewers"
+        maxlength="4096"></textarea>
+      <button class="add" @click=${ele.addComment}>Comment</button>
+    </div>
+  `;
+
+  private static commentTemplate = (ele: CommentsSk, c: Comment) => html`
+    <div class="comment">
+      <div class="header">
+        <span class="author">${c.created_by}</span>
+        <span class="created">${new Date(c.created).toLocaleString()}</span>
+        <button
+          class="delete"
+          ?hidden=${ele._readOnly}
+          @click=${() => ele.deleteComment(c)}>
+          Delete
+        </button>
+      </div>
+      <div class="text">${c.text}</div>
+    </div>
+  `;
+
+  private _digest: Digest = '';
+
+  private _crs = '';
+
+  private _changelistID = '';
+
+  private _readOnly = false;
+
+  private comments: Comment[] = [];
+
+  constructor() {
+    super(CommentsSk.template);
+  }
+
+  connectedCallback(): void {
+    super.connectedCallback();
+    this._render();
+    this.fetch();
+  }
+
+  /** The digest whose comments are shown. */
+  get digest(): Digest {
+    return this._digest;
+  }
+
+  set digest(d: Digest) {
+    this._digest = d;
+    this.fetch();
+  }
+
+  /** The code review system of the changelist whose comments are shown. */
+  get crs(): string {
+    return this._crs;
+  }
+
+  set crs(crs: string) {
+    this._crs = crs;
+    this.fetch();
+  }
+
+  /** The changelist whose comments are shown. */
+  get changelistID(): string {
+    return this._changelistID;
+  }
+
+  set changelistID(id: string) {
+    this._changelistID = id;
+    this.fetch();
+  }
+
+  /** Hides the controls to add and delete comments if true. */
+  get readOnly(): boolean {
+    return this._readOnly;
+  }
+
+  set readOnly(r: boolean) {
+    this._readOnly = r;
+    this._render();
+  }
+
+  private queryString(): string {
+    if (this._crs && this._changelistID) {
+      return `crs=${encodeURIComponent(this._crs)}&changelist_id=${encodeURIComponent(
+        this._changelistID
+      )}`;
+    }
+    return `digest=${encodeURIComponent(this._digest)}`;
+  }
+
+  private fetch() {
+    if (!this._connected || (!this._digest && !this._changelistID)) {
+      return;
+    }
+    sendBeginTask(this);
+    fetch(`/json/v1/comments?${this.queryString()}`)
+      .then(jsonOrThrow)
+      .then((resp: CommentsResponse) => {
+        this.comments = resp.comments;
+        this._render();
+        sendEndTask(this);
+      })
+      .catch((e) => sendFetchError(this, e, 'comments'));
+  }
+
+  private addComment() {
+    const textarea = this.querySelector<HTMLTextAreaElement>('textarea')!;
+    const req: AddCommentRequest = { text: textarea.value };
+    if (this._crs && this._changelistID) {
+      req.crs = this._crs;
+      req.changelist_id = this._changelistID;
+    } else {
+      req.digest = this._digest;
+    }
+    sendBeginTask(this);
+    fetch('/json/v1/comments/add', {
+      method: 'POST',
+      body: JSON.stringify(req),
+      headers: {
+        'Content-Type': 'application/json',
+      },
+    })
+      .then(jsonOrThrow)
+      .then(() => {
+        textarea.value = '';
+        sendEndTask(this);
+        this.fetch();
+      })
+      .catch((e) => sendFetchError(this, e, 'adding comment'));
+  }
+
+  private deleteComment(c: Comment) {
+    sendBeginTask(this);
+    fetch(`/json/v1/comments/del/${c.id}`, { method: 'POST' })
+      .then(jsonOrThrow)
+      .then(() => {
+        sendEndTask(this);
+        this.fetch();
+      })
+      .catch((e) => sendFetchError(this, e, 'deleting comment'));
+  }
+}
+
+define('comments-sk', CommentsSk);
@@ -0,0 +1,73 @@
+import './index';
+
+import fetchMock from 'fetch-mock';
+import { expect } from 'chai';
+import { $, $$ } from '../../../infra-sk/modules/dom';
+import {
+  eventPromise,
+  setUpElementUnderTest,
+} from '../../../infra-sk/modules/test_util';
+import { CommentsSk } from './comments-sk';
+import { digestComments } from './test_data';
+
+describe('comments-sk', () => {
+  const newInstance = setUpElementUnderTest<CommentsSk>('comments-sk');
+
+  let commentsSk: CommentsSk;
+
+  afterEach(() => {
+    expect(fetchMock.done()).to.be.true; // All mock RPCs called at least once.
+    fetchMock.reset();
+  });
+
+  it('shows the comments about a digest', async () => {
+    fetchMock.get(
+      '/json/v1/comments?digest=a01a01a01a01a01a01a01a01a01a01a0',
+      digestComments
+    );
+    const event = eventPromise('end-task');
+    commentsSk = newInstance((ele) => {
+      ele.digest = 'a01a01a01a01a01a01a01a01a01a01a0';
+    });
+    await event;
+
+    const authors = $('.comment .author', commentsSk).map(
+      (e) => (e as HTMLElement).innerText
+    );
+    expect(authors).to.deep.equal(['alpha@example.com', 'beta@example.com']);
+  });
+
+  it('adds a comment about a changelist', async () => {
+    fetchMock.get('/json/v1/comments?crs=gerrit&changelist_id=1234', {
+      comments: [],
+    });
+    let event = eventPromise('end-task');
+    commentsSk = newInstance((ele) => {
+      ele.crs = 'gerrit';
+      ele.changelistID = '1234';
+    });
+    await event;
+
+    fetchMock.post('/json/v1/comments/add', (_, opts) => {
+      expect(opts.body).to.equal(
+        '{"text":"LGTM","crs":"gerrit","changelist_id":"1234"}'
+      );
+      return { id: 'abc' };
+    });
+    $$<HTMLTextAreaElement>('textarea', commentsSk)!.value = 'LGTM';
+    event = eventPromise('end-task');
+    $$<HTMLButtonElement>('button.add', commentsSk)!.click();
+    await event;
+  });
+
+  it('hides the controls when read only', async () => {
+    fetchMock.get('glob:/json/v1/comments?*', digestComments);
+    const event = eventPromise('end-task');
+    commentsSk = newInstance((ele) => {
+      ele.digest = 'a01a01a01a01a01a01a01a01a01a01a0';
+      ele.readOnly = true;
+    });
+    await event;
+    expect($$<HTMLElement>('.new_comment', commentsSk)!.hidden).to.be.true;
+  });
+});
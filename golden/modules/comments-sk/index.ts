@@ -0,0 +1 @@
+import './comments-sk';
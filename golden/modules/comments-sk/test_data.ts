@@ -0,0 +1,20 @@
+import { CommentsResponse } from '../rpc_types';
+
+export const digestComments: CommentsResponse = {
+  comments: [
+    {
+      id: '0b2c3e6a-7d1f-4c2e-9a55-4c1d8d1e9f00',
+      digest: 'a01a01a01a01a01a01a01a01a01a01a0',
+      created_by: 'alpha@example.com',
+      created: '2022-03-01T12:30:00Z',
+      text: 'This is the reference rendering for the new text shaper.',
+    },
+    {
+      id: '5f7e9b1c-2a3d-4e5f-8a9b-0c1d2e3f4a5b',
+      digest: 'a01a01a01a01a01a01a01a01a01a01a0',
+      created_by: 'beta@example.com',
+      created: '2022-03-02T08:00:00Z',
+      text: 'The antialiasing on the right edge looks off.\nFiled a bug.',
+    },
+  ],
+};
@@ -1,6 +1,7 @@
 import { HintableObject } from '../../infra-sk/modules/hintable';
 import { fromObject } from '../../infra-sk/modules/query';
 import { Params } from './rpc_types';
+import { imageURLParams, immutableImageURLs } from './settings';
 import {
   SearchCriteria,
   SearchCriteriaToHintableObject,
@@ -21,6 +22,22 @@ export function humanReadableQuery(queryStr: string): string {
 
 const imagePrefix = '/img/images';
 const diffPrefix = '/img/diffs';
+// Images under this prefix are content-addressed, so they can be cached forever, e.g. by a CDN.
+const immutablePrefix = '/img/immutable';
+
+/**
+ * Returns the path to the image with the given name, i.e. either "images/<digest>.png" or
+ * "diffs/<left>-<right>.png". If the instance is configured to use immutable image URLs, those
+ * (and their signature, if any) are used instead.
+ */
+function imagePath(legacyPrefix: string, name: string): string {
+  if (!immutableImageURLs()) {
+    return `${legacyPrefix}/${name}`;
+  }
+  const params = imageURLParams();
+  const dir = legacyPrefix.substring('/img'.length);
+  return `${immutablePrefix}${dir}/${name}${params ? `?${params}` : ''}`;
+}
 
 /**
  * Returns a link to the PNG image associated with the given digest.
@@ -32,7 +49,7 @@ export function digestImagePath(digest: string): string {
     return '';
   }
 
-  return `${imagePrefix}/${digest}.png`;
+  return imagePath(imagePrefix, `${digest}.png`);
 }
 
 /** Returns a link to the PNG image associated with the diff between the given digests. */
@@ -43,7 +60,7 @@ export function digestDiffImagePath(d1: string, d2: string): string {
   // We have a canonical diff order where we sort the two digests alphabetically then join them
   // in order.
   const order = d1 < d2 ? `${d1}-${d2}` : `${d2}-${d1}`;
-  return `${diffPrefix}/${order}.png`;
+  return imagePath(diffPrefix, `${order}.png`);
 }
 
 /**
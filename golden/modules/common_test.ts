@@ -13,6 +13,7 @@ import {
 } from './common';
 import { eventPromise } from '../../infra-sk/modules/test_util';
 import { SearchCriteria } from './search-controls-sk/search-controls-sk';
+import { testOnlySetSettings } from './settings';
 
 describe('humanReadableQuery', () => {
   it('turns url encoded queries into human readable version', () => {
@@ -53,6 +54,32 @@ describe('digestDiffImagePath', () => {
   });
 });
 
+describe('immutable image paths', () => {
+  afterEach(() => {
+    testOnlySetSettings({});
+  });
+
+  it('returns content-addressed paths if configured', () => {
+    testOnlySetSettings({ immutableImageURLs: true });
+    expect(digestImagePath(aDigest)).to.equal(
+      '/img/immutable/images/aaab78c9711cb79197d47f448ba51338.png'
+    );
+    expect(digestDiffImagePath(bDigest, aDigest)).to.equal(
+      '/img/immutable/diffs/aaab78c9711cb79197d47f448ba51338-bbb8b07beb4e1247c2cbafdb92b93e55.png'
+    );
+  });
+
+  it('appends the signature if there is one', () => {
+    testOnlySetSettings({
+      immutableImageURLs: true,
+      imageURLParams: 'expires=1614837967&sig=abc',
+    });
+    expect(digestImagePath(aDigest)).to.equal(
+      '/img/immutable/images/aaab78c9711cb79197d47f448ba51338.png?expires=1614837967&sig=abc'
+    );
+  });
+});
+
 describe('detailHref', () => {
   it('returns a path with and without an changelist id', () => {
     expect(
@@ -126,3 +126,15 @@ fetchMock.post(
     return mro;
   }, 300)
 );
+
+fetchMock.get('glob:/json/v1/comments?*', {
+  comments: [
+    {
+      id: 'abc',
+      digest: 'a01a01a01a01a01a01a01a01a01a01a0',
+      created_by: 'alpha@example.com',
+      created: '2022-03-01T12:30:00Z',
+      text: 'This is the reference rendering.',
+    },
+  ],
+});
@@ -11,6 +11,10 @@
  * @evt begin-task/end-task - when a POST request is in flight to handle triaging.
  * @evt triage - Emitted when the user triages the digest. e.detail contains the assigned Label.
  *
+ * If the instance is configured with a variant key (e.g. the scale images are rendered at), the
+ * digests drawn by the other variants of the traces are shown next to the comparison and can
+ * optionally be triaged along with this digest.
+ *
  *   Children elements emit the following events of note:
  * @evt show-commits - Event generated when a trace dot is clicked. e.detail contains
  *   the blamelist (an array of commits that could have made up that dot).
@@ -27,16 +31,23 @@ import {
   clusterPageHref,
   detailHref,
   diffPageHref,
+  digestImagePath,
   sendBeginTask,
   sendEndTask,
   sendFetchError,
 } from '../common';
-import { customTriagingDisallowedMsg } from '../settings';
+import {
+  customTriagingDisallowedMsg,
+  isReadOnlyMirror,
+  variantKey,
+} from '../settings';
 
+import '../../../elements-sk/modules/checkbox-sk';
 import '../../../elements-sk/modules/icons/group-work-icon-sk';
 import '../dots-sk';
 import '../dots-legend-sk';
 import '../triage-sk';
+import '../comments-sk';
 import '../image-compare-sk';
 import '../blamelist-panel-sk';
 import '../../../infra-sk/modules/paramset-sk';
@@ -118,10 +129,13 @@ export class DigestDetailsSk extends ElementSk {
               class="negative_warning">
               Closest image is negative!
             </div>
-            <!-- TODO(kjlubick) Comments would go here -->
+            <comments-sk
+              .digest=${ele._details.digest}
+              .readOnly=${isReadOnlyMirror()}></comments-sk>
           </div>
         </div>
       </div>
+      ${DigestDetailsSk.variantsTemplate(ele)}
       ${DigestDetailsSk.traceInfoTemplate(ele)}
       ${DigestDetailsSk.paramsetTemplate(ele)}
     </div>
@@ -171,7 +185,7 @@ export class DigestDetailsSk extends ElementSk {
           <triage-sk
             @change=${ele.triageChangeHandler}
             .value=${ele._details.status}
-            .readOnly=${disallowTriaging}>
+            .readOnly=${disallowTriaging || isReadOnlyMirror()}>
           </triage-sk>
           ${DigestDetailsSk.triageHistoryTemplate(ele)}
           ${disallowTriagingMessage}
@@ -238,7 +252,7 @@ export class DigestDetailsSk extends ElementSk {
         <triage-sk
           @change=${ele.triageChangeHandler}
           .value=${ele._details.status}
-          .readOnly=${disallowTriaging}>
+          .readOnly=${disallowTriaging || isReadOnlyMirror()}>
         </triage-sk>
         ${DigestDetailsSk.triageHistoryTemplate(ele)} ${disallowTriagingMessage}
       </div>
@@ -327,6 +341,54 @@ export class DigestDetailsSk extends ElementSk {
     `;
   };
 
+  private static variantsTemplate = (ele: DigestDetailsSk) => {
+    if (!ele._details.variants?.length) {
+      return '';
+    }
+    let maybeGrouping: Params | null = null;
+    try {
+      maybeGrouping = ele.getGrouping();
+    } catch {
+      // Nothing to do.
+    }
+    // Triaging variants together is only supported on the primary branch.
+    const canTriageVariants = !ele._changeListID && !isReadOnlyMirror();
+    return html`
+      <div class="variants">
+        <div class="variants_header">
+          <span class="variants_title">
+            Other ${variantKey() || 'variant'}s of these traces
+          </span>
+          <checkbox-sk
+            class="include_variants"
+            label="Triage these along with this digest"
+            ?hidden=${!canTriageVariants}
+            ?checked=${ele.includeVariants}
+            @change=${ele.toggleIncludeVariants}>
+          </checkbox-sk>
+        </div>
+        <div class="variant_digests">
+          ${ele._details.variants.map(
+            (v) => html`
+              <a
+                class="variant"
+                href=${maybeGrouping ? detailHref(maybeGrouping, v.digest) : ''}
+                target="_blank"
+                rel="noopener"
+                title=${v.digest}>
+                <img
+                  src=${digestImagePath(v.digest)}
+                  alt="Digest ${v.digest}" />
+                <span class="variant_label">${v.variant}</span>
+                <span class="variant_status ${v.status}">${v.status}</span>
+              </a>
+            `
+          )}
+        </div>
+      </div>
+    `;
+  };
+
   private static traceInfoTemplate = (ele: DigestDetailsSk) => {
     if (
       !ele._details.traces ||
@@ -429,6 +491,9 @@ export class DigestDetailsSk extends ElementSk {
 
   private _fullSizeImages = false;
 
+  // If true, triaging this digest also triages the digests drawn by its variants.
+  private includeVariants = false;
+
   constructor() {
     super(DigestDetailsSk.template);
   }
@@ -572,6 +637,11 @@ export class DigestDetailsSk extends ElementSk {
     this._render();
   }
 
+  private toggleIncludeVariants(e: Event) {
+    this.includeVariants = (e.target as HTMLInputElement).checked;
+    this._render();
+  }
+
   private triageChangeHandler(e: CustomEvent<Label>) {
     e.stopPropagation();
     const newLabel = e.detail;
@@ -646,6 +716,13 @@ export class DigestDetailsSk extends ElementSk {
       triageRequest.changelist_id = this._changeListID;
       triageRequest.crs = this._crs;
     }
+    const includeVariants =
+      this.includeVariants &&
+      !triageRequest.changelist_id &&
+      !!this._details.variants?.length;
+    if (includeVariants) {
+      triageRequest.include_variants = true;
+    }
 
     const restorePreviousStatusInUI = () => {
       this.querySelector<TriageSk>('triage-sk')!.value = labelBefore;
@@ -667,6 +744,11 @@ export class DigestDetailsSk extends ElementSk {
           if (triageResponse.status === 'ok') {
             // Triaging was successful.
             this._details.status = label;
+            if (includeVariants) {
+              this._details.variants!.forEach((v) => {
+                v.status = label;
+              });
+            }
             this._details.triage_history ||= [];
             this._details.triage_history!.unshift({
               user: 'me',
@@ -65,6 +65,14 @@ export class DigestDetailsSkPO extends PageObject {
     return this.bySelector('.negative_warning');
   }
 
+  private get variantLabels(): PageObjectElementList {
+    return this.bySelectorAll('.variants .variant');
+  }
+
+  private get includeVariantsCheckbox(): PageObjectElement {
+    return this.bySelector('.variants checkbox-sk.include_variants');
+  }
+
   private get zoomDialog(): PageObjectElement {
     return this.bySelector('dialog.zoom_dialog');
   }
@@ -120,6 +128,19 @@ export class DigestDetailsSkPO extends PageObject {
     return !(await this.closestImageIsNegativeWarning.hasAttribute('hidden'));
   }
 
+  /** Returns the variants as "<variant> <status>" strings. */
+  getVariants(): Promise<string[]> {
+    return this.variantLabels.map(async (v) => {
+      const label = await v.bySelector('.variant_label').innerText;
+      const status = await v.bySelector('.variant_status').innerText;
+      return `${label} ${status}`;
+    });
+  }
+
+  async clickIncludeVariantsCheckbox() {
+    await this.includeVariantsCheckbox.click();
+  }
+
   isZoomDialogOpen() {
     return this.zoomDialog.hasAttribute('open');
   }
@@ -12,6 +12,7 @@ import {
   twoHundredCommits,
   typicalDetails,
   typicalDetailsDisallowTriaging,
+  typicalDetailsWithVariants,
 } from './test_data';
 import { DigestDetailsSk } from './digest-details-sk';
 import { DigestDetailsSkPO } from './digest-details-sk_po';
@@ -236,6 +237,58 @@ describe('digest-details-sk', () => {
     });
   });
 
+  describe('layout with variants', () => {
+    beforeEach(() => {
+      digestDetailsSk.groupings = deepCopy(groupingsResponse);
+      digestDetailsSk.details = deepCopy(typicalDetailsWithVariants);
+      digestDetailsSk.commits = deepCopy(twoHundredCommits);
+    });
+
+    afterEach(() => {
+      expect(fetchMock.done()).to.be.true; // All mock RPCs called at least once.
+      fetchMock.reset();
+    });
+
+    it('shows the variants', async () => {
+      expect(await digestDetailsSkPO.getVariants()).to.deep.equal([
+        '2x positive',
+        '2x untriaged',
+      ]);
+    });
+
+    it('triages the variants too if requested', async () => {
+      const triageRequest: TriageRequestV3 = {
+        deltas: [
+          {
+            grouping: {
+              source_type: 'infra',
+              name: 'dots-legend-sk_too-many-digests',
+            },
+            digest: '6246b773851984c726cb2e1cb13510c2',
+            label_before: 'positive',
+            label_after: 'negative',
+          },
+        ],
+        include_variants: true,
+      };
+      const triageResponse: TriageResponse = { status: 'ok' };
+      fetchMock.post(
+        { url: '/json/v3/triage', body: triageRequest },
+        { status: 200, body: triageResponse }
+      );
+
+      await digestDetailsSkPO.clickIncludeVariantsCheckbox();
+      const endPromise = eventPromise('end-task');
+      await digestDetailsSkPO.triageSkPO.clickButton('negative');
+      await endPromise;
+
+      expect(await digestDetailsSkPO.getVariants()).to.deep.equal([
+        '2x negative',
+        '2x negative',
+      ]);
+    });
+  });
+
   describe('layout with changelist id, positive and negative references', () => {
     beforeEach(() => {
       digestDetailsSk.groupings = deepCopy(groupingsResponse);
@@ -129,6 +129,22 @@ const disallowTriaging = (searchResult: SearchResult): SearchResult => {
 
 export const typicalDetailsDisallowTriaging = disallowTriaging(typicalDetails);
 
+export const typicalDetailsWithVariants: SearchResult = {
+  ...deepCopy(typicalDetails),
+  variants: [
+    {
+      variant: '2x',
+      digest: '99c58c7002073346ff55f446d47d6311',
+      status: 'positive',
+    },
+    {
+      variant: '2x',
+      digest: 'ec3b8f27397d99581e06eaa46d6d5837',
+      status: 'untriaged',
+    },
+  ],
+};
+
 export const negativeOnly: SearchResult = {
   test: 'dots-legend-sk_too-many-digests',
   digest: '6246b773851984c726cb2e1cb13510c2',
@@ -58,12 +58,30 @@ export interface SRDiffDigest {
 	combinedMetric: number;
 	pixelDiffPercent: number;
 	maxRGBADiffs: number[];
+	ssim?: number | null;
+	deltaE?: number | null;
+	channelWeightedMetric?: number | null;
 	dimDiffer: boolean;
 	digest: Digest;
 	status: Label;
 	paramset: ParamSet;
 }
 
+export interface TriageSuggestion {
+	label: Label;
+	closestDigest: Digest;
+	combinedMetric: number;
+	numDiffPixels: number;
+	pixelDiffPercent: number;
+	maxRGBADiffs: number[];
+}
+
+export interface DigestVariant {
+	variant: string;
+	digest: Digest;
+	status: Label;
+}
+
 export interface SearchResult {
 	digest: Digest;
 	test: TestName;
@@ -73,6 +91,8 @@ export interface SearchResult {
 	traces: TraceGroup;
 	refDiffs: { [key: string]: SRDiffDigest | null } | null;
 	closestRef: RefClosest;
+	suggestion?: TriageSuggestion | null;
+	variants?: DigestVariant[] | null;
 }
 
 export interface Commit {
@@ -119,6 +139,7 @@ export interface TriageRequestV3 {
 	changelist_id?: string;
 	crs?: string;
 	image_matching_algorithm?: string;
+	include_variants?: boolean;
 }
 
 export interface TriageConflict {
@@ -136,6 +157,8 @@ export interface TriageResponse {
 export interface GUICorpusStatus {
 	name: string;
 	untriagedCount: number;
+	oldestUntriagedAgeSeconds?: number;
+	triagedLast24h?: number;
 }
 
 export interface StatusResponse {
@@ -172,13 +195,35 @@ export interface TriageLogEntry {
 	details: TriageDelta[];
 }
 
+export interface Comment {
+	id: string;
+	digest?: Digest;
+	crs?: string;
+	changelist_id?: string;
+	created_by: string;
+	created: string;
+	text: string;
+}
+
 export interface TriageLogResponse {
 	entries: TriageLogEntry[];
+	comments: Comment[];
 	offset: number;
 	size: number;
 	total: number;
 }
 
+export interface CommentsResponse {
+	comments: Comment[];
+}
+
+export interface AddCommentRequest {
+	digest?: Digest;
+	crs?: string;
+	changelist_id?: string;
+	text: string;
+}
+
 export interface ChangelistsResponse {
 	changelists: Changelist[] | null;
 	offset: number;
@@ -246,6 +291,87 @@ export interface DigestDetails {
 	commits: Commit[] | null;
 }
 
+export interface DetailsBulkEntry {
+	grouping: Params;
+	digest: Digest;
+}
+
+export interface DetailsBulkRequest {
+	entries: DetailsBulkEntry[] | null;
+	changelist_id?: string;
+	crs?: string;
+}
+
+export interface DetailsBulkResult {
+	grouping: Params;
+	digest: Digest;
+	details?: DigestDetails | null;
+	error?: string;
+}
+
+export interface DetailsBulkResponse {
+	results: DetailsBulkResult[] | null;
+}
+
+export interface SimilarDigest {
+	digest: Digest;
+	distance: number;
+	grouping: Params;
+	status: Label;
+}
+
+export interface SimilarDigestsResponse {
+	digest: Digest;
+	results: SimilarDigest[] | null;
+}
+
+export interface IgnoreRuleStats {
+	id: string;
+	query: string;
+	owner: string;
+	expires: string;
+	expired: boolean;
+	matched_traces: number;
+	exclusive_traces: number;
+}
+
+export interface IgnoreStatsResponse {
+	rules: IgnoreRuleStats[] | null;
+	num_unused: number;
+	num_expired: number;
+}
+
+export interface FlakyTest {
+	grouping: Params;
+	test: TestName;
+	flaky_traces: number;
+	max_digests: number;
+}
+
+export interface FlakyCorpus {
+	corpus: string;
+	tests: FlakyTest[] | null;
+}
+
+export interface FlakyTestsResponse {
+	corpora: FlakyCorpus[] | null;
+}
+
+export interface FacetValue {
+	value: string;
+	count: number;
+}
+
+export interface SearchFacet {
+	key: string;
+	values: FacetValue[] | null;
+}
+
+export interface SearchFacetsResponse {
+	total: number;
+	facets: SearchFacet[] | null;
+}
+
 export interface ClusterDiffNode {
 	name: Digest;
 	status: Label;
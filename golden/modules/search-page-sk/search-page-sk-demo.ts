@@ -35,6 +35,7 @@ Date.now = () => fakeNow;
 fetchMock.get('/json/v2/trstatus', statusResponse);
 fetchMock.get('/json/v2/paramset', paramSetResponse!);
 fetchMock.get('/json/v1/groupings', groupingsResponse);
+fetchMock.get('glob:/json/v1/comments?*', { comments: [] });
 fetchMock.get('/json/v2/changelist/gerrit/123456', changeListSummaryResponse);
 
 // We simulate the search endpoint, but only take into account the negative/positive/untriaged
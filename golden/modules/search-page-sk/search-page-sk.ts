@@ -22,7 +22,7 @@ import {
   SearchCriteriaToHintableObject,
 } from '../search-controls-sk/search-controls-sk';
 import { sendBeginTask, sendEndTask, sendFetchError } from '../common';
-import { defaultCorpus } from '../settings';
+import { defaultCorpus, isReadOnlyMirror } from '../settings';
 import {
   ChangelistSummaryResponse,
   GroupingsResponse,
@@ -37,6 +37,7 @@ import '../../../elements-sk/modules/checkbox-sk';
 import '../bulk-triage-sk';
 import '../search-controls-sk';
 import '../changelist-controls-sk';
+import '../comments-sk';
 import '../digest-details-sk';
 import '../pagination-sk';
 import { DigestDetailsSk } from '../digest-details-sk/digest-details-sk';
@@ -123,6 +124,14 @@ export class SearchPageSk extends ElementSk {
         @cl-control-change=${el.onChangelistControlsChange}>
       </changelist-controls-sk>
 
+      ${el.crs && el.changelistId
+        ? html`<comments-sk
+            class="changelist_comments"
+            .crs=${el.crs}
+            .changelistID=${el.changelistId}
+            .readOnly=${isReadOnlyMirror()}></comments-sk>`
+        : ''}
+
       <p class="summary">${SearchPageSk.summary(el)}</p>
 
       ${SearchPageSk.paginationTemplate(el, 'top')}
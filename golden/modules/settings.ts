@@ -11,6 +11,10 @@ export interface GoldSettings {
   defaultCorpus?: string;
   baseRepoURL?: string;
   customTriagingDisallowedMsg?: string;
+  isReadOnlyMirror?: boolean;
+  immutableImageURLs?: boolean;
+  imageURLParams?: string;
+  variantKey?: string;
 }
 
 function getSettings(): GoldSettings | undefined {
@@ -33,6 +37,22 @@ export function customTriagingDisallowedMsg(): string {
   return getSettings()?.customTriagingDisallowedMsg || '';
 }
 
+export function isReadOnlyMirror(): boolean {
+  return getSettings()?.isReadOnlyMirror || false;
+}
+
+export function immutableImageURLs(): boolean {
+  return getSettings()?.immutableImageURLs || false;
+}
+
+export function imageURLParams(): string {
+  return getSettings()?.imageURLParams || '';
+}
+
+export function variantKey(): string {
+  return getSettings()?.variantKey || '';
+}
+
 export function testOnlySetSettings(newSettings: GoldSettings) {
   (window as any).GoldSettings = newSettings;
 }
@@ -3,7 +3,8 @@
  * @description <h2><code>triagelog-page-sk</code></h2>
  *
  * Allows the user to page through the diff triage logs, and optionally undo
- * labels applied to triaged diffs.
+ * labels applied to triaged diffs. Comments about the changelist and about the
+ * triaged digests are shown alongside the log entries.
  */
 
 import { html } from 'lit-html';
@@ -19,11 +20,17 @@ import {
   sendEndTask,
   sendFetchError,
 } from '../common';
-import { TriageDelta, TriageLogEntry, TriageLogResponse } from '../rpc_types';
+import {
+  Comment,
+  TriageDelta,
+  TriageLogEntry,
+  TriageLogResponse,
+} from '../rpc_types';
 import { PaginationSkPageChangedEventDetail } from '../pagination-sk/pagination-sk';
 
 export class TriagelogPageSk extends ElementSk {
   private static template = (el: TriagelogPageSk) => html`
+    ${TriagelogPageSk.changelistCommentsTemplate(el)}
     <table>
       <thead>
         <tr>
@@ -48,6 +55,23 @@ export class TriagelogPageSk extends ElementSk {
     </pagination-sk>
   `;
 
+  private static changelistCommentsTemplate = (el: TriagelogPageSk) => {
+    const comments = el.comments.filter((c) => !c.digest);
+    if (!comments.length) {
+      return html``;
+    }
+    return html`
+      <div class="changelist-comments">
+        <strong>Comments on this changelist</strong>
+        ${comments.map(
+          (c) => html`<div class="comment">
+            ${c.created_by}: <span class="comment-text">${c.text}</span>
+          </div>`
+        )}
+      </div>
+    `;
+  };
+
   private static logEntryTemplate = (
     el: TriagelogPageSk,
     entry: TriageLogEntry
@@ -110,10 +134,24 @@ export class TriagelogPageSk extends ElementSk {
         ${delta.label_after}
       </td>
     </tr>
+    ${el.comments
+      .filter((c) => c.digest === delta.digest)
+      .map(
+        (c) => html`
+          <tr class="details comment">
+            <td></td>
+            <td colspan="3">
+              ${c.created_by}: <span class="comment-text">${c.text}</span>
+            </td>
+          </tr>
+        `
+      )}
   `;
 
   private entries: TriageLogEntry[] = []; // Log entries fetched from the server.
 
+  private comments: Comment[] = []; // Comments about the CL and the digests in entries.
+
   private pageOffset = 0; // Reflected in the URL.
 
   private pageSize = 0; // Reflected in the URL.
@@ -216,6 +254,7 @@ export class TriagelogPageSk extends ElementSk {
       .then(jsonOrThrow)
       .then((response: TriageLogResponse) => {
         this.entries = response.entries || [];
+        this.comments = response.comments || [];
         this.pageOffset = response.offset || 0;
         this.pageSize = response.size || 0;
         this.totalEntries = response.total || 0;
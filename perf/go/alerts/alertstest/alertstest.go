@@ -77,6 +77,43 @@ func Store_SaveWithID(t *testing.T, a alerts.Store) {
 	assert.Equal(t, cfg, cfgs[0])
 }
 
+// TemplateStore_SaveListDelete tests that an alerts.TemplateStore instance
+// operates as expected.
+func TemplateStore_SaveListDelete(t *testing.T, s alerts.TemplateStore) {
+	ctx := context.Background()
+
+	tmpl := alerts.NewTemplate()
+	tmpl.Name = "per benchmark"
+	tmpl.Alert.Query = "benchmark={{benchmark}}"
+	tmpl.Variables = map[string][]string{"benchmark": {"speedometer", "jetstream"}}
+	require.NoError(t, s.Save(ctx, tmpl))
+	require.NotEqual(t, alerts.BadAlertIDAsAsString, tmpl.IDAsString)
+
+	// Update it.
+	tmpl.Variables["benchmark"] = append(tmpl.Variables["benchmark"], "motionmark")
+	require.NoError(t, s.Save(ctx, tmpl))
+
+	// Store a second template.
+	other := alerts.NewTemplate()
+	other.Name = "another"
+	other.Alert.Query = "config={{config}}"
+	other.Variables = map[string][]string{"config": {"8888"}}
+	require.NoError(t, s.Save(ctx, other))
+
+	// Confirm both are listed, ordered by name.
+	templates, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, other, templates[0])
+	assert.Equal(t, tmpl, templates[1])
+
+	// Delete one.
+	require.NoError(t, s.Delete(ctx, int(other.IDAsStringToInt())))
+	templates, err = s.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*alerts.Template{tmpl}, templates)
+}
+
 // SubTestFunction is a func we will call to test one aspect of an
 // implementation of regression.Store.
 type SubTestFunction func(t *testing.T, store alerts.Store)
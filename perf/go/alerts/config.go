@@ -124,7 +124,9 @@ type Alert struct {
 	IDAsString            string                            `json:"id_as_string"    `
 	DisplayName           string                            `json:"display_name"    `
 	Query                 string                            `json:"query"           `                       // The query to perform on the trace store to select the traces to alert on.
+	DerivedMetric         string                            `json:"derived_metric,omitempty"`               // The name of a derived metric to detect regressions in, instead of the traces that match Query.
 	Alert                 string                            `json:"alert"           `                       // Email address to send alerts to.
+	Chat                  string                            `json:"chat,omitempty"`                         // The name of the chat channel, from NotifyConfig.ChatChannels, to send alerts to.
 	IssueTrackerComponent SerializesToString                `json:"issue_tracker_component" go2ts:"string"` // The issue tracker component to send alerts to.
 	Interesting           float32                           `json:"interesting"     `                       // The regression interestingness threshold.
 	BugURITemplate        string                            `json:"bug_uri_template"`                       // URI Template used for reporting bugs. Format TBD.
@@ -149,8 +151,26 @@ type Alert struct {
 	MinimumNum        int       `json:"minimum_num"` // How many traces need to be found interesting before an alert is fired.
 	Category          string    `json:"category"   ` // Which category this alert falls into.
 
+	// MinTraces is the minimum number of matched traces that must have data at a
+	// commit for that commit to be checked for regressions. 0 means no minimum.
+	MinTraces int `json:"min_traces,omitempty"`
+
+	// MinDataFraction is the minimum fraction, in [0, 1], of the matched traces
+	// that must have data at a commit for that commit to be checked for
+	// regressions. This avoids false steps caused by partially uploaded data.
+	// 0 means no minimum.
+	MinDataFraction float32 `json:"min_data_fraction,omitempty"`
+
 	// Action to take for this alert. It could be none, report or bisect.
 	Action types.AlertAction `json:"action,omitempty"` // What action should be taken by the detected anomalies.
+
+	// TemplateID is the ID of the Template this Alert was expanded from, if any.
+	// Such Alerts should be changed by changing the Template.
+	TemplateID string `json:"template_id,omitempty"`
+
+	// TemplateValues are the values of the Template variables this Alert was
+	// expanded with.
+	TemplateValues map[string]string `json:"template_values,omitempty"`
 }
 
 type AlertsStatus struct {
@@ -302,6 +322,15 @@ func (c *Alert) Validate() error {
 			}
 		}
 	}
+	if c.DerivedMetric != "" && c.GroupBy != "" {
+		return fmt.Errorf("Invalid Config: Group By can't be used with the derived metric %q", c.DerivedMetric)
+	}
+	if c.MinTraces < 0 {
+		return fmt.Errorf("Invalid Config: MinTraces must not be negative: %d", c.MinTraces)
+	}
+	if c.MinDataFraction < 0 || c.MinDataFraction > 1 {
+		return fmt.Errorf("Invalid Config: MinDataFraction must be in [0, 1]: %g", c.MinDataFraction)
+	}
 	if c.StepUpOnly {
 		c.StepUpOnly = false
 		c.DirectionAsString = UP
@@ -88,6 +88,32 @@ func TestValidate(t *testing.T) {
 	assert.Error(t, a.Validate())
 }
 
+func TestValidate_DataCompleteness(t *testing.T) {
+	a := NewConfig()
+	a.MinTraces = 10
+	a.MinDataFraction = 0.5
+	assert.NoError(t, a.Validate())
+
+	a.MinTraces = -1
+	assert.Error(t, a.Validate())
+
+	a.MinTraces = 0
+	a.MinDataFraction = 1.5
+	assert.Error(t, a.Validate())
+
+	a.MinDataFraction = -0.1
+	assert.Error(t, a.Validate())
+}
+
+func TestValidate_DerivedMetric(t *testing.T) {
+	a := NewConfig()
+	a.DerivedMetric = "ratio"
+	assert.NoError(t, a.Validate())
+
+	a.GroupBy = "config"
+	assert.Error(t, a.Validate())
+}
+
 func TestGroupedBy(t *testing.T) {
 	testCases := []struct {
 		value    string
@@ -18,3 +18,14 @@ type AlertSchema struct {
 	// Stored as a Unit timestamp.
 	LastModified int `sql:"last_modified INT"`
 }
+
+// AlertTemplateSchema represents the SQL schema of the AlertTemplates table.
+type AlertTemplateSchema struct {
+	ID int `sql:"id INT PRIMARY KEY DEFAULT unique_rowid()"`
+
+	// An alerts.Template serialized as JSON.
+	Template string `sql:"template TEXT"`
+
+	// Stored as a Unit timestamp.
+	LastModified int `sql:"last_modified INT"`
+}
@@ -24,6 +24,10 @@ const (
 	deleteAlert
 	listActiveAlerts
 	listAllAlerts
+	insertTemplate
+	updateTemplate
+	deleteTemplate
+	listTemplates
 )
 
 // statements holds all the raw SQL statements used.
@@ -65,6 +69,32 @@ var statements = map[statement]string{
 		FROM
 			ALERTS
 		`,
+	insertTemplate: `
+		INSERT INTO
+			AlertTemplates (template, last_modified)
+		VALUES
+			($1, $2)
+		RETURNING
+			id
+		`,
+	updateTemplate: `
+		UPSERT INTO
+			AlertTemplates (id, template, last_modified)
+		VALUES
+			($1, $2, $3)
+		`,
+	deleteTemplate: `
+		DELETE FROM
+			AlertTemplates
+		WHERE
+			id=$1
+		`,
+	listTemplates: `
+		SELECT
+			id, template
+		FROM
+			AlertTemplates
+		`,
 }
 
 // SQLAlertStore implements the alerts.Store interface.
@@ -19,3 +19,10 @@ func TestSQLAlertStore_CockroachDB(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLTemplateStore_CockroachDB(t *testing.T) {
+	db := sqltest.NewCockroachDBForTests(t, "alerttemplatestore")
+	store, err := NewTemplateStore(db)
+	require.NoError(t, err)
+	alertstest.TemplateStore_SaveListDelete(t, store)
+}
@@ -0,0 +1,92 @@
+package sqlalertstore
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/sql/pool"
+	"go.goldmine.build/perf/go/alerts"
+)
+
+// SQLTemplateStore implements the alerts.TemplateStore interface.
+type SQLTemplateStore struct {
+	// db is the database interface.
+	db pool.Pool
+}
+
+// NewTemplateStore returns a new *SQLTemplateStore.
+//
+// We presume all migrations have been run against db before this function is
+// called.
+func NewTemplateStore(db pool.Pool) (*SQLTemplateStore, error) {
+	return &SQLTemplateStore{
+		db: db,
+	}, nil
+}
+
+// Save implements the alerts.TemplateStore interface.
+func (s *SQLTemplateStore) Save(ctx context.Context, t *alerts.Template) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to serialize Template for saving with ID=%s", t.IDAsString)
+	}
+	now := time.Now().Unix()
+
+	if t.IDAsString == alerts.BadAlertIDAsAsString {
+		newID := alerts.BadAlertID
+		// Not a valid ID, so this should be an insert, not an update.
+		if err := s.db.QueryRow(ctx, statements[insertTemplate], string(b), now).Scan(&newID); err != nil {
+			return skerr.Wrapf(err, "Failed to insert template")
+		}
+		t.SetIDFromInt64(newID)
+	} else {
+		if _, err := s.db.Exec(ctx, statements[updateTemplate], t.IDAsStringToInt(), string(b), now); err != nil {
+			return skerr.Wrapf(err, "Failed to update Template with ID=%s", t.IDAsString)
+		}
+	}
+	return nil
+}
+
+// Delete implements the alerts.TemplateStore interface.
+func (s *SQLTemplateStore) Delete(ctx context.Context, id int) error {
+	if _, err := s.db.Exec(ctx, statements[deleteTemplate], id); err != nil {
+		return skerr.Wrapf(err, "Failed to delete Template with ID=%d", id)
+	}
+	return nil
+}
+
+// List implements the alerts.TemplateStore interface.
+func (s *SQLTemplateStore) List(ctx context.Context) ([]*alerts.Template, error) {
+	rows, err := s.db.Query(ctx, statements[listTemplates])
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	ret := []*alerts.Template{}
+	for rows.Next() {
+		var id int64
+		var serializedTemplate string
+		if err := rows.Scan(&id, &serializedTemplate); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		t := &alerts.Template{}
+		if err := json.Unmarshal([]byte(serializedTemplate), t); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to deserialize JSON Template.")
+		}
+		t.SetIDFromInt64(id)
+		ret = append(ret, t)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name == ret[j].Name {
+			return ret[i].IDAsString < ret[j].IDAsString
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// Confirm SQLTemplateStore implements alerts.TemplateStore.
+var _ alerts.TemplateStore = (*SQLTemplateStore)(nil)
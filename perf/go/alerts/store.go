@@ -17,3 +17,16 @@ type Store interface {
 	// response.
 	List(ctx context.Context, includeDeleted bool) ([]*Alert, error)
 }
+
+// TemplateStore is the interface used to persist Templates.
+type TemplateStore interface {
+	// Save can write a new, or update an existing, Template. New Templates will
+	// have an ID of -1. On insert the ID of the Template will be updated.
+	Save(ctx context.Context, t *Template) error
+
+	// Delete removes the Template with the given id.
+	Delete(ctx context.Context, id int) error
+
+	// List retrieves all the Templates, ordered by name.
+	List(ctx context.Context) ([]*Template, error)
+}
@@ -0,0 +1,249 @@
+package alerts
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/util"
+)
+
+// maxTemplateExpansions limits how many Alerts a single Template can expand
+// into.
+const maxTemplateExpansions = 500
+
+// placeholderRegex matches placeholders, e.g. "{{benchmark}}", in the fields of
+// a Template's Alert.
+var placeholderRegex = regexp.MustCompile(`{{\s*([a-zA-Z0-9_]*)\s*}}`)
+
+// variableNameRegex matches valid Template variable names.
+var variableNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// Template describes a family of Alerts that only differ by some values, e.g.
+// one Alert per benchmark. The string fields of Alert may contain placeholders
+// of the form {{name}}, and the Template expands into one Alert for every
+// combination of the values of Variables.
+type Template struct {
+	IDAsString string `json:"id_as_string"`
+	Name       string `json:"name"`
+
+	// Alert is the pattern for the expanded Alerts. Its ID and state are
+	// ignored.
+	Alert Alert `json:"alert"`
+
+	// Variables maps each variable name to the values it takes.
+	Variables map[string][]string `json:"variables"`
+}
+
+// NewTemplate creates a new Template properly initialized.
+func NewTemplate() *Template {
+	return &Template{
+		IDAsString: BadAlertIDAsAsString,
+		Alert:      *NewConfig(),
+		Variables:  map[string][]string{},
+	}
+}
+
+// IDAsStringToInt returns the IDAsString as an int64.
+//
+// An invalid alert id (-1) will be returned if the string can't be parsed.
+func (t *Template) IDAsStringToInt() int64 {
+	return IDAsStringToInt(t.IDAsString)
+}
+
+// SetIDFromInt64 sets the ID of the Template.
+func (t *Template) SetIDFromInt64(id int64) {
+	t.IDAsString = IDToString(id)
+}
+
+// patternFields returns pointers to the fields of a that may contain
+// placeholders. The boolean is true if values substituted into the field must
+// be escaped as query values.
+func patternFields(a *Alert) map[*string]bool {
+	return map[*string]bool{
+		&a.DisplayName:    false,
+		&a.Query:          true,
+		&a.Alert:          false,
+		&a.BugURITemplate: false,
+		&a.GroupBy:        false,
+		&a.Owner:          false,
+		&a.Category:       false,
+	}
+}
+
+// Validate returns an error if the Template is not valid.
+func (t *Template) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return skerr.Fmt("a template must have a name")
+	}
+	if len(t.Variables) == 0 {
+		return skerr.Fmt("a template must have at least one variable")
+	}
+	used := map[string]bool{}
+	for field := range patternFields(&t.Alert) {
+		for _, match := range placeholderRegex.FindAllStringSubmatch(*field, -1) {
+			if _, ok := t.Variables[match[1]]; !ok {
+				return skerr.Fmt("placeholder %q does not refer to a variable", match[0])
+			}
+			used[match[1]] = true
+		}
+	}
+	numExpansions := 1
+	for name, values := range t.Variables {
+		if !variableNameRegex.MatchString(name) {
+			return skerr.Fmt("invalid variable name %q", name)
+		}
+		if !used[name] {
+			// Otherwise the template would expand into identical Alerts.
+			return skerr.Fmt("variable %q is not used", name)
+		}
+		if len(values) == 0 {
+			return skerr.Fmt("variable %q has no values", name)
+		}
+		if len(util.NewStringSet(values)) != len(values) {
+			return skerr.Fmt("variable %q has duplicate values", name)
+		}
+		for _, v := range values {
+			if v == "" {
+				return skerr.Fmt("variable %q has an empty value", name)
+			}
+		}
+		numExpansions *= len(values)
+		if numExpansions > maxTemplateExpansions {
+			return skerr.Fmt("a template may expand into at most %d alerts", maxTemplateExpansions)
+		}
+	}
+	return nil
+}
+
+// Expand returns the Alerts described by the Template, one for every
+// combination of the values of the Variables. The Alerts are not stored yet, so
+// their IDs are BadAlertID.
+func (t *Template) Expand() ([]*Alert, error) {
+	if err := t.Validate(); err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	names := make([]string, 0, len(t.Variables))
+	for name := range t.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	combinations := []map[string]string{{}}
+	for _, name := range names {
+		next := make([]map[string]string, 0, len(combinations)*len(t.Variables[name]))
+		for _, c := range combinations {
+			for _, v := range t.Variables[name] {
+				nc := make(map[string]string, len(c)+1)
+				for k, cv := range c {
+					nc[k] = cv
+				}
+				nc[name] = v
+				next = append(next, nc)
+			}
+		}
+		combinations = next
+	}
+
+	ret := make([]*Alert, 0, len(combinations))
+	for _, values := range combinations {
+		a := t.Alert
+		for field, isQuery := range patternFields(&a) {
+			*field = placeholderRegex.ReplaceAllStringFunc(*field, func(placeholder string) string {
+				v := values[placeholderRegex.FindStringSubmatch(placeholder)[1]]
+				if isQuery {
+					return url.QueryEscape(v)
+				}
+				return v
+			})
+		}
+		a.IDAsString = BadAlertIDAsAsString
+		a.StateAsString = ACTIVE
+		a.TemplateID = t.IDAsString
+		a.TemplateValues = values
+		if err := a.Validate(); err != nil {
+			return nil, skerr.Wrapf(err, "expanding %v", values)
+		}
+		ret = append(ret, &a)
+	}
+	return ret, nil
+}
+
+// templateValuesKey returns a string that uniquely identifies the given
+// combination of variable values.
+func templateValuesKey(values map[string]string) string {
+	v := url.Values{}
+	for k, value := range values {
+		v.Set(k, value)
+	}
+	return v.Encode()
+}
+
+// SyncTemplate makes the Alerts in store that were expanded from t match the
+// current t.Expand(). Alerts for combinations that still exist keep their IDs,
+// so regressions found by them stay associated with them. Alerts for
+// combinations that no longer exist are deleted. The Template must already have
+// been stored, so that it has an ID. The up to date Alerts are returned.
+func SyncTemplate(ctx context.Context, store Store, t *Template) ([]*Alert, error) {
+	if t.IDAsStringToInt() == BadAlertID {
+		return nil, skerr.Fmt("the template must be stored before its alerts")
+	}
+	expanded, err := t.Expand()
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	existing, err := templateAlerts(ctx, store, t.IDAsString)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	for _, a := range expanded {
+		key := templateValuesKey(a.TemplateValues)
+		if old, ok := existing[key]; ok {
+			a.IDAsString = old.IDAsString
+			delete(existing, key)
+		}
+		if err := store.Save(ctx, a); err != nil {
+			return nil, skerr.Wrapf(err, "saving alert for %v", a.TemplateValues)
+		}
+	}
+	for _, a := range existing {
+		if err := store.Delete(ctx, int(a.IDAsStringToInt())); err != nil {
+			return nil, skerr.Wrapf(err, "deleting alert %s", a.IDAsString)
+		}
+	}
+	return expanded, nil
+}
+
+// DeleteTemplateAlerts deletes all the Alerts that were expanded from the
+// Template with the given ID.
+func DeleteTemplateAlerts(ctx context.Context, store Store, templateID string) error {
+	existing, err := templateAlerts(ctx, store, templateID)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	for _, a := range existing {
+		if err := store.Delete(ctx, int(a.IDAsStringToInt())); err != nil {
+			return skerr.Wrapf(err, "deleting alert %s", a.IDAsString)
+		}
+	}
+	return nil
+}
+
+// templateAlerts returns the active Alerts that were expanded from the Template
+// with the given ID, keyed by templateValuesKey.
+func templateAlerts(ctx context.Context, store Store, templateID string) (map[string]*Alert, error) {
+	all, err := store.List(ctx, false)
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	ret := map[string]*Alert{}
+	for _, a := range all {
+		if a.TemplateID == templateID {
+			ret[templateValuesKey(a.TemplateValues)] = a
+		}
+	}
+	return ret, nil
+}
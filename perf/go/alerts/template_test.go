@@ -0,0 +1,136 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTemplate() *Template {
+	t := NewTemplate()
+	t.IDAsString = "7"
+	t.Name = "Per benchmark"
+	t.Alert.DisplayName = "{{benchmark}} on {{ bot }}"
+	t.Alert.Query = "benchmark={{benchmark}}&bot={{bot}}"
+	t.Alert.GroupBy = "test"
+	t.Variables = map[string][]string{
+		"benchmark": {"speedometer", "jet stream"},
+		"bot":       {"linux"},
+	}
+	return t
+}
+
+func TestTemplateExpand_OneAlertPerCombination(t *testing.T) {
+	alerts, err := newTestTemplate().Expand()
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+
+	assert.Equal(t, "speedometer on linux", alerts[0].DisplayName)
+	assert.Equal(t, "benchmark=speedometer&bot=linux", alerts[0].Query)
+	assert.Equal(t, "test", alerts[0].GroupBy)
+	assert.Equal(t, BadAlertIDAsAsString, alerts[0].IDAsString)
+	assert.Equal(t, "7", alerts[0].TemplateID)
+	assert.Equal(t, map[string]string{"benchmark": "speedometer", "bot": "linux"}, alerts[0].TemplateValues)
+
+	// Values substituted into the query are escaped.
+	assert.Equal(t, "jet stream on linux", alerts[1].DisplayName)
+	assert.Equal(t, "benchmark=jet+stream&bot=linux", alerts[1].Query)
+}
+
+func TestTemplateValidate_InvalidTemplates_ReturnError(t *testing.T) {
+	for name, modify := range map[string]func(*Template){
+		"no name":              func(t *Template) { t.Name = " " },
+		"no variables":         func(t *Template) { t.Variables = nil },
+		"undefined variable":   func(t *Template) { t.Alert.Alert = "{{owner}}@example.com" },
+		"unused variable":      func(t *Template) { t.Variables["config"] = []string{"8888"} },
+		"no values":            func(t *Template) { t.Variables["bot"] = nil },
+		"duplicate values":     func(t *Template) { t.Variables["bot"] = []string{"linux", "linux"} },
+		"empty value":          func(t *Template) { t.Variables["bot"] = []string{""} },
+		"group by in query":    func(t *Template) { t.Alert.GroupBy = "bot" },
+		"too many expansions":  func(t *Template) { t.Variables["bot"] = make([]string, maxTemplateExpansions) },
+		"invalid variable key": func(t *Template) { t.Alert.Owner = "{{}}" },
+	} {
+		tmpl := newTestTemplate()
+		modify(tmpl)
+		_, err := tmpl.Expand()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSyncTemplate_ValuesChanged_ExistingAlertsKeepTheirIDs(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{}
+	unrelated := NewConfig()
+	require.NoError(t, store.Save(ctx, unrelated))
+
+	tmpl := newTestTemplate()
+	created, err := SyncTemplate(ctx, store, tmpl)
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	speedometerID := created[0].IDAsString
+
+	tmpl.Variables["benchmark"] = []string{"speedometer", "motionmark"}
+	updated, err := SyncTemplate(ctx, store, tmpl)
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+	assert.Equal(t, "speedometer on linux", updated[0].DisplayName)
+	assert.Equal(t, speedometerID, updated[0].IDAsString)
+	assert.Equal(t, "motionmark on linux", updated[1].DisplayName)
+	assert.NotEqual(t, created[1].IDAsString, updated[1].IDAsString)
+
+	active, err := store.List(ctx, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Alert{unrelated, updated[0], updated[1]}, active)
+
+	require.NoError(t, DeleteTemplateAlerts(ctx, store, tmpl.IDAsString))
+	active, err = store.List(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, []*Alert{unrelated}, active)
+}
+
+func TestSyncTemplate_TemplateNotStored_ReturnsError(t *testing.T) {
+	tmpl := newTestTemplate()
+	tmpl.IDAsString = BadAlertIDAsAsString
+	_, err := SyncTemplate(context.Background(), &memoryStore{}, tmpl)
+	assert.Error(t, err)
+}
+
+// memoryStore is a Store that keeps Alerts in memory.
+type memoryStore struct {
+	alerts []*Alert
+}
+
+func (m *memoryStore) Save(_ context.Context, cfg *Alert) error {
+	if cfg.IDAsString == BadAlertIDAsAsString {
+		cfg.SetIDFromInt64(int64(len(m.alerts) + 1))
+		m.alerts = append(m.alerts, cfg)
+		return nil
+	}
+	for i, a := range m.alerts {
+		if a.IDAsString == cfg.IDAsString {
+			m.alerts[i] = cfg
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, id int) error {
+	for _, a := range m.alerts {
+		if a.IDAsStringToInt() == int64(id) {
+			a.StateAsString = DELETED
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) List(_ context.Context, includeDeleted bool) ([]*Alert, error) {
+	var ret []*Alert
+	for _, a := range m.alerts {
+		if includeDeleted || a.StateAsString == ACTIVE {
+			ret = append(ret, a)
+		}
+	}
+	return ret, nil
+}
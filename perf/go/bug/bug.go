@@ -7,10 +7,13 @@ import (
 	"gopkg.in/olivere/elastic.v5/uritemplates"
 )
 
-// Expand the uriTemplate given a link to the regressing cluster, the commit, and the user's message about the regression.
-func Expand(uriTemplate string, clusterLink string, c provider.Commit, message string) string {
+// Expand the uriTemplate given a link to the regressing cluster, a link to a
+// rendered chart of the cluster, the commit, and the user's message about the
+// regression.
+func Expand(uriTemplate string, clusterLink string, chartLink string, c provider.Commit, message string) string {
 	expansion := map[string]string{
 		"cluster_url": clusterLink,
+		"chart_url":   chartLink,
 		"commit_url":  c.URL,
 		"message":     message,
 	}
@@ -27,6 +30,7 @@ func ExampleExpand(uriTemplate string) string {
 		URL: "https://skia.googlesource.com/skia/+show/d261e1075a93677442fdf7fe72aba7e583863664",
 	}
 	clusterLink := "https://perf.skia.org/t/?begin=1498332791&end=1498528391&subset=flagged"
+	chartLink := "https://perf.skia.org/_/reg/chart?alert=1&cid=1234&dir=high&format=png"
 	message := "Looks like a regression."
-	return Expand(uriTemplate, clusterLink, c, message)
+	return Expand(uriTemplate, clusterLink, chartLink, c, message)
 }
@@ -14,6 +14,13 @@ func TestExpand(t *testing.T) {
 	}
 	clusterLink := "https://perf.skia.org/t/?begin=1498332791&end=1498528391&subset=flagged"
 	message := "noise"
-	buglink := Expand("https://example.com/?link={cluster_url}&commit={commit_url}&message={message}", clusterLink, c, message)
+	chartLink := "https://perf.skia.org/_/reg/chart?alert=1&cid=1234&format=png"
+	buglink := Expand("https://example.com/?link={cluster_url}&commit={commit_url}&message={message}", clusterLink, chartLink, c, message)
 	assert.Equal(t, "https://example.com/?link=https%3A%2F%2Fperf.skia.org%2Ft%2F%3Fbegin%3D1498332791%26end%3D1498528391%26subset%3Dflagged&commit=https%3A%2F%2Fskia.googlesource.com%2Fskia%2F%2Bshow%2Fd261e1075a93677442fdf7fe72aba7e583863664&message=noise", buglink)
 }
+
+func TestExpand_ChartURL_IsExpanded(t *testing.T) {
+	chartLink := "https://perf.skia.org/_/reg/chart?alert=1&cid=1234&format=png"
+	buglink := Expand("https://example.com/?chart={chart_url}", "", chartLink, provider.Commit{}, "")
+	assert.Equal(t, "https://example.com/?chart=https%3A%2F%2Fperf.skia.org%2F_%2Freg%2Fchart%3Falert%3D1%26cid%3D1234%26format%3Dpng", buglink)
+}
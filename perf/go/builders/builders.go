@@ -22,6 +22,8 @@ import (
 	"go.goldmine.build/perf/go/alerts"
 	"go.goldmine.build/perf/go/alerts/sqlalertstore"
 	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/derivedmetrics"
+	"go.goldmine.build/perf/go/derivedmetrics/sqlderivedmetricstore"
 	"go.goldmine.build/perf/go/file"
 	"go.goldmine.build/perf/go/file/dirsource"
 	"go.goldmine.build/perf/go/file/gcssource"
@@ -174,6 +176,20 @@ func NewAlertStoreFromConfig(ctx context.Context, local bool, instanceConfig *co
 	return nil, skerr.Fmt("Unknown datastore type: %q", instanceConfig.DataStoreConfig.DataStoreType)
 }
 
+// NewAlertTemplateStoreFromConfig creates a new alerts.TemplateStore from the
+// InstanceConfig.
+func NewAlertTemplateStoreFromConfig(ctx context.Context, local bool, instanceConfig *config.InstanceConfig) (alerts.TemplateStore, error) {
+	switch instanceConfig.DataStoreConfig.DataStoreType {
+	case config.CockroachDBDataStoreType:
+		db, err := NewCockroachDBFromConfig(ctx, instanceConfig, true)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		return sqlalertstore.NewTemplateStore(db)
+	}
+	return nil, skerr.Fmt("Unknown datastore type: %q", instanceConfig.DataStoreConfig.DataStoreType)
+}
+
 // NewRegressionStoreFromConfig creates a new regression.RegressionStore from
 // the InstanceConfig.
 //
@@ -218,6 +234,20 @@ func NewGraphsShortcutStoreFromConfig(ctx context.Context, local bool, instanceC
 	return nil, skerr.Fmt("Unknown datastore type: %q", instanceConfig.DataStoreConfig.DataStoreType)
 }
 
+// NewDerivedMetricStoreFromConfig creates a new derivedmetrics.Store from the
+// InstanceConfig.
+func NewDerivedMetricStoreFromConfig(ctx context.Context, local bool, instanceConfig *config.InstanceConfig) (derivedmetrics.Store, error) {
+	switch instanceConfig.DataStoreConfig.DataStoreType {
+	case config.CockroachDBDataStoreType:
+		db, err := NewCockroachDBFromConfig(ctx, instanceConfig, true)
+		if err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		return sqlderivedmetricstore.New(db)
+	}
+	return nil, skerr.Fmt("Unknown datastore type: %q", instanceConfig.DataStoreConfig.DataStoreType)
+}
+
 // NewSourceFromConfig creates a new file.Source from the InstanceConfig.
 //
 // If local is true then we aren't running in production.
@@ -0,0 +1,201 @@
+// Package chart renders the centroid of a cluster as an image, so that it can
+// be attached to bugs filed for a regression.
+package chart
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/vec32"
+	"go.goldmine.build/perf/go/clustering2"
+)
+
+const (
+	// Width and Height are the size of the rendered images in pixels.
+	Width  = 600
+	Height = 300
+
+	// margin is the space in pixels around the plotted area, which holds the
+	// labels in the SVG.
+	margin = 30
+)
+
+var (
+	background = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	traceColor = color.NRGBA{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff}
+	stepColor  = color.NRGBA{R: 0xd6, G: 0x27, B: 0x28, A: 0xff}
+)
+
+// point is a position in the image, in pixels.
+type point struct {
+	x, y float64
+}
+
+// layout is the centroid of a cluster and its step, scaled to the image.
+type layout struct {
+	// trace are the points of the centroid, missing values are skipped.
+	trace []point
+
+	// turningX is the horizontal position of the turning point, or -1 if the
+	// cluster doesn't have a step.
+	turningX float64
+
+	// step is the step function fit to the centroid, i.e. the mean of the
+	// centroid before and after the turning point. It is empty if the cluster
+	// doesn't have a step.
+	step []point
+
+	// min and max are the smallest and largest values of the centroid.
+	min, max float32
+}
+
+// newLayout returns the layout of the given cluster.
+func newLayout(cl *clustering2.ClusterSummary) layout {
+	ret := layout{
+		turningX: -1,
+		min:      float32(math.Inf(1)),
+		max:      float32(math.Inf(-1)),
+	}
+	for _, v := range cl.Centroid {
+		if v == vec32.MissingDataSentinel {
+			continue
+		}
+		ret.min = float32(math.Min(float64(ret.min), float64(v)))
+		ret.max = float32(math.Max(float64(ret.max), float64(v)))
+	}
+	if ret.min > ret.max {
+		// There are no values to plot.
+		ret.min, ret.max = 0, 0
+		return ret
+	}
+
+	n := len(cl.Centroid)
+	xOf := func(i int) float64 {
+		if n == 1 {
+			return Width / 2
+		}
+		return margin + float64(i)*float64(Width-2*margin)/float64(n-1)
+	}
+	yOf := func(v float32) float64 {
+		if ret.max == ret.min {
+			return Height / 2
+		}
+		return float64(Height-margin) - float64(v-ret.min)*float64(Height-2*margin)/float64(ret.max-ret.min)
+	}
+	for i, v := range cl.Centroid {
+		if v == vec32.MissingDataSentinel {
+			continue
+		}
+		ret.trace = append(ret.trace, point{x: xOf(i), y: yOf(v)})
+	}
+
+	if cl.StepFit == nil || cl.StepFit.TurningPoint <= 0 || cl.StepFit.TurningPoint >= n {
+		return ret
+	}
+	turningPoint := cl.StepFit.TurningPoint
+	ret.turningX = xOf(turningPoint)
+	before := vec32.Mean(cl.Centroid[:turningPoint])
+	after := vec32.Mean(cl.Centroid[turningPoint:])
+	ret.step = []point{
+		{x: xOf(0), y: yOf(before)},
+		{x: ret.turningX, y: yOf(before)},
+		{x: ret.turningX, y: yOf(after)},
+		{x: xOf(n - 1), y: yOf(after)},
+	}
+	return ret
+}
+
+// annotation returns the description of the step of the cluster.
+func annotation(cl *clustering2.ClusterSummary) string {
+	if cl.StepFit == nil {
+		return fmt.Sprintf("%d traces", cl.Num)
+	}
+	return fmt.Sprintf("%s: step size %g, regression %g, %d traces", cl.StepFit.Status, cl.StepFit.StepSize, cl.StepFit.Regression, cl.Num)
+}
+
+// polyline returns the points formatted for the points attribute of an SVG
+// polyline.
+func polyline(points []point) string {
+	ret := make([]string, 0, len(points))
+	for _, p := range points {
+		ret = append(ret, fmt.Sprintf("%.1f,%.1f", p.x, p.y))
+	}
+	return strings.Join(ret, " ")
+}
+
+// svgColor returns the color formatted for SVG.
+func svgColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// SVG writes the centroid of the cluster as an SVG image to w. The step fit to
+// the centroid is drawn as a dashed line, and is described in a label along
+// with the range of the values.
+func SVG(w io.Writer, cl *clustering2.ClusterSummary) error {
+	l := newLayout(cl)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", Width, Height, Width, Height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", Width, Height, svgColor(background))
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", margin, margin/2+4, html.EscapeString(annotation(cl)))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%g</text>`+"\n", Width-4, margin, l.max)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%g</text>`+"\n", Width-4, Height-margin, l.min)
+	if len(l.step) > 0 {
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1" stroke-dasharray="4 4"/>`+"\n", polyline(l.step), svgColor(stepColor))
+	}
+	if len(l.trace) > 0 {
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", polyline(l.trace), svgColor(traceColor))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return skerr.Wrap(err)
+}
+
+// PNG writes the centroid of the cluster as a PNG image to w. The step fit to
+// the centroid is drawn as a dashed line. Unlike SVG, no labels are drawn.
+func PNG(w io.Writer, cl *clustering2.ClusterSummary) error {
+	l := newLayout(cl)
+	img := image.NewNRGBA(image.Rect(0, 0, Width, Height))
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			img.SetNRGBA(x, y, background)
+		}
+	}
+	for i := 1; i < len(l.step); i++ {
+		drawLine(img, l.step[i-1], l.step[i], stepColor, true)
+	}
+	for i := 1; i < len(l.trace); i++ {
+		drawLine(img, l.trace[i-1], l.trace[i], traceColor, false)
+	}
+	if len(l.trace) == 1 {
+		drawLine(img, l.trace[0], l.trace[0], traceColor, false)
+	}
+	return skerr.Wrap(png.Encode(w, img))
+}
+
+// drawLine draws a two pixel wide line from p1 to p2. Dashed lines alternate
+// between four pixels drawn and four pixels skipped.
+func drawLine(img *image.NRGBA, p1, p2 point, c color.NRGBA, dashed bool) {
+	dx, dy := p2.x-p1.x, p2.y-p1.y
+	steps := int(math.Ceil(math.Max(math.Abs(dx), math.Abs(dy))))
+	if steps == 0 {
+		steps = 1
+	}
+	for i := 0; i <= steps; i++ {
+		if dashed && (i/4)%2 == 1 {
+			continue
+		}
+		t := float64(i) / float64(steps)
+		x := int(math.Round(p1.x + t*dx))
+		y := int(math.Round(p1.y + t*dy))
+		img.SetNRGBA(x, y, c)
+		img.SetNRGBA(x+1, y, c)
+		img.SetNRGBA(x, y+1, c)
+	}
+}
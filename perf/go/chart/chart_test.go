@@ -0,0 +1,76 @@
+package chart
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/go/vec32"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/stepfit"
+)
+
+func clusterForTest() *clustering2.ClusterSummary {
+	return &clustering2.ClusterSummary{
+		Centroid: []float32{1, 1, 1, vec32.MissingDataSentinel, 3, 3},
+		StepFit: &stepfit.StepFit{
+			TurningPoint: 3,
+			StepSize:     -2,
+			Regression:   -20,
+			Status:       stepfit.HIGH,
+		},
+		Num: 5,
+	}
+}
+
+func TestNewLayout_ClusterWithStep_ScaledToImage(t *testing.T) {
+	l := newLayout(clusterForTest())
+	assert.Equal(t, float32(1), l.min)
+	assert.Equal(t, float32(3), l.max)
+	// The missing value is skipped.
+	require.Len(t, l.trace, 5)
+	assert.Equal(t, point{x: margin, y: Height - margin}, l.trace[0])
+	assert.Equal(t, point{x: Width - margin, y: margin}, l.trace[4])
+	assert.Equal(t, float64(margin+3*(Width-2*margin)/5), l.turningX)
+	require.Len(t, l.step, 4)
+	assert.Equal(t, float64(Height-margin), l.step[0].y)
+	assert.Equal(t, float64(margin), l.step[3].y)
+}
+
+func TestNewLayout_NoStepFit_NoStepDrawn(t *testing.T) {
+	cl := clusterForTest()
+	cl.StepFit = nil
+	l := newLayout(cl)
+	assert.Equal(t, float64(-1), l.turningX)
+	assert.Empty(t, l.step)
+}
+
+func TestNewLayout_NoValues_NothingDrawn(t *testing.T) {
+	l := newLayout(&clustering2.ClusterSummary{Centroid: []float32{vec32.MissingDataSentinel}})
+	assert.Empty(t, l.trace)
+	assert.Empty(t, l.step)
+}
+
+func TestSVG_ClusterWithStep_ContainsTraceStepAndAnnotation(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, SVG(&b, clusterForTest()))
+	svg := b.String()
+	assert.Contains(t, svg, `<svg xmlns="http://www.w3.org/2000/svg" width="600" height="300"`)
+	assert.Contains(t, svg, "High: step size -2, regression -20, 5 traces")
+	assert.Contains(t, svg, `stroke="#1f77b4" stroke-width="2"`)
+	assert.Contains(t, svg, `stroke-dasharray="4 4"`)
+}
+
+func TestPNG_ClusterWithStep_DrawsTraceAndStep(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, PNG(&b, clusterForTest()))
+	img, err := png.Decode(&b)
+	require.NoError(t, err)
+	assert.Equal(t, Width, img.Bounds().Dx())
+	assert.Equal(t, Height, img.Bounds().Dy())
+	assert.Equal(t, traceColor, color.NRGBAModel.Convert(img.At(margin, Height-margin)))
+	assert.Equal(t, background, color.NRGBAModel.Convert(img.At(0, 0)))
+}
@@ -66,6 +66,12 @@ type NotifyConfig struct {
 	// Notifications is set to use an issue tracker.
 	IssueTrackerAPIKeySecretName string `json:"issue_tracker_api_key_secret_name,omitempty"`
 
+	// ChatChannels is a routing table from channel names, which can be used as
+	// the chat destination of an alert, to the URLs of Slack or Google Chat
+	// incoming webhooks. Only used if Notifications is set to send chat
+	// messages.
+	ChatChannels map[string]string `json:"chat_channels,omitempty"`
+
 	// The following fields, Subject, Body, MissingSubject and MissingBody, are
 	// all golang text templates. See notify.TemplateContext for the values that
 	// are available to the templates.
@@ -234,6 +240,17 @@ type IngestionConfig struct {
 	// an interface that ingests files and optionally provides a channel
 	// of events when a file is ingested.
 	FileIngestionTopicName string `json:"file_ingestion_pubsub_topic_name"`
+
+	// CommitPubSubTopicName is the PubSub topic name that commit landing
+	// events are published to, for example by gitsync. If set, and doing event
+	// driven regression detection, the clusterers will also subscribe to this
+	// topic and run regression detection for just the Alerts that match traces
+	// that have data at the newly landed commit.
+	//
+	// Each message is expected to carry the repo URL as its body and a map of
+	// branch name to the git hash at the head of that branch as its
+	// attributes.
+	CommitPubSubTopicName string `json:"commit_pubsub_topic_name,omitempty"`
 }
 
 // GitRepoConfig is the config for the git repo.
@@ -392,6 +409,7 @@ type FrontendFlags struct {
 	FeedbackURL                string
 	DisableGitUpdate           bool
 	DisableMetricsUpdate       bool
+	ReadOnly                   bool
 }
 
 // AsCliFlags returns a slice of cli.Flag.
@@ -565,6 +583,12 @@ show up as a query option in the UI for the "test" key.
 			Value:       false,
 			Usage:       "Disables updating of the database metrics",
 		},
+		&cli.BoolFlag{
+			Destination: &flags.ReadOnly,
+			Name:        "read_only",
+			Value:       false,
+			Usage:       "Start the instance in read-only mode, where all endpoints that modify data return a 503. Can be changed at runtime by an admin via /_/readonly.",
+		},
 	}
 }
 
@@ -690,6 +714,24 @@ type QueryConfig struct {
 	DefaultUrlValues map[string]string `json:"default_url_values,omitempty"`
 }
 
+// ObfuscationConfig configures hiding the values of selected params from users
+// that are not logged in, e.g. because the trace keys of a public instance
+// contain internal bot or config names. The values are replaced by a keyed
+// hash, which is stable, so the hashed values can still be used in queries.
+type ObfuscationConfig struct {
+	// Params are the keys whose values are obfuscated, e.g. ["bot", "config"].
+	Params []string `json:"params"`
+
+	// KeySecretProject is the name of the GCP project where the secret key
+	// used for hashing is stored in the secret manager.
+	KeySecretProject string `json:"key_secret_project"`
+
+	// KeySecretName is the name of the secret in the secret manager that
+	// holds the key used for hashing. Changing the key changes all the
+	// obfuscated values, which breaks any links shared by anonymous users.
+	KeySecretName string `json:"key_secret_name"`
+}
+
 // InstanceConfig contains all the info needed by a Perf instance.
 type InstanceConfig struct {
 	// URL is the root URL at which this instance is available, for example: "https://example.com".
@@ -743,6 +785,10 @@ type InstanceConfig struct {
 	AnomalyConfig   AnomalyConfig   `json:"anomaly_config,omitempty"`
 	QueryConfig     QueryConfig     `json:"query_config,omitempty"`
 
+	// Obfuscation, if set, hides the values of selected params from users that
+	// are not logged in.
+	Obfuscation *ObfuscationConfig `json:"obfuscation,omitempty"`
+
 	// Measurement ID to use when tracking user metrics with Google Analytics.
 	GoogleAnalyticsMeasurementID string `json:"ga_measurement_id,omitempty"`
 }
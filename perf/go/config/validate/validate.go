@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"net/url"
 	"regexp"
 	"time"
 
@@ -74,6 +75,13 @@ func Validate(i config.InstanceConfig) error {
 		}
 	}
 
+	for name, webhook := range i.NotifyConfig.ChatChannels {
+		u, err := url.Parse(webhook)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return skerr.Fmt("chat_channels[%q] must be an https URL", name)
+		}
+	}
+
 	if i.InvalidParamCharRegex != "" {
 		re, err := regexp.Compile(i.InvalidParamCharRegex)
 		if err != nil {
@@ -87,6 +95,10 @@ func Validate(i config.InstanceConfig) error {
 		}
 	}
 
+	if i.Obfuscation != nil && len(i.Obfuscation.Params) == 0 {
+		return skerr.Fmt("obfuscation.params must not be empty when obfuscation is set.")
+	}
+
 	// Validate the Notify Config.
 	if i.NotifyConfig.Notifications == notifytypes.MarkdownIssueTracker && (len(i.NotifyConfig.Body) > 0 || i.NotifyConfig.Subject != "" || len(i.NotifyConfig.MissingBody) > 0 || i.NotifyConfig.MissingSubject != "") {
 		f, err := notify.NewMarkdownFormatter("", &(i.NotifyConfig))
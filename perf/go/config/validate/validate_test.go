@@ -76,6 +76,18 @@ func TestInstanceConfigValidate_MarkdownIssueTrackerButAPIKeySecretNameNotSet_Re
 	require.Contains(t, Validate(i).Error(), "issue_tracker_api_key_secret_name must be supplied")
 }
 
+func TestInstanceConfigValidate_ChatChannelNotHTTPS_ReturnsError(t *testing.T) {
+	i := config.InstanceConfig{
+		NotifyConfig: config.NotifyConfig{
+			Notifications: notifytypes.HTMLEmailAndChat,
+			ChatChannels: map[string]string{
+				"perf-alerts": "http://hooks.slack.com/services/T000/B000/XXXX",
+			},
+		},
+	}
+	require.Contains(t, Validate(i).Error(), `chat_channels["perf-alerts"] must be an https URL`)
+}
+
 func TestInstanceConfigValidate_InvalidParamCharRegexMatchesComma_ReturnsError(t *testing.T) {
 	i := config.InstanceConfig{
 		InvalidParamCharRegex: ",",
@@ -89,3 +101,13 @@ func TestInstanceConfigValidate_InvalidParamCharRegexMatchesEqual_ReturnsError(t
 	}
 	require.Contains(t, Validate(i).Error(), "invalid_param_char_regex must match")
 }
+
+func TestInstanceConfigValidate_ObfuscationWithoutParams_ReturnsError(t *testing.T) {
+	i := config.InstanceConfig{
+		Obfuscation: &config.ObfuscationConfig{
+			KeySecretProject: "project",
+			KeySecretName:    "name",
+		},
+	}
+	require.Contains(t, Validate(i).Error(), "obfuscation.params must not be empty")
+}
@@ -0,0 +1,90 @@
+// Package derivedmetrics handles storing and retrieving derived metrics, which
+// are calc formulas saved under a name.
+//
+// A derived metric can be referenced from any formula, and from an Alert, as
+//
+//	metric("name")
+//
+// so that commonly used formulas, such as ratios between two benchmarks, only
+// need to be defined once.
+package derivedmetrics
+
+import (
+	"context"
+	"regexp"
+
+	"go.goldmine.build/go/calc"
+	"go.goldmine.build/go/skerr"
+)
+
+// validName is the format of the name of a derived metric.
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)
+
+// DerivedMetric is a named calc formula.
+type DerivedMetric struct {
+	// Name is the unique name of the derived metric, used to refer to it from
+	// formulas and Alerts.
+	Name string `json:"name"`
+
+	// Formula is the calc formula, e.g. `ratio(ave(filter("a=b")),
+	// ave(filter("a=c")))`.
+	Formula string `json:"formula"`
+
+	// Description is a human readable description of the derived metric.
+	Description string `json:"description"`
+
+	// Owner is the email address of the person responsible for the derived
+	// metric.
+	Owner string `json:"owner"`
+
+	// LastModified is the time the derived metric was last saved, in Unix
+	// seconds.
+	LastModified int64 `json:"last_modified"`
+}
+
+// Validate returns an error if the DerivedMetric is not valid.
+func (d *DerivedMetric) Validate() error {
+	if !validName.MatchString(d.Name) {
+		return skerr.Fmt("Invalid name %q: only letters, digits, '_', '.' and '-' are allowed.", d.Name)
+	}
+	if _, err := calc.Parse(d.Formula); err != nil {
+		return skerr.Wrapf(err, "Invalid formula %q", d.Formula)
+	}
+	return nil
+}
+
+// Store is an interface for things that persist DerivedMetrics.
+type Store interface {
+	// Save writes a new, or updates an existing, DerivedMetric with the same
+	// name. LastModified is set to the current time.
+	Save(ctx context.Context, d *DerivedMetric) error
+
+	// Get returns the DerivedMetric with the given name.
+	Get(ctx context.Context, name string) (*DerivedMetric, error)
+
+	// Delete removes the DerivedMetric with the given name.
+	Delete(ctx context.Context, name string) error
+
+	// List returns all the DerivedMetrics, ordered by name.
+	List(ctx context.Context) ([]*DerivedMetric, error)
+}
+
+// Formulas returns the formulas of all the DerivedMetrics in the store, keyed
+// by name, in the form used by calc.Context.Metrics.
+func Formulas(ctx context.Context, store Store) (map[string]string, error) {
+	all, err := store.List(ctx)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "loading derived metrics")
+	}
+	ret := make(map[string]string, len(all))
+	for _, d := range all {
+		ret[d.Name] = d.Formula
+	}
+	return ret, nil
+}
+
+// Reference returns the formula that refers to the derived metric with the
+// given name.
+func Reference(name string) string {
+	return `metric("` + name + `")`
+}
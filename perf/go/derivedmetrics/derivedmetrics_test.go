@@ -0,0 +1,39 @@
+package derivedmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/go/calc"
+)
+
+func TestValidate_ValidDerivedMetric_Success(t *testing.T) {
+	d := &DerivedMetric{
+		Name:    "speedometer.ratio_v8",
+		Formula: `ratio(ave(filter("config=v8")), ave(filter("config=jsc")))`,
+	}
+	assert.NoError(t, d.Validate())
+}
+
+func TestValidate_InvalidName_ReturnsError(t *testing.T) {
+	for _, name := range []string{"", "has space", `quote"`, "paren()"} {
+		d := &DerivedMetric{Name: name, Formula: `filter("")`}
+		assert.Error(t, d.Validate(), name)
+	}
+}
+
+func TestValidate_InvalidFormula_ReturnsError(t *testing.T) {
+	for _, formula := range []string{"", `filter(`, `"config=v8"`} {
+		d := &DerivedMetric{Name: "name", Formula: formula}
+		assert.Error(t, d.Validate(), formula)
+	}
+}
+
+func TestReference_IsParsedAsMetricFunc(t *testing.T) {
+	n, err := calc.Parse(Reference("speedometer.ratio_v8"))
+	require.NoError(t, err)
+	assert.Equal(t, "metric", n.Val)
+	require.Len(t, n.Args, 1)
+	assert.Equal(t, "speedometer.ratio_v8", n.Args[0].Val)
+}
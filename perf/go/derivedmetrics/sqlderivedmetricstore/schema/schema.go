@@ -0,0 +1,12 @@
+package schema
+
+// DerivedMetricSchema represents the SQL schema of the DerivedMetrics table.
+type DerivedMetricSchema struct {
+	Name string `sql:"name TEXT UNIQUE NOT NULL PRIMARY KEY"`
+
+	// A derivedmetrics.DerivedMetric serialized as JSON.
+	Metric string `sql:"metric TEXT"`
+
+	// Stored as a Unit timestamp.
+	LastModified int `sql:"last_modified INT"`
+}
@@ -0,0 +1,131 @@
+// Package sqlderivedmetricstore implements derivedmetrics.Store using an SQL
+// database.
+package sqlderivedmetricstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/sql/pool"
+	"go.goldmine.build/perf/go/derivedmetrics"
+)
+
+// statement is an SQL statement identifier.
+type statement int
+
+const (
+	// The identifiers for all the SQL statements used.
+	saveMetric statement = iota
+	getMetric
+	deleteMetric
+	listMetrics
+)
+
+// statements holds all the raw SQL statemens.
+var statements = map[statement]string{
+	saveMetric: `
+		UPSERT INTO
+			DerivedMetrics (name, metric, last_modified)
+		VALUES
+			($1, $2, $3)
+		`,
+	getMetric: `
+		SELECT
+			metric
+		FROM
+			DerivedMetrics
+		WHERE
+			name=$1
+		`,
+	deleteMetric: `
+		DELETE FROM
+			DerivedMetrics
+		WHERE
+			name=$1
+		`,
+	listMetrics: `
+		SELECT
+			metric
+		FROM
+			DerivedMetrics
+		ORDER BY
+			name
+		`,
+}
+
+// SQLDerivedMetricStore implements the derivedmetrics.Store interface using an
+// SQL database.
+type SQLDerivedMetricStore struct {
+	db pool.Pool
+}
+
+// New returns a new *SQLDerivedMetricStore.
+//
+// We presume all migrations have been run against db before this function is
+// called.
+func New(db pool.Pool) (*SQLDerivedMetricStore, error) {
+	return &SQLDerivedMetricStore{
+		db: db,
+	}, nil
+}
+
+// Save implements the derivedmetrics.Store interface.
+func (s *SQLDerivedMetricStore) Save(ctx context.Context, d *derivedmetrics.DerivedMetric) error {
+	d.LastModified = time.Now().Unix()
+	b, err := json.Marshal(d)
+	if err != nil {
+		return skerr.Wrapf(err, "Failed to serialize derived metric %q", d.Name)
+	}
+	if _, err := s.db.Exec(ctx, statements[saveMetric], d.Name, string(b), d.LastModified); err != nil {
+		return skerr.Wrapf(err, "Failed to save derived metric %q", d.Name)
+	}
+	return nil
+}
+
+// Get implements the derivedmetrics.Store interface.
+func (s *SQLDerivedMetricStore) Get(ctx context.Context, name string) (*derivedmetrics.DerivedMetric, error) {
+	var encoded string
+	if err := s.db.QueryRow(ctx, statements[getMetric], name).Scan(&encoded); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to load derived metric %q", name)
+	}
+	var d derivedmetrics.DerivedMetric
+	if err := json.Unmarshal([]byte(encoded), &d); err != nil {
+		return nil, skerr.Wrapf(err, "Failed to decode derived metric %q", name)
+	}
+	return &d, nil
+}
+
+// Delete implements the derivedmetrics.Store interface.
+func (s *SQLDerivedMetricStore) Delete(ctx context.Context, name string) error {
+	if _, err := s.db.Exec(ctx, statements[deleteMetric], name); err != nil {
+		return skerr.Wrapf(err, "Failed to delete derived metric %q", name)
+	}
+	return nil
+}
+
+// List implements the derivedmetrics.Store interface.
+func (s *SQLDerivedMetricStore) List(ctx context.Context) ([]*derivedmetrics.DerivedMetric, error) {
+	rows, err := s.db.Query(ctx, statements[listMetrics])
+	if err != nil {
+		return nil, skerr.Wrap(err)
+	}
+	defer rows.Close()
+	ret := []*derivedmetrics.DerivedMetric{}
+	for rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return nil, skerr.Wrap(err)
+		}
+		d := &derivedmetrics.DerivedMetric{}
+		if err := json.Unmarshal([]byte(encoded), d); err != nil {
+			return nil, skerr.Wrapf(err, "Failed to decode derived metric.")
+		}
+		ret = append(ret, d)
+	}
+	return ret, nil
+}
+
+// Confirm SQLDerivedMetricStore implements derivedmetrics.Store.
+var _ derivedmetrics.Store = (*SQLDerivedMetricStore)(nil)
@@ -0,0 +1,52 @@
+package sqlderivedmetricstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/perf/go/derivedmetrics"
+	"go.goldmine.build/perf/go/sql/sqltest"
+)
+
+func TestSQLDerivedMetricStore_SaveGetListDelete(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.NewCockroachDBForTests(t, "derivedmetricstore")
+	store, err := New(db)
+	require.NoError(t, err)
+
+	ratio := &derivedmetrics.DerivedMetric{
+		Name:        "ratio",
+		Formula:     `ratio(ave(filter("config=v8")), ave(filter("config=jsc")))`,
+		Description: "v8 relative to jsc",
+		Owner:       "alice@example.com",
+	}
+	require.NoError(t, store.Save(ctx, ratio))
+	assert.NotZero(t, ratio.LastModified)
+
+	got, err := store.Get(ctx, "ratio")
+	require.NoError(t, err)
+	assert.Equal(t, ratio, got)
+
+	// Saving with the same name updates the existing derived metric.
+	ratio.Description = "updated"
+	require.NoError(t, store.Save(ctx, ratio))
+	count := &derivedmetrics.DerivedMetric{
+		Name:    "count",
+		Formula: `count(filter(""))`,
+	}
+	require.NoError(t, store.Save(ctx, count))
+
+	all, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*derivedmetrics.DerivedMetric{count, ratio}, all)
+
+	require.NoError(t, store.Delete(ctx, "count"))
+	all, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*derivedmetrics.DerivedMetric{ratio}, all)
+
+	_, err = store.Get(ctx, "count")
+	assert.Error(t, err)
+}
@@ -8,11 +8,14 @@ import (
 	"net/url"
 	"time"
 
+	"go.goldmine.build/go/calc"
 	"go.goldmine.build/go/metrics2"
 	"go.goldmine.build/go/now"
+	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/query"
 	"go.goldmine.build/go/skerr"
 	"go.goldmine.build/go/sklog"
+	"go.goldmine.build/go/vec32"
 	"go.goldmine.build/perf/go/alerts"
 	"go.goldmine.build/perf/go/config"
 	"go.goldmine.build/perf/go/dataframe"
@@ -92,6 +95,128 @@ func NewDataFrameIterator(
 	if err != nil {
 		return nil, skerr.Wrap(err)
 	}
+	build := func(ctx context.Context, end time.Time, n int32) (*dataframe.DataFrame, error) {
+		return dfBuilder.NewNFromQuery(ctx, end, q, n, progress)
+	}
+	return newDataFrameIterator(ctx, build, perfGit, regressionStateCallback, domain, alert, anomalyConfig)
+}
+
+// NewDataFrameIteratorFromFormula is NewDataFrameIterator, but the dataframes
+// hold the traces produced by the given calc formula instead of the traces
+// that match a query. The metrics are the formulas of the derived metrics that
+// the formula may refer to, keyed by name.
+func NewDataFrameIteratorFromFormula(
+	ctx context.Context,
+	progress progress.Progress,
+	dfBuilder dataframe.DataFrameBuilder,
+	perfGit perfgit.Git,
+	regressionStateCallback types.ProgressCallback,
+	formula string,
+	metrics map[string]string,
+	domain types.Domain,
+	alert *alerts.Alert,
+	anomalyConfig config.AnomalyConfig,
+) (DataFrameIterator, error) {
+	ctx, span := trace.StartSpan(ctx, "dfiter.NewDataFrameIteratorFromFormula")
+	defer span.End()
+
+	build := func(ctx context.Context, end time.Time, n int32) (*dataframe.DataFrame, error) {
+		return dataFrameFromFormula(ctx, dfBuilder, formula, metrics, end, n, progress)
+	}
+	return newDataFrameIterator(ctx, build, perfGit, regressionStateCallback, domain, alert, anomalyConfig)
+}
+
+// dataFrameFromFormula evaluates the calc formula over the n commits that end
+// at end.
+//
+// The first query in the formula picks the n commits, i.e. the commits where
+// it has data, and all the other queries are loaded over the same commits, so
+// that the rows they return line up column by column.
+func dataFrameFromFormula(ctx context.Context, dfBuilder dataframe.DataFrameBuilder, formula string, metrics map[string]string, end time.Time, n int32, progress progress.Progress) (*dataframe.DataFrame, error) {
+	// During the calculation 'rowsFromQuery' will be called to load up data, we
+	// capture the dataframe that's created by the first call for its Header.
+	var df *dataframe.DataFrame
+	rowsFromQuery := func(s string) (types.TraceSet, error) {
+		u, err := url.ParseQuery(s)
+		if err != nil {
+			return nil, err
+		}
+		q, err := query.New(u)
+		if err != nil {
+			return nil, err
+		}
+		if df == nil {
+			df, err = dfBuilder.NewNFromQuery(ctx, end, q, n, progress)
+			if err != nil {
+				return nil, err
+			}
+			rows := types.TraceSet{}
+			for k, v := range df.TraceSet {
+				rows[k] = vec32.Dup(v)
+			}
+			return rows, nil
+		}
+		if len(df.Header) == 0 {
+			return types.TraceSet{}, nil
+		}
+		begin := time.Unix(int64(df.Header[0].Timestamp), 0)
+		// The end of the range is exclusive.
+		last := time.Unix(int64(df.Header[len(df.Header)-1].Timestamp), 0).Add(time.Second)
+		other, err := dfBuilder.NewFromQueryAndRange(ctx, begin, last, q, false, progress)
+		if err != nil {
+			return nil, err
+		}
+		return alignToHeader(other, df.Header), nil
+	}
+	calcContext := calc.NewContext(rowsFromQuery, nil)
+	calcContext.Metrics = metrics
+	rows, err := calcContext.Eval(formula)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Calculation failed")
+	}
+	if df == nil {
+		return nil, skerr.Fmt("Formula %q doesn't query any data", formula)
+	}
+	df.TraceSet = rows
+	df.ParamSet = paramtools.NewReadOnlyParamSet()
+	return df, nil
+}
+
+// alignToHeader returns copies of the traces in df laid out over the columns
+// of header. Values at commits that aren't in header are dropped, and commits
+// in header that df doesn't have data for are filled with
+// vec32.MissingDataSentinel.
+func alignToHeader(df *dataframe.DataFrame, header []*dataframe.ColumnHeader) types.TraceSet {
+	columns := make(map[types.CommitNumber]int, len(header))
+	for i, h := range header {
+		columns[h.Offset] = i
+	}
+	ret := make(types.TraceSet, len(df.TraceSet))
+	for key, trace := range df.TraceSet {
+		aligned := vec32.New(len(header))
+		for i, h := range df.Header {
+			if col, ok := columns[h.Offset]; ok && i < len(trace) {
+				aligned[col] = trace[i]
+			}
+		}
+		ret[key] = aligned
+	}
+	return ret
+}
+
+// newDataFrameIterator returns a DataFrameIterator over the dataframes
+// returned by build, which is called with the time of the last commit and the
+// number of commits to include in the dataframe.
+func newDataFrameIterator(
+	ctx context.Context,
+	build func(ctx context.Context, end time.Time, n int32) (*dataframe.DataFrame, error),
+	perfGit perfgit.Git,
+	regressionStateCallback types.ProgressCallback,
+	domain types.Domain,
+	alert *alerts.Alert,
+	anomalyConfig config.AnomalyConfig,
+) (DataFrameIterator, error) {
+	var err error
 	var df *dataframe.DataFrame
 	if domain.Offset == 0 {
 		if anomalyConfig.SettlingTime != 0 {
@@ -102,7 +227,7 @@ func NewDataFrameIterator(
 			}
 		}
 
-		df, err = dfBuilder.NewNFromQuery(ctx, domain.End, q, domain.N, progress)
+		df, err = build(ctx, domain.End, domain.N)
 		if err != nil {
 			if regressionStateCallback != nil {
 				regressionStateCallback("Failed querying the data due to an internal error.")
@@ -141,7 +266,7 @@ func NewDataFrameIterator(
 
 			return nil, skerr.Wrapf(err, "Failed to look up CommitNumber of a single cluster request.")
 		}
-		df, err = dfBuilder.NewNFromQuery(ctx, time.Unix(commit.Timestamp, 0), q, n, progress)
+		df, err = build(ctx, time.Unix(commit.Timestamp, 0), n)
 		if err != nil {
 			if regressionStateCallback != nil {
 				regressionStateCallback("Failed querying the data due to an internal error.")
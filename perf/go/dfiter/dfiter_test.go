@@ -45,6 +45,13 @@ func addValuesAtIndex(store tracestore.TraceStore, index types.CommitNumber, key
 }
 
 func newForTest(t *testing.T) (context.Context, dataframe.DataFrameBuilder, perfgit.Git, time.Time) {
+	ctx, dfb, g, _, lastTimeStamp := newForTestWithStore(t)
+	return ctx, dfb, g, lastTimeStamp
+}
+
+// newForTestWithStore is newForTest that also returns the TraceStore, so tests
+// can add their own traces.
+func newForTestWithStore(t *testing.T) (context.Context, dataframe.DataFrameBuilder, perfgit.Git, tracestore.TraceStore, time.Time) {
 	ctx, db, _, _, _, instanceConfig := gittest.NewForTest(t)
 	g, err := perfgit.New(ctx, true, db, instanceConfig)
 	require.NoError(t, err)
@@ -89,7 +96,7 @@ func newForTest(t *testing.T) (context.Context, dataframe.DataFrameBuilder, perf
 	instanceConfig.DataStoreConfig.TileSize = testTileSize
 	require.NoError(t, err)
 	dfb := dfbuilder.NewDataFrameBuilderFromTraceStore(g, store, 2, false)
-	return ctx, dfb, g, lastTimeStamp
+	return ctx, dfb, g, store, lastTimeStamp
 }
 
 func TestNewDataFrameIterator_MultipleDataframes_SingleFrameOfLengthThree(t *testing.T) {
@@ -131,6 +138,62 @@ func TestNewDataFrameIterator_MultipleDataframes_SingleFrameOfLengthThree(t *tes
 	require.False(t, iter.Next())
 }
 
+func TestNewDataFrameIteratorFromFormula_DerivedMetric_FramesHoldFormulaResults(t *testing.T) {
+	ctx, dfb, g, _ := newForTest(t)
+
+	alert := &alerts.Alert{
+		Radius: 1,
+	}
+	domain := types.Domain{
+		End:    gittest.StartTime.Add(8 * time.Minute), // Some time after the last commit.
+		N:      10,
+		Offset: 0,
+	}
+	metrics := map[string]string{
+		"x86_8888": `filter("arch=x86&config=8888")`,
+	}
+	iter, err := NewDataFrameIteratorFromFormula(ctx, progress.New(), dfb, g, nil, `metric("x86_8888")`, metrics, domain, alert, defaultAnomalyConfig)
+	require.NoError(t, err)
+	require.True(t, iter.Next())
+	df, err := iter.Value(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.TraceSet{
+		",arch=x86,config=8888,": types.Trace{1.2, 1.3, 1.7},
+	}, df.TraceSet)
+	assert.Len(t, df.Header, 3)
+}
+
+func TestNewDataFrameIteratorFromFormula_UnknownDerivedMetric_ReturnsError(t *testing.T) {
+	ctx, dfb, g, _ := newForTest(t)
+
+	alert := &alerts.Alert{
+		Radius: 1,
+	}
+	domain := types.Domain{
+		End: gittest.StartTime.Add(8 * time.Minute),
+		N:   10,
+	}
+	_, err := NewDataFrameIteratorFromFormula(ctx, progress.New(), dfb, g, nil, `metric("unknown")`, map[string]string{}, domain, alert, defaultAnomalyConfig)
+	require.Error(t, err)
+}
+
+func TestDataFrameFromFormula_QueriesOfDifferentSparsity_RowsAlignedToFirstQuery(t *testing.T) {
+	ctx, dfb, _, store, _ := newForTestWithStore(t)
+
+	// Only has data at two of the four commits the other traces have data at.
+	require.NoError(t, addValuesAtIndex(store, 1, map[string]float32{",arch=riscv,config=8888,": 2.6}, "gs://bar.json", gittest.StartTime.Add(time.Minute)))
+	require.NoError(t, addValuesAtIndex(store, 8, map[string]float32{",arch=riscv,config=8888,": 3.6}, "gs://bar.json", gittest.StartTime.Add(8*time.Minute)))
+
+	df, err := dataFrameFromFormula(ctx, dfb, `ratio(filter("arch=riscv"), filter("arch=x86&config=8888"))`, nil, gittest.StartTime.Add(9*time.Minute), 10, progress.New())
+	require.NoError(t, err)
+	require.Len(t, df.Header, 2)
+	assert.Equal(t, types.CommitNumber(1), df.Header[0].Offset)
+	assert.Equal(t, types.CommitNumber(8), df.Header[1].Offset)
+	assert.Equal(t, types.TraceSet{
+		`ratio(filter("arch=riscv"), filter("arch=x86&config=8888"))`: types.Trace{2, 2},
+	}, df.TraceSet)
+}
+
 func TestNewDataFrameIterator_MultipleDataframes_TwoFramesOfLengthTwo(t *testing.T) {
 	ctx, dfb, g, _ := newForTest(t)
 
@@ -14,7 +14,9 @@ import (
 	"go.goldmine.build/go/sklog"
 	"go.goldmine.build/perf/go/config"
 	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/derivedmetrics"
 	perfgit "go.goldmine.build/perf/go/git"
+	"go.goldmine.build/perf/go/obfuscate"
 	"go.goldmine.build/perf/go/progress"
 	"go.goldmine.build/perf/go/regression"
 	"go.goldmine.build/perf/go/shortcut"
@@ -34,16 +36,24 @@ type Requests struct {
 	dfBuilder     dataframe.DataFrameBuilder
 	tracker       progress.Tracker
 	paramsProvier regression.ParamsetProvider
+
+	derivedMetrics derivedmetrics.Store
+
+	// obfuscatorFor returns the Obfuscator to apply to the results of the
+	// given request, or nil if the results are returned as is.
+	obfuscatorFor func(*http.Request) *obfuscate.Obfuscator
 }
 
 // New create a new dryrun Request processor.
-func New(perfGit perfgit.Git, tracker progress.Tracker, shortcutStore shortcut.Store, dfBuilder dataframe.DataFrameBuilder, paramsProvider regression.ParamsetProvider) *Requests {
+func New(perfGit perfgit.Git, tracker progress.Tracker, shortcutStore shortcut.Store, dfBuilder dataframe.DataFrameBuilder, paramsProvider regression.ParamsetProvider, derivedMetrics derivedmetrics.Store, obfuscatorFor func(*http.Request) *obfuscate.Obfuscator) *Requests {
 	ret := &Requests{
-		perfGit:       perfGit,
-		shortcutStore: shortcutStore,
-		dfBuilder:     dfBuilder,
-		tracker:       tracker,
-		paramsProvier: paramsProvider,
+		perfGit:        perfGit,
+		shortcutStore:  shortcutStore,
+		dfBuilder:      dfBuilder,
+		tracker:        tracker,
+		paramsProvier:  paramsProvider,
+		derivedMetrics: derivedMetrics,
+		obfuscatorFor:  obfuscatorFor,
 	}
 	return ret
 }
@@ -62,7 +72,7 @@ func (d *Requests) StartHandler(w http.ResponseWriter, r *http.Request) {
 	auditlog.LogWithUser(r, "", "dryrun", req)
 	d.tracker.Add(req.Progress)
 
-	if req.Alert.Query == "" {
+	if req.Alert.Query == "" && req.Alert.DerivedMetric == "" {
 		req.Progress.Error("Query must not be empty.")
 		if err := req.Progress.JSON(w); err != nil {
 			sklog.Errorf("Failed to encode paramset: %s", err)
@@ -77,6 +87,19 @@ func (d *Requests) StartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Alert.DerivedMetric != "" {
+		formulas, err := derivedmetrics.Formulas(ctx, d.derivedMetrics)
+		if err != nil {
+			req.Progress.Error(err.Error())
+			if err := req.Progress.JSON(w); err != nil {
+				sklog.Errorf("Failed to encode paramset: %s", err)
+			}
+			return
+		}
+		req.SetDerivedMetrics(formulas)
+	}
+
+	o := d.obfuscatorFor(r)
 	foundRegressions := map[types.CommitNumber]*regression.Regression{}
 
 	// Create a callback that will be passed each found Regression. It will
@@ -121,9 +144,13 @@ func (d *Requests) StartHandler(w http.ResponseWriter, r *http.Request) {
 				sklog.Errorf("Failed to look up commit %d: %s", commitNumber, err)
 				continue
 			}
+			reg := foundRegressions[commitNumber]
+			if o != nil {
+				reg = regression.Obfuscate(o, reg)
+			}
 			regressions = append(regressions, &RegressionAtCommit{
 				CID:        details,
-				Regression: foundRegressions[commitNumber],
+				Regression: reg,
 			})
 		}
 		req.Progress.Results(regressions)
@@ -35,6 +35,7 @@ import (
 	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/query"
 	"go.goldmine.build/go/roles"
+	"go.goldmine.build/go/secret"
 	"go.goldmine.build/go/skerr"
 	"go.goldmine.build/go/sklog"
 	"go.goldmine.build/go/sklog/sklogimpl"
@@ -43,20 +44,27 @@ import (
 	"go.goldmine.build/perf/go/alerts"
 	"go.goldmine.build/perf/go/bug"
 	"go.goldmine.build/perf/go/builders"
+	"go.goldmine.build/perf/go/chart"
+	"go.goldmine.build/perf/go/clustering2"
 	"go.goldmine.build/perf/go/config"
 	"go.goldmine.build/perf/go/config/validate"
 	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/derivedmetrics"
 	"go.goldmine.build/perf/go/dfbuilder"
 	"go.goldmine.build/perf/go/dryrun"
 	perfgit "go.goldmine.build/perf/go/git"
 	"go.goldmine.build/perf/go/graphsshortcut"
+	"go.goldmine.build/perf/go/heatmap"
 	"go.goldmine.build/perf/go/ingest/format"
+	"go.goldmine.build/perf/go/ingest/parser"
 	"go.goldmine.build/perf/go/notify"
 	"go.goldmine.build/perf/go/notifytypes"
+	"go.goldmine.build/perf/go/obfuscate"
 	"go.goldmine.build/perf/go/progress"
 	"go.goldmine.build/perf/go/psrefresh"
 	"go.goldmine.build/perf/go/regression"
 	"go.goldmine.build/perf/go/regression/continuous"
+	"go.goldmine.build/perf/go/regression/fixcheck"
 	"go.goldmine.build/perf/go/shortcut"
 	"go.goldmine.build/perf/go/tracestore"
 	"go.goldmine.build/perf/go/tracing"
@@ -97,6 +105,20 @@ const (
 	// making a request that involves the database. For more complex requests
 	// use config.QueryMaxRuntime.
 	defaultDatabaseTimeout = time.Minute
+
+	// defaultEmbedWidth and defaultEmbedHeight are the size, in pixels, of an
+	// embedded chart if the size isn't specified.
+	defaultEmbedWidth  = 800
+	defaultEmbedHeight = 400
+
+	// minEmbedSize and maxEmbedSize are the bounds, in pixels, on the width
+	// and height of an embedded chart.
+	minEmbedSize = 100
+	maxEmbedSize = 4000
+
+	// defaultReadOnlyMessage is displayed to users when the instance is in
+	// read-only mode and no other message was given.
+	defaultReadOnlyMessage = "Perf is in read-only mode. Changes can not be saved at this time."
 )
 
 var (
@@ -130,6 +152,10 @@ type Frontend struct {
 
 	alertStore alerts.Store
 
+	alertTemplateStore alerts.TemplateStore
+
+	derivedMetricStore derivedmetrics.Store
+
 	shortcutStore shortcut.Store
 
 	configProvider alerts.ConfigProvider
@@ -156,12 +182,27 @@ type Frontend struct {
 	// progressTracker tracks long running web requests.
 	progressTracker progress.Tracker
 
+	// runningFrameRequests tracks the FrameRequests being processed, so their
+	// partial results can be returned.
+	runningFrameRequests *frame.RunningFrameRequests
+
 	loginProvider alogin.Login
 
+	// obfuscator, if not nil, hides the values of some params from users that
+	// are not logged in. See config.ObfuscationConfig.
+	obfuscator *obfuscate.Obfuscator
+
 	// The HOST parsed out of Config.URL.
 	host string
 
 	urlProvider *urlprovider.URLProvider
+
+	// readOnlyMutex protects readOnlyMessage.
+	readOnlyMutex sync.RWMutex
+
+	// readOnlyMessage is non-empty if the instance is in read-only mode, in
+	// which case it is the message displayed to users.
+	readOnlyMessage string
 }
 
 // New returns a new Frontend instance.
@@ -169,6 +210,9 @@ func New(flags *config.FrontendFlags) (*Frontend, error) {
 	f := &Frontend{
 		flags: flags,
 	}
+	if flags.ReadOnly {
+		f.setReadOnly(true, "")
+	}
 	f.initialize()
 
 	return f, nil
@@ -200,6 +244,7 @@ var templateFilenames = []string{
 	"trybot.html",
 	"favorites.html",
 	"revisions.html",
+	"embed.html",
 }
 
 func (f *Frontend) loadTemplatesImpl() {
@@ -302,6 +347,120 @@ func (f *Frontend) templateHandler(name string) http.HandlerFunc {
 	}
 }
 
+// embedDimension parses a width or height value for an embedded chart,
+// returning the default value if it is missing or invalid, and clamping it to
+// a sane range otherwise.
+func embedDimension(value string, defaultValue int) int {
+	ret, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	if ret < minEmbedSize {
+		return minEmbedSize
+	}
+	if ret > maxEmbedSize {
+		return maxEmbedSize
+	}
+	return ret
+}
+
+// embedHandler serves a minimal, read-only chart page with no navigation or
+// query controls, suitable for including in an iframe. All query parameters
+// other than 'width' and 'height' are interpreted by the page the same way
+// the explore page does, e.g. 'keys' for a shortcut id.
+func (f *Frontend) embedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	f.loadTemplates()
+	context, err := f.getPageContext()
+	if err != nil {
+		sklog.Errorf("Failed to JSON encode window.perf context: %s", err)
+	}
+	oembedURL := fmt.Sprintf("%s/oembed?format=json&url=%s", config.Config.URL, url.QueryEscape(config.Config.URL+r.URL.RequestURI()))
+	if err := f.templates.ExecuteTemplate(w, "embed.html", map[string]interface{}{
+		"context":                      context,
+		"GoogleAnalyticsMeasurementID": config.Config.GoogleAnalyticsMeasurementID,
+		"Nonce":                        secure.CSPNonce(r.Context()),
+		"Width":                        embedDimension(r.FormValue("width"), defaultEmbedWidth),
+		"Height":                       embedDimension(r.FormValue("height"), defaultEmbedHeight),
+		"OEmbedURL":                    oembedURL,
+	}); err != nil {
+		sklog.Error("Failed to expand template:", err)
+	}
+}
+
+// OEmbedResponse is the response to an oEmbed request, see https://oembed.com.
+type OEmbedResponse struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// oembedResponseFromURL builds the oEmbed response for the given URL of either
+// an explore page or an embed page on this instance.
+func oembedResponseFromURL(instanceURL, target string, maxWidth, maxHeight string) (OEmbedResponse, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return OEmbedResponse{}, skerr.Wrapf(err, "Invalid url.")
+	}
+	instance, err := url.Parse(instanceURL)
+	if err != nil {
+		return OEmbedResponse{}, skerr.Wrapf(err, "Invalid instance url.")
+	}
+	if u.Host != instance.Host {
+		return OEmbedResponse{}, skerr.Fmt("URL %q is not hosted by this instance.", target)
+	}
+	if u.Path != "/e/" && u.Path != "/embed/" {
+		return OEmbedResponse{}, skerr.Fmt("URL %q can not be embedded.", target)
+	}
+	q := u.Query()
+	width := embedDimension(q.Get("width"), defaultEmbedWidth)
+	height := embedDimension(q.Get("height"), defaultEmbedHeight)
+	if mw := embedDimension(maxWidth, maxEmbedSize); mw < width {
+		width = mw
+	}
+	if mh := embedDimension(maxHeight, maxEmbedSize); mh < height {
+		height = mh
+	}
+	q.Set("width", strconv.Itoa(width))
+	q.Set("height", strconv.Itoa(height))
+	src := fmt.Sprintf("%s/embed/?%s", instanceURL, q.Encode())
+
+	return OEmbedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		Title:        "Perf",
+		ProviderName: "Perf",
+		ProviderURL:  instanceURL,
+		HTML:         fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" frameborder="0"></iframe>`, template.HTMLEscapeString(src), width, height),
+		Width:        width,
+		Height:       height,
+	}, nil
+}
+
+// oembedHandler implements the oEmbed protocol, see https://oembed.com, for
+// explore and embed pages. Only the JSON format is supported.
+func (f *Frontend) oembedHandler(w http.ResponseWriter, r *http.Request) {
+	if format := r.FormValue("format"); format != "" && format != "json" {
+		http.Error(w, "Only the json format is supported.", http.StatusNotImplemented)
+		return
+	}
+	resp, err := oembedResponseFromURL(config.Config.URL, r.FormValue("url"), r.FormValue("maxwidth"), r.FormValue("maxheight"))
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to build oEmbed response.", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to encode oEmbed response: %s", err)
+	}
+}
+
 // newParamsetProvider returns a regression.ParamsetProvider which produces a paramset
 // for the current tiles.
 func newParamsetProvider(pf *psrefresh.ParamSetRefresher) regression.ParamsetProvider {
@@ -331,6 +490,7 @@ func (f *Frontend) initialize() {
 		sklog.Fatalf("Failed to initialize Tracker: %s", err)
 	}
 	f.progressTracker.Start(ctx)
+	f.runningFrameRequests = frame.NewRunningFrameRequests()
 
 	// Keep HTTP request metrics.
 	severities := sklogimpl.AllSeverities()
@@ -374,6 +534,12 @@ func (f *Frontend) initialize() {
 	if err != nil {
 		sklog.Fatalf("Failed to initialize login: %s", err)
 	}
+	if cfg.Obfuscation != nil {
+		f.obfuscator, err = newObfuscator(ctx, cfg.Obfuscation)
+		if err != nil {
+			sklog.Fatalf("Failed to initialize obfuscation: %s", err)
+		}
+	}
 
 	// Fix up resources dir values.
 	if f.flags.ResourcesDir == "" {
@@ -455,6 +621,14 @@ func (f *Frontend) initialize() {
 	if err != nil {
 		sklog.Fatal(err)
 	}
+	f.alertTemplateStore, err = builders.NewAlertTemplateStoreFromConfig(ctx, f.flags.Local, config.Config)
+	if err != nil {
+		sklog.Fatal(err)
+	}
+	f.derivedMetricStore, err = builders.NewDerivedMetricStoreFromConfig(ctx, f.flags.Local, config.Config)
+	if err != nil {
+		sklog.Fatal(err)
+	}
 	f.shortcutStore, err = builders.NewShortcutStoreFromConfig(ctx, f.flags.Local, config.Config)
 	if err != nil {
 		sklog.Fatal(err)
@@ -482,19 +656,23 @@ func (f *Frontend) initialize() {
 	}
 	paramsProvider := newParamsetProvider(f.paramsetRefresher)
 
-	f.dryrunRequests = dryrun.New(f.perfGit, f.progressTracker, f.shortcutStore, f.dfBuilder, paramsProvider)
+	f.dryrunRequests = dryrun.New(f.perfGit, f.progressTracker, f.shortcutStore, f.dfBuilder, paramsProvider, f.derivedMetricStore, f.obfuscatorFor)
 
 	if f.flags.DoClustering {
 		go func() {
 			for i := 0; i < f.flags.NumContinuousParallel; i++ {
 				// Start running continuous clustering looking for regressions.
 				time.Sleep(startClusterDelay)
-				c := continuous.New(f.perfGit, f.shortcutStore, f.configProvider, f.regStore, f.notifier, paramsProvider, f.dfBuilder,
-					cfg, f.flags)
+				c := continuous.New(f.perfGit, f.shortcutStore, f.configProvider, f.regStore, f.notifier, paramsProvider, f.dfBuilder, f.traceStore,
+					f.derivedMetricStore, cfg, f.flags)
 				f.continuous = append(f.continuous, c)
 				go c.Run(context.Background())
 			}
 		}()
+
+		// Verify the fixes of regressions triaged as fixed by a commit.
+		fixcheck.New(f.perfGit, f.regStore, f.configProvider, f.shortcutStore, f.dfBuilder,
+			notify.NewUserNotifier(&config.Config.NotifyConfig), f.flags.Radius, config.Config.URL).Start(ctx)
 	}
 }
 
@@ -545,12 +723,18 @@ func (f *Frontend) alertsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (f *Frontend) initpageHandler(w http.ResponseWriter, _ *http.Request) {
+func (f *Frontend) initpageHandler(w http.ResponseWriter, r *http.Request) {
+	_, msg := f.readOnlyStatus()
+	ps := f.getParamSet()
+	if o := f.obfuscatorFor(r); o != nil {
+		ps = o.ParamSet(ps)
+	}
 	resp := &frame.FrameResponse{
 		DataFrame: &dataframe.DataFrame{
-			ParamSet: f.getParamSet(),
+			ParamSet: ps,
 		},
 		Skps: []int{},
+		Msg:  msg,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -566,6 +750,7 @@ func (f *Frontend) trybotLoadHandler(w http.ResponseWriter, r *http.Request) {
 		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusInternalServerError)
 		return
 	}
+	o := f.obfuscatorFor(r)
 	prog := progress.New()
 	f.progressTracker.Add(prog)
 	go func() {
@@ -581,6 +766,9 @@ func (f *Frontend) trybotLoadHandler(w http.ResponseWriter, r *http.Request) {
 			sklog.Errorf("trybot failed to load results: %s", err)
 			return
 		}
+		if o != nil {
+			resp = obfuscateTryBotResponse(o, resp)
+		}
 		prog.FinishedWithResults(resp)
 	}()
 	if err := prog.JSON(w); err != nil {
@@ -591,10 +779,15 @@ func (f *Frontend) trybotLoadHandler(w http.ResponseWriter, r *http.Request) {
 // RangeRequest is used in cidRangeHandler and is used to query for a range of
 // cid.CommitIDs that include the range between [begin, end) and include the
 // explicit CommitID of "Source, Offset".
+//
+// BeginCommit and EndCommit, if set, address the range by commit number
+// instead, and take precedence over Begin and End.
 type RangeRequest struct {
-	Offset types.CommitNumber `json:"offset"`
-	Begin  int64              `json:"begin"`
-	End    int64              `json:"end"`
+	Offset      types.CommitNumber  `json:"offset"`
+	Begin       int64               `json:"begin"`
+	End         int64               `json:"end"`
+	BeginCommit *types.CommitNumber `json:"begin_commit,omitempty"`
+	EndCommit   *types.CommitNumber `json:"end_commit,omitempty"`
 }
 
 // cidRangeHandler accepts a POST'd JSON serialized RangeRequest
@@ -609,8 +802,13 @@ func (f *Frontend) cidRangeHandler(w http.ResponseWriter, r *http.Request) {
 		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusInternalServerError)
 		return
 	}
+	begin, end, err := f.unixTimestampRange(ctx, rr.Begin, rr.End, rr.BeginCommit, rr.EndCommit)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid commit range.", http.StatusBadRequest)
+		return
+	}
 
-	resp, err := f.perfGit.CommitSliceFromTimeRange(ctx, time.Unix(rr.Begin, 0), time.Unix(rr.End, 0))
+	resp, err := f.perfGit.CommitSliceFromTimeRange(ctx, time.Unix(begin, 0), time.Unix(end, 0))
 	if err != nil {
 		httputils.ReportError(w, err, "Failed to look up commits", http.StatusInternalServerError)
 		return
@@ -655,6 +853,9 @@ func (f *Frontend) cidRangeHandler(w http.ResponseWriter, r *http.Request) {
 //     running request, {id}.
 //   - Query the status of the running request (_/frame/status/{id}).
 //   - Finally return the constructed DataFrame (_/frame/results/{id}).
+//
+// While the request is running the traces loaded so far are available at
+// _/frame/partial/{id}, and the request can be stopped via _/frame/cancel/{id}.
 func (f *Frontend) frameStartHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	fr := frame.NewFrameRequest()
@@ -677,7 +878,40 @@ func (f *Frontend) frameStartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	f.progressTracker.Add(fr.Progress)
+	begin, end, err := f.unixTimestampRange(r.Context(), int64(fr.Begin), int64(fr.End), fr.BeginCommit, fr.EndCommit)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid commit range.", http.StatusBadRequest)
+		return
+	}
+	fr.Begin = int(begin)
+	fr.End = int(end)
+
+	if o := f.obfuscatorFor(r); o != nil {
+		d := o.Deobfuscator(f.paramsetRefresher.Get())
+		for i, q := range fr.Queries {
+			raw, err := d.ReplaceQuery(q)
+			if err != nil {
+				httputils.ReportError(w, err, "Invalid query.", http.StatusBadRequest)
+				return
+			}
+			fr.Queries[i] = raw
+		}
+		for i, formula := range fr.Formulas {
+			fr.Formulas[i] = d.Replace(formula)
+		}
+		fr.Obfuscator = o
+	}
+
+	if len(fr.Formulas) > 0 {
+		formulas, err := derivedmetrics.Formulas(r.Context(), f.derivedMetricStore)
+		if err != nil {
+			httputils.ReportError(w, err, "Failed to load derived metrics.", http.StatusInternalServerError)
+			return
+		}
+		fr.DerivedMetrics = formulas
+	}
+
+	id := f.progressTracker.Add(fr.Progress)
 	go func() {
 		// Intentionally using a background context here because the calculation will go on in the background after
 		// the request finishes
@@ -685,7 +919,7 @@ func (f *Frontend) frameStartHandler(w http.ResponseWriter, r *http.Request) {
 		timeoutCtx, cancel := context.WithTimeout(ctx, config.QueryMaxRunTime)
 		defer cancel()
 		defer span.End()
-		err := frame.ProcessFrameRequest(timeoutCtx, fr, f.perfGit, f.dfBuilder, f.shortcutStore)
+		err := f.runningFrameRequests.Process(timeoutCtx, id, fr, f.perfGit, f.dfBuilder, f.shortcutStore)
 		if err != nil {
 			fr.Progress.Error(err.Error())
 		} else {
@@ -698,6 +932,33 @@ func (f *Frontend) frameStartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// framePartialHandler returns a frame.PartialFrameResponse with the traces
+// loaded so far by a running FrameRequest, so they can be displayed before the
+// request completes. Returns 404 if the request is not running, e.g. because it
+// has already finished, in which case the results are available via
+// _/status/{id}.
+func (f *Frontend) framePartialHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	partial, ok := f.runningFrameRequests.Partial(chi.URLParam(r, "id"))
+	if !ok {
+		httputils.ReportError(w, fmt.Errorf("Not running."), "No running request with that id.", http.StatusNotFound)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(partial); err != nil {
+		sklog.Errorf("Failed to encode partial frame response: %s", err)
+	}
+}
+
+// frameCancelHandler stops a running FrameRequest, for example once the user
+// has seen enough of the partial results. Returns 404 if the request is not
+// running.
+func (f *Frontend) frameCancelHandler(w http.ResponseWriter, r *http.Request) {
+	if !f.runningFrameRequests.Cancel(chi.URLParam(r, "id")) {
+		httputils.ReportError(w, fmt.Errorf("Not running."), "No running request with that id.", http.StatusNotFound)
+		return
+	}
+}
+
 // CountHandlerRequest is the JSON format for the countHandler request.
 type CountHandlerRequest struct {
 	Q     string `json:"q"`
@@ -724,6 +985,16 @@ func (f *Frontend) countHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	o := f.obfuscatorFor(r)
+	if o != nil {
+		raw, err := o.Deobfuscator(f.paramsetRefresher.Get()).ReplaceQuery(cr.Q)
+		if err != nil {
+			httputils.ReportError(w, err, "Invalid URL query.", http.StatusInternalServerError)
+			return
+		}
+		cr.Q = raw
+	}
+
 	u, err := url.ParseQuery(cr.Q)
 	if err != nil {
 		httputils.ReportError(w, err, "Invalid URL query.", http.StatusInternalServerError)
@@ -749,11 +1020,142 @@ func (f *Frontend) countHandler(w http.ResponseWriter, r *http.Request) {
 		resp.Count = int(count)
 		resp.Paramset = filterParamSetIfNeeded(ps.Freeze())
 	}
+	if o != nil {
+		resp.Paramset = o.ParamSet(resp.Paramset)
+	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		sklog.Errorf("Failed to encode paramset: %s", err)
 	}
 }
 
+// heatmapHandler takes the POST'd heatmap.Request and returns a heatmap.Heatmap
+// of the matching traces over the requested range of commits.
+func (f *Frontend) heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	var hr heatmap.Request
+	if err := json.NewDecoder(r.Body).Decode(&hr); err != nil {
+		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusBadRequest)
+		return
+	}
+	if hr.End <= hr.Begin {
+		httputils.ReportError(w, skerr.Fmt("end %d is not after begin %d", hr.End, hr.Begin), "The end of the range must be after its beginning.", http.StatusBadRequest)
+		return
+	}
+	beginCommit, endCommit, err := f.unixTimestampRangeToCommitNumberRange(ctx, int64(hr.Begin), int64(hr.End))
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid time range.", http.StatusBadRequest)
+		return
+	}
+	if endCommit-beginCommit > heatmap.MaxCommits {
+		httputils.ReportError(w, skerr.Fmt("range spans %d commits", endCommit-beginCommit), fmt.Sprintf("The range spans too many commits, the limit is %d.", heatmap.MaxCommits), http.StatusBadRequest)
+		return
+	}
+
+	o := f.obfuscatorFor(r)
+	if o != nil {
+		raw, err := o.Deobfuscator(f.paramsetRefresher.Get()).ReplaceQuery(hr.Q)
+		if err != nil {
+			httputils.ReportError(w, err, "Invalid URL query.", http.StatusBadRequest)
+			return
+		}
+		hr.Q = raw
+	}
+
+	u, err := url.ParseQuery(hr.Q)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid URL query.", http.StatusBadRequest)
+		return
+	}
+	q, err := query.New(u)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid query.", http.StatusBadRequest)
+		return
+	}
+	if q.Empty() {
+		httputils.ReportError(w, skerr.Fmt("empty query"), "A query is required.", http.StatusBadRequest)
+		return
+	}
+	count, _, err := f.dfBuilder.PreflightQuery(ctx, q, f.getParamSet())
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to Preflight the query, too many key-value pairs selected. Limit is 200.", http.StatusBadRequest)
+		return
+	}
+	if count > heatmap.MaxTraces {
+		httputils.ReportError(w, skerr.Fmt("query matches %d traces", count), fmt.Sprintf("The query matches too many traces, the limit is %d.", heatmap.MaxTraces), http.StatusBadRequest)
+		return
+	}
+
+	df, err := f.dfBuilder.NewFromQueryAndRange(ctx, time.Unix(int64(hr.Begin), 0), time.Unix(int64(hr.End), 0), q, false, progress.New())
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to load traces.", http.StatusInternalServerError)
+		return
+	}
+	maxRows, maxCols := hr.Limits()
+	resp := heatmap.New(df, maxRows, maxCols)
+	if o != nil {
+		for i, key := range resp.Rows {
+			resp.Rows[i] = o.Key(key)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to encode heatmap: %s", err)
+	}
+}
+
+// maxSimulatedFileSize is the largest file accepted by ingestSimulateHandler
+// and ingestConvertHandler.
+const maxSimulatedFileSize = 10 * 1024 * 1024
+
+// ingestSimulateHandler runs the POST'd results file through the ingestion
+// parser, without writing anything, and returns a parser.Simulation of the
+// traces it would create, so new users can check their uploads before setting
+// up ingestion.
+func (f *Frontend) ingestSimulateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if f.loginProvider.LoggedInAs(r) == alogin.NotLoggedIn {
+		httputils.ReportError(w, fmt.Errorf("Not logged in."), "You must be logged in to complete this action.", http.StatusUnauthorized)
+		return
+	}
+
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSimulatedFileSize))
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to read the file.", http.StatusBadRequest)
+		return
+	}
+	p, err := parser.New(config.Config)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to create parser.", http.StatusInternalServerError)
+		return
+	}
+	sim, err := p.Simulate(b, "upload.json")
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to parse the file.", http.StatusBadRequest)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(sim); err != nil {
+		sklog.Errorf("Failed to encode simulation: %s", err)
+	}
+}
+
+// ingestConvertHandler converts a POST'd nanobench results file, i.e.
+// format.BenchData, into the current ingestion format.Format, which produces
+// the same traces when ingested. This lets harnesses that still emit the
+// legacy format keep uploading while they are migrated.
+func (f *Frontend) ingestConvertHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	benchData, err := format.ParseLegacyFormat(http.MaxBytesReader(w, r.Body, maxSimulatedFileSize))
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to parse the file.", http.StatusBadRequest)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(format.ConvertLegacyFormat(benchData)); err != nil {
+		sklog.Errorf("Failed to encode converted file: %s", err)
+	}
+}
+
 // CIDHandlerResponse is the form of the response from the /_/cid/ endpoint.
 type CIDHandlerResponse struct {
 	// CommitSlice describes all the commits requested.
@@ -819,9 +1221,23 @@ func (f *Frontend) clusterStartHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	auditlog.LogWithUser(r, f.loginProvider.LoggedInAs(r).String(), "cluster", req)
 
+	if req.Alert != nil && req.Alert.DerivedMetric != "" {
+		formulas, err := derivedmetrics.Formulas(r.Context(), f.derivedMetricStore)
+		if err != nil {
+			httputils.ReportError(w, err, "Failed to load derived metrics.", http.StatusInternalServerError)
+			return
+		}
+		req.SetDerivedMetrics(formulas)
+	}
+
+	o := f.obfuscatorFor(r)
 	cb := func(ctx context.Context, _ *regression.RegressionDetectionRequest, clusterResponse []*regression.RegressionDetectionResponse, _ string) {
 		// We don't do GroupBy clustering, so there will only be one clusterResponse.
-		req.Progress.Results(clusterResponse[0])
+		resp := clusterResponse[0]
+		if o != nil {
+			resp = regression.ObfuscateResponse(o, resp)
+		}
+		req.Progress.Results(resp)
 	}
 	f.progressTracker.Add(req.Progress)
 
@@ -860,7 +1276,18 @@ func (f *Frontend) keysHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 	w.Header().Set("Content-Type", "application/json")
 
-	id, err := f.shortcutStore.Insert(ctx, r.Body)
+	body := io.Reader(r.Body)
+	if o := f.obfuscatorFor(r); o != nil {
+		// The keys come from obfuscated results, so store the raw keys.
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			httputils.ReportError(w, err, "Failed to read body.", http.StatusInternalServerError)
+			return
+		}
+		body = strings.NewReader(o.Deobfuscator(f.paramsetRefresher.Get()).Replace(string(b)))
+	}
+
+	id, err := f.shortcutStore.Insert(ctx, body)
 	if err != nil {
 		httputils.ReportError(w, err, "Error inserting shortcut.", http.StatusInternalServerError)
 		return
@@ -891,6 +1318,9 @@ func (f *Frontend) getGraphsShortcutHandler(w http.ResponseWriter, r *http.Reque
 		httputils.ReportError(w, err, "Failed to get keys shortcut.", http.StatusInternalServerError)
 		return
 	}
+	if o := f.obfuscatorFor(r); o != nil {
+		sc = obfuscateGraphsShortcut(o, sc)
+	}
 
 	if err := json.NewEncoder(w).Encode(sc); err != nil {
 		sklog.Errorf("Failed to write or encode output: %s", err)
@@ -907,6 +1337,23 @@ func (f *Frontend) createGraphsShortcutHandler(w http.ResponseWriter, r *http.Re
 		httputils.ReportError(w, err, "Unable to read shortcut body.", http.StatusInternalServerError)
 		return
 	}
+	if o := f.obfuscatorFor(r); o != nil {
+		// The queries come from obfuscated results, so store the raw queries.
+		d := o.Deobfuscator(f.paramsetRefresher.Get())
+		for _, g := range shortcut.Graphs {
+			for i, q := range g.Queries {
+				raw, err := d.ReplaceQuery(q)
+				if err != nil {
+					httputils.ReportError(w, err, "Invalid query.", http.StatusBadRequest)
+					return
+				}
+				g.Queries[i] = raw
+			}
+			for i, formula := range g.Formulas {
+				g.Formulas[i] = d.Replace(formula)
+			}
+		}
+	}
 
 	id, err := f.graphsShortcutStore.InsertShortcut(ctx, shortcut)
 	if err != nil {
@@ -1000,6 +1447,77 @@ func (f *Frontend) isEditor(w http.ResponseWriter, r *http.Request, action strin
 	return true
 }
 
+func (f *Frontend) isAdmin(w http.ResponseWriter, r *http.Request, action string, body interface{}) bool {
+	user := f.loginProvider.LoggedInAs(r)
+	if !f.loginProvider.HasRole(r, roles.Admin) {
+		httputils.ReportError(w, fmt.Errorf("Not an admin."), "You must be an admin to complete this action.", http.StatusUnauthorized)
+		return false
+	}
+	auditlog.LogWithUser(r, user.String(), action, body)
+	return true
+}
+
+// readOnlyStatus returns true and the message to display to users if the
+// instance is in read-only mode.
+func (f *Frontend) readOnlyStatus() (bool, string) {
+	f.readOnlyMutex.RLock()
+	defer f.readOnlyMutex.RUnlock()
+	return f.readOnlyMessage != "", f.readOnlyMessage
+}
+
+// setReadOnly puts the instance in, or takes it out of, read-only mode. If msg
+// is empty then defaultReadOnlyMessage is used.
+func (f *Frontend) setReadOnly(readOnly bool, msg string) {
+	f.readOnlyMutex.Lock()
+	defer f.readOnlyMutex.Unlock()
+	if !readOnly {
+		f.readOnlyMessage = ""
+		return
+	}
+	if msg == "" {
+		msg = defaultReadOnlyMessage
+	}
+	f.readOnlyMessage = msg
+}
+
+// rejectIfReadOnly wraps a handler that modifies data so that it returns a
+// 503 while the instance is in read-only mode.
+func (f *Frontend) rejectIfReadOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if readOnly, msg := f.readOnlyStatus(); readOnly {
+			httputils.ReportError(w, skerr.Fmt("Rejected %s %s: instance is read-only", r.Method, r.URL.Path), msg, http.StatusServiceUnavailable)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// ReadOnlyStatus is both the request and the response of readOnlyHandler.
+type ReadOnlyStatus struct {
+	ReadOnly bool   `json:"read_only"`
+	Message  string `json:"message"`
+}
+
+// readOnlyHandler allows an admin to put the instance in, or take it out of,
+// read-only mode without a restart.
+func (f *Frontend) readOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req ReadOnlyStatus
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusBadRequest)
+		return
+	}
+	if !f.isAdmin(w, r, "read-only", req) {
+		return
+	}
+	f.setReadOnly(req.ReadOnly, req.Message)
+	readOnly, msg := f.readOnlyStatus()
+	sklog.Infof("Read-only mode set to %t: %q", readOnly, msg)
+	if err := json.NewEncoder(w).Encode(ReadOnlyStatus{ReadOnly: readOnly, Message: msg}); err != nil {
+		sklog.Errorf("Failed to write or encode output: %s", err)
+	}
+}
+
 // TriageRequest is used in triageHandler.
 type TriageRequest struct {
 	Cid         types.CommitNumber      `json:"cid"`
@@ -1030,6 +1548,19 @@ func (f *Frontend) triageHandler(w http.ResponseWriter, r *http.Request) {
 	if !f.isEditor(w, r, "triage", tr) {
 		return
 	}
+	if tr.Triage.FixedBy != 0 {
+		if tr.Triage.Status != regression.Negative {
+			httputils.ReportError(w, skerr.Fmt("status is %q", tr.Triage.Status), "Only regressions triaged as negative can be fixed by a commit.", http.StatusBadRequest)
+			return
+		}
+		if tr.Triage.FixedBy <= tr.Cid {
+			httputils.ReportError(w, skerr.Fmt("fixed by %d, found at %d", tr.Triage.FixedBy, tr.Cid), "The fixing commit must come after the regression.", http.StatusBadRequest)
+			return
+		}
+	}
+	// A new triage needs a new check of the fix, if any.
+	tr.Triage.Fix = regression.FixUnchecked
+	tr.Triage.TriagedBy = f.loginProvider.LoggedInAs(r).String()
 	detail, err := f.perfGit.CommitFromCommitNumber(ctx, tr.Cid)
 	if err != nil {
 		httputils.ReportError(w, err, "Failed to find CommitID.", http.StatusInternalServerError)
@@ -1065,7 +1596,14 @@ func (f *Frontend) triageHandler(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 		}
-		resp.Bug = bug.Expand(uritemplate, link, detail, tr.Triage.Message)
+		chartQuery := url.Values{
+			"cid":    []string{strconv.Itoa(int(detail.CommitNumber))},
+			"alert":  []string{tr.Alert.IDAsString},
+			"dir":    []string{tr.ClusterType},
+			"format": []string{"png"},
+		}
+		chartLink := fmt.Sprintf("%s/_/reg/chart?%s", r.Header.Get("Origin"), chartQuery.Encode())
+		resp.Bug = bug.Expand(uritemplate, link, chartLink, detail, tr.Triage.Message)
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		sklog.Errorf("Failed to write or encode output: %s", err)
@@ -1088,6 +1626,29 @@ func (f *Frontend) unixTimestampRangeToCommitNumberRange(ctx context.Context, be
 	return beginCommitNumber, endCommitNumber, nil
 }
 
+// unixTimestampRange returns the range of Unix timestamps in seconds, [begin,
+// end), given either as timestamps or as the commit numbers beginCommit and
+// endCommit, which take precedence if they are not nil. The returned range
+// includes both beginCommit and endCommit.
+func (f *Frontend) unixTimestampRange(ctx context.Context, begin, end int64, beginCommit, endCommit *types.CommitNumber) (int64, int64, error) {
+	if beginCommit != nil {
+		commit, err := f.perfGit.CommitFromCommitNumber(ctx, *beginCommit)
+		if err != nil {
+			return 0, 0, skerr.Wrapf(err, "looking up begin commit %d", *beginCommit)
+		}
+		begin = commit.Timestamp
+	}
+	if endCommit != nil {
+		commit, err := f.perfGit.CommitFromCommitNumber(ctx, *endCommit)
+		if err != nil {
+			return 0, 0, skerr.Wrapf(err, "looking up end commit %d", *endCommit)
+		}
+		// The end of a time range is exclusive.
+		end = commit.Timestamp + 1
+	}
+	return begin, end, nil
+}
+
 // regressionCount returns the number of commits that have regressions for alerts
 // in the given category. The time range of commits is REGRESSION_COUNT_DURATION.
 func (f *Frontend) regressionCount(ctx context.Context, category string) (int, error) {
@@ -1142,6 +1703,212 @@ func (f *Frontend) regressionCountHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// RegressionDetailResponse is the response from regressionDetailHandler.
+type RegressionDetailResponse struct {
+	Commit     provider.Commit        `json:"commit"`
+	Alert      *alerts.Alert          `json:"alert"`
+	Regression *regression.Regression `json:"regression"`
+}
+
+// regressionDetailHandler returns everything needed to display a single
+// regression as a serialized JSON RegressionDetailResponse. The regression is
+// identified by the 'cid' and 'alert' query parameters, which are the commit
+// number and the alert id.
+//
+// The Regression includes the cluster summaries, the dataframe they were
+// found in, and the history of the triage changes made to it.
+func (f *Frontend) regressionDetailHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	commitNumber, alertID, reg, ok := f.regressionForRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	commit, err := f.perfGit.CommitFromCommitNumber(ctx, commitNumber)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to find commit.", http.StatusInternalServerError)
+		return
+	}
+
+	configs, err := f.configProvider.GetAllAlertConfigs(ctx, true)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to load alerts.", http.StatusInternalServerError)
+		return
+	}
+	var alert *alerts.Alert
+	for _, cfg := range configs {
+		if cfg.IDAsString == alertID {
+			alert = cfg
+			break
+		}
+	}
+
+	if o := f.obfuscatorFor(r); o != nil {
+		reg = regression.Obfuscate(o, reg)
+		if alert != nil {
+			alert = obfuscateAlert(o, alert)
+		}
+	}
+
+	resp := RegressionDetailResponse{
+		Commit:     commit,
+		Alert:      alert,
+		Regression: reg,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to write or encode output: %s", err)
+	}
+}
+
+// regressionForRequest returns the regression identified by the 'cid' and
+// 'alert' query parameters of the request, along with the commit number and
+// alert id. If the regression can't be loaded an error is reported to w and
+// false is returned.
+func (f *Frontend) regressionForRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) (types.CommitNumber, string, *regression.Regression, bool) {
+	cid, err := strconv.Atoi(r.FormValue("cid"))
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid commit number.", http.StatusBadRequest)
+		return types.BadCommitNumber, "", nil, false
+	}
+	commitNumber := types.CommitNumber(cid)
+	alertID := r.FormValue("alert")
+	if alertID == "" {
+		httputils.ReportError(w, skerr.Fmt("missing alert id"), "An alert id is required.", http.StatusBadRequest)
+		return types.BadCommitNumber, "", nil, false
+	}
+
+	regMap, err := f.regStore.Range(ctx, commitNumber, commitNumber)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to load regressions.", http.StatusInternalServerError)
+		return types.BadCommitNumber, "", nil, false
+	}
+	regs, ok := regMap[commitNumber]
+	if !ok {
+		http.NotFound(w, r)
+		return types.BadCommitNumber, "", nil, false
+	}
+	reg, ok := regs.ByAlertID[alertID]
+	if !ok {
+		http.NotFound(w, r)
+		return types.BadCommitNumber, "", nil, false
+	}
+	return commitNumber, alertID, reg, true
+}
+
+// regressionChartHandler renders the centroid of the cluster of a regression,
+// annotated with its step, as an image. The regression is identified by the
+// 'cid' and 'alert' query parameters, like in regressionDetailHandler. The
+// 'dir' query parameter selects the "low" or "high" cluster, and defaults to
+// whichever one was found. The 'format' query parameter is either "svg", the
+// default, or "png".
+//
+// The URLs are stable, so they can be used in bug templates via {chart_url}.
+func (f *Frontend) regressionChartHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = "svg"
+	}
+	if format != "svg" && format != "png" {
+		httputils.ReportError(w, skerr.Fmt("unknown format %q", format), "The format must be svg or png.", http.StatusBadRequest)
+		return
+	}
+
+	_, _, reg, ok := f.regressionForRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+	var cl *clustering2.ClusterSummary
+	switch r.FormValue("dir") {
+	case "low":
+		cl = reg.Low
+	case "high":
+		cl = reg.High
+	case "":
+		cl = reg.High
+		if cl == nil {
+			cl = reg.Low
+		}
+	default:
+		httputils.ReportError(w, skerr.Fmt("unknown dir %q", r.FormValue("dir")), "The dir must be low or high.", http.StatusBadRequest)
+		return
+	}
+	if cl == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var err error
+	if format == "png" {
+		w.Header().Set("Content-Type", "image/png")
+		err = chart.PNG(w, cl)
+	} else {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		err = chart.SVG(w, cl)
+	}
+	if err != nil {
+		sklog.Errorf("Failed to write chart: %s", err)
+	}
+}
+
+// obfuscateTryBotResponse returns a copy of resp with the param values of the
+// results and the ParamSet obfuscated.
+func obfuscateTryBotResponse(o *obfuscate.Obfuscator, resp results.TryBotResponse) results.TryBotResponse {
+	ret := resp
+	ret.ParamSet = o.ParamSet(resp.ParamSet)
+	ret.Results = make([]results.TryBotResult, 0, len(resp.Results))
+	for _, res := range resp.Results {
+		res.Params = o.Params(res.Params)
+		ret.Results = append(ret.Results, res)
+	}
+	return ret
+}
+
+// obfuscateAlert returns a copy of a with the values in its query obfuscated.
+func obfuscateAlert(o *obfuscate.Obfuscator, a *alerts.Alert) *alerts.Alert {
+	ret := *a
+	ret.Query = o.Query(a.Query)
+	return &ret
+}
+
+// obfuscateAlerts returns a copy of configs with the values in their queries
+// obfuscated.
+func obfuscateAlerts(o *obfuscate.Obfuscator, configs []*alerts.Alert) []*alerts.Alert {
+	ret := make([]*alerts.Alert, 0, len(configs))
+	for _, a := range configs {
+		ret = append(ret, obfuscateAlert(o, a))
+	}
+	return ret
+}
+
+// obfuscateGraphsShortcut returns a copy of sc with the values in the queries
+// and formulas of its graphs obfuscated.
+func obfuscateGraphsShortcut(o *obfuscate.Obfuscator, sc *graphsshortcut.GraphsShortcut) *graphsshortcut.GraphsShortcut {
+	ret := &graphsshortcut.GraphsShortcut{
+		Graphs: make([]graphsshortcut.GraphConfig, 0, len(sc.Graphs)),
+	}
+	for _, g := range sc.Graphs {
+		cfg := graphsshortcut.GraphConfig{
+			Queries:  make([]string, 0, len(g.Queries)),
+			Formulas: make([]string, 0, len(g.Formulas)),
+			Keys:     g.Keys,
+		}
+		for _, q := range g.Queries {
+			cfg.Queries = append(cfg.Queries, o.Query(q))
+		}
+		for _, formula := range g.Formulas {
+			cfg.Formulas = append(cfg.Formulas, o.Formula(formula))
+		}
+		ret.Graphs = append(ret.Graphs, cfg)
+	}
+	return ret
+}
+
 // Subset is the Subset of regressions we are querying for.
 type Subset string
 
@@ -1156,12 +1923,15 @@ var AllRegressionSubset = []Subset{SubsetAll, SubsetRegressions, SubsetUntriaged
 // RegressionRangeRequest is used in regressionRangeHandler and is used to query for a range of
 // of Regressions.
 //
-// Begin and End are Unix timestamps in seconds.
+// Begin and End are Unix timestamps in seconds. BeginCommit and EndCommit, if set, address the
+// range by commit number instead, and take precedence over Begin and End.
 type RegressionRangeRequest struct {
-	Begin       int64  `json:"begin"`
-	End         int64  `json:"end"`
-	Subset      Subset `json:"subset"`
-	AlertFilter string `json:"alert_filter"` // Can be an alertfilter constant, or a category prefixed with "cat:".
+	Begin       int64               `json:"begin"`
+	End         int64               `json:"end"`
+	BeginCommit *types.CommitNumber `json:"begin_commit,omitempty"`
+	EndCommit   *types.CommitNumber `json:"end_commit,omitempty"`
+	Subset      Subset              `json:"subset"`
+	AlertFilter string              `json:"alert_filter"` // Can be an alertfilter constant, or a category prefixed with "cat:".
 }
 
 // RegressionRow are all the Regression's for a specific commit. It is used in
@@ -1203,6 +1973,12 @@ func (f *Frontend) regressionRangeHandler(w http.ResponseWriter, r *http.Request
 		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusInternalServerError)
 		return
 	}
+	begin, end, err := f.unixTimestampRange(ctx, rr.Begin, rr.End, rr.BeginCommit, rr.EndCommit)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid commit range.", http.StatusBadRequest)
+		return
+	}
+	rr.Begin, rr.End = begin, end
 	commitNumberBegin, commitNumberEnd, err := f.unixTimestampRangeToCommitNumberRange(ctx, rr.Begin, rr.End)
 	if err != nil {
 		httputils.ReportError(w, err, "Invalid time range.", http.StatusInternalServerError)
@@ -1323,6 +2099,16 @@ func (f *Frontend) regressionRangeHandler(w http.ResponseWriter, r *http.Request
 		}
 		ret.Table = append(ret.Table, row)
 	}
+	if o := f.obfuscatorFor(r); o != nil {
+		ret.Header = obfuscateAlerts(o, ret.Header)
+		for _, row := range ret.Table {
+			for i, reg := range row.Columns {
+				if reg != nil {
+					row.Columns[i] = regression.Obfuscate(o, reg)
+				}
+			}
+		}
+	}
 	if err := json.NewEncoder(w).Encode(ret); err != nil {
 		sklog.Errorf("Failed to write or encode output: %s", err)
 	}
@@ -1348,8 +2134,9 @@ func (f *Frontend) detailsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If the trace is really a calculation then don't provide any details, but
-	// also don't generate an error.
-	if !query.IsValid(dr.TraceID) {
+	// also don't generate an error. The same goes for users who can't see the
+	// raw trace keys, since the source file contains them.
+	if !query.IsValid(dr.TraceID) || f.obfuscatorFor(r) != nil {
 		ret := format.Format{
 			Version: 0, // Specifying an unacceptable version of the format causes the control to be hidden.
 		}
@@ -1400,12 +2187,21 @@ type ShiftRequest struct {
 
 	// End is the commit number at the end of the range.
 	End types.CommitNumber `json:"end"`
+
+	// BeginTimestamp and EndTimestamp, if set, address the range by Unix
+	// timestamp in seconds instead, and take precedence over Begin and End.
+	// They are converted to the most recent commits at or before them.
+	BeginTimestamp *int64 `json:"begin_ts,omitempty"`
+	EndTimestamp   *int64 `json:"end_ts,omitempty"`
 }
 
-// ShiftResponse are the timestamps from a ShiftRequest.
+// ShiftResponse are the timestamps from a ShiftRequest, along with the commit
+// numbers they belong to.
 type ShiftResponse struct {
-	Begin int64 `json:"begin"` // In seconds from the epoch.
-	End   int64 `json:"end"`   // In seconds from the epoch.
+	Begin       int64              `json:"begin"` // In seconds from the epoch.
+	End         int64              `json:"end"`   // In seconds from the epoch.
+	BeginCommit types.CommitNumber `json:"begin_commit"`
+	EndCommit   types.CommitNumber `json:"end_commit"`
 }
 
 // shiftHandler computes a new begin and end timestamp for a dataframe given
@@ -1426,12 +2222,27 @@ func (f *Frontend) shiftHandler(w http.ResponseWriter, r *http.Request) {
 	var end time.Time
 	var err error
 
+	if sr.BeginTimestamp != nil {
+		sr.Begin, err = f.perfGit.CommitNumberFromTime(ctx, time.Unix(*sr.BeginTimestamp, 0))
+		if err != nil {
+			httputils.ReportError(w, err, "Failed to look up begin commit.", http.StatusBadRequest)
+			return
+		}
+	}
+	if sr.EndTimestamp != nil {
+		sr.End, err = f.perfGit.CommitNumberFromTime(ctx, time.Unix(*sr.EndTimestamp, 0))
+		if err != nil {
+			sr.End = types.BadCommitNumber
+		}
+	}
+
 	commit, err := f.perfGit.CommitFromCommitNumber(ctx, sr.Begin)
 	if err != nil {
 		httputils.ReportError(w, err, "Failed to look up begin commit.", http.StatusBadRequest)
 		return
 	}
 	begin = time.Unix(commit.Timestamp, 0)
+	beginCommit := commit.CommitNumber
 
 	commit, err = f.perfGit.CommitFromCommitNumber(ctx, sr.End)
 	if err != nil {
@@ -1450,8 +2261,74 @@ func (f *Frontend) shiftHandler(w http.ResponseWriter, r *http.Request) {
 	end = time.Unix(commit.Timestamp, 0)
 
 	resp := ShiftResponse{
-		Begin: begin.Unix(),
-		End:   end.Unix(),
+		Begin:       begin.Unix(),
+		End:         end.Unix(),
+		BeginCommit: beginCommit,
+		EndCommit:   commit.CommitNumber,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to write JSON response: %s", err)
+	}
+}
+
+// ConvertRangeRequest is a range of commits, addressed either by Unix
+// timestamps in seconds, [Begin, End), or by commit numbers, [BeginCommit,
+// EndCommit], which take precedence if set. BeginCommit and EndCommit must be
+// set together.
+type ConvertRangeRequest struct {
+	Begin       int64               `json:"begin"`
+	End         int64               `json:"end"`
+	BeginCommit *types.CommitNumber `json:"begin_commit,omitempty"`
+	EndCommit   *types.CommitNumber `json:"end_commit,omitempty"`
+}
+
+// ConvertRangeResponse is the range from a ConvertRangeRequest addressed both
+// ways.
+//
+// Begin and End are the Unix timestamps in seconds of the range, [Begin, End).
+// BeginCommit and EndCommit are the most recent commits at or before Begin and
+// End, which is how the regression range endpoint converts timestamps.
+type ConvertRangeResponse struct {
+	Begin       int64              `json:"begin"`
+	End         int64              `json:"end"`
+	BeginCommit types.CommitNumber `json:"begin_commit"`
+	EndCommit   types.CommitNumber `json:"end_commit"`
+}
+
+// convertRangeHandler accepts a POST'd JSON serialized ConvertRangeRequest and
+// returns a ConvertRangeResponse, so that clients don't need to convert between
+// timestamps and commit numbers themselves.
+func (f *Frontend) convertRangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	var cr ConvertRangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&cr); err != nil {
+		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusBadRequest)
+		return
+	}
+	if (cr.BeginCommit == nil) != (cr.EndCommit == nil) {
+		httputils.ReportError(w, skerr.Fmt("only one of begin_commit and end_commit is set"), "Both begin_commit and end_commit must be set, or neither.", http.StatusBadRequest)
+		return
+	}
+	begin, end, err := f.unixTimestampRange(ctx, cr.Begin, cr.End, cr.BeginCommit, cr.EndCommit)
+	if err != nil {
+		httputils.ReportError(w, err, "Invalid commit range.", http.StatusBadRequest)
+		return
+	}
+	resp := ConvertRangeResponse{
+		Begin: begin,
+		End:   end,
+	}
+	if cr.BeginCommit != nil && cr.EndCommit != nil {
+		resp.BeginCommit, resp.EndCommit = *cr.BeginCommit, *cr.EndCommit
+	} else {
+		resp.BeginCommit, resp.EndCommit, err = f.unixTimestampRangeToCommitNumberRange(ctx, begin, end)
+		if err != nil {
+			httputils.ReportError(w, err, "Invalid time range.", http.StatusBadRequest)
+			return
+		}
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		sklog.Errorf("Failed to write JSON response: %s", err)
@@ -1467,6 +2344,10 @@ func (f *Frontend) alertListHandler(w http.ResponseWriter, r *http.Request) {
 	resp, err := f.configProvider.GetAllAlertConfigs(ctx, show == "true")
 	if err != nil {
 		httputils.ReportError(w, err, "Failed to retrieve alert configs.", http.StatusInternalServerError)
+		return
+	}
+	if o := f.obfuscatorFor(r); o != nil {
+		resp = obfuscateAlerts(o, resp)
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		sklog.Errorf("Failed to write JSON response: %s", err)
@@ -1508,6 +2389,20 @@ func (f *Frontend) alertUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check the stored alert, since the request can't be trusted to say which
+	// template, if any, the alert belongs to.
+	templateID, err := f.storedAlertTemplateID(ctx, cfg.IDAsString)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to load the alert.", http.StatusInternalServerError)
+		return
+	}
+	if templateID != "" {
+		httputils.ReportError(w, skerr.Fmt("alert %s belongs to template %s", cfg.IDAsString, templateID), "Alerts created from a template must be changed by updating the template.", http.StatusBadRequest)
+		return
+	}
+	// Only templates create alerts that belong to them.
+	cfg.TemplateID = ""
+
 	if err := cfg.Validate(); err != nil {
 		httputils.ReportError(w, err, "Invalid Alert", http.StatusInternalServerError)
 	}
@@ -1515,7 +2410,7 @@ func (f *Frontend) alertUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	if err := f.alertStore.Save(ctx, cfg); err != nil {
 		httputils.ReportError(w, err, "Failed to save alerts.Config.", http.StatusInternalServerError)
 	}
-	err := json.NewEncoder(w).Encode(AlertUpdateResponse{
+	err = json.NewEncoder(w).Encode(AlertUpdateResponse{
 		IDAsString: cfg.IDAsString,
 	})
 	if err != nil {
@@ -1523,6 +2418,24 @@ func (f *Frontend) alertUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// storedAlertTemplateID returns the TemplateID of the stored alert with the
+// given id, or the empty string if the alert is new or doesn't exist.
+func (f *Frontend) storedAlertTemplateID(ctx context.Context, id string) (string, error) {
+	if id == alerts.BadAlertIDAsAsString {
+		return "", nil
+	}
+	configs, err := f.alertStore.List(ctx, true)
+	if err != nil {
+		return "", skerr.Wrap(err)
+	}
+	for _, a := range configs {
+		if a.IDAsString == id {
+			return a.TemplateID, nil
+		}
+	}
+	return "", nil
+}
+
 func (f *Frontend) alertDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
 	defer cancel()
@@ -1545,6 +2458,178 @@ func (f *Frontend) alertDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (f *Frontend) alertTemplateListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	resp, err := f.alertTemplateStore.List(ctx)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve alert templates.", http.StatusInternalServerError)
+		return
+	}
+	if f.obfuscatorFor(r) != nil {
+		// The variables of templates are raw param values, and substituting
+		// obfuscated values into the query isn't reliable, so templates are
+		// only shown to logged in users.
+		resp = []*alerts.Template{}
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to write JSON response: %s", err)
+	}
+}
+
+func (f *Frontend) alertTemplateNewHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(alerts.NewTemplate()); err != nil {
+		sklog.Errorf("Failed to write JSON response: %s", err)
+	}
+}
+
+// AlertTemplateUpdateResponse is the JSON response when an alerts.Template is
+// created or updated.
+type AlertTemplateUpdateResponse struct {
+	IDAsString string `json:"id_as_string"`
+
+	// Alerts are the Alerts the Template currently expands into.
+	Alerts []*alerts.Alert `json:"alerts"`
+}
+
+// alertTemplateUpdateHandler stores the POST'd alerts.Template and then
+// creates, updates, and deletes Alerts so that they match the expansion of the
+// Template.
+func (f *Frontend) alertTemplateUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	defer refreshConfigProvider(ctx, f.configProvider)
+	w.Header().Set("Content-Type", "application/json")
+
+	t := &alerts.Template{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusBadRequest)
+		return
+	}
+
+	if !f.isEditor(w, r, "alert-template-update", t) {
+		return
+	}
+
+	// Expand before saving so that an invalid Template is never stored.
+	if _, err := t.Expand(); err != nil {
+		httputils.ReportError(w, err, "Invalid alert template.", http.StatusBadRequest)
+		return
+	}
+	if err := f.alertTemplateStore.Save(ctx, t); err != nil {
+		httputils.ReportError(w, err, "Failed to save alert template.", http.StatusInternalServerError)
+		return
+	}
+	expanded, err := alerts.SyncTemplate(ctx, f.alertStore, t)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to update the alerts of the template.", http.StatusInternalServerError)
+		return
+	}
+	err = json.NewEncoder(w).Encode(AlertTemplateUpdateResponse{
+		IDAsString: t.IDAsString,
+		Alerts:     expanded,
+	})
+	if err != nil {
+		sklog.Errorf("Failed to write JSON response: %s", err)
+	}
+}
+
+// alertTemplateDeleteHandler deletes an alerts.Template along with all the
+// Alerts it expanded into.
+func (f *Frontend) alertTemplateDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	defer refreshConfigProvider(ctx, f.configProvider)
+	w.Header().Set("Content-Type", "application/json")
+
+	sid := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(sid, 10, 64)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to parse alert template id.", http.StatusBadRequest)
+		return
+	}
+
+	if !f.isEditor(w, r, "alert-template-delete", sid) {
+		return
+	}
+
+	// Delete the Alerts first, so a failure can be fixed by deleting again.
+	if err := alerts.DeleteTemplateAlerts(ctx, f.alertStore, sid); err != nil {
+		httputils.ReportError(w, err, "Failed to delete the alerts of the template.", http.StatusInternalServerError)
+		return
+	}
+	if err := f.alertTemplateStore.Delete(ctx, int(id)); err != nil {
+		httputils.ReportError(w, err, "Failed to delete the alert template.", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (f *Frontend) derivedMetricListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	resp, err := f.derivedMetricStore.List(ctx)
+	if err != nil {
+		httputils.ReportError(w, err, "Failed to retrieve derived metrics.", http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		sklog.Errorf("Failed to write JSON response: %s", err)
+	}
+}
+
+// derivedMetricUpdateHandler stores the POST'd derivedmetrics.DerivedMetric,
+// replacing any existing derived metric with the same name.
+func (f *Frontend) derivedMetricUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	d := &derivedmetrics.DerivedMetric{}
+	if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+		httputils.ReportError(w, err, "Failed to decode JSON.", http.StatusBadRequest)
+		return
+	}
+
+	if !f.isEditor(w, r, "derived-metric-update", d) {
+		return
+	}
+
+	if err := d.Validate(); err != nil {
+		httputils.ReportError(w, err, "Invalid derived metric.", http.StatusBadRequest)
+		return
+	}
+	if d.Owner == "" {
+		d.Owner = f.loginProvider.LoggedInAs(r).String()
+	}
+	if err := f.derivedMetricStore.Save(ctx, d); err != nil {
+		httputils.ReportError(w, err, "Failed to save derived metric.", http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		sklog.Errorf("Failed to write JSON response: %s", err)
+	}
+}
+
+func (f *Frontend) derivedMetricDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), defaultDatabaseTimeout)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	name := chi.URLParam(r, "name")
+	if !f.isEditor(w, r, "derived-metric-delete", name) {
+		return
+	}
+	if err := f.derivedMetricStore.Delete(ctx, name); err != nil {
+		httputils.ReportError(w, err, "Failed to delete derived metric.", http.StatusInternalServerError)
+		return
+	}
+}
+
 // TryBugRequest is a request to try a bug template URI.
 type TryBugRequest struct {
 	BugURITemplate string `json:"bug_uri_template"`
@@ -1700,6 +2785,8 @@ func (f *Frontend) Serve() {
 	router.HandleFunc("/v/", f.templateHandler("revisions.html"))
 	router.HandleFunc("/g/{dest:[ect]}/{hash:[a-zA-Z0-9]+}", f.gotoHandler)
 	router.HandleFunc("/help/", f.helpHandler)
+	router.Get("/embed/", f.embedHandler)
+	router.Get("/oembed", f.oembedHandler)
 
 	// JSON handlers.
 
@@ -1709,31 +2796,47 @@ func (f *Frontend) Serve() {
 	router.HandleFunc("/_/initpage/", f.initpageHandler)
 	router.Post("/_/cidRange/", f.cidRangeHandler)
 	router.Post("/_/count/", f.countHandler)
+	router.Post("/_/heatmap/", f.heatmapHandler)
+	router.Post("/_/ingest/simulate", f.ingestSimulateHandler)
+	router.Post("/_/ingest/convert", f.ingestConvertHandler)
 	router.Post("/_/cid/", f.cidHandler)
-	router.Post("/_/keys/", f.keysHandler)
+	router.Post("/_/keys/", f.rejectIfReadOnly(f.keysHandler))
 
 	router.Post("/_/frame/start", f.frameStartHandler)
+	router.Get("/_/frame/partial/{id:[a-zA-Z0-9-]+}", f.framePartialHandler)
+	router.Post("/_/frame/cancel/{id:[a-zA-Z0-9-]+}", f.frameCancelHandler)
 	router.Post("/_/cluster/start", f.clusterStartHandler)
 	router.Post("/_/trybot/load/", f.trybotLoadHandler)
 	router.Post("/_/dryrun/start", f.dryrunRequests.StartHandler)
 
 	router.Post("/_/reg/", f.regressionRangeHandler)
 	router.Get("/_/reg/count", f.regressionCountHandler)
-	router.Post("/_/triage/", f.triageHandler)
+	router.Get("/_/reg/detail", f.regressionDetailHandler)
+	router.Get("/_/reg/chart", f.regressionChartHandler)
+	router.Post("/_/triage/", f.rejectIfReadOnly(f.triageHandler))
 	router.HandleFunc("/_/alerts/", f.alertsHandler)
 	router.Post("/_/details/", f.detailsHandler)
 	router.Post("/_/shift/", f.shiftHandler)
+	router.Post("/_/range/convert", f.convertRangeHandler)
 	router.Get("/_/alert/list/{show}", f.alertListHandler)
 	router.Get("/_/alert/new", f.alertNewHandler)
-	router.Post("/_/alert/update", f.alertUpdateHandler)
-	router.Post("/_/alert/delete/{id:[0-9]+}", f.alertDeleteHandler)
+	router.Post("/_/alert/update", f.rejectIfReadOnly(f.alertUpdateHandler))
+	router.Post("/_/alert/delete/{id:[0-9]+}", f.rejectIfReadOnly(f.alertDeleteHandler))
+	router.Get("/_/alert/template/list", f.alertTemplateListHandler)
+	router.Get("/_/alert/template/new", f.alertTemplateNewHandler)
+	router.Post("/_/alert/template/update", f.rejectIfReadOnly(f.alertTemplateUpdateHandler))
+	router.Post("/_/alert/template/delete/{id:[0-9]+}", f.rejectIfReadOnly(f.alertTemplateDeleteHandler))
+	router.Get("/_/derived/list", f.derivedMetricListHandler)
+	router.Post("/_/derived/update", f.rejectIfReadOnly(f.derivedMetricUpdateHandler))
+	router.Post("/_/derived/delete/{name}", f.rejectIfReadOnly(f.derivedMetricDeleteHandler))
 	router.Post("/_/alert/bug/try", f.alertBugTryHandler)
 	router.Post("/_/alert/notify/try", f.alertNotifyTryHandler)
 
 	router.Get("/_/login/status", f.loginStatus)
+	router.Post("/_/readonly", f.readOnlyHandler)
 
 	router.Post("/_/shortcut/get", f.getGraphsShortcutHandler)
-	router.Post("/_/shortcut/update", f.createGraphsShortcutHandler)
+	router.Post("/_/shortcut/update", f.rejectIfReadOnly(f.createGraphsShortcutHandler))
 
 	router.Get("/_/favorites/", f.favoritesHandler)
 	router.Get("/_/defaults/", f.defaultsHandler)
@@ -1755,6 +2858,29 @@ func (f *Frontend) Serve() {
 	sklog.Fatal(server.ListenAndServe())
 }
 
+// newObfuscator returns an Obfuscator using the key stored in the secret
+// manager.
+func newObfuscator(ctx context.Context, cfg *config.ObfuscationConfig) (*obfuscate.Obfuscator, error) {
+	secretClient, err := secret.NewClient(ctx)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "creating secret client")
+	}
+	key, err := secretClient.Get(ctx, cfg.KeySecretProject, cfg.KeySecretName, secret.VersionLatest)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "loading obfuscation key from project: %q  name: %q", cfg.KeySecretProject, cfg.KeySecretName)
+	}
+	return obfuscate.New([]byte(key), cfg.Params), nil
+}
+
+// obfuscatorFor returns the Obfuscator to apply to the given request, or nil if
+// the user is logged in or obfuscation is not configured.
+func (f *Frontend) obfuscatorFor(r *http.Request) *obfuscate.Obfuscator {
+	if f.obfuscator == nil || f.loginProvider.LoggedInAs(r) != "" {
+		return nil
+	}
+	return f.obfuscator
+}
+
 // getParamSet returns a fresh paramtools.ParamSet that represents all the
 // traces stored in the two most recent tiles in the trace store. It is filtered
 // if such filtering is turned on in the config.
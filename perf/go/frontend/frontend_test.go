@@ -3,15 +3,40 @@ package frontend
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"image/png"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.goldmine.build/go/alogin"
 	"go.goldmine.build/go/alogin/mocks"
+	"go.goldmine.build/go/git/provider"
+	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/roles"
+	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/perf/go/alerts"
+	alertsmocks "go.goldmine.build/perf/go/alerts/mock"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/config"
+	gitmocks "go.goldmine.build/perf/go/git/mocks"
+	"go.goldmine.build/perf/go/graphsshortcut"
+	graphsshortcutmocks "go.goldmine.build/perf/go/graphsshortcut/mocks"
+	"go.goldmine.build/perf/go/heatmap"
+	"go.goldmine.build/perf/go/ingest/format"
+	"go.goldmine.build/perf/go/ingest/parser"
+	"go.goldmine.build/perf/go/obfuscate"
+	"go.goldmine.build/perf/go/regression"
+	regressionmocks "go.goldmine.build/perf/go/regression/mocks"
+	"go.goldmine.build/perf/go/stepfit"
+	"go.goldmine.build/perf/go/trybot/results"
+	"go.goldmine.build/perf/go/types"
+	"go.goldmine.build/perf/go/ui/frame"
 )
 
 func setupForTest(t *testing.T, userIsEditor bool) (*httptest.ResponseRecorder, *http.Request, *Frontend) {
@@ -55,3 +80,531 @@ func TestFrontendDetailsHandler_InvalidTraceID_ReturnsErrorMessage(t *testing.T)
 	require.Equal(t, http.StatusOK, w.Result().StatusCode)
 	require.Contains(t, w.Body.String(), "version\":0")
 }
+
+func TestEmbedDimension(t *testing.T) {
+	require.Equal(t, defaultEmbedWidth, embedDimension("", defaultEmbedWidth))
+	require.Equal(t, defaultEmbedWidth, embedDimension("not-a-number", defaultEmbedWidth))
+	require.Equal(t, 640, embedDimension("640", defaultEmbedWidth))
+	require.Equal(t, minEmbedSize, embedDimension("1", defaultEmbedWidth))
+	require.Equal(t, maxEmbedSize, embedDimension("100000", defaultEmbedWidth))
+}
+
+func TestOEmbedResponseFromURL_ExploreURL_ReturnsIFrameForEmbedPage(t *testing.T) {
+	resp, err := oembedResponseFromURL("https://perf.example.org", "https://perf.example.org/e/?keys=X1234&width=1000&height=500", "600", "")
+	require.NoError(t, err)
+	require.Equal(t, "rich", resp.Type)
+	require.Equal(t, "1.0", resp.Version)
+	require.Equal(t, 600, resp.Width)
+	require.Equal(t, 500, resp.Height)
+	require.Equal(t, `<iframe src="https://perf.example.org/embed/?height=500&amp;keys=X1234&amp;width=600" width="600" height="500" frameborder="0"></iframe>`, resp.HTML)
+}
+
+func TestOEmbedResponseFromURL_URLOnDifferentHost_ReturnsError(t *testing.T) {
+	_, err := oembedResponseFromURL("https://perf.example.org", "https://evil.example.com/e/?keys=X1234", "", "")
+	require.Error(t, err)
+}
+
+func TestOEmbedResponseFromURL_URLIsNotAChart_ReturnsError(t *testing.T) {
+	_, err := oembedResponseFromURL("https://perf.example.org", "https://perf.example.org/a/", "", "")
+	require.Error(t, err)
+}
+
+func TestRejectIfReadOnly_NotReadOnly_CallsHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/triage/", nil)
+	f := &Frontend{}
+	called := false
+	f.rejectIfReadOnly(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})(w, r)
+	require.True(t, called)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRejectIfReadOnly_ReadOnly_Returns503WithMessage(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/triage/", nil)
+	f := &Frontend{}
+	f.setReadOnly(true, "Database migration in progress.")
+	f.rejectIfReadOnly(func(w http.ResponseWriter, r *http.Request) {
+		require.Fail(t, "handler should not be called")
+	})(w, r)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Contains(t, w.Body.String(), "Database migration in progress.")
+}
+
+func TestSetReadOnly_EmptyMessage_UsesDefaultMessage(t *testing.T) {
+	f := &Frontend{}
+	f.setReadOnly(true, "")
+	readOnly, msg := f.readOnlyStatus()
+	require.True(t, readOnly)
+	require.Equal(t, defaultReadOnlyMessage, msg)
+
+	f.setReadOnly(false, "ignored")
+	readOnly, msg = f.readOnlyStatus()
+	require.False(t, readOnly)
+	require.Empty(t, msg)
+}
+
+func TestFrontendReadOnlyHandler_UserIsAdmin_ChangesMode(t *testing.T) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/readonly", bytes.NewBufferString(`{"read_only": true, "message": "Back soon."}`))
+	login.On("LoggedInAs", r).Return(alogin.EMail("admin@example.org"))
+	login.On("HasRole", r, roles.Admin).Return(true)
+	f := &Frontend{
+		loginProvider: login,
+	}
+	f.readOnlyHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp ReadOnlyStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, ReadOnlyStatus{ReadOnly: true, Message: "Back soon."}, resp)
+	readOnly, msg := f.readOnlyStatus()
+	require.True(t, readOnly)
+	require.Equal(t, "Back soon.", msg)
+}
+
+func TestFrontendReadOnlyHandler_UserIsNotAdmin_ReportsError(t *testing.T) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/readonly", bytes.NewBufferString(`{"read_only": true}`))
+	login.On("LoggedInAs", r).Return(alogin.EMail("nobody@example.org"))
+	login.On("HasRole", r, roles.Admin).Return(false)
+	f := &Frontend{
+		loginProvider: login,
+	}
+	f.readOnlyHandler(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	readOnly, _ := f.readOnlyStatus()
+	require.False(t, readOnly)
+}
+
+func TestFramePartialHandler_RequestNotRunning_Returns404(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/_/frame/partial/unknown-id", nil)
+	f := &Frontend{
+		runningFrameRequests: frame.NewRunningFrameRequests(),
+	}
+	f.framePartialHandler(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFrameCancelHandler_RequestNotRunning_Returns404(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/frame/cancel/unknown-id", nil)
+	f := &Frontend{
+		runningFrameRequests: frame.NewRunningFrameRequests(),
+	}
+	f.frameCancelHandler(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestObfuscatorFor_UserNotLoggedIn_ReturnsObfuscator(t *testing.T) {
+	login := mocks.NewLogin(t)
+	r := httptest.NewRequest("GET", "/_/initpage/", nil)
+	login.On("LoggedInAs", r).Return(alogin.EMail(""))
+	f := &Frontend{
+		loginProvider: login,
+		obfuscator:    obfuscate.New([]byte("secret"), []string{"bot"}),
+	}
+	require.Equal(t, f.obfuscator, f.obfuscatorFor(r))
+}
+
+func TestObfuscatorFor_UserLoggedIn_ReturnsNil(t *testing.T) {
+	login := mocks.NewLogin(t)
+	r := httptest.NewRequest("GET", "/_/initpage/", nil)
+	login.On("LoggedInAs", r).Return(alogin.EMail("nobody@example.org"))
+	f := &Frontend{
+		loginProvider: login,
+		obfuscator:    obfuscate.New([]byte("secret"), []string{"bot"}),
+	}
+	require.Nil(t, f.obfuscatorFor(r))
+}
+
+func TestFrontendDetailsHandler_UserNotLoggedInOnObfuscatedInstance_ReturnsNoDetails(t *testing.T) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/details", bytes.NewBufferString(`{"cid": 0, "traceid": ",arch=x86,bot=internal,"}`))
+	login.On("LoggedInAs", r).Return(alogin.EMail(""))
+	f := &Frontend{
+		loginProvider: login,
+		obfuscator:    obfuscate.New([]byte("secret"), []string{"bot"}),
+	}
+	f.detailsHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.Contains(t, w.Body.String(), "version\":0")
+}
+
+func TestIngestSimulateHandler_UserNotLoggedIn_ReportsError(t *testing.T) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/ingest/simulate", bytes.NewBufferString(`{}`))
+	login.On("LoggedInAs", r).Return(alogin.NotLoggedIn)
+	f := &Frontend{
+		loginProvider: login,
+	}
+	f.ingestSimulateHandler(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestIngestSimulateHandler_ValidFile_ReturnsSimulation(t *testing.T) {
+	oldConfig := config.Config
+	config.Config = &config.InstanceConfig{}
+	defer func() { config.Config = oldConfig }()
+
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/ingest/simulate", bytes.NewBufferString(`{
+		"version": 1,
+		"git_hash": "fe4a4029a080bc955e9588d05a6cd9eb490845d4",
+		"key": {"arch": "x86"},
+		"results": [{"key": {"test": "draw"}, "measurement": 1.5}]
+	}`))
+	login.On("LoggedInAs", r).Return(alogin.EMail("nobody@example.org"))
+	f := &Frontend{
+		loginProvider: login,
+	}
+	f.ingestSimulateHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var sim parser.Simulation
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&sim))
+	require.Equal(t, 1, sim.TraceCount)
+	require.Equal(t, []parser.SimulatedTrace{{
+		TraceID: ",arch=x86,test=draw,",
+		Params:  paramtools.Params{"arch": "x86", "test": "draw"},
+		Value:   1.5,
+	}}, sim.Traces)
+	require.Empty(t, sim.Warnings)
+}
+
+func TestIngestSimulateHandler_InvalidFile_ReportsError(t *testing.T) {
+	oldConfig := config.Config
+	config.Config = &config.InstanceConfig{}
+	defer func() { config.Config = oldConfig }()
+
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/ingest/simulate", bytes.NewBufferString(`this is not json`))
+	login.On("LoggedInAs", r).Return(alogin.EMail("nobody@example.org"))
+	f := &Frontend{
+		loginProvider: login,
+	}
+	f.ingestSimulateHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestObfuscateTryBotResponse_ParamsAndParamSet_ValuesObfuscatedAndOriginalUnchanged(t *testing.T) {
+	o := obfuscate.New([]byte("secret"), []string{"bot"})
+	resp := results.TryBotResponse{
+		Results: []results.TryBotResult{
+			{Params: paramtools.Params{"arch": "x86", "bot": "internal"}, Median: 1},
+		},
+		ParamSet: paramtools.ReadOnlyParamSet{"arch": {"x86"}, "bot": {"internal"}},
+	}
+
+	got := obfuscateTryBotResponse(o, resp)
+
+	require.Equal(t, paramtools.Params{"arch": "x86", "bot": o.Value("bot", "internal")}, got.Results[0].Params)
+	require.Equal(t, float32(1), got.Results[0].Median)
+	require.Equal(t, []string{o.Value("bot", "internal")}, got.ParamSet["bot"])
+	require.Equal(t, "internal", resp.Results[0].Params["bot"])
+	require.Equal(t, []string{"internal"}, resp.ParamSet["bot"])
+}
+
+func TestAlertListHandler_UserNotLoggedInOnObfuscatedInstance_QueriesObfuscated(t *testing.T) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/_/alert/list/false", nil)
+	login.On("LoggedInAs", r).Return(alogin.EMail(""))
+	configProvider := alertsmocks.NewConfigProvider(t)
+	cfg := alerts.NewConfig()
+	cfg.Query = "arch=x86&bot=internal"
+	configProvider.On("GetAllAlertConfigs", testutils.AnyContext, false).Return([]*alerts.Alert{cfg}, nil)
+	o := obfuscate.New([]byte("secret"), []string{"bot"})
+	f := &Frontend{
+		loginProvider:  login,
+		configProvider: configProvider,
+		obfuscator:     o,
+	}
+	f.alertListHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got []*alerts.Alert
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Len(t, got, 1)
+	require.Equal(t, "arch=x86&bot="+o.Value("bot", "internal"), got[0].Query)
+	require.Equal(t, "arch=x86&bot=internal", cfg.Query)
+}
+
+func TestGetGraphsShortcutHandler_UserNotLoggedInOnObfuscatedInstance_QueriesAndFormulasObfuscated(t *testing.T) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/shortcut/get", bytes.NewBufferString(`{"id": "abc"}`))
+	login.On("LoggedInAs", r).Return(alogin.EMail(""))
+	store := graphsshortcutmocks.NewStore(t)
+	store.On("GetShortcut", testutils.AnyContext, "abc").Return(&graphsshortcut.GraphsShortcut{
+		Graphs: []graphsshortcut.GraphConfig{{
+			Queries:  []string{"bot=internal"},
+			Formulas: []string{`norm(filter("bot=internal"))`},
+			Keys:     "123",
+		}},
+	}, nil)
+	o := obfuscate.New([]byte("secret"), []string{"bot"})
+	f := &Frontend{
+		loginProvider:       login,
+		graphsShortcutStore: store,
+		obfuscator:          o,
+	}
+	f.getGraphsShortcutHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got graphsshortcut.GraphsShortcut
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	bot := o.Value("bot", "internal")
+	require.Equal(t, []graphsshortcut.GraphConfig{{
+		Queries:  []string{"bot=" + bot},
+		Formulas: []string{`norm(filter("bot=` + bot + `"))`},
+		Keys:     "123",
+	}}, got.Graphs)
+}
+
+func TestIngestConvertHandler_LegacyFile_ReturnsFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/ingest/convert", bytes.NewBufferString(`{"gitHash": "abc", "key": {"arch": "x86"}, "results": {"a_test": {"8888": {"min_ms": 1.5}}}}`))
+	f := &Frontend{}
+	f.ingestConvertHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got format.Format
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, format.Format{
+		Version: format.FileFormatVersion,
+		GitHash: "abc",
+		Key:     map[string]string{"arch": "x86"},
+		Results: []format.Result{
+			{
+				Key: map[string]string{"test": "a_test", "config": "8888"},
+				Measurements: map[string][]format.SingleMeasurement{
+					format.LegacySubResultKey: {{Value: "min_ms", Measurement: 1.5}},
+				},
+			},
+		},
+	}, got)
+}
+
+func TestIngestConvertHandler_InvalidFile_ReportsError(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/ingest/convert", bytes.NewBufferString(`this is not json`))
+	f := &Frontend{}
+	f.ingestConvertHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func setupAlertUpdateForTest(t *testing.T, body string, stored []*alerts.Alert) (*httptest.ResponseRecorder, *http.Request, *Frontend, *alertsmocks.Store) {
+	login := mocks.NewLogin(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/alert/update", bytes.NewBufferString(body))
+	login.On("LoggedInAs", r).Return(alogin.EMail("nobody@example.org"))
+	login.On("HasRole", r, roles.Editor).Return(true)
+	configProvider := alertsmocks.NewConfigProvider(t)
+	configProvider.On("Refresh", testutils.AnyContext).Return(nil)
+	store := alertsmocks.NewStore(t)
+	store.On("List", testutils.AnyContext, true).Return(stored, nil)
+	f := &Frontend{
+		loginProvider:  login,
+		configProvider: configProvider,
+		alertStore:     store,
+	}
+	return w, r, f, store
+}
+
+func TestAlertUpdateHandler_StoredAlertBelongsToTemplate_ReturnsBadRequest(t *testing.T) {
+	stored := alerts.NewConfig()
+	stored.SetIDFromInt64(12)
+	stored.TemplateID = "3"
+	// The request omits the TemplateID of the stored alert.
+	w, r, f, _ := setupAlertUpdateForTest(t, `{"id_as_string": "12", "query": "arch=x86", "step": "original"}`, []*alerts.Alert{stored})
+
+	f.alertUpdateHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlertUpdateHandler_RequestSetsTemplateID_SavedWithoutTemplateID(t *testing.T) {
+	stored := alerts.NewConfig()
+	stored.SetIDFromInt64(12)
+	w, r, f, store := setupAlertUpdateForTest(t, `{"id_as_string": "12", "query": "arch=x86", "step": "original", "template_id": "3"}`, []*alerts.Alert{stored})
+	store.On("Save", testutils.AnyContext, mock.MatchedBy(func(a *alerts.Alert) bool {
+		return a.IDAsString == "12" && a.TemplateID == ""
+	})).Return(nil)
+
+	f.alertUpdateHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUnixTimestampRange_CommitNumbersSet_CommitNumbersTakePrecedence(t *testing.T) {
+	ctx := context.Background()
+	g := gitmocks.NewGit(t)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(10)).Return(provider.Commit{CommitNumber: 10, Timestamp: 1000}, nil)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(12)).Return(provider.Commit{CommitNumber: 12, Timestamp: 1200}, nil)
+	f := &Frontend{perfGit: g}
+
+	beginCommit, endCommit := types.CommitNumber(10), types.CommitNumber(12)
+	begin, end, err := f.unixTimestampRange(ctx, 1, 2, &beginCommit, &endCommit)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), begin)
+	// The end is exclusive, so it must be past the end commit.
+	require.Equal(t, int64(1201), end)
+}
+
+func TestUnixTimestampRange_CommitNumbersNotSet_TimestampsReturnedUnchanged(t *testing.T) {
+	f := &Frontend{}
+	begin, end, err := f.unixTimestampRange(context.Background(), 1, 2, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), begin)
+	require.Equal(t, int64(2), end)
+}
+
+func TestUnixTimestampRange_UnknownCommitNumber_ReturnsError(t *testing.T) {
+	g := gitmocks.NewGit(t)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(99)).Return(provider.Commit{}, errors.New("not found"))
+	f := &Frontend{perfGit: g}
+
+	endCommit := types.CommitNumber(99)
+	_, _, err := f.unixTimestampRange(context.Background(), 1, 2, nil, &endCommit)
+	require.Error(t, err)
+}
+
+func TestHeatmapHandler_EndNotAfterBegin_ReturnsBadRequest(t *testing.T) {
+	f := &Frontend{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/heatmap", bytes.NewBufferString(`{"begin": 1000, "end": 1000, "q": "arch=x86"}`))
+	f.heatmapHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHeatmapHandler_RangeSpansTooManyCommits_ReturnsBadRequest(t *testing.T) {
+	g := gitmocks.NewGit(t)
+	g.On("CommitNumberFromTime", testutils.AnyContext, time.Unix(1000, 0)).Return(types.CommitNumber(10), nil)
+	g.On("CommitNumberFromTime", testutils.AnyContext, time.Unix(2000, 0)).Return(types.CommitNumber(10+heatmap.MaxCommits+1), nil)
+	f := &Frontend{perfGit: g}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/heatmap", bytes.NewBufferString(`{"begin": 1000, "end": 2000, "q": "arch=x86"}`))
+	f.heatmapHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "too many commits")
+}
+
+func TestConvertRangeHandler_OnlyOneCommitNumber_ReturnsBadRequest(t *testing.T) {
+	f := &Frontend{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/range/convert", bytes.NewBufferString(`{"begin": 1000, "end": 1201, "begin_commit": 10}`))
+	f.convertRangeHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestConvertRangeHandler_Timestamps_ReturnsCommitNumbers(t *testing.T) {
+	g := gitmocks.NewGit(t)
+	g.On("CommitNumberFromTime", testutils.AnyContext, time.Unix(1000, 0)).Return(types.CommitNumber(10), nil)
+	g.On("CommitNumberFromTime", testutils.AnyContext, time.Unix(1201, 0)).Return(types.CommitNumber(12), nil)
+	f := &Frontend{perfGit: g}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/range/convert", bytes.NewBufferString(`{"begin": 1000, "end": 1201}`))
+	f.convertRangeHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var got ConvertRangeResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, ConvertRangeResponse{Begin: 1000, End: 1201, BeginCommit: 10, EndCommit: 12}, got)
+}
+
+func TestConvertRangeHandler_CommitNumbers_ReturnsTimestamps(t *testing.T) {
+	g := gitmocks.NewGit(t)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(10)).Return(provider.Commit{CommitNumber: 10, Timestamp: 1000}, nil)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(12)).Return(provider.Commit{CommitNumber: 12, Timestamp: 1200}, nil)
+	f := &Frontend{perfGit: g}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/range/convert", bytes.NewBufferString(`{"begin_commit": 10, "end_commit": 12}`))
+	f.convertRangeHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var got ConvertRangeResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, ConvertRangeResponse{Begin: 1000, End: 1201, BeginCommit: 10, EndCommit: 12}, got)
+}
+
+func TestShiftHandler_Timestamps_ReturnsTimestampsAndCommitNumbers(t *testing.T) {
+	g := gitmocks.NewGit(t)
+	g.On("CommitNumberFromTime", testutils.AnyContext, time.Unix(1050, 0)).Return(types.CommitNumber(10), nil)
+	g.On("CommitNumberFromTime", testutils.AnyContext, time.Unix(1250, 0)).Return(types.CommitNumber(12), nil)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(10)).Return(provider.Commit{CommitNumber: 10, Timestamp: 1000}, nil)
+	g.On("CommitFromCommitNumber", testutils.AnyContext, types.CommitNumber(12)).Return(provider.Commit{CommitNumber: 12, Timestamp: 1200}, nil)
+	f := &Frontend{perfGit: g}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/_/shift/", bytes.NewBufferString(`{"begin_ts": 1050, "end_ts": 1250}`))
+	f.shiftHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var got ShiftResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, ShiftResponse{Begin: 1000, End: 1200, BeginCommit: 10, EndCommit: 12}, got)
+}
+
+func regressionStoreForChartTest(t *testing.T) *regressionmocks.Store {
+	store := regressionmocks.NewStore(t)
+	store.On("Range", testutils.AnyContext, types.CommitNumber(12), types.CommitNumber(12)).Return(map[types.CommitNumber]*regression.AllRegressionsForCommit{
+		12: {
+			ByAlertID: map[string]*regression.Regression{
+				"1": {
+					High: &clustering2.ClusterSummary{
+						Centroid: []float32{1, 1, 3, 3},
+						StepFit:  &stepfit.StepFit{TurningPoint: 2, Status: stepfit.HIGH},
+					},
+				},
+			},
+		},
+	}, nil)
+	return store
+}
+
+func TestRegressionChartHandler_DefaultFormat_ReturnsSVG(t *testing.T) {
+	f := &Frontend{regStore: regressionStoreForChartTest(t)}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/_/reg/chart?cid=12&alert=1", nil)
+	f.regressionChartHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	require.Contains(t, w.Body.String(), "<svg")
+}
+
+func TestRegressionChartHandler_PNGFormat_ReturnsPNG(t *testing.T) {
+	f := &Frontend{regStore: regressionStoreForChartTest(t)}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/_/reg/chart?cid=12&alert=1&dir=high&format=png", nil)
+	f.regressionChartHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	_, err := png.Decode(w.Body)
+	require.NoError(t, err)
+}
+
+func TestRegressionChartHandler_ClusterNotFound_Returns404(t *testing.T) {
+	f := &Frontend{regStore: regressionStoreForChartTest(t)}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/_/reg/chart?cid=12&alert=1&dir=low", nil)
+	f.regressionChartHandler(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRegressionChartHandler_InvalidFormat_ReportsError(t *testing.T) {
+	f := &Frontend{}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/_/reg/chart?cid=12&alert=1&format=gif", nil)
+	f.regressionChartHandler(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
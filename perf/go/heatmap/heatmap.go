@@ -0,0 +1,200 @@
+// Package heatmap summarizes a DataFrame as a matrix of normalized deltas,
+// with a row per trace and a column per commit, or per bucket of commits, that
+// can be rendered as a heatmap.
+//
+// Each cell is the change in the trace's value at that commit, divided by the
+// standard deviation of the trace, so cells from traces with very different
+// magnitudes can be compared. A regression that affects many traces at once
+// shows up as a column of brightly colored cells.
+package heatmap
+
+import (
+	"sort"
+
+	"go.goldmine.build/go/vec32"
+	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/dataframe"
+)
+
+const (
+	// DefaultMaxRows is the number of rows used if the Request doesn't specify
+	// one.
+	DefaultMaxRows = 200
+
+	// DefaultMaxCols is the number of columns used if the Request doesn't
+	// specify one.
+	DefaultMaxCols = 100
+
+	// MaxRows is the largest number of rows a Heatmap can have.
+	MaxRows = 1000
+
+	// MaxCols is the largest number of columns a Heatmap can have.
+	MaxCols = 500
+
+	// MaxCommits is the largest number of commits the range of a Request may
+	// span, since every commit in the range is loaded before being bucketed
+	// into columns.
+	MaxCommits = 4 * MaxCols
+
+	// MaxTraces is the largest number of traces the query of a Request may
+	// match, since every matching trace is loaded to pick the rows.
+	MaxTraces = 20000
+)
+
+// Request is the request for a Heatmap.
+type Request struct {
+	// Begin is the start of the range of commits, in Unix seconds.
+	Begin int `json:"begin"`
+
+	// End is the end of the range of commits, in Unix seconds.
+	End int `json:"end"`
+
+	// Q is the query, in URL query format, that selects the traces.
+	Q string `json:"q"`
+
+	// MaxRows is the maximum number of traces to return. Defaults to
+	// DefaultMaxRows and can't be larger than MaxRows.
+	MaxRows int `json:"max_rows"`
+
+	// MaxCols is the maximum number of columns to return, commits are bucketed
+	// together if there are more commits than columns. Defaults to
+	// DefaultMaxCols and can't be larger than MaxCols.
+	MaxCols int `json:"max_cols"`
+}
+
+// Limits returns the maximum number of rows and columns of the Heatmap, after
+// applying the defaults and caps.
+func (r Request) Limits() (int, int) {
+	return limit(r.MaxRows, DefaultMaxRows, MaxRows), limit(r.MaxCols, DefaultMaxCols, MaxCols)
+}
+
+func limit(value, defaultValue, maxValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	if value > maxValue {
+		return maxValue
+	}
+	return value
+}
+
+// Heatmap is a matrix of normalized deltas.
+type Heatmap struct {
+	// Rows are the trace ids of the rows, ordered by the largest absolute
+	// value in each row, so the traces that changed the most come first.
+	Rows []string `json:"rows"`
+
+	// Columns is the first commit of each column.
+	Columns []*dataframe.ColumnHeader `json:"columns"`
+
+	// BucketSize is the number of commits in each column. The last column may
+	// contain fewer commits.
+	BucketSize int `json:"bucket_size"`
+
+	// Cells are the normalized deltas, indexed as Cells[row][column].
+	Cells [][]float32 `json:"cells"`
+
+	// TotalTraces is the number of traces that matched the query, which is
+	// larger than len(Rows) if the Heatmap was truncated.
+	TotalTraces int `json:"total_traces"`
+}
+
+// New returns a Heatmap of the traces in df with at most maxRows rows and
+// maxCols columns.
+//
+// Missing data points don't contribute a delta, the change is attributed to
+// the next commit that has a value. If there are more commits than maxCols
+// then consecutive commits are bucketed together and the cell holds the sum of
+// the deltas in the bucket, i.e. the net change across the bucket.
+func New(df *dataframe.DataFrame, maxRows, maxCols int) *Heatmap {
+	numCommits := len(df.Header)
+	bucketSize := 1
+	if maxCols > 0 && numCommits > maxCols {
+		bucketSize = (numCommits + maxCols - 1) / maxCols
+	}
+	ret := &Heatmap{
+		Rows:        []string{},
+		Columns:     []*dataframe.ColumnHeader{},
+		BucketSize:  bucketSize,
+		Cells:       [][]float32{},
+		TotalTraces: len(df.TraceSet),
+	}
+	for i := 0; i < numCommits; i += bucketSize {
+		ret.Columns = append(ret.Columns, df.Header[i])
+	}
+
+	type row struct {
+		traceID string
+		cells   []float32
+		score   float32
+	}
+	rows := make([]row, 0, len(df.TraceSet))
+	for traceID, trace := range df.TraceSet {
+		cells := bucket(deltas(trace), bucketSize)
+		var score float32
+		for _, x := range cells {
+			if x < 0 {
+				x = -x
+			}
+			if x > score {
+				score = x
+			}
+		}
+		rows = append(rows, row{traceID: traceID, cells: cells, score: score})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].score != rows[j].score {
+			return rows[i].score > rows[j].score
+		}
+		return rows[i].traceID < rows[j].traceID
+	})
+	if len(rows) > maxRows {
+		rows = rows[:maxRows]
+	}
+	for _, r := range rows {
+		ret.Rows = append(ret.Rows, r.traceID)
+		ret.Cells = append(ret.Cells, r.cells)
+	}
+	return ret
+}
+
+// deltas returns the change at each point of the trace, divided by the
+// standard deviation of the trace.
+func deltas(trace []float32) []float32 {
+	ret := make([]float32, len(trace))
+	_, stddev, err := vec32.MeanAndStdDev(trace)
+	if err != nil {
+		// The trace contains no data.
+		return ret
+	}
+	last := vec32.MissingDataSentinel
+	for i, x := range trace {
+		if x == vec32.MissingDataSentinel {
+			continue
+		}
+		if last != vec32.MissingDataSentinel {
+			ret[i] = x - last
+			if stddev > config.MinStdDev {
+				ret[i] /= stddev
+			}
+		}
+		last = x
+	}
+	return ret
+}
+
+// bucket sums each consecutive run of bucketSize values.
+func bucket(values []float32, bucketSize int) []float32 {
+	if bucketSize <= 1 {
+		return values
+	}
+	ret := make([]float32, 0, (len(values)+bucketSize-1)/bucketSize)
+	for i := 0; i < len(values); i += bucketSize {
+		end := i + bucketSize
+		if end > len(values) {
+			end = len(values)
+		}
+		ret = append(ret, vec32.Sum(values[i:end]))
+	}
+	return ret
+}
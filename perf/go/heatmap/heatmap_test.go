@@ -0,0 +1,104 @@
+package heatmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.goldmine.build/go/vec32"
+	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/types"
+)
+
+const e = vec32.MissingDataSentinel
+
+func dataFrameForTest(numCommits int, traceSet types.TraceSet) *dataframe.DataFrame {
+	df := dataframe.NewEmpty()
+	for i := 0; i < numCommits; i++ {
+		df.Header = append(df.Header, &dataframe.ColumnHeader{
+			Offset:    types.CommitNumber(i),
+			Timestamp: dataframe.TimestampSeconds(1000 + i),
+		})
+	}
+	df.TraceSet = traceSet
+	return df
+}
+
+func TestRequestLimits_ZeroValues_ReturnsDefaults(t *testing.T) {
+	rows, cols := Request{}.Limits()
+	assert.Equal(t, DefaultMaxRows, rows)
+	assert.Equal(t, DefaultMaxCols, cols)
+}
+
+func TestRequestLimits_ValuesTooLarge_ReturnsCaps(t *testing.T) {
+	rows, cols := Request{MaxRows: MaxRows + 1, MaxCols: MaxCols + 1}.Limits()
+	assert.Equal(t, MaxRows, rows)
+	assert.Equal(t, MaxCols, cols)
+}
+
+func TestRequestLimits_ValidValues_ReturnsValues(t *testing.T) {
+	rows, cols := Request{MaxRows: 10, MaxCols: 20}.Limits()
+	assert.Equal(t, 10, rows)
+	assert.Equal(t, 20, cols)
+}
+
+func TestNew_OneColumnPerCommit_DeltasAreNormalizedByStdDev(t *testing.T) {
+	df := dataFrameForTest(4, types.TraceSet{
+		",config=8888,": types.Trace{1, 1, 3, 3}, // StdDev is 1.
+		",config=565,":  types.Trace{2, 2, 2, 2}, // StdDev is 0.
+	})
+	hm := New(df, 10, 10)
+	assert.Equal(t, &Heatmap{
+		Rows:       []string{",config=8888,", ",config=565,"},
+		Columns:    df.Header,
+		BucketSize: 1,
+		Cells: [][]float32{
+			{0, 0, 2, 0},
+			{0, 0, 0, 0},
+		},
+		TotalTraces: 2,
+	}, hm)
+}
+
+func TestNew_MissingData_DeltaIsAttributedToNextCommitWithData(t *testing.T) {
+	df := dataFrameForTest(4, types.TraceSet{
+		",config=8888,": types.Trace{1, e, 3, 3},
+	})
+	hm := New(df, 10, 10)
+	// StdDev of {1, 3, 3} is 0.9428.
+	assert.Len(t, hm.Cells[0], 4)
+	assert.Equal(t, float32(0), hm.Cells[0][1])
+	assert.InDelta(t, 2/0.9428, hm.Cells[0][2], 0.001)
+}
+
+func TestNew_AllDataMissing_ReturnsZeroes(t *testing.T) {
+	df := dataFrameForTest(3, types.TraceSet{
+		",config=8888,": types.Trace{e, e, e},
+	})
+	hm := New(df, 10, 10)
+	assert.Equal(t, [][]float32{{0, 0, 0}}, hm.Cells)
+}
+
+func TestNew_MoreCommitsThanColumns_CommitsAreBucketed(t *testing.T) {
+	df := dataFrameForTest(5, types.TraceSet{
+		",config=8888,": types.Trace{1, 1, 3, 3, 1}, // StdDev is 0.98.
+	})
+	hm := New(df, 10, 2)
+	assert.Equal(t, 3, hm.BucketSize)
+	assert.Equal(t, []*dataframe.ColumnHeader{df.Header[0], df.Header[3]}, hm.Columns)
+	assert.Len(t, hm.Cells[0], 2)
+	assert.InDelta(t, 2/0.9798, hm.Cells[0][0], 0.001)
+	assert.InDelta(t, -2/0.9798, hm.Cells[0][1], 0.001)
+}
+
+func TestNew_MoreTracesThanRows_KeepsTracesWithLargestChanges(t *testing.T) {
+	df := dataFrameForTest(3, types.TraceSet{
+		",config=8888,": types.Trace{1, 1, 1},
+		",config=565,":  types.Trace{1, 1, 5},
+		",config=gles,": types.Trace{1, 5, 1},
+	})
+	hm := New(df, 2, 10)
+	// Ties are broken by trace id.
+	assert.Equal(t, []string{",config=565,", ",config=gles,"}, hm.Rows)
+	assert.Len(t, hm.Cells, 2)
+	assert.Equal(t, 3, hm.TotalTraces)
+}
@@ -0,0 +1,104 @@
+package format
+
+import (
+	"fmt"
+	"sort"
+
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/query"
+)
+
+// CheckResult is the result of Check.
+type CheckResult struct {
+	// Problems are descriptions of the problems found in the file, which the
+	// schema can't detect, but which will cause data to be lost during
+	// ingestion.
+	Problems []string
+
+	// Warnings are descriptions of things in the file that will be altered
+	// during ingestion.
+	Warnings []string
+
+	// TraceCount is the number of distinct traces the file contains points
+	// for.
+	TraceCount int
+
+	// ParamSet is the ParamSet of all the traces in the file.
+	ParamSet paramtools.ParamSet
+}
+
+// Check looks for problems in a parsed file that the schema can't detect, i.e.
+// more than one point for the same trace, of which ingestion only keeps one.
+//
+// Keys or values with characters that ingestion replaces with '_' are reported
+// as warnings, since instances may be configured to allow more characters.
+//
+// It also returns the number of traces and the ParamSet of the file, which
+// help estimate how many traces the file will add to an instance.
+func Check(f Format) CheckResult {
+	ret := CheckResult{
+		ParamSet: paramtools.ParamSet{},
+	}
+	problems := map[string]bool{}
+	warnings := map[string]bool{}
+	checkParams := func(p map[string]string) {
+		for key, value := range p {
+			if query.InvalidChar.MatchString(key) || key == "" {
+				warnings[fmt.Sprintf("Key %q contains characters other than [a-zA-Z0-9._-], they will be replaced with '_'.", key)] = true
+			}
+			if query.InvalidChar.MatchString(value) || value == "" {
+				warnings[fmt.Sprintf("Value %q of key %q contains characters other than [a-zA-Z0-9._-], they will be replaced with '_'.", value, key)] = true
+			}
+		}
+	}
+
+	traceIDs := map[string]bool{}
+	addPoint := func(p paramtools.Params) {
+		checkParams(p)
+		p = query.ForceValid(p)
+		traceID, err := query.MakeKeyFast(p)
+		if err != nil {
+			problems[fmt.Sprintf("Could not make a trace id from %v: %s", p, err)] = true
+			return
+		}
+		if traceIDs[traceID] {
+			problems[fmt.Sprintf("More than one point for trace %s, only one will be kept.", traceID)] = true
+			return
+		}
+		traceIDs[traceID] = true
+		ret.ParamSet.AddParams(p)
+	}
+
+	keyParams := paramtools.Params(f.Key)
+	for _, result := range f.Results {
+		p := keyParams.Copy()
+		p.Add(result.Key)
+		if len(result.Measurements) == 0 {
+			addPoint(p)
+			continue
+		}
+		for key, measurements := range result.Measurements {
+			for _, measurement := range measurements {
+				singleParam := p.Copy()
+				singleParam[key] = measurement.Value
+				addPoint(singleParam)
+			}
+		}
+	}
+
+	ret.Problems = sortedKeys(problems)
+	ret.Warnings = sortedKeys(warnings)
+	ret.ParamSet.Normalize()
+	ret.TraceCount = len(traceIDs)
+	return ret
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]bool) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.goldmine.build/go/paramtools"
+)
+
+func TestCheck_ValidFile_NoProblems(t *testing.T) {
+	f := Format{
+		Version: FileFormatVersion,
+		Key: map[string]string{
+			"arch": "x86",
+		},
+		Results: []Result{
+			{
+				Key:         map[string]string{"test": "a"},
+				Measurement: 1,
+			},
+			{
+				Key: map[string]string{"test": "b"},
+				Measurements: map[string][]SingleMeasurement{
+					"stat": {
+						{Value: "min", Measurement: 1},
+						{Value: "max", Measurement: 2},
+					},
+				},
+			},
+		},
+	}
+	res := Check(f)
+	assert.Empty(t, res.Problems)
+	assert.Empty(t, res.Warnings)
+	assert.Equal(t, 3, res.TraceCount)
+	assert.Equal(t, paramtools.ParamSet{
+		"arch": {"x86"},
+		"stat": {"max", "min"},
+		"test": {"a", "b"},
+	}, res.ParamSet)
+}
+
+func TestCheck_InvalidCharsAndDuplicatePoints_ReportsWarningsAndProblems(t *testing.T) {
+	f := Format{
+		Version: FileFormatVersion,
+		Key: map[string]string{
+			"arch": "x86",
+		},
+		Results: []Result{
+			{
+				Key:         map[string]string{"test": "a b"},
+				Measurement: 1,
+			},
+			{
+				// Becomes the same trace as the one above once the invalid
+				// characters are replaced.
+				Key:         map[string]string{"test": "a_b"},
+				Measurement: 2,
+			},
+		},
+	}
+	res := Check(f)
+	assert.Equal(t, []string{
+		"More than one point for trace ,arch=x86,test=a_b,, only one will be kept.",
+	}, res.Problems)
+	assert.Equal(t, []string{
+		`Value "a b" of key "test" contains characters other than [a-zA-Z0-9._-], they will be replaced with '_'.`,
+	}, res.Warnings)
+	assert.Equal(t, 1, res.TraceCount)
+}
@@ -0,0 +1,113 @@
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"go.goldmine.build/perf/go/types"
+)
+
+// LegacySubResultKey is the key that the sub-results of each nanobench result,
+// e.g. "min_ms", are stored under in the trace ids.
+const LegacySubResultKey = "sub_result"
+
+// ConvertLegacyFormat converts nanobench BenchData into a Format that
+// produces the same trace ids and values when ingested, so harnesses that
+// still emit the legacy format can be migrated without changing their traces.
+//
+// Each test and config pair becomes a single Result with one measurement per
+// sub-result. Samples and values that aren't numbers are ignored, just like
+// they are when BenchData is ingested.
+func ConvertLegacyFormat(b *BenchData) Format {
+	ret := Format{
+		Version:  FileFormatVersion,
+		GitHash:  b.Hash,
+		Issue:    types.CL(b.Issue),
+		Patchset: b.PatchSet,
+		Key:      copyStringMap(b.Key),
+		Results:  []Result{},
+	}
+
+	testNames := make([]string, 0, len(b.Results))
+	for testName := range b.Results {
+		testNames = append(testNames, testName)
+	}
+	sort.Strings(testNames)
+	for _, testName := range testNames {
+		allConfigs := b.Results[testName]
+		configNames := make([]string, 0, len(allConfigs))
+		for configName := range allConfigs {
+			configNames = append(configNames, configName)
+		}
+		sort.Strings(configNames)
+		for _, configName := range configNames {
+			if result, ok := convertLegacyResult(testName, configName, b.Options, allConfigs[configName]); ok {
+				ret.Results = append(ret.Results, result)
+			}
+		}
+	}
+	return ret
+}
+
+// convertLegacyResult returns the Result for a single BenchResult, and false
+// if the BenchResult doesn't contain any values.
+func convertLegacyResult(testName, configName string, options map[string]string, benchResult BenchResult) (Result, bool) {
+	// The Key of a Result takes precedence over the Key of the Format, which
+	// matches the order in which the legacy params are applied.
+	key := map[string]string{
+		"test":   testName,
+		"config": configName,
+	}
+	for k, v := range options {
+		key[k] = v
+	}
+	if resultOptions, ok := benchResult["options"].(map[string]interface{}); ok {
+		for k, vi := range resultOptions {
+			// The GL_ values aren't ingested, see parser.buildInitialParams.
+			if strings.HasPrefix(k, "GL_") {
+				continue
+			}
+			if s, ok := vi.(string); ok {
+				key[k] = s
+			}
+		}
+	}
+
+	measurements := []SingleMeasurement{}
+	for subResult, vi := range benchResult {
+		if subResult == "options" || subResult == "samples" {
+			continue
+		}
+		value, ok := vi.(float64)
+		if !ok {
+			continue
+		}
+		measurements = append(measurements, SingleMeasurement{
+			Value:       subResult,
+			Measurement: float32(value),
+		})
+	}
+	if len(measurements) == 0 {
+		return Result{}, false
+	}
+	sort.Slice(measurements, func(i, j int) bool {
+		return measurements[i].Value < measurements[j].Value
+	})
+	return Result{
+		Key: key,
+		Measurements: map[string][]SingleMeasurement{
+			LegacySubResultKey: measurements,
+		},
+	}, true
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
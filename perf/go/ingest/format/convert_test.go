@@ -0,0 +1,81 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyFile = `{
+  "gitHash": "fe4a4029a080bc955e9588d05a6cd9eb490845d4",
+  "issue": "123",
+  "patchset": "2",
+  "key": {
+    "arch": "x86",
+    "config": "overridden"
+  },
+  "options": {
+    "system": "UNIX"
+  },
+  "results": {
+    "draw_a_circle": {
+      "8888": {
+        "min_ms": 1.5,
+        "max_ms": 2.5,
+        "name": "not a number",
+        "samples": [1.5, 2.5],
+        "options": {
+          "source_type": "bench",
+          "GL_VENDOR": "ignored",
+          "not_a_string": 1
+        }
+      },
+      "gpu": {
+        "options": {
+          "source_type": "bench"
+        }
+      }
+    }
+  }
+}`
+
+func TestConvertLegacyFormat_ValidFile_Success(t *testing.T) {
+	b, err := ParseLegacyFormat(strings.NewReader(legacyFile))
+	require.NoError(t, err)
+
+	assert.Equal(t, Format{
+		Version:  FileFormatVersion,
+		GitHash:  "fe4a4029a080bc955e9588d05a6cd9eb490845d4",
+		Issue:    "123",
+		Patchset: "2",
+		Key: map[string]string{
+			"arch":   "x86",
+			"config": "overridden",
+		},
+		Results: []Result{
+			{
+				Key: map[string]string{
+					"test":        "draw_a_circle",
+					"config":      "8888",
+					"system":      "UNIX",
+					"source_type": "bench",
+				},
+				Measurements: map[string][]SingleMeasurement{
+					LegacySubResultKey: {
+						{Value: "max_ms", Measurement: 2.5},
+						{Value: "min_ms", Measurement: 1.5},
+					},
+				},
+			},
+		},
+	}, ConvertLegacyFormat(b))
+}
+
+func TestConvertLegacyFormat_NoResults_ReturnsEmptyResults(t *testing.T) {
+	f := ConvertLegacyFormat(&BenchData{Hash: "abc"})
+	assert.Equal(t, FileFormatVersion, f.Version)
+	assert.Empty(t, f.Results)
+	assert.NotNil(t, f.Results)
+}
@@ -235,6 +235,25 @@ func (p *Parser) extractFromVersion1File(r io.Reader, filename string) ([]paramt
 	return params, values, f.GitHash, f.Key, nil
 }
 
+// extract the params and values from the contents of a file, which may be in
+// either format.Format or the legacy format.
+func (p *Parser) extract(b []byte, filename string) ([]paramtools.Params, []float32, string, map[string]string, error) {
+	r := bytes.NewReader(b)
+
+	// Expect the file to be in format.FileFormat.
+	sklog.Info("About to extract")
+	params, values, hash, commonKeys, err := p.extractFromVersion1File(r, filename)
+	if err != nil {
+		// Fallback to the legacy format.
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, "", nil, skerr.Wrap(err)
+		}
+		sklog.Info("About to extract from legacy.")
+		params, values, hash, commonKeys, err = p.extractFromLegacyFile(r, filename)
+	}
+	return params, values, hash, commonKeys, err
+}
+
 // Parse the given file.File contents.
 //
 // Returns two parallel slices, each slice contains the params and then the
@@ -260,19 +279,7 @@ func (p *Parser) Parse(ctx context.Context, file file.File) ([]paramtools.Params
 		p.parseFailCounter.Inc(1)
 		return nil, nil, "", skerr.Wrap(err)
 	}
-	r := bytes.NewReader(b)
-
-	// Expect the file to be in format.FileFormat.
-	sklog.Info("About to extract")
-	params, values, hash, commonKeys, err := p.extractFromVersion1File(r, file.Name)
-	if err != nil {
-		// Fallback to the legacy format.
-		if _, err := r.Seek(0, io.SeekStart); err != nil {
-			return nil, nil, "", skerr.Wrap(err)
-		}
-		sklog.Info("About to extract from legacy.")
-		params, values, hash, commonKeys, err = p.extractFromLegacyFile(r, file.Name)
-	}
+	params, values, hash, commonKeys, err := p.extract(b, file.Name)
 	if err != nil && err != ErrFileShouldBeSkipped {
 		p.parseFailCounter.Inc(1)
 	}
@@ -53,6 +53,33 @@ func TestGetParamsAndValuesFromLegacyFormat_Success(t *testing.T) {
 	assert.Contains(t, params, expectedGoodParams)
 }
 
+func TestConvertLegacyFormat_SameParamsAndValuesAsLegacyFormat(t *testing.T) {
+	for _, filename := range []string{"success.json", "samples_success.json", "one_measurement.json"} {
+		t.Run(filename, func(t *testing.T) {
+			r := testutils.GetReader(t, filepath.Join(legacyVersionName, filename))
+			benchData, err := format.ParseLegacyFormat(r)
+			require.NoError(t, err)
+			legacyParams, legacyValues := getParamsAndValuesFromLegacyFormat(benchData)
+
+			params, values := getParamsAndValuesFromVersion1Format(format.ConvertLegacyFormat(benchData), query.InvalidChar)
+
+			require.Len(t, params, len(legacyParams))
+			legacy := map[string]float32{}
+			for i, p := range legacyParams {
+				traceID, err := query.MakeKeyFast(p)
+				require.NoError(t, err)
+				legacy[traceID] = legacyValues[i]
+			}
+			for i, p := range params {
+				traceID, err := query.MakeKeyFast(p)
+				require.NoError(t, err)
+				require.Contains(t, legacy, traceID)
+				assert.Equal(t, legacy[traceID], values[i], traceID)
+			}
+		})
+	}
+}
+
 func TestGetParamsAndValuesFromFormat_Success(t *testing.T) {
 	// Load the sample data file as BenchData.
 	r := testutils.GetReader(t, filepath.Join(versionOneName, "success.json"))
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/query"
+	"go.goldmine.build/perf/go/ingest/format"
+)
+
+const (
+	// MaxSimulatedTraces is the maximum number of traces returned in a
+	// Simulation, the rest are only counted.
+	MaxSimulatedTraces = 1000
+
+	// highCardinalityValues is the number of values above which a key is
+	// reported as having a high cardinality.
+	highCardinalityValues = 100
+)
+
+// SimulatedTrace is a single value that ingesting a file would write.
+type SimulatedTrace struct {
+	TraceID string            `json:"trace_id"`
+	Params  paramtools.Params `json:"params"`
+	Value   float32           `json:"value"`
+}
+
+// Simulation describes what ingesting a file would do.
+type Simulation struct {
+	// GitHash is the git hash the values would be written at.
+	GitHash string `json:"git_hash"`
+
+	// Skipped is true if ingestion would skip the file, either because it
+	// contains no data or because its branch isn't ingested by this instance.
+	Skipped bool `json:"skipped"`
+
+	// TraceCount is the number of traces the file would write to.
+	TraceCount int `json:"trace_count"`
+
+	// Traces are the values that would be written, sorted by trace id. At most
+	// MaxSimulatedTraces are returned.
+	Traces []SimulatedTrace `json:"traces"`
+
+	// ParamSet is the ParamSet of all the traces.
+	ParamSet paramtools.ReadOnlyParamSet `json:"paramset"`
+
+	// Warnings are descriptions of problems in the file, such as data that
+	// would be lost or altered during ingestion, or keys with so many values
+	// that the traces are hard to query.
+	Warnings []string `json:"warnings"`
+}
+
+// Simulate parses the contents of a file the same way Parse does, but instead
+// of returning the params and values for ingestion it returns a description of
+// the traces that would be written, along with warnings about the file.
+//
+// An error is only returned if the file can't be parsed at all.
+func (p *Parser) Simulate(b []byte, filename string) (*Simulation, error) {
+	ret := &Simulation{
+		Traces:   []SimulatedTrace{},
+		ParamSet: paramtools.ReadOnlyParamSet{},
+		Warnings: []string{},
+	}
+
+	// Only format.Format files can be checked for problems beyond parsing.
+	if f, err := format.Parse(bytes.NewReader(b)); err == nil {
+		check := format.Check(f)
+		ret.Warnings = append(ret.Warnings, check.Problems...)
+		ret.Warnings = append(ret.Warnings, check.Warnings...)
+	}
+
+	params, values, hash, commonKeys, err := p.extract(b, filename)
+	if err != nil {
+		return nil, err
+	}
+	ret.GitHash = hash
+	if branch, ok := p.checkBranchName(commonKeys); !ok {
+		ret.Skipped = true
+		ret.Warnings = append(ret.Warnings, fmt.Sprintf("The file would be skipped since this instance doesn't ingest the branch %q.", branch))
+		return ret, nil
+	}
+	if len(params) == 0 {
+		ret.Skipped = true
+		ret.Warnings = append(ret.Warnings, "The file would be skipped since it contains no data.")
+		return ret, nil
+	}
+
+	ps := paramtools.ParamSet{}
+	traces := map[string]SimulatedTrace{}
+	for i, param := range params {
+		traceID, err := query.MakeKey(param)
+		if err != nil {
+			ret.Warnings = append(ret.Warnings, fmt.Sprintf("Could not make a trace id from %v, its value will not be ingested: %s", param, err))
+			continue
+		}
+		traces[traceID] = SimulatedTrace{
+			TraceID: traceID,
+			Params:  param,
+			Value:   values[i],
+		}
+		ps.AddParams(param)
+	}
+	ps.Normalize()
+	ret.ParamSet = ps.Freeze()
+	ret.TraceCount = len(traces)
+	ret.Warnings = append(ret.Warnings, cardinalityWarnings(ps)...)
+
+	for _, t := range traces {
+		ret.Traces = append(ret.Traces, t)
+	}
+	sort.Slice(ret.Traces, func(i, j int) bool {
+		return ret.Traces[i].TraceID < ret.Traces[j].TraceID
+	})
+	if len(ret.Traces) > MaxSimulatedTraces {
+		ret.Traces = ret.Traces[:MaxSimulatedTraces]
+	}
+	return ret, nil
+}
+
+// cardinalityWarnings returns warnings for the keys in ps which have so many
+// values that they are unlikely to be useful for querying, such as keys that
+// hold timestamps or unique ids.
+func cardinalityWarnings(ps paramtools.ParamSet) []string {
+	var ret []string
+	for key, values := range ps {
+		if len(values) > highCardinalityValues {
+			ret = append(ret, fmt.Sprintf("Key %q has %d different values in a single file, keys with many values, such as timestamps or ids, make traces hard to query and slow down the instance.", key, len(values)))
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/testutils"
+)
+
+func readTestFile(t *testing.T, subdir, filename string) []byte {
+	b, err := io.ReadAll(testutils.GetReader(t, filepath.Join(subdir, filename)))
+	require.NoError(t, err)
+	return b
+}
+
+func TestSimulate_ValidFile_ReturnsTracesAndWarnings(t *testing.T) {
+	p, _ := parserForTest(t, versionOneName, "success.json")
+	sim, err := p.Simulate(readTestFile(t, versionOneName, "success.json"), "success.json")
+	require.NoError(t, err)
+	assert.False(t, sim.Skipped)
+	assert.Equal(t, "fe4a4029a080bc955e9588d05a6cd9eb490845d4", sim.GitHash)
+	assert.Equal(t, 4, sim.TraceCount)
+	require.Len(t, sim.Traces, 4)
+	assert.Contains(t, sim.Traces, SimulatedTrace{
+		TraceID: ",arch=x86,branch=some-branch-name,config=meta,gpu=GTX660,model=ShuttleA,os=Ubuntu12,sub_result=max_rss_mb,system=UNIX,test=memory_usage_0_0,",
+		Params:  expectedGoodParams,
+		Value:   858,
+	})
+	assert.Equal(t, []string{"565", "8888", "gpu", "meta"}, sim.ParamSet["config"])
+	// The test names contain '+' which is replaced during ingestion.
+	require.Len(t, sim.Warnings, 2)
+	assert.Contains(t, sim.Warnings[0], `"memory+usage_0_0"`)
+	assert.Contains(t, sim.Warnings[1], `"min+ms"`)
+	// Nothing was counted as ingested.
+	assert.Equal(t, int64(0), p.parseCounter.Get())
+}
+
+func TestSimulate_UnknownBranch_Skipped(t *testing.T) {
+	p, _ := parserForTest(t, versionOneName, "unknown_branch.json")
+	sim, err := p.Simulate(readTestFile(t, versionOneName, "unknown_branch.json"), "unknown_branch.json")
+	require.NoError(t, err)
+	assert.True(t, sim.Skipped)
+	assert.Empty(t, sim.Traces)
+	assert.Equal(t, []string{`The file would be skipped since this instance doesn't ingest the branch "ignoreme".`}, sim.Warnings)
+}
+
+func TestSimulate_NoData_Skipped(t *testing.T) {
+	p, _ := parserForTest(t, versionOneName, "no_results.json")
+	sim, err := p.Simulate(readTestFile(t, versionOneName, "no_results.json"), "no_results.json")
+	require.NoError(t, err)
+	assert.True(t, sim.Skipped)
+	assert.Contains(t, sim.Warnings, "The file would be skipped since it contains no data.")
+}
+
+func TestSimulate_InvalidFile_ReturnsError(t *testing.T) {
+	p, _ := parserForTest(t, versionOneName, "invalid.json")
+	_, err := p.Simulate(readTestFile(t, versionOneName, "invalid.json"), "invalid.json")
+	require.Error(t, err)
+}
+
+func TestCardinalityWarnings_KeyWithManyValues_ReturnsWarning(t *testing.T) {
+	ps := paramtools.ParamSet{"config": []string{"8888"}}
+	for i := 0; i <= highCardinalityValues; i++ {
+		ps.AddParams(paramtools.Params{"timestamp": fmt.Sprintf("%d", i)})
+	}
+	warnings := cardinalityWarnings(ps)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], `Key "timestamp" has 101 different values`)
+}
@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"go.goldmine.build/go/git/provider"
+	"go.goldmine.build/go/httputils"
+	"go.goldmine.build/go/metrics2"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/perf/go/alerts"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/ui/frame"
+)
+
+// Chat messages use the subset of formatting that both Slack and Google Chat
+// understand in the text of a message sent to an incoming webhook, i.e. *bold*
+// and <url|text> links.
+const (
+	newRegressionChat = `A Perf Regression ({{.Cluster.StepFit.Status}}) has been found with {{.Cluster.Num}} matching traces.
+<{{.URL}}/g/t/{{.Commit.GitHash}}|Triage> | <{{.ViewOnDashboard}}|View the cluster> | <{{.CommitURL}}|Commit>
+From Alert <{{.URL}}/a/?{{.Alert.IDAsString}}|{{chatEscape .Alert.DisplayName}}>`
+
+	regressionMissingChat = `A Perf Regression ({{.Cluster.StepFit.Status}}) can no longer be found.
+<{{.URL}}/g/t/{{.Commit.GitHash}}|Triage> | <{{.CommitURL}}|Commit>
+From Alert <{{.URL}}/a/?{{.Alert.IDAsString}}|{{chatEscape .Alert.DisplayName}}>`
+)
+
+var (
+	chatFuncs                     = template.FuncMap{"chatEscape": chatEscape}
+	chatTemplateNewRegression     = template.Must(template.New("newRegressionChat").Funcs(chatFuncs).Parse(newRegressionChat))
+	chatTemplateRegressionMissing = template.Must(template.New("regressionMissingChat").Funcs(chatFuncs).Parse(regressionMissingChat))
+
+	chatEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// chatEscape escapes the characters that have a special meaning in chat
+// messages.
+func chatEscape(s string) string {
+	return chatEscaper.Replace(s)
+}
+
+// ChatFormatter implements Formatter for messages sent by ChatTransport.
+type ChatFormatter struct {
+	commitRangeURITemplate string
+}
+
+// NewChatFormatter returns a new ChatFormatter.
+func NewChatFormatter(commitRangeURITemplate string) ChatFormatter {
+	return ChatFormatter{
+		commitRangeURITemplate: commitRangeURITemplate,
+	}
+}
+
+// FormatNewRegression implements Formatter.
+func (c ChatFormatter) FormatNewRegression(ctx context.Context, commit, previousCommit provider.Commit, alert *alerts.Alert, cl *clustering2.ClusterSummary, URL string, frame *frame.FrameResponse) (string, string, error) {
+	templateContext := &TemplateContext{
+		URL:             URL,
+		ViewOnDashboard: viewOnDashboard(cl, URL, frame),
+		PreviousCommit:  previousCommit,
+		Commit:          commit,
+		CommitURL:       URLFromCommitRange(commit, previousCommit, c.commitRangeURITemplate),
+		Alert:           alert,
+		Cluster:         cl,
+	}
+
+	var b bytes.Buffer
+	if err := chatTemplateNewRegression.Execute(&b, templateContext); err != nil {
+		return "", "", skerr.Wrapf(err, "format chat message for a new regression")
+	}
+	subject := fmt.Sprintf("%s - Regression found for %s", alert.DisplayName, commit.Subject)
+	return b.String(), subject, nil
+}
+
+// FormatRegressionMissing implements Formatter.
+func (c ChatFormatter) FormatRegressionMissing(ctx context.Context, commit, previousCommit provider.Commit, alert *alerts.Alert, cl *clustering2.ClusterSummary, URL string, frame *frame.FrameResponse) (string, string, error) {
+	templateContext := &TemplateContext{
+		URL:            URL,
+		PreviousCommit: previousCommit,
+		Commit:         commit,
+		CommitURL:      URLFromCommitRange(commit, previousCommit, c.commitRangeURITemplate),
+		Alert:          alert,
+		Cluster:        cl,
+	}
+
+	var b bytes.Buffer
+	if err := chatTemplateRegressionMissing.Execute(&b, templateContext); err != nil {
+		return "", "", skerr.Wrapf(err, "format chat message for a regression that has gone missing")
+	}
+	subject := fmt.Sprintf("%s - Regression no longer found for %s", alert.DisplayName, commit.Subject)
+	return b.String(), subject, nil
+}
+
+var _ Formatter = ChatFormatter{}
+
+// chatMessage is the body of a request to a Slack or Google Chat incoming
+// webhook.
+type chatMessage struct {
+	Text string `json:"text"`
+}
+
+// ChatTransport implements Transport by posting messages to Slack or Google
+// Chat incoming webhooks.
+//
+// Chat messages can't be threaded the same way across chat systems, so no
+// threading reference is returned.
+type ChatTransport struct {
+	client   *http.Client
+	channels map[string]string
+
+	sendNewRegression         metrics2.Counter
+	sendNewRegressionFail     metrics2.Counter
+	sendRegressionMissing     metrics2.Counter
+	sendRegressionMissingFail metrics2.Counter
+}
+
+// NewChatTransport returns a new ChatTransport that uses the routing table in
+// cfg.ChatChannels. Failed deliveries are retried with exponential backoff.
+func NewChatTransport(cfg *config.NotifyConfig) *ChatTransport {
+	return &ChatTransport{
+		client:                    httputils.DefaultClientConfig().With2xxOnly().Client(),
+		channels:                  cfg.ChatChannels,
+		sendNewRegression:         metrics2.GetCounter("perf_chat_sent_new_regression"),
+		sendNewRegressionFail:     metrics2.GetCounter("perf_chat_sent_new_regression_fail"),
+		sendRegressionMissing:     metrics2.GetCounter("perf_chat_sent_regression_missing"),
+		sendRegressionMissingFail: metrics2.GetCounter("perf_chat_sent_regression_missing_fail"),
+	}
+}
+
+// SendNewRegression implements Transport.
+func (c *ChatTransport) SendNewRegression(ctx context.Context, alert *alerts.Alert, body, subject string) (string, error) {
+	if err := c.send(ctx, alert, body, subject); err != nil {
+		c.sendNewRegressionFail.Inc(1)
+		return "", skerr.Wrap(err)
+	}
+	c.sendNewRegression.Inc(1)
+	return "", nil
+}
+
+// SendRegressionMissing implements Transport.
+func (c *ChatTransport) SendRegressionMissing(ctx context.Context, threadingReference string, alert *alerts.Alert, body, subject string) error {
+	if err := c.send(ctx, alert, body, subject); err != nil {
+		c.sendRegressionMissingFail.Inc(1)
+		return skerr.Wrap(err)
+	}
+	c.sendRegressionMissing.Inc(1)
+	return nil
+}
+
+// send posts the message to the webhook of the alert.
+func (c *ChatTransport) send(ctx context.Context, alert *alerts.Alert, body, subject string) error {
+	webhook, err := c.webhookURL(alert)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	b, err := json.Marshal(chatMessage{
+		Text: fmt.Sprintf("*%s*\n%s", chatEscape(subject), body),
+	})
+	if err != nil {
+		return skerr.Wrapf(err, "encoding chat message")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(b))
+	if err != nil {
+		return skerr.Wrapf(err, "creating chat request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Don't include the error, it contains the webhook URL, which is a
+		// secret.
+		return skerr.Fmt("sending chat message for alert #%s failed", alert.IDAsString)
+	}
+	return skerr.Wrap(resp.Body.Close())
+}
+
+// webhookURL returns the webhook to send the notifications for the alert to.
+// Alert.Chat must be the name of a channel in the routing table. Webhook URLs
+// are secrets, so they are never stored in an Alert, which anyone can read.
+func (c *ChatTransport) webhookURL(alert *alerts.Alert) (string, error) {
+	if alert.Chat == "" {
+		return "", skerr.Fmt("notification not sent, no chat channel set for alert #%s", alert.IDAsString)
+	}
+	webhook, ok := c.channels[alert.Chat]
+	if !ok {
+		return "", skerr.Fmt("notification not sent, unknown chat channel %q for alert #%s", alert.Chat, alert.IDAsString)
+	}
+	return webhook, nil
+}
+
+var _ Transport = (*ChatTransport)(nil)
+
+// emailAndChatNotifier sends notifications by email, chat, or both, depending
+// on which destinations are set in each Alert.
+type emailAndChatNotifier struct {
+	email Notifier
+	chat  Notifier
+}
+
+// newEmailAndChatNotifier returns a new emailAndChatNotifier.
+func newEmailAndChatNotifier(email, chat Notifier) Notifier {
+	return &emailAndChatNotifier{
+		email: email,
+		chat:  chat,
+	}
+}
+
+// RegressionFound implements Notifier. The threading reference of the email,
+// if any, is returned.
+func (n *emailAndChatNotifier) RegressionFound(ctx context.Context, commit, previousCommit provider.Commit, alert *alerts.Alert, cl *clustering2.ClusterSummary, frame *frame.FrameResponse) (string, error) {
+	if alert.Alert == "" && alert.Chat == "" {
+		return "", skerr.Fmt("notification not sent, no email address or chat channel set for alert #%s", alert.IDAsString)
+	}
+	var threadingReference string
+	var emailErr, chatErr error
+	if alert.Alert != "" {
+		threadingReference, emailErr = n.email.RegressionFound(ctx, commit, previousCommit, alert, cl, frame)
+	}
+	if alert.Chat != "" {
+		_, chatErr = n.chat.RegressionFound(ctx, commit, previousCommit, alert, cl, frame)
+	}
+	return threadingReference, errors.Join(emailErr, chatErr)
+}
+
+// RegressionMissing implements Notifier.
+func (n *emailAndChatNotifier) RegressionMissing(ctx context.Context, commit, previousCommit provider.Commit, alert *alerts.Alert, cl *clustering2.ClusterSummary, frame *frame.FrameResponse, threadingReference string) error {
+	if alert.Alert == "" && alert.Chat == "" {
+		return skerr.Fmt("notification not sent, no email address or chat channel set for alert #%s", alert.IDAsString)
+	}
+	var emailErr, chatErr error
+	if alert.Alert != "" {
+		emailErr = n.email.RegressionMissing(ctx, commit, previousCommit, alert, cl, frame, threadingReference)
+	}
+	if alert.Chat != "" {
+		chatErr = n.chat.RegressionMissing(ctx, commit, previousCommit, alert, cl, frame, "")
+	}
+	return errors.Join(emailErr, chatErr)
+}
+
+// ExampleSend implements Notifier.
+func (n *emailAndChatNotifier) ExampleSend(ctx context.Context, alert *alerts.Alert) error {
+	if alert.Alert == "" && alert.Chat == "" {
+		return skerr.Fmt("notification not sent, no email address or chat channel set for alert #%s", alert.IDAsString)
+	}
+	var emailErr, chatErr error
+	if alert.Alert != "" {
+		emailErr = n.email.ExampleSend(ctx, alert)
+	}
+	if alert.Chat != "" {
+		chatErr = n.chat.ExampleSend(ctx, alert)
+	}
+	return errors.Join(emailErr, chatErr)
+}
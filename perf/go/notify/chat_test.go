@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/perf/go/alerts"
+	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/notify/mocks"
+)
+
+const (
+	newChatMessage = "A Perf Regression (High) has been found with 10 matching traces.\n<https://perf.skia.org/g/t/333333333333|Triage> | <https://perf.skia.org/e/?end=1498176001&keys=&num_commits=250&request_type=1&xbaroffset=2|View the cluster> | <https://example.org/from/111111111111/to/333333333333/|Commit>\nFrom Alert <https://perf.skia.org/a/?123|MyAlert &lt;chat&gt;>"
+	newChatSubject = "MyAlert <chat> - Regression found for Fix a bug."
+)
+
+func TestChatFormatter_FormatNewRegression_LinksToTriageAndClusterPages(t *testing.T) {
+	alert := &alerts.Alert{
+		IDAsString:  "123",
+		DisplayName: "MyAlert <chat>",
+	}
+	commit := commit
+	commit.Subject = "Fix a bug."
+	body, subject, err := NewChatFormatter(uriTemplate).FormatNewRegression(context.Background(), commit, previousCommit, alert, cl, instanceURL, frameResponse)
+	require.NoError(t, err)
+	assert.Equal(t, newChatMessage, body)
+	assert.Equal(t, newChatSubject, subject)
+}
+
+func TestChatTransport_SendNewRegressionToChannel_PostsToRoutedWebhook(t *testing.T) {
+	var received chatMessage
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/webhook", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	tr := NewChatTransport(&config.NotifyConfig{
+		ChatChannels: map[string]string{"perf-alerts": s.URL + "/webhook"},
+	})
+
+	ref, err := tr.SendNewRegression(context.Background(), &alerts.Alert{Chat: "perf-alerts"}, "body", "a <subject>")
+	require.NoError(t, err)
+	assert.Empty(t, ref)
+	assert.Equal(t, "*a &lt;subject&gt;*\nbody", received.Text)
+}
+
+func TestChatTransport_WebhookReturnsClientError_ReturnsErrorWithoutURL(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+	tr := NewChatTransport(&config.NotifyConfig{
+		ChatChannels: map[string]string{"perf-alerts": s.URL + "/secret-token"},
+	})
+
+	err := tr.SendRegressionMissing(context.Background(), "", &alerts.Alert{IDAsString: "123", Chat: "perf-alerts"}, "body", "subject")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret-token")
+}
+
+func TestChatTransport_ChatIsNotAKnownChannel_ReturnsError(t *testing.T) {
+	tr := NewChatTransport(&config.NotifyConfig{})
+	for _, chat := range []string{"", "unknown-channel", "https://hooks.slack.com/services/T000/B000/XXXX", "https://chat.googleapis.com/v1/spaces/AAAA/messages?key=k&token=t"} {
+		_, err := tr.SendNewRegression(context.Background(), &alerts.Alert{IDAsString: "123", Chat: chat}, "body", "subject")
+		assert.Error(t, err, chat)
+	}
+}
+
+func TestEmailAndChatNotifier_OnlyChatSet_OnlySendsChat(t *testing.T) {
+	email := mocks.NewTransport(t)
+	chat := mocks.NewTransport(t)
+	chat.On("SendNewRegression", testutils.AnyContext, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+	chat.On("SendRegressionMissing", testutils.AnyContext, "", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	n := newEmailAndChatNotifier(newNotifier(NewHTMLFormatter(""), email, instanceURL), newNotifier(NewChatFormatter(""), chat, instanceURL))
+	err := n.ExampleSend(context.Background(), &alerts.Alert{IDAsString: "123", Chat: "perf-alerts"})
+	require.NoError(t, err)
+	email.AssertNotCalled(t, "SendNewRegression", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailAndChatNotifier_BothSetAndChatFails_ReturnsEmailThreadingReferenceAndError(t *testing.T) {
+	email := mocks.NewTransport(t)
+	email.On("SendNewRegression", testutils.AnyContext, mock.Anything, mock.Anything, mock.Anything).Return(mockThreadingID, nil)
+	chat := mocks.NewTransport(t)
+	chat.On("SendNewRegression", testutils.AnyContext, mock.Anything, mock.Anything, mock.Anything).Return("", errMock)
+
+	n := newEmailAndChatNotifier(newNotifier(NewHTMLFormatter(""), email, instanceURL), newNotifier(NewChatFormatter(""), chat, instanceURL))
+	alert := &alerts.Alert{IDAsString: "123", Alert: "someone@example.org", Chat: "perf-alerts"}
+	ref, err := n.RegressionFound(context.Background(), commit, previousCommit, alert, cl, frameResponse)
+	require.ErrorIs(t, err, errMock)
+	assert.Equal(t, mockThreadingID, ref)
+}
+
+func TestEmailAndChatNotifier_NoDestinationSet_ReturnsError(t *testing.T) {
+	n := newEmailAndChatNotifier(newNotifier(NewHTMLFormatter(""), mocks.NewTransport(t), instanceURL), newNotifier(NewChatFormatter(""), mocks.NewTransport(t), instanceURL))
+	_, err := n.RegressionFound(context.Background(), commit, previousCommit, &alerts.Alert{IDAsString: "123"}, cl, frameResponse)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no email address or chat channel")
+}
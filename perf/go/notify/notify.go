@@ -23,7 +23,7 @@ type Formatter interface {
 	FormatRegressionMissing(ctx context.Context, commit, previousCommit provider.Commit, alert *alerts.Alert, cl *clustering2.ClusterSummary, URL string, frame *frame.FrameResponse) (string, string, error)
 }
 
-// Transport has implementations for email, issuetracker, and the noop implementation.
+// Transport has implementations for email, chat, issuetracker, and the noop implementation.
 type Transport interface {
 	SendNewRegression(ctx context.Context, alert *alerts.Alert, body, subject string) (threadingReference string, err error)
 	SendRegressionMissing(ctx context.Context, threadingReference string, alert *alerts.Alert, body, subject string) (err error)
@@ -185,6 +185,10 @@ func New(ctx context.Context, cfg *config.NotifyConfig, URL, commitRangeURITempl
 		return newNotifier(NewHTMLFormatter(commitRangeURITemplate), NewNoopTransport(), URL), nil
 	case notifytypes.HTMLEmail:
 		return newNotifier(NewHTMLFormatter(commitRangeURITemplate), NewEmailTransport(), URL), nil
+	case notifytypes.HTMLEmailAndChat:
+		email := newNotifier(NewHTMLFormatter(commitRangeURITemplate), NewEmailTransport(), URL)
+		chat := newNotifier(NewChatFormatter(commitRangeURITemplate), NewChatTransport(cfg), URL)
+		return newEmailAndChatNotifier(email, chat), nil
 	case notifytypes.MarkdownIssueTracker:
 		tracker, err := NewIssueTrackerTransport(ctx, cfg)
 		if err != nil {
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+
+	"go.goldmine.build/email/go/emailclient"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/notifytypes"
+)
+
+// UserNotifier sends messages to a single user, as opposed to the addresses
+// configured in an alert.
+type UserNotifier interface {
+	// SendToUser sends the HTML body with the given subject to the user with
+	// the given email address.
+	SendToUser(ctx context.Context, user, subject, body string) error
+}
+
+// emailUserNotifier implements UserNotifier using emailclient.
+type emailUserNotifier struct {
+	client emailclient.Client
+}
+
+// SendToUser implements UserNotifier.
+func (e emailUserNotifier) SendToUser(ctx context.Context, user, subject, body string) error {
+	if user == "" {
+		return skerr.Fmt("No notification sent. No user given for %q", subject)
+	}
+	if _, err := e.client.SendWithMarkup("", fromAddress, []string{user}, subject, body, "", ""); err != nil {
+		return skerr.Wrapf(err, "sending notification by email")
+	}
+	return nil
+}
+
+// noopUserNotifier implements UserNotifier by doing nothing.
+type noopUserNotifier struct{}
+
+// SendToUser implements UserNotifier.
+func (noopUserNotifier) SendToUser(ctx context.Context, user, subject, body string) error {
+	return nil
+}
+
+// NewUserNotifier returns a UserNotifier for the given config. Users are only
+// notified when notifications are sent by email, since chat channels aren't
+// per user.
+func NewUserNotifier(cfg *config.NotifyConfig) UserNotifier {
+	switch cfg.Notifications {
+	case notifytypes.HTMLEmail, notifytypes.HTMLEmailAndChat:
+		return emailUserNotifier{client: emailclient.New()}
+	default:
+		return noopUserNotifier{}
+	}
+}
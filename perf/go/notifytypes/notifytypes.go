@@ -7,6 +7,10 @@ const (
 	// HTMLEmail means send HTML formatted emails.
 	HTMLEmail Type = "html_email"
 
+	// HTMLEmailAndChat means send HTML formatted emails, messages to Slack or
+	// Google Chat, or both, depending on the destinations set in each alert.
+	HTMLEmailAndChat Type = "html_email_and_chat"
+
 	// MarkdownIssueTracker means send Markdown formatted notifications to the
 	// issue tracker.
 	MarkdownIssueTracker Type = "markdown_issuetracker"
@@ -16,4 +20,4 @@ const (
 )
 
 // AllNotifierTypes is the list of all valid NotifyTypes.
-var AllNotifierTypes []Type = []Type{HTMLEmail, MarkdownIssueTracker, None}
+var AllNotifierTypes []Type = []Type{HTMLEmail, HTMLEmailAndChat, MarkdownIssueTracker, None}
@@ -0,0 +1,226 @@
+// Package obfuscate hides the values of selected trace params, e.g. internal
+// bot or config names, from users that are not logged in by replacing them with
+// a stable keyed hash.
+//
+// The same value always hashes to the same obfuscated value, so obfuscated
+// values can still be used to build queries, and Deobfuscator maps them back
+// to the raw values before the queries are run.
+package obfuscate
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/query"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/types"
+)
+
+const (
+	// prefix starts every obfuscated value.
+	prefix = "x"
+
+	// hashBytes is how many bytes of the HMAC are kept, which is plenty to
+	// avoid collisions between the values of a single param.
+	hashBytes = 10
+)
+
+// quotedRegex matches the double quoted arguments of a formula, such as the
+// query in `filter("arch=x86&config=8888")`.
+var quotedRegex = regexp.MustCompile(`"[^"]*"`)
+
+// tokenRegex matches obfuscated values embedded in a larger string, e.g. a
+// URL encoded query or a formula.
+var tokenRegex = regexp.MustCompile(fmt.Sprintf("%s[0-9a-f]{%d}", prefix, 2*hashBytes))
+
+// Obfuscator replaces the values of the configured params with keyed hashes.
+// It is safe for concurrent use.
+type Obfuscator struct {
+	key    []byte
+	params map[string]bool
+}
+
+// New returns an Obfuscator that hashes the values of the given params with
+// the given secret key. Anyone who knows the key can reverse the hashes by
+// guessing values, so it must not be shared.
+func New(key []byte, params []string) *Obfuscator {
+	p := make(map[string]bool, len(params))
+	for _, param := range params {
+		p[param] = true
+	}
+	return &Obfuscator{
+		key:    key,
+		params: p,
+	}
+}
+
+// hash returns the obfuscated form of s.
+func (o *Obfuscator) hash(s string) string {
+	mac := hmac.New(sha256.New, o.key)
+	_, _ = mac.Write([]byte(s))
+	return prefix + hex.EncodeToString(mac.Sum(nil)[:hashBytes])
+}
+
+// Value returns the obfuscated form of the given value of param. Values of
+// params that are not obfuscated are returned unchanged.
+func (o *Obfuscator) Value(param, value string) string {
+	if !o.params[param] {
+		return value
+	}
+	// Include the param so equal values of different params can't be
+	// correlated.
+	return o.hash(param + "=" + value)
+}
+
+// Params returns a copy of p with the values obfuscated.
+func (o *Obfuscator) Params(p paramtools.Params) paramtools.Params {
+	ret := make(paramtools.Params, len(p))
+	for k, v := range p {
+		ret[k] = o.Value(k, v)
+	}
+	return ret
+}
+
+// Key returns the obfuscated form of a structured trace key, e.g.
+// ",arch=x86,config=8888,". Keys that are not structured, such as the results
+// of formulas, may embed any param value, so they are hashed as a whole.
+func (o *Obfuscator) Key(key string) string {
+	p, err := query.ParseKeyFast(key)
+	if err != nil {
+		return o.hash(key)
+	}
+	ret, err := query.MakeKeyFast(o.Params(p))
+	if err != nil {
+		return o.hash(key)
+	}
+	return ret
+}
+
+// Query returns the obfuscated form of a URL encoded query, such as
+// "arch=x86&config=!8888". Negated values keep their "!" prefix so the query
+// still works once deobfuscated. Regexes over obfuscated params can't be
+// mapped to obfuscated values, so they are hashed as a whole and won't match
+// any traces. Queries that can't be parsed are hashed as a whole.
+func (o *Obfuscator) Query(q string) string {
+	values, err := url.ParseQuery(q)
+	if err != nil {
+		return o.hash(q)
+	}
+	for k, vs := range values {
+		if !o.params[k] {
+			continue
+		}
+		for i, v := range vs {
+			switch {
+			case strings.HasPrefix(v, "~"):
+				vs[i] = o.hash(k + "=" + v)
+			case strings.HasPrefix(v, "!"):
+				vs[i] = "!" + o.Value(k, v[1:])
+			case v == "*":
+			default:
+				vs[i] = o.Value(k, v)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// Formula returns the obfuscated form of a formula, e.g.
+// `ave(filter("config=8888"))`, with the queries it contains obfuscated.
+func (o *Obfuscator) Formula(formula string) string {
+	return quotedRegex.ReplaceAllStringFunc(formula, func(quoted string) string {
+		return `"` + o.Query(quoted[1:len(quoted)-1]) + `"`
+	})
+}
+
+// ParamSet returns a copy of ps with the values obfuscated.
+func (o *Obfuscator) ParamSet(ps paramtools.ReadOnlyParamSet) paramtools.ReadOnlyParamSet {
+	ret := make(paramtools.ParamSet, len(ps))
+	for k, values := range ps {
+		obfuscated := make([]string, 0, len(values))
+		for _, v := range values {
+			obfuscated = append(obfuscated, o.Value(k, v))
+		}
+		if o.params[k] {
+			sort.Strings(obfuscated)
+		}
+		ret[k] = obfuscated
+	}
+	return ret.Freeze()
+}
+
+// DataFrame returns a copy of df with the trace keys and ParamSet obfuscated.
+// The traces themselves are shared with df.
+func (o *Obfuscator) DataFrame(df *dataframe.DataFrame) *dataframe.DataFrame {
+	if df == nil {
+		return nil
+	}
+	traceSet := make(types.TraceSet, len(df.TraceSet))
+	for key, trace := range df.TraceSet {
+		traceSet[o.Key(key)] = trace
+	}
+	return &dataframe.DataFrame{
+		TraceSet: traceSet,
+		Header:   df.Header,
+		ParamSet: o.ParamSet(df.ParamSet),
+		Skip:     df.Skip,
+	}
+}
+
+// Deobfuscator maps obfuscated values back to the raw values. It only knows
+// about the values in the ParamSet it was created from.
+type Deobfuscator struct {
+	raw map[string]string
+}
+
+// Deobfuscator returns a Deobfuscator for all the obfuscated values in ps,
+// which is usually the ParamSet of all the traces in the instance.
+func (o *Obfuscator) Deobfuscator(ps paramtools.ReadOnlyParamSet) *Deobfuscator {
+	raw := map[string]string{}
+	for k, values := range ps {
+		if !o.params[k] {
+			continue
+		}
+		for _, v := range values {
+			raw[o.Value(k, v)] = v
+		}
+	}
+	return &Deobfuscator{raw: raw}
+}
+
+// Replace returns s with all the obfuscated values it contains replaced by
+// their raw values. It works on anything that embeds values verbatim, such as
+// URL encoded queries, formulas and trace keys. Unknown obfuscated values are
+// left unchanged, so they won't match any traces.
+func (d *Deobfuscator) Replace(s string) string {
+	return tokenRegex.ReplaceAllStringFunc(s, func(token string) string {
+		if v, ok := d.raw[token]; ok {
+			return v
+		}
+		return token
+	})
+}
+
+// ReplaceQuery is like Replace, but for URL encoded queries, such as
+// "arch=x86&config=8888", so that raw values are correctly escaped.
+func (d *Deobfuscator) ReplaceQuery(q string) (string, error) {
+	values, err := url.ParseQuery(q)
+	if err != nil {
+		return "", skerr.Wrap(err)
+	}
+	for k, vs := range values {
+		for i, v := range vs {
+			vs[i] = d.Replace(v)
+		}
+		values[k] = vs
+	}
+	return values.Encode(), nil
+}
@@ -0,0 +1,110 @@
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/types"
+)
+
+var testParamSet = paramtools.ReadOnlyParamSet{
+	"arch":   []string{"arm", "x86"},
+	"bot":    []string{"internal-bot-1", "internal bot 2"},
+	"config": []string{"8888", "gles"},
+}
+
+func newTestObfuscator() *Obfuscator {
+	return New([]byte("secret"), []string{"bot"})
+}
+
+func TestValue_ObfuscatedParam_StableAndKeyed(t *testing.T) {
+	o := newTestObfuscator()
+	v := o.Value("bot", "internal-bot-1")
+	assert.NotEqual(t, "internal-bot-1", v)
+	assert.Regexp(t, tokenRegex, v)
+	assert.Equal(t, v, o.Value("bot", "internal-bot-1"))
+	assert.NotEqual(t, v, New([]byte("another secret"), []string{"bot"}).Value("bot", "internal-bot-1"))
+	assert.NotEqual(t, v, New([]byte("secret"), []string{"bot", "other"}).Value("other", "internal-bot-1"))
+}
+
+func TestValue_OtherParam_Unchanged(t *testing.T) {
+	assert.Equal(t, "x86", newTestObfuscator().Value("arch", "x86"))
+}
+
+func TestKey_StructuredKey_OnlyObfuscatedParamsChange(t *testing.T) {
+	o := newTestObfuscator()
+	assert.Equal(t, ",arch=x86,bot="+o.Value("bot", "internal-bot-1")+",", o.Key(",arch=x86,bot=internal-bot-1,"))
+}
+
+func TestKey_FormulaKey_HashedAsAWhole(t *testing.T) {
+	key := `norm(filter("bot=internal-bot-1"))`
+	v := newTestObfuscator().Key(key)
+	assert.NotContains(t, v, "internal-bot-1")
+	assert.Regexp(t, tokenRegex, v)
+}
+
+func TestDataFrame_KeysAndParamSetObfuscated(t *testing.T) {
+	o := newTestObfuscator()
+	df := &dataframe.DataFrame{
+		TraceSet: types.TraceSet{
+			",arch=x86,bot=internal-bot-1,": types.Trace{1, 2},
+		},
+		Header: []*dataframe.ColumnHeader{{Offset: 1}, {Offset: 2}},
+		Skip:   1,
+	}
+	df.BuildParamSet()
+
+	actual := o.DataFrame(df)
+	bot := o.Value("bot", "internal-bot-1")
+	assert.Equal(t, types.TraceSet{",arch=x86,bot=" + bot + ",": types.Trace{1, 2}}, actual.TraceSet)
+	assert.Equal(t, paramtools.ReadOnlyParamSet{"arch": []string{"x86"}, "bot": []string{bot}}, actual.ParamSet)
+	assert.Equal(t, df.Header, actual.Header)
+	assert.Equal(t, 1, actual.Skip)
+	// The original is untouched.
+	assert.Contains(t, df.TraceSet, ",arch=x86,bot=internal-bot-1,")
+}
+
+func TestDeobfuscator_ReplaceQuery_RawValuesEscaped(t *testing.T) {
+	o := newTestObfuscator()
+	d := o.Deobfuscator(testParamSet)
+	q := "arch=x86&bot=" + o.Value("bot", "internal bot 2") + "&bot=" + o.Value("bot", "unknown")
+	actual, err := d.ReplaceQuery(q)
+	require.NoError(t, err)
+	assert.Equal(t, "arch=x86&bot=internal+bot+2&bot="+o.Value("bot", "unknown"), actual)
+}
+
+func TestDeobfuscator_Replace_Formula(t *testing.T) {
+	o := newTestObfuscator()
+	d := o.Deobfuscator(testParamSet)
+	assert.Equal(t, `ave(filter("bot=internal-bot-1"))`, d.Replace(`ave(filter("bot=`+o.Value("bot", "internal-bot-1")+`"))`))
+}
+
+func TestQuery_ObfuscatedParam_RoundTripsThroughDeobfuscator(t *testing.T) {
+	o := newTestObfuscator()
+	q := "arch=x86&bot=internal+bot+2&bot=%21internal-bot-1"
+	actual := o.Query(q)
+	assert.NotContains(t, actual, "internal")
+	assert.Equal(t, "arch=x86&bot="+o.Value("bot", "internal bot 2")+"&bot=%21"+o.Value("bot", "internal-bot-1"), actual)
+
+	raw, err := o.Deobfuscator(testParamSet).ReplaceQuery(actual)
+	require.NoError(t, err)
+	assert.Equal(t, q, raw)
+}
+
+func TestQuery_RegexOverObfuscatedParam_HashedAsAWhole(t *testing.T) {
+	o := newTestObfuscator()
+	actual := o.Query("arch=~x.*&bot=~internal.*")
+	assert.NotContains(t, actual, "internal")
+	assert.Contains(t, actual, "arch=~x.%2A")
+}
+
+func TestFormula_QueriesObfuscated(t *testing.T) {
+	o := newTestObfuscator()
+	actual := o.Formula(`norm(filter("arch=x86&bot=internal-bot-1"))`)
+	assert.Equal(t, `norm(filter("arch=x86&bot=`+o.Value("bot", "internal-bot-1")+`"))`, actual)
+	assert.Equal(t, `norm(filter("arch=x86&bot=internal-bot-1"))`, o.Deobfuscator(testParamSet).Replace(actual))
+}
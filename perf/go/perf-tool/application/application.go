@@ -47,6 +47,8 @@ type Application interface {
 	DatabaseRestoreAlerts(local bool, instanceConfig *config.InstanceConfig, inputFile string) error
 	DatabaseRestoreShortcuts(local bool, instanceConfig *config.InstanceConfig, inputFile string) error
 	DatabaseRestoreRegressions(local bool, instanceConfig *config.InstanceConfig, inputFile string) error
+	SnapshotExport(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, outputFile, backupTo string, numTiles int) error
+	SnapshotImport(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, inputFile string, rewrites []string) error
 	TilesLast(store tracestore.TraceStore) error
 	TilesList(store tracestore.TraceStore, num int) error
 	TracesList(store tracestore.TraceStore, queryString string, tileNumber types.TileNumber) error
@@ -185,6 +187,19 @@ func (app) DatabaseBackupAlerts(local bool, instanceConfig *config.InstanceConfi
 	defer util.Close(f)
 	z := zip.NewWriter(f)
 
+	if err := backupAlerts(ctx, z, local, instanceConfig); err != nil {
+		return skerr.Wrap(err)
+	}
+	if err := z.Close(); err != nil {
+		return skerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// backupAlerts writes all the alerts, including deleted ones, into the given
+// .zip archive.
+func backupAlerts(ctx context.Context, z *zip.Writer, local bool, instanceConfig *config.InstanceConfig) error {
 	alertStore, err := builders.NewAlertStoreFromConfig(ctx, local, instanceConfig)
 	if err != nil {
 		return skerr.Wrap(err)
@@ -204,10 +219,6 @@ func (app) DatabaseBackupAlerts(local bool, instanceConfig *config.InstanceConfi
 			return skerr.Wrap(err)
 		}
 	}
-	if err := z.Close(); err != nil {
-		return skerr.Wrap(err)
-	}
-
 	return nil
 }
 
@@ -268,6 +279,19 @@ func (app) DatabaseBackupShortcuts(local bool, instanceConfig *config.InstanceCo
 	return nil
 }
 
+// parseBackupToDate parses the date, of the form 2006-01-02, of the oldest
+// Regressions to back up. It defaults to four weeks ago.
+func parseBackupToDate(backupTo string) (time.Time, error) {
+	if backupTo == "" {
+		return time.Now().Add(-time.Hour * 24 * 7 * 4), nil
+	}
+	backupToDate, err := time.Parse("2006-01-02", backupTo)
+	if err != nil {
+		return time.Time{}, skerr.Wrap(err)
+	}
+	return backupToDate, nil
+}
+
 // allRegressionsForCommitWithCommitNumber is the struct we actually write into
 // the backup.
 type allRegressionsForCommitWithCommitNumber struct {
@@ -280,15 +304,9 @@ type allRegressionsForCommitWithCommitNumber struct {
 func (app) DatabaseBackupRegressions(local bool, instanceConfig *config.InstanceConfig, outputFile, backupTo string) error {
 	ctx := context.Background()
 
-	var backupToDate time.Time
-	if backupTo == "" {
-		backupToDate = time.Now().Add(-time.Hour * 24 * 7 * 4)
-	} else {
-		var err error
-		backupToDate, err = time.Parse("2006-01-02", backupTo)
-		if err != nil {
-			return skerr.Wrap(err)
-		}
+	backupToDate, err := parseBackupToDate(backupTo)
+	if err != nil {
+		return skerr.Wrap(err)
 	}
 	fmt.Printf("Backing up from %v\n", backupToDate)
 
@@ -299,6 +317,20 @@ func (app) DatabaseBackupRegressions(local bool, instanceConfig *config.Instance
 	defer util.Close(f)
 	z := zip.NewWriter(f)
 
+	if err := backupRegressions(ctx, z, local, instanceConfig, backupToDate); err != nil {
+		return skerr.Wrap(err)
+	}
+	if err := z.Close(); err != nil {
+		return skerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// backupRegressions writes all the Regressions found at commits since
+// backupToDate into the given .zip archive, along with all the shortcuts that
+// are mentioned in those Regressions.
+func backupRegressions(ctx context.Context, z *zip.Writer, local bool, instanceConfig *config.InstanceConfig, backupToDate time.Time) error {
 	// Backup Regressions.
 	regressionsZipWriter, err := z.Create(backupFilenameRegressions)
 	if err != nil {
@@ -405,10 +437,6 @@ func (app) DatabaseBackupRegressions(local bool, instanceConfig *config.Instance
 	}
 
 	fmt.Println()
-	if err := z.Close(); err != nil {
-		return skerr.Wrap(err)
-	}
-
 	return nil
 }
 
@@ -442,6 +470,12 @@ func (app) DatabaseRestoreAlerts(local bool, instanceConfig *config.InstanceConf
 	}
 	defer util.Close(z)
 
+	return restoreAlerts(ctx, z, local, instanceConfig, nil)
+}
+
+// restoreAlerts restores the Alerts in the given .zip archive to the
+// database, after applying the rewriter to them.
+func restoreAlerts(ctx context.Context, z *zip.ReadCloser, local bool, instanceConfig *config.InstanceConfig, rw *rewriter) error {
 	alertStore, err := builders.NewAlertStoreFromConfig(ctx, local, instanceConfig)
 	if err != nil {
 		return skerr.Wrap(err)
@@ -461,6 +495,7 @@ func (app) DatabaseRestoreAlerts(local bool, instanceConfig *config.InstanceConf
 		if err != nil {
 			return skerr.Wrap(err)
 		}
+		rw.rewriteAlert(&alert)
 		if err := alertStore.Save(ctx, &alert); err != nil {
 			return skerr.Wrap(err)
 		}
@@ -527,6 +562,13 @@ func (app) DatabaseRestoreRegressions(local bool, instanceConfig *config.Instanc
 	}
 	defer util.Close(z)
 
+	return restoreRegressions(ctx, z, local, instanceConfig, nil)
+}
+
+// restoreRegressions restores the Regressions in the given .zip archive, and
+// their associated shortcuts, to the database, after applying the rewriter to
+// them.
+func restoreRegressions(ctx context.Context, z *zip.ReadCloser, local bool, instanceConfig *config.InstanceConfig, rw *rewriter) error {
 	// Restore Regressions
 	regressionStore, err := builders.NewRegressionStoreFromConfig(ctx, local, instanceConfig)
 	if err != nil {
@@ -557,6 +599,9 @@ func (app) DatabaseRestoreRegressions(local bool, instanceConfig *config.Instanc
 		if err != nil {
 			return err
 		}
+		for _, r := range a.AllRegressionsForCommit.ByAlertID {
+			rw.rewriteRegression(r)
+		}
 		err = regressionStore.Write(ctx, map[types.CommitNumber]*regression.AllRegressionsForCommit{
 			a.CommitNumber: a.AllRegressionsForCommit,
 		})
@@ -777,10 +822,20 @@ func (app) IngestForceReingest(local bool, instanceConfig *config.InstanceConfig
 	return nil
 }
 
+// IngestValidate validates an ingestion file against the schema, and then
+// checks it for problems that would cause data to be lost during ingestion,
+// such as duplicate points, and warns about params that would be altered. It also
+// prints the number of traces in the file and the number of values for each
+// param, to help estimate the number of traces the file will add to an
+// instance.
 func (app) IngestValidate(inputFile string, verbose bool) error {
 	ctx := context.Background()
 	err := util.WithReadFile(inputFile, func(r io.Reader) error {
-		schemaViolations, err := format.Validate(ctx, r)
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("Read Failed: %s", err)
+		}
+		schemaViolations, err := format.Validate(ctx, bytes.NewReader(b))
 		for i, violation := range schemaViolations {
 			fmt.Printf("%d - %s\n", i, violation)
 		}
@@ -788,6 +843,28 @@ func (app) IngestValidate(inputFile string, verbose bool) error {
 			// Unwrap the error since this gets printed as a user facing error message.
 			return fmt.Errorf("Validation Failed: %s", skerr.Unwrap(err))
 		}
+
+		f, err := format.Parse(bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("Parse Failed: %s", skerr.Unwrap(err))
+		}
+		res := format.Check(f)
+		for _, warning := range res.Warnings {
+			fmt.Printf("Warning - %s\n", warning)
+		}
+		for i, problem := range res.Problems {
+			fmt.Printf("%d - %s\n", i, problem)
+		}
+		fmt.Printf("Traces: %d\n", res.TraceCount)
+		fmt.Printf("Params:\n")
+		keys := res.ParamSet.Keys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %s: %d values\n", key, len(res.ParamSet[key]))
+		}
+		if len(res.Problems) > 0 {
+			return fmt.Errorf("Validation Failed: found %d problems", len(res.Problems))
+		}
 		return nil
 	})
 	if err != nil {
@@ -605,6 +605,162 @@ func (_c *Application_IngestValidate_Call) RunAndReturn(run func(inputFile strin
 	return _c
 }
 
+// SnapshotExport provides a mock function for the type Application
+func (_mock *Application) SnapshotExport(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, outputFile string, backupTo string, numTiles int) error {
+	ret := _mock.Called(local, store, instanceConfig, outputFile, backupTo, numTiles)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotExport")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(bool, tracestore.TraceStore, *config.InstanceConfig, string, string, int) error); ok {
+		r0 = returnFunc(local, store, instanceConfig, outputFile, backupTo, numTiles)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Application_SnapshotExport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SnapshotExport'
+type Application_SnapshotExport_Call struct {
+	*mock.Call
+}
+
+// SnapshotExport is a helper method to define mock.On call
+//   - local bool
+//   - store tracestore.TraceStore
+//   - instanceConfig *config.InstanceConfig
+//   - outputFile string
+//   - backupTo string
+//   - numTiles int
+func (_e *Application_Expecter) SnapshotExport(local interface{}, store interface{}, instanceConfig interface{}, outputFile interface{}, backupTo interface{}, numTiles interface{}) *Application_SnapshotExport_Call {
+	return &Application_SnapshotExport_Call{Call: _e.mock.On("SnapshotExport", local, store, instanceConfig, outputFile, backupTo, numTiles)}
+}
+
+func (_c *Application_SnapshotExport_Call) Run(run func(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, outputFile string, backupTo string, numTiles int)) *Application_SnapshotExport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 bool
+		if args[0] != nil {
+			arg0 = args[0].(bool)
+		}
+		var arg1 tracestore.TraceStore
+		if args[1] != nil {
+			arg1 = args[1].(tracestore.TraceStore)
+		}
+		var arg2 *config.InstanceConfig
+		if args[2] != nil {
+			arg2 = args[2].(*config.InstanceConfig)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 string
+		if args[4] != nil {
+			arg4 = args[4].(string)
+		}
+		var arg5 int
+		if args[5] != nil {
+			arg5 = args[5].(int)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+			arg5,
+		)
+	})
+	return _c
+}
+
+func (_c *Application_SnapshotExport_Call) Return(err error) *Application_SnapshotExport_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Application_SnapshotExport_Call) RunAndReturn(run func(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, outputFile string, backupTo string, numTiles int) error) *Application_SnapshotExport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SnapshotImport provides a mock function for the type Application
+func (_mock *Application) SnapshotImport(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, inputFile string, rewrites []string) error {
+	ret := _mock.Called(local, store, instanceConfig, inputFile, rewrites)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SnapshotImport")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(bool, tracestore.TraceStore, *config.InstanceConfig, string, []string) error); ok {
+		r0 = returnFunc(local, store, instanceConfig, inputFile, rewrites)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// Application_SnapshotImport_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SnapshotImport'
+type Application_SnapshotImport_Call struct {
+	*mock.Call
+}
+
+// SnapshotImport is a helper method to define mock.On call
+//   - local bool
+//   - store tracestore.TraceStore
+//   - instanceConfig *config.InstanceConfig
+//   - inputFile string
+//   - rewrites []string
+func (_e *Application_Expecter) SnapshotImport(local interface{}, store interface{}, instanceConfig interface{}, inputFile interface{}, rewrites interface{}) *Application_SnapshotImport_Call {
+	return &Application_SnapshotImport_Call{Call: _e.mock.On("SnapshotImport", local, store, instanceConfig, inputFile, rewrites)}
+}
+
+func (_c *Application_SnapshotImport_Call) Run(run func(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, inputFile string, rewrites []string)) *Application_SnapshotImport_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 bool
+		if args[0] != nil {
+			arg0 = args[0].(bool)
+		}
+		var arg1 tracestore.TraceStore
+		if args[1] != nil {
+			arg1 = args[1].(tracestore.TraceStore)
+		}
+		var arg2 *config.InstanceConfig
+		if args[2] != nil {
+			arg2 = args[2].(*config.InstanceConfig)
+		}
+		var arg3 string
+		if args[3] != nil {
+			arg3 = args[3].(string)
+		}
+		var arg4 []string
+		if args[4] != nil {
+			arg4 = args[4].([]string)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+			arg3,
+			arg4,
+		)
+	})
+	return _c
+}
+
+func (_c *Application_SnapshotImport_Call) Return(err error) *Application_SnapshotImport_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *Application_SnapshotImport_Call) RunAndReturn(run func(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, inputFile string, rewrites []string) error) *Application_SnapshotImport_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // TilesLast provides a mock function for the type Application
 func (_mock *Application) TilesLast(store tracestore.TraceStore) error {
 	ret := _mock.Called(store)
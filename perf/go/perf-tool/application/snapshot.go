@@ -0,0 +1,249 @@
+package application
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/util"
+	"go.goldmine.build/perf/go/alerts"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/regression"
+	"go.goldmine.build/perf/go/shortcut"
+	"go.goldmine.build/perf/go/tracestore"
+	"go.goldmine.build/perf/go/types"
+)
+
+// backupFilenameMetadata is the filename of the snapshotMetadata inside a
+// snapshot .zip file. The rest of the snapshot uses the same files as the
+// backups.
+const backupFilenameMetadata = "metadata"
+
+// rewriteSeparator separates the old and new values in a rewrite rule.
+//
+// We can't use '=' since it appears in trace ids.
+const rewriteSeparator = "=>"
+
+// tileMetadata describes a single tile of the instance a snapshot was taken
+// from.
+type tileMetadata struct {
+	TileNumber types.TileNumber
+	TraceCount int64
+	ParamSet   paramtools.ReadOnlyParamSet
+}
+
+// snapshotMetadata describes the instance a snapshot was taken from.
+type snapshotMetadata struct {
+	URL          string
+	Created      time.Time
+	BackupToDate time.Time
+	Tiles        []tileMetadata
+}
+
+// rewrite replaces every occurrence of Old with New.
+type rewrite struct {
+	Old string
+	New string
+}
+
+// rewriter applies rewrites to the data in a snapshot as it is imported, so
+// for example, alerts and regressions from a production instance can be moved
+// into the namespace of a staging instance. A nil *rewriter leaves everything
+// unchanged.
+type rewriter struct {
+	rewrites []rewrite
+}
+
+// newRewriter returns a *rewriter from rules of the form "old=>new". It
+// returns nil if there are no rules.
+func newRewriter(rules []string) (*rewriter, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	ret := &rewriter{}
+	for _, rule := range rules {
+		oldValue, newValue, ok := strings.Cut(rule, rewriteSeparator)
+		if !ok || oldValue == "" {
+			return nil, skerr.Fmt("Invalid rewrite %q, must be of the form old%snew.", rule, rewriteSeparator)
+		}
+		ret.rewrites = append(ret.rewrites, rewrite{Old: oldValue, New: newValue})
+	}
+	return ret, nil
+}
+
+// rewriteString applies all the rewrites, in order, to s.
+func (r *rewriter) rewriteString(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rw := range r.rewrites {
+		s = strings.ReplaceAll(s, rw.Old, rw.New)
+	}
+	return s
+}
+
+// rewriteAlert applies the rewrites to the name, query and notification
+// addresses of the alert.
+func (r *rewriter) rewriteAlert(alert *alerts.Alert) {
+	if r == nil {
+		return
+	}
+	alert.DisplayName = r.rewriteString(alert.DisplayName)
+	alert.Query = r.rewriteString(alert.Query)
+	alert.Alert = r.rewriteString(alert.Alert)
+	alert.Owner = r.rewriteString(alert.Owner)
+}
+
+// rewriteClusterSummary applies the rewrites to the trace ids of the cluster
+// and updates the shortcut to match.
+func (r *rewriter) rewriteClusterSummary(cl *clustering2.ClusterSummary) {
+	if r == nil || cl == nil {
+		return
+	}
+	for i, key := range cl.Keys {
+		cl.Keys[i] = r.rewriteString(key)
+	}
+	for i, vp := range cl.ParamSummaries {
+		cl.ParamSummaries[i].Value = r.rewriteString(vp.Value)
+	}
+	if cl.Shortcut != "" {
+		cl.Shortcut = shortcut.IDFromKeys(&shortcut.Shortcut{Keys: cl.Keys})
+	}
+}
+
+// rewriteRegression applies the rewrites to the trace ids in the regression.
+func (r *rewriter) rewriteRegression(reg *regression.Regression) {
+	if r == nil || reg == nil {
+		return
+	}
+	r.rewriteClusterSummary(reg.Low)
+	r.rewriteClusterSummary(reg.High)
+	if reg.Frame == nil || reg.Frame.DataFrame == nil {
+		return
+	}
+	df := reg.Frame.DataFrame
+	traceSet := make(types.TraceSet, len(df.TraceSet))
+	for key, trace := range df.TraceSet {
+		traceSet[r.rewriteString(key)] = trace
+	}
+	df.TraceSet = traceSet
+	df.BuildParamSet()
+}
+
+// SnapshotExport writes a snapshot of the instance, i.e. the alerts,
+// the regressions found since backupTo along with their shortcuts, and a
+// description of the most recent numTiles tiles, to outputFile.
+func (app) SnapshotExport(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, outputFile, backupTo string, numTiles int) error {
+	ctx := context.Background()
+
+	backupToDate, err := parseBackupToDate(backupTo)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	metadata := snapshotMetadata{
+		URL:          instanceConfig.URL,
+		Created:      time.Now(),
+		BackupToDate: backupToDate,
+	}
+	latestTileNumber, err := store.GetLatestTile(ctx)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	for tileNumber := latestTileNumber; tileNumber > latestTileNumber-types.TileNumber(numTiles) && tileNumber >= 0; tileNumber-- {
+		count, err := store.TraceCount(ctx, tileNumber)
+		if err != nil {
+			return skerr.Wrapf(err, "failed to count traces for tile %d", tileNumber)
+		}
+		ps, err := store.GetParamSet(ctx, tileNumber)
+		if err != nil {
+			return skerr.Wrapf(err, "failed to load the paramset for tile %d", tileNumber)
+		}
+		metadata.Tiles = append(metadata.Tiles, tileMetadata{
+			TileNumber: tileNumber,
+			TraceCount: count,
+			ParamSet:   ps,
+		})
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer util.Close(f)
+	z := zip.NewWriter(f)
+
+	metadataZipWriter, err := z.Create(backupFilenameMetadata)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	if err := gob.NewEncoder(metadataZipWriter).Encode(metadata); err != nil {
+		return skerr.Wrap(err)
+	}
+	if err := backupAlerts(ctx, z, local, instanceConfig); err != nil {
+		return skerr.Wrap(err)
+	}
+	fmt.Printf("Backing up regressions from %v\n", backupToDate)
+	if err := backupRegressions(ctx, z, local, instanceConfig, backupToDate); err != nil {
+		return skerr.Wrap(err)
+	}
+	if err := z.Close(); err != nil {
+		return skerr.Wrap(err)
+	}
+
+	return nil
+}
+
+// SnapshotImport imports a snapshot written by SnapshotExport
+// into the database, applying the given rewrites, of the form "old=>new", to
+// the alerts and regressions.
+//
+// The tiles in the snapshot are compared to the tiles of this instance, since
+// the trace data itself isn't part of the snapshot and needs to be ingested
+// separately.
+func (app) SnapshotImport(local bool, store tracestore.TraceStore, instanceConfig *config.InstanceConfig, inputFile string, rewrites []string) error {
+	ctx := context.Background()
+
+	rw, err := newRewriter(rewrites)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+
+	z, err := zip.OpenReader(inputFile)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	defer util.Close(z)
+
+	metadataZipReader, err := findFileInZip(backupFilenameMetadata, z)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	var metadata snapshotMetadata
+	if err := gob.NewDecoder(metadataZipReader).Decode(&metadata); err != nil {
+		return skerr.Wrap(err)
+	}
+	fmt.Printf("Importing snapshot of %s taken at %v, with regressions from %v.\n", metadata.URL, metadata.Created, metadata.BackupToDate)
+	fmt.Println("tile\tnum traces\tnum traces in snapshot")
+	for _, tile := range metadata.Tiles {
+		count, err := store.TraceCount(ctx, tile.TileNumber)
+		if err != nil {
+			return skerr.Wrapf(err, "failed to count traces for tile %d", tile.TileNumber)
+		}
+		fmt.Printf("%d\t%d\t%d\n", tile.TileNumber, count, tile.TraceCount)
+	}
+
+	if err := restoreAlerts(ctx, z, local, instanceConfig, rw); err != nil {
+		return skerr.Wrap(err)
+	}
+	if err := restoreRegressions(ctx, z, local, instanceConfig, rw); err != nil {
+		return skerr.Wrap(err)
+	}
+	return nil
+}
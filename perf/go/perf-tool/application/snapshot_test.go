@@ -0,0 +1,72 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/perf/go/alerts"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/regression"
+	"go.goldmine.build/perf/go/shortcut"
+	"go.goldmine.build/perf/go/types"
+	"go.goldmine.build/perf/go/ui/frame"
+)
+
+func TestNewRewriter_NoRules_ReturnsNilWhichLeavesValuesUnchanged(t *testing.T) {
+	rw, err := newRewriter(nil)
+	require.NoError(t, err)
+	assert.Nil(t, rw)
+	assert.Equal(t, ",arch=x86,", rw.rewriteString(",arch=x86,"))
+}
+
+func TestNewRewriter_InvalidRule_ReturnsError(t *testing.T) {
+	_, err := newRewriter([]string{"arch=x86"})
+	require.Error(t, err)
+
+	_, err = newRewriter([]string{"=>x86"})
+	require.Error(t, err)
+}
+
+func TestRewriter_RewriteAlert_Success(t *testing.T) {
+	rw, err := newRewriter([]string{"ChromiumPerf=>ChromiumPerfStaging", "@example.com=>@example.org"})
+	require.NoError(t, err)
+	alert := alerts.NewConfig()
+	alert.DisplayName = "ChromiumPerf speedometer"
+	alert.Query = "master=ChromiumPerf&bot=linux"
+	alert.Alert = "perf@example.com"
+	rw.rewriteAlert(alert)
+
+	assert.Equal(t, "ChromiumPerfStaging speedometer", alert.DisplayName)
+	assert.Equal(t, "master=ChromiumPerfStaging&bot=linux", alert.Query)
+	assert.Equal(t, "perf@example.org", alert.Alert)
+}
+
+func TestRewriter_RewriteRegression_TraceIDsAndShortcutUpdated(t *testing.T) {
+	rw, err := newRewriter([]string{",master=ChromiumPerf,=>,master=Staging,"})
+	require.NoError(t, err)
+
+	df := dataframe.NewEmpty()
+	df.TraceSet[",arch=x86,master=ChromiumPerf,"] = types.Trace{1, 2}
+	df.BuildParamSet()
+	reg := &regression.Regression{
+		High: &clustering2.ClusterSummary{
+			Keys:     []string{",arch=x86,master=ChromiumPerf,"},
+			Shortcut: "X",
+			ParamSummaries: []clustering2.ValuePercent{
+				{Value: "master=ChromiumPerf", Percent: 100},
+			},
+		},
+		Frame: &frame.FrameResponse{DataFrame: df},
+	}
+	rw.rewriteRegression(reg)
+
+	assert.Equal(t, []string{",arch=x86,master=Staging,"}, reg.High.Keys)
+	assert.Equal(t, shortcut.IDFromKeys(&shortcut.Shortcut{Keys: []string{",arch=x86,master=Staging,"}}), reg.High.Shortcut)
+	// Only whole trace ids are rewritten by this rule.
+	assert.Equal(t, "master=ChromiumPerf", reg.High.ParamSummaries[0].Value)
+	assert.Equal(t, types.TraceSet{",arch=x86,master=Staging,": {1, 2}}, df.TraceSet)
+	assert.Equal(t, []string{"Staging"}, df.ParamSet["master"])
+	assert.Nil(t, reg.Low)
+}
@@ -34,6 +34,7 @@ const (
 	numTilesListFlagName     = "num"
 	outputFilenameFlagName   = "out"
 	queryFlagName            = "query"
+	rewriteFlagName          = "rewrite"
 	startTimeFlagName        = "start"
 	stopTimeFlagName         = "stop"
 	tileNumberFlagName       = "tile"
@@ -103,6 +104,11 @@ var queryFlag = &cli.StringFlag{
 	Required: true,
 }
 
+var rewriteFlag = &cli.StringSliceFlag{
+	Name:  rewriteFlagName,
+	Usage: "A rewrite of the form 'old=>new' to apply to the alerts and regressions being imported, e.g. 'ChromiumPerf=>ChromiumPerfStaging'. May be repeated.",
+}
+
 var tileNumberFlag = &cli.Int64Flag{
 	Name:  tileNumberFlagName,
 	Value: int64(types.BadTileNumber),
@@ -116,6 +122,12 @@ var numTilesListFlag = &cli.IntFlag{
 	EnvVars: []string{"PERF_CONFIG_FILENAME"},
 }
 
+var snapshotNumTilesFlag = &cli.IntFlag{
+	Name:  numTilesListFlagName,
+	Value: 10,
+	Usage: "The number of recent tiles to describe in the snapshot.",
+}
+
 var trybotNumCommitsFlag = &cli.IntFlag{
 	Name:  trybotNumCommitsFlagName,
 	Value: 5,
@@ -373,7 +385,7 @@ func actualMain(app application.Application) {
 					},
 					{
 						Name:        "validate",
-						Description: "Validate an ingestion file",
+						Description: "Validate an ingestion file and check it for duplicate points and params that will be altered during ingestion.",
 						Flags: []cli.Flag{
 							inputFilenameFlag,
 							verboseFlag,
@@ -515,6 +527,72 @@ using the same input file for both restores.
 					},
 				},
 			},
+			{
+				Name: "snapshot",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "export",
+						Usage: "Writes a snapshot of the instance, e.g. to refresh a staging instance from production.",
+						Description: `Writes a snapshot of the alerts, the regressions found since --backup_to_date
+and the shortcuts they use, along with a description of the last --num
+tiles, to a single file that can be imported with:
+
+    'perf-tool snapshot import'
+
+Trace data is not part of the snapshot.
+`,
+						Flags: []cli.Flag{
+							localFlag,
+							configFilenameFlag,
+							connectionStringFlag,
+							requiredOutputFilenameFlag,
+							backupToDateFlag,
+							snapshotNumTilesFlag,
+						},
+						Action: func(c *cli.Context) error {
+							instanceConfig, err := instanceConfigFromFlags(c)
+							if err != nil {
+								return skerr.Wrap(err)
+							}
+							store, err := getStore(c)
+							if err != nil {
+								return skerr.Wrap(err)
+							}
+							return app.SnapshotExport(c.Bool(localFlagName), store, instanceConfig, c.String(outputFilenameFlagName), c.String(backupToDateFlagName), c.Int(numTilesListFlagName))
+						},
+					},
+					{
+						Name:  "import",
+						Usage: "Imports a snapshot written by 'perf-tool snapshot export'.",
+						Description: `Imports the alerts and regressions in the snapshot, applying each --rewrite
+to the alert names, queries and notification addresses, and to the trace ids
+of the regressions, so the data can be moved into the namespace of this
+instance.
+
+The tiles in the snapshot are compared to the tiles of this instance so you
+can check that comparable trace data has been ingested.
+`,
+						Flags: []cli.Flag{
+							localFlag,
+							configFilenameFlag,
+							connectionStringFlag,
+							inputFilenameFlag,
+							rewriteFlag,
+						},
+						Action: func(c *cli.Context) error {
+							instanceConfig, err := instanceConfigFromFlags(c)
+							if err != nil {
+								return skerr.Wrap(err)
+							}
+							store, err := getStore(c)
+							if err != nil {
+								return skerr.Wrap(err)
+							}
+							return app.SnapshotImport(c.Bool(localFlagName), store, instanceConfig, c.String(inputFilenameFlagName), c.StringSlice(rewriteFlagName))
+						},
+					},
+				},
+			},
 			{
 				Name: "trybot",
 				Subcommands: []*cli.Command{
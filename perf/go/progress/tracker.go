@@ -20,7 +20,9 @@ import (
 // It will cache Progresses for a time after they complete.
 type Tracker interface {
 	// Add a Progress to the tracker. This will update the URL of the Progress.
-	Add(prog Progress)
+	// Returns the id the Progress is tracked under, which is the last part
+	// of the URL.
+	Add(prog Progress) string
 
 	// Handler for HTTP requests for Progress updates.
 	Handler(w http.ResponseWriter, r *http.Request)
@@ -142,14 +144,15 @@ func (t *tracker) singleStep(ctx context.Context) {
 	t.numEntriesInCache.Update(int64(len(t.cache.Keys())))
 }
 
-// Handler implements Tracker.
-func (t *tracker) Add(prog Progress) {
+// Add implements Tracker.
+func (t *tracker) Add(prog Progress) string {
 	id := uuid.Must(uuid.NewRandom()).String()
 	prog.URL(t.basePath + id)
 	t.cache.Add(id, &cacheEntry{
 		prog,
 		time.Time{},
 	})
+	return id
 }
 
 // Handler implements Tracker.
@@ -41,6 +41,16 @@ func TestTracker_Add_ProgressAppearsInCacheAndMetrics(t *testing.T) {
 	assert.Equal(t, int64(1), tr.numEntriesInCache.Get())
 }
 
+func TestTracker_Add_ReturnsIDUsedInURL(t *testing.T) {
+
+	tr, err := NewTracker("/foo/")
+	require.NoError(t, err)
+	p := New()
+	id := tr.Add(p)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, "/foo/"+id, p.state.URL)
+}
+
 func TestTracker_ProgressIsFinished_ProgressStillAppearsInCacheAndMetrics(t *testing.T) {
 
 	tr, p := setup(t)
@@ -12,6 +12,7 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"go.goldmine.build/go/ctxutil"
+	"go.goldmine.build/go/git"
 	"go.goldmine.build/go/metrics2"
 	"go.goldmine.build/go/paramtools"
 	"go.goldmine.build/go/pubsub/sub"
@@ -21,12 +22,14 @@ import (
 	"go.goldmine.build/perf/go/alerts"
 	"go.goldmine.build/perf/go/config"
 	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/derivedmetrics"
 	perfgit "go.goldmine.build/perf/go/git"
 	"go.goldmine.build/perf/go/ingestevents"
 	"go.goldmine.build/perf/go/notify"
 	"go.goldmine.build/perf/go/regression"
 	"go.goldmine.build/perf/go/shortcut"
 	"go.goldmine.build/perf/go/stepfit"
+	"go.goldmine.build/perf/go/tracestore"
 	"go.goldmine.build/perf/go/types"
 )
 
@@ -52,6 +55,8 @@ type Continuous struct {
 	notifier       notify.Notifier
 	paramsProvider regression.ParamsetProvider
 	dfBuilder      dataframe.DataFrameBuilder
+	traceStore     tracestore.TraceStore
+	derivedMetrics derivedmetrics.Store
 	pollingDelay   time.Duration
 	instanceConfig *config.InstanceConfig
 	flags          *config.FrontendFlags
@@ -73,6 +78,8 @@ func New(
 	notifier notify.Notifier,
 	paramsProvider regression.ParamsetProvider,
 	dfBuilder dataframe.DataFrameBuilder,
+	traceStore tracestore.TraceStore,
+	derivedMetrics derivedmetrics.Store,
 	instanceConfig *config.InstanceConfig,
 	flags *config.FrontendFlags) *Continuous {
 	return &Continuous{
@@ -84,6 +91,8 @@ func New(
 		current:        &alerts.Alert{},
 		paramsProvider: paramsProvider,
 		dfBuilder:      dfBuilder,
+		traceStore:     traceStore,
+		derivedMetrics: derivedMetrics,
 		pollingDelay:   pollingClusteringDelay,
 		instanceConfig: instanceConfig,
 		flags:          flags,
@@ -205,6 +214,97 @@ func (c *Continuous) getPubSubSubscription() (*pubsub.Subscription, error) {
 	return sub.New(ctx, c.flags.Local, c.instanceConfig.IngestionConfig.SourceConfig.Project, c.instanceConfig.IngestionConfig.FileIngestionTopicName, maxParallelReceives)
 }
 
+// getCommitPubSubSubscription returns a pubsub.Subscription to commit landing
+// events or an error if the subscription can't be established.
+func (c *Continuous) getCommitPubSubSubscription() (*pubsub.Subscription, error) {
+	if c.instanceConfig.IngestionConfig.CommitPubSubTopicName == "" {
+		return nil, skerr.Fmt("Commit subscription name isn't set.")
+	}
+
+	ctx := context.Background()
+	return sub.New(ctx, c.flags.Local, c.instanceConfig.IngestionConfig.SourceConfig.Project, c.instanceConfig.IngestionConfig.CommitPubSubTopicName, maxParallelReceives)
+}
+
+// traceIDsFromCommitEvent returns all the trace ids that have data at the
+// commit(s) described in the commit landing PubSub message.
+//
+// The message body is the URL of the repo, and events for other repos than the
+// one this instance follows are ignored. The message attributes are a map of
+// branch name to git hash. If the instance follows a specific branch then only
+// that branch is considered.
+func (c *Continuous) traceIDsFromCommitEvent(ctx context.Context, msg *pubsub.Message) ([]string, error) {
+	if !c.isThisRepo(string(msg.Data)) {
+		sklog.Infof("Ignoring commit event for another repo: %q", string(msg.Data))
+		return []string{}, nil
+	}
+	hashes := []string{}
+	if branch := c.instanceConfig.GitRepoConfig.Branch; branch != "" {
+		if hash, ok := msg.Attributes[branch]; ok {
+			hashes = append(hashes, hash)
+		}
+	} else {
+		for _, hash := range msg.Attributes {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return []string{}, nil
+	}
+
+	ret := []string{}
+	found := false
+	for _, hash := range hashes {
+		commitNumber, err := c.perfGit.CommitNumberFromGitHash(ctx, hash)
+		if err != nil {
+			sklog.Warningf("Commit %q not found: %s", hash, err)
+			continue
+		}
+		found = true
+		traceIDs, err := c.traceStore.GetTraceIDsAtCommit(ctx, commitNumber)
+		if err != nil {
+			return nil, skerr.Wrapf(err, "Failed to load trace ids for commit %d", commitNumber)
+		}
+		ret = append(ret, traceIDs...)
+	}
+	if !found {
+		// The commit probably hasn't been picked up by perfGit yet.
+		return nil, skerr.Fmt("None of the commits %q are known yet.", hashes)
+	}
+	return ret, nil
+}
+
+// isThisRepo returns true if repoURL refers to the repo this instance follows,
+// allowing for small variations in the URL, see git.NormalizeURL.
+func (c *Continuous) isThisRepo(repoURL string) bool {
+	configured := c.instanceConfig.GitRepoConfig.URL
+	if repoURL == configured {
+		return true
+	}
+	normalized, err := git.NormalizeURL(repoURL)
+	if err != nil {
+		return false
+	}
+	normalizedConfigured, err := git.NormalizeURL(configured)
+	if err != nil {
+		return false
+	}
+	return normalized == normalizedConfigured
+}
+
+// paramSetFromTraceIDs returns the ParamSet for all the given trace ids.
+func paramSetFromTraceIDs(traceIDs []string) paramtools.ReadOnlyParamSet {
+	ps := paramtools.NewParamSet()
+	for _, traceID := range traceIDs {
+		p, err := query.ParseKey(traceID)
+		if err != nil {
+			continue
+		}
+		ps.AddParams(p)
+	}
+	ps.Normalize()
+	return ps.Freeze()
+}
+
 func (c *Continuous) callProvider(ctx context.Context) ([]*alerts.Alert, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, config.QueryMaxRunTime)
 	defer cancel()
@@ -219,8 +319,24 @@ func (c *Continuous) buildConfigAndParamsetChannel(ctx context.Context) <-chan c
 	ret := make(chan configsAndParamSet)
 
 	if c.flags.EventDrivenRegressionDetection {
+		started := false
+		if c.instanceConfig.IngestionConfig.CommitPubSubTopicName != "" {
+			commitSub, err := c.getCommitPubSubSubscription()
+			if err != nil {
+				sklog.Errorf("Failed to create commit pubsub subscription: %s", err)
+			} else {
+				go c.receiveCommitEvents(ctx, commitSub, ret)
+				started = true
+				sklog.Info("Started commit driven clustering.")
+			}
+		}
+
 		sub, err := c.getPubSubSubscription()
 		if err != nil {
+			if started {
+				sklog.Errorf("Failed to create pubsub subscription, only doing commit driven regression detection: %s", err)
+				return ret
+			}
 			sklog.Errorf("Failed to create pubsub subscription, not doing event driven regression detection: %s", err)
 			// Just fall through and look for regressions over all the Alerts continuously.
 		} else {
@@ -328,6 +444,62 @@ func (c *Continuous) buildConfigAndParamsetChannel(ctx context.Context) <-chan c
 	return ret
 }
 
+// receiveCommitEvents listens for commit landing PubSub events and emits the
+// configs that match the traces that have data at that commit.
+func (c *Continuous) receiveCommitEvents(ctx context.Context, commitSub *pubsub.Subscription, ret chan<- configsAndParamSet) {
+	// commitNackCounter is the number of commit events we weren't able to process.
+	commitNackCounter := metrics2.GetCounter("perf_clustering_commit_event_nack", nil)
+	// commitAckCounter is the number of commit events we were able to process.
+	commitAckCounter := metrics2.GetCounter("perf_clustering_commit_event_ack", nil)
+	for {
+		if err := ctx.Err(); err != nil {
+			sklog.Info("Channel context error %s", err)
+			return
+		}
+		err := commitSub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+			sklog.Infof("Received incoming commit event for %q.", string(msg.Data))
+			success := false
+			defer func() {
+				if success {
+					commitAckCounter.Inc(1)
+					msg.Ack()
+				} else {
+					commitNackCounter.Inc(1)
+					msg.Nack()
+				}
+			}()
+
+			traceIDs, err := c.traceIDsFromCommitEvent(ctx, msg)
+			if err != nil {
+				sklog.Errorf("Failed to find traces for commit event: %s", err)
+				return
+			}
+			if len(traceIDs) == 0 {
+				success = true
+				return
+			}
+
+			configs, err := c.callProvider(ctx)
+			if err != nil {
+				sklog.Errorf("Failed to get list of configs: %s", err)
+				return
+			}
+
+			matchingConfigs := matchingConfigsFromTraceIDs(traceIDs, configs)
+			if len(matchingConfigs) > 0 {
+				ret <- configsAndParamSet{
+					configs:  matchingConfigs,
+					paramset: paramSetFromTraceIDs(traceIDs),
+				}
+			}
+			success = true
+		})
+		if err != nil {
+			sklog.Errorf("Failed receiving commit pubsub message: %s", err)
+		}
+	}
+}
+
 // matchingConfigsFromTraceIDs returns a slice of Alerts that match at least one
 // trace from the given traceIDs slice.
 //
@@ -470,6 +642,14 @@ func (c *Continuous) ProcessAlertConfig(ctx context.Context, cfg *alerts.Alert)
 	req := regression.NewRegressionDetectionRequest()
 	req.Alert = cfg
 	req.Domain = domain
+	if cfg.DerivedMetric != "" {
+		formulas, err := derivedmetrics.Formulas(ctx, c.derivedMetrics)
+		if err != nil {
+			sklog.Warningf("Failed regression detection: Alert %q: %s", cfg.DisplayName, err)
+			return
+		}
+		req.SetDerivedMetrics(formulas)
+	}
 
 	expandBaseRequest := regression.ExpandBaseAlertByGroupBy
 	if c.flags.EventDrivenRegressionDetection {
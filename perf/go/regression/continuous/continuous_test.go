@@ -2,10 +2,12 @@ package continuous
 
 import (
 	"context"
+	"errors"
 	"net/url"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/pubsub"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.goldmine.build/go/git/provider"
@@ -23,6 +25,7 @@ import (
 	regressionmocks "go.goldmine.build/perf/go/regression/mocks"
 	shortcutmocks "go.goldmine.build/perf/go/shortcut/mocks"
 	"go.goldmine.build/perf/go/stepfit"
+	tracestoremocks "go.goldmine.build/perf/go/tracestore/mocks"
 	"go.goldmine.build/perf/go/types"
 	"go.goldmine.build/perf/go/ui/frame"
 )
@@ -200,6 +203,102 @@ func createArgsForReportRegressions(t *testing.T) (*Continuous, *regression.Regr
 
 }
 
+func TestParamSetFromTraceIDs_InvalidTraceIDsAreSkipped(t *testing.T) {
+	ps := paramSetFromTraceIDs([]string{",arch=x86,config=8888,", ",arch=arm,config=8888,", "not-a-valid-trace-id"})
+	assert.Equal(t, paramtools.ReadOnlyParamSet{
+		"arch":   []string{"arm", "x86"},
+		"config": []string{"8888"},
+	}, ps)
+}
+
+func TestTraceIDsFromCommitEvent_BranchMatches_ReturnsTraceIDsAtCommit(t *testing.T) {
+	ctx := context.Background()
+	g := gitmocks.NewGit(t)
+	g.On("CommitNumberFromGitHash", testutils.AnyContext, "abcdef").Return(types.CommitNumber(12), nil)
+	ts := tracestoremocks.NewTraceStore(t)
+	ts.On("GetTraceIDsAtCommit", testutils.AnyContext, types.CommitNumber(12)).Return([]string{",arch=x86,config=8888,"}, nil)
+
+	c := Continuous{
+		perfGit:    g,
+		traceStore: ts,
+		instanceConfig: &config.InstanceConfig{
+			GitRepoConfig: config.GitRepoConfig{
+				URL:    "https://example.com/repo.git",
+				Branch: "main",
+			},
+		},
+	}
+	traceIDs, err := c.traceIDsFromCommitEvent(ctx, &pubsub.Message{
+		Data: []byte("https://example.com/repo.git"),
+		Attributes: map[string]string{
+			"main":  "abcdef",
+			"other": "012345",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{",arch=x86,config=8888,"}, traceIDs)
+}
+
+func TestTraceIDsFromCommitEvent_BranchNotInEvent_ReturnsEmptySlice(t *testing.T) {
+	c := Continuous{
+		instanceConfig: &config.InstanceConfig{
+			GitRepoConfig: config.GitRepoConfig{
+				URL:    "https://example.com/repo.git",
+				Branch: "main",
+			},
+		},
+	}
+	traceIDs, err := c.traceIDsFromCommitEvent(context.Background(), &pubsub.Message{
+		Data: []byte("https://example.com/repo.git"),
+		Attributes: map[string]string{
+			"other": "012345",
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traceIDs)
+}
+
+func TestTraceIDsFromCommitEvent_CommitNotKnownYet_ReturnsError(t *testing.T) {
+	g := gitmocks.NewGit(t)
+	g.On("CommitNumberFromGitHash", testutils.AnyContext, "abcdef").Return(types.BadCommitNumber, errors.New("not found"))
+
+	c := Continuous{
+		perfGit: g,
+		instanceConfig: &config.InstanceConfig{
+			GitRepoConfig: config.GitRepoConfig{
+				URL:    "https://example.com/repo.git",
+				Branch: "main",
+			},
+		},
+	}
+	_, err := c.traceIDsFromCommitEvent(context.Background(), &pubsub.Message{
+		Data: []byte("https://example.com/repo"),
+		Attributes: map[string]string{
+			"main": "abcdef",
+		},
+	})
+	require.Error(t, err)
+}
+
+func TestTraceIDsFromCommitEvent_OtherRepo_ReturnsEmptySlice(t *testing.T) {
+	c := Continuous{
+		instanceConfig: &config.InstanceConfig{
+			GitRepoConfig: config.GitRepoConfig{
+				URL:    "https://example.com/repo.git",
+				Branch: "main",
+			},
+		},
+	}
+	traceIDs, err := c.traceIDsFromCommitEvent(context.Background(), &pubsub.Message{
+		Data: []byte("https://example.com/other-repo.git"),
+		Attributes: map[string]string{
+			"main": "abcdef",
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, traceIDs)
+}
+
 func TestReportRegressions_EmptyRegressionDetectionResponse_NoRegressionsReported(t *testing.T) {
 	c, req, resp, cfg, _ := createArgsForReportRegressions(t)
 	// We know this works since we didn't need to supply any implementations for any of the mocks.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"go.goldmine.build/go/metrics2"
@@ -15,6 +16,7 @@ import (
 	"go.goldmine.build/perf/go/clustering2"
 	"go.goldmine.build/perf/go/config"
 	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/derivedmetrics"
 	"go.goldmine.build/perf/go/dfiter"
 	perfgit "go.goldmine.build/perf/go/git"
 	"go.goldmine.build/perf/go/progress"
@@ -47,6 +49,10 @@ const (
 
 	// maxK is the largest K used for clustering.
 	maxK = 200
+
+	// maxSkippedReasons is the number of the most recent reasons for skipping
+	// a commit that are reported in the Progress.
+	maxSkippedReasons = 10
 )
 
 // DetectorResponseProcessor is a callback that is called with RegressionDetectionResponses as a RegressionDetectionRequest is being processed.
@@ -74,6 +80,10 @@ type RegressionDetectionRequest struct {
 
 	// Progress of the detection request.
 	Progress progress.Progress `json:"-"`
+
+	// derivedMetrics are the formulas of the derived metrics, keyed by name,
+	// that are available to an Alert with a DerivedMetric.
+	derivedMetrics map[string]string
 }
 
 // Query returns the query that the RegressionDetectionRequest process is
@@ -96,6 +106,13 @@ func (r *RegressionDetectionRequest) SetQuery(q string) {
 	r.query = q
 }
 
+// SetDerivedMetrics sets the formulas of the derived metrics, keyed by name,
+// which must include the DerivedMetric of the Alert, if any, and every derived
+// metric it refers to.
+func (r *RegressionDetectionRequest) SetDerivedMetrics(formulas map[string]string) {
+	r.derivedMetrics = formulas
+}
+
 // NewRegressionDetectionRequest returns a new RegressionDetectionRequest.
 func NewRegressionDetectionRequest() *RegressionDetectionRequest {
 	return &RegressionDetectionRequest{
@@ -117,6 +134,9 @@ type regressionDetectionProcess struct {
 	iter                      dfiter.DataFrameIterator
 	detectorResponseProcessor DetectorResponseProcessor
 	shortcutStore             shortcut.Store
+
+	// skipped are the reasons commits were skipped because of incomplete data.
+	skipped []string
 }
 
 // BaseAlertHandling determines how Alerts should be handled by ProcessRegressions.
@@ -178,7 +198,15 @@ func ProcessRegressions(ctx context.Context,
 			req.Progress.Message("Iteration", msg)
 		}
 
-		iter, err := dfiter.NewDataFrameIterator(timeoutContext, req.Progress, dfBuilder, perfGit, iterErrorCallback, req.Query(), req.Domain, req.Alert, anomalyConfig)
+		var iter dfiter.DataFrameIterator
+		var err error
+		if req.Alert.DerivedMetric != "" {
+			formula := derivedmetrics.Reference(req.Alert.DerivedMetric)
+			req.Progress.Message("Formula", formula)
+			iter, err = dfiter.NewDataFrameIteratorFromFormula(timeoutContext, req.Progress, dfBuilder, perfGit, iterErrorCallback, formula, req.derivedMetrics, req.Domain, req.Alert, anomalyConfig)
+		} else {
+			iter, err = dfiter.NewDataFrameIterator(timeoutContext, req.Progress, dfBuilder, perfGit, iterErrorCallback, req.Query(), req.Domain, req.Alert, anomalyConfig)
+		}
 		if err != nil {
 			if iteration == ContinueOnError {
 				// Don't log if we just didn't get enough data.
@@ -278,6 +306,50 @@ func tooMuchMissingData(tr types.Trace) bool {
 	return missing(tr[:n]) || missing(tr[len(tr)-n:])
 }
 
+// incompleteDataReason returns why the commit at the center of the DataFrame
+// should not be checked for regressions, or the empty string if it should be.
+//
+// A commit is skipped if fewer traces have data at that commit than required
+// by the MinTraces and MinDataFraction of the Alert, since steps found when
+// only part of the data has been uploaded are usually false positives.
+func incompleteDataReason(df *dataframe.DataFrame, alert *alerts.Alert) string {
+	if (alert.MinTraces <= 0 && alert.MinDataFraction <= 0) || len(df.Header) == 0 {
+		return ""
+	}
+	mid := len(df.Header) / 2
+	total := len(df.TraceSet)
+	withData := 0
+	for _, tr := range df.TraceSet {
+		if tr[mid] != vec32.MissingDataSentinel {
+			withData++
+		}
+	}
+	commitNumber := df.Header[mid].Offset
+	if withData < alert.MinTraces {
+		return fmt.Sprintf("Commit %d: %d of %d traces have data, need at least %d.", commitNumber, withData, total, alert.MinTraces)
+	}
+	if alert.MinDataFraction > 0 && (total == 0 || float32(withData) < alert.MinDataFraction*float32(total)) {
+		percent := 0.0
+		if total > 0 {
+			percent = 100 * float64(withData) / float64(total)
+		}
+		return fmt.Sprintf("Commit %d: %d of %d traces (%.0f%%) have data, need at least %.0f%%.", commitNumber, withData, total, percent, 100*alert.MinDataFraction)
+	}
+	return ""
+}
+
+// reportSkipped records in the Progress which commits were skipped because of
+// incomplete data, and why.
+func (p *regressionDetectionProcess) reportSkipped(reason string) {
+	sklog.Info(reason)
+	p.skipped = append(p.skipped, reason)
+	recent := p.skipped
+	if len(recent) > maxSkippedReasons {
+		recent = recent[len(recent)-maxSkippedReasons:]
+	}
+	p.request.Progress.Message("Skipped", fmt.Sprintf("%d commit(s) skipped because of incomplete data. %s", len(p.skipped), strings.Join(recent, " ")))
+}
+
 // shortcutFromKeys stores a new shortcut for each regression based on its Keys.
 func (p *regressionDetectionProcess) shortcutFromKeys(ctx context.Context, summary *clustering2.ClusterSummaries) error {
 	var err error
@@ -305,6 +377,10 @@ func (p *regressionDetectionProcess) run(ctx context.Context) error {
 		}
 		p.request.Progress.Message("Gathering", fmt.Sprintf("Next dataframe: %d traces", len(df.TraceSet)))
 		sklog.Infof("Next dataframe: %d traces", len(df.TraceSet))
+		if reason := incompleteDataReason(df, p.request.Alert); reason != "" {
+			p.reportSkipped(reason)
+			continue
+		}
 		before := len(df.TraceSet)
 		// Filter out Traces with insufficient data. I.e. we need 50% or more data
 		// on either side of the target commit.
@@ -3,6 +3,7 @@ package regression
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,7 @@ import (
 	"go.goldmine.build/go/vec32"
 	"go.goldmine.build/perf/go/alerts"
 	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/dataframe"
 	"go.goldmine.build/perf/go/dataframe/mocks"
 	"go.goldmine.build/perf/go/progress"
 	"go.goldmine.build/perf/go/types"
@@ -74,6 +76,50 @@ func TestTooMuchMissingData(t *testing.T) {
 	}
 }
 
+// dataFrameForCompletenessTest returns a DataFrame with three commits, where
+// the middle commit, 11, has data in withData of the total traces.
+func dataFrameForCompletenessTest(total, withData int) *dataframe.DataFrame {
+	df := dataframe.NewEmpty()
+	df.Header = []*dataframe.ColumnHeader{{Offset: 10}, {Offset: 11}, {Offset: 12}}
+	for i := 0; i < total; i++ {
+		tr := types.Trace{1, e, 1}
+		if i < withData {
+			tr[1] = 1
+		}
+		df.TraceSet[fmt.Sprintf(",arch=x%d,", i)] = tr
+	}
+	return df
+}
+
+func TestIncompleteDataReason_NoRequirements_ReturnsEmptyString(t *testing.T) {
+	assert.Empty(t, incompleteDataReason(dataFrameForCompletenessTest(10, 0), alerts.NewConfig()))
+}
+
+func TestIncompleteDataReason_RequirementsMet_ReturnsEmptyString(t *testing.T) {
+	alert := alerts.NewConfig()
+	alert.MinTraces = 5
+	alert.MinDataFraction = 0.5
+	assert.Empty(t, incompleteDataReason(dataFrameForCompletenessTest(10, 5), alert))
+}
+
+func TestIncompleteDataReason_TooFewTraces_ReturnsReason(t *testing.T) {
+	alert := alerts.NewConfig()
+	alert.MinTraces = 5
+	assert.Equal(t, "Commit 11: 4 of 10 traces have data, need at least 5.", incompleteDataReason(dataFrameForCompletenessTest(10, 4), alert))
+}
+
+func TestIncompleteDataReason_TooSmallFraction_ReturnsReason(t *testing.T) {
+	alert := alerts.NewConfig()
+	alert.MinDataFraction = 0.9
+	assert.Equal(t, "Commit 11: 8 of 10 traces (80%) have data, need at least 90%.", incompleteDataReason(dataFrameForCompletenessTest(10, 8), alert))
+}
+
+func TestIncompleteDataReason_NoTraces_ReturnsReason(t *testing.T) {
+	alert := alerts.NewConfig()
+	alert.MinDataFraction = 0.5
+	assert.Equal(t, "Commit 11: 0 of 0 traces (0%) have data, need at least 50%.", incompleteDataReason(dataFrameForCompletenessTest(0, 0), alert))
+}
+
 func TestProcessRegressions_BadQueryValue_ReturnsError(t *testing.T) {
 
 	alert := alerts.NewConfig() // A known query that will fail to parse.
@@ -0,0 +1,295 @@
+// Package fixcheck verifies that regressions which were triaged as fixed by a
+// commit actually recovered at that commit.
+//
+// Once enough data has arrived after the fixing commit, step detection is run
+// again on each trace of the regression, centered on the fixing commit. If the
+// majority of the traces step back in the opposite direction of the
+// regression then the fix is marked as verified, otherwise the regression is
+// reopened and the user who triaged it is notified.
+package fixcheck
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"time"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/go/sklog"
+	"go.goldmine.build/go/util"
+	"go.goldmine.build/go/vec32"
+	"go.goldmine.build/perf/go/alerts"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/dataframe"
+	perfgit "go.goldmine.build/perf/go/git"
+	"go.goldmine.build/perf/go/notify"
+	"go.goldmine.build/perf/go/progress"
+	"go.goldmine.build/perf/go/regression"
+	"go.goldmine.build/perf/go/shortcut"
+	"go.goldmine.build/perf/go/stepfit"
+	"go.goldmine.build/perf/go/types"
+)
+
+const (
+	// checkPeriod is how often to look for fixes to verify.
+	checkPeriod = time.Hour
+
+	// lookback is how far back to look for regressions that were triaged as
+	// fixed.
+	lookback = 30 * 24 * time.Hour
+)
+
+// Checker verifies the fixes of regressions.
+type Checker struct {
+	perfGit       perfgit.Git
+	store         regression.Store
+	provider      alerts.ConfigProvider
+	shortcutStore shortcut.Store
+	dfBuilder     dataframe.DataFrameBuilder
+	notifier      notify.UserNotifier
+
+	// defaultRadius is used for alerts that don't specify a radius.
+	defaultRadius int
+
+	// url is the URL of this instance of Perf.
+	url string
+}
+
+// New returns a new *Checker.
+func New(perfGit perfgit.Git, store regression.Store, provider alerts.ConfigProvider, shortcutStore shortcut.Store, dfBuilder dataframe.DataFrameBuilder, notifier notify.UserNotifier, defaultRadius int, url string) *Checker {
+	return &Checker{
+		perfGit:       perfGit,
+		store:         store,
+		provider:      provider,
+		shortcutStore: shortcutStore,
+		dfBuilder:     dfBuilder,
+		notifier:      notifier,
+		defaultRadius: defaultRadius,
+		url:           url,
+	}
+}
+
+// Start checks the fixes periodically until the context is cancelled.
+func (c *Checker) Start(ctx context.Context) {
+	go util.RepeatCtx(ctx, checkPeriod, func(ctx context.Context) {
+		if err := c.CheckOnce(ctx); err != nil {
+			sklog.Errorf("Failed to check regression fixes: %s", err)
+		}
+	})
+}
+
+// CheckOnce checks all the recent regressions that were triaged as fixed and
+// have not been checked yet.
+func (c *Checker) CheckOnce(ctx context.Context) error {
+	end, err := c.perfGit.CommitNumberFromTime(ctx, time.Time{})
+	if err != nil {
+		return skerr.Wrapf(err, "finding the most recent commit")
+	}
+	begin, err := c.perfGit.CommitNumberFromTime(ctx, time.Now().Add(-lookback))
+	if err != nil {
+		return skerr.Wrapf(err, "finding the first commit to check")
+	}
+	regressions, err := c.store.Range(ctx, begin, end)
+	if err != nil {
+		return skerr.Wrapf(err, "loading regressions")
+	}
+	alertsByID, err := c.alertsByID(ctx)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+
+	for commitNumber, all := range regressions {
+		for alertID, reg := range all.ByAlertID {
+			alert, ok := alertsByID[alertID]
+			if !ok {
+				continue
+			}
+			if needsCheck(reg.High, reg.HighStatus) {
+				if err := c.checkAndUpdate(ctx, commitNumber, end, alert, reg.High, reg.HighStatus, stepfit.HIGH); err != nil {
+					sklog.Errorf("Failed to check fix of high regression at %d for alert %s: %s", commitNumber, alertID, err)
+				}
+			}
+			if needsCheck(reg.Low, reg.LowStatus) {
+				if err := c.checkAndUpdate(ctx, commitNumber, end, alert, reg.Low, reg.LowStatus, stepfit.LOW); err != nil {
+					sklog.Errorf("Failed to check fix of low regression at %d for alert %s: %s", commitNumber, alertID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// alertsByID returns all the alerts, including deleted ones, keyed by their
+// ID.
+func (c *Checker) alertsByID(ctx context.Context) (map[string]*alerts.Alert, error) {
+	all, err := c.provider.GetAllAlertConfigs(ctx, true)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "loading alerts")
+	}
+	ret := make(map[string]*alerts.Alert, len(all))
+	for _, a := range all {
+		ret[a.IDAsString] = a
+	}
+	return ret, nil
+}
+
+// needsCheck returns true if the regression was triaged as fixed and that fix
+// has not been checked yet.
+func needsCheck(cl *clustering2.ClusterSummary, tr regression.TriageStatus) bool {
+	return cl != nil && tr.Status == regression.Negative && tr.FixedBy > 0 && tr.Fix == regression.FixUnchecked
+}
+
+// checkAndUpdate checks the fix of a single regression and stores the result.
+// Nothing is stored if there isn't enough data yet to decide.
+func (c *Checker) checkAndUpdate(ctx context.Context, commitNumber, mostRecent types.CommitNumber, alert *alerts.Alert, cl *clustering2.ClusterSummary, tr regression.TriageStatus, direction stepfit.StepFitStatus) error {
+	radius := types.CommitNumber(alert.Radius)
+	if radius == 0 {
+		radius = types.CommitNumber(c.defaultRadius)
+	}
+	if radius < 1 {
+		radius = 1
+	}
+	if tr.FixedBy <= commitNumber || tr.FixedBy+radius > mostRecent {
+		// Either the fix can't be checked or we need to wait for more data.
+		return nil
+	}
+	if cl.Shortcut == "" {
+		return nil
+	}
+	sc, err := c.shortcutStore.Get(ctx, cl.Shortcut)
+	if err != nil {
+		return skerr.Wrapf(err, "loading the traces of the regression")
+	}
+
+	// Only look at the regressed range before the fix, i.e. don't include data
+	// from before the regression.
+	before := radius
+	if tr.FixedBy-commitNumber < before {
+		before = tr.FixedBy - commitNumber
+	}
+	df, err := c.dataFrame(ctx, sc.Keys, tr.FixedBy-before, tr.FixedBy+radius)
+	if err != nil {
+		return skerr.Wrap(err)
+	}
+	recovered, ok := hasRecovered(df, tr.FixedBy, int(before), int(radius), alert, direction)
+	if !ok {
+		sklog.Infof("Not enough data to check the fix of the regression at %d for alert %s.", commitNumber, alert.IDAsString)
+		return nil
+	}
+
+	if recovered {
+		tr.Fix = regression.FixVerified
+	} else {
+		tr.Fix = regression.FixNotRecovered
+		tr.Status = regression.Untriaged
+	}
+	if direction == stepfit.HIGH {
+		err = c.store.TriageHigh(ctx, commitNumber, alert.IDAsString, tr)
+	} else {
+		err = c.store.TriageLow(ctx, commitNumber, alert.IDAsString, tr)
+	}
+	if err != nil {
+		return skerr.Wrapf(err, "storing the result of the fix check")
+	}
+	if !recovered {
+		c.notifyTriager(ctx, commitNumber, alert, tr)
+	}
+	return nil
+}
+
+// dataFrame returns the data for the given keys in the range [begin, end] of
+// commits.
+func (c *Checker) dataFrame(ctx context.Context, keys []string, begin, end types.CommitNumber) (*dataframe.DataFrame, error) {
+	beginCommit, err := c.perfGit.CommitFromCommitNumber(ctx, begin)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "looking up commit %d", begin)
+	}
+	endCommit, err := c.perfGit.CommitFromCommitNumber(ctx, end)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "looking up commit %d", end)
+	}
+	// The end of the time range is exclusive.
+	df, err := c.dfBuilder.NewFromKeysAndRange(ctx, keys, time.Unix(beginCommit.Timestamp, 0), time.Unix(endCommit.Timestamp+1, 0), false, progress.New())
+	if err != nil {
+		return nil, skerr.Wrapf(err, "loading the traces of the regression")
+	}
+	return df, nil
+}
+
+// hasRecovered runs step detection centered on the fix commit over every
+// trace in the DataFrame. It returns true if the majority of the traces step
+// in the opposite direction of the regression. The second return value is
+// false if none of the traces have enough data to decide.
+func hasRecovered(df *dataframe.DataFrame, fixedBy types.CommitNumber, before, after int, alert *alerts.Alert, direction stepfit.StepFitStatus) (bool, bool) {
+	fixIndex := -1
+	for i, h := range df.Header {
+		if h.Offset == fixedBy {
+			fixIndex = i
+			break
+		}
+	}
+	// Step detection needs the same number of points on each side of the fix.
+	n := before
+	if after < n {
+		n = after
+	}
+	if fixIndex < n || fixIndex+n >= len(df.Header) {
+		return false, false
+	}
+
+	checked, recovered := 0, 0
+	for _, trace := range df.TraceSet {
+		t := vec32.Dup(trace[fixIndex-n : fixIndex+n+1])
+		if tooMuchMissingData(t) {
+			continue
+		}
+		vec32.Fill(t)
+		checked++
+		sf := stepfit.GetStepFitAtMid(t, config.MinStdDev, alert.Interesting, alert.Step)
+		if sf.Status != stepfit.UNINTERESTING && sf.Status != direction {
+			recovered++
+		}
+	}
+	if checked == 0 {
+		return false, false
+	}
+	return recovered*2 > checked, true
+}
+
+// tooMuchMissingData returns true if either half of the trace is missing more
+// than half of its values.
+func tooMuchMissingData(t []float32) bool {
+	mid := len(t) / 2
+	count := func(values []float32) int {
+		ret := 0
+		for _, v := range values {
+			if v == vec32.MissingDataSentinel {
+				ret++
+			}
+		}
+		return ret
+	}
+	return count(t[:mid])*2 > mid || count(t[mid:])*2 > len(t)-mid
+}
+
+// notifyTriager tells the user who triaged the regression as fixed that the
+// regression was reopened.
+func (c *Checker) notifyTriager(ctx context.Context, commitNumber types.CommitNumber, alert *alerts.Alert, tr regression.TriageStatus) {
+	if tr.TriagedBy == "" {
+		return
+	}
+	commit, err := c.perfGit.CommitFromCommitNumber(ctx, commitNumber)
+	if err != nil {
+		sklog.Errorf("Failed to look up commit %d: %s", commitNumber, err)
+		return
+	}
+	link := fmt.Sprintf("%s/t/?begin=%d&end=%d&subset=all", c.url, commit.Timestamp, commit.Timestamp+1)
+	subject := fmt.Sprintf("Perf regression for %q was not fixed by commit %d", alert.DisplayName, tr.FixedBy)
+	body := fmt.Sprintf(`<p>You triaged the regression found by <b>%s</b> at <a href="%s">%s</a> as fixed by commit %d.</p>
+<p>The metric did not recover at that commit, so the regression has been reopened.</p>`,
+		html.EscapeString(alert.DisplayName), link, html.EscapeString(commit.Subject), tr.FixedBy)
+	if err := c.notifier.SendToUser(ctx, tr.TriagedBy, subject, body); err != nil {
+		sklog.Errorf("Failed to notify %s that a fix was not verified: %s", tr.TriagedBy, err)
+	}
+}
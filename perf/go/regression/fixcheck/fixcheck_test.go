@@ -0,0 +1,111 @@
+package fixcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.goldmine.build/go/vec32"
+	"go.goldmine.build/perf/go/alerts"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/regression"
+	"go.goldmine.build/perf/go/stepfit"
+	"go.goldmine.build/perf/go/types"
+)
+
+const (
+	fixedBy = types.CommitNumber(12)
+	e       = vec32.MissingDataSentinel
+)
+
+func newAlert() *alerts.Alert {
+	a := alerts.NewConfig()
+	a.Step = types.AbsoluteStep
+	a.Interesting = 1
+	return a
+}
+
+// dataFrameWithTraces returns a DataFrame whose columns start at commit 10.
+func dataFrameWithTraces(traces ...types.Trace) *dataframe.DataFrame {
+	df := dataframe.NewEmpty()
+	for i := range traces[0] {
+		df.Header = append(df.Header, &dataframe.ColumnHeader{Offset: types.CommitNumber(10 + i)})
+	}
+	for i, tr := range traces {
+		df.TraceSet[string(rune('a'+i))] = tr
+	}
+	return df
+}
+
+func TestNeedsCheck(t *testing.T) {
+	cl := &clustering2.ClusterSummary{}
+	fixed := regression.TriageStatus{Status: regression.Negative, FixedBy: fixedBy}
+	assert.True(t, needsCheck(cl, fixed))
+
+	assert.False(t, needsCheck(nil, fixed))
+
+	notFixed := fixed
+	notFixed.FixedBy = 0
+	assert.False(t, needsCheck(cl, notFixed))
+
+	positive := fixed
+	positive.Status = regression.Positive
+	assert.False(t, needsCheck(cl, positive))
+
+	checked := fixed
+	checked.Fix = regression.FixVerified
+	assert.False(t, needsCheck(cl, checked))
+}
+
+func TestHasRecovered_HighRegressionStepsBackDown_ReturnsTrue(t *testing.T) {
+	df := dataFrameWithTraces(
+		types.Trace{10, 10, 1, 1, 1},
+		types.Trace{10, 10, 1, 1, 1},
+		types.Trace{10, 10, 10, 10, 10},
+	)
+	recovered, ok := hasRecovered(df, fixedBy, 2, 2, newAlert(), stepfit.HIGH)
+	assert.True(t, ok)
+	assert.True(t, recovered)
+}
+
+func TestHasRecovered_MajorityDidNotRecover_ReturnsFalse(t *testing.T) {
+	df := dataFrameWithTraces(
+		types.Trace{10, 10, 1, 1, 1},
+		types.Trace{10, 10, 10, 10, 10},
+	)
+	recovered, ok := hasRecovered(df, fixedBy, 2, 2, newAlert(), stepfit.HIGH)
+	assert.True(t, ok)
+	assert.False(t, recovered)
+}
+
+func TestHasRecovered_StepInSameDirection_ReturnsFalse(t *testing.T) {
+	df := dataFrameWithTraces(
+		types.Trace{10, 10, 1, 1, 1},
+	)
+	recovered, ok := hasRecovered(df, fixedBy, 2, 2, newAlert(), stepfit.LOW)
+	assert.True(t, ok)
+	assert.False(t, recovered)
+}
+
+func TestHasRecovered_NotEnoughData_ReturnsNotOK(t *testing.T) {
+	// Not enough commits after the fix.
+	df := dataFrameWithTraces(
+		types.Trace{10, 10, 1},
+	)
+	_, ok := hasRecovered(df, fixedBy, 2, 2, newAlert(), stepfit.HIGH)
+	assert.False(t, ok)
+
+	// Every trace is missing most of its data.
+	df = dataFrameWithTraces(
+		types.Trace{e, e, 1, 1, 1},
+	)
+	_, ok = hasRecovered(df, fixedBy, 2, 2, newAlert(), stepfit.HIGH)
+	assert.False(t, ok)
+}
+
+func TestTooMuchMissingData(t *testing.T) {
+	assert.False(t, tooMuchMissingData([]float32{1, 2, 3, 4}))
+	assert.False(t, tooMuchMissingData([]float32{e, 2, 3, e}))
+	assert.True(t, tooMuchMissingData([]float32{e, e, 3, 4}))
+	assert.True(t, tooMuchMissingData([]float32{1, 2, e, e, e}))
+}
@@ -0,0 +1,65 @@
+package regression
+
+import (
+	"strings"
+
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/obfuscate"
+	"go.goldmine.build/perf/go/ui/frame"
+)
+
+// Obfuscate returns a copy of reg with the param values in the dataframe and
+// the cluster summaries obfuscated.
+func Obfuscate(o *obfuscate.Obfuscator, reg *Regression) *Regression {
+	ret := *reg
+	ret.Low = obfuscateClusterSummary(o, reg.Low)
+	ret.High = obfuscateClusterSummary(o, reg.High)
+	ret.Frame = obfuscateFrame(o, reg.Frame)
+	return &ret
+}
+
+// ObfuscateResponse returns a copy of resp with the param values in the
+// dataframe and the cluster summaries obfuscated.
+func ObfuscateResponse(o *obfuscate.Obfuscator, resp *RegressionDetectionResponse) *RegressionDetectionResponse {
+	ret := *resp
+	if resp.Summary != nil {
+		summary := *resp.Summary
+		summary.Clusters = make([]*clustering2.ClusterSummary, 0, len(resp.Summary.Clusters))
+		for _, cl := range resp.Summary.Clusters {
+			summary.Clusters = append(summary.Clusters, obfuscateClusterSummary(o, cl))
+		}
+		ret.Summary = &summary
+	}
+	ret.Frame = obfuscateFrame(o, resp.Frame)
+	return &ret
+}
+
+// obfuscateFrame returns a copy of fr with the param values in its dataframe
+// obfuscated.
+func obfuscateFrame(o *obfuscate.Obfuscator, fr *frame.FrameResponse) *frame.FrameResponse {
+	if fr == nil {
+		return nil
+	}
+	ret := *fr
+	ret.DataFrame = o.DataFrame(fr.DataFrame)
+	return &ret
+}
+
+// obfuscateClusterSummary returns a copy of cl with the values of the
+// ParamSummaries obfuscated and the trace keys dropped.
+func obfuscateClusterSummary(o *obfuscate.Obfuscator, cl *clustering2.ClusterSummary) *clustering2.ClusterSummary {
+	if cl == nil {
+		return nil
+	}
+	ret := *cl
+	ret.Keys = nil
+	ret.ParamSummaries = make([]clustering2.ValuePercent, 0, len(cl.ParamSummaries))
+	for _, vp := range cl.ParamSummaries {
+		// ParamSummaries values are of the form "key=value".
+		if key, value, ok := strings.Cut(vp.Value, "="); ok {
+			vp.Value = key + "=" + o.Value(key, value)
+		}
+		ret.ParamSummaries = append(ret.ParamSummaries, vp)
+	}
+	return &ret
+}
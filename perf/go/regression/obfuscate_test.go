@@ -0,0 +1,74 @@
+package regression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/go/paramtools"
+	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/dataframe"
+	"go.goldmine.build/perf/go/obfuscate"
+	"go.goldmine.build/perf/go/types"
+	"go.goldmine.build/perf/go/ui/frame"
+)
+
+const testKey = ",arch=x86,bot=internal,"
+
+func newTestClusterSummary() *clustering2.ClusterSummary {
+	return &clustering2.ClusterSummary{
+		Keys: []string{testKey},
+		ParamSummaries: []clustering2.ValuePercent{
+			{Value: "arch=x86", Percent: 100},
+			{Value: "bot=internal", Percent: 100},
+		},
+	}
+}
+
+func newTestFrame() *frame.FrameResponse {
+	return &frame.FrameResponse{
+		DataFrame: &dataframe.DataFrame{
+			TraceSet: types.TraceSet{testKey: types.Trace{1, 2}},
+			ParamSet: paramtools.ReadOnlyParamSet{"arch": {"x86"}, "bot": {"internal"}},
+		},
+	}
+}
+
+func TestObfuscate_ClusterSummaryAndFrame_ValuesObfuscatedAndOriginalUnchanged(t *testing.T) {
+	o := obfuscate.New([]byte("secret"), []string{"bot"})
+	reg := NewRegression()
+	reg.Low = newTestClusterSummary()
+	reg.Frame = newTestFrame()
+
+	got := Obfuscate(o, reg)
+
+	require.Nil(t, got.High)
+	require.Empty(t, got.Low.Keys)
+	require.Equal(t, []clustering2.ValuePercent{
+		{Value: "arch=x86", Percent: 100},
+		{Value: "bot=" + o.Value("bot", "internal"), Percent: 100},
+	}, got.Low.ParamSummaries)
+	require.Contains(t, got.Frame.DataFrame.TraceSet, o.Key(testKey))
+	require.Equal(t, "bot=internal", reg.Low.ParamSummaries[1].Value)
+	require.Contains(t, reg.Frame.DataFrame.TraceSet, testKey)
+}
+
+func TestObfuscateResponse_ClustersAndFrame_ValuesObfuscatedAndOriginalUnchanged(t *testing.T) {
+	o := obfuscate.New([]byte("secret"), []string{"bot"})
+	resp := &RegressionDetectionResponse{
+		Summary: &clustering2.ClusterSummaries{
+			Clusters: []*clustering2.ClusterSummary{newTestClusterSummary()},
+			K:        1,
+		},
+		Frame: newTestFrame(),
+	}
+
+	got := ObfuscateResponse(o, resp)
+
+	require.Equal(t, 1, got.Summary.K)
+	require.Len(t, got.Summary.Clusters, 1)
+	require.Empty(t, got.Summary.Clusters[0].Keys)
+	require.Equal(t, "bot="+o.Value("bot", "internal"), got.Summary.Clusters[0].ParamSummaries[1].Value)
+	require.Contains(t, got.Frame.DataFrame.TraceSet, o.Key(testKey))
+	require.Equal(t, []string{testKey}, resp.Summary.Clusters[0].Keys)
+	require.Contains(t, resp.Frame.DataFrame.TraceSet, testKey)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"go.goldmine.build/perf/go/clustering2"
+	"go.goldmine.build/perf/go/types"
 	"go.goldmine.build/perf/go/ui/frame"
 )
 
@@ -32,6 +33,27 @@ const (
 // AllStatus is a slice of all values of type Status.
 var AllStatus = []Status{None, Positive, Negative, Untriaged}
 
+// FixStatus is the result of checking that a regression which was triaged as
+// fixed by a commit actually recovered after that commit.
+type FixStatus string
+
+// FixStatus constants.
+const (
+	// FixUnchecked means the fix has not been checked yet, usually because not
+	// enough data has arrived after the fixing commit.
+	FixUnchecked FixStatus = ""
+
+	// FixVerified means the metric recovered at the fixing commit.
+	FixVerified FixStatus = "verified"
+
+	// FixNotRecovered means the metric did not recover at the fixing commit,
+	// so the regression was reopened.
+	FixNotRecovered FixStatus = "not_recovered"
+)
+
+// AllFixStatus is a slice of all values of type FixStatus.
+var AllFixStatus = []FixStatus{FixUnchecked, FixVerified, FixNotRecovered}
+
 // AllRegressionsForCommit is a map[alertid]Regression.
 type AllRegressionsForCommit struct {
 	ByAlertID map[string]*Regression `json:"by_query"`
@@ -42,6 +64,25 @@ type AllRegressionsForCommit struct {
 type TriageStatus struct {
 	Status  Status `json:"status"`
 	Message string `json:"message"`
+
+	// FixedBy is the commit that a Negative regression was reported to be
+	// fixed by, or 0 if no fix was reported.
+	FixedBy types.CommitNumber `json:"fixed_by,omitempty"`
+
+	// TriagedBy is the email of the user who last triaged the regression.
+	TriagedBy string `json:"triaged_by,omitempty"`
+
+	// Fix is the result of checking that the regression recovered at FixedBy.
+	Fix FixStatus `json:"fix,omitempty"`
+}
+
+// TriageEvent records a single change to the TriageStatus of a regression.
+type TriageEvent struct {
+	// Status is the TriageStatus the regression was changed to.
+	Status TriageStatus `json:"status"`
+
+	// Timestamp is when the change was made, in Unix seconds.
+	Timestamp int64 `json:"ts"`
 }
 
 // Regression tracks the status of the Low and High regression clusters, if they
@@ -58,6 +99,11 @@ type Regression struct {
 	Frame      *frame.FrameResponse        `json:"frame"` // Describes the Low and High ClusterSummary's.
 	LowStatus  TriageStatus                `json:"low_status"`
 	HighStatus TriageStatus                `json:"high_status"`
+
+	// LowHistory and HighHistory are all the changes made to LowStatus and
+	// HighStatus respectively, oldest first.
+	LowHistory  []TriageEvent `json:"low_history,omitempty"`
+	HighHistory []TriageEvent `json:"high_history,omitempty"`
 }
 
 // NewRegression returns a new *Regression.
@@ -85,10 +131,12 @@ func (r *Regression) Merge(rhs *Regression) *Regression {
 		if r.Low != nil && (rhs.Low.StepFit.Regression > r.Low.StepFit.Regression) {
 			r.Low = rhs.Low
 			r.LowStatus = rhs.LowStatus
+			r.LowHistory = rhs.LowHistory
 			r.Frame = rhs.Frame
 		} else {
 			r.Low = rhs.Low
 			r.LowStatus = rhs.LowStatus
+			r.LowHistory = rhs.LowHistory
 			r.Frame = rhs.Frame
 		}
 	}
@@ -96,10 +144,12 @@ func (r *Regression) Merge(rhs *Regression) *Regression {
 		if r.High != nil && (rhs.High.StepFit.Regression < r.High.StepFit.Regression) {
 			r.High = rhs.High
 			r.HighStatus = rhs.HighStatus
+			r.HighHistory = rhs.HighHistory
 			r.Frame = rhs.Frame
 		} else {
 			r.High = rhs.High
 			r.HighStatus = rhs.HighStatus
+			r.HighHistory = rhs.HighHistory
 			r.Frame = rhs.Frame
 		}
 	}
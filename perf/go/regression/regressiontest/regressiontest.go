@@ -81,6 +81,12 @@ func SetLowAndTriage(t *testing.T, store regression.Store) {
 	}
 	assert.Equal(t, regression.Positive, ranges[key].ByAlertID["1"].LowStatus.Status)
 
+	// Confirm the triage was recorded in the history.
+	history := ranges[key].ByAlertID["1"].LowHistory
+	require.Len(t, history, 1)
+	assert.Equal(t, tr, history[0].Status)
+	assert.NotZero(t, history[0].Timestamp)
+
 	ranges, err = store.Range(ctx, 1, 3)
 	require.NoError(t, err)
 	assert.Len(t, ranges, 1)
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 
 	"go.goldmine.build/go/metrics2"
+	"go.goldmine.build/go/now"
 	"go.goldmine.build/go/skerr"
 	"go.goldmine.build/go/sklog"
 	"go.goldmine.build/go/sql/pool"
@@ -140,17 +141,23 @@ func (s *SQLRegressionStore) SetLow(ctx context.Context, commitNumber types.Comm
 	return ret, err
 }
 
-// TriageLow implements the regression.Store interface.
+// TriageLow implements the regression.Store interface. The change is also
+// appended to the LowHistory of the regression.
 func (s *SQLRegressionStore) TriageLow(ctx context.Context, commitNumber types.CommitNumber, alertID string, tr regression.TriageStatus) error {
+	event := regression.TriageEvent{Status: tr, Timestamp: now.Now(ctx).Unix()}
 	return s.readModifyWrite(ctx, commitNumber, alertID, true /* mustExist*/, func(r *regression.Regression) {
 		r.LowStatus = tr
+		r.LowHistory = append(r.LowHistory, event)
 	})
 }
 
-// TriageHigh implements the regression.Store interface.
+// TriageHigh implements the regression.Store interface. The change is also
+// appended to the HighHistory of the regression.
 func (s *SQLRegressionStore) TriageHigh(ctx context.Context, commitNumber types.CommitNumber, alertID string, tr regression.TriageStatus) error {
+	event := regression.TriageEvent{Status: tr, Timestamp: now.Now(ctx).Unix()}
 	return s.readModifyWrite(ctx, commitNumber, alertID, true /* mustExist*/, func(r *regression.Regression) {
 		r.HighStatus = tr
+		r.HighHistory = append(r.HighHistory, event)
 	})
 }
 
@@ -27,12 +27,21 @@ import (
 
 // The two vars below should be updated everytime there's a schema change.
 var FromLiveToNext = `
-	CREATE INDEX IF NOT EXISTS by_key_value
-	ON postings	(tile_number, key_value);
+	CREATE TABLE IF NOT EXISTS AlertTemplates (
+		id INT PRIMARY KEY DEFAULT unique_rowid(),
+		template TEXT,
+		last_modified INT
+	);
+	CREATE TABLE IF NOT EXISTS DerivedMetrics (
+		name TEXT UNIQUE NOT NULL PRIMARY KEY,
+		metric TEXT,
+		last_modified INT
+	);
 `
 
 var FromNextToLive = `
-	DROP INDEX IF EXISTS postings@by_key_value;
+	DROP TABLE IF EXISTS AlertTemplates;
+	DROP TABLE IF EXISTS DerivedMetrics;
 `
 
 // This function will check whether there's a new schema checked-in,
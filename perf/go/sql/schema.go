@@ -9,6 +9,11 @@ const Schema = `CREATE TABLE IF NOT EXISTS Alerts (
   config_state INT DEFAULT 0,
   last_modified INT
 );
+CREATE TABLE IF NOT EXISTS AlertTemplates (
+  id INT PRIMARY KEY DEFAULT unique_rowid(),
+  template TEXT,
+  last_modified INT
+);
 CREATE TABLE IF NOT EXISTS Commits (
   commit_number INT PRIMARY KEY,
   git_hash TEXT UNIQUE NOT NULL,
@@ -16,6 +21,11 @@ CREATE TABLE IF NOT EXISTS Commits (
   author TEXT,
   subject TEXT
 );
+CREATE TABLE IF NOT EXISTS DerivedMetrics (
+  name TEXT UNIQUE NOT NULL PRIMARY KEY,
+  metric TEXT,
+  last_modified INT
+);
 CREATE TABLE IF NOT EXISTS GraphsShortcuts (
   id TEXT UNIQUE NOT NULL PRIMARY KEY,
   graphs TEXT
@@ -67,6 +77,12 @@ var Alerts = []string{
 	"last_modified",
 }
 
+var AlertTemplates = []string{
+	"id",
+	"template",
+	"last_modified",
+}
+
 var Commits = []string{
 	"commit_number",
 	"git_hash",
@@ -75,6 +91,12 @@ var Commits = []string{
 	"subject",
 }
 
+var DerivedMetrics = []string{
+	"name",
+	"metric",
+	"last_modified",
+}
+
 var GraphsShortcuts = []string{
 	"id",
 	"graphs",
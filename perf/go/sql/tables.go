@@ -4,6 +4,7 @@ package sql
 
 import (
 	alertschema "go.goldmine.build/perf/go/alerts/sqlalertstore/schema"
+	derivedmetricschema "go.goldmine.build/perf/go/derivedmetrics/sqlderivedmetricstore/schema"
 	gitschema "go.goldmine.build/perf/go/git/schema"
 	graphsshortcutschema "go.goldmine.build/perf/go/graphsshortcut/graphsshortcutstore/schema"
 	regressionschema "go.goldmine.build/perf/go/regression/sqlregressionstore/schema"
@@ -14,7 +15,9 @@ import (
 // Tables represents the full schema of the SQL database.
 type Tables struct {
 	Alerts          []alertschema.AlertSchema
+	AlertTemplates  []alertschema.AlertTemplateSchema
 	Commits         []gitschema.Commit
+	DerivedMetrics  []derivedmetricschema.DerivedMetricSchema
 	GraphsShortcuts []graphsshortcutschema.GraphsShortcutSchema
 	ParamSets       []traceschema.ParamSetsSchema
 	Postings        []traceschema.PostingsSchema
@@ -368,6 +368,74 @@ func (_c *TraceStore_GetSource_Call) RunAndReturn(run func(ctx context.Context,
 	return _c
 }
 
+// GetTraceIDsAtCommit provides a mock function for the type TraceStore
+func (_mock *TraceStore) GetTraceIDsAtCommit(ctx context.Context, commitNumber types.CommitNumber) ([]string, error) {
+	ret := _mock.Called(ctx, commitNumber)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTraceIDsAtCommit")
+	}
+
+	var r0 []string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(context.Context, types.CommitNumber) ([]string, error)); ok {
+		return returnFunc(ctx, commitNumber)
+	}
+	if returnFunc, ok := ret.Get(0).(func(context.Context, types.CommitNumber) []string); ok {
+		r0 = returnFunc(ctx, commitNumber)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(context.Context, types.CommitNumber) error); ok {
+		r1 = returnFunc(ctx, commitNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// TraceStore_GetTraceIDsAtCommit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTraceIDsAtCommit'
+type TraceStore_GetTraceIDsAtCommit_Call struct {
+	*mock.Call
+}
+
+// GetTraceIDsAtCommit is a helper method to define mock.On call
+//   - ctx context.Context
+//   - commitNumber types.CommitNumber
+func (_e *TraceStore_Expecter) GetTraceIDsAtCommit(ctx interface{}, commitNumber interface{}) *TraceStore_GetTraceIDsAtCommit_Call {
+	return &TraceStore_GetTraceIDsAtCommit_Call{Call: _e.mock.On("GetTraceIDsAtCommit", ctx, commitNumber)}
+}
+
+func (_c *TraceStore_GetTraceIDsAtCommit_Call) Run(run func(ctx context.Context, commitNumber types.CommitNumber)) *TraceStore_GetTraceIDsAtCommit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 types.CommitNumber
+		if args[1] != nil {
+			arg1 = args[1].(types.CommitNumber)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *TraceStore_GetTraceIDsAtCommit_Call) Return(strings []string, err error) *TraceStore_GetTraceIDsAtCommit_Call {
+	_c.Call.Return(strings, err)
+	return _c
+}
+
+func (_c *TraceStore_GetTraceIDsAtCommit_Call) RunAndReturn(run func(ctx context.Context, commitNumber types.CommitNumber) ([]string, error)) *TraceStore_GetTraceIDsAtCommit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetTraceIDsBySource provides a mock function for the type TraceStore
 func (_mock *TraceStore) GetTraceIDsBySource(ctx context.Context, sourceFilename string, tileNumber types.TileNumber) ([]string, error) {
 	ret := _mock.Called(ctx, sourceFilename, tileNumber)
@@ -276,6 +276,7 @@ const (
 	readTraces
 	getLastNSources
 	getTraceIDsBySource
+	getTraceIDsAtCommit
 	countMatchingTraces
 	restrictClause
 	deleteCommit
@@ -617,6 +618,20 @@ var statements = map[statement]string{
             Postings.tile_number= $2
         ORDER BY
             Postings.trace_id`,
+	getTraceIDsAtCommit: `
+        SELECT
+            Postings.key_value, Postings.trace_id
+        FROM
+            Postings@by_trace_id
+            INNER LOOKUP JOIN
+                TraceValues@primary
+            ON
+                TraceValues.trace_id = Postings.trace_id
+                AND TraceValues.commit_number = $2
+        WHERE
+            Postings.tile_number = $1
+        ORDER BY
+            Postings.trace_id`,
 	countCommitInCommitNumberRange: `
 		SELECT
 			count(*)
@@ -928,6 +943,34 @@ func (s *SQLTraceStore) GetTraceIDsBySource(ctx context.Context, sourceFilename
 		return nil, skerr.Wrapf(err, "Failed for sourceFilename=%q and tileNumber=%d", sourceFilename, tileNumber)
 	}
 
+	ret, err := traceIDsFromPostingsRows(rows)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed scanning for sourceFilename=%q and tileNumber=%d", sourceFilename, tileNumber)
+	}
+	return ret, nil
+}
+
+// GetTraceIDsAtCommit implements the tracestore.TraceStore interface.
+func (s *SQLTraceStore) GetTraceIDsAtCommit(ctx context.Context, commitNumber types.CommitNumber) ([]string, error) {
+	ctx, span := trace.StartSpan(ctx, "sqltracestore.GetTraceIDsAtCommit")
+	defer span.End()
+
+	tileNumber := s.TileNumber(commitNumber)
+	rows, err := s.db.Query(ctx, statements[getTraceIDsAtCommit], tileNumber, commitNumber)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed for commitNumber=%d", commitNumber)
+	}
+	ret, err := traceIDsFromPostingsRows(rows)
+	if err != nil {
+		return nil, skerr.Wrapf(err, "Failed scanning for commitNumber=%d", commitNumber)
+	}
+	return ret, nil
+}
+
+// traceIDsFromPostingsRows builds up trace ids from rows of (key_value,
+// trace_id) pairs read from the Postings table. The rows must be ordered by
+// trace_id.
+func traceIDsFromPostingsRows(rows pgx.Rows) ([]string, error) {
 	// We queried the Postings table, build up each traceid from all the
 	// key=value pairs returned.
 	var currentTraceIDAsBytes []byte
@@ -937,7 +980,7 @@ func (s *SQLTraceStore) GetTraceIDsBySource(ctx context.Context, sourceFilename
 		var keyValue string
 		var traceIDAsBytes []byte
 		if err := rows.Scan(&keyValue, &traceIDAsBytes); err != nil {
-			return nil, skerr.Wrapf(err, "Failed scanning for sourceFilename=%q and tileNumber=%d", sourceFilename, tileNumber)
+			return nil, skerr.Wrap(err)
 		}
 		// If we hit a new trace_id then we have a complete traceID.
 		if !bytes.Equal(currentTraceIDAsBytes, traceIDAsBytes) {
@@ -747,6 +747,23 @@ func TestGetTraceIDsBySource_LookForSourceThatDoesNotExist_ReturnsEmptySlice(t *
 	require.Empty(t, traceIDs)
 }
 
+func TestGetTraceIDsAtCommit_CommitInSecondTile_Success(t *testing.T) {
+	ctx, s := commonTestSetup(t, true)
+
+	traceIDs, err := s.GetTraceIDsAtCommit(ctx, types.CommitNumber(8))
+	require.NoError(t, err)
+	expected := []string{",arch=x86,config=565,", ",arch=x86,config=8888,"}
+	require.ElementsMatch(t, expected, traceIDs)
+}
+
+func TestGetTraceIDsAtCommit_CommitWithNoData_ReturnsEmptySlice(t *testing.T) {
+	ctx, s := commonTestSetup(t, true)
+
+	traceIDs, err := s.GetTraceIDsAtCommit(ctx, types.CommitNumber(2))
+	require.NoError(t, err)
+	require.Empty(t, traceIDs)
+}
+
 func TestWriteTraces_InsertDifferentValueAndFile_OverwriteExistingTraceValues(t *testing.T) {
 	ctx, s := commonTestSetupWithCommits(t, true)
 	traceName1 := ",arch=x86,config=8888,"
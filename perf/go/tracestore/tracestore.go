@@ -45,6 +45,10 @@ type TraceStore interface {
 	// ingested file.
 	GetTraceIDsBySource(ctx context.Context, sourceFilename string, tileNumber types.TileNumber) ([]string, error)
 
+	// GetTraceIDsAtCommit returns all the traceIDs that have a value at the
+	// given commit.
+	GetTraceIDsAtCommit(ctx context.Context, commitNumber types.CommitNumber) ([]string, error)
+
 	// OffsetFromCommitNumber returns the offset from within a Tile that a commit sits.
 	OffsetFromCommitNumber(commitNumber types.CommitNumber) int32
 
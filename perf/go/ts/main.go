@@ -16,10 +16,13 @@ import (
 	"go.goldmine.build/perf/go/alerts"
 	"go.goldmine.build/perf/go/clustering2"
 	"go.goldmine.build/perf/go/config"
+	"go.goldmine.build/perf/go/derivedmetrics"
 	"go.goldmine.build/perf/go/dryrun"
 	"go.goldmine.build/perf/go/frontend"
 	"go.goldmine.build/perf/go/graphsshortcut"
+	"go.goldmine.build/perf/go/heatmap"
 	"go.goldmine.build/perf/go/ingest/format"
+	"go.goldmine.build/perf/go/ingest/parser"
 	"go.goldmine.build/perf/go/notifytypes"
 	"go.goldmine.build/perf/go/pinpoint"
 	"go.goldmine.build/perf/go/pivot"
@@ -59,21 +62,28 @@ func main() {
 	generator.AddMultiple(generator,
 		alerts.Alert{},
 		alerts.AlertsStatus{},
+		alerts.Template{},
 		clustering2.ClusterSummary{},
 		clustering2.ValuePercent{},
 		config.Favorites{},
 		config.QueryConfig{},
+		derivedmetrics.DerivedMetric{},
 		dryrun.RegressionAtCommit{},
 		frame.FrameRequest{},
 		frame.FrameResponse{},
+		frame.PartialFrameResponse{},
+		frontend.AlertTemplateUpdateResponse{},
 		frontend.AlertUpdateResponse{},
 		frontend.CIDHandlerResponse{},
 		frontend.ClusterStartResponse{},
 		frontend.CommitDetailsRequest{},
+		frontend.ConvertRangeRequest{},
+		frontend.ConvertRangeResponse{},
 		frontend.CountHandlerRequest{},
 		frontend.CountHandlerResponse{},
 		frontend.GetGraphsShortcutRequest{},
 		frontend.RangeRequest{},
+		frontend.RegressionDetailResponse{},
 		frontend.RegressionRangeRequest{},
 		frontend.RegressionRangeResponse{},
 		frontend.ShiftRequest{},
@@ -103,6 +113,7 @@ func main() {
 		{frontend.AllRegressionSubset, "Subset"},
 		{regression.AllProcessState, "ProcessState"},
 		{regression.AllStatus, "Status"},
+		{regression.AllFixStatus, "FixStatus"},
 		{stepfit.AllStepFitStatus, "StepFitStatus"},
 		{types.AllClusterAlgos, "ClusterAlgo"},
 		{types.AllStepDetections, "StepDetection"},
@@ -117,6 +128,10 @@ func main() {
 	generator.AddToNamespace(progress.SerializedProgress{}, "progress")
 
 	generator.AddToNamespace(format.Format{}, "ingest")
+	generator.AddToNamespace(parser.Simulation{}, "ingest")
+
+	generator.AddToNamespace(heatmap.Request{}, "heatmap")
+	generator.AddToNamespace(heatmap.Heatmap{}, "heatmap")
 
 	err := util.WithWriteFile(*outputPath, func(w io.Writer) error {
 		return generator.Render(w)
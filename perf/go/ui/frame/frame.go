@@ -18,6 +18,7 @@ import (
 	"go.goldmine.build/perf/go/config"
 	"go.goldmine.build/perf/go/dataframe"
 	perfgit "go.goldmine.build/perf/go/git"
+	"go.goldmine.build/perf/go/obfuscate"
 	"go.goldmine.build/perf/go/pivot"
 	"go.goldmine.build/perf/go/progress"
 	"go.goldmine.build/perf/go/shortcut"
@@ -84,8 +85,22 @@ type FrameRequest struct {
 	NumCommits  int32       `json:"num_commits"` // If RequestType is REQUEST_COMPACT, then the number of commits to show before End, and Begin is ignored.
 	RequestType RequestType `json:"request_type"`
 
+	// BeginCommit and EndCommit, if set, address the range by commit number
+	// instead, and take precedence over Begin and End. The range is inclusive of
+	// both commits.
+	BeginCommit *types.CommitNumber `json:"begin_commit,omitempty"`
+	EndCommit   *types.CommitNumber `json:"end_commit,omitempty"`
+
 	Pivot *pivot.Request `json:"pivot"`
 
+	// Obfuscator, if not nil, is applied to the trace keys of the results,
+	// e.g. because the request came from a user that is not logged in.
+	Obfuscator *obfuscate.Obfuscator `json:"-"`
+
+	// DerivedMetrics are the formulas of the derived metrics, keyed by name,
+	// that Formulas can refer to via metric().
+	DerivedMetrics map[string]string `json:"-"`
+
 	Progress progress.Progress `json:"-"`
 }
 
@@ -120,6 +135,10 @@ type frameRequestProcess struct {
 	search        int     // The current search (either Formula or Query) being processed.
 	totalSearches int     // The total number of Formulas and Queries in the FrameRequest.
 	percent       float32 // The percentage of the searches complete [0.0-1.0].
+
+	// onPartial, if not nil, is called with the results accumulated so far
+	// each time a search completes and more searches remain.
+	onPartial func(*PartialFrameResponse)
 }
 
 // ProcessFrameRequest starts processing a FrameRequest.
@@ -128,16 +147,29 @@ type frameRequestProcess struct {
 //
 // The finished results are stored in the FrameRequestProcess.Progress.Results.
 func ProcessFrameRequest(ctx context.Context, req *FrameRequest, perfGit perfgit.Git, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut.Store) error {
+	return processFrameRequest(ctx, req, perfGit, dfBuilder, shortcutStore, nil)
+}
+
+// numSearches returns the number of searches, i.e. Queries, Formulas and
+// Keys, in the given request.
+func numSearches(req *FrameRequest) int {
 	numKeys := 0
 	if req.Keys != "" {
 		numKeys = 1
 	}
+	return len(req.Formulas) + len(req.Queries) + numKeys
+}
+
+// processFrameRequest is ProcessFrameRequest, but also calls onPartial, if
+// not nil, with intermediate results as each search completes.
+func processFrameRequest(ctx context.Context, req *FrameRequest, perfGit perfgit.Git, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut.Store, onPartial func(*PartialFrameResponse)) error {
 	ret := &frameRequestProcess{
 		perfGit:       perfGit,
 		request:       req,
-		totalSearches: len(req.Formulas) + len(req.Queries) + numKeys,
+		totalSearches: numSearches(req),
 		dfBuilder:     dfBuilder,
 		shortcutStore: shortcutStore,
+		onPartial:     onPartial,
 	}
 	df, err := ret.run(ctx)
 	if err != nil {
@@ -151,6 +183,9 @@ func ProcessFrameRequest(ctx context.Context, req *FrameRequest, perfGit perfgit
 		return ret.reportError(err, "Failed to get skps.")
 	}
 
+	if req.Obfuscator != nil {
+		resp.DataFrame = req.Obfuscator.DataFrame(resp.DataFrame)
+	}
 	ret.request.Progress.Results(resp)
 	return nil
 }
@@ -167,6 +202,36 @@ func (p *frameRequestProcess) searchInc() {
 	p.search += 1
 }
 
+// reportPartial passes the traces accumulated so far in df to onPartial.
+//
+// Nothing is reported after the last search, since the final results are
+// reported via the Progress, or for pivot requests, since a pivot is only
+// meaningful once all the traces have been loaded.
+func (p *frameRequestProcess) reportPartial(ctx context.Context, df *dataframe.DataFrame) {
+	if p.onPartial == nil || p.search >= p.totalSearches {
+		return
+	}
+	if p.request.Pivot != nil && len(p.request.Pivot.GroupBy) > 0 {
+		return
+	}
+	// ResponseFromDataFrame may replace the TraceSet when truncating, so pass
+	// in a copy to leave df untouched. The skps are left for the final
+	// response, as is any truncation message.
+	partialDF := *df
+	resp, err := ResponseFromDataFrame(ctx, nil, &partialDF, nil, true, progress.New())
+	if err != nil {
+		// No commits have been found yet, report progress without traces.
+		resp = nil
+	} else if p.request.Obfuscator != nil {
+		resp.DataFrame = p.request.Obfuscator.DataFrame(resp.DataFrame)
+	}
+	p.onPartial(&PartialFrameResponse{
+		Response:  resp,
+		Completed: p.search,
+		Total:     p.totalSearches,
+	})
+}
+
 // run does the work in a FrameRequestProcess. It does not return until all the
 // work is done or the request failed. Should be run as a Go routine.
 func (p *frameRequestProcess) run(ctx context.Context) (*dataframe.DataFrame, error) {
@@ -188,6 +253,7 @@ func (p *frameRequestProcess) run(ctx context.Context) (*dataframe.DataFrame, er
 		}
 		df = dataframe.Join(df, newDF)
 		p.searchInc()
+		p.reportPartial(ctx, df)
 	}
 
 	p.request.Progress.Message("Loading", "Formulas")
@@ -200,6 +266,7 @@ func (p *frameRequestProcess) run(ctx context.Context) (*dataframe.DataFrame, er
 		}
 		df = dataframe.Join(df, newDF)
 		p.searchInc()
+		p.reportPartial(ctx, df)
 	}
 
 	p.request.Progress.Message("Loading", "Keys")
@@ -401,6 +468,7 @@ func (p *frameRequestProcess) doCalc(ctx context.Context, formula string, begin,
 	}
 
 	calcContext := calc.NewContext(rowsFromQuery, rowsFromShortcut)
+	calcContext.Metrics = p.request.DerivedMetrics
 	rows, err := calcContext.Eval(formula)
 	if err != nil {
 		return nil, skerr.Wrapf(err, "Calculation failed")
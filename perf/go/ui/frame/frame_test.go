@@ -19,6 +19,7 @@ import (
 	"go.goldmine.build/perf/go/dataframe/mocks"
 	perfgit "go.goldmine.build/perf/go/git"
 	"go.goldmine.build/perf/go/git/gittest"
+	"go.goldmine.build/perf/go/obfuscate"
 	"go.goldmine.build/perf/go/pivot"
 	"go.goldmine.build/perf/go/progress"
 	"go.goldmine.build/perf/go/shortcut"
@@ -374,6 +375,76 @@ func TestRun_QueryAndThenPivot_ReturnsPivotedDataFrame(t *testing.T) {
 	require.Equal(t, actualDf.TraceSet[",config=8888,"], types.Trace{1, 2, 3})
 }
 
+func TestRun_TwoQueriesWithOnPartial_PartialResultsReportedAfterFirstQuery(t *testing.T) {
+
+	dfbMock, df, fr := frameRequestForTest(t)
+	fr.request.Queries = []string{"config=8888", "config=565"}
+	fr.request.End = int(testTimeEnd.Unix())
+	fr.totalSearches = 2
+	var partials []*PartialFrameResponse
+	fr.onPartial = func(partial *PartialFrameResponse) {
+		partials = append(partials, partial)
+	}
+
+	dfbMock.On("NewNFromQuery", testutils.AnyContext, testTimeEnd, mock.Anything, fr.request.NumCommits, fr.request.Progress).Return(df, nil)
+
+	_, err := fr.run(context.Background())
+	require.NoError(t, err)
+	// Only the first query is reported, the final results go to the Progress.
+	require.Len(t, partials, 1)
+	assert.Equal(t, 1, partials[0].Completed)
+	assert.Equal(t, 2, partials[0].Total)
+	require.NotNil(t, partials[0].Response)
+	assert.Equal(t, df.TraceSet, partials[0].Response.DataFrame.TraceSet)
+}
+
+func TestRun_OnPartialWithObfuscator_PartialResultsObfuscated(t *testing.T) {
+
+	dfbMock, df, fr := frameRequestForTest(t)
+	fr.request.Queries = []string{"config=8888", "config=565"}
+	fr.request.End = int(testTimeEnd.Unix())
+	obfuscator := obfuscate.New([]byte("secret"), []string{"config"})
+	fr.request.Obfuscator = obfuscator
+	fr.totalSearches = 2
+	var partials []*PartialFrameResponse
+	fr.onPartial = func(partial *PartialFrameResponse) {
+		partials = append(partials, partial)
+	}
+
+	dfbMock.On("NewNFromQuery", testutils.AnyContext, testTimeEnd, mock.Anything, fr.request.NumCommits, fr.request.Progress).Return(df, nil)
+
+	actualDf, err := fr.run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, partials, 1)
+	require.NotNil(t, partials[0].Response)
+	assert.Equal(t, types.TraceSet{
+		",arch=x86,config=" + obfuscator.Value("config", "8888") + ",": types.Trace{1, 2, 3},
+		",arch=x86,config=" + obfuscator.Value("config", "565") + ",":  types.Trace{2, 4, 6},
+	}, partials[0].Response.DataFrame.TraceSet)
+	// The DataFrame used to build the final results still has the raw keys.
+	assert.Contains(t, actualDf.TraceSet, ",arch=x86,config=8888,")
+}
+
+func TestRun_PivotWithOnPartial_NoPartialResultsReported(t *testing.T) {
+
+	dfbMock, df, fr := frameRequestForTest(t)
+	fr.request.Queries = []string{"config=8888", "config=565"}
+	fr.request.End = int(testTimeEnd.Unix())
+	fr.request.Pivot = &pivot.Request{
+		GroupBy:   []string{"config"},
+		Operation: pivot.Sum,
+	}
+	fr.totalSearches = 2
+	fr.onPartial = func(partial *PartialFrameResponse) {
+		assert.Fail(t, "Partial results should not be reported for pivot requests.")
+	}
+
+	dfbMock.On("NewNFromQuery", testutils.AnyContext, testTimeEnd, mock.Anything, fr.request.NumCommits, fr.request.Progress).Return(df, nil)
+
+	_, err := fr.run(context.Background())
+	require.NoError(t, err)
+}
+
 func TestRun_ValidQueryAndThenInvalidPivot_ReturnsError(t *testing.T) {
 
 	dfbMock, df, fr := frameRequestForTest(t)
@@ -0,0 +1,115 @@
+package frame
+
+import (
+	"context"
+	"sync"
+
+	"go.goldmine.build/go/skerr"
+	"go.goldmine.build/perf/go/dataframe"
+	perfgit "go.goldmine.build/perf/go/git"
+	"go.goldmine.build/perf/go/shortcut"
+)
+
+// PartialFrameResponse is the response for a FrameRequest that is still being
+// processed.
+type PartialFrameResponse struct {
+	// Response holds the traces from all the searches completed so far. It is
+	// nil if no search has completed, or none of them matched any commits.
+	Response *FrameResponse `json:"response"`
+
+	// Completed is the number of searches, i.e. Queries, Formulas and Keys,
+	// that have completed.
+	Completed int `json:"completed"`
+
+	// Total is the total number of searches in the FrameRequest.
+	Total int `json:"total"`
+}
+
+// runningFrameRequest is a FrameRequest being processed by
+// RunningFrameRequests.
+type runningFrameRequest struct {
+	cancel  context.CancelFunc
+	partial PartialFrameResponse
+}
+
+// RunningFrameRequests keeps track of the FrameRequests currently being
+// processed, so their partial results can be retrieved and so they can be
+// cancelled before they are complete.
+type RunningFrameRequests struct {
+	mutex sync.Mutex
+
+	// running maps ids to the requests being processed. Protected by mutex.
+	running map[string]*runningFrameRequest
+}
+
+// NewRunningFrameRequests returns a new RunningFrameRequests.
+func NewRunningFrameRequests() *RunningFrameRequests {
+	return &RunningFrameRequests{
+		running: map[string]*runningFrameRequest{},
+	}
+}
+
+// Process is ProcessFrameRequest, but the partial results are available via
+// Partial under the given id while the request is being processed, and the
+// request can be stopped via Cancel.
+//
+// It does not return until all the work is complete or the request has been
+// cancelled.
+func (r *RunningFrameRequests) Process(ctx context.Context, id string, req *FrameRequest, perfGit perfgit.Git, dfBuilder dataframe.DataFrameBuilder, shortcutStore shortcut.Store) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.mutex.Lock()
+	r.running[id] = &runningFrameRequest{
+		cancel: cancel,
+		partial: PartialFrameResponse{
+			Total: numSearches(req),
+		},
+	}
+	r.mutex.Unlock()
+
+	defer func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		delete(r.running, id)
+	}()
+
+	err := processFrameRequest(ctx, req, perfGit, dfBuilder, shortcutStore, func(partial *PartialFrameResponse) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if running, ok := r.running[id]; ok {
+			running.partial = *partial
+		}
+	})
+	if err != nil && ctx.Err() == context.Canceled {
+		return skerr.Wrapf(err, "Request was cancelled")
+	}
+	return err
+}
+
+// Partial returns the partial results of the request with the given id. The
+// bool is false if no request with that id is running, for example because it
+// has already finished, in which case the final results are available via its
+// progress.Progress.
+func (r *RunningFrameRequests) Partial(id string) (PartialFrameResponse, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	running, ok := r.running[id]
+	if !ok {
+		return PartialFrameResponse{}, false
+	}
+	return running.partial, true
+}
+
+// Cancel stops processing the request with the given id. It returns false if
+// no request with that id is running.
+func (r *RunningFrameRequests) Cancel(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	running, ok := r.running[id]
+	if !ok {
+		return false
+	}
+	running.cancel()
+	return true
+}
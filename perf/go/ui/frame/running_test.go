@@ -0,0 +1,55 @@
+package frame
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.goldmine.build/go/testutils"
+	"go.goldmine.build/perf/go/progress"
+)
+
+const testRequestID = "my-request-id"
+
+func TestRunningFrameRequests_UnknownID_PartialAndCancelReturnFalse(t *testing.T) {
+
+	r := NewRunningFrameRequests()
+	_, ok := r.Partial(testRequestID)
+	assert.False(t, ok)
+	assert.False(t, r.Cancel(testRequestID))
+}
+
+func TestRunningFrameRequests_CancelDuringSecondQuery_PartialResultsAvailableAndProcessReturnsError(t *testing.T) {
+
+	dfbMock, df, _ := frameRequestForTest(t)
+	req := &FrameRequest{
+		Queries:     []string{"config=8888", "config=565"},
+		RequestType: REQUEST_COMPACT,
+		End:         int(testTimeEnd.Unix()),
+		NumCommits:  10,
+		Progress:    progress.New(),
+	}
+	r := NewRunningFrameRequests()
+
+	dfbMock.On("NewNFromQuery", testutils.AnyContext, testTimeEnd, mock.Anything, req.NumCommits, req.Progress).Return(df, nil).Once()
+	dfbMock.On("NewNFromQuery", testutils.AnyContext, testTimeEnd, mock.Anything, req.NumCommits, req.Progress).Run(func(args mock.Arguments) {
+		partial, ok := r.Partial(testRequestID)
+		require.True(t, ok)
+		assert.Equal(t, 1, partial.Completed)
+		assert.Equal(t, 2, partial.Total)
+		assert.Equal(t, df.TraceSet, partial.Response.DataFrame.TraceSet)
+
+		require.True(t, r.Cancel(testRequestID))
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled).Once()
+
+	err := r.Process(context.Background(), testRequestID, req, nil, dfbMock, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Request was cancelled")
+
+	// Once Process returns the request is no longer tracked.
+	_, ok := r.Partial(testRequestID)
+	assert.False(t, ok)
+}
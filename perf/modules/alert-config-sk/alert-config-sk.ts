@@ -182,6 +182,17 @@ export class AlertConfigSk extends ElementSk {
       </a>
     </div>
 
+    <label for="derived-metric">
+      Or the name of a derived metric to monitor instead. The query above still
+      selects which new data causes the alert to run.
+    </label>
+    <input
+      id="derived-metric"
+      type="text"
+      .value=${ele._config.derived_metric || ''}
+      @input=${(e: InputEvent) =>
+        (ele._config.derived_metric = (e.target! as HTMLInputElement).value)} />
+
     <h3>What triggers an alert</h3>
     <h4>Grouping</h4>
     <label for="grouping">
@@ -276,6 +287,33 @@ export class AlertConfigSk extends ElementSk {
       @input=${(e: InputEvent) =>
         (ele._config.minimum_num = +(e.target! as HTMLInputElement).value)} />
 
+    <h4>Data Completeness</h4>
+    <label for="min-traces">
+      Minimum number of traces that must have data at a commit before it is
+      checked for regressions. 0 means no minimum.
+    </label>
+    <input
+      id="min-traces"
+      type="number"
+      min="0"
+      .value=${(ele._config.min_traces || 0).toString()}
+      @input=${(e: InputEvent) =>
+        (ele._config.min_traces = +(e.target! as HTMLInputElement).value)} />
+    <label for="min-data-fraction">
+      Minimum fraction of traces, between 0 and 1, that must have data at a
+      commit before it is checked for regressions. 0 means no minimum.
+    </label>
+    <input
+      id="min-data-fraction"
+      type="number"
+      min="0"
+      max="1"
+      step="0.05"
+      .value=${(ele._config.min_data_fraction || 0).toString()}
+      @input=${(e: InputEvent) =>
+        (ele._config.min_data_fraction = +(e.target! as HTMLInputElement)
+          .value)} />
+
     <h4>Sparse</h4>
     <checkbox-sk
       ?checked=${ele._config.sparse}
@@ -311,7 +349,8 @@ export class AlertConfigSk extends ElementSk {
           </select-sk>
         `
       : html``}
-    ${window.perf.notifications === 'html_email'
+    ${window.perf.notifications === 'html_email' ||
+    window.perf.notifications === 'html_email_and_chat'
       ? html`
           <h3>Where are alerts sent</h3>
           <label for="sent">
@@ -322,6 +361,19 @@ export class AlertConfigSk extends ElementSk {
             .value=${ele._config.alert}
             @input=${(e: InputEvent) =>
               (ele._config.alert = (e.target! as HTMLInputElement).value)} />
+          ${window.perf.notifications === 'html_email_and_chat'
+            ? html`
+                <label for="chat">
+                  Chat Destination: The name of a chat channel configured for
+                  this instance.
+                </label>
+                <input
+                  id="chat"
+                  .value=${ele._config.chat || ''}
+                  @input=${(e: InputEvent) =>
+                    (ele._config.chat = (e.target! as HTMLInputElement).value)} />
+              `
+            : html``}
           <button @click=${ele.testAlert}>Test</button>
           <spinner-sk id="alertSpinner"></spinner-sk>
         `
@@ -358,7 +410,8 @@ export class AlertConfigSk extends ElementSk {
       ? html``
       : html`<h3>Where are bugs filed</h3>
           <label for="template">
-            Bug URI Template: {cluster_url}, {commit_url}, and {message}.
+            Bug URI Template: {cluster_url}, {chart_url}, {commit_url}, and
+            {message}.
           </label>
           <input
             id="template"
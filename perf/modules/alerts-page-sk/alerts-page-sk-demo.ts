@@ -23,6 +23,7 @@ fetchMock.post(
 );
 
 fetchMock.post('/_/alert/update', 200);
+fetchMock.get('/_/alert/template/list', []);
 
 // eslint-disable-next-line no-use-before-define
 fetchMock.get('/_/alert/list/false', (): Alert[] => [
@@ -3,6 +3,10 @@
  * @description <h2><code>alerts-page-sk</code></h2>
  *
  * A page for editing all the alert configs.
+ *
+ * Alerts that were expanded from an alert template are grouped under the name
+ * of their template. They can't be edited individually, instead the template
+ * is updated via the /_/alert/template/ API.
  */
 import '../../../elements-sk/modules/checkbox-sk';
 import '../../../elements-sk/modules/icons/delete-icon-sk';
@@ -23,6 +27,7 @@ import {
   Alert,
   ConfigState,
   ReadOnlyParamSet,
+  Template,
 } from '../json';
 import { validate } from '../alert';
 import { LoggedIn } from '../../../infra-sk/modules/alogin-sk/alogin-sk';
@@ -42,6 +47,8 @@ class AlertsPageSk extends ElementSk {
 
   private alerts: Alert[] = [];
 
+  private templates: Template[] = [];
+
   private showDeleted: boolean = false;
 
   private isEditor: boolean = false;
@@ -119,39 +126,66 @@ class AlertsPageSk extends ElementSk {
     return html`${msg}`;
   }
 
-  private static rows = (ele: AlertsPageSk) =>
-    ele.alerts.map(
-      (item) => html`
-        <tr>
-          <td>
-            <create-icon-sk
-              title="Edit"
-              @click=${ele.edit}
-              .__config=${item}
-              ?disabled=${!ele.isEditor}></create-icon-sk>
-          </td>
-          <td>${item.display_name}</td>
-          <td>
-            <paramset-sk .paramsets=${[toParamSet(item.query)]}></paramset-sk>
-          </td>
-          <td>${AlertsPageSk.alertOrComponent(item)}</td>
-          ${window.perf.need_alert_action === true
-            ? html` <td>${item.action ?? 'noaction'}</td> `
-            : html``}
-          <td>${item.owner}</td>
-          <td>${AlertsPageSk.displayIfAlertIsInvalid(item)}</td>
-          <td>
-            <delete-icon-sk
-              title="Delete"
-              @click=${ele.delete}
-              .__config=${item}
-              ?disabled=${!ele.isEditor}></delete-icon-sk>
-          </td>
-          <td><a href=${AlertsPageSk.dryrunUrl(item)}> Dry Run </a></td>
-          <td>${AlertsPageSk.ifNotActive(item.state)}</td>
-        </tr>
-      `
+  private static rows = (ele: AlertsPageSk) => html`
+    ${ele.alerts
+      .filter((item) => !item.template_id)
+      .map((item) => AlertsPageSk.row(ele, item))}
+    ${ele.templates.map((t) => AlertsPageSk.templateRows(ele, t))}
+  `;
+
+  private static templateRows(ele: AlertsPageSk, t: Template) {
+    const items = ele.alerts.filter(
+      (item) => item.template_id === t.id_as_string
     );
+    return html`
+      <tr class="template">
+        <td></td>
+        <td colspan="5">Template: ${t.name} (${items.length} alerts)</td>
+        <td></td>
+        <td>
+          <delete-icon-sk
+            title="Delete the template and all of its alerts"
+            @click=${ele.deleteTemplate}
+            .__template=${t}
+            ?disabled=${!ele.isEditor}></delete-icon-sk>
+        </td>
+        <td></td>
+        <td></td>
+      </tr>
+      ${items.map((item) => AlertsPageSk.row(ele, item))}
+    `;
+  }
+
+  private static row = (ele: AlertsPageSk, item: Alert) => html`
+    <tr class=${item.template_id ? 'fromTemplate' : ''}>
+      <td>
+        <create-icon-sk
+          title=${item.template_id ? 'Edit the template instead' : 'Edit'}
+          @click=${ele.edit}
+          .__config=${item}
+          ?disabled=${!ele.isEditor || !!item.template_id}></create-icon-sk>
+      </td>
+      <td>${item.display_name}</td>
+      <td>
+        <paramset-sk .paramsets=${[toParamSet(item.query)]}></paramset-sk>
+      </td>
+      <td>${AlertsPageSk.alertOrComponent(item)}</td>
+      ${window.perf.need_alert_action === true
+        ? html` <td>${item.action ?? 'noaction'}</td> `
+        : html``}
+      <td>${item.owner}</td>
+      <td>${AlertsPageSk.displayIfAlertIsInvalid(item)}</td>
+      <td>
+        <delete-icon-sk
+          title="Delete"
+          @click=${ele.delete}
+          .__config=${item}
+          ?disabled=${!ele.isEditor || !!item.template_id}></delete-icon-sk>
+      </td>
+      <td><a href=${AlertsPageSk.dryrunUrl(item)}> Dry Run </a></td>
+      <td>${AlertsPageSk.ifNotActive(item.state)}</td>
+    </tr>
+  `;
 
   private static alertOrComponent(item: Alert) {
     if (window.perf.notifications !== 'markdown_issuetracker') {
@@ -187,7 +221,10 @@ class AlertsPageSk extends ElementSk {
     const pList = this.listPromise().then((json) => {
       this.alerts = json;
     });
-    Promise.all([pInit, pList])
+    const pTemplates = this.templateListPromise().then((json) => {
+      this.templates = json;
+    });
+    Promise.all([pInit, pList, pTemplates])
       .then(() => {
         this._render();
         this.dialog = this.querySelector<HTMLDialogElement>('dialog');
@@ -212,12 +249,22 @@ class AlertsPageSk extends ElementSk {
   }
 
   /**
-   * Load all the alerts from the server.
+   * Start a request to get all the alert templates.
+   *
+   * @returns {Promise} The started fetch().
+   */
+  private templateListPromise() {
+    return fetch('/_/alert/template/list').then(jsonOrThrow);
+  }
+
+  /**
+   * Load all the alerts and alert templates from the server.
    */
   private list() {
-    this.listPromise()
-      .then((json: Alert[]) => {
-        this.alerts = json;
+    Promise.all([this.listPromise(), this.templateListPromise()])
+      .then(([alerts, templates]: [Alert[], Template[]]) => {
+        this.alerts = alerts;
+        this.templates = templates;
         this._render();
         this.openOnLoad();
       })
@@ -304,6 +351,18 @@ class AlertsPageSk extends ElementSk {
       .catch(errorMessage);
   }
 
+  private deleteTemplate(e: MouseEvent) {
+    const t = (e.target! as any).__template as Template;
+    fetch(`/_/alert/template/delete/${t.id_as_string}`, {
+      method: 'POST',
+    })
+      .then(okOrThrow)
+      .then(() => {
+        this.list();
+      })
+      .catch(errorMessage);
+  }
+
   /** The Alert being edited. */
   get cfg() {
     return this._cfg;
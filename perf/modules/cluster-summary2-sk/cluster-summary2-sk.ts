@@ -270,7 +270,22 @@ export class ClusterSummary2Sk extends ElementSk {
           ).value;
         }}
         label="Message" />
+      <label class="fixed-by">
+        Fixed by commit
+        <input
+          type="number"
+          min="0"
+          .value=${ele.triageStatus.fixed_by
+            ? String(ele.triageStatus.fixed_by)
+            : ''}
+          @change=${(e: InputEvent) => {
+            const fixedBy = +(e.currentTarget! as HTMLInputElement).value;
+            ele.triageStatus.fixed_by =
+              fixedBy > 0 ? CommitNumber(fixedBy) : undefined;
+          }} />
+      </label>
       <button class="action" @click=${ele.update}>Update</button>
+      ${ClusterSummary2Sk.fixStatusTemplate(ele)}
     </div>
     <commit-detail-panel-sk id="commits" selectable></commit-detail-panel-sk>
     <div class="actions">
@@ -291,6 +306,22 @@ export class ClusterSummary2Sk extends ElementSk {
     </collapse-sk>
   `;
 
+  private static fixStatusTemplate = (ele: ClusterSummary2Sk) => {
+    switch (ele.triageStatus.fix) {
+      case 'verified':
+        return html`<p class="fix-status">
+          Verified fixed by commit ${ele.triageStatus.fixed_by}.
+        </p>`;
+      case 'not_recovered':
+        return html`<p class="fix-status not-recovered">
+          Reopened: the metric did not recover at commit
+          ${ele.triageStatus.fixed_by}.
+        </p>`;
+      default:
+        return html``;
+    }
+  };
+
   private static leastSquares = (ele: ClusterSummary2Sk) => html`
     <div class="labelled">
       ${ele.labels.lse}
@@ -0,0 +1,62 @@
+/**
+ * @module module/embed-sk
+ * @description <h2><code>embed-sk</code></h2>
+ *
+ * A minimal, read-only chart suitable for embedding in other sites via an
+ * iframe. The state of the chart, e.g. the shortcut id in 'keys', is read from
+ * the URL query parameters in the same format the explore page uses, but
+ * changes to the state are never reflected back to the URL.
+ *
+ * @attr width - The width of the chart in pixels.
+ *
+ * @attr height - The height of the chart in pixels.
+ */
+import { html } from 'lit-html';
+import { define } from '../../../elements-sk/modules/define';
+import { ExploreSimpleSk, State } from '../explore-simple-sk/explore-simple-sk';
+import { stateReflector } from '../../../infra-sk/modules/stateReflector';
+import { HintableObject } from '../../../infra-sk/modules/hintable';
+import { ElementSk } from '../../../infra-sk/modules/ElementSk';
+
+import '../explore-simple-sk';
+
+export class EmbedSk extends ElementSk {
+  private exploreSimpleSk: ExploreSimpleSk | null = null;
+
+  constructor() {
+    super(EmbedSk.template);
+  }
+
+  connectedCallback(): void {
+    super.connectedCallback();
+    this._render();
+
+    const width = this.getAttribute('width');
+    if (width) {
+      this.style.width = `${width}px`;
+    }
+    const height = this.getAttribute('height');
+    if (height) {
+      this.style.height = `${height}px`;
+    }
+
+    this.exploreSimpleSk = this.querySelector('explore-simple-sk');
+    this.exploreSimpleSk!.openQueryByDefault = false;
+    this.exploreSimpleSk!.navOpen = false;
+    // The returned stateHasChanged callback is intentionally never called,
+    // embedded charts are read-only and never update the URL.
+    stateReflector(
+      () => this.exploreSimpleSk!.state as unknown as HintableObject,
+      (hintableState) => {
+        const state = hintableState as unknown as State;
+        this.exploreSimpleSk!.state = state;
+      }
+    );
+  }
+
+  private static template = () => html`
+    <explore-simple-sk></explore-simple-sk>
+  `;
+}
+
+define('embed-sk', EmbedSk);
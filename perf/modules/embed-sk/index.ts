@@ -0,0 +1 @@
+import './embed-sk';
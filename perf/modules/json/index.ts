@@ -16,7 +16,9 @@ export interface Alert {
 	id_as_string: string;
 	display_name: string;
 	query: string;
+	derived_metric?: string;
 	alert: string;
+	chat?: string;
 	issue_tracker_component: SerializesToString;
 	interesting: number;
 	bug_uri_template: string;
@@ -32,13 +34,24 @@ export interface Alert {
 	sparse: boolean;
 	minimum_num: number;
 	category: string;
+	min_traces?: number;
+	min_data_fraction?: number;
 	action?: AlertAction;
+	template_id?: string;
+	template_values?: { [key: string]: string } | null;
 }
 
 export interface AlertsStatus {
 	alerts: number;
 }
 
+export interface Template {
+	id_as_string: string;
+	name: string;
+	alert: Alert;
+	variables: { [key: string]: string[] | null } | null;
+}
+
 export interface RevisionInfo {
 	master: string;
 	bot: string;
@@ -101,6 +114,14 @@ export interface QueryConfig {
 	default_url_values?: { [key: string]: string } | null;
 }
 
+export interface DerivedMetric {
+	name: string;
+	formula: string;
+	description: string;
+	owner: string;
+	last_modified: number;
+}
+
 export interface Commit {
 	offset: CommitNumber;
 	hash: string;
@@ -147,9 +168,23 @@ export interface FrameResponse {
 	anomalymap: AnomalyMap;
 }
 
+export interface PartialFrameResponse {
+	response: FrameResponse | null;
+	completed: number;
+	total: number;
+}
+
 export interface TriageStatus {
 	status: Status;
 	message: string;
+	fixed_by?: CommitNumber;
+	triaged_by?: string;
+	fix?: FixStatus;
+}
+
+export interface TriageEvent {
+	status: TriageStatus;
+	ts: number;
 }
 
 export interface Regression {
@@ -158,6 +193,8 @@ export interface Regression {
 	frame: FrameResponse | null;
 	low_status: TriageStatus;
 	high_status: TriageStatus;
+	low_history?: TriageEvent[] | null;
+	high_history?: TriageEvent[] | null;
 }
 
 export interface RegressionAtCommit {
@@ -174,9 +211,16 @@ export interface FrameRequest {
 	tz: string;
 	num_commits: number;
 	request_type: RequestType;
+	begin_commit?: CommitNumber | null;
+	end_commit?: CommitNumber | null;
 	pivot: pivot.Request | null;
 }
 
+export interface AlertTemplateUpdateResponse {
+	id_as_string: string;
+	alerts: (Alert | null)[] | null;
+}
+
 export interface AlertUpdateResponse {
 	IDAsString: string;
 }
@@ -195,6 +239,20 @@ export interface CommitDetailsRequest {
 	traceid: string;
 }
 
+export interface ConvertRangeRequest {
+	begin: number;
+	end: number;
+	begin_commit?: CommitNumber | null;
+	end_commit?: CommitNumber | null;
+}
+
+export interface ConvertRangeResponse {
+	begin: number;
+	end: number;
+	begin_commit: CommitNumber;
+	end_commit: CommitNumber;
+}
+
 export interface CountHandlerRequest {
 	q: string;
 	begin: number;
@@ -214,11 +272,21 @@ export interface RangeRequest {
 	offset: CommitNumber;
 	begin: number;
 	end: number;
+	begin_commit?: CommitNumber | null;
+	end_commit?: CommitNumber | null;
+}
+
+export interface RegressionDetailResponse {
+	commit: Commit;
+	alert: Alert | null;
+	regression: Regression | null;
 }
 
 export interface RegressionRangeRequest {
 	begin: number;
 	end: number;
+	begin_commit?: CommitNumber | null;
+	end_commit?: CommitNumber | null;
 	subset: Subset;
 	alert_filter: string;
 }
@@ -237,11 +305,15 @@ export interface RegressionRangeResponse {
 export interface ShiftRequest {
 	begin: CommitNumber;
 	end: CommitNumber;
+	begin_ts?: number | null;
+	end_ts?: number | null;
 }
 
 export interface ShiftResponse {
 	begin: number;
 	end: number;
+	begin_commit: CommitNumber;
+	end_commit: CommitNumber;
 }
 
 export interface SkPerfConfig {
@@ -409,6 +481,45 @@ export namespace ingest {
 	}
 }
 
+export namespace ingest {
+	export interface SimulatedTrace {
+		trace_id: string;
+		params: Params;
+		value: number;
+	}
+}
+
+export namespace ingest {
+	export interface Simulation {
+		git_hash: string;
+		skipped: boolean;
+		trace_count: number;
+		traces: ingest.SimulatedTrace[] | null;
+		paramset: ReadOnlyParamSet;
+		warnings: string[] | null;
+	}
+}
+
+export namespace heatmap {
+	export interface Request {
+		begin: number;
+		end: number;
+		q: string;
+		max_rows: number;
+		max_cols: number;
+	}
+}
+
+export namespace heatmap {
+	export interface Heatmap {
+		rows: string[] | null;
+		columns: (ColumnHeader | null)[] | null;
+		bucket_size: number;
+		cells: (number[] | null)[] | null;
+		total_traces: number;
+	}
+}
+
 export type Params = { [key: string]: string } & {
 	/**
 	* WARNING: Do not reference this field from application code.
@@ -543,11 +654,13 @@ export type AnomalyMap = { [key: string]: CommitNumberAnomalyMap } | null;
 
 export type Status = '' | 'positive' | 'negative' | 'untriaged';
 
+export type FixStatus = '' | 'verified' | 'not_recovered';
+
 export type RequestType = 0 | 1;
 
 export type Subset = 'all' | 'regressions' | 'untriaged';
 
-export type NotifierTypes = 'html_email' | 'markdown_issuetracker' | 'none';
+export type NotifierTypes = 'html_email' | 'html_email_and_chat' | 'markdown_issuetracker' | 'none';
 
 export type TraceFormat = 'chrome' | '';
 